@@ -0,0 +1,170 @@
+// ABOUTME: Argo CD config management plugin - lints .prom files during manifest generation
+// ABOUTME: Passes the app's YAML manifests through and adds a Kubernetes Event per finding on the Application
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"gopkg.in/yaml.v3"
+)
+
+// Used when the application-namespace plugin parameter is unset
+const defaultApplicationNamespace = "argocd"
+
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "generate" {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry-argocd-plugin generate")
+		fmt.Fprintln(os.Stderr, "Run by Argo CD's CMP server in the application's source directory.")
+		os.Exit(2)
+	}
+
+	if err := generate(".", os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate writes the directory's manifests followed by an Event for every
+// lint problem in its .prom files. Argo CD reads the result from stdout.
+func generate(dir string, out io.Writer) error {
+	app := os.Getenv("ARGOCD_APP_NAME")
+	if app == "" {
+		return errors.New("ARGOCD_APP_NAME is not set; this command is run by Argo CD")
+	}
+	appNamespace := os.Getenv("PARAM_APPLICATION_NAMESPACE")
+	if appNamespace == "" {
+		appNamespace = defaultApplicationNamespace
+	}
+
+	var docs []any
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			manifests, err := readManifests(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			docs = append(docs, manifests...)
+		case ".prom":
+			events, err := lintEvents(path, app, appNamespace)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, events...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// readManifests returns the Kubernetes objects in a YAML file, skipping
+// documents without apiVersion and kind such as Helm values or CI config
+func readManifests(path string) ([]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []any
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]any
+		if err := dec.Decode(&doc); err == io.EOF {
+			return manifests, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if doc["apiVersion"] != nil && doc["kind"] != nil {
+			manifests = append(manifests, doc)
+		}
+	}
+}
+
+func lintEvents(path, app, appNamespace string) ([]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := metrics.Parse(string(data))
+	if err != nil {
+		return []any{event(app, appNamespace, "MetricParseError", "Warning", fmt.Sprintf("%s: %v", path, err))}, nil
+	}
+
+	var events []any
+	// Event names come from the message, so repeats of a finding are dropped
+	seen := make(map[string]bool)
+	for _, f := range rules.Problems(rules.Check(parsed)) {
+		message := fmt.Sprintf("%s: %s %s: %s", path, f.Severity, f.Code, f.Message)
+		if seen[message] {
+			continue
+		}
+		seen[message] = true
+
+		eventType := "Normal"
+		if f.Severity == rules.SeverityError || f.Severity == rules.SeverityWarning {
+			eventType = "Warning"
+		}
+		events = append(events, event(app, appNamespace, "MetricLint", eventType, message))
+	}
+	return events, nil
+}
+
+// event builds a core/v1 Event on the Application. Names are derived from the
+// message and timestamps are omitted so unchanged findings never show as a diff.
+func event(app, appNamespace, reason, eventType, message string) map[string]any {
+	sum := sha256.Sum256([]byte(message))
+	return map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("%s.goodtelemetry-%s", app, hex.EncodeToString(sum[:])[:12]),
+			"namespace": appNamespace,
+		},
+		"involvedObject": map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"name":       app,
+			"namespace":  appNamespace,
+		},
+		"reason":  reason,
+		"type":    eventType,
+		"message": message,
+		"source": map[string]any{
+			"component": "goodtelemetry",
+		},
+	}
+}
@@ -0,0 +1,80 @@
+// ABOUTME: allowlist subcommand - builds a label allowlist from the labels existing metric files use
+// ABOUTME: Prints one label name per line, the format lint and eval read with --allowlist
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+func runAllowlist(args []string) int {
+	if len(args) == 0 || args[0] != "generate" {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry allowlist generate FILE...")
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("allowlist generate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry allowlist generate FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var parsed []*metrics.ParsedMetrics
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitInternal
+		}
+		p, err := metrics.Parse(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			return exitFindings
+		}
+		parsed = append(parsed, p)
+	}
+
+	for _, label := range rules.LabelNames(parsed...) {
+		fmt.Println(label)
+	}
+	return exitClean
+}
+
+// allowlistFlag adds --allowlist, returning a function that reads the label
+// names from the file it names: one per line, skipping blanks and # comments
+func allowlistFlag(fs *flag.FlagSet) func() ([]string, error) {
+	path := fs.String("allowlist", "", "file of approved label names, one per line; any other label is flagged (see allowlist generate)")
+	return func() ([]string, error) {
+		if *path == "" {
+			return nil, nil
+		}
+		f, err := os.Open(*path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		var labels []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				labels = append(labels, line)
+			}
+		}
+		return labels, scanner.Err()
+	}
+}
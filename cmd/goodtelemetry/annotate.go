@@ -0,0 +1,241 @@
+// ABOUTME: annotate subcommand - evaluates the labels of ServiceMonitor and PodMonitor resources in manifests
+// ABOUTME: Writes score, verdict and evaluation time back as goodtelemetry.io annotations, an audit trail kept in git
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/server"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	annotationScore       = "goodtelemetry.io/score"
+	annotationVerdict     = "goodtelemetry.io/verdict"
+	annotationEvaluatedAt = "goodtelemetry.io/evaluated-at"
+)
+
+func runAnnotate(args []string) int {
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("annotate", flag.ContinueOnError)
+	mode := fs.String("mode", cfg.Profile, "naming convention to check: "+strings.Join(rules.ProfileNames(), ", "))
+	fs.StringVar(&cfg.LLMURL, "llm-url", cfg.LLMURL, "Ollama API endpoint (env LLM_BACKEND_URL)")
+	fs.StringVar(&cfg.Model, "model", cfg.Model, "Ollama model to use (env OLLAMA_MODEL)")
+	verbose := fs.Bool("verbose", false, "log the prompt and raw LLM response to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry annotate [--mode MODE] [--llm-url URL] [--model MODEL] FILE...")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	profile, err := rules.Profile(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	if !*verbose {
+		// The LLM client logs whole prompts and responses for the server log
+		log.SetOutput(io.Discard)
+	}
+	client := llm.NewClient(cfg.LLMURL, cfg.Model)
+	client.SetAPIKey(cfg.LLMAPIKey)
+	client.SetRouting(cfg.Routing)
+
+	code := exitClean
+	for _, path := range fs.Args() {
+		if c := annotateFile(path, profile, client); c > code {
+			code = c
+		}
+	}
+	return code
+}
+
+// annotateFile evaluates each monitor in the manifest at path and rewrites the
+// file with its annotations set
+func annotateFile(path string, profile rules.NamingProfile, client *llm.Client) int {
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: document %d: %v\n", path, len(docs)+1, err)
+			return exitFindings
+		}
+		docs = append(docs, &doc)
+	}
+
+	annotated := 0
+	for _, doc := range docs {
+		if len(doc.Content) == 0 {
+			continue
+		}
+		obj := doc.Content[0]
+		kind := mappingValue(obj, "kind")
+		if kind == nil || (kind.Value != "ServiceMonitor" && kind.Value != "PodMonitor") {
+			continue
+		}
+		where := kind.Value
+		if name := mappingValue(mappingValue(obj, "metadata"), "name"); name != nil {
+			where += "/" + name.Value
+		}
+
+		manifest, err := yaml.Marshal(doc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s: %v\n", path, where, err)
+			return exitInternal
+		}
+		parsed, err := parseKubernetes(string(manifest), profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s: %v, skipped\n", path, where, err)
+			continue
+		}
+		evaluation, err := client.Evaluate(context.Background(), parsed, profile.PromptInstructions, profile.Fingerprint(), "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s: %v\n", path, where, err)
+			return exitLLMUnavailable
+		}
+
+		score := ""
+		if evaluation.LetterGrade != "" {
+			score = strconv.Itoa(evaluation.Score)
+		}
+		setAnnotations(obj, map[string]string{
+			annotationScore:       score,
+			annotationVerdict:     evaluation.Verdict,
+			annotationEvaluatedAt: time.Now().UTC().Format(time.RFC3339),
+		})
+		annotated++
+		fmt.Printf("%s: %s: %s\n", path, where, evaluation.Verdict)
+	}
+	if annotated == 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s: no ServiceMonitor or PodMonitor with labels to evaluate\n", path)
+		return exitClean
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+			return exitInternal
+		}
+	}
+	if err := enc.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+		return exitInternal
+	}
+	if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+	return exitClean
+}
+
+// mappingValue returns the value under key in a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setAnnotations writes metadata.annotations on a resource, creating the
+// mappings as needed. An empty value removes the annotation, so a stale one
+// doesn't outlive the evaluation that set it.
+func setAnnotations(obj *yaml.Node, annotations map[string]string) {
+	metadata := ensureMapping(obj, "metadata")
+	existing := ensureMapping(metadata, "annotations")
+	for _, key := range []string{annotationScore, annotationVerdict, annotationEvaluatedAt} {
+		value, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		i := mappingIndex(existing, key)
+		switch {
+		case value == "" && i >= 0:
+			existing.Content = append(existing.Content[:i], existing.Content[i+2:]...)
+		case value == "":
+		case i >= 0:
+			// A comment on the old value stays with the new one
+			node := stringNode(value)
+			node.LineComment = existing.Content[i+1].LineComment
+			existing.Content[i+1] = node
+		default:
+			existing.Content = append(existing.Content, stringNode(key), stringNode(value))
+		}
+	}
+}
+
+func ensureMapping(node *yaml.Node, key string) *yaml.Node {
+	if value := mappingValue(node, key); value != nil && value.Kind == yaml.MappingNode {
+		return value
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if i := mappingIndex(node, key); i >= 0 {
+		// Replaces a null such as "annotations:" with nothing after it
+		node.Content[i+1] = value
+	} else {
+		node.Content = append(node.Content, stringNode(key), value)
+	}
+	return value
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// stringNode quotes values such as scores so they stay strings, as annotations must be
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,110 @@
+// ABOUTME: Golden tests for the annotate subcommand with the LLM stubbed - each manifest under testdata/annotate
+// ABOUTME: is rewritten and compared to its .golden file, which pins comment and layout preservation
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the annotate golden files from this run")
+
+const annotateDir = "testdata/annotate"
+
+const stubEvaluation = `VERDICT: Good
+SCORE: 90
+STRENGTHS:
+- Topology labels only
+ISSUES:
+- None
+RECOMMENDATIONS:
+- Keep it up
+`
+
+// newStubOllama answers every generate call with stubEvaluation
+func newStubOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"response": stubEvaluation, "done": true})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+var evaluatedAt = regexp.MustCompile(`(goodtelemetry\.io/evaluated-at: )"([^"]+)"`)
+
+func TestAnnotateGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join(annotateDir, "*.yaml"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no manifests in %s: %v", annotateDir, err)
+	}
+	t.Setenv("LLM_BACKEND_URL", newStubOllama(t).URL)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			manifest, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), name+".yaml")
+			if err := os.WriteFile(path, manifest, 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			var code int
+			captureOutput(t, func() { code = runAnnotate([]string{path}) })
+			if code != exitClean {
+				t.Fatalf("annotate exited %d, want %d", code, exitClean)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o640 {
+				t.Errorf("mode = %v, want the file's own 0640 kept", info.Mode().Perm())
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The evaluation time is the only thing that changes between runs
+			for _, m := range evaluatedAt.FindAllSubmatch(got, -1) {
+				at, err := time.Parse(time.RFC3339, string(m[2]))
+				if err != nil || time.Since(at) > time.Minute {
+					t.Errorf("evaluated-at %q is not the time of this run", m[2])
+				}
+			}
+			got = evaluatedAt.ReplaceAll(got, []byte(`$1"<evaluated-at>"`))
+
+			golden := filepath.Join(annotateDir, name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading %s: %v; write it with go test ./cmd/goodtelemetry -run TestAnnotateGolden -update", golden, err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("annotated %s differs from %s.\ngot:\n%s\nwant:\n%s", input, golden, got, want)
+			}
+		})
+	}
+}
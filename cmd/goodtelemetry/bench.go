@@ -0,0 +1,107 @@
+// ABOUTME: bench subcommand (hidden) - times the static pipeline on a synthetic scrape of a chosen size
+// ABOUTME: Lets users size a deployment on their own hardware without an LLM or real metrics
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/improve"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	series := fs.Int("series", 100000, "series in the synthetic scrape")
+	families := fs.Int("metrics", 10, "metric families the series are spread over")
+	labels := fs.Int("labels", 3, "labels on every series")
+	runs := fs.Int("runs", 3, "times to run the pipeline; the fastest run is reported")
+	mode := fs.String("mode", rules.DefaultProfile, "naming convention to check: "+strings.Join(rules.ProfileNames(), ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry bench [--series N] [--metrics N] [--labels N] [--runs N] [--mode MODE]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *series < 1 || *families < 1 || *labels < 1 || *runs < 1 {
+		fmt.Fprintln(os.Stderr, "error: --series, --metrics, --labels and --runs must be at least 1")
+		return exitUsage
+	}
+	profile, err := rules.Profile(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	input := metrics.SyntheticScrape(*series, *families, *labels)
+	fmt.Printf("Synthetic scrape: %d series in %d metric(s) with %d label(s), %s\n", *series, *families, *labels, humanBytes(len(input)))
+
+	stages := []string{"parse", "check", "improve"}
+	best := make(map[string]time.Duration)
+	var bestTotal time.Duration
+	var allocs, allocBytes uint64
+	for run := range *runs {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		took := make(map[string]time.Duration)
+		start := time.Now()
+		parsed, err := profile.Parse(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: parsing the synthetic scrape: %v\n", err)
+			return exitInternal
+		}
+		took["parse"] = time.Since(start)
+
+		mark := time.Now()
+		evaluated, _ := naming.ExcludeRuntime(parsed)
+		profile.Check(evaluated)
+		took["check"] = time.Since(mark)
+
+		mark = time.Now()
+		improve.Example(evaluated, profile.LabelOrder())
+		rules.NamespaceTree(evaluated)
+		rules.SummaryMigrations(evaluated)
+		took["improve"] = time.Since(mark)
+		total := time.Since(start)
+
+		runtime.ReadMemStats(&after)
+		if run == 0 || total < bestTotal {
+			bestTotal = total
+			for _, stage := range stages {
+				best[stage] = took[stage]
+			}
+			allocs, allocBytes = after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc
+		}
+	}
+
+	fmt.Printf("Fastest of %d run(s):\n", *runs)
+	for _, stage := range stages {
+		fmt.Printf("  %-8s %v\n", stage, best[stage].Round(time.Microsecond))
+	}
+	fmt.Printf("  %-8s %v (%.0f series/s)\n", "total", bestTotal.Round(time.Microsecond), float64(*series)/bestTotal.Seconds())
+	fmt.Printf("Allocations: %d (%s)\n", allocs, humanBytes(int(allocBytes)))
+	return exitClean
+}
+
+func humanBytes(n int) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,74 @@
+// ABOUTME: convert subcommand - migrates metrics from other formats to Prometheus text format
+// ABOUTME: Reads a file, detects or accepts its format, writes the converted exposition to stdout
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wbollock/good_telemetry/internal/formats"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := fs.String("from", "", "input format: statsd, dogstatsd, influxdb or openmetrics (auto-detected when empty)")
+	to := fs.String("to", "prometheus", "output format (only prometheus is supported)")
+	strict := fs.Bool("strict-openmetrics", false, "fail on OpenMetrics input that doesn't end with # EOF instead of warning")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry convert [--from FORMAT] [--to prometheus] [--strict-openmetrics] FILE")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *to != "prometheus" {
+		fmt.Fprintf(os.Stderr, "unsupported output format %q (only prometheus is supported)\n", *to)
+		return 2
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	input := string(data)
+
+	var format formats.Format
+	if *from != "" {
+		format, err = formats.ParseFormat(*from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+	} else {
+		format, err = formats.Detect(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v (use --from to set the format)\n", err)
+			return 1
+		}
+	}
+
+	converted, err := formats.Convert(input, format, formats.Options{StrictOpenMetrics: *strict})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: converting %s: %v\n", format, err)
+		return 1
+	}
+
+	for _, warning := range converted.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	if err := metrics.WriteText(os.Stdout, converted.Metrics, converted.Types, converted.Help); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
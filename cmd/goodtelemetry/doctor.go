@@ -0,0 +1,190 @@
+// ABOUTME: doctor subcommand - diagnoses the local environment before filing issues or debugging
+// ABOUTME: Checks Ollama reachability, model installation, docs/examples/config paths and the Go toolchain
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/config"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/server"
+)
+
+// Keep in sync with the go directive in go.mod
+const minGoVersion = "go1.25"
+
+type checkResult struct {
+	ok     bool
+	detail string
+	hint   string
+}
+
+type check struct {
+	name string
+	run  func() checkResult
+}
+
+func runDoctor(args []string) int {
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		// Nothing else can be checked reliably without configuration
+		fmt.Printf("✗ Secrets: %v\n", err)
+		fmt.Printf("    → check the *_FILE paths and VAULT_* settings\n")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.StringVar(&cfg.LLMURL, "llm-url", cfg.LLMURL, "Ollama API endpoint (env LLM_BACKEND_URL)")
+	fs.StringVar(&cfg.Model, "model", cfg.Model, "Ollama model to use (env OLLAMA_MODEL)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry doctor [--llm-url URL] [--model MODEL]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	client := llm.NewClient(cfg.LLMURL, cfg.Model)
+	client.SetAPIKey(cfg.LLMAPIKey)
+	var tags *llm.TagsResponse
+	var tagsErr error
+
+	docsDir := os.Getenv("DOCS_DIR")
+	if docsDir == "" {
+		docsDir = "./docs"
+	}
+
+	checks := []check{
+		{"Ollama reachable", func() checkResult {
+			tags, tagsErr = client.Tags(context.Background())
+			if tagsErr != nil {
+				return checkResult{
+					detail: fmt.Sprintf("%s: %v", cfg.LLMURL, tagsErr),
+					hint:   "start Ollama with `ollama serve` or set LLM_BACKEND_URL to the right host",
+				}
+			}
+			return checkResult{ok: true, detail: cfg.LLMURL}
+		}},
+		{"Model installed", func() checkResult {
+			if tagsErr != nil {
+				return checkResult{detail: "skipped, Ollama is unreachable", hint: "fix the Ollama check first"}
+			}
+			if !tags.HasModel(cfg.Model) {
+				return checkResult{
+					detail: fmt.Sprintf("%q is not installed", cfg.Model),
+					hint:   fmt.Sprintf("run `ollama pull %s` or set OLLAMA_MODEL to an installed model", cfg.Model),
+				}
+			}
+			return checkResult{ok: true, detail: cfg.Model}
+		}},
+		{"Docs directory", func() checkResult {
+			return checkDocsDir(docsDir)
+		}},
+		{"Examples file", func() checkResult {
+			return checkExamplesFile(os.Getenv("EXAMPLES_FILE"))
+		}},
+		{"Config file", func() checkResult {
+			return checkConfigFile(cfg)
+		}},
+		{"Go toolchain", checkGoToolchain},
+	}
+
+	failures := 0
+	for _, c := range checks {
+		result := c.run()
+		mark := "✓"
+		if !result.ok {
+			mark = "✗"
+			failures++
+		}
+		fmt.Printf("%s %s: %s\n", mark, c.name, result.detail)
+		if !result.ok && result.hint != "" {
+			fmt.Printf("    → %s\n", result.hint)
+		}
+	}
+
+	if failures > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failures, len(checks))
+		return 1
+	}
+	fmt.Printf("\nAll %d checks passed\n", len(checks))
+	return 0
+}
+
+func checkDocsDir(dir string) checkResult {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return checkResult{
+			detail: fmt.Sprintf("%s: %v", dir, err),
+			hint:   "create the directory or point DOCS_DIR at the RAG knowledge base",
+		}
+	}
+
+	files := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			files++
+		}
+	}
+	if files == 0 {
+		return checkResult{
+			detail: fmt.Sprintf("%s contains no files", dir),
+			hint:   "add markdown or text documents to the directory",
+		}
+	}
+	return checkResult{ok: true, detail: fmt.Sprintf("%s (%d files)", dir, files)}
+}
+
+func checkConfigFile(cfg server.Config) checkResult {
+	path := cfg.ConfigFilePath()
+	if path == "" {
+		return checkResult{ok: true, detail: "CONFIG_FILE not set, using environment only"}
+	}
+
+	if _, err := config.Load(path); err != nil {
+		return checkResult{detail: err.Error(), hint: "fix the YAML (see config.example.yaml) or unset CONFIG_FILE"}
+	}
+	return checkResult{ok: true, detail: path}
+}
+
+func checkExamplesFile(path string) checkResult {
+	if path == "" {
+		return checkResult{ok: true, detail: "EXAMPLES_FILE not set, using built-in examples"}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return checkResult{detail: fmt.Sprintf("%s: %v", path, err), hint: "fix the path in EXAMPLES_FILE or unset it"}
+	}
+	if !json.Valid(data) {
+		return checkResult{detail: fmt.Sprintf("%s is not valid JSON", path), hint: "validate the file with `jq . " + path + "`"}
+	}
+	return checkResult{ok: true, detail: path}
+}
+
+func checkGoToolchain() checkResult {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return checkResult{
+			detail: "go not found on PATH",
+			hint:   fmt.Sprintf("install Go %s or newer to build from source", strings.TrimPrefix(minGoVersion, "go")),
+		}
+	}
+
+	goVersion := strings.TrimSpace(string(out))
+	if version.Compare(goVersion, minGoVersion) < 0 {
+		return checkResult{
+			detail: fmt.Sprintf("%s is older than the required %s", goVersion, minGoVersion),
+			hint:   fmt.Sprintf("upgrade Go to %s or newer", strings.TrimPrefix(minGoVersion, "go")),
+		}
+	}
+	return checkResult{ok: true, detail: goVersion}
+}
@@ -0,0 +1,199 @@
+// ABOUTME: eval subcommand - runs a full evaluation (static checks and LLM) on one file from the terminal
+// ABOUTME: Reads exposition text, client_golang definitions in Go source, or Prometheus Operator manifests
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/formats"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/server"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+func runEval(args []string) int {
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	format := fs.String("format", "", "input format: prometheus, go or kubernetes (from the file extension when empty)")
+	mode := fs.String("mode", cfg.Profile, "naming convention to check: "+strings.Join(rules.ProfileNames(), ", "))
+	fs.StringVar(&cfg.LLMURL, "llm-url", cfg.LLMURL, "Ollama API endpoint (env LLM_BACKEND_URL)")
+	fs.StringVar(&cfg.Model, "model", cfg.Model, "Ollama model to use (env OLLAMA_MODEL)")
+	verbose := fs.Bool("verbose", false, "log the prompt and raw LLM response to stderr")
+	summaryFile := fs.String("summary-file", "", "also write a JSON summary of the results to this path")
+	lexicon := lexiconFlags(fs)
+	allowlist := allowlistFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry eval [--format prometheus|go|kubernetes] [--mode MODE] [--llm-url URL] [--model MODEL] [--vague-words WORDS] [--forbidden-words WORDS] [--allowlist FILE] [--summary-file PATH] FILE")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	profile, err := rules.Profile(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	profile = profile.WithLexicon(lexicon())
+	if profile.AllowedLabels, err = allowlist(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading allowlist: %v\n", err)
+		return exitUsage
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = "prometheus"
+		if filepath.Ext(path) == ".go" {
+			*format = "go"
+		}
+	}
+
+	summary := newSummary("eval", map[string]any{"format": *format, "mode": *mode, "model": cfg.Model, "lexicon": lexicon()})
+	finish := func(code int) int {
+		if err := summary.write(*summaryFile, code); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing summary: %v\n", err)
+			return exitInternal
+		}
+		return code
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return finish(exitInternal)
+	}
+	// Held to the same limits as submissions to the server
+	req := api.EvaluateRequest{Metrics: string(data)}
+	if err := req.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+		summary.addFile(path, nil, nil, "", err)
+		return finish(exitFindings)
+	}
+
+	var parsed *metrics.ParsedMetrics
+	switch *format {
+	case "prometheus":
+		parsed, err = profile.Parse(req.Metrics)
+	case "go":
+		parsed, err = parseGoSource(req.Metrics, profile)
+	case "kubernetes":
+		parsed, err = parseKubernetes(req.Metrics, profile)
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported format %q (choose prometheus, go or kubernetes)\n", *format)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+		summary.addFile(path, nil, nil, "", err)
+		return finish(exitFindings)
+	}
+
+	if !*verbose {
+		// The LLM client logs whole prompts and responses for the server log
+		log.SetOutput(io.Discard)
+	}
+
+	client := llm.NewClient(cfg.LLMURL, cfg.Model)
+	client.SetAPIKey(cfg.LLMAPIKey)
+	client.SetRouting(cfg.Routing)
+	if cfg.LLMCassetteDir != "" {
+		if err := client.SetCassettes(cfg.LLMCassetteDir, cfg.LLMCassetteMode); err != nil {
+			fmt.Fprintf(os.Stderr, "error: LLM_CASSETTE_MODE: %v\n", err)
+			return finish(exitUsage)
+		}
+	}
+	findings := profile.Check(parsed)
+	evaluation, err := client.Evaluate(context.Background(), parsed, profile.PromptInstructions, profile.Fingerprint(), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		summary.addFile(path, parsed, findings, "", nil)
+		return finish(exitLLMUnavailable)
+	}
+	summary.addFile(path, parsed, findings, evaluation.Verdict, nil)
+
+	fmt.Printf("Verdict: %s (%s)\n", evaluation.Verdict, evaluation.Model)
+	if evaluation.LetterGrade != "" {
+		fmt.Printf("Score: %d (%s)\n", evaluation.Score, evaluation.LetterGrade)
+	}
+	if *format != "prometheus" {
+		fmt.Println("\nMetrics found:")
+		for _, m := range parsed.Metrics {
+			fmt.Printf("  %s\n", m.Raw)
+		}
+	}
+	printSection("Input warnings", parsed.Warnings)
+	printSection("Input normalized", parsed.Normalized)
+	printSection("Static checks", findingMessages(rules.Problems(findings)))
+	printSection("Issues", evaluation.Issues)
+	printSection("Recommendations", evaluation.Recommendations)
+	if evaluation.ImprovedExample != "" {
+		fmt.Printf("\nImproved example:\n%s\n", evaluation.ImprovedExample)
+	}
+
+	for _, f := range findings {
+		if f.Severity == rules.SeverityError || f.Severity == rules.SeverityWarning {
+			return finish(exitFindings)
+		}
+	}
+	return finish(exitClean)
+}
+
+func findingMessages(findings []rules.Finding) []string {
+	messages := make([]string, len(findings))
+	for i, f := range findings {
+		messages[i] = fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message)
+	}
+	return messages
+}
+
+func printSection(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, item := range items {
+		fmt.Printf("  - %s\n", item)
+	}
+}
+
+// parseKubernetes reads the metric and label names in Prometheus Operator
+// manifests and judges their cardinality against the profile's thresholds
+func parseKubernetes(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, error) {
+	found, err := formats.ParseKubernetesYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	parsed := &metrics.ParsedMetrics{
+		Metrics: found,
+		Help:    make(map[string]string),
+		Types:   make(map[string]string),
+	}
+	// Monitor labels are attached to up, which Prometheus records as a gauge
+	for _, m := range found {
+		if m.Name == "up" {
+			parsed.Types["up"] = "gauge"
+		}
+	}
+	parsed.ReanalyzeWithOptions(profile.CardinalityOptions())
+	return parsed, nil
+}
@@ -0,0 +1,74 @@
+// ABOUTME: Git integration for the CLI - finds changed files and installs the pre-commit hook
+// ABOUTME: Shells out to git so it works with whatever repository layout the user has
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var errNotGitRepo = errors.New("not inside a git repository")
+
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func gitTopLevel() (string, error) {
+	top, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", errNotGitRepo
+	}
+	return top, nil
+}
+
+// changedFiles lists added, copied and modified files relative to base (or the
+// index when staged), as absolute paths
+func changedFiles(base string, staged bool) ([]string, error) {
+	top, err := gitTopLevel()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff", "--name-only", "--diff-filter=ACM"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, base)
+
+	out, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+
+	var files []string
+	for _, name := range strings.Split(out, "\n") {
+		files = append(files, filepath.Join(top, name))
+	}
+	return files, nil
+}
+
+func gitHooksDir() (string, error) {
+	if _, err := gitTopLevel(); err != nil {
+		return "", err
+	}
+	dir, err := gitOutput("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(dir)
+}
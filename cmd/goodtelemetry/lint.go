@@ -0,0 +1,272 @@
+// ABOUTME: lint subcommand - runs the static rule engine over metric files without calling an LLM
+// ABOUTME: Can limit itself to files changed in git so it is fast enough for a pre-commit hook
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/formats"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+const defaultLintGlobs = "*.prom"
+
+// analyzers maps a file extension to the function that extracts findings from it
+var analyzers = map[string]func(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, []rules.Finding, error){
+	".prom": lintExposition,
+	".txt":  lintExposition,
+	".go":   lintGoSource,
+}
+
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	changed := fs.Bool("changed", false, "only lint files changed in git (relative to --base)")
+	staged := fs.Bool("staged", false, "with --changed, only lint staged changes")
+	base := fs.String("base", "HEAD", "git revision to diff against with --changed")
+	globs := fs.String("glob", defaultLintGlobs, "comma-separated file globs to lint with --changed")
+	mode := fs.String("mode", rules.DefaultProfile, "naming convention to check: "+strings.Join(rules.ProfileNames(), ", "))
+	source := fs.String("source", "", "set to "+rules.TextfileSource+" to check files for node_exporter's textfile collector")
+	summaryFile := fs.String("summary-file", "", "also write a JSON summary of the results to this path")
+	lexicon := lexiconFlags(fs)
+	allowlist := allowlistFlag(fs)
+	push := pushFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry lint [--mode MODE] [--source textfile] [--vague-words WORDS] [--forbidden-words WORDS] [--allowlist FILE] [--summary-file PATH] [--push-gateway URL [--push-job NAME] [--push-label KEY=VALUE]... [--push-required]] [--changed [--staged] [--base REV] [--glob GLOBS]] [FILE...]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	profile, err := rules.Profile(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	profile = profile.WithLexicon(lexicon())
+	if profile.AllowedLabels, err = allowlist(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading allowlist: %v\n", err)
+		return exitUsage
+	}
+	if !rules.ValidSource(*source) {
+		fmt.Fprintf(os.Stderr, "error: unknown source %q (only %s is supported)\n", *source, rules.TextfileSource)
+		return exitUsage
+	}
+	if *push.url != "" && *push.job == "" {
+		fmt.Fprintln(os.Stderr, "error: --push-job must not be empty")
+		return exitUsage
+	}
+
+	summary := newSummary("lint", map[string]any{"mode": *mode, "source": *source, "lexicon": lexicon()})
+	finish := func(code int) int {
+		if err := summary.write(*summaryFile, code); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing summary: %v\n", err)
+			return exitInternal
+		}
+		if !push.push(summary, code) {
+			return exitInternal
+		}
+		return code
+	}
+
+	files := fs.Args()
+	if *changed {
+		all, err := changedFiles(*base, *staged)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return finish(exitInternal)
+		}
+		files = append(files, filterGlobs(all, strings.Split(*globs, ","))...)
+		if len(files) == 0 {
+			return finish(exitClean)
+		}
+	} else if len(files) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	code := exitClean
+	for _, file := range files {
+		file = displayPath(file)
+		parsed, findings, err := lintFile(file, profile, *source)
+		summary.addFile(file, parsed, findings, "", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", file, err)
+			// A file that can't be read is worse than one that doesn't parse
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				code = exitInternal
+			} else if code == exitClean {
+				code = exitFindings
+			}
+			continue
+		}
+
+		for _, f := range rules.Problems(findings) {
+			fmt.Printf("%s: %s %s: %s\n", file, f.Severity, f.Code, f.Message)
+			if (f.Severity == rules.SeverityError || f.Severity == rules.SeverityWarning) && code == exitClean {
+				code = exitFindings
+			}
+		}
+	}
+	return finish(code)
+}
+
+// lexiconFlags adds the word list flags, returning the lexicon they describe
+func lexiconFlags(fs *flag.FlagSet) func() rules.Lexicon {
+	vague := fs.String("vague-words", "", "comma-separated words flagged as vague in metric names, replacing the built-in list")
+	forbidden := fs.String("forbidden-words", "", "comma-separated words that are errors in metric and label names")
+	return func() rules.Lexicon {
+		var l rules.Lexicon
+		if *vague != "" {
+			l.Vague = splitList(*vague)
+		}
+		l.Forbidden = splitList(*forbidden)
+		return l
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func lintFile(path string, profile rules.NamingProfile, source string) (*metrics.ParsedMetrics, []rules.Finding, error) {
+	analyze, ok := analyzers[filepath.Ext(path)]
+	if !ok {
+		// Files selected explicitly or by glob default to exposition text
+		analyze = lintExposition
+	}
+	if source == rules.TextfileSource {
+		// Whatever the name, e.g. backup.prom.tmp before the cron job renames it
+		analyze = lintTextfile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return analyze(string(data), profile)
+}
+
+func lintExposition(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, []rules.Finding, error) {
+	parsed, err := profile.Parse(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parsed, profile.Check(parsed), nil
+}
+
+// lintTextfile adds the textfile collector rules to the exposition checks
+func lintTextfile(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, []rules.Finding, error) {
+	parsed, err := profile.Parse(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	findings := profile.Check(parsed)
+	findings = append(findings, rules.CheckTextfile(parsed)...)
+	return parsed, append(findings, rules.CheckTextfileWrite(content)...), nil
+}
+
+func lintGoSource(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, []rules.Finding, error) {
+	parsed, err := parseGoSource(content, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parsed, profile.Check(parsed), nil
+}
+
+// parseGoSource reads client_golang definitions and judges their cardinality
+// against the profile's thresholds
+func parseGoSource(content string, profile rules.NamingProfile) (*metrics.ParsedMetrics, error) {
+	parsed, err := formats.ParseGoSourceMetrics(content)
+	if err != nil {
+		return nil, err
+	}
+	parsed.ReanalyzeWithOptions(profile.CardinalityOptions())
+	return parsed, nil
+}
+
+// displayPath shortens absolute paths from git to be relative to the working directory
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// filterGlobs keeps files whose base name or path matches any glob
+func filterGlobs(files, globs []string) []string {
+	var matched []string
+	for _, file := range files {
+		for _, glob := range globs {
+			glob = strings.TrimSpace(glob)
+			if glob == "" {
+				continue
+			}
+			if ok, _ := filepath.Match(glob, filepath.Base(file)); ok {
+				matched = append(matched, file)
+				break
+			}
+			if ok, _ := filepath.Match(glob, file); ok {
+				matched = append(matched, file)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func runInstallHook(args []string) int {
+	fs := flag.NewFlagSet("install-hook", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite an existing pre-commit hook")
+	globs := fs.String("glob", defaultLintGlobs, "comma-separated file globs the hook lints")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry install-hook [--force] [--glob GLOBS]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dir, err := gitHooksDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	path := filepath.Join(dir, "pre-commit")
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "error: %s already exists (use --force to overwrite)\n", path)
+		return 1
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\n# Installed by goodtelemetry install-hook\nexec goodtelemetry lint --changed --staged --glob '%s'\n", *globs)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Installed pre-commit hook at %s\n", path)
+	return 0
+}
@@ -0,0 +1,159 @@
+// ABOUTME: Tests for lint --changed and install-hook against throwaway git repositories
+// ABOUTME: No changed files is a quiet success, while running outside a repository is a clear error
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureOutput runs fn with stdout and stderr redirected, returning what it wrote to each
+func captureOutput(t *testing.T, fn func()) (stdout, stderr string) {
+	t.Helper()
+	read := func(target **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *target
+		*target = w
+		out := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			out <- string(data)
+		}()
+		return func() string {
+			w.Close()
+			*target = orig
+			return <-out
+		}
+	}
+	restoreStdout := read(&os.Stdout)
+	restoreStderr := read(&os.Stderr)
+	fn()
+	return restoreStdout(), restoreStderr()
+}
+
+// gitRepo makes an empty repository with one commit and changes into it
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	git(t, "init", "-q")
+	writeFile(t, dir, "clean.prom", cleanExposition)
+	git(t, "add", ".")
+	git(t, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-qm", "initial")
+	return dir
+}
+
+func git(t *testing.T, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+// outsideGitRepo changes into a directory git won't find a repository above
+func outsideGitRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	t.Chdir(dir)
+}
+
+func TestLintChangedWithNoChangesIsQuiet(t *testing.T) {
+	dir := gitRepo(t)
+	// Neither an unmatched change nor an untracked file counts
+	writeFile(t, dir, "notes.md", "notes")
+	git(t, "add", "notes.md")
+	writeFile(t, dir, "untracked.prom", badExposition)
+
+	for _, args := range [][]string{{"--changed"}, {"--changed", "--staged"}} {
+		var code int
+		stdout, stderr := captureOutput(t, func() { code = runLint(args) })
+		if code != exitClean || stdout != "" || stderr != "" {
+			t.Errorf("lint %v = %d, stdout %q, stderr %q, want a quiet %d", args, code, stdout, stderr, exitClean)
+		}
+	}
+}
+
+func TestLintChangedLintsOnlyChangedFiles(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "bad.prom", badExposition)
+	git(t, "add", "bad.prom")
+
+	var code int
+	stdout, _ := captureOutput(t, func() { code = runLint([]string{"--changed", "--staged"}) })
+	if code != exitFindings {
+		t.Errorf("exit code = %d, want %d", code, exitFindings)
+	}
+	if !strings.Contains(stdout, "bad.prom:") || strings.Contains(stdout, "clean.prom") {
+		t.Errorf("stdout = %q, want findings for bad.prom only", stdout)
+	}
+
+	// An unstaged edit isn't part of --staged, but is part of the diff against HEAD
+	writeFile(t, dir, "clean.prom", badExposition)
+	stdout, _ = captureOutput(t, func() { code = runLint([]string{"--changed"}) })
+	if code != exitFindings || !strings.Contains(stdout, "clean.prom:") {
+		t.Errorf("lint --changed = %d, stdout %q, want findings for the edited clean.prom", code, stdout)
+	}
+}
+
+func TestLintChangedOutsideGitRepo(t *testing.T) {
+	outsideGitRepo(t)
+	var code int
+	stdout, stderr := captureOutput(t, func() { code = runLint([]string{"--changed"}) })
+	if code != exitInternal || stdout != "" {
+		t.Errorf("exit code = %d, stdout %q, want %d and no output", code, stdout, exitInternal)
+	}
+	if stderr != "error: not inside a git repository\n" {
+		t.Errorf("stderr = %q, want the not-a-repository error", stderr)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	dir := gitRepo(t)
+	var code int
+	captureOutput(t, func() { code = runInstallHook([]string{"--glob", "*.prom,*.go"}) })
+	if code != 0 {
+		t.Fatalf("install-hook = %d", code)
+	}
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	script, err := os.ReadFile(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(script), "exec goodtelemetry lint --changed --staged --glob '*.prom,*.go'") {
+		t.Errorf("hook = %q", script)
+	}
+
+	// An existing hook is kept unless --force
+	_, stderr := captureOutput(t, func() { code = runInstallHook(nil) })
+	if code != 1 || !strings.Contains(stderr, "already exists") {
+		t.Errorf("second install-hook = %d, stderr %q", code, stderr)
+	}
+	captureOutput(t, func() { code = runInstallHook([]string{"--force"}) })
+	if code != 0 {
+		t.Errorf("install-hook --force = %d", code)
+	}
+}
+
+func TestInstallHookOutsideGitRepo(t *testing.T) {
+	outsideGitRepo(t)
+	var code int
+	_, stderr := captureOutput(t, func() { code = runInstallHook(nil) })
+	if code != 1 || stderr != "error: not inside a git repository\n" {
+		t.Errorf("install-hook = %d, stderr %q, want 1 and the not-a-repository error", code, stderr)
+	}
+}
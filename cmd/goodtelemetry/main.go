@@ -0,0 +1,63 @@
+// ABOUTME: goodtelemetry command-line tool - dispatches to subcommands
+// ABOUTME: Runs the web server or offline utilities for working with metrics
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"convert", "Convert StatsD, DogStatsD, InfluxDB or OpenMetrics input to Prometheus text format", runConvert},
+	{"serve", "Run the web UI and evaluation server", runServe},
+	{"doctor", "Check that the environment is configured correctly", runDoctor},
+	{"eval", "Evaluate a metrics file or Go source with the static checks and the LLM", runEval},
+	{"lint", "Run static checks on metric files, optionally only those changed in git", runLint},
+	{"watch", "Lint metric files and Go sources again whenever they change, showing what changed", runWatch},
+	{"report", "Report metric naming consistency across the Go source in a directory", runReport},
+	{"annotate", "Evaluate ServiceMonitor and PodMonitor labels and record the results as annotations", runAnnotate},
+	{"allowlist", "Generate a label allowlist from the labels in existing metric files", runAllowlist},
+	{"validate-schema", "Check a label schema file, optionally against a sample", runValidateSchema},
+	{"install-hook", "Install a git pre-commit hook that lints changed metric files", runInstallHook},
+}
+
+// Commands that work but are left out of the usage text
+var hiddenCommands = []command{
+	{"bench", "Time the static pipeline on a synthetic scrape", runBench},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, cmd := range append(commands, hiddenCommands...) {
+		if cmd.name == name {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+
+	if name != "help" && name != "-h" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: goodtelemetry <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", cmd.name, cmd.summary)
+	}
+}
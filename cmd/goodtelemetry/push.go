@@ -0,0 +1,189 @@
+// ABOUTME: Pushgateway export of lint results - a few summary series per run for charting telemetry quality
+// ABOUTME: Builds them with the exposition writer and PUTs them under a grouping key of job and extra labels
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+const pushTimeout = 10 * time.Second
+
+var pushLabelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// pushLabels collects repeated --push-label key=value flags
+type pushLabels map[string]string
+
+func (l pushLabels) String() string {
+	pairs := make([]string, 0, len(l))
+	for name, value := range l {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l pushLabels) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, "=")
+	if !ok || !pushLabelNameRegex.MatchString(name) || name == "job" {
+		return fmt.Errorf("expected key=value with a valid label name other than job, got %q", pair)
+	}
+	l[name] = value
+	return nil
+}
+
+// pushOptions are the Pushgateway flags
+type pushOptions struct {
+	url      *string
+	job      *string
+	labels   pushLabels
+	required *bool
+}
+
+func pushFlags(fs *flag.FlagSet) *pushOptions {
+	opts := &pushOptions{labels: pushLabels{}}
+	opts.url = fs.String("push-gateway", "", "Pushgateway URL to push summary series to (auth from PUSHGATEWAY_USERNAME/PUSHGATEWAY_PASSWORD or PUSHGATEWAY_TOKEN)")
+	opts.job = fs.String("push-job", "goodtelemetry_lint", "job label of the pushed series")
+	fs.Var(opts.labels, "push-label", "extra grouping label as key=value, e.g. repo=payments (repeatable)")
+	opts.required = fs.Bool("push-required", false, "exit 3 when the push fails instead of only warning")
+	return opts
+}
+
+// push sends the summary series when --push-gateway is set, reporting false
+// only when the push failed and was required
+func (o *pushOptions) push(s *Summary, exitCode int) bool {
+	if *o.url == "" {
+		return true
+	}
+	err := pushSummary(*o.url, *o.job, o.labels, s, exitCode)
+	if err == nil {
+		return true
+	}
+	if *o.required {
+		fmt.Fprintf(os.Stderr, "error: pushing to %s: %v\n", *o.url, err)
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "warning: pushing to %s: %v\n", *o.url, err)
+	return true
+}
+
+// summarySeries renders the summary as exposition text
+func summarySeries(s *Summary, exitCode int, now time.Time) (string, error) {
+	var ms []metrics.Metric
+	sample := func(name string, labels map[string]string, value float64) {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		ms = append(ms, metrics.Metric{Name: name, Labels: labels, Value: strconv.FormatFloat(value, 'f', -1, 64)})
+	}
+
+	for _, severity := range []rules.Severity{rules.SeverityError, rules.SeverityWarning, rules.SeverityInfo} {
+		sample("goodtelemetry_lint_findings", map[string]string{"severity": string(severity)}, float64(s.Counts[severity]))
+	}
+	sample("goodtelemetry_lint_files", nil, float64(len(s.Files)))
+	sample("goodtelemetry_lint_estimated_series", nil, float64(s.EstimatedSeries))
+	overBudget := 0.0
+	if s.BudgetStatus == budgetOver {
+		overBudget = 1
+	}
+	sample("goodtelemetry_lint_over_budget", nil, overBudget)
+	sample("goodtelemetry_lint_exit_code", nil, float64(exitCode))
+	sample("goodtelemetry_lint_last_run_timestamp_seconds", nil, float64(now.Unix()))
+
+	types := map[string]string{
+		"goodtelemetry_lint_findings":                   "gauge",
+		"goodtelemetry_lint_files":                      "gauge",
+		"goodtelemetry_lint_estimated_series":           "gauge",
+		"goodtelemetry_lint_over_budget":                "gauge",
+		"goodtelemetry_lint_exit_code":                  "gauge",
+		"goodtelemetry_lint_last_run_timestamp_seconds": "gauge",
+	}
+	help := map[string]string{
+		"goodtelemetry_lint_findings":                   "Findings of the last lint run by severity.",
+		"goodtelemetry_lint_files":                      "Files checked by the last lint run.",
+		"goodtelemetry_lint_estimated_series":           "Series the linted metrics are estimated to create.",
+		"goodtelemetry_lint_over_budget":                "1 when a linted file's cardinality is High or worse.",
+		"goodtelemetry_lint_exit_code":                  "Exit code of the last lint run.",
+		"goodtelemetry_lint_last_run_timestamp_seconds": "When the last lint run finished.",
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf, ms, types, help); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func pushSummary(gateway, job string, labels pushLabels, s *Summary, exitCode int) error {
+	body, err := summarySeries(s, exitCode, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// PUT replaces every series in the group, so series from a previous
+	// run that this one no longer reports don't linger
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(gateway, "/")+groupingPath(job, labels), strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	if token := os.Getenv("PUSHGATEWAY_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user := os.Getenv("PUSHGATEWAY_USERNAME"); user != "" {
+		req.SetBasicAuth(user, os.Getenv("PUSHGATEWAY_PASSWORD"))
+	}
+
+	resp, err := (&http.Client{Timeout: pushTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// groupingPath builds /metrics/job/<job>/<name>/<value>..., with labels in
+// name order and values the Pushgateway can't take in a path base64 encoded
+func groupingPath(job string, labels pushLabels) string {
+	var sb strings.Builder
+	sb.WriteString("/metrics")
+	writePair := func(name, value string) {
+		if value == "" || strings.Contains(value, "/") {
+			sb.WriteString("/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value)))
+			if value == "" {
+				// An empty value needs a non-empty path segment
+				sb.WriteString("=")
+			}
+			return
+		}
+		sb.WriteString("/" + name + "/" + url.PathEscape(value))
+	}
+
+	writePair("job", job)
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writePair(name, labels[name])
+	}
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+// ABOUTME: Integration test for lint --push-gateway against an httptest Pushgateway
+// ABOUTME: Pins the exposition body and grouping path, auth from the environment, and soft or required failures
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pushgateway records the pushes it receives and answers with status
+type pushgateway struct {
+	*httptest.Server
+	mu     sync.Mutex
+	pushes []push
+}
+
+type push struct {
+	method, path, auth, contentType, body string
+}
+
+func newPushgateway(t *testing.T, status int) *pushgateway {
+	t.Helper()
+	g := &pushgateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		g.mu.Lock()
+		g.pushes = append(g.pushes, push{r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("Content-Type"), string(body)})
+		g.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *pushgateway) received(t *testing.T) push {
+	t.Helper()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.pushes) != 1 {
+		t.Fatalf("pushgateway received %d pushes, want 1", len(g.pushes))
+	}
+	return g.pushes[0]
+}
+
+func TestLintPushesSummarySeries(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeFile(t, dir, "bad.prom", badExposition)
+	gateway := newPushgateway(t, http.StatusOK)
+	t.Setenv("PUSHGATEWAY_TOKEN", "push-token")
+
+	var code int
+	captureOutput(t, func() {
+		code = runLint([]string{"--push-gateway", gateway.URL + "/", "--push-job", "ci lint",
+			"--push-label", "repo=payments", "--push-label", "branch=feature/x", "--push-label", "env=", bad})
+	})
+	if code != exitFindings {
+		t.Errorf("exit code = %d, want %d", code, exitFindings)
+	}
+
+	got := gateway.received(t)
+	if got.method != http.MethodPut || got.auth != "Bearer push-token" || got.contentType != "text/plain; version=0.0.4" {
+		t.Errorf("push = %s with Authorization %q and Content-Type %q", got.method, got.auth, got.contentType)
+	}
+	if want := "/metrics/job/ci%20lint/branch@base64/ZmVhdHVyZS94/env@base64/=/repo/payments"; got.path != want {
+		t.Errorf("grouping path = %s, want %s", got.path, want)
+	}
+
+	body := regexp.MustCompile(`(goodtelemetry_lint_last_run_timestamp_seconds) \d+`).ReplaceAllString(got.body, "$1 NOW")
+	for _, want := range []string{
+		"# TYPE goodtelemetry_lint_findings gauge\n",
+		`goodtelemetry_lint_findings{severity="error"} `,
+		`goodtelemetry_lint_findings{severity="warning"} `,
+		"goodtelemetry_lint_files 1\n",
+		"goodtelemetry_lint_exit_code 1\n",
+		"goodtelemetry_lint_over_budget 0\n",
+		"goodtelemetry_lint_last_run_timestamp_seconds NOW\n",
+		"# HELP goodtelemetry_lint_exit_code Exit code of the last lint run.\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestLintPushBasicAuth(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "clean.prom", cleanExposition)
+	gateway := newPushgateway(t, http.StatusOK)
+	t.Setenv("PUSHGATEWAY_TOKEN", "")
+	t.Setenv("PUSHGATEWAY_USERNAME", "ci")
+	t.Setenv("PUSHGATEWAY_PASSWORD", "secret")
+
+	captureOutput(t, func() { runLint([]string{"--push-gateway", gateway.URL, file}) })
+	got := gateway.received(t)
+	if got.auth != "Basic Y2k6c2VjcmV0" {
+		t.Errorf("Authorization = %q, want basic auth for ci:secret", got.auth)
+	}
+	if got.path != "/metrics/job/goodtelemetry_lint" {
+		t.Errorf("grouping path = %s, want the default job alone", got.path)
+	}
+}
+
+func TestLintPushFailures(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "clean.prom", cleanExposition)
+	gateway := newPushgateway(t, http.StatusInternalServerError)
+
+	var code int
+	_, stderr := captureOutput(t, func() { code = runLint([]string{"--push-gateway", gateway.URL, file}) })
+	if code != exitClean || !strings.HasPrefix(stderr, "warning: pushing to ") || !strings.Contains(stderr, "500") {
+		t.Errorf("failed push = exit %d, stderr %q, want a warning and the clean exit code", code, stderr)
+	}
+
+	_, stderr = captureOutput(t, func() { code = runLint([]string{"--push-gateway", gateway.URL, "--push-required", file}) })
+	if code != exitInternal || !strings.HasPrefix(stderr, "error: pushing to ") {
+		t.Errorf("failed required push = exit %d, stderr %q, want %d and an error", code, stderr, exitInternal)
+	}
+
+	for _, label := range []string{"job=other", "no-equals", "1bad=x"} {
+		captureOutput(t, func() { code = runLint([]string{"--push-gateway", gateway.URL, "--push-label", label, file}) })
+		if code != exitUsage {
+			t.Errorf("--push-label %s = exit %d, want %d", label, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// ABOUTME: report subcommand - a naming consistency report over every metric a Go codebase registers
+// ABOUTME: Groups metrics by prefix and base name, flags label and namespace spellings that disagree, as JSON or an HTML table
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/formats"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// consistencyReport is the report's JSON form
+type consistencyReport struct {
+	Dir     string         `json:"dir"`
+	Metrics []reportMetric `json:"metrics"`
+	Issues  []reportIssue  `json:"issues"`
+}
+
+// reportMetric is one metric family registered in Go source
+type reportMetric struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Prefix   string   `json:"prefix"`
+	BaseName string   `json:"base_name"`
+	Labels   []string `json:"labels"`
+	File     string   `json:"file"`
+}
+
+type reportIssue struct {
+	// The prefix or metric the issue is about
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "directory searched recursively for Go source")
+	format := flags.String("format", "json", "output format: json or html")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry report [--dir DIR] [--format json|html]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *format != "json" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "error: unknown format %q (choose json or html)\n", *format)
+		return exitUsage
+	}
+
+	report, err := buildReport(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+
+	if *format == "html" {
+		err = reportHTML.Execute(os.Stdout, report)
+	} else {
+		err = writeReportJSON(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+	if len(report.Issues) > 0 {
+		return exitFindings
+	}
+	return exitClean
+}
+
+func writeReportJSON(w io.Writer, report *consistencyReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// buildReport reads every Go file under dir, skipping tests, vendor, testdata
+// and hidden directories. Files that don't parse are reported and skipped.
+func buildReport(dir string) (*consistencyReport, error) {
+	report := &consistencyReport{Dir: dir, Metrics: []reportMetric{}, Issues: []reportIssue{}}
+	combined := &metrics.ParsedMetrics{Types: make(map[string]string), Help: make(map[string]string)}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		parsed, err := formats.ParseGoSourceMetrics(string(data))
+		if errors.Is(err, formats.ErrNoGoDefinitions) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			return nil
+		}
+
+		report.Metrics = append(report.Metrics, reportMetrics(parsed, path)...)
+		combined.Metrics = append(combined.Metrics, parsed.Metrics...)
+		maps.Copy(combined.Types, parsed.Types)
+		maps.Copy(combined.Help, parsed.Help)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(report.Metrics, func(a, b reportMetric) int {
+		return strings.Compare(a.Name+"\x00"+a.File, b.Name+"\x00"+b.File)
+	})
+	report.Issues = append(report.Issues, labelSpellingIssues(report.Metrics)...)
+	report.Issues = append(report.Issues, redefinitionIssues(report.Metrics)...)
+	for _, f := range rules.Check(combined) {
+		if f.Code == "namespace-inconsistent" {
+			report.Issues = append(report.Issues, reportIssue{Subject: "namespaces", Message: f.Message})
+		}
+	}
+	return report, nil
+}
+
+// reportMetrics lists a file's metric families with the labels each is defined with
+func reportMetrics(parsed *metrics.ParsedMetrics, file string) []reportMetric {
+	labels := make(map[string]map[string]bool)
+	for _, m := range parsed.Metrics {
+		family, _, ok := parsed.DeclaredFamily(m.Name)
+		if !ok {
+			continue
+		}
+		if labels[family] == nil {
+			labels[family] = make(map[string]bool)
+		}
+		for l := range m.Labels {
+			// Added by the histogram itself
+			if l != "le" {
+				labels[family][l] = true
+			}
+		}
+	}
+
+	var found []reportMetric
+	for _, name := range slices.Sorted(maps.Keys(parsed.Types)) {
+		parts := rules.Decompose(strings.TrimSuffix(name, "_total"))
+		found = append(found, reportMetric{
+			Name:     name,
+			Type:     parsed.Types[name],
+			Prefix:   parts.Namespace,
+			BaseName: parts.Name,
+			Labels:   slices.Sorted(maps.Keys(labels[name])),
+			File:     file,
+		})
+	}
+	return found
+}
+
+// labelSpellingIssues flags metrics under one prefix that name the same
+// dimension differently, such as method and http_method under http_. Label
+// names match once the prefix and underscores are removed.
+func labelSpellingIssues(ms []reportMetric) []reportIssue {
+	// prefix -> dimension -> label spelling -> metrics using it
+	byPrefix := make(map[string]map[string]map[string]int)
+	total := make(map[string]int)
+	for _, m := range ms {
+		if m.Prefix == "" {
+			continue
+		}
+		total[m.Prefix]++
+		if byPrefix[m.Prefix] == nil {
+			byPrefix[m.Prefix] = make(map[string]map[string]int)
+		}
+		for _, l := range m.Labels {
+			dimension := strings.ReplaceAll(strings.TrimPrefix(l, m.Prefix+"_"), "_", "")
+			if byPrefix[m.Prefix][dimension] == nil {
+				byPrefix[m.Prefix][dimension] = make(map[string]int)
+			}
+			byPrefix[m.Prefix][dimension][l]++
+		}
+	}
+
+	var issues []reportIssue
+	for _, prefix := range slices.Sorted(maps.Keys(byPrefix)) {
+		for _, dimension := range slices.Sorted(maps.Keys(byPrefix[prefix])) {
+			spellings := byPrefix[prefix][dimension]
+			if len(spellings) < 2 {
+				continue
+			}
+			// Most used spelling first
+			labels := slices.Sorted(maps.Keys(spellings))
+			slices.SortStableFunc(labels, func(a, b string) int { return spellings[b] - spellings[a] })
+			var uses []string
+			for i, l := range labels {
+				verb := "use"
+				if spellings[l] == 1 {
+					verb = "uses"
+				}
+				use := fmt.Sprintf("%d %s '%s'", spellings[l], verb, l)
+				if i == 0 {
+					use += " label"
+				}
+				uses = append(uses, use)
+			}
+			issues = append(issues, reportIssue{
+				Subject: prefix + "_",
+				Message: fmt.Sprintf("Found %d metrics with '%s_' prefix but %s - inconsistent",
+					total[prefix], prefix, joinAnd(uses)),
+			})
+		}
+	}
+	return issues
+}
+
+// redefinitionIssues flags a metric defined in several files with different
+// types or labels, which makes queries across them disagree
+func redefinitionIssues(ms []reportMetric) []reportIssue {
+	byName := make(map[string][]reportMetric)
+	for _, m := range ms {
+		byName[m.Name] = append(byName[m.Name], m)
+	}
+
+	var issues []reportIssue
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		defs := byName[name]
+		first := defs[0]
+		for _, d := range defs[1:] {
+			if d.Type != first.Type || !slices.Equal(d.Labels, first.Labels) {
+				issues = append(issues, reportIssue{
+					Subject: name,
+					Message: fmt.Sprintf("%s is a %s with labels (%s) in %s but a %s with labels (%s) in %s",
+						name, first.Type, strings.Join(first.Labels, ", "), first.File, d.Type, strings.Join(d.Labels, ", "), d.File),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// joinAnd joins items as "a, b and c"
+func joinAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Metric naming consistency: {{ .Dir }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 6px 10px; border-bottom: 1px solid #ddd; text-align: left; }
+th { cursor: pointer; }
+td { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>Metric naming consistency: {{ .Dir }}</h1>
+<h2>Issues</h2>
+{{ if .Issues }}<ul>{{ range .Issues }}
+<li><code>{{ .Subject }}</code>: {{ .Message }}</li>{{ end }}
+</ul>{{ else }}<p>No inconsistencies found.</p>{{ end }}
+<h2>Metrics ({{ len .Metrics }})</h2>
+<table id="metrics">
+<thead><tr><th>Metric</th><th>Type</th><th>Prefix</th><th>Base name</th><th>Labels</th><th>File</th></tr></thead>
+<tbody>{{ range .Metrics }}
+<tr><td>{{ .Name }}</td><td>{{ .Type }}</td><td>{{ .Prefix }}</td><td>{{ .BaseName }}</td><td>{{ join .Labels ", " }}</td><td>{{ .File }}</td></tr>{{ end }}
+</tbody>
+</table>
+<script>
+// Click a column heading to sort by it; click again to reverse
+document.querySelectorAll('#metrics th').forEach((th, col) => {
+    th.addEventListener('click', () => {
+        const body = document.querySelector('#metrics tbody');
+        const asc = th.dataset.order !== 'asc';
+        th.dataset.order = asc ? 'asc' : 'desc';
+        const rows = Array.from(body.rows);
+        rows.sort((a, b) => a.cells[col].textContent.localeCompare(b.cells[col].textContent) * (asc ? 1 : -1));
+        rows.forEach(r => body.appendChild(r));
+    });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,141 @@
+// ABOUTME: Tests for the report subcommand over a throwaway Go tree - label spellings and redefinitions that disagree
+// ABOUTME: Tests, vendor, testdata and hidden directories are skipped, and the HTML form lists every metric
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func goMetric(constructor, name, labels string) string {
+	return `	_ = promauto.` + constructor + `(prometheus.CounterOpts{Name: "` + name + `", Help: "Documented."}, []string{` + labels + `})
+`
+}
+
+// writeReportTree lays out a Go module whose metrics the report should find,
+// and some it should skip
+func writeReportTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	file := func(path string, defs ...string) {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		src := "package x\n\nfunc init() {\n" + strings.Join(defs, "") + "}\n"
+		if err := os.WriteFile(full, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file("server/handlers.go",
+		goMetric("NewCounterVec", "http_requests_total", `"method", "code"`),
+		goMetric("NewHistogramVec", "http_request_duration_seconds", `"method"`))
+	file("client/client.go", goMetric("NewCounterVec", "http_client_requests_total", `"http_method"`))
+	file("queue/a.go", goMetric("NewGaugeVec", "queue_depth", `"queue"`))
+	file("queue/b.go", goMetric("NewCounterVec", "queue_depth", `"queue", "shard"`))
+
+	for _, skipped := range []string{"server/handlers_test.go", "vendor/lib/lib.go", "testdata/fixture.go", ".cache/gen.go"} {
+		file(skipped, goMetric("NewCounterVec", "skipped_total", `"user_id"`))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.go"), []byte("package x\nfunc {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReportJSON(t *testing.T) {
+	dir := writeReportTree(t)
+
+	var code int
+	stdout, stderr := captureOutput(t, func() { code = runReport([]string{"--dir", dir}) })
+	if code != exitFindings {
+		t.Errorf("exit code = %d, want %d for a report with issues", code, exitFindings)
+	}
+	if !strings.Contains(stderr, "broken.go: error:") {
+		t.Errorf("stderr = %q, want the file that doesn't parse named", stderr)
+	}
+
+	var report consistencyReport
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&report); err != nil {
+		t.Fatalf("decoding %s: %v", stdout, err)
+	}
+
+	var names []string
+	for _, m := range report.Metrics {
+		names = append(names, m.Name)
+		if strings.Contains(m.File, "skipped") || m.Name == "skipped_total" {
+			t.Errorf("reported %s from %s, which should be skipped", m.Name, m.File)
+		}
+	}
+	wantNames := []string{"http_client_requests_total", "http_request_duration_seconds", "http_requests_total", "queue_depth", "queue_depth"}
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("metrics = %q, want %q", names, wantNames)
+	}
+	requests := report.Metrics[2]
+	if requests.Type != "counter" || requests.Prefix != "http" || requests.BaseName != "requests" ||
+		!slices.Equal(requests.Labels, []string{"code", "method"}) || requests.File != filepath.Join(dir, "server", "handlers.go") {
+		t.Errorf("http_requests_total = %+v", requests)
+	}
+	if labels := report.Metrics[1].Labels; !slices.Equal(labels, []string{"method"}) {
+		t.Errorf("histogram labels = %q, want le left out", labels)
+	}
+
+	wantIssues := []reportIssue{
+		{"http_", "Found 3 metrics with 'http_' prefix but 2 use 'method' label and 1 uses 'http_method' - inconsistent"},
+		{"queue_depth", "queue_depth is a gauge with labels (queue) in " + filepath.Join(dir, "queue", "a.go") +
+			" but a counter with labels (queue, shard) in " + filepath.Join(dir, "queue", "b.go")},
+	}
+	for _, want := range wantIssues {
+		if !slices.Contains(report.Issues, want) {
+			t.Errorf("issues = %+v, want %+v", report.Issues, want)
+		}
+	}
+}
+
+func TestReportHTML(t *testing.T) {
+	dir := writeReportTree(t)
+
+	var code int
+	stdout, _ := captureOutput(t, func() { code = runReport([]string{"--dir", dir, "--format", "html"}) })
+	if code != exitFindings {
+		t.Errorf("exit code = %d, want %d", code, exitFindings)
+	}
+	if !strings.HasPrefix(stdout, "<!DOCTYPE html>") || strings.Count(stdout, "<tr><td>") != 5 {
+		t.Errorf("want an HTML page with a row per metric:\n%s", stdout)
+	}
+	for _, want := range []string{
+		"<td>http_requests_total</td><td>counter</td><td>http</td><td>requests</td><td>code, method</td>",
+		"but 2 use &#39;method&#39; label and 1 uses &#39;http_method&#39;",
+		"<th>Metric</th>",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
+
+func TestReportCleanTreeAndBadFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	var code int
+	stdout, _ := captureOutput(t, func() { code = runReport([]string{"--dir", dir}) })
+	if code != exitClean || !strings.Contains(stdout, `"metrics": []`) || !strings.Contains(stdout, `"issues": []`) {
+		t.Errorf("clean tree = %d:\n%s", code, stdout)
+	}
+
+	captureOutput(t, func() { code = runReport([]string{"--format", "csv"}) })
+	if code != exitUsage {
+		t.Errorf("--format csv = %d, want %d", code, exitUsage)
+	}
+	captureOutput(t, func() { code = runReport([]string{"--dir", filepath.Join(dir, "missing")}) })
+	if code != exitInternal {
+		t.Errorf("a missing dir = %d, want %d", code, exitInternal)
+	}
+}
@@ -0,0 +1,45 @@
+// ABOUTME: serve subcommand - runs the Good Telemetry web UI from the goodtelemetry binary
+// ABOUTME: Flags override the same environment variables the standalone web binary reads
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wbollock/good_telemetry/internal/server"
+)
+
+func runServe(args []string) int {
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "port to listen on (env WEB_PORT)")
+	fs.StringVar(&cfg.LLMURL, "llm-url", cfg.LLMURL, "Ollama API endpoint (env LLM_BACKEND_URL)")
+	fs.StringVar(&cfg.Model, "model", cfg.Model, "Ollama model to use (env OLLAMA_MODEL)")
+	fs.StringVar(&cfg.Routing.FastModel, "fast-model", cfg.Routing.FastModel, "model for small submissions, empty disables routing (env OLLAMA_FAST_MODEL)")
+	fs.StringVar(&cfg.Profile, "mode", cfg.Profile, "naming convention to judge metrics by (env NAMING_PROFILE)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry serve [--port PORT] [--llm-url URL] [--model MODEL] [--fast-model MODEL] [--mode MODE]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := server.Run(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,166 @@
+// ABOUTME: Machine-readable contract for tools wrapping the CLI - exit codes and the --summary-file JSON
+// ABOUTME: Both are stable: renaming a field or reusing an exit code is a breaking change and bumps summaryVersion
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// Exit codes of lint and eval
+const (
+	exitClean = 0
+	// A file has an error or warning finding, or couldn't be parsed
+	exitFindings = 1
+	// Bad flags or arguments
+	exitUsage = 2
+	// Reading input, running git or writing the summary failed
+	exitInternal = 3
+	// eval couldn't get an answer from the LLM
+	exitLLMUnavailable = 4
+)
+
+// summaryVersion is bumped whenever a Summary field changes meaning or goes away
+const summaryVersion = 1
+
+// Set with -ldflags "-X main.buildVersion=..."; falls back to the module version
+var buildVersion = ""
+
+// Budget statuses, from the cardinality level of each file
+const (
+	budgetOK   = "ok"
+	budgetOver = "over"
+)
+
+// Summary is what --summary-file writes, whatever the human-readable output
+type Summary struct {
+	Version     int    `json:"version"`
+	ToolVersion string `json:"tool_version"`
+	Command     string `json:"command"`
+	// Hash of the settings that decide the findings, so results from
+	// different runs can be told apart
+	ConfigHash string `json:"config_hash"`
+	ExitCode   int    `json:"exit_code"`
+	// Findings by severity across all files, praise left out
+	Counts map[rules.Severity]int `json:"counts"`
+	// Empty unless the LLM judged the metrics
+	WorstVerdict    string        `json:"worst_verdict,omitempty"`
+	EstimatedSeries int           `json:"estimated_series"`
+	BudgetStatus    string        `json:"budget_status"`
+	Files           []FileSummary `json:"files"`
+}
+
+type FileSummary struct {
+	Path             string                 `json:"path"`
+	Counts           map[rules.Severity]int `json:"counts"`
+	Verdict          string                 `json:"verdict,omitempty"`
+	EstimatedSeries  int                    `json:"estimated_series"`
+	CardinalityLevel string                 `json:"cardinality_level,omitempty"`
+	Findings         []FindingSummary       `json:"findings"`
+	// Set when the file couldn't be read or parsed
+	Error string `json:"error,omitempty"`
+}
+
+type FindingSummary struct {
+	Severity rules.Severity `json:"severity"`
+	Code     string         `json:"code"`
+	Metric   string         `json:"metric,omitempty"`
+	Message  string         `json:"message"`
+}
+
+// Verdicts from best to worst
+var verdictRank = map[string]int{"Good": 1, "Needs Improvement": 2, "Poor": 3}
+
+func newSummary(command string, settings any) *Summary {
+	return &Summary{
+		Version:      summaryVersion,
+		ToolVersion:  toolVersion(),
+		Command:      command,
+		ConfigHash:   configHash(settings),
+		Counts:       emptyCounts(),
+		BudgetStatus: budgetOK,
+		Files:        []FileSummary{},
+	}
+}
+
+// addFile records one file's results; parsed may be nil when it failed to parse
+func (s *Summary) addFile(path string, parsed *metrics.ParsedMetrics, findings []rules.Finding, verdict string, fileErr error) {
+	file := FileSummary{Path: path, Counts: emptyCounts(), Verdict: verdict, Findings: []FindingSummary{}}
+	if fileErr != nil {
+		file.Error = fileErr.Error()
+	}
+
+	for _, f := range rules.Problems(findings) {
+		file.Counts[f.Severity]++
+		s.Counts[f.Severity]++
+		file.Findings = append(file.Findings, FindingSummary{Severity: f.Severity, Code: f.Code, Metric: f.Metric, Message: f.Message})
+	}
+
+	if parsed != nil {
+		for _, m := range parsed.MemoryBreakdown {
+			file.EstimatedSeries += m.EstimatedSeries
+		}
+		if parsed.CardinalityAnalysis != nil {
+			file.CardinalityLevel = parsed.CardinalityAnalysis.CardinalityLevel
+		}
+		if overBudget(file.CardinalityLevel) {
+			s.BudgetStatus = budgetOver
+		}
+	}
+	s.EstimatedSeries += file.EstimatedSeries
+
+	if verdictRank[verdict] > verdictRank[s.WorstVerdict] {
+		s.WorstVerdict = verdict
+	}
+	s.Files = append(s.Files, file)
+}
+
+// write saves the summary to path, doing nothing when path is empty
+func (s *Summary) write(path string, exitCode int) error {
+	if path == "" {
+		return nil
+	}
+	s.ExitCode = exitCode
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// overBudget reports cardinality levels at or past the profile's high threshold,
+// including series that can't be bounded at all
+func overBudget(level string) bool {
+	switch level {
+	case "", "Low", "Medium", "Cannot Estimate (single sample)":
+		return false
+	}
+	return true
+}
+
+func emptyCounts() map[rules.Severity]int {
+	return map[rules.Severity]int{rules.SeverityError: 0, rules.SeverityWarning: 0, rules.SeverityInfo: 0}
+}
+
+func configHash(settings any) string {
+	data, _ := json.Marshal(settings)
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func toolVersion() string {
+	if buildVersion != "" {
+		return buildVersion
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
@@ -0,0 +1,241 @@
+// ABOUTME: Contract test for tools wrapping the CLI - pins the exit codes and the --summary-file format
+// ABOUTME: A failure here means a breaking change: bump summaryVersion and update summaryV1 deliberately
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// summaryV1 is the summary format frozen at version 1. It is a copy rather
+// than Summary itself, so a change to Summary fails the test.
+type summaryV1 struct {
+	Version         int            `json:"version"`
+	ToolVersion     string         `json:"tool_version"`
+	Command         string         `json:"command"`
+	ConfigHash      string         `json:"config_hash"`
+	ExitCode        int            `json:"exit_code"`
+	Counts          map[string]int `json:"counts"`
+	WorstVerdict    string         `json:"worst_verdict"`
+	EstimatedSeries int            `json:"estimated_series"`
+	BudgetStatus    string         `json:"budget_status"`
+	Files           []struct {
+		Path             string         `json:"path"`
+		Counts           map[string]int `json:"counts"`
+		Verdict          string         `json:"verdict"`
+		EstimatedSeries  int            `json:"estimated_series"`
+		CardinalityLevel string         `json:"cardinality_level"`
+		Findings         []struct {
+			Severity string `json:"severity"`
+			Code     string `json:"code"`
+			Metric   string `json:"metric"`
+			Message  string `json:"message"`
+		} `json:"findings"`
+		Error string `json:"error"`
+	} `json:"files"`
+}
+
+const (
+	cleanExposition = `# HELP http_requests_total Total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 1
+`
+	badExposition = `# TYPE requestCount counter
+requestCount 1
+`
+)
+
+func TestExitCodesAreFrozen(t *testing.T) {
+	frozen := map[string][2]int{
+		"clean":           {exitClean, 0},
+		"findings":        {exitFindings, 1},
+		"usage":           {exitUsage, 2},
+		"internal":        {exitInternal, 3},
+		"llm unavailable": {exitLLMUnavailable, 4},
+	}
+	for name, codes := range frozen {
+		if codes[0] != codes[1] {
+			t.Errorf("exit code %s is %d, frozen at %d", name, codes[0], codes[1])
+		}
+	}
+	if summaryVersion != 1 {
+		t.Errorf("summaryVersion is %d; update summaryV1 to match the new format", summaryVersion)
+	}
+}
+
+func TestLintExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	clean := writeFile(t, dir, "clean.prom", cleanExposition)
+	bad := writeFile(t, dir, "bad.prom", badExposition)
+	unparseable := writeFile(t, dir, "unparseable.prom", "this is not { exposition\n")
+	tenant := writeFile(t, dir, "tenant.prom", strings.Replace(cleanExposition, `method="GET"`, `method="GET",tenant_id="a"`, 1))
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"clean file", []string{clean}, exitClean},
+		{"warning findings", []string{bad}, exitFindings},
+		{"unparseable file", []string{unparseable}, exitFindings},
+		{"unknown flag", []string{"--no-such-flag", clean}, exitUsage},
+		{"no files", nil, exitUsage},
+		{"unknown mode", []string{"--mode", "nope", clean}, exitUsage},
+		{"tenant label", []string{tenant}, exitClean},
+		{"tenant label under mimir", []string{"--mode", "mimir", tenant}, exitFindings},
+		{"missing file", []string{filepath.Join(dir, "missing.prom")}, exitInternal},
+		{"missing file beats findings", []string{bad, filepath.Join(dir, "missing.prom")}, exitInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runLint(tt.args); got != tt.want {
+				t.Errorf("runLint(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalExitsLLMUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "clean.prom", cleanExposition)
+	summaryPath := filepath.Join(dir, "summary.json")
+
+	// Nothing listens on port 1, so the LLM call fails at once
+	got := runEval([]string{"--llm-url", "http://127.0.0.1:1", "--summary-file", summaryPath, file})
+	if got != exitLLMUnavailable {
+		t.Fatalf("runEval = %d, want %d", got, exitLLMUnavailable)
+	}
+	summary := readSummary(t, summaryPath)
+	if summary.Command != "eval" || summary.ExitCode != exitLLMUnavailable {
+		t.Errorf("summary command %q exit code %d, want eval and %d", summary.Command, summary.ExitCode, exitLLMUnavailable)
+	}
+	if summary.WorstVerdict != "" {
+		t.Errorf("worst verdict = %q without an LLM answer, want empty", summary.WorstVerdict)
+	}
+}
+
+func TestLintSummaryFile(t *testing.T) {
+	dir := t.TempDir()
+	clean := writeFile(t, dir, "clean.prom", cleanExposition)
+	bad := writeFile(t, dir, "bad.prom", badExposition)
+	summaryPath := filepath.Join(dir, "summary.json")
+
+	if got := runLint([]string{"--summary-file", summaryPath, clean, bad}); got != exitFindings {
+		t.Fatalf("runLint = %d, want %d", got, exitFindings)
+	}
+	summary := readSummary(t, summaryPath)
+
+	if summary.Version != summaryVersion || summary.Command != "lint" || summary.ExitCode != exitFindings {
+		t.Errorf("version %d command %q exit code %d, want %d lint %d", summary.Version, summary.Command, summary.ExitCode, summaryVersion, exitFindings)
+	}
+	if summary.ToolVersion == "" {
+		t.Error("tool_version is empty")
+	}
+	if len(summary.ConfigHash) != len("sha256:")+64 || summary.ConfigHash[:7] != "sha256:" {
+		t.Errorf("config_hash = %q, want sha256:<64 hex digits>", summary.ConfigHash)
+	}
+	for _, severity := range []string{"error", "warning", "info"} {
+		if _, ok := summary.Counts[severity]; !ok {
+			t.Errorf("counts has no %q entry", severity)
+		}
+	}
+	if summary.Counts["warning"] != 2 {
+		t.Errorf("warning count = %d, want 2", summary.Counts["warning"])
+	}
+	if summary.BudgetStatus != budgetOK {
+		t.Errorf("budget status = %q, want %q", summary.BudgetStatus, budgetOK)
+	}
+
+	if len(summary.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(summary.Files))
+	}
+	if f := summary.Files[0]; f.Path != clean || len(f.Findings) != 0 || f.Error != "" {
+		t.Errorf("clean file summary = %+v, want no findings or error", f)
+	}
+	if total := summary.Files[0].EstimatedSeries + summary.Files[1].EstimatedSeries; total == 0 || summary.EstimatedSeries != total {
+		t.Errorf("estimated series = %d, want the files' nonzero total %d", summary.EstimatedSeries, total)
+	}
+	codes := map[string]bool{}
+	for _, f := range summary.Files[1].Findings {
+		codes[f.Code] = true
+		if f.Message == "" || f.Metric != "requestCount" {
+			t.Errorf("finding %+v lacks its message or metric", f)
+		}
+	}
+	if !codes["camel-case"] || !codes["type-mismatch"] {
+		t.Errorf("bad file finding codes = %v, want camel-case and type-mismatch", codes)
+	}
+}
+
+func TestSummaryConfigHashFollowsSettings(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "clean.prom", cleanExposition)
+	hash := func(args ...string) string {
+		path := filepath.Join(dir, "summary.json")
+		runLint(append(append(args, "--summary-file", path), file))
+		return readSummary(t, path).ConfigHash
+	}
+
+	if hash() != hash() {
+		t.Error("config_hash differs between identical runs")
+	}
+	if hash() == hash("--forbidden-words", "temp") {
+		t.Error("config_hash is unchanged by --forbidden-words")
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readSummary decodes a summary file strictly into summaryV1
+func readSummary(t *testing.T, path string) summaryV1 {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s summaryV1
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("summary doesn't match version 1: %v\n%s", err, data)
+	}
+	return s
+}
+
+func TestEvalValidatesLikeTheServer(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, message string
+	}{
+		{"blank.prom", " \n\t\n", api.ErrNoMetrics.Message},
+		{"oversized.prom", strings.Repeat("a", api.MaxMetricsBytes+1), "Metrics are 1048577 bytes; at most 1048576 are accepted"},
+	}
+	for _, tt := range tests {
+		file := writeFile(t, dir, tt.name, tt.content)
+		var got int
+		_, stderr := captureOutput(t, func() {
+			// Refused before the LLM is called, so nothing needs to listen
+			got = runEval([]string{"--llm-url", "http://127.0.0.1:1", file})
+		})
+		if got != exitFindings {
+			t.Errorf("%s: runEval = %d, want %d", tt.name, got, exitFindings)
+		}
+		if want := "error: " + file + ": " + tt.message + "\n"; stderr != want {
+			t.Errorf("%s: stderr = %q, want %q", tt.name, stderr, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// ABOUTME: validate-schema subcommand - lints a label schema file, reporting each mistake with its line
+// ABOUTME: With --test-metric, shows which schemas a sample matches and whether it passes them
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/labelschema"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func runValidateSchema(args []string) int {
+	fs := flag.NewFlagSet("validate-schema", flag.ContinueOnError)
+	testMetric := fs.String("test-metric", "", `sample to check against the schema, e.g. 'http_requests_total{method="GET"} 1'`)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry validate-schema [--test-metric SAMPLE] FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	path := fs.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+	schemas, issues := labelschema.Parse(data)
+	for _, issue := range issues {
+		fmt.Printf("%s:%d: error: %s\n", path, issue.Line, issue.Message)
+	}
+	if len(issues) > 0 {
+		return exitFindings
+	}
+	fmt.Printf("%s: %d schema(s), no issues\n", path, len(schemas))
+
+	if *testMetric == "" {
+		return exitClean
+	}
+	parsed, err := metrics.Parse(*testMetric)
+	if err != nil || len(parsed.Metrics) != 1 {
+		fmt.Fprintf(os.Stderr, "error: --test-metric must be one sample: %v\n", err)
+		return exitUsage
+	}
+	sample := parsed.Metrics[0]
+
+	matched := labelschema.Matching(schemas, sample.Name)
+	if len(matched) == 0 {
+		fmt.Printf("%s matches no schema\n", sample.Name)
+		return exitClean
+	}
+	code := exitClean
+	for _, s := range matched {
+		name := s.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Printf("%s matches schema %s (line %d): %s\n", sample.Name, name, s.Line, strings.Join(s.Rules(), "; "))
+		violations := s.Check(sample)
+		for _, v := range violations {
+			fmt.Printf("  fail: %s\n", v)
+		}
+		if len(violations) == 0 {
+			fmt.Println("  pass")
+		} else {
+			code = exitFindings
+		}
+	}
+	return code
+}
@@ -0,0 +1,37 @@
+// ABOUTME: Tests for the validate-schema subcommand - exit codes for a clean file, a broken file
+// ABOUTME: and a sample that fails the schema given with --test-metric
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSchemaExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.yaml", "schemas:\n  - metrics: [\"http_*\"]\n    required: [method]\n    values:\n      method: \"GET|POST\"\n")
+	broken := writeFile(t, dir, "broken.yaml", "schemas:\n  - metrics: [\"http_*\"]\n    values:\n      method: \"(GET\"\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"valid file", []string{valid}, exitClean},
+		{"invalid pattern", []string{broken}, exitFindings},
+		{"passing sample", []string{"--test-metric", `http_requests_total{method="GET"} 1`, valid}, exitClean},
+		{"failing sample", []string{"--test-metric", `http_requests_total{method="PATCH"} 1`, valid}, exitFindings},
+		{"sample no schema matches", []string{"--test-metric", `up 1`, valid}, exitClean},
+		{"unparseable sample", []string{"--test-metric", `{`, valid}, exitUsage},
+		{"no file", nil, exitUsage},
+		{"missing file", []string{filepath.Join(dir, "missing.yaml")}, exitInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runValidateSchema(tt.args); got != tt.want {
+				t.Errorf("runValidateSchema(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// ABOUTME: watch subcommand - re-lints metric files and Go sources whenever they change on disk
+// ABOUTME: Prints the findings that appeared, went away or changed since each file's last successful lint
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// Editors that save on every keystroke produce a burst of writes per edit
+const defaultWatchDebounce = 500 * time.Millisecond
+
+const (
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+)
+
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	debounce := fs.Duration("debounce", defaultWatchDebounce, "wait this long after the last change before linting again")
+	mode := fs.String("mode", rules.DefaultProfile, "naming convention to check: "+strings.Join(rules.ProfileNames(), ", "))
+	lexicon := lexiconFlags(fs)
+	allowlist := allowlistFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goodtelemetry watch [--debounce DURATION] [--mode MODE] [--vague-words WORDS] [--forbidden-words WORDS] [--allowlist FILE] FILE...")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	profile, err := rules.Profile(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	profile = profile.WithLexicon(lexicon())
+	if profile.AllowedLabels, err = allowlist(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading allowlist: %v\n", err)
+		return exitUsage
+	}
+
+	files, err := expandGlobs(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitInternal
+	}
+	defer watcher.Close()
+
+	// Directories are watched so editors that save by renaming a temp file
+	// over the original are still seen
+	for _, file := range files {
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: watching %s: %v\n", file, err)
+			return exitInternal
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &watchRun{profile: profile, last: make(map[string]map[string][]string), color: colorOutput()}
+	for _, file := range files {
+		w.lint(file)
+	}
+	fmt.Printf("Watching %d files; press Ctrl-C to stop\n", len(files))
+	return w.watch(ctx, watcher, files, *debounce)
+}
+
+// watch re-lints files as watcher reports changes to them, once a burst of
+// changes has been quiet for debounce, until ctx is done
+func (w *watchRun) watch(ctx context.Context, watcher *fsnotify.Watcher, files []string, debounce time.Duration) int {
+	watched := make(map[string]bool)
+	for _, file := range files {
+		watched[file] = true
+	}
+	changed := make(map[string]bool)
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return exitClean
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return exitInternal
+			}
+			if name := filepath.Clean(event.Name); watched[name] && event.Has(fsnotify.Write|fsnotify.Create) {
+				changed[name] = true
+				pending = time.After(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return exitInternal
+			}
+			fmt.Fprintf(os.Stderr, "error: watching: %v\n", err)
+		case <-pending:
+			pending = nil
+			for _, file := range files {
+				if changed[file] {
+					w.lint(file)
+				}
+			}
+			clear(changed)
+		}
+	}
+}
+
+// expandGlobs expands patterns the shell left alone, such as quoted ones, and
+// cleans every path so it matches fsnotify's event names
+func expandGlobs(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file", pattern)
+		}
+		for _, m := range matches {
+			files = append(files, filepath.Clean(m))
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// colorOutput reports whether stdout is a terminal and NO_COLOR isn't set
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// watchRun holds each file's findings from its last successful lint
+type watchRun struct {
+	profile rules.NamingProfile
+	// file -> code and metric -> finding lines
+	last  map[string]map[string][]string
+	color bool
+}
+
+// lint checks file and prints how its findings differ from the last
+// successful lint. Findings with the same code and metric whose messages
+// differ are shown as changed. A file that fails keeps its last findings.
+func (w *watchRun) lint(file string) {
+	stamp := time.Now().Format(time.TimeOnly)
+	_, findings, err := lintFile(file, w.profile, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] %s: error: %v\n", stamp, file, err)
+		return
+	}
+
+	current := make(map[string][]string)
+	for _, f := range rules.Problems(findings) {
+		key := f.Code + "\x00" + f.Metric
+		current[key] = append(current[key], fmt.Sprintf("%s: %s %s: %s", file, f.Severity, f.Code, f.Message))
+	}
+	previous := w.last[file]
+	w.last[file] = current
+
+	var added, removed, modified []string
+	for key, lines := range current {
+		old, ok := previous[key]
+		switch {
+		case !ok:
+			added = append(added, lines...)
+		case !slices.Equal(old, lines):
+			modified = append(modified, lines...)
+		}
+	}
+	for key, lines := range previous {
+		if _, ok := current[key]; !ok {
+			removed = append(removed, lines...)
+		}
+	}
+
+	fmt.Printf("[%s] %s: %d issues (%d new, %d resolved, %d changed)\n", stamp, file,
+		len(rules.Problems(findings)), len(added), len(removed), len(modified))
+	w.print("+", ansiRed, added)
+	w.print("-", ansiGreen, removed)
+	w.print("~", ansiYellow, modified)
+}
+
+func (w *watchRun) print(mark, color string, lines []string) {
+	slices.Sort(lines)
+	for _, line := range lines {
+		if w.color {
+			fmt.Printf("%s%s %s%s\n", color, mark, line, ansiReset)
+		} else {
+			fmt.Printf("%s %s\n", mark, line)
+		}
+	}
+}
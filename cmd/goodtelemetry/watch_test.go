@@ -0,0 +1,143 @@
+// ABOUTME: Tests for the watch subcommand - findings new, resolved or changed since each file's last good lint
+// ABOUTME: A burst of saves is linted once after the debounce, and quoted globs expand to clean, sorted paths
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+func newWatchRun(t *testing.T) *watchRun {
+	t.Helper()
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &watchRun{profile: profile, last: make(map[string]map[string][]string)}
+}
+
+var watchStamp = regexp.MustCompile(`(?m)^\[\d\d:\d\d:\d\d\] `)
+
+func TestWatchDiffsFindings(t *testing.T) {
+	w := newWatchRun(t)
+	file := filepath.Join(t.TempDir(), "metrics.prom")
+	const unbounded = "error high-cardinality-label: Remove "
+
+	steps := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"clean", "# TYPE http_requests_total counter\nhttp_requests_total{method=\"GET\"} 1\n",
+			[]string{"0 issues (0 new, 0 resolved, 0 changed)"}},
+		{"a finding appears", "# TYPE http_requests_total counter\nhttp_requests_total{user_id=\"1\"} 1\n",
+			[]string{"1 issues (1 new, 0 resolved, 0 changed)", "+ " + file + ": " + unbounded + "user_id"}},
+		{"the same finding on another label", "# TYPE http_requests_total counter\nhttp_requests_total{request_id=\"1\"} 1\n",
+			[]string{"1 issues (0 new, 0 resolved, 1 changed)", "~ " + file + ": " + unbounded + "request_id"}},
+		// A file that fails to lint keeps its last findings to compare against
+		{"unparseable", "http_requests_total{request_id=\"1\" 1\n", nil},
+		{"fixed", "# TYPE http_requests_total counter\nhttp_requests_total{method=\"GET\"} 1\n",
+			[]string{"0 issues (0 new, 1 resolved, 0 changed)", "- " + file + ": " + unbounded + "request_id"}},
+	}
+	for _, step := range steps {
+		if err := os.WriteFile(file, []byte(step.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr := captureOutput(t, func() { w.lint(file) })
+		if step.want == nil {
+			if stdout != "" || !strings.Contains(stderr, file+": error:") {
+				t.Errorf("%s: stdout %q, stderr %q, want only an error", step.name, stdout, stderr)
+			}
+			continue
+		}
+		if !watchStamp.MatchString(stdout) {
+			t.Errorf("%s: output %q isn't stamped with the time", step.name, stdout)
+		}
+		lines := strings.Split(strings.TrimSpace(watchStamp.ReplaceAllString(stdout, "")), "\n")
+		if len(lines) != len(step.want) || lines[0] != file+": "+step.want[0] {
+			t.Errorf("%s: output %q, want %q", step.name, lines, step.want)
+			continue
+		}
+		for i, want := range step.want[1:] {
+			if !strings.HasPrefix(lines[i+1], want) {
+				t.Errorf("%s: line %q, want it to start %q", step.name, lines[i+1], want)
+			}
+		}
+		if strings.Contains(stdout, ansiReset) {
+			t.Errorf("%s: colored output with color off", step.name)
+		}
+	}
+}
+
+func TestWatchDebouncesBursts(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "metrics.prom", "up 1\n")
+	other := writeFile(t, dir, "other.prom", "up 1\n")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newWatchRun(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	stdout, _ := captureOutput(t, func() {
+		go func() { done <- w.watch(ctx, watcher, []string{file}, 200*time.Millisecond) }()
+		for range 3 {
+			if err := os.WriteFile(file, []byte("up 1\n"), 0o644); err != nil {
+				t.Error(err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		// Files that aren't watched don't trigger a lint
+		if err := os.WriteFile(other, []byte("up 2\n"), 0o644); err != nil {
+			t.Error(err)
+		}
+		time.Sleep(600 * time.Millisecond)
+		cancel()
+		if code := <-done; code != exitClean {
+			t.Errorf("watch = %d, want %d once cancelled", code, exitClean)
+		}
+	})
+
+	if n := strings.Count(stdout, file+": 1 issues"); n != 1 {
+		t.Errorf("linted %d times, want once for the burst:\n%s", n, stdout)
+	}
+	if strings.Contains(stdout, other) {
+		t.Errorf("linted a file that isn't watched:\n%s", stdout)
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.prom", "up 1\n")
+	b := writeFile(t, dir, "b.prom", "up 1\n")
+	writeFile(t, dir, "c.go", "package c\n")
+
+	files, err := expandGlobs([]string{filepath.Join(dir, "*.prom"), dir + "/./b.prom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b}; !slices.Equal(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+
+	if _, err := expandGlobs([]string{filepath.Join(dir, "*.txt")}); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("a glob matching nothing = %v, want an error", err)
+	}
+}
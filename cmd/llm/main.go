@@ -4,18 +4,27 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+
+	"github.com/wbollock/good_telemetry/internal/llm"
 )
 
 func main() {
-	// TODO: Initialize Ollama client
 	// TODO: Load RAG knowledge base
-	// TODO: Set up routes
 	// TODO: Load configuration
 
+	ollamaURL := os.Getenv("OLLAMA_URL")
+	if ollamaURL == "" {
+		ollamaURL = "http://localhost:11434"
+	}
+	client := llm.NewClient(ollamaURL, "")
+
 	http.HandleFunc("/evaluate", handleEvaluate)
+	http.HandleFunc("GET /models", handleModels(client))
 
 	port := ":8081"
 	log.Printf("Starting Good Telemetry LLM backend on %s", port)
@@ -27,3 +36,18 @@ func main() {
 func handleEvaluate(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "LLM Evaluation Service - Coming Soon")
 }
+
+// handleModels lists the models installed on Ollama, for a model picker
+func handleModels(client *llm.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		tags, err := client.Tags(r.Context())
+		if err != nil {
+			log.Printf("[Models] Error listing models: %v", err)
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]llm.ModelInfo{"models": tags.ModelInfos()})
+	}
+}
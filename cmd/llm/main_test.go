@@ -0,0 +1,88 @@
+// ABOUTME: Tests for the LLM backend's model listing endpoint against a stub Ollama
+// ABOUTME: Installed models come back with size and capability hints; an unreachable Ollama is a bad gateway
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/llm"
+)
+
+func TestHandleModels(t *testing.T) {
+	tests := []struct {
+		name       string
+		ollama     http.HandlerFunc
+		wantStatus int
+		want       []llm.ModelInfo
+	}{
+		{
+			name: "installed models",
+			ollama: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/tags" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(`{"models":[
+					{"name":"llama2:latest","size":3825819519,"digest":"78e26419b446","modified_at":"2024-05-01T10:00:00Z"},
+					{"name":"mistral:latest","size":4109865159,"digest":"f974a74358d6","modified_at":"2024-05-02T10:00:00Z"}]}`))
+			},
+			wantStatus: http.StatusOK,
+			want: []llm.ModelInfo{
+				{Name: "llama2:latest", SizeGB: 3.8, ContextWindow: 4096},
+				{Name: "mistral:latest", SizeGB: 4.1, ContextWindow: 32768, JSONMode: true},
+			},
+		},
+		{
+			name: "no models installed",
+			ollama: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"models":[]}`))
+			},
+			wantStatus: http.StatusOK,
+			want:       []llm.ModelInfo{},
+		},
+		{
+			name: "ollama failing",
+			ollama: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ollama := httptest.NewServer(tt.ollama)
+			defer ollama.Close()
+
+			rec := httptest.NewRecorder()
+			handleModels(llm.NewClient(ollama.URL, ""))(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+
+			var body struct {
+				Models []llm.ModelInfo `json:"models"`
+				Error  string          `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body.Error == "" {
+					t.Error("error body is empty, want the reason")
+				}
+				return
+			}
+			if !reflect.DeepEqual(body.Models, tt.want) {
+				t.Errorf("models = %+v, want %+v", body.Models, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// ABOUTME: Abuse protection for the public evaluator - honeypot, per-session cap and math challenge
+// ABOUTME: Stateless: the evaluation count and challenge answers travel in HMAC-signed values
+
+package abuse
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Form field bots fill in and people never see
+const HoneypotField = "website"
+
+const challengeLifetime = 10 * time.Minute
+
+type Guard struct {
+	// Evaluations allowed per session before a challenge; 0 disables the cap
+	limit   atomic.Int64
+	apiKeys []string
+	key     []byte
+}
+
+func NewGuard(limit int, apiKeys []string) (*Guard, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, k := range apiKeys {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	g := &Guard{apiKeys: keys, key: key}
+	g.SetLimit(limit)
+	return g, nil
+}
+
+// SetLimit changes the per-session cap, e.g. on config reload
+func (g *Guard) SetLimit(limit int) {
+	g.limit.Store(int64(limit))
+}
+
+// Bypass reports whether a request carries one of the configured API keys
+func (g *Guard) Bypass(bearer string) bool {
+	for _, k := range g.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(k)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Count reads the evaluation count from a session cookie. A missing or forged
+// cookie starts a new session at 0, as dropping the cookie would anyway.
+func (g *Guard) Count(cookie string) int {
+	body, sig, ok := strings.Cut(cookie, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(g.sign(body))) {
+		return 0
+	}
+	n, err := strconv.Atoi(body)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SessionCookie encodes an evaluation count as a signed cookie value
+func (g *Guard) SessionCookie(count int) string {
+	body := strconv.Itoa(count)
+	return body + "." + g.sign(body)
+}
+
+// NeedsChallenge reports whether a session has used up its evaluations
+func (g *Guard) NeedsChallenge(count int) bool {
+	limit := int(g.limit.Load())
+	return limit > 0 && count >= limit
+}
+
+// Challenge returns an arithmetic question and a signed token that verifies the answer
+func (g *Guard) Challenge() (question, token string, err error) {
+	a, err := rand.Int(rand.Reader, big.NewInt(9))
+	if err != nil {
+		return "", "", err
+	}
+	b, err := rand.Int(rand.Reader, big.NewInt(9))
+	if err != nil {
+		return "", "", err
+	}
+
+	x, y := a.Int64()+1, b.Int64()+1
+	expires := strconv.FormatInt(time.Now().Add(challengeLifetime).Unix(), 10)
+	// The token holds only the expiry and a MAC over answer+expiry, so the answer isn't readable
+	token = expires + "." + g.sign(strconv.FormatInt(x+y, 10)+"|"+expires)
+	return fmt.Sprintf("What is %d + %d?", x, y), token, nil
+}
+
+// VerifyChallenge checks an answer against a token from Challenge
+func (g *Guard) VerifyChallenge(token, answer string) bool {
+	expires, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(g.sign(strings.TrimSpace(answer)+"|"+expires)))
+}
+
+func (g *Guard) sign(body string) string {
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
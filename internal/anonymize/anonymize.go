@@ -0,0 +1,103 @@
+// ABOUTME: Anonymizer for published evaluations - scrubs hostnames, IPs, emails and configured patterns
+// ABOUTME: Maps each distinct match to its own placeholder so label value counts survive scrubbing
+
+package anonymize
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type pattern struct {
+	re *regexp.Regexp
+	// Placeholders are prefix_1, prefix_2, ... per distinct match
+	prefix string
+}
+
+// Built-in patterns, applied inside label values and DogStatsD tags only so
+// dot.separated metric names are not mistaken for hostnames. Placeholders
+// contain no dots or @ so later patterns never match them.
+var builtin = []pattern{
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "email"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "ip"},
+	// IPv6, including :: compressed forms such as fe80::1 and ::1
+	{regexp.MustCompile(`\b(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{1,4}\b|::(?:[0-9A-Fa-f]{1,4}:){0,6}[0-9A-Fa-f]{1,4}\b`), "ip"},
+	{regexp.MustCompile(`\b(?:[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?\.)+[A-Za-z]{2,}\b`), "host"},
+}
+
+var (
+	// A quoted label value, or the tags section of a DogStatsD line
+	quotedValue = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	tagSection  = regexp.MustCompile(`\|#[^|]*`)
+)
+
+type Anonymizer struct {
+	// Configured patterns, e.g. org-specific name prefixes, applied to the whole text
+	custom []pattern
+}
+
+// New compiles the configured patterns, which are scrubbed everywhere in the
+// input including metric and label names
+func New(patterns []string) (*Anonymizer, error) {
+	a := &Anonymizer{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub pattern %q: %w", p, err)
+		}
+		a.custom = append(a.custom, pattern{re: re, prefix: "redacted"})
+	}
+	return a, nil
+}
+
+// Scrub replaces sensitive values in input with placeholders. It fails rather
+// than return text in which a configured pattern still matches.
+func (a *Anonymizer) Scrub(input string) (string, error) {
+	placeholders := make(map[string]string)
+	counts := make(map[string]int)
+	replace := func(p pattern) func(string) string {
+		return func(match string) string {
+			if placeholder, ok := placeholders[match]; ok {
+				return placeholder
+			}
+			counts[p.prefix]++
+			placeholder := fmt.Sprintf("%s_%d", p.prefix, counts[p.prefix])
+			placeholders[match] = placeholder
+			return placeholder
+		}
+	}
+
+	scrubValues := func(s string) string {
+		for _, p := range builtin {
+			s = p.re.ReplaceAllStringFunc(s, replace(p))
+		}
+		return s
+	}
+	// Built-ins first: a configured pattern replacing part of a hostname
+	// would leave the rest of it unrecognizable
+	out := quotedValue.ReplaceAllStringFunc(input, scrubValues)
+	out = tagSection.ReplaceAllStringFunc(out, scrubValues)
+
+	for _, p := range a.custom {
+		out = p.re.ReplaceAllStringFunc(out, replace(p))
+	}
+
+	if leaked := a.Leaks(out); len(leaked) > 0 {
+		return "", fmt.Errorf("scrubbed text still matches %s", strings.Join(leaked, ", "))
+	}
+	return out, nil
+}
+
+// Leaks returns the configured patterns that match text, sorted
+func (a *Anonymizer) Leaks(text string) []string {
+	var leaked []string
+	for _, p := range a.custom {
+		if p.re.MatchString(text) {
+			leaked = append(leaked, p.re.String())
+		}
+	}
+	sort.Strings(leaked)
+	return leaked
+}
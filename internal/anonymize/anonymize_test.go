@@ -0,0 +1,77 @@
+// ABOUTME: Tests for the anonymizer - built-in and configured patterns leave no trace in scrubbed text
+// ABOUTME: Distinct values keep distinct placeholders, so the number of label values survives
+
+package anonymize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrubBuiltins(t *testing.T) {
+	a, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `up{instance="db1.prod.example.com:9100",addr="10.0.0.12",owner="ops@example.com"} 1
+up{instance="db2.prod.example.com:9100",addr="10.0.0.13",owner="ops@example.com"} 1
+up{instance="db1.prod.example.com:9100",addr="fe80::1:2:3",owner="dev@example.com"} 1
+up{instance="localhost",addr="::1",owner="dev@example.com"} 1
+app.requests:1|c|#host:web1.example.org`
+	want := `up{instance="host_1:9100",addr="ip_1",owner="email_1"} 1
+up{instance="host_2:9100",addr="ip_2",owner="email_1"} 1
+up{instance="host_1:9100",addr="ip_3",owner="email_2"} 1
+up{instance="localhost",addr="ip_4",owner="email_2"} 1
+app.requests:1|c|#host:host_3`
+
+	got, err := a.Scrub(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Scrub =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestScrubConfiguredPatternsEverywhere(t *testing.T) {
+	a, err := New([]string{`acme`, `proj-[a-z]+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `# HELP acme_billing_requests_total Requests to acme billing.
+# TYPE acme_billing_requests_total counter
+acme_billing_requests_total{project="proj-phoenix",acme_team="proj-hydra"} 3`
+
+	got, err := a.Scrub(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"acme", "proj-", "phoenix", "hydra"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("scrubbed text holds %q:\n%s", secret, got)
+		}
+	}
+	if leaked := a.Leaks(got); len(leaked) > 0 {
+		t.Errorf("Leaks = %v", leaked)
+	}
+	if !strings.Contains(got, `redacted_1_billing_requests_total{project="redacted_2",redacted_1_team="redacted_3"} 3`) {
+		t.Errorf("scrubbed text = %s, want consistent placeholders", got)
+	}
+}
+
+func TestScrubRefusesToLeak(t *testing.T) {
+	// Each pattern's placeholder matches the other pattern
+	a, err := New([]string{`secret`, `redacted_\d`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Scrub(`up{team="secret"} 1`); err == nil {
+		t.Errorf("Scrub = %q, want an error rather than text matching a pattern", got)
+	}
+}
+
+func TestNewRejectsInvalidPatterns(t *testing.T) {
+	if _, err := New([]string{`(unclosed`}); err == nil {
+		t.Error("New accepted an invalid pattern")
+	}
+}
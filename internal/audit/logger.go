@@ -0,0 +1,237 @@
+// ABOUTME: Audit log - records who submitted which metrics and what verdict they got
+// ABOUTME: Appends JSON lines to a file that is rotated daily and pruned after a retention period
+
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ActionEvaluate = "evaluate"
+	ActionExport   = "export"
+	ActionShare    = "share"
+)
+
+// Date layout used in rotated file names, e.g. audit-2025-01-31.jsonl
+const dayLayout = "2006-01-02"
+
+type AuditEvent struct {
+	Timestamp         time.Time `json:"timestamp"`
+	ClientIP          string    `json:"client_ip"`
+	TenantID          string    `json:"tenant_id,omitempty"`
+	User              string    `json:"user,omitempty"`
+	MetricFingerprint string    `json:"metric_fingerprint"`
+	Verdict           string    `json:"verdict,omitempty"`
+	Score             string    `json:"score,omitempty"`
+	Action            string    `json:"action"`
+}
+
+type Logger struct {
+	path          string
+	retentionDays int
+
+	mu   sync.Mutex
+	file *os.File
+	day  string
+}
+
+// Open appends to the audit log at path. Rotated files older than
+// retentionDays are deleted; zero keeps them forever.
+func Open(path string, retentionDays int) (*Logger, error) {
+	l := &Logger{path: path, retentionDays: retentionDays}
+
+	day := today()
+	if info, err := os.Stat(path); err == nil {
+		// A file left from an earlier day is rotated on the first write
+		day = info.ModTime().UTC().Format(dayLayout)
+	}
+
+	if err := l.openFile(day); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Fingerprint identifies a metric submission without storing its contents
+func Fingerprint(input string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(input)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (l *Logger) Log(event AuditEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	event.Timestamp = event.Timestamp.UTC()
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day := today(); day != l.day {
+		if err := l.rotate(day); err != nil {
+			return err
+		}
+	}
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query returns events with start <= timestamp <= end, oldest first.
+// A zero start or end leaves that side of the range open.
+func (l *Logger) Query(start, end time.Time) ([]AuditEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rotated, err := l.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	events := []AuditEvent{}
+	for _, path := range append(rotated, l.path) {
+		if err := readEvents(path, func(e AuditEvent) {
+			if !start.IsZero() && e.Timestamp.Before(start) {
+				return
+			}
+			if !end.IsZero() && e.Timestamp.After(end) {
+				return
+			}
+			events = append(events, e)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events, nil
+}
+
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *Logger) openFile(day string) error {
+	if dir := filepath.Dir(l.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating audit log directory: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	l.file = f
+	l.day = day
+	return nil
+}
+
+// rotate moves the current file aside under its date and starts a fresh one
+func (l *Logger) rotate(day string) error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, l.rotatedName(l.day)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+	if err := l.openFile(day); err != nil {
+		return err
+	}
+	return l.prune()
+}
+
+func (l *Logger) prune() error {
+	if l.retentionDays <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -l.retentionDays).Format(dayLayout)
+	rotated, err := l.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range rotated {
+		// Dates in YYYY-MM-DD form compare correctly as strings
+		if l.dayOf(path) < cutoff {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Logger) rotatedName(day string) string {
+	ext := filepath.Ext(l.path)
+	return strings.TrimSuffix(l.path, ext) + "-" + day + ext
+}
+
+func (l *Logger) dayOf(rotated string) string {
+	ext := filepath.Ext(l.path)
+	return strings.TrimSuffix(strings.TrimPrefix(rotated, strings.TrimSuffix(l.path, ext)+"-"), ext)
+}
+
+// rotatedFiles lists earlier days' files in date order
+func (l *Logger) rotatedFiles() ([]string, error) {
+	matches, err := filepath.Glob(l.rotatedName("*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range matches {
+		if _, err := time.Parse(dayLayout, l.dayOf(path)); err == nil {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readEvents(path string, fn func(AuditEvent)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Skip a line torn by a crash mid-write rather than failing the whole query
+			continue
+		}
+		fn(event)
+	}
+	return scanner.Err()
+}
+
+func today() string {
+	return time.Now().UTC().Format(dayLayout)
+}
@@ -0,0 +1,224 @@
+// ABOUTME: OIDC single sign-on for the web UI - redirects to the identity provider and validates ID tokens
+// ABOUTME: Keeps the signed-in user in a signed session cookie and exposes it to handlers via the gin context
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// CallbackPath receives the authorization code from the identity provider
+const CallbackPath = "/auth/callback"
+
+const (
+	sessionCookie = "gt_session"
+	stateCookie   = "gt_oidc_state"
+	userKey       = "auth.user"
+
+	sessionLifetime = 12 * time.Hour
+	stateLifetime   = 10 * time.Minute
+)
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Signs session cookies; a random key is generated when empty, which
+	// signs everyone out on restart
+	SessionKey []byte
+}
+
+// User is the identity taken from a validated ID token
+type User struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// ID returns the most readable identifier for audit records
+func (u User) ID() string {
+	if u.Email != "" {
+		return u.Email
+	}
+	return u.Subject
+}
+
+type OIDC struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	sessions *sessionCodec
+}
+
+// NewOIDC discovers the provider's endpoints from its issuer URL
+func NewOIDC(ctx context.Context, cfg OIDCConfig) (*OIDC, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovering OIDC provider %s: %w", cfg.Issuer, err)
+	}
+
+	key := cfg.SessionKey
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return &OIDC{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		sessions: &sessionCodec{key: key},
+	}, nil
+}
+
+// Middleware requires a signed-in user. Browsers navigating to a page are sent
+// to the identity provider; htmx and other requests get a 401 (with HX-Redirect
+// so htmx reloads the current page into the login flow).
+func (o *OIDC) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookie, err := c.Cookie(sessionCookie); err == nil {
+			if user, err := o.sessions.decode(cookie); err == nil {
+				c.Set(userKey, user)
+				c.Next()
+				return
+			}
+		}
+
+		if c.GetHeader("HX-Request") == "true" {
+			page := "/"
+			if current, err := url.Parse(c.GetHeader("HX-Current-URL")); err == nil {
+				page = safeReturnPath(current.RequestURI())
+			}
+			c.Header("HX-Redirect", page)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if c.Request.Method != http.MethodGet {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		o.redirectToProvider(c)
+	}
+}
+
+// Callback finishes the login: checks state, exchanges the code and validates the ID token
+func (o *OIDC) Callback(c *gin.Context) {
+	stored, err := c.Cookie(stateCookie)
+	// state|nonce|return path; the path is last since it may hold a |
+	parts := strings.SplitN(stored, "|", 3)
+	if err != nil || len(parts) != 3 || parts[0] == "" || parts[1] == "" || c.Query("state") != parts[0] {
+		c.String(http.StatusBadRequest, "invalid login state, please try again")
+		return
+	}
+	nonce, returnTo := parts[1], parts[2]
+	c.SetCookie(stateCookie, "", -1, "/", "", isSecure(c), true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		log.Printf("[Auth] Identity provider returned error: %s %s", errParam, c.Query("error_description"))
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	token, err := o.oauth.Exchange(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		log.Printf("[Auth] Error exchanging authorization code: %v", err)
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		log.Printf("[Auth] Token response had no id_token")
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+	idToken, err := o.verifier.Verify(c.Request.Context(), rawIDToken)
+	if err != nil {
+		log.Printf("[Auth] Error verifying ID token: %v", err)
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+	// The nonce ties the ID token to this browser's login, so a token issued for another can't be replayed
+	if idToken.Nonce != nonce {
+		log.Printf("[Auth] ID token nonce does not match this login")
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	var user User
+	if err := idToken.Claims(&user); err != nil {
+		log.Printf("[Auth] Error reading ID token claims: %v", err)
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	cookie, err := o.sessions.encode(user, time.Now().Add(sessionLifetime))
+	if err != nil {
+		log.Printf("[Auth] Error encoding session: %v", err)
+		c.String(http.StatusInternalServerError, "sign-in failed")
+		return
+	}
+	c.SetCookie(sessionCookie, cookie, int(sessionLifetime.Seconds()), "/", "", isSecure(c), true)
+
+	log.Printf("[Auth] Signed in %s", user.ID())
+	c.Redirect(http.StatusFound, safeReturnPath(returnTo))
+}
+
+// CurrentUser returns the signed-in user, if authentication is enabled and succeeded
+func CurrentUser(c *gin.Context) (User, bool) {
+	v, ok := c.Get(userKey)
+	if !ok {
+		return User{}, false
+	}
+	user, ok := v.(User)
+	return user, ok
+}
+
+func (o *OIDC) redirectToProvider(c *gin.Context) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(buf[:16])
+	nonce := base64.RawURLEncoding.EncodeToString(buf[16:])
+
+	// The state cookie also keeps the nonce and remembers where to send the user after login
+	c.SetCookie(stateCookie, state+"|"+nonce+"|"+c.Request.URL.RequestURI(), int(stateLifetime.Seconds()), "/", "", isSecure(c), true)
+	c.Redirect(http.StatusFound, o.oauth.AuthCodeURL(state, oidc.Nonce(nonce)))
+	c.Abort()
+}
+
+// safeReturnPath only allows local paths so the login flow can't become an open
+// redirect. Browsers read a backslash as a slash, so /\evil.com is rejected too.
+func safeReturnPath(path string) string {
+	u, err := url.Parse(path)
+	if err != nil || u.IsAbs() || u.Host != "" || !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return "/"
+	}
+	return path
+}
+
+func isSecure(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,317 @@
+// ABOUTME: Tests for OIDC sign-in against a fake identity provider that signs ID tokens with its own key
+// ABOUTME: Covers the state and nonce checks, the session cookie, and return paths that must stay on this site
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testClientID = "good-telemetry"
+
+// fakeProvider serves discovery, keys and a token endpoint whose ID token
+// carries whatever nonce the test sets
+type fakeProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "test",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken(t),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeProvider) idToken(t *testing.T) string {
+	enc := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	now := time.Now()
+	signed := enc(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"}) + "." + enc(map[string]any{
+		"iss": p.URL, "aud": testClientID, "sub": "user-1", "email": "ada@example.com",
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "nonce": p.nonce,
+	})
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestRouter(t *testing.T, p *fakeProvider) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	o, err := NewOIDC(context.Background(), OIDCConfig{
+		Issuer:       p.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://app.test" + CallbackPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET(CallbackPath, o.Callback)
+	r.GET("/page", o.Middleware(), func(c *gin.Context) {
+		user, _ := CurrentUser(c)
+		c.String(http.StatusOK, user.ID())
+	})
+	return r
+}
+
+func serve(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func cookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// startLogin visits a page signed out and returns the state cookie and the
+// state and nonce sent to the identity provider
+func startLogin(t *testing.T, r http.Handler, p *fakeProvider, page string) (*http.Cookie, string, string) {
+	t.Helper()
+	rec := serve(r, httptest.NewRequest(http.MethodGet, page, nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("signed-out page = %d, want a redirect to the provider", rec.Code)
+	}
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(loc.String(), p.URL+"/authorize") {
+		t.Fatalf("redirected to %q, want the provider's authorize endpoint", loc)
+	}
+	state := cookie(rec, stateCookie)
+	if state == nil || !state.HttpOnly {
+		t.Fatalf("state cookie = %+v, want an HttpOnly cookie", state)
+	}
+	q := loc.Query()
+	if q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("authorize request %q lacks a state or nonce", loc)
+	}
+	return state, q.Get("state"), q.Get("nonce")
+}
+
+// setStateCookie rewrites the state cookie's stored value, which gin query-escapes
+func setStateCookie(t *testing.T, value string, change func(stored string) string) string {
+	t.Helper()
+	stored, err := url.QueryUnescape(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return url.QueryEscape(change(stored))
+}
+
+func TestSignIn(t *testing.T) {
+	p := newFakeProvider(t)
+	r := newTestRouter(t, p)
+
+	state, stateParam, nonce := startLogin(t, r, p, "/page?tab=history")
+	p.nonce = nonce
+
+	req := httptest.NewRequest(http.MethodGet, CallbackPath+"?code=good-code&state="+stateParam, nil)
+	req.AddCookie(state)
+	rec := serve(r, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/page?tab=history" {
+		t.Fatalf("callback = %d to %q, want a redirect back to /page?tab=history", rec.Code, rec.Header().Get("Location"))
+	}
+	if c := cookie(rec, stateCookie); c == nil || c.MaxAge >= 0 {
+		t.Errorf("state cookie = %+v, want it cleared", c)
+	}
+	session := cookie(rec, sessionCookie)
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("session cookie = %+v, want an HttpOnly cookie", session)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/page", nil)
+	req.AddCookie(session)
+	if rec := serve(r, req); rec.Code != http.StatusOK || rec.Body.String() != "ada@example.com" {
+		t.Errorf("signed-in page = %d %q, want 200 for ada@example.com", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// Changes the callback after a normal login started
+		query       func(state string) string
+		stateCookie func(stored string) string
+		nonce       func(nonce string) string
+		want        int
+	}{
+		{name: "a state that doesn't match the cookie",
+			query: func(state string) string { return "code=good-code&state=other" }, want: http.StatusBadRequest},
+		{name: "no state cookie",
+			stateCookie: func(string) string { return "" }, want: http.StatusBadRequest},
+		{name: "a state cookie without a nonce",
+			stateCookie: func(stored string) string { s, _, _ := strings.Cut(stored, "|"); return s + "|/page" },
+			want:        http.StatusBadRequest},
+		{name: "an ID token for another login's nonce",
+			nonce: func(string) string { return "someone-elses-nonce" }, want: http.StatusUnauthorized},
+		{name: "an ID token without a nonce",
+			nonce: func(string) string { return "" }, want: http.StatusUnauthorized},
+		{name: "an error from the provider",
+			query: func(state string) string { return "error=access_denied&state=" + state }, want: http.StatusUnauthorized},
+		{name: "a code the provider won't exchange",
+			query: func(state string) string { return "code=bad-code&state=" + state }, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProvider(t)
+			r := newTestRouter(t, p)
+			state, stateParam, nonce := startLogin(t, r, p, "/page")
+
+			p.nonce = nonce
+			if tt.nonce != nil {
+				p.nonce = tt.nonce(nonce)
+			}
+			query := "code=good-code&state=" + stateParam
+			if tt.query != nil {
+				query = tt.query(stateParam)
+			}
+			if tt.stateCookie != nil {
+				state.Value = setStateCookie(t, state.Value, tt.stateCookie)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, CallbackPath+"?"+query, nil)
+			if state.Value != "" {
+				req.AddCookie(state)
+			}
+			rec := serve(r, req)
+			if rec.Code != tt.want {
+				t.Errorf("callback = %d, want %d", rec.Code, tt.want)
+			}
+			if cookie(rec, sessionCookie) != nil {
+				t.Error("a rejected callback set a session cookie")
+			}
+		})
+	}
+}
+
+func TestCallbackReturnsOnlyToLocalPaths(t *testing.T) {
+	p := newFakeProvider(t)
+	r := newTestRouter(t, p)
+	state, stateParam, nonce := startLogin(t, r, p, "/page")
+	p.nonce = nonce
+
+	// A state cookie set by anything but this server is only trusted as far as its return path is safe
+	state.Value = setStateCookie(t, state.Value, func(stored string) string {
+		return stored[:strings.LastIndex(stored, "|")] + `|/\evil.example`
+	})
+
+	req := httptest.NewRequest(http.MethodGet, CallbackPath+"?code=good-code&state="+stateParam, nil)
+	req.AddCookie(state)
+	rec := serve(r, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" {
+		t.Errorf("callback = %d to %q, want a redirect to /", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestSignedOutHTMXRequest(t *testing.T) {
+	p := newFakeProvider(t)
+	r := newTestRouter(t, p)
+
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{"http://app.test/page?tab=history", "/page?tab=history"},
+		{"http://app.test//evil.example", "/"},
+		// A backslash is escaped on the way through, so the browser stays on this site
+		{`http://app.test/\evil.example`, "/%5Cevil.example"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("HX-Request", "true")
+		req.Header.Set("HX-Current-URL", tt.current)
+		rec := serve(r, req)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("HX-Redirect") != tt.want {
+			t.Errorf("%s: %d with HX-Redirect %q, want 401 with %q", tt.current, rec.Code, rec.Header().Get("HX-Redirect"), tt.want)
+		}
+	}
+}
+
+func TestSafeReturnPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "/"},
+		{"/page?tab=history#top", "/page?tab=history#top"},
+		{"/a|b", "/a|b"},
+		{"", "/"},
+		{"page", "/"},
+		{"//evil.example", "/"},
+		{"https://evil.example/", "/"},
+		{`/\evil.example`, "/"},
+		{`/\/evil.example`, "/"},
+		{`/page\..\x`, "/"},
+		{"javascript:alert(1)", "/"},
+	}
+	for _, tt := range tests {
+		if got := safeReturnPath(tt.path); got != tt.want {
+			t.Errorf("safeReturnPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// ABOUTME: Build identification - the commit and time a binary was built from
+// ABOUTME: Injected with -ldflags -X at build time, falling back to the VCS stamp go build records
+
+package buildinfo
+
+import "runtime/debug"
+
+// Set at build time:
+//
+//	go build -ldflags "-X github.com/wbollock/good_telemetry/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/wbollock/good_telemetry/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Commit    string
+	BuildTime string
+)
+
+// Info returns the commit and build time. Without ldflags the commit and its
+// time come from the VCS stamp; either is "unknown" when nothing recorded it.
+func Info() (commit, buildTime string) {
+	commit, buildTime = Commit, BuildTime
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && buildTime == "":
+				buildTime = s.Value
+			}
+		}
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
+	return commit, buildTime
+}
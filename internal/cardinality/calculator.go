@@ -5,7 +5,10 @@ package cardinality
 
 import (
 	"fmt"
+	"maps"
+	"net"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -17,11 +20,30 @@ type Analysis struct {
 	HighCardinalityRisks []string
 	LabelAnalysis        map[string]LabelInfo
 	Warnings             []string
+
+	// Labels replaced on every deploy, sorted; churn, unlike instant
+	// cardinality, grows with deploy frequency. See ApplyChurn.
+	ChurnLabels          []string
+	DeploysPerDay        float64
+	ChurnSeriesPerDeploy int
+	// Head block series relative to EstimatedSeries; 0 until ApplyChurn finds churn
+	ChurnMultiplier float64
 }
 
 type LabelInfo struct {
 	Name               string
+	// Distinct raw values, as Prometheus counts them
 	EstimatedValues    int
+	// Distinct values once Unicode forms and invisible characters are normalized
+	NormalizedValues   int
+	// Raw values that only differ in their normalization, grouped by what they look like
+	LookalikeValues    [][]string
+	// EstimatedValues came from Options.ValueEstimator, not the input
+	ValueEstimated     bool
+	// The user's asserted bound on distinct values, from Options.Bounds; 0 if none
+	AssertedBound      int
+	// Values are replaced on every deploy
+	ChurnsOnDeploy     bool
 	CardinalityRisk    string
 	IsHighCardinality  bool
 	RecommendedAction  string
@@ -33,13 +55,57 @@ const (
 	// Conservative estimate: 3KB per series for low churn
 	// Higher estimate: 6KB per series for high churn
 	memoryPerSeriesBytes = 3000
-
-	// Thresholds
-	lowCardinalityThreshold    = 100
-	mediumCardinalityThreshold = 1000
-	highCardinalityThreshold   = 10000
 )
 
+// Thresholds set when label value counts and series totals are called out
+type Thresholds struct {
+	// Unique values of one label worth monitoring, and worth reviewing
+	LabelValuesMonitor int
+	LabelValuesReview  int
+	// Series totals separating Low, Medium, High and Very High cardinality
+	SeriesLow    int
+	SeriesMedium int
+	SeriesHigh   int
+}
+
+// DefaultThresholds suit a single Prometheus server
+var DefaultThresholds = Thresholds{
+	LabelValuesMonitor: 20,
+	LabelValuesReview:  100,
+	SeriesLow:          100,
+	SeriesMedium:       1000,
+	SeriesHigh:         10000,
+}
+
+// Options configure an analysis
+type Options struct {
+	Thresholds Thresholds
+	// Typical distinct values of a label, standing in for the one value a
+	// single-sample input shows; 0 means no estimate. See EstimateFrom.
+	ValueEstimator func(labelName string) int
+	// Label name to the most distinct values the user asserts it has. A
+	// bounded label isn't treated as unbounded whatever its name, and counts
+	// with its bound unless the input shows more values.
+	Bounds map[string]int
+}
+
+// DefaultValueEstimators are typical distinct value counts of common label names
+var DefaultValueEstimators = map[string]int{
+	"method":  8,
+	"status":  25,
+	"region":  6,
+	"zone":    3,
+	"env":     3,
+	"cluster": 5,
+}
+
+// EstimateFrom returns a ValueEstimator looking label names up in estimates
+func EstimateFrom(estimates map[string]int) func(labelName string) int {
+	return func(labelName string) int {
+		return estimates[labelName]
+	}
+}
+
 var highCardinalityPatterns = map[string]*regexp.Regexp{
 	"user_id":       regexp.MustCompile(`(?i)^(user_?id|userid|user_?name|username)$`),
 	"email":         regexp.MustCompile(`(?i)^(email|e_?mail)$`),
@@ -53,12 +119,58 @@ var highCardinalityPatterns = map[string]*regexp.Regexp{
 	"volume":        regexp.MustCompile(`(?i)^(vol|volume|volume_?id|disk|disk_?id)$`),
 }
 
+var (
+	// Runs of digits, as in /api/users/12345
+	digitRunValue = regexp.MustCompile(`[0-9]{3,}`)
+	// HTTP status codes and other three-digit codes, which are bounded
+	statusCodeValue = regexp.MustCompile(`^[1-5][0-9]{2}$`)
+	uuidValue       = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	ipv4Value       = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
+)
+
+// Labels whose values are numbers by definition: histogram bucket bounds and
+// summary quantiles
+var numericLabels = []string{"le", "quantile"}
+
+// idLikeValue reports whether value looks like it carries an ID: a run of
+// three or more digits other than a bare status code, a UUID or an IP address
+func idLikeValue(value string) bool {
+	if statusCodeValue.MatchString(value) {
+		return false
+	}
+	return digitRunValue.MatchString(value) || uuidValue.MatchString(value) ||
+		ipv4Value.MatchString(value) || net.ParseIP(value) != nil
+}
+
+// DetectUnboundedLabelValues reports whether most of values look like IDs,
+// such as user IDs in request paths, so the label is unbounded whatever its name
+func DetectUnboundedLabelValues(values []string) bool {
+	idLike := 0
+	for _, v := range values {
+		if idLikeValue(v) {
+			idLike++
+		}
+	}
+	return idLike > 0 && idLike*2 > len(values)
+}
+
 func Analyze(allLabels []map[string]string) *Analysis {
+	return AnalyzeWithThresholds(allLabels, DefaultThresholds)
+}
+
+// AnalyzeWithThresholds is Analyze for backends with different cardinality limits
+func AnalyzeWithThresholds(allLabels []map[string]string, t Thresholds) *Analysis {
+	return AnalyzeWithOptions(allLabels, Options{Thresholds: t})
+}
+
+// AnalyzeWithOptions is Analyze with the thresholds and value estimates in o
+func AnalyzeWithOptions(allLabels []map[string]string, o Options) *Analysis {
+	t := o.Thresholds
 	if len(allLabels) == 0 {
 		return &Analysis{
 			EstimatedSeries:     1,
 			MemoryEstimateBytes: memoryPerSeriesBytes,
-			MemoryEstimateHuman: formatBytes(memoryPerSeriesBytes),
+			MemoryEstimateHuman: formatBytes(memoryPerSeriesBytes, SI),
 			CardinalityLevel:    "Low",
 			LabelAnalysis:       make(map[string]LabelInfo),
 		}
@@ -82,19 +194,44 @@ func Analyze(allLabels []map[string]string) *Analysis {
 
 	totalCardinality := 1
 	hasHighCardinalityRisk := false
+	// A single sample shows one value of each label, so the estimator's
+	// typical counts are used where it has them
+	var estimated []string
+	// Labels with a bound asserted in o.Bounds
+	var bounded []string
 
 	for labelName, values := range labelCounts {
 		uniqueValues := len(values)
-		totalCardinality *= uniqueValues
+		valueCount := fmt.Sprintf("%d unique values", uniqueValues)
+		bound := o.Bounds[labelName]
+		if bound > 0 {
+			uniqueValues = max(uniqueValues, bound)
+			valueCount = fmt.Sprintf("up to %d unique values by your assertion", uniqueValues)
+			bounded = append(bounded, labelName)
+		} else if len(allLabels) == 1 && o.ValueEstimator != nil {
+			if estimate := o.ValueEstimator(labelName); estimate > 0 {
+				uniqueValues = estimate
+				valueCount = fmt.Sprintf("typically %d unique values", uniqueValues)
+				estimated = append(estimated, labelName)
+			}
+		}
+		totalCardinality = saturatingMul(totalCardinality, uniqueValues)
 
 		info := LabelInfo{
 			Name:            labelName,
 			EstimatedValues: uniqueValues,
+			ValueEstimated:  slices.Contains(estimated, labelName),
+			AssertedBound:   bound,
+		}
+		info.NormalizedValues, info.LookalikeValues = lookalikeGroups(values)
+		if ChurnsOnDeploy(labelName) {
+			info.ChurnsOnDeploy = true
+			analysis.ChurnLabels = append(analysis.ChurnLabels, labelName)
 		}
 
 		// Check for high-cardinality patterns
 		for patternName, pattern := range highCardinalityPatterns {
-			if pattern.MatchString(labelName) {
+			if bound == 0 && pattern.MatchString(labelName) {
 				info.IsHighCardinality = true
 				info.CardinalityRisk = "HIGH"
 				info.RecommendedAction = fmt.Sprintf("Remove %s label (detected as %s) - unbounded cardinality", labelName, patternName)
@@ -103,15 +240,26 @@ func Analyze(allLabels []map[string]string) *Analysis {
 				break
 			}
 		}
+		if !info.IsHighCardinality && bound == 0 && !slices.Contains(numericLabels, labelName) {
+			observed := slices.Sorted(maps.Keys(values))
+			if DetectUnboundedLabelValues(observed) {
+				example := observed[slices.IndexFunc(observed, idLikeValue)]
+				info.IsHighCardinality = true
+				info.CardinalityRisk = "HIGH"
+				info.RecommendedAction = fmt.Sprintf("label '%s' contains values that look like IDs (e.g., '%s') — normalize to template paths", labelName, example)
+				analysis.HighCardinalityRisks = append(analysis.HighCardinalityRisks, info.RecommendedAction)
+				hasHighCardinalityRisk = true
+			}
+		}
 
 		if !info.IsHighCardinality {
-			if uniqueValues > 100 {
+			if uniqueValues > t.LabelValuesReview {
 				info.CardinalityRisk = "MEDIUM"
-				info.RecommendedAction = fmt.Sprintf("Review %s label - %d unique values is high", labelName, uniqueValues)
+				info.RecommendedAction = fmt.Sprintf("Review %s label - %s is high", labelName, valueCount)
 				analysis.Warnings = append(analysis.Warnings, info.RecommendedAction)
-			} else if uniqueValues > 20 {
+			} else if uniqueValues > t.LabelValuesMonitor {
 				info.CardinalityRisk = "LOW-MEDIUM"
-				info.RecommendedAction = fmt.Sprintf("Monitor %s label - %d unique values", labelName, uniqueValues)
+				info.RecommendedAction = fmt.Sprintf("Monitor %s label - %s", labelName, valueCount)
 			} else {
 				info.CardinalityRisk = "LOW"
 				info.RecommendedAction = "Good cardinality"
@@ -120,6 +268,14 @@ func Analyze(allLabels []map[string]string) *Analysis {
 
 		analysis.LabelAnalysis[labelName] = info
 	}
+	slices.Sort(analysis.ChurnLabels)
+	slices.Sort(estimated)
+	slices.Sort(bounded)
+	for _, labelName := range bounded {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+			"%s is bounded at %d values by your assertion; re-evaluate if the number of %s values grows",
+			labelName, analysis.LabelAnalysis[labelName].EstimatedValues, labelName))
+	}
 
 	// Set overall estimates
 	if hasHighCardinalityRisk {
@@ -135,7 +291,7 @@ func Analyze(allLabels []map[string]string) *Analysis {
 	} else {
 		// Can only estimate if we have multiple samples showing variety
 		numSamples := len(allLabels)
-		if numSamples == 1 {
+		if numSamples == 1 && len(estimated) == 0 && len(bounded) == 0 {
 			analysis.EstimatedSeries = 0
 			analysis.CardinalityLevel = "Cannot Estimate (single sample)"
 			analysis.MemoryEstimateBytes = 0
@@ -145,42 +301,120 @@ func Analyze(allLabels []map[string]string) *Analysis {
 				"Labels appear safe (no high-risk patterns detected)")
 		} else {
 			analysis.EstimatedSeries = totalCardinality
-			if totalCardinality < lowCardinalityThreshold {
+			if totalCardinality == MaxEstimatedSeries {
+				analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("At least %d series; estimates stop there", MaxEstimatedSeries))
+			}
+			basis := "Observed"
+			if len(bounded) > 0 {
+				basis = "Estimated"
+			}
+			if len(estimated) > 0 {
+				basis = "Estimated"
+				analysis.Warnings = append(analysis.Warnings,
+					fmt.Sprintf("Single sample - series estimated from typical value counts of %s", strings.Join(estimated, ", ")))
+			}
+			if totalCardinality < t.SeriesLow {
 				analysis.CardinalityLevel = "Low"
-			} else if totalCardinality < mediumCardinalityThreshold {
+			} else if totalCardinality < t.SeriesMedium {
 				analysis.CardinalityLevel = "Medium"
-			} else if totalCardinality < highCardinalityThreshold {
+			} else if totalCardinality < t.SeriesHigh {
 				analysis.CardinalityLevel = "High"
-				analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("Observed: ~%d unique combinations", totalCardinality))
+				analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s: ~%d unique combinations", basis, totalCardinality))
 			} else {
 				analysis.CardinalityLevel = "Very High"
-				analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("Observed: ~%d unique combinations - consider reducing labels", totalCardinality))
+				analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s: ~%d unique combinations - consider reducing labels", basis, totalCardinality))
 			}
-			analysis.MemoryEstimateBytes = int64(analysis.EstimatedSeries) * memoryPerSeriesBytes
-			analysis.MemoryEstimateHuman = formatBytes(analysis.MemoryEstimateBytes)
+			analysis.MemoryEstimateBytes = MemoryBytes(analysis.EstimatedSeries)
+			analysis.MemoryEstimateHuman = formatBytes(analysis.MemoryEstimateBytes, SI)
 		}
 	}
 
 	return analysis
 }
 
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// CardinalityPoint is the projected size of a metric on one day
+type CardinalityPoint struct {
+	Day             int
+	EstimatedSeries int
+	MemoryBytes     int64
+}
+
+// Series estimates and projections stop growing here, far beyond what any
+// server holds, so the memory estimate can't overflow
+const MaxEstimatedSeries = 1_000_000_000_000
+
+// saturatingMul multiplies two non-negative series counts, stopping at MaxEstimatedSeries
+func saturatingMul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > MaxEstimatedSeries/b {
+		return MaxEstimatedSeries
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
+	return min(a*b, MaxEstimatedSeries)
+}
+
+// ProjectCardinality models series growth for days days, from day 0 (today)
+// to day days, assuming each label gains its number of new values every day.
+// The series count is the product of every label's projected value count;
+// rates for labels the analysis doesn't know are ignored.
+func ProjectCardinality(current *Analysis, newValuesPerDayPerLabel map[string]int, days int) []CardinalityPoint {
+	points := make([]CardinalityPoint, 0, days+1)
+	for day := 0; day <= days; day++ {
+		series := 1.0
+		for name, info := range current.LabelAnalysis {
+			series *= float64(info.EstimatedValues + max(newValuesPerDayPerLabel[name], 0)*day)
+		}
+		series = min(series, MaxEstimatedSeries)
+		points = append(points, CardinalityPoint{
+			Day:             day,
+			EstimatedSeries: int(series),
+			MemoryBytes:     int64(series) * memoryPerSeriesBytes,
+		})
+	}
+	return points
+}
+
+// ByteFormat picks the multiples sizes are written in
+type ByteFormat int
+
+const (
+	// SI counts in powers of 1000 (KB, MB, GB), as memory estimates are shown
+	SI ByteFormat = iota
+	// IEC counts in powers of 1024 (KiB, MiB, GiB)
+	IEC
+)
+
+func formatBytes(bytes int64, format ByteFormat) string {
+	// Scaled as an unsigned magnitude, which math.MinInt64 still has
+	sign, magnitude := "", uint64(bytes)
+	if bytes < 0 {
+		sign, magnitude = "-", -magnitude
+	}
+	unit, suffix := uint64(1000), "B"
+	if format == IEC {
+		unit, suffix = 1024, "iB"
+	}
+	if magnitude < unit {
+		return fmt.Sprintf("%s%d B", sign, magnitude)
+	}
+	div, exp := unit, 0
+	for n := magnitude / unit; n >= unit; n /= unit {
 		div *= unit
 		exp++
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return fmt.Sprintf("%s%.1f %c%s", sign, float64(magnitude)/float64(div), "KMGTPE"[exp], suffix)
 }
 
 // Simple version for basic metrics without full label analysis
 func EstimateSimple(numSeries int) string {
-	bytes := int64(numSeries) * memoryPerSeriesBytes
-	return formatBytes(bytes)
+	return formatBytes(MemoryBytes(numSeries), SI)
+}
+
+// MemoryBytes is the memory numSeries series take, in bytes, counting at most
+// MaxEstimatedSeries
+func MemoryBytes(numSeries int) int64 {
+	return int64(min(numSeries, MaxEstimatedSeries)) * memoryPerSeriesBytes
 }
 
 // Check if a metric name follows best practices
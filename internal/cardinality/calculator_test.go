@@ -0,0 +1,190 @@
+// ABOUTME: Tests for series estimates - asserted label bounds and typical value counts of a single sample multiply into the estimate
+// ABOUTME: Products past MaxEstimatedSeries stop there, as do projections, and sizes down to math.MinInt64 are written with their sign
+
+package cardinality
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBoundedEstimates(t *testing.T) {
+	series := []map[string]string{
+		{"customer_id": "1", "region": "eu", "tenant": "a", "shard": "1"},
+		{"customer_id": "2", "region": "us", "tenant": "b", "shard": "2"},
+	}
+	tests := []struct {
+		name   string
+		bounds map[string]int
+		want   int
+	}{
+		{"observed", nil, 16},
+		{"one bound", map[string]int{"customer_id": 80}, 80 * 2 * 2 * 2},
+		{"bounds at the largest accepted", map[string]int{"customer_id": 100_000, "region": 100_000}, 100_000 * 100_000 * 2 * 2},
+		{"past the ceiling", map[string]int{"customer_id": 100_000, "region": 100_000, "tenant": 100_000, "shard": 100_000}, MaxEstimatedSeries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds, Bounds: tt.bounds})
+			if a.EstimatedSeries != tt.want {
+				t.Errorf("EstimatedSeries = %d, want %d", a.EstimatedSeries, tt.want)
+			}
+			if a.MemoryEstimateBytes != MemoryBytes(tt.want) || a.MemoryEstimateBytes <= 0 {
+				t.Errorf("MemoryEstimateBytes = %d, want %d", a.MemoryEstimateBytes, MemoryBytes(tt.want))
+			}
+			capped := slices.Contains(a.Warnings, "At least 1000000000000 series; estimates stop there")
+			if capped != (tt.want == MaxEstimatedSeries) {
+				t.Errorf("warnings = %q", a.Warnings)
+			}
+		})
+	}
+}
+
+func TestSaturatingMul(t *testing.T) {
+	tests := []struct{ a, b, want int }{
+		{0, 5, 0},
+		{5, 0, 0},
+		{3, 4, 12},
+		{MaxEstimatedSeries, 1, MaxEstimatedSeries},
+		{MaxEstimatedSeries, 2, MaxEstimatedSeries},
+		{1 << 40, 1 << 40, MaxEstimatedSeries},
+		{1_000_000, 1_000_001, MaxEstimatedSeries},
+	}
+	for _, tt := range tests {
+		if got := saturatingMul(tt.a, tt.b); got != tt.want {
+			t.Errorf("saturatingMul(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes  int64
+		format ByteFormat
+		want   string
+	}{
+		{0, SI, "0 B"},
+		{999, SI, "999 B"},
+		{1000, SI, "1.0 KB"},
+		{3_000_000_000, SI, "3.0 GB"},
+		{3_000_000_000, IEC, "2.8 GiB"},
+		{1023, IEC, "1023 B"},
+		{1024, IEC, "1.0 KiB"},
+		{-1, SI, "-1 B"},
+		{-15_000_000, SI, "-15.0 MB"},
+		{-1024, IEC, "-1.0 KiB"},
+		{math.MaxInt64, SI, "9.2 EB"},
+		{math.MinInt64, SI, "-9.2 EB"},
+		{math.MinInt64, IEC, "-8.0 EiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.bytes, tt.format); got != tt.want {
+			t.Errorf("formatBytes(%d, %d) = %q, want %q", tt.bytes, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestProjectCardinality(t *testing.T) {
+	// 2 methods x 3 pods today
+	series := []map[string]string{
+		{"method": "GET", "pod": "a"}, {"method": "POST", "pod": "b"}, {"method": "GET", "pod": "c"},
+	}
+	tests := []struct {
+		name   string
+		growth map[string]int
+		days   int
+		want   []int
+	}{
+		{"no growth", nil, 2, []int{6, 6, 6}},
+		{"one label grows", map[string]int{"pod": 2}, 3, []int{6, 10, 14, 18}},
+		{"both labels grow", map[string]int{"pod": 1, "method": 1}, 2, []int{6, 12, 20}},
+		{"unknown labels and negative rates are ignored", map[string]int{"region": 5, "pod": -3}, 1, []int{6, 6}},
+		{"today only", map[string]int{"pod": 2}, 0, []int{6}},
+		{"growth past the ceiling", map[string]int{"pod": 1_000_000, "method": 1_000_000}, 2, []int{6, MaxEstimatedSeries, MaxEstimatedSeries}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds})
+			points := ProjectCardinality(a, tt.growth, tt.days)
+			if len(points) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.want))
+			}
+			for i, p := range points {
+				want := tt.want[i]
+				if p.Day != i || p.EstimatedSeries != want || p.MemoryBytes != MemoryBytes(want) {
+					t.Errorf("point %d = %+v, want day %d with %d series in %d bytes", i, p, i, want, MemoryBytes(want))
+				}
+			}
+		})
+	}
+}
+
+func TestValueEstimates(t *testing.T) {
+	estimator := EstimateFrom(DefaultValueEstimators)
+	tests := []struct {
+		name      string
+		series    []map[string]string
+		estimator func(string) int
+		want      int
+		wantLevel string
+		// The single-sample warning, when the estimate came from the estimator
+		wantWarning string
+	}{
+		{
+			name:        "known labels on a single sample",
+			series:      []map[string]string{{"method": "GET", "status": "200", "handler": "/"}},
+			estimator:   estimator,
+			want:        8 * 25,
+			wantLevel:   "Medium",
+			wantWarning: "Single sample - series estimated from typical value counts of method, status",
+		},
+		{
+			name:      "no known labels on a single sample",
+			series:    []map[string]string{{"handler": "/"}},
+			estimator: estimator,
+			want:      0,
+			wantLevel: "Cannot Estimate (single sample)",
+		},
+		{
+			name:      "a single sample without an estimator",
+			series:    []map[string]string{{"method": "GET"}},
+			want:      0,
+			wantLevel: "Cannot Estimate (single sample)",
+		},
+		{
+			name:        "an estimate of 0 is no estimate",
+			series:      []map[string]string{{"method": "GET", "region": "eu"}},
+			estimator:   EstimateFrom(map[string]int{"method": 0, "region": 6}),
+			want:        6,
+			wantLevel:   "Low",
+			wantWarning: "Single sample - series estimated from typical value counts of region",
+		},
+		{
+			name:      "several samples are observed",
+			series:    []map[string]string{{"method": "GET"}, {"method": "POST"}},
+			estimator: estimator,
+			want:      2,
+			wantLevel: "Low",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeWithOptions(tt.series, Options{Thresholds: DefaultThresholds, ValueEstimator: tt.estimator})
+			if a.EstimatedSeries != tt.want || a.CardinalityLevel != tt.wantLevel {
+				t.Errorf("estimate = %d %q, want %d %q", a.EstimatedSeries, a.CardinalityLevel, tt.want, tt.wantLevel)
+			}
+			hasWarning := slices.ContainsFunc(a.Warnings, func(w string) bool { return strings.HasPrefix(w, "Single sample") })
+			if tt.wantWarning == "" && hasWarning || tt.wantWarning != "" && !slices.Contains(a.Warnings, tt.wantWarning) {
+				t.Errorf("warnings = %q, want %q", a.Warnings, tt.wantWarning)
+			}
+			for name, info := range a.LabelAnalysis {
+				wantEstimated := tt.wantWarning != "" && strings.Contains(tt.wantWarning, name)
+				if info.ValueEstimated != wantEstimated {
+					t.Errorf("%s ValueEstimated = %v, want %v", name, info.ValueEstimated, wantEstimated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// ABOUTME: Deploy churn - labels whose values are replaced on every deploy, such as pod or image_tag
+// ABOUTME: Bounded at any instant, but each deploy leaves a generation of series in the head block
+
+package cardinality
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultDeploysPerDay is assumed when no deploy frequency is given
+const DefaultDeploysPerDay = 1.0
+
+// The head block holds up to about this many hours of samples, so series a
+// deploy replaced stay in memory next to their replacements this long
+const headBlockHours = 3
+
+var churnPattern = regexp.MustCompile(`(?i)^(pod|pod_?name|replica|replica_?set|container_?id|build_?id|build|deployment_?hash|image|image_?tag|image_?id|git_?(sha|commit)|commit|version|revision)$`)
+
+// ChurnsOnDeploy reports whether a label's values are typically replaced on every deploy
+func ChurnsOnDeploy(label string) bool {
+	return churnPattern.MatchString(label)
+}
+
+// ApplyChurn estimates the series each deploy replaces, at deploysPerDay, and
+// scales the memory estimate by how many generations the head block holds
+// at once. Analyses without churn labels or a series estimate are unchanged.
+func (a *Analysis) ApplyChurn(deploysPerDay float64) {
+	if len(a.ChurnLabels) == 0 || a.EstimatedSeries == 0 || deploysPerDay <= 0 {
+		return
+	}
+
+	a.DeploysPerDay = deploysPerDay
+	// Every series carries the churning labels' combination, so each deploy replaces them all
+	a.ChurnSeriesPerDeploy = a.EstimatedSeries
+	a.ChurnMultiplier = 1 + deploysPerDay*headBlockHours/24
+	a.MemoryEstimateBytes = int64(float64(a.MemoryEstimateBytes) * a.ChurnMultiplier)
+	a.MemoryEstimateHuman = formatBytes(a.MemoryEstimateBytes, SI)
+	a.Warnings = append(a.Warnings, fmt.Sprintf(
+		"%s change on every deploy: at %g deploys a day each one replaces ~%d series (~%d new series a day), "+
+			"and the head block holds about %.1fx the instant series count, which the memory estimate includes",
+		strings.Join(a.ChurnLabels, ", "), deploysPerDay, a.ChurnSeriesPerDeploy,
+		int(float64(a.ChurnSeriesPerDeploy)*deploysPerDay), a.ChurnMultiplier))
+}
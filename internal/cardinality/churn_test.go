@@ -0,0 +1,66 @@
+// ABOUTME: Tests for deploy churn - a pod label replaces every series on each deploy
+// ABOUTME: More frequent deploys keep more generations in the head block and raise the memory estimate
+
+package cardinality
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChurnScalesWithDeployFrequency(t *testing.T) {
+	series := []map[string]string{
+		{"pod": "api-7d9f-abcde", "method": "GET"},
+		{"pod": "api-7d9f-fghij", "method": "POST"},
+	}
+	base := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds})
+	if !slices.Contains(base.ChurnLabels, "pod") {
+		t.Fatalf("ChurnLabels = %q, want pod", base.ChurnLabels)
+	}
+
+	tests := []struct {
+		name           string
+		deploysPerDay  float64
+		wantMultiplier float64
+		wantWarning    string
+	}{
+		{"weekly", 1.0 / 7, 1 + 3.0/(7*24), "each one replaces ~4 series (~0 new series a day)"},
+		{"hourly", 24, 4, "at 24 deploys a day each one replaces ~4 series (~96 new series a day)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds})
+			a.ApplyChurn(tt.deploysPerDay)
+			if a.ChurnSeriesPerDeploy != base.EstimatedSeries {
+				t.Errorf("ChurnSeriesPerDeploy = %d, want %d", a.ChurnSeriesPerDeploy, base.EstimatedSeries)
+			}
+			if a.ChurnMultiplier != tt.wantMultiplier {
+				t.Errorf("ChurnMultiplier = %g, want %g", a.ChurnMultiplier, tt.wantMultiplier)
+			}
+			if want := int64(float64(base.MemoryEstimateBytes) * tt.wantMultiplier); a.MemoryEstimateBytes != want {
+				t.Errorf("MemoryEstimateBytes = %d, want %d", a.MemoryEstimateBytes, want)
+			}
+			if !slices.ContainsFunc(a.Warnings, func(w string) bool { return strings.Contains(w, tt.wantWarning) }) {
+				t.Errorf("warnings = %q, want one containing %q", a.Warnings, tt.wantWarning)
+			}
+		})
+	}
+
+	weekly := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds})
+	weekly.ApplyChurn(1.0 / 7)
+	hourly := AnalyzeWithOptions(series, Options{Thresholds: DefaultThresholds})
+	hourly.ApplyChurn(24)
+	if hourly.MemoryEstimateBytes <= weekly.MemoryEstimateBytes {
+		t.Errorf("hourly memory %d not above weekly %d", hourly.MemoryEstimateBytes, weekly.MemoryEstimateBytes)
+	}
+}
+
+func TestChurnSkipsMetricsWithoutChurnLabels(t *testing.T) {
+	a := AnalyzeWithOptions([]map[string]string{{"method": "GET"}, {"method": "POST"}}, Options{Thresholds: DefaultThresholds})
+	before := a.MemoryEstimateBytes
+	a.ApplyChurn(24)
+	if a.ChurnMultiplier != 0 || a.MemoryEstimateBytes != before || len(a.Warnings) != 0 {
+		t.Errorf("ApplyChurn changed an analysis without churn labels: %+v", a)
+	}
+}
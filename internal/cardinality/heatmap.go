@@ -0,0 +1,69 @@
+// ABOUTME: Label interaction heat map - how many series each pair of labels can produce together
+// ABOUTME: Labels multiply: 50 pods by 20 endpoints is up to 1000 series, which per-label counts don't show
+
+package cardinality
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Labels a heat map covers at most, those with the most values, so it stays legible
+const MaxHeatMapLabels = 12
+
+// HeatMapCell is one pair of labels
+type HeatMapCell struct {
+	Row    string
+	Column string
+	// Value combinations of the pair: the product of their distinct value
+	// counts, or the label's own count on the diagonal
+	Combinations int
+	// log(Combinations) relative to the largest cell, from 0 to 1
+	Intensity float64
+}
+
+// GenerateHeatMapData builds a symmetric matrix of label pairs, rows and
+// columns ordered by distinct values with the most first, so the pairs
+// driving the series count gather in the top left. It returns nil for fewer
+// than two labels, where there is no interaction to show.
+func GenerateHeatMapData(analysis *Analysis) [][]HeatMapCell {
+	if analysis == nil || len(analysis.LabelAnalysis) < 2 {
+		return nil
+	}
+	labels := make([]LabelInfo, 0, len(analysis.LabelAnalysis))
+	for name, info := range analysis.LabelAnalysis {
+		info.Name = name
+		labels = append(labels, info)
+	}
+	slices.SortFunc(labels, func(a, b LabelInfo) int {
+		return cmp.Or(cmp.Compare(b.EstimatedValues, a.EstimatedValues), cmp.Compare(a.Name, b.Name))
+	})
+	if len(labels) > MaxHeatMapLabels {
+		labels = labels[:MaxHeatMapLabels]
+	}
+
+	peak := 1
+	cells := make([][]HeatMapCell, len(labels))
+	for i, row := range labels {
+		cells[i] = make([]HeatMapCell, len(labels))
+		for j, col := range labels {
+			combinations := row.EstimatedValues
+			if i != j {
+				combinations *= col.EstimatedValues
+			}
+			cells[i][j] = HeatMapCell{Row: row.Name, Column: col.Name, Combinations: combinations}
+			peak = max(peak, combinations)
+		}
+	}
+	if peak > 1 {
+		for i := range cells {
+			for j := range cells[i] {
+				if n := cells[i][j].Combinations; n > 1 {
+					cells[i][j].Intensity = math.Log(float64(n)) / math.Log(float64(peak))
+				}
+			}
+		}
+	}
+	return cells
+}
@@ -0,0 +1,54 @@
+// ABOUTME: Label value normalization - finds values that look the same but are distinct series
+// ABOUTME: Only the analysis normalizes; parsed samples keep their raw bytes
+
+package cardinality
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Characters that render as nothing, dropped before values are compared
+var invisible = strings.NewReplacer(
+	"\u200b", "", // zero-width space
+	"\u200c", "", // zero-width non-joiner
+	"\u200d", "", // zero-width joiner
+	"\u2060", "", // word joiner
+	"\ufeff", "", // byte order mark
+	"\u00ad", "", // soft hyphen
+)
+
+// NormalizeLabelValue is the form a value is compared in: NFC, without
+// invisible characters, with Unicode spaces such as NBSP as plain spaces and
+// surrounding whitespace trimmed
+func NormalizeLabelValue(v string) string {
+	v = invisible.Replace(norm.NFC.String(v))
+	v = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, v)
+	return strings.TrimSpace(v)
+}
+
+// lookalikeGroups groups the raw values that share a normalized form, leaving
+// out values that are alone in theirs. Groups and their values are sorted.
+func lookalikeGroups(values map[string]bool) (normalized int, groups [][]string) {
+	byForm := make(map[string][]string)
+	for v := range values {
+		form := NormalizeLabelValue(v)
+		byForm[form] = append(byForm[form], v)
+	}
+	for _, raw := range byForm {
+		if len(raw) > 1 {
+			slices.Sort(raw)
+			groups = append(groups, raw)
+		}
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+	return len(byForm), groups
+}
@@ -0,0 +1,57 @@
+// ABOUTME: Tests for label value normalization - composed and decomposed accents, zero-width characters and NBSP compare equal
+// ABOUTME: LabelInfo counts raw and normalized values, and groups only the values that look alike
+
+package cardinality
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLabelValue(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"composed", "café", "café"},
+		{"decomposed", "cafe\u0301", "café"},
+		{"zero-width space", "api\u200b", "api"},
+		{"zero-width joiner inside", "ap\u200di", "api"},
+		{"byte order mark", "\ufeffapi", "api"},
+		{"soft hyphen", "check\u00adout", "checkout"},
+		{"NBSP", "eu\u00a0west", "eu west"},
+		{"surrounding whitespace", " eu-west\t", "eu-west"},
+		{"plain", "eu-west", "eu-west"},
+		{"distinct letters stay distinct", "ı", "ı"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLabelValue(tt.raw); got != tt.want {
+				t.Errorf("NormalizeLabelValue(%+q) = %+q, want %+q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookalikeValuesCountBothWays(t *testing.T) {
+	var series []map[string]string
+	for _, city := range []string{"café", "cafe\u0301", "café\u200b", "paris", "paris\u00a0", "berlin"} {
+		series = append(series, map[string]string{"city": city})
+	}
+	info := Analyze(series).LabelAnalysis["city"]
+
+	if info.EstimatedValues != 6 || info.NormalizedValues != 3 {
+		t.Errorf("raw = %d, normalized = %d, want 6 and 3", info.EstimatedValues, info.NormalizedValues)
+	}
+	want := [][]string{
+		{"cafe\u0301", "café", "café\u200b"},
+		{"paris", "paris\u00a0"},
+	}
+	if !reflect.DeepEqual(info.LookalikeValues, want) {
+		t.Errorf("lookalikes = %+q, want %+q", info.LookalikeValues, want)
+	}
+
+	clean := Analyze([]map[string]string{{"city": "paris"}, {"city": "berlin"}}).LabelAnalysis["city"]
+	if clean.NormalizedValues != clean.EstimatedValues || clean.LookalikeValues != nil {
+		t.Errorf("distinct values: %+v", clean)
+	}
+}
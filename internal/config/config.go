@@ -0,0 +1,108 @@
+// ABOUTME: YAML configuration file - settings that can change while the server runs
+// ABOUTME: Overrides the environment at startup and is re-read when the file changes
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wbollock/good_telemetry/internal/anonymize"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/quota"
+	"github.com/wbollock/good_telemetry/internal/redact"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the optional YAML config. Unset fields leave the environment's value in place.
+type File struct {
+	Model                  string `yaml:"model"`
+	FastModel              string `yaml:"fast_model"`
+	Profile                string `yaml:"profile"`
+	SessionEvaluationLimit *int   `yaml:"session_evaluation_limit"`
+	Cost                   struct {
+		PromptPer1K   *float64 `yaml:"prompt_per_1k_tokens"`
+		ResponsePer1K *float64 `yaml:"response_per_1k_tokens"`
+	} `yaml:"cost"`
+	Gallery struct {
+		// Regexes scrubbed from evaluations before they are published
+		ScrubPatterns []string `yaml:"scrub_patterns"`
+	} `yaml:"gallery"`
+	Naming struct {
+		// Replace the built-in vague words flagged in metric names
+		VagueWords []string `yaml:"vague_words"`
+		// Words that must never appear in metric or label names
+		ForbiddenWords []string `yaml:"forbidden_words"`
+		// Retired metric names and their replacements, on top of the built-in exporter renames
+		Renames map[string]string `yaml:"renames"`
+		// When set, the only label names allowed; see goodtelemetry allowlist generate
+		AllowedLabels []string `yaml:"allowed_labels"`
+		// The order labels are written in; replaces metrics.DefaultLabelOrder
+		LabelOrder *metrics.LabelOrder `yaml:"label_order"`
+	} `yaml:"naming"`
+	// Request limits by API key pattern; the first matching pattern applies
+	Quotas []quota.QuotaPolicy `yaml:"quotas"`
+	Redact struct {
+		// Name to regex, redacted from logs and stored inputs on top of the built-in patterns
+		Patterns map[string]string `yaml:"patterns"`
+	} `yaml:"redact"`
+	// Teams sharing the instance, each with its own history, profile and LLM budget
+	Tenants []tenant.Tenant `yaml:"tenants"`
+	// Typical distinct values by label name, used to estimate the series of a
+	// single-sample submission; overrides cardinality.DefaultValueEstimators
+	LabelValueEstimates map[string]int `yaml:"label_value_estimates"`
+}
+
+// Load parses a config file, rejecting unknown keys so typos don't go unnoticed
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	// An empty file decodes as io.EOF and means no overrides
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if f.Profile != "" {
+		if _, err := rules.Profile(f.Profile); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if _, err := anonymize.New(f.Gallery.ScrubPatterns); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := redact.New(f.Redact.Patterns); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for label, estimate := range f.LabelValueEstimates {
+		if estimate < 0 {
+			return nil, fmt.Errorf("%s: label_value_estimates: %s must not be negative", path, label)
+		}
+	}
+	for _, q := range f.Quotas {
+		if err := q.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := tenant.ValidateAll(f.Tenants); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, t := range f.Tenants {
+		if t.Profile == "" {
+			continue
+		}
+		if _, err := rules.Profile(t.Profile); err != nil {
+			return nil, fmt.Errorf("%s: tenant %s: %w", path, t.ID, err)
+		}
+	}
+	return &f, nil
+}
@@ -0,0 +1,60 @@
+// ABOUTME: Tests for loading the YAML config file
+// ABOUTME: naming.renames maps retired metric names to their replacements; label_value_estimates can't be negative
+
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRenames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "naming:\n  renames:\n    billing_jobs: billing_jobs_processed_total\n    node_cpu: node_cpu_seconds_total\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Naming.Renames) != 2 || f.Naming.Renames["billing_jobs"] != "billing_jobs_processed_total" {
+		t.Errorf("renames = %v", f.Naming.Renames)
+	}
+}
+
+func TestLoadLabelValueEstimates(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    map[string]int
+		wantErr string
+	}{
+		{name: "estimates", yaml: "label_value_estimates:\n  region: 12\n  method: 0\n", want: map[string]int{"region": 12, "method": 0}},
+		{name: "negative estimate", yaml: "label_value_estimates:\n  region: -1\n", wantErr: "label_value_estimates: region must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(f.LabelValueEstimates, tt.want) {
+				t.Errorf("label_value_estimates = %v, want %v", f.LabelValueEstimates, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// ABOUTME: Config file watchers - reload the YAML config when it changes on disk
+// ABOUTME: Handles plain files edited in place and Kubernetes ConfigMap mounts updated by symlink swap
+
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Editors and kubelet produce bursts of events for one logical change
+const reloadDebounce = 200 * time.Millisecond
+
+// Kubernetes mounts ConfigMap keys as symlinks through ..data, which is
+// atomically re-pointed at a fresh timestamped directory on every update
+const kubernetesDataLink = "..data"
+
+// ConfigWatcher reloads a config file when it is written or replaced
+type ConfigWatcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewConfigWatcher watches path and calls onChange with each successfully
+// parsed version. The parent directory is watched so editors that save by
+// renaming a temp file over the original are still seen.
+func NewConfigWatcher(path string, onChange func(*File)) (*ConfigWatcher, error) {
+	name := filepath.Clean(path)
+	return watch(filepath.Dir(name), path, func(event fsnotify.Event) bool {
+		return filepath.Clean(event.Name) == name &&
+			event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename)
+	}, onChange)
+}
+
+// NewKubernetesConfigWatcher watches a ConfigMap mount directory and reloads
+// mountPath/fileName after each ConfigMap update. Writes to the file itself
+// are never seen there (the symlink target changes instead), so it reacts to
+// the CREATE of ..data in the mount directory.
+func NewKubernetesConfigWatcher(mountPath, fileName string, onChange func(*File)) (*ConfigWatcher, error) {
+	dataLink := filepath.Join(filepath.Clean(mountPath), kubernetesDataLink)
+	return watch(mountPath, filepath.Join(mountPath, fileName), func(event fsnotify.Event) bool {
+		return filepath.Clean(event.Name) == dataLink && event.Has(fsnotify.Create)
+	}, onChange)
+}
+
+func (w *ConfigWatcher) Close() error {
+	close(w.done)
+	return w.watcher.Close()
+}
+
+func watch(dir, path string, match func(fsnotify.Event) bool, onChange func(*File)) (*ConfigWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &ConfigWatcher{watcher: watcher, done: make(chan struct{})}
+	go func() {
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-w.done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if match(event) {
+					pending = time.After(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("[Config] Watch error: %v", err)
+			case <-pending:
+				pending = nil
+				f, err := Load(path)
+				if err != nil {
+					// Keep running on the last good config
+					log.Printf("[Config] Ignoring invalid config update: %v", err)
+					continue
+				}
+				log.Printf("[Config] Reloaded %s", path)
+				onChange(f)
+			}
+		}
+	}()
+	return w, nil
+}
@@ -0,0 +1,54 @@
+// ABOUTME: LLM cost accounting - converts prompt/response token counts into money at configurable rates
+// ABOUTME: Falls back to a character-based token estimate when the backend doesn't report counts
+
+package cost
+
+import (
+	"os"
+	"strconv"
+)
+
+// Rough average for English text and exposition-format metrics
+const charsPerToken = 4
+
+type Pricing struct {
+	PromptPer1K   float64
+	ResponsePer1K float64
+}
+
+// PricingFromEnv reads $/1k-token rates from COST_PROMPT_PER_1K_TOKENS and
+// COST_RESPONSE_PER_1K_TOKENS; unset or invalid values count as free (self-hosted Ollama)
+func PricingFromEnv() Pricing {
+	return Pricing{
+		PromptPer1K:   envFloat("COST_PROMPT_PER_1K_TOKENS"),
+		ResponsePer1K: envFloat("COST_RESPONSE_PER_1K_TOKENS"),
+	}
+}
+
+func (p Pricing) Cost(promptTokens, responseTokens int) float64 {
+	return float64(promptTokens)/1000*p.PromptPer1K + float64(responseTokens)/1000*p.ResponsePer1K
+}
+
+// EstimateTokens approximates the token count of text from its length
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// ProjectMonthly extrapolates spend observed over a number of days to 30 days
+func ProjectMonthly(spent float64, days float64) float64 {
+	if days <= 0 {
+		return 0
+	}
+	return spent / days * 30
+}
+
+func envFloat(key string) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
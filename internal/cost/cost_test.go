@@ -0,0 +1,84 @@
+// ABOUTME: Tests for the pricing math - cost at $/1k-token rates, the character-based estimate and the monthly projection
+// ABOUTME: Unset, invalid or negative rates from the environment count as free
+
+package cost
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name             string
+		pricing          Pricing
+		prompt, response int
+		want             float64
+	}{
+		{"free", Pricing{}, 5000, 1000, 0},
+		{"prompt only", Pricing{PromptPer1K: 0.5}, 2000, 1000, 1},
+		{"response only", Pricing{ResponsePer1K: 2}, 2000, 500, 1},
+		{"both", Pricing{PromptPer1K: 0.01, ResponsePer1K: 0.03}, 1500, 250, 0.0225},
+		{"no tokens", Pricing{PromptPer1K: 1, ResponsePer1K: 1}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pricing.Cost(tt.prompt, tt.response); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Cost(%d, %d) = %v, want %v", tt.prompt, tt.response, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"up{job=\"api\"} 1", 4},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestProjectMonthly(t *testing.T) {
+	tests := []struct {
+		spent, days, want float64
+	}{
+		{7, 7, 30},
+		{1, 1, 30},
+		{2.5, 30, 2.5},
+		{5, 0, 0},
+		{5, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := ProjectMonthly(tt.spent, tt.days); got != tt.want {
+			t.Errorf("ProjectMonthly(%v, %v) = %v, want %v", tt.spent, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestPricingFromEnv(t *testing.T) {
+	tests := []struct {
+		prompt, response string
+		want             Pricing
+	}{
+		{"0.01", "0.03", Pricing{PromptPer1K: 0.01, ResponsePer1K: 0.03}},
+		{"", "", Pricing{}},
+		{"cheap", "-1", Pricing{}},
+	}
+	for _, tt := range tests {
+		t.Setenv("COST_PROMPT_PER_1K_TOKENS", tt.prompt)
+		t.Setenv("COST_RESPONSE_PER_1K_TOKENS", tt.response)
+		if got := PricingFromEnv(); got != tt.want {
+			t.Errorf("PricingFromEnv with %q, %q = %+v, want %+v", tt.prompt, tt.response, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// ABOUTME: Dogfooding tests - our showcase examples and our own /metrics must pass the static rule engine
+// ABOUTME: Catches a rule change that starts flagging what we show as good or stops flagging what we show as poor
+
+package dogfood
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wbollock/good_telemetry/internal/examples"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+)
+
+func TestGoodExamplesHaveNoErrors(t *testing.T) {
+	for _, e := range examplesWithVerdict(t, "Good") {
+		t.Run(e.ID, func(t *testing.T) {
+			findings := check(t, e.Metrics)
+			if errs := withSeverity(findings, rules.SeverityError); len(errs) > 0 {
+				t.Errorf("Good example has error findings %v; all findings: %v", errs, codes(findings))
+			}
+		})
+	}
+}
+
+func TestPoorExamplesHaveErrors(t *testing.T) {
+	for _, e := range examplesWithVerdict(t, "Poor") {
+		t.Run(e.ID, func(t *testing.T) {
+			findings := check(t, e.Metrics)
+			if len(withSeverity(findings, rules.SeverityError)) == 0 {
+				t.Errorf("Poor example has no error findings; all findings: %v", codes(findings))
+			}
+		})
+	}
+}
+
+func TestSelfMetricsHaveNoErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	selfmetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics returned %d", rec.Code)
+	}
+	exposition := rec.Body.String()
+
+	// Vectors without children yet are registered but not exposed, so their
+	// names come from Describe alone
+	described := describedMetrics()
+	if len(described) == 0 {
+		t.Fatal("the registry describes no metrics")
+	}
+	exposed := exposedNames(t, exposition)
+	for _, d := range described {
+		if !slices.Contains(exposed, d.name) {
+			exposition += "# HELP " + d.name + " " + d.help + "\n" + d.name + " 0\n"
+		}
+	}
+
+	parsed := parse(t, exposition)
+	for _, d := range described {
+		if !slices.Contains(familyNames(parsed), d.name) {
+			t.Errorf("described metric %s is missing from the checked exposition", d.name)
+		}
+	}
+	findings := rules.Problems(profile(t).Check(parsed))
+	if errs := withSeverity(findings, rules.SeverityError); len(errs) > 0 {
+		t.Errorf("self-metrics have error findings %v; all findings: %v", errs, codes(findings))
+	}
+}
+
+type describedMetric struct {
+	name, help string
+}
+
+// Desc has no accessors; its String form quotes the name and help
+var descRegex = regexp.MustCompile(`fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*")`)
+
+// describedMetrics collects every metric the self-metrics registry describes
+func describedMetrics() []describedMetric {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		selfmetrics.Registry.Describe(ch)
+		close(ch)
+	}()
+	var described []describedMetric
+	for desc := range ch {
+		m := descRegex.FindStringSubmatch(desc.String())
+		if m == nil {
+			continue
+		}
+		name, _ := strconv.Unquote(m[1])
+		help, _ := strconv.Unquote(m[2])
+		described = append(described, describedMetric{name: name, help: help})
+	}
+	return described
+}
+
+// exposedNames lists the families with # TYPE lines in exposition
+func exposedNames(t *testing.T, exposition string) []string {
+	t.Helper()
+	var names []string
+	for _, line := range strings.Split(exposition, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[0] == "#" && fields[1] == "TYPE" {
+			names = append(names, fields[2])
+		}
+	}
+	return names
+}
+
+// familyNames lists the families parsed has samples for, by their declared names
+func familyNames(parsed *metrics.ParsedMetrics) []string {
+	var names []string
+	for _, m := range parsed.Metrics {
+		names = append(names, m.Name)
+	}
+	for name := range parsed.Types {
+		names = append(names, name)
+	}
+	return names
+}
+
+func examplesWithVerdict(t *testing.T, verdict string) []examples.Example {
+	t.Helper()
+	var found []examples.Example
+	for _, e := range examples.All() {
+		if e.Verdict == verdict {
+			found = append(found, e)
+		}
+	}
+	if len(found) == 0 {
+		t.Fatalf("the example store has no %s examples", verdict)
+	}
+	return found
+}
+
+func profile(t *testing.T) rules.NamingProfile {
+	t.Helper()
+	p, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func parse(t *testing.T, input string) *metrics.ParsedMetrics {
+	t.Helper()
+	parsed, err := profile(t).Parse(input)
+	if err != nil {
+		t.Fatalf("parsing: %v\n%s", err, input)
+	}
+	return parsed
+}
+
+// check runs the static engine over input, praise left out
+func check(t *testing.T, input string) []rules.Finding {
+	t.Helper()
+	return rules.Problems(profile(t).Check(parse(t, input)))
+}
+
+func withSeverity(findings []rules.Finding, severity rules.Severity) []string {
+	var matched []rules.Finding
+	for _, f := range findings {
+		if f.Severity == severity {
+			matched = append(matched, f)
+		}
+	}
+	return codes(matched)
+}
+
+// codes describes findings as "severity code (metric)" for failure messages
+func codes(findings []rules.Finding) []string {
+	described := make([]string, len(findings))
+	for i, f := range findings {
+		described[i] = string(f.Severity) + " " + f.Code + " (" + f.Metric + ")"
+	}
+	return described
+}
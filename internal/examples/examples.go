@@ -0,0 +1,97 @@
+// ABOUTME: Showcase example store - curated good and bad metrics with their expected evaluations
+// ABOUTME: Served on the examples page and available to anything that needs known-verdict inputs
+
+package examples
+
+// All returns the hardcoded showcase examples
+func All() []Example {
+	return []Example{
+		{
+			ID:      "http-requests",
+			Metrics: `http_requests_total{method="GET", handler="/api/users", status="200"} 1027`,
+			Verdict: "Good",
+			Issues:  []string{},
+			Recommendations: []string{
+				"This is a well-structured counter metric",
+				"Uses appropriate _total suffix",
+				"Labels are low-cardinality and meaningful",
+			},
+			CardinalityEstimate: "Low (3 methods × ~10 handlers × 5 status codes = ~150 series)",
+			MemoryEstimate:      "~3KB RAM per series = ~450KB total",
+		},
+		{
+			ID:      "api-response-time",
+			Metrics: `api_response_time{user_id="12345", endpoint="/profile"} 0.234`,
+			Verdict: "Needs Improvement",
+			Issues: []string{
+				"user_id is unbounded high-cardinality label",
+				"Missing _seconds suffix for time measurement",
+				"Should be a histogram, not gauge",
+			},
+			Recommendations: []string{
+				"Remove user_id label - use it in logs instead",
+				"Rename to api_response_duration_seconds",
+				"Convert to histogram for percentile calculations",
+			},
+			CardinalityEstimate: "CRITICAL: Unbounded (1 series per user × endpoints = potentially millions)",
+			MemoryEstimate:      "Could easily exceed 10GB+ with 100k users",
+		},
+		{
+			ID:      "cache-hit-ratio",
+			Metrics: `cache_hit_ratio 0.87`,
+			Verdict: "Needs Improvement",
+			Issues: []string{
+				"Ratio should be calculated in queries, not stored as metric",
+				"Missing labels to identify which cache",
+			},
+			Recommendations: []string{
+				"Store cache_hits_total and cache_misses_total instead",
+				"Add cache_name label",
+				"Calculate ratio: cache_hits_total / (cache_hits_total + cache_misses_total)",
+			},
+			CardinalityEstimate: "N/A - antipattern",
+			MemoryEstimate:      "N/A",
+		},
+		{
+			ID:      "volume-attachment",
+			Metrics: `volume_attachment{vol="vol-abc123", inode="1048576", timestamp="1729783200", cluster="prod-east"} 1`,
+			Verdict: "Poor",
+			Issues: []string{
+				"vol label creates series per volume (2566+ unique values)",
+				"inode label is extremely high-cardinality (529+ unique values)",
+				"timestamp as label is a cardinal sin - creates infinite series",
+				"Combines multiple unbounded labels = cardinality explosion",
+			},
+			Recommendations: []string{
+				"Remove vol label - aggregate at pool/cluster level instead",
+				"Remove inode completely - use logs for per-inode tracking",
+				"NEVER use timestamp as a label - Prometheus already timestamps samples",
+				"Keep only cluster/pool labels for aggregation",
+				"Real example: 2566 vols × 529 inodes × 1606 timestamps = 2.18 BILLION series",
+			},
+			CardinalityEstimate: "CATASTROPHIC: 2.18+ billion potential series (2566 vol × 529 inode × 1606 timestamp)",
+			MemoryEstimate:      "6.5+ TB RAM required (likely to crash Prometheus entirely)",
+		},
+	}
+}
+
+// Get returns the showcase example with the given ID
+func Get(id string) (Example, bool) {
+	for _, e := range All() {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Example{}, false
+}
+
+type Example struct {
+	// Stable across releases, used in URLs
+	ID                  string
+	Metrics             string
+	Verdict             string
+	Issues              []string
+	Recommendations     []string
+	CardinalityEstimate string
+	MemoryEstimate      string
+}
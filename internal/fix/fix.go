@@ -0,0 +1,194 @@
+// ABOUTME: Quick-fix edits - the minimal text changes that resolve one static finding, for editor integrations
+// ABOUTME: Covers renames, TYPE lines and label removals, with byte offsets into the submitted text
+
+package fix
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// ErrNoFix is wrapped by every error for a finding that can't be fixed
+// mechanically, including issues only the LLM raises
+var ErrNoFix = errors.New("no deterministic fix")
+
+// Edit replaces the bytes from Start up to End of the original text;
+// Start equal to End inserts
+type Edit struct {
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Replacement string `json:"replacement"`
+}
+
+// Target picks the finding to fix. Metric and Label narrow it down when the
+// code is raised more than once.
+type Target struct {
+	Code   string
+	Metric string
+	Label  string
+}
+
+type fixer func(doc *document, parsed *metrics.ParsedMetrics, f rules.Finding, t Target) ([]Edit, error)
+
+var fixers = map[string]fixer{
+	"camel-case":             fixCamelCase,
+	"type-mismatch":          fixTypeMismatch,
+	"type-missing":           fixTypeMissing,
+	"high-cardinality-label": fixHighCardinalityLabel,
+}
+
+// Fix returns the edits that resolve the targeted finding in input, in text
+// order, and input with them applied
+func Fix(input string, profile rules.NamingProfile, t Target) ([]Edit, string, error) {
+	parsed, err := profile.Parse(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var finding *rules.Finding
+	for _, f := range rules.Problems(profile.Check(parsed)) {
+		if f.Code == t.Code && (t.Metric == "" || f.Metric == "" || f.Metric == t.Metric) {
+			finding = &f
+			break
+		}
+	}
+
+	fix, ok := fixers[t.Code]
+	switch {
+	case finding == nil && !ok:
+		return nil, "", fmt.Errorf("%w: the static checks raise no %s finding for this input; issues found by the LLM have to be fixed by hand", ErrNoFix, t.Code)
+	case finding == nil:
+		return nil, "", fmt.Errorf("%w: the input has no %s finding%s", ErrNoFix, t.Code, forMetric(t.Metric))
+	case !ok:
+		return nil, "", fmt.Errorf("%w: %s findings need a judgement call; supported codes are %s", ErrNoFix, t.Code, strings.Join(Codes(), ", "))
+	}
+
+	edits, err := fix(scan(input), parsed, *finding, t)
+	if err != nil {
+		return nil, "", err
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+
+	var sb strings.Builder
+	last := 0
+	for _, e := range edits {
+		sb.WriteString(input[last:e.Start])
+		sb.WriteString(e.Replacement)
+		last = e.End
+	}
+	sb.WriteString(input[last:])
+	return edits, sb.String(), nil
+}
+
+// Codes lists the finding codes Fix can resolve
+func Codes() []string {
+	codes := make([]string, 0, len(fixers))
+	for code := range fixers {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+func forMetric(metric string) string {
+	if metric == "" {
+		return ""
+	}
+	return " for " + metric
+}
+
+func fixCamelCase(doc *document, _ *metrics.ParsedMetrics, f rules.Finding, _ Target) ([]Edit, error) {
+	return doc.rename(func(name string) string {
+		if name == f.Metric {
+			return naming.ToSnakeCase(name)
+		}
+		return name
+	}), nil
+}
+
+// fixTypeMismatch trusts the # TYPE declaration and renames the samples to
+// match, as the improved example does
+func fixTypeMismatch(doc *document, parsed *metrics.ParsedMetrics, f rules.Finding, _ Target) ([]Edit, error) {
+	family := f.Metric
+	switch typ := parsed.Types[family]; {
+	case typ == "counter" && !strings.HasSuffix(family, "_total"):
+		return doc.rename(func(name string) string {
+			if name == family {
+				return name + "_total"
+			}
+			return name
+		}), nil
+	case typ == "gauge" && strings.HasSuffix(family, "_total"):
+		return doc.rename(func(name string) string {
+			if name == family {
+				return strings.TrimSuffix(name, "_total")
+			}
+			return name
+		}), nil
+	case typ == "summary":
+		var edits []Edit
+		for _, l := range doc.types {
+			if l.name == family {
+				edits = append(edits, Edit{Start: l.typeStart, End: l.typeStart + len(l.typ), Replacement: "histogram"})
+			}
+		}
+		for _, s := range doc.samples {
+			if s.name == family && s.label("le") != nil {
+				edits = append(edits, Edit{Start: s.start, End: s.start + len(s.name), Replacement: family + "_bucket"})
+			}
+		}
+		return edits, nil
+	}
+	return nil, fmt.Errorf("%w: %s needs the missing histogram series added by hand", ErrNoFix, family)
+}
+
+// fixTypeMissing inserts the TYPE line the finding suggests before the family's first sample
+func fixTypeMissing(doc *document, parsed *metrics.ParsedMetrics, f rules.Finding, _ Target) ([]Edit, error) {
+	for _, s := range doc.samples {
+		if s.name != f.Metric && strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(s.name, "_bucket"), "_sum"), "_count") != f.Metric {
+			continue
+		}
+		typ := parsed.TypeOf(s.name)
+		if typ == "untyped" {
+			return nil, fmt.Errorf("%w: the type of %s can't be told from its samples", ErrNoFix, f.Metric)
+		}
+		return []Edit{{Start: s.lineStart, End: s.lineStart, Replacement: fmt.Sprintf("# TYPE %s %s\n", f.Metric, typ)}}, nil
+	}
+	return nil, fmt.Errorf("%w: no samples of %s found", ErrNoFix, f.Metric)
+}
+
+// fixHighCardinalityLabel removes the label from every sample carrying it
+func fixHighCardinalityLabel(doc *document, parsed *metrics.ParsedMetrics, _ rules.Finding, t Target) ([]Edit, error) {
+	var candidates []string
+	for name, info := range parsed.CardinalityAnalysis.LabelAnalysis {
+		if info.IsHighCardinality {
+			candidates = append(candidates, name)
+		}
+	}
+	slices.Sort(candidates)
+
+	label := t.Label
+	switch {
+	case label == "" && len(candidates) == 1:
+		label = candidates[0]
+	case label == "":
+		return nil, fmt.Errorf("%w: choose the label to remove, one of %s", ErrNoFix, strings.Join(candidates, ", "))
+	case !slices.Contains(candidates, label):
+		return nil, fmt.Errorf("%w: %s is not a high-cardinality label here; those are %s", ErrNoFix, label, strings.Join(candidates, ", "))
+	}
+
+	var edits []Edit
+	for _, s := range doc.samples {
+		if e, ok := s.removeLabel(label); ok {
+			edits = append(edits, e)
+		}
+	}
+	return edits, nil
+}
@@ -0,0 +1,114 @@
+// ABOUTME: Tests for quick-fix edits - each supported code on inputs with multi-byte characters before the edit
+// ABOUTME: Offsets must land on the exact bytes, the fixed text must lose the finding, and other codes are refused
+
+package fix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		target Target
+		// The original bytes each edit replaces, in order
+		replaced []string
+		want     string
+	}{
+		{
+			name:     "camel case",
+			input:    "# HELP checkoutRequests Requests to the café ☕ checkout.\n# TYPE checkoutRequests counter\ncheckoutRequests{shop=\"Zürich 🇨🇭\"} 3\n",
+			target:   Target{Code: "camel-case"},
+			replaced: []string{"checkoutRequests", "checkoutRequests", "checkoutRequests"},
+			want:     "# HELP checkout_requests Requests to the café ☕ checkout.\n# TYPE checkout_requests counter\ncheckout_requests{shop=\"Zürich 🇨🇭\"} 3\n",
+		},
+		{
+			name:     "counter without _total",
+			input:    "# HELP orders Orders placed — über alles.\n# TYPE orders counter\norders{city=\"東京\"} 5\norders{city=\"Köln\"} 2\n",
+			target:   Target{Code: "type-mismatch", Metric: "orders"},
+			replaced: []string{"orders", "orders", "orders", "orders"},
+			want:     "# HELP orders_total Orders placed — über alles.\n# TYPE orders_total counter\norders_total{city=\"東京\"} 5\norders_total{city=\"Köln\"} 2\n",
+		},
+		{
+			name:     "missing TYPE",
+			input:    "# HELP jobs_total Jobs run, ünïcödé.\njobs_total{queue=\"naïve\"} 4\n",
+			target:   Target{Code: "type-missing", Metric: "jobs_total"},
+			replaced: []string{""},
+			want:     "# HELP jobs_total Jobs run, ünïcödé.\n# TYPE jobs_total counter\njobs_total{queue=\"naïve\"} 4\n",
+		},
+		{
+			name: "high-cardinality label",
+			input: "# HELP logins_total Logins.\n# TYPE logins_total counter\n" +
+				"logins_total{user_id=\"ü-1\",region=\"eu\"} 1\nlogins_total{region=\"eu\",user_id=\"ü-2\"} 1\nlogins_total{user_id=\"ü-3\"} 1\n",
+			target:   Target{Code: "high-cardinality-label", Label: "user_id"},
+			replaced: []string{`user_id="ü-1",`, `,user_id="ü-2"`, `{user_id="ü-3"}`},
+			want: "# HELP logins_total Logins.\n# TYPE logins_total counter\n" +
+				"logins_total{region=\"eu\"} 1\nlogins_total{region=\"eu\"} 1\nlogins_total 1\n",
+		},
+	}
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits, text, err := Fix(tt.input, profile, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tt.want {
+				t.Errorf("text =\n%s\nwant\n%s", text, tt.want)
+			}
+			if len(edits) != len(tt.replaced) {
+				t.Fatalf("edits = %+v, want %d", edits, len(tt.replaced))
+			}
+			for i, e := range edits {
+				if got := tt.input[e.Start:e.End]; got != tt.replaced[i] {
+					t.Errorf("edit %d replaces %q at %d-%d, want %q", i, got, e.Start, e.End, tt.replaced[i])
+				}
+			}
+
+			// The fixed text no longer raises the finding
+			parsed, err := profile.Parse(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range rules.Problems(profile.Check(parsed)) {
+				if f.Code == tt.target.Code && (tt.target.Metric == "" || f.Metric == tt.target.Metric) {
+					t.Errorf("still raised: %s", f.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestFixRefusesWhatItCannotFix(t *testing.T) {
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "# HELP logins_total Logins.\n# TYPE logins_total counter\nlogins_total{user_id=\"1\",session_id=\"a\"} 1\nlogins_total{user_id=\"2\",session_id=\"b\"} 1\n"
+	tests := []struct {
+		name   string
+		target Target
+		want   string
+	}{
+		{"issue only the LLM raises", Target{Code: "vague-description"}, "issues found by the LLM have to be fixed by hand"},
+		{"code not raised here", Target{Code: "camel-case"}, "the input has no camel-case finding"},
+		{"several labels to choose from", Target{Code: "high-cardinality-label"}, "choose the label to remove, one of session_id, user_id"},
+		{"label that isn't high-cardinality", Target{Code: "high-cardinality-label", Label: "region"}, "region is not a high-cardinality label here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Fix(input, profile, tt.target)
+			if !errors.Is(err, ErrNoFix) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Fix = %v, want ErrNoFix saying %q", err, tt.want)
+			}
+		})
+	}
+}
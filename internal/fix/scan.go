@@ -0,0 +1,198 @@
+// ABOUTME: Position-keeping scan of exposition text - where each name, label pair and TYPE sits in the input
+// ABOUTME: Offsets are bytes into the original text, so multi-byte label values don't shift later positions
+
+package fix
+
+import (
+	"strings"
+)
+
+type document struct {
+	samples []sample
+	// # HELP and # TYPE lines
+	metadata []metadata
+	types    []metadata
+}
+
+type sample struct {
+	lineStart int
+	// Offset of the name, which starts the sample
+	start int
+	name  string
+	// Offsets of { and }, or -1 when the sample has no label set
+	open, close int
+	labels      []labelPair
+}
+
+// labelPair spans name="value", without surrounding commas or spaces
+type labelPair struct {
+	name       string
+	start, end int
+}
+
+type metadata struct {
+	name      string
+	nameStart int
+	// TYPE lines only
+	typ       string
+	typeStart int
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || b == ':' || b == '.' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r'
+}
+
+// scan finds the samples and metadata lines of input
+func scan(input string) *document {
+	doc := &document{}
+	lineStart := 0
+	for lineStart <= len(input) {
+		lineEnd := strings.IndexByte(input[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(input)
+		} else {
+			lineEnd += lineStart
+		}
+		doc.scanLine(input, lineStart, lineEnd)
+		lineStart = lineEnd + 1
+	}
+	return doc
+}
+
+func (doc *document) scanLine(input string, lineStart, lineEnd int) {
+	i := skipSpaces(input, lineStart, lineEnd)
+	if i == lineEnd {
+		return
+	}
+
+	if input[i] == '#' {
+		i = skipSpaces(input, i+1, lineEnd)
+		keyword, i := word(input, i, lineEnd)
+		if keyword != "HELP" && keyword != "TYPE" {
+			return
+		}
+		nameStart := skipSpaces(input, i, lineEnd)
+		name, i := word(input, nameStart, lineEnd)
+		m := metadata{name: name, nameStart: nameStart}
+		if keyword == "HELP" {
+			doc.metadata = append(doc.metadata, m)
+			return
+		}
+		m.typeStart = skipSpaces(input, i, lineEnd)
+		m.typ, _ = word(input, m.typeStart, lineEnd)
+		doc.metadata = append(doc.metadata, m)
+		doc.types = append(doc.types, m)
+		return
+	}
+
+	s := sample{lineStart: lineStart, start: i, open: -1, close: -1}
+	for i < lineEnd && isNameByte(input[i]) {
+		i++
+	}
+	s.name = input[s.start:i]
+	if s.name == "" {
+		return
+	}
+	if i < lineEnd && input[i] == '{' {
+		s.open = i
+		s.labels, s.close = scanLabels(input, i+1, lineEnd)
+	}
+	doc.samples = append(doc.samples, s)
+}
+
+// scanLabels reads label pairs up to the closing brace, returning its offset
+func scanLabels(input string, i, lineEnd int) ([]labelPair, int) {
+	var pairs []labelPair
+	for i < lineEnd {
+		for i < lineEnd && (isSpace(input[i]) || input[i] == ',') {
+			i++
+		}
+		if i >= lineEnd || input[i] == '}' {
+			return pairs, i
+		}
+
+		pair := labelPair{start: i}
+		eq := strings.IndexByte(input[i:lineEnd], '=')
+		if eq < 0 {
+			return pairs, lineEnd
+		}
+		pair.name = strings.TrimSpace(input[i : i+eq])
+		i = skipSpaces(input, i+eq+1, lineEnd)
+		if i < lineEnd && input[i] == '"' {
+			// '"' and '\' never occur inside a multi-byte UTF-8 sequence
+			for i++; i < lineEnd && input[i] != '"'; i++ {
+				if input[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		}
+		pair.end = min(i, lineEnd)
+		pairs = append(pairs, pair)
+	}
+	return pairs, lineEnd
+}
+
+func skipSpaces(input string, i, end int) int {
+	for i < end && isSpace(input[i]) {
+		i++
+	}
+	return i
+}
+
+// word reads up to the next space
+func word(input string, i, end int) (string, int) {
+	start := i
+	for i < end && !isSpace(input[i]) {
+		i++
+	}
+	return input[start:i], i
+}
+
+func (s *sample) label(name string) *labelPair {
+	for i := range s.labels {
+		if s.labels[i].name == name {
+			return &s.labels[i]
+		}
+	}
+	return nil
+}
+
+// removeLabel deletes a pair and the comma separating it from its neighbour,
+// or the whole label set when it was the only pair
+func (s *sample) removeLabel(name string) (Edit, bool) {
+	for i, pair := range s.labels {
+		if pair.name != name {
+			continue
+		}
+		switch {
+		case len(s.labels) == 1:
+			return Edit{Start: s.open, End: s.close + 1}, true
+		case i < len(s.labels)-1:
+			return Edit{Start: pair.start, End: s.labels[i+1].start}, true
+		default:
+			return Edit{Start: s.labels[i-1].end, End: pair.end}, true
+		}
+	}
+	return Edit{}, false
+}
+
+// rename edits every sample and metadata name that rename changes
+func (doc *document) rename(rename func(string) string) []Edit {
+	var edits []Edit
+	for _, s := range doc.samples {
+		if renamed := rename(s.name); renamed != s.name {
+			edits = append(edits, Edit{Start: s.start, End: s.start + len(s.name), Replacement: renamed})
+		}
+	}
+	for _, m := range doc.metadata {
+		if renamed := rename(m.name); renamed != m.name {
+			edits = append(edits, Edit{Start: m.nameStart, End: m.nameStart + len(m.name), Replacement: renamed})
+		}
+	}
+	return edits
+}
@@ -0,0 +1,75 @@
+// ABOUTME: DogStatsD reader that keeps names and tags exactly as written
+// ABOUTME: Used to judge Datadog conventions, where Convert would rewrite them to Prometheus style
+
+package formats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// DogStatsD metric types and the Prometheus type closest to each
+var dogStatsDTypes = map[string]string{
+	"c":  "counter",
+	"g":  "gauge",
+	"ms": "histogram",
+	"h":  "histogram",
+	"d":  "histogram",
+	"s":  "gauge",
+}
+
+// ParseDogStatsD reads DogStatsD lines without converting them. Tags become
+// labels, with an empty value for a bare tag that has no colon.
+func ParseDogStatsD(input string) (*metrics.ParsedMetrics, error) {
+	parsed := &metrics.ParsedMetrics{
+		Help:  make(map[string]string),
+		Types: make(map[string]string),
+	}
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sections := strings.Split(line, "|")
+		sep := strings.LastIndex(sections[0], ":")
+		if len(sections) < 2 || sep <= 0 {
+			return nil, fmt.Errorf("line %d: invalid dogstatsd format: %s", i+1, line)
+		}
+		typ, ok := dogStatsDTypes[sections[1]]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unsupported dogstatsd metric type %q", i+1, sections[1])
+		}
+
+		m := metrics.Metric{
+			Name:   sections[0][:sep],
+			Value:  sections[0][sep+1:],
+			Labels: make(map[string]string),
+			Raw:    line,
+		}
+		for _, section := range sections[2:] {
+			if !strings.HasPrefix(section, "#") {
+				continue
+			}
+			for _, tag := range strings.Split(section[1:], ",") {
+				if tag != "" {
+					key, value, _ := strings.Cut(tag, ":")
+					m.Labels[key] = value
+				}
+			}
+		}
+
+		parsed.Metrics = append(parsed.Metrics, m)
+		parsed.Types[m.Name] = typ
+	}
+
+	if len(parsed.Metrics) == 0 {
+		return nil, fmt.Errorf("no valid metrics found")
+	}
+	parsed.Reanalyze(cardinality.DefaultThresholds)
+	return parsed, nil
+}
@@ -0,0 +1,142 @@
+// ABOUTME: Metric format conversion - turns StatsD, DogStatsD, InfluxDB and OpenMetrics input into Prometheus metrics
+// ABOUTME: Detects the input format, normalizes names to Prometheus conventions and collects lossy-conversion warnings
+
+package formats
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/naming"
+)
+
+type Format string
+
+const (
+	StatsD      Format = "statsd"
+	DogStatsD   Format = "dogstatsd"
+	InfluxDB    Format = "influxdb"
+	OpenMetrics Format = "openmetrics"
+)
+
+// Converted holds the result of a conversion: samples ready for the Prometheus
+// text writer, the TYPE and HELP of each family, and anything that could not be preserved
+type Converted struct {
+	Metrics  []metrics.Metric
+	Types    map[string]string
+	Help     map[string]string
+	Warnings []string
+}
+
+var (
+	// Matches: name:value|type with optional |@rate and |#tags sections
+	statsdLineRegex = regexp.MustCompile(`^[^\s:|]+:[^|\s]+\|(c|g|ms|h|s|d)(\||$)`)
+	// Matches: measurement[,tag=value...] field=value[,field=value...] [timestamp]
+	influxLineRegex = regexp.MustCompile(`^[^\s,]+(,\S+)?\s+[^\s=]+=\S+`)
+
+	invalidNameCharRegex    = regexp.MustCompile(`[^a-zA-Z0-9_:]+`)
+	repeatedUnderscoreRegex = regexp.MustCompile(`_{2,}`)
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case StatsD, DogStatsD, InfluxDB, OpenMetrics:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported input format %q (expected statsd, dogstatsd, influxdb or openmetrics)", s)
+}
+
+// Detect guesses the input format from the first non-comment line
+func Detect(input string) (Format, error) {
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			return OpenMetrics, nil
+		}
+
+		if statsdLineRegex.MatchString(line) {
+			if strings.Contains(line, "|#") {
+				return DogStatsD, nil
+			}
+			return StatsD, nil
+		}
+		sample, _ := stripExemplar(line)
+		sample, _ = stripTimestamp(sample)
+		if _, err := metrics.ParseLine(sample); err == nil {
+			return OpenMetrics, nil
+		}
+		if influxLineRegex.MatchString(line) {
+			return InfluxDB, nil
+		}
+		return "", fmt.Errorf("could not detect input format from line: %s", line)
+	}
+	return "", fmt.Errorf("no input to convert")
+}
+
+// Options adjust how strictly input is read
+type Options struct {
+	// Reject OpenMetrics input that doesn't end with # EOF instead of warning
+	StrictOpenMetrics bool
+}
+
+func Convert(input string, from Format, opts Options) (*Converted, error) {
+	var (
+		result *Converted
+		err    error
+	)
+
+	switch from {
+	case StatsD, DogStatsD:
+		result, err = convertStatsD(input)
+	case InfluxDB:
+		result, err = convertInfluxDB(input)
+	case OpenMetrics:
+		result, err = convertOpenMetrics(input, opts.StrictOpenMetrics)
+	default:
+		return nil, fmt.Errorf("unsupported input format %q", from)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Metrics) == 0 {
+		return nil, fmt.Errorf("no valid metrics found")
+	}
+	return result, nil
+}
+
+// normalizeName converts a foreign metric or label name to Prometheus snake_case:
+// camelCase and acronym boundaries become underscores, dots/dashes/other invalid
+// characters become underscores, and a leading digit gets an underscore prefix
+func normalizeName(name string) string {
+	name = naming.ToSnakeCase(name)
+	name = invalidNameCharRegex.ReplaceAllString(name, "_")
+	name = repeatedUnderscoreRegex.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// normalizeLabelName is normalizeName without colons, which are not valid in label names
+func normalizeLabelName(name string) string {
+	return normalizeName(strings.ReplaceAll(name, ":", "_"))
+}
+
+// newMetric builds a Metric whose Raw field matches the canonical exposition line
+func newMetric(name string, labels map[string]string, value string) metrics.Metric {
+	m := metrics.Metric{
+		Name:   name,
+		Labels: labels,
+		Value:  value,
+	}
+	m.Raw = metrics.FormatSample(m)
+	return m
+}
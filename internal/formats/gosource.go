@@ -0,0 +1,291 @@
+// ABOUTME: Go source reader - finds client_golang metric definitions with go/ast
+// ABOUTME: Turns NewCounterVec/NewGaugeVec/NewHistogramVec (and friends) calls into the series they expose
+
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// client_golang constructors and the metric type each creates. Matched by
+// function name, so prometheus.X, promauto.X and promauto.With(reg).X all count.
+var goConstructors = map[string]string{
+	"NewCounter":      "counter",
+	"NewCounterVec":   "counter",
+	"NewGauge":        "gauge",
+	"NewGaugeVec":     "gauge",
+	"NewHistogram":    "histogram",
+	"NewHistogramVec": "histogram",
+	"NewSummary":      "summary",
+	"NewSummaryVec":   "summary",
+}
+
+// goDefinition is one metric defined in Go source
+type goDefinition struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	// ConstLabels, whose values are known
+	constLabels map[string]string
+}
+
+// ErrNoGoDefinitions is returned for Go source that defines no metrics
+var ErrNoGoDefinitions = errors.New("no client_golang metric definitions found")
+
+// ParseGoSource extracts the metrics defined in a Go file. Each definition
+// becomes the series client_golang exposes for it: histograms get a +Inf
+// bucket, _sum and _count. Label values aren't known from source, so
+// variable labels hold a <label> placeholder.
+func ParseGoSource(src string) ([]metrics.Metric, error) {
+	parsed, err := ParseGoSourceMetrics(src)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Metrics, nil
+}
+
+// ParseGoSourceMetrics is ParseGoSource with each definition's Help and TYPE kept
+func ParseGoSourceMetrics(src string) (*metrics.ParsedMetrics, error) {
+	defs, err := goDefinitions(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(defs) == 0 {
+		return nil, ErrNoGoDefinitions
+	}
+
+	parsed := &metrics.ParsedMetrics{
+		Help:  make(map[string]string),
+		Types: make(map[string]string),
+	}
+	for _, d := range defs {
+		labels := make(map[string]string, len(d.labels)+len(d.constLabels))
+		for k, v := range d.constLabels {
+			labels[k] = v
+		}
+		for _, l := range d.labels {
+			labels[l] = "<" + l + ">"
+		}
+
+		switch d.typ {
+		case "histogram":
+			bucket := make(map[string]string, len(labels)+1)
+			for k, v := range labels {
+				bucket[k] = v
+			}
+			bucket["le"] = "+Inf"
+			parsed.Metrics = append(parsed.Metrics,
+				newMetric(d.name+"_bucket", bucket, "0"),
+				newMetric(d.name+"_sum", labels, "0"),
+				newMetric(d.name+"_count", labels, "0"))
+		case "summary":
+			parsed.Metrics = append(parsed.Metrics,
+				newMetric(d.name+"_sum", labels, "0"),
+				newMetric(d.name+"_count", labels, "0"))
+		default:
+			parsed.Metrics = append(parsed.Metrics, newMetric(d.name, labels, "0"))
+		}
+		parsed.Types[d.name] = d.typ
+		if d.help != "" {
+			parsed.Help[d.name] = d.help
+		}
+	}
+
+	parsed.Reanalyze(cardinality.DefaultThresholds)
+	return parsed, nil
+}
+
+func goDefinitions(src string) ([]goDefinition, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Go source: %w", err)
+	}
+
+	consts := goStringConsts(file)
+	var defs []goDefinition
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		typ, ok := goConstructors[sel.Sel.Name]
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		def, ok := goOpts(call.Args[0], consts)
+		if !ok {
+			return true
+		}
+		def.typ = typ
+		if strings.HasSuffix(sel.Sel.Name, "Vec") && len(call.Args) > 1 {
+			def.labels = goStringSlice(call.Args[1], consts)
+		}
+		defs = append(defs, def)
+		return true
+	})
+	return defs, nil
+}
+
+// goOpts reads Namespace, Subsystem, Name, Help and ConstLabels from an opts
+// literal, joining the name parts like prometheus.BuildFQName
+func goOpts(expr ast.Expr, consts map[string]string) (goDefinition, bool) {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return goDefinition{}, false
+	}
+
+	var def goDefinition
+	var parts [3]string
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		switch key.Name {
+		case "Namespace":
+			parts[0], _ = goString(kv.Value, consts)
+		case "Subsystem":
+			parts[1], _ = goString(kv.Value, consts)
+		case "Name":
+			parts[2], _ = goString(kv.Value, consts)
+		case "Help":
+			def.help, _ = goString(kv.Value, consts)
+		case "ConstLabels":
+			def.constLabels = goStringMap(kv.Value, consts)
+		}
+	}
+
+	// A name that isn't a literal or constant can't be judged
+	if parts[2] == "" {
+		return goDefinition{}, false
+	}
+	var name []string
+	for _, p := range parts {
+		if p != "" {
+			name = append(name, p)
+		}
+	}
+	def.name = strings.Join(name, "_")
+	return def, true
+}
+
+// goString resolves a string literal, a constant declared in the file, or a
+// concatenation of them
+func goString(expr ast.Expr, consts map[string]string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		s, ok := consts[e.Name]
+		return s, ok
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, okX := goString(e.X, consts)
+		y, okY := goString(e.Y, consts)
+		return x + y, okX && okY
+	case *ast.ParenExpr:
+		return goString(e.X, consts)
+	}
+	return "", false
+}
+
+func goStringSlice(expr ast.Expr, consts map[string]string) []string {
+	if id, ok := expr.(*ast.Ident); ok && id.Obj != nil {
+		// A package-level var holding the label names
+		if spec, ok := id.Obj.Decl.(*ast.ValueSpec); ok {
+			for i, n := range spec.Names {
+				if n.Name == id.Name && i < len(spec.Values) {
+					expr = spec.Values[i]
+				}
+			}
+		}
+	}
+
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	var labels []string
+	for _, elt := range lit.Elts {
+		if s, ok := goString(elt, consts); ok {
+			labels = append(labels, s)
+		}
+	}
+	return labels
+}
+
+func goStringMap(expr ast.Expr, consts map[string]string) map[string]string {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, okKey := goString(kv.Key, consts)
+		value, okValue := goString(kv.Value, consts)
+		if okKey && okValue {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// goStringConsts collects the string constants declared anywhere in the file
+func goStringConsts(file *ast.File) map[string]string {
+	consts := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		decl, ok := n.(*ast.GenDecl)
+		if !ok || decl.Tok != token.CONST {
+			return true
+		}
+		for _, spec := range decl.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					continue
+				}
+				if s, ok := goString(vs.Values[i], consts); ok {
+					consts[name.Name] = s
+				}
+			}
+		}
+		return true
+	})
+	return consts
+}
@@ -0,0 +1,155 @@
+// ABOUTME: InfluxDB line protocol parser - maps measurements, tags and fields onto Prometheus samples
+// ABOUTME: Each field becomes its own metric; string fields and timestamps cannot be represented and are dropped
+
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func convertInfluxDB(input string) (*Converted, error) {
+	result := &Converted{Types: make(map[string]string)}
+	droppedTimestamps := false
+	droppedStrings := false
+	convertedBools := false
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sections := splitUnescaped(line, ' ')
+		if len(sections) < 2 || len(sections) > 3 {
+			return nil, fmt.Errorf("line %d: invalid line protocol: %s", i+1, line)
+		}
+		if len(sections) == 3 {
+			droppedTimestamps = true
+		}
+
+		series := splitUnescaped(sections[0], ',')
+		measurement := normalizeName(unescapeInflux(series[0]))
+		labels := make(map[string]string)
+		for _, tag := range series[1:] {
+			key, value, found := cutUnescaped(tag, '=')
+			if !found {
+				return nil, fmt.Errorf("line %d: invalid tag %q", i+1, tag)
+			}
+			labels[normalizeLabelName(unescapeInflux(key))] = unescapeInflux(value)
+		}
+
+		for _, field := range splitUnescaped(sections[1], ',') {
+			key, rawValue, found := cutUnescaped(field, '=')
+			if !found {
+				return nil, fmt.Errorf("line %d: invalid field %q", i+1, field)
+			}
+
+			value, kind, err := parseInfluxFieldValue(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: field %q: %w", i+1, key, err)
+			}
+			switch kind {
+			case "string":
+				droppedStrings = true
+				continue
+			case "bool":
+				convertedBools = true
+			}
+
+			name := measurement
+			if fieldName := normalizeName(unescapeInflux(key)); fieldName != "value" {
+				name = measurement + "_" + fieldName
+			}
+			result.Metrics = append(result.Metrics, newMetric(name, copyLabels(labels), value))
+		}
+	}
+
+	result.Warnings = append(result.Warnings, "InfluxDB fields carry no metric type; output is untyped, add # TYPE lines once you know each metric's type")
+	if droppedTimestamps {
+		result.Warnings = append(result.Warnings, "timestamps were dropped; Prometheus assigns scrape timestamps itself")
+	}
+	if droppedStrings {
+		result.Warnings = append(result.Warnings, "string fields were dropped; Prometheus samples must be numeric")
+	}
+	if convertedBools {
+		result.Warnings = append(result.Warnings, "boolean fields were converted to 1/0")
+	}
+
+	return result, nil
+}
+
+// parseInfluxFieldValue returns the Prometheus sample value for a field and its line protocol kind
+func parseInfluxFieldValue(raw string) (string, string, error) {
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return "1", "bool", nil
+	case "f", "F", "false", "False", "FALSE":
+		return "0", "bool", nil
+	}
+
+	if strings.HasPrefix(raw, `"`) {
+		return "", "string", nil
+	}
+
+	if strings.HasSuffix(raw, "i") || strings.HasSuffix(raw, "u") {
+		v, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid integer value %q", raw)
+		}
+		return strconv.FormatInt(v, 10), "integer", nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid float value %q", raw)
+	}
+	return formatFloat(v), "float", nil
+}
+
+// splitUnescaped splits s on sep, ignoring backslash-escaped separators and
+// separators inside double-quoted strings
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	inQuotes := false
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				if i > start {
+					parts = append(parts, s[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+func cutUnescaped(s string, sep byte) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+var influxUnescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=", `\\`, `\`)
+
+func unescapeInflux(s string) string {
+	return influxUnescaper.Replace(s)
+}
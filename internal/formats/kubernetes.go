@@ -0,0 +1,277 @@
+// ABOUTME: Kubernetes manifest reader - finds the metric and label names operators put in Prometheus Operator resources
+// ABOUTME: Reads PrometheusRule exprs and records, monitor target labels and relabelings, and prometheus.io/port annotations
+
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// Target labels and metric relabelings apply to every series a monitor
+// scrapes, whose names the manifest doesn't say, so they are attached to up
+const scrapedSeries = "up"
+
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		// PrometheusRule
+		Groups []struct {
+			Rules []struct {
+				Record string            `yaml:"record"`
+				Alert  string            `yaml:"alert"`
+				Expr   string            `yaml:"expr"`
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"rules"`
+		} `yaml:"groups"`
+
+		// ServiceMonitor and PodMonitor
+		TargetLabels        []string          `yaml:"targetLabels"`
+		PodTargetLabels     []string          `yaml:"podTargetLabels"`
+		Endpoints           []monitorEndpoint `yaml:"endpoints"`
+		PodMetricsEndpoints []monitorEndpoint `yaml:"podMetricsEndpoints"`
+
+		// Workloads carry scrape annotations on their pod template
+		Template struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type monitorEndpoint struct {
+	MetricRelabelings []struct {
+		Action      string `yaml:"action"`
+		TargetLabel string `yaml:"targetLabel"`
+		Replacement string `yaml:"replacement"`
+	} `yaml:"metricRelabelings"`
+}
+
+// ParseKubernetesYAML extracts metric and label names from a multi-document
+// manifest: metrics referenced or recorded by PrometheusRule rules, and the
+// labels ServiceMonitor/PodMonitor targetLabels and metricRelabelings add,
+// attached to up. prometheus.io/port annotations must name a valid port.
+// Label values come from the manifest where it sets them and are a <label>
+// placeholder otherwise.
+func ParseKubernetesYAML(manifest string) ([]metrics.Metric, error) {
+	var result []metrics.Metric
+	seen := make(map[string]bool)
+	add := func(name string, labels map[string]string) {
+		m := newMetric(name, labels, "0")
+		if !seen[m.Raw] {
+			seen[m.Raw] = true
+			result = append(result, m)
+		}
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for doc := 1; ; doc++ {
+		var obj kubernetesObject
+		err := dec.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", doc, err)
+		}
+		where := fmt.Sprintf("%s/%s", obj.Kind, obj.Metadata.Name)
+
+		for _, annotations := range []map[string]string{obj.Metadata.Annotations, obj.Spec.Template.Metadata.Annotations} {
+			if port, ok := annotations["prometheus.io/port"]; ok {
+				if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+					return nil, fmt.Errorf("%s: prometheus.io/port %q is not a port number", where, port)
+				}
+			}
+		}
+
+		switch obj.Kind {
+		case "PrometheusRule":
+			for _, g := range obj.Spec.Groups {
+				for _, r := range g.Rules {
+					for _, ref := range exprMetrics(r.Expr) {
+						add(ref.Name, ref.Labels)
+					}
+					if r.Record != "" {
+						add(r.Record, r.Labels)
+					}
+				}
+			}
+		case "ServiceMonitor", "PodMonitor":
+			labels := make(map[string]string)
+			for _, l := range append(obj.Spec.TargetLabels, obj.Spec.PodTargetLabels...) {
+				labels[l] = "<" + l + ">"
+			}
+			for _, e := range append(obj.Spec.Endpoints, obj.Spec.PodMetricsEndpoints...) {
+				for _, r := range e.MetricRelabelings {
+					// Only replace (the default) writes targetLabel; internal labels are dropped after relabeling
+					if r.TargetLabel == "" || strings.HasPrefix(r.TargetLabel, "__") || (r.Action != "" && r.Action != "replace") {
+						continue
+					}
+					value := r.Replacement
+					if value == "" || strings.Contains(value, "$") {
+						value = "<" + r.TargetLabel + ">"
+					}
+					labels[r.TargetLabel] = value
+				}
+			}
+			if len(labels) > 0 {
+				add(scrapedSeries, labels)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no metric or label names found in PrometheusRule, ServiceMonitor or PodMonitor resources")
+	}
+	return result, nil
+}
+
+// PromQL words that look like metric names but aren't: operators, modifiers
+// and aggregations
+var promqlKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "bool": true, "offset": true,
+	"by": true, "without": true, "on": true, "ignoring": true,
+	"group_left": true, "group_right": true, "inf": true, "nan": true,
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true,
+	"stdvar": true, "count": true, "count_values": true, "bottomk": true, "topk": true,
+	"quantile": true, "limitk": true, "limit_ratio": true,
+}
+
+// exprMetrics finds the metric selectors in a PromQL expression, keeping
+// the labels of equality matchers
+func exprMetrics(expr string) []metrics.Metric {
+	var found []metrics.Metric
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			i = skipQuoted(expr, i)
+		case c == '[':
+			// Range or subquery duration
+			i = skipTo(expr, i, ']')
+		case c == '{':
+			// {__name__="x"} selector without a bare name
+			end := skipTo(expr, i, '}')
+			labels := parseMatchers(expr[i+1 : end-1])
+			if name, ok := labels["__name__"]; ok {
+				delete(labels, "__name__")
+				found = append(found, metrics.Metric{Name: name, Labels: labels})
+			}
+			i = end
+		case isIdentStart(c):
+			start := i
+			for i < len(expr) && isIdentChar(expr[i]) {
+				i++
+			}
+			word := expr[start:i]
+			j := i
+			for j < len(expr) && (expr[j] == ' ' || expr[j] == '\t' || expr[j] == '\n') {
+				j++
+			}
+
+			switch {
+			case j < len(expr) && expr[j] == '(':
+				// A function call, or a grouping clause whose label list is skipped
+				switch strings.ToLower(word) {
+				case "by", "without", "on", "ignoring", "group_left", "group_right":
+					i = skipTo(expr, j, ')')
+				}
+			case promqlKeywords[strings.ToLower(word)]:
+			case j < len(expr) && expr[j] == '{':
+				end := skipTo(expr, j, '}')
+				found = append(found, metrics.Metric{Name: word, Labels: parseMatchers(expr[j+1 : end-1])})
+				i = end
+			default:
+				found = append(found, metrics.Metric{Name: word, Labels: map[string]string{}})
+			}
+		case c >= '0' && c <= '9':
+			// Numbers and durations such as 5m or 1e3
+			for i < len(expr) && (isIdentChar(expr[i]) || expr[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return found
+}
+
+// parseMatchers reads label="value" matchers; regex and negative matchers
+// say nothing about the values a series carries, so they get a placeholder
+func parseMatchers(s string) map[string]string {
+	labels := make(map[string]string)
+	for _, matcher := range splitUnescaped(s, ',') {
+		matcher = strings.TrimSpace(matcher)
+		if matcher == "" {
+			continue
+		}
+		op := strings.IndexAny(matcher, "=!")
+		if op <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(matcher[:op])
+		rest := strings.TrimLeft(matcher[op:], "=!~")
+		value, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil || !strings.HasPrefix(matcher[op:], "=") || strings.HasPrefix(matcher[op:], "=~") {
+			value = "<" + name + ">"
+		}
+		labels[name] = value
+	}
+	return labels
+}
+
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		if s[i] == '\\' && quote != '`' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// skipTo returns the index after the close matching the bracket at i,
+// ignoring brackets inside quotes
+func skipTo(s string, i int, close byte) int {
+	open := s[i]
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '"', '\'', '`':
+			i = skipQuoted(s, i)
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return len(s)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
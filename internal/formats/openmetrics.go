@@ -0,0 +1,163 @@
+// ABOUTME: OpenMetrics to Prometheus text conversion - rewrites families, types and samples the classic format understands
+// ABOUTME: Drops # EOF, # UNIT, exemplars, timestamps and _created series, warning about anything lossy
+
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// OpenMetrics types without a direct Prometheus text equivalent
+var openMetricsTypeFallbacks = map[string]string{
+	"info":           "gauge",
+	"stateset":       "gauge",
+	"gaugehistogram": "untyped",
+	"unknown":        "untyped",
+}
+
+var openMetricsSampleSuffixes = map[string]string{
+	"counter": "_total",
+	"info":    "_info",
+}
+
+// convertOpenMetrics rewrites OpenMetrics input; strict rejects input that
+// doesn't end with # EOF, which otherwise only warns
+func convertOpenMetrics(input string, strict bool) (*Converted, error) {
+	result := &Converted{
+		Types: make(map[string]string),
+		Help:  make(map[string]string),
+	}
+	familyTypes := make(map[string]string)
+	warned := make(map[string]bool)
+
+	warnOnce := func(key, msg string) {
+		if !warned[key] {
+			warned[key] = true
+			result.Warnings = append(result.Warnings, msg)
+		}
+	}
+
+	if problem := metrics.CheckEOF(input); problem != "" {
+		if strict {
+			return nil, errors.New(problem)
+		}
+		warnOnce("eof", problem)
+	}
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "# EOF" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			family := normalizeName(fields[2])
+
+			switch fields[1] {
+			case "TYPE":
+				if len(fields) < 4 {
+					return nil, fmt.Errorf("line %d: TYPE without a type: %s", i+1, line)
+				}
+				familyTypes[family] = fields[3]
+			case "HELP":
+				result.Help[family] = strings.Join(fields[3:], " ")
+			case "UNIT":
+				warnOnce("unit", "# UNIT metadata was dropped; Prometheus text format has no unit line (keep the unit as a name suffix)")
+			}
+			continue
+		}
+
+		line, hadExemplar := stripExemplar(line)
+		if hadExemplar {
+			warnOnce("exemplar", "exemplars were dropped; they require OpenMetrics exposition to be scraped")
+		}
+
+		line, hadTimestamp := stripTimestamp(line)
+		if hadTimestamp {
+			warnOnce("timestamp", "sample timestamps were dropped; Prometheus assigns scrape timestamps itself")
+		}
+
+		m, err := metrics.ParseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		name := normalizeName(m.Name)
+		if strings.HasSuffix(name, "_created") {
+			warnOnce("created", "_created series were dropped; Prometheus text format does not carry creation timestamps")
+			continue
+		}
+
+		labels := make(map[string]string, len(m.Labels))
+		for k, v := range m.Labels {
+			labels[normalizeLabelName(k)] = v
+		}
+		result.Metrics = append(result.Metrics, newMetric(name, labels, m.Value))
+	}
+
+	families := make([]string, 0, len(familyTypes))
+	for family := range familyTypes {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	for _, family := range families {
+		omType := familyTypes[family]
+		promType := omType
+		if fallback, ok := openMetricsTypeFallbacks[omType]; ok {
+			promType = fallback
+			warnOnce("type:"+omType, fmt.Sprintf("OpenMetrics type %q has no Prometheus text equivalent; emitted as %s", omType, fallback))
+		}
+
+		// OpenMetrics names counter and info families without their sample suffix;
+		// Prometheus text names the family after the sample
+		if suffix, ok := openMetricsSampleSuffixes[omType]; ok && !strings.HasSuffix(family, suffix) {
+			if help, ok := result.Help[family]; ok {
+				delete(result.Help, family)
+				result.Help[family+suffix] = help
+			}
+			family += suffix
+		}
+
+		if promType != "untyped" {
+			result.Types[family] = promType
+		}
+	}
+
+	return result, nil
+}
+
+// stripExemplar removes a trailing "# {...} value" exemplar from a sample line
+func stripExemplar(line string) (string, bool) {
+	if sample, exemplar, found := strings.Cut(line, " # "); found && strings.HasPrefix(exemplar, "{") {
+		return strings.TrimSpace(sample), true
+	}
+	return line, false
+}
+
+// stripTimestamp removes a trailing timestamp after the sample value
+func stripTimestamp(line string) (string, bool) {
+	rest := line
+	prefix := ""
+	if idx := strings.LastIndex(line, "}"); idx >= 0 {
+		prefix, rest = line[:idx+1], line[idx+1:]
+	}
+
+	fields := strings.Fields(rest)
+	if prefix == "" && len(fields) == 3 {
+		return fields[0] + " " + fields[1], true
+	}
+	if prefix != "" && len(fields) == 2 {
+		return prefix + " " + fields[0], true
+	}
+	return line, false
+}
@@ -0,0 +1,193 @@
+// ABOUTME: StatsD and DogStatsD line parser - aggregates counter, gauge, timer and set events into Prometheus samples
+// ABOUTME: Timers become summaries in seconds, sample rates are scaled out and DogStatsD tags become labels
+
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+type statsdSeries struct {
+	name   string
+	labels map[string]string
+	kind   string
+	value  float64
+	count  float64
+	set    map[string]bool
+}
+
+func convertStatsD(input string) (*Converted, error) {
+	result := &Converted{Types: make(map[string]string)}
+	series := make(map[string]*statsdSeries)
+	var order []string
+	warned := make(map[string]bool)
+
+	warnOnce := func(key, msg string) {
+		if !warned[key] {
+			warned[key] = true
+			result.Warnings = append(result.Warnings, msg)
+		}
+	}
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sections := strings.Split(line, "|")
+		if len(sections) < 2 {
+			return nil, fmt.Errorf("line %d: invalid statsd format: %s", i+1, line)
+		}
+
+		sep := strings.LastIndex(sections[0], ":")
+		if sep <= 0 {
+			return nil, fmt.Errorf("line %d: missing value in statsd line: %s", i+1, line)
+		}
+		rawName, rawValue := sections[0][:sep], sections[0][sep+1:]
+		kind := sections[1]
+
+		rate := 1.0
+		labels := make(map[string]string)
+		for _, section := range sections[2:] {
+			switch {
+			case strings.HasPrefix(section, "@"):
+				r, err := strconv.ParseFloat(section[1:], 64)
+				if err != nil || r <= 0 || r > 1 {
+					return nil, fmt.Errorf("line %d: invalid sample rate %q", i+1, section)
+				}
+				rate = r
+			case strings.HasPrefix(section, "#"):
+				for _, tag := range strings.Split(section[1:], ",") {
+					if tag == "" {
+						continue
+					}
+					key, value, found := strings.Cut(tag, ":")
+					if !found {
+						value = "true"
+						warnOnce("tag:"+key, fmt.Sprintf("tag %q has no value; converted to label %s=\"true\"", key, normalizeLabelName(key)))
+					}
+					labels[normalizeLabelName(key)] = value
+				}
+			default:
+				warnOnce("section:"+section, fmt.Sprintf("unsupported statsd section %q dropped", section))
+			}
+		}
+
+		if rate != 1 {
+			warnOnce("rate", "sample rates were applied to scale values; converted counts are estimates")
+		}
+
+		name := normalizeName(rawName)
+		if kind == "s" {
+			addSetMember(series, &order, name, labels, rawValue)
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimPrefix(rawValue, "+"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", i+1, rawValue)
+		}
+
+		switch kind {
+		case "c":
+			s := getSeries(series, &order, counterName(name), labels, "counter")
+			s.value += value / rate
+		case "g":
+			s := getSeries(series, &order, name, labels, "gauge")
+			if strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-") {
+				s.value += value
+			} else {
+				s.value = value
+			}
+		case "ms":
+			s := getSeries(series, &order, secondsName(name), labels, "summary")
+			s.value += value / 1000 / rate
+			s.count += 1 / rate
+			warnOnce("ms", "timers were converted to summaries in seconds with _sum and _count only; percentiles are not preserved")
+		case "h", "d":
+			s := getSeries(series, &order, name, labels, "summary")
+			s.value += value / rate
+			s.count += 1 / rate
+			warnOnce("h", "histograms/distributions were converted to summaries with _sum and _count only; bucket information is not preserved")
+		default:
+			return nil, fmt.Errorf("line %d: unsupported statsd metric type %q", i+1, kind)
+		}
+	}
+
+	for _, key := range order {
+		s := series[key]
+		result.Types[s.name] = s.kind
+
+		switch s.kind {
+		case "summary":
+			result.Metrics = append(result.Metrics,
+				newMetric(s.name+"_sum", s.labels, formatFloat(s.value)),
+				newMetric(s.name+"_count", copyLabels(s.labels), formatFloat(s.count)))
+		case "set":
+			result.Types[s.name] = "gauge"
+			result.Metrics = append(result.Metrics, newMetric(s.name, s.labels, formatFloat(float64(len(s.set)))))
+			warnOnce("set", "sets were converted to gauges of unique values seen in the input; the members themselves are not preserved")
+		default:
+			result.Metrics = append(result.Metrics, newMetric(s.name, s.labels, formatFloat(s.value)))
+		}
+	}
+
+	return result, nil
+}
+
+func getSeries(series map[string]*statsdSeries, order *[]string, name string, labels map[string]string, kind string) *statsdSeries {
+	key := seriesKey(name, labels)
+	if s, ok := series[key]; ok {
+		return s
+	}
+	s := &statsdSeries{name: name, labels: labels, kind: kind}
+	series[key] = s
+	*order = append(*order, key)
+	return s
+}
+
+func addSetMember(series map[string]*statsdSeries, order *[]string, name string, labels map[string]string, member string) {
+	s := getSeries(series, order, name, labels, "set")
+	if s.set == nil {
+		s.set = make(map[string]bool)
+	}
+	s.set[member] = true
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	return metrics.FormatSample(metrics.Metric{Name: name, Labels: labels})
+}
+
+func counterName(name string) string {
+	if strings.HasSuffix(name, "_total") {
+		return name
+	}
+	return name + "_total"
+}
+
+func secondsName(name string) string {
+	for _, suffix := range []string{"_ms", "_milliseconds"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	if strings.HasSuffix(name, "_seconds") {
+		return name
+	}
+	return name + "_seconds"
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,165 @@
+// ABOUTME: Grafana dashboard generation - one panel per evaluated metric, chosen by metric type
+// ABOUTME: Counters get rate() graphs, gauges stat panels and histograms p50/p99 graphs
+
+package grafana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Metric is one metric family to chart
+type Metric struct {
+	// Name as exposed, e.g. http_requests_total, or the histogram family without _bucket
+	Name string
+	// counter, gauge, histogram, summary or untyped
+	Type string
+	// Label names other than le and quantile
+	Labels []string
+	// Shown as the panel description, e.g. the evaluation verdict
+	Verdict string
+}
+
+// Panels are laid out two to a row
+const (
+	panelWidth  = 12
+	panelHeight = 8
+)
+
+type dashboard struct {
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []panel    `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type panel struct {
+	ID          int        `json:"id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	GridPos     gridPos    `json:"gridPos"`
+	Datasource  datasource `json:"datasource"`
+	Targets     []target   `json:"targets"`
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+}
+
+// GenerateGrafanaDashboard builds dashboard JSON that Grafana's import accepts.
+// Queries go to a Prometheus data source picked when the dashboard is imported.
+func GenerateGrafanaDashboard(metrics []Metric) ([]byte, error) {
+	if len(metrics) == 0 {
+		return nil, errors.New("no metrics to chart")
+	}
+
+	d := dashboard{
+		Title:         "Good Telemetry: " + metrics[0].Name,
+		Tags:          []string{"good-telemetry"},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Time:          timeRange{From: "now-6h", To: "now"},
+		Templating: templating{List: []variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+		}},
+	}
+	if len(metrics) > 1 {
+		d.Title = fmt.Sprintf("Good Telemetry: %d metrics", len(metrics))
+	}
+
+	for i, m := range metrics {
+		p := panel{
+			ID:          i + 1,
+			Title:       m.Name,
+			Description: m.Verdict,
+			GridPos:     gridPos{H: panelHeight, W: panelWidth, X: (i % 2) * panelWidth, Y: (i / 2) * panelHeight},
+			Datasource:  datasource{Type: "prometheus", UID: "${datasource}"},
+		}
+		p.Type, p.Targets = panelQueries(m)
+		d.Panels = append(d.Panels, p)
+	}
+
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// panelQueries picks the panel type and PromQL for a metric's type
+func panelQueries(m Metric) (string, []target) {
+	legend := legendFormat(m.Labels)
+	switch m.Type {
+	case "counter":
+		return "timeseries", []target{
+			{RefID: "A", Expr: aggregate(m.Labels, fmt.Sprintf("rate(%s[5m])", m.Name)), LegendFormat: legend},
+		}
+	case "gauge":
+		return "stat", []target{
+			{RefID: "A", Expr: aggregate(m.Labels, m.Name), LegendFormat: legend},
+		}
+	case "histogram":
+		by := append([]string{"le"}, m.Labels...)
+		rate := aggregate(by, fmt.Sprintf("rate(%s_bucket[5m])", m.Name))
+		return "timeseries", []target{
+			{RefID: "A", Expr: fmt.Sprintf("histogram_quantile(0.5, %s)", rate), LegendFormat: strings.TrimSpace("p50 " + legend)},
+			{RefID: "B", Expr: fmt.Sprintf("histogram_quantile(0.99, %s)", rate), LegendFormat: strings.TrimSpace("p99 " + legend)},
+		}
+	case "summary":
+		return "timeseries", []target{
+			{RefID: "A", Expr: m.Name, LegendFormat: strings.TrimSpace("{{quantile}} " + legend)},
+		}
+	default:
+		return "timeseries", []target{
+			{RefID: "A", Expr: m.Name, LegendFormat: legend},
+		}
+	}
+}
+
+// aggregate sums expr by labels, or returns it unchanged when there are none
+func aggregate(labels []string, expr string) string {
+	if len(labels) == 0 {
+		return expr
+	}
+	return fmt.Sprintf("sum by (%s) (%s)", strings.Join(labels, ", "), expr)
+}
+
+func legendFormat(labels []string) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = "{{" + l + "}}"
+	}
+	return strings.Join(parts, " ")
+}
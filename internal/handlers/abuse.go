@@ -0,0 +1,98 @@
+// ABOUTME: Abuse screening for evaluation requests and the robots.txt served to crawlers
+// ABOUTME: Fakes success for honeypot hits and asks for a math answer once a session hits its cap
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/abuse"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const (
+	evalCookie       = "gt_evals"
+	evalCookieMaxAge = 24 * 60 * 60
+)
+
+const robotsTxt = `User-agent: *
+Disallow: /evaluate
+Disallow: /stats
+Disallow: /api/
+`
+
+func (h *Handler) Robots(c *gin.Context) {
+	c.String(http.StatusOK, robotsTxt)
+}
+
+// screenEvaluation applies abuse protection to an evaluation request. It
+// returns false when it has already written the response.
+func (h *Handler) screenEvaluation(c *gin.Context, req api.EvaluateRequest) bool {
+	// Tenant keys are held to their tenant's LLM budget instead
+	if h.guard == nil || h.guard.Bypass(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")) || middleware.TenantByKey(c) {
+		return true
+	}
+
+	if c.PostForm(abuse.HoneypotField) != "" {
+		selfmetrics.AbuseBlocked.WithLabelValues("honeypot").Inc()
+		log.Printf("[Abuse] Honeypot field filled in by %s", middleware.ClientIP(c))
+		// Look like a normal result so the script has no signal to adapt to
+		evaluation := &llm.Evaluation{Verdict: "Analysis Completed"}
+		respond(c, http.StatusOK, "result.html", gin.H{
+			"evaluation": evaluation,
+			"metrics":    &metrics.ParsedMetrics{},
+		}, api.EvaluateResponse{Evaluation: apiEvaluation(evaluation), Problems: []api.Finding{}, Praise: []api.Finding{}})
+		return false
+	}
+
+	cookie, _ := c.Cookie(evalCookie)
+	count := h.guard.Count(cookie)
+	if h.guard.NeedsChallenge(count) {
+		if req.ChallengeToken == "" {
+			selfmetrics.AbuseBlocked.WithLabelValues("challenge_required").Inc()
+			h.renderChallenge(c, req.Metrics, false)
+			return false
+		}
+		if !h.guard.VerifyChallenge(req.ChallengeToken, req.ChallengeAnswer) {
+			selfmetrics.AbuseBlocked.WithLabelValues("challenge_failed").Inc()
+			h.renderChallenge(c, req.Metrics, true)
+			return false
+		}
+		count = 0
+	}
+
+	c.SetCookie(evalCookie, h.guard.SessionCookie(count+1), evalCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	return true
+}
+
+func (h *Handler) renderChallenge(c *gin.Context, input string, failed bool) {
+	question, token, err := h.guard.Challenge()
+	if err != nil {
+		log.Printf("[Abuse] Error creating challenge: %v", err)
+		renderError(c, http.StatusInternalServerError, "error.retry", "Failed to evaluate metrics, please try again")
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		code, message := api.ErrorChallengeRequired, "This session has used up its evaluations; answer the challenge to go on"
+		if failed {
+			code, message = api.ErrorChallengeFailed, "The challenge answer was wrong or expired; answer the new challenge"
+		}
+		c.JSON(http.StatusForbidden, api.ErrorResponse{Error: message, Code: code, Challenge: &api.Challenge{Question: question, Token: token}})
+		return
+	}
+	// layout.html has htmx swap 403s, so the challenge lands in the results area
+	render(c, http.StatusForbidden, "challenge.html", gin.H{
+		"question": question,
+		"token":    token,
+		"metrics":  input,
+		"failed":   failed,
+	})
+}
@@ -0,0 +1,289 @@
+// ABOUTME: Tests for abuse screening on /evaluate - honeypot hits get a fake success, and capped sessions a challenge
+// ABOUTME: API keys and quick evaluations skip both, a session without a cookie starts at 0, and a solved challenge starts it over
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/abuse"
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const abuseMetrics = `# HELP http_requests_total Total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 1
+`
+
+// guardedRouter serves /evaluate with a cap of limit evaluations per session
+func guardedRouter(t *testing.T, limit int) (*gin.Engine, *stubOllama) {
+	t.Helper()
+	ollama := newStubOllama(t, nil)
+	h := newTestHandler(t, ollama.URL)
+	guard, err := abuse.NewGuard(limit, []string{"api-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.guard = guard
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", h.Index)
+	r.POST("/evaluate", h.Evaluate)
+	r.POST("/api/v1/evaluate/quick", h.EvaluateQuick)
+	r.GET("/robots.txt", h.Robots)
+	return r, ollama
+}
+
+// postEvaluate submits form with the session cookie, returning the response
+// and the cookie it set, or cookie again when it set none
+func postEvaluate(t *testing.T, r http.Handler, form url.Values, cookie, apiKey string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: evalCookie, Value: cookie})
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == evalCookie {
+			cookie = c.Value
+		}
+	}
+	return rec, cookie
+}
+
+// sessionCookie is the cookie the index page starts a session with
+func sessionCookie(t *testing.T, r http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == evalCookie {
+			return c.Value
+		}
+	}
+	t.Fatal("the index page started no session")
+	return ""
+}
+
+type screenedBody struct {
+	// Set only on challenges
+	Code      string
+	Challenge *api.Challenge
+	// Set only on evaluation results
+	Evaluation *struct{ Verdict string }
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) screenedBody {
+	t.Helper()
+	var body screenedBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+// challengeAnswer works out the answer to a challenge's question
+func challengeAnswer(t *testing.T, challenge *api.Challenge) string {
+	t.Helper()
+	var x, y int
+	if _, err := fmt.Sscanf(challenge.Question, "What is %d + %d?", &x, &y); err != nil {
+		t.Fatalf("question %q: %v", challenge.Question, err)
+	}
+	return fmt.Sprint(x + y)
+}
+
+// abuseBlocked scrapes the count of requests blocked for reason
+func abuseBlocked(t *testing.T, reason string) float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	selfmetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	prefix := `goodtelemetry_abuse_blocked_total{reason="` + reason + `"} `
+	for line := range strings.Lines(rec.Body.String()) {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestHoneypotFakesSuccess(t *testing.T) {
+	r, ollama := guardedRouter(t, 5)
+	blocked := abuseBlocked(t, "honeypot")
+
+	form := url.Values{"metrics": {abuseMetrics}, abuse.HoneypotField: {"http://spam.example"}}
+	rec, _ := postEvaluate(t, r, form, sessionCookie(t, r), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want a normal-looking 200", rec.Code)
+	}
+	if body := decodeBody(t, rec); body.Evaluation == nil || body.Evaluation.Verdict != "Analysis Completed" {
+		t.Errorf("body = %s, want the fake result", rec.Body.String())
+	}
+	if ollama.calls.Load() != 0 {
+		t.Errorf("the LLM was called %d times for a honeypot hit", ollama.calls.Load())
+	}
+	if got := abuseBlocked(t, "honeypot") - blocked; got != 1 {
+		t.Errorf("honeypot blocks counted = %v, want 1", got)
+	}
+
+	// API keys aren't screened, even with the field filled in
+	rec, _ = postEvaluate(t, r, form, "", "api-key")
+	if body := decodeBody(t, rec); body.Evaluation == nil || body.Evaluation.Verdict != "Good" || ollama.calls.Load() != 1 {
+		t.Errorf("with an API key = %s, want a real evaluation", rec.Body.String())
+	}
+	if rec.Header().Get("X-Robots-Tag") != "noindex" {
+		t.Errorf("X-Robots-Tag = %q, want results kept out of search indexes", rec.Header().Get("X-Robots-Tag"))
+	}
+}
+
+func TestSessionCapNeedsAChallenge(t *testing.T) {
+	r, ollama := guardedRouter(t, 2)
+	form := url.Values{"metrics": {abuseMetrics}}
+	cookie := sessionCookie(t, r)
+
+	var rec *httptest.ResponseRecorder
+	for i := range 2 {
+		rec, cookie = postEvaluate(t, r, form, cookie, "")
+		if body := decodeBody(t, rec); body.Evaluation == nil || body.Evaluation.Verdict != "Good" {
+			t.Fatalf("evaluation %d under the cap = %s", i+1, rec.Body.String())
+		}
+	}
+
+	calls := ollama.calls.Load()
+	rec, cookie = postEvaluate(t, r, form, cookie, "")
+	challenge := decodeBody(t, rec)
+	if rec.Code != http.StatusForbidden || challenge.Code != api.ErrorChallengeRequired || challenge.Challenge == nil ||
+		challenge.Challenge.Question == "" || challenge.Challenge.Token == "" {
+		t.Fatalf("third evaluation = %d %s, want a 403 challenge", rec.Code, rec.Body.String())
+	}
+	if ollama.calls.Load() != calls {
+		t.Errorf("LLM calls = %d, want none past the cap", ollama.calls.Load()-calls)
+	}
+
+	// A wrong answer is asked again
+	wrong := url.Values{"metrics": {abuseMetrics}, "challenge_token": {challenge.Challenge.Token}, "challenge_answer": {"99"}}
+	rec, _ = postEvaluate(t, r, wrong, cookie, "")
+	if body := decodeBody(t, rec); rec.Code != http.StatusForbidden || body.Code != api.ErrorChallengeFailed || body.Challenge == nil {
+		t.Errorf("wrong answer = %d %s, want a new challenge marked failed", rec.Code, rec.Body.String())
+	}
+
+	right := url.Values{"metrics": {abuseMetrics}, "challenge_token": {challenge.Challenge.Token}, "challenge_answer": {challengeAnswer(t, challenge.Challenge)}}
+	rec, cookie = postEvaluate(t, r, right, cookie, "")
+	if body := decodeBody(t, rec); body.Evaluation == nil || body.Evaluation.Verdict != "Good" {
+		t.Fatalf("right answer = %s, want the evaluation", rec.Body.String())
+	}
+	// The solved challenge starts the count over
+	rec, _ = postEvaluate(t, r, form, cookie, "")
+	if body := decodeBody(t, rec); body.Evaluation == nil {
+		t.Errorf("evaluation after the challenge = %s, want it under the cap again", rec.Body.String())
+	}
+}
+
+func TestChallengeAnsweredInAJSONBody(t *testing.T) {
+	r, _ := guardedRouter(t, 1)
+	post := func(req api.EvaluateRequest, cookie string) (*httptest.ResponseRecorder, string) {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest(http.MethodPost, "/evaluate", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/json")
+		if cookie != "" {
+			httpReq.AddCookie(&http.Cookie{Name: evalCookie, Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httpReq)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == evalCookie {
+				cookie = c.Value
+			}
+		}
+		return rec, cookie
+	}
+
+	_, cookie := post(api.EvaluateRequest{Metrics: abuseMetrics}, "")
+	rec, cookie := post(api.EvaluateRequest{Metrics: abuseMetrics}, cookie)
+	challenge := decodeBody(t, rec)
+	if challenge.Challenge == nil {
+		t.Fatalf("second evaluation = %d %s, want a challenge", rec.Code, rec.Body.String())
+	}
+
+	answered := api.EvaluateRequest{Metrics: abuseMetrics, ChallengeToken: challenge.Challenge.Token, ChallengeAnswer: challengeAnswer(t, challenge.Challenge)}
+	rec, _ = post(answered, cookie)
+	if body := decodeBody(t, rec); rec.Code != http.StatusOK || body.Evaluation == nil {
+		t.Errorf("answer in a JSON body = %d %s, want the evaluation", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMissingOrForgedSessionStartsAtZero(t *testing.T) {
+	r, _ := guardedRouter(t, 2)
+	form := url.Values{"metrics": {abuseMetrics}}
+	for name, cookie := range map[string]string{"missing": "", "forged": "0.forged"} {
+		rec, next := postEvaluate(t, r, form, cookie, "")
+		if body := decodeBody(t, rec); rec.Code != http.StatusOK || body.Evaluation == nil {
+			t.Errorf("%s cookie = %d %s, want the evaluation", name, rec.Code, rec.Body.String())
+		}
+		if next == cookie {
+			t.Errorf("%s cookie: no session cookie was issued", name)
+		}
+	}
+}
+
+func TestUnscreenedRequests(t *testing.T) {
+	r, ollama := guardedRouter(t, 1)
+	form := url.Values{"metrics": {abuseMetrics}}
+	_, cookie := postEvaluate(t, r, form, "", "")
+	if rec, _ := postEvaluate(t, r, form, cookie, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("session past the cap = %d, want a challenge", rec.Code)
+	}
+
+	// API keys skip the cap
+	rec, _ := postEvaluate(t, r, form, cookie, "api-key")
+	if body := decodeBody(t, rec); body.Evaluation == nil {
+		t.Errorf("with an API key = %d %s, want the evaluation", rec.Code, rec.Body.String())
+	}
+
+	// Quick evaluations don't call the LLM, so the cap doesn't apply
+	calls := ollama.calls.Load()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/evaluate/quick", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.AddCookie(&http.Cookie{Name: evalCookie, Value: cookie})
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "challenge") {
+		t.Errorf("quick evaluation past the cap = %d %s, want the static results", rec.Code, rec.Body.String())
+	}
+	if got := ollama.calls.Load() - calls; got != 0 {
+		t.Errorf("a quick evaluation made %d LLM calls", got)
+	}
+}
+
+func TestRobots(t *testing.T) {
+	r, _ := guardedRouter(t, 0)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if !strings.Contains(rec.Body.String(), "Disallow: /evaluate\n") {
+		t.Errorf("robots.txt = %q, want /evaluate disallowed", rec.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+// ABOUTME: Audit API - lets compliance teams pull audit events for a time range
+// ABOUTME: Also records evaluation events when an audit log is configured
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/audit"
+	"github.com/wbollock/good_telemetry/internal/auth"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+func (h *Handler) AuditEvents(c *gin.Context) {
+	start, err := parseTimeParam(c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC 3339 timestamp"})
+		return
+	}
+	end, err := parseTimeParam(c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be an RFC 3339 timestamp"})
+		return
+	}
+
+	events, err := h.audit.Query(start, end)
+	if err != nil {
+		log.Printf("[Audit] Error reading audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// recordAudit logs an audit event when auditing is enabled
+func (h *Handler) recordAudit(c *gin.Context, event audit.AuditEvent) {
+	h.logAudit(h.auditIdentity(c, event))
+}
+
+// auditIdentity fills in who made the request, for events logged after it has finished
+func (h *Handler) auditIdentity(c *gin.Context, event audit.AuditEvent) audit.AuditEvent {
+	event.ClientIP = middleware.ClientIP(c)
+	if t := middleware.CurrentTenant(c); t.ID != tenant.DefaultID {
+		event.TenantID = t.ID
+	} else {
+		event.TenantID = c.GetHeader("X-Tenant-ID")
+	}
+	if user, ok := auth.CurrentUser(c); ok {
+		event.User = user.ID()
+	}
+	return event
+}
+
+func (h *Handler) logAudit(event audit.AuditEvent) {
+	if h.audit == nil {
+		return
+	}
+	if err := h.audit.Log(event); err != nil {
+		log.Printf("[Audit] Error writing audit event: %v", err)
+	}
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,29 @@
+// ABOUTME: Evaluation cache administration - evicts cached LLM evaluations on request
+// ABOUTME: Useful after a model is updated in place under the same name, which the cache key can't see
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/llm"
+)
+
+// ClearEvaluationCache evicts every cached evaluation
+func (h *Handler) ClearEvaluationCache(c *gin.Context) {
+	n, err := h.llmClient.ClearCache()
+	switch {
+	case errors.Is(err, llm.ErrNoCache):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("[Cache] Error clearing evaluation cache: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear evaluation cache"})
+		return
+	}
+	log.Printf("[Cache] Cleared %d cached evaluation(s)", n)
+	c.JSON(http.StatusOK, gin.H{"cleared": n})
+}
@@ -0,0 +1,93 @@
+// ABOUTME: Metric catalog API - lists every evaluated metric for Grafana's metric browser
+// ABOUTME: Serves metric names with labels and type, plus label-value completion and their cardinality as Prometheus metrics
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+)
+
+// Catalog estimates stop growing here so a metric with many unbounded labels
+// can't overflow
+const maxCatalogSeries = 1_000_000_000_000
+
+func (h *Handler) MetricCatalog(c *gin.Context) {
+	catalog, err := h.history.Catalog(middleware.CurrentTenant(c).ID)
+	if err != nil {
+		log.Printf("[Catalog] Error reading metric catalog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read metric catalog"})
+		return
+	}
+	c.JSON(http.StatusOK, catalog)
+}
+
+func (h *Handler) LabelValues(c *gin.Context) {
+	values, err := h.history.LabelValues(middleware.CurrentTenant(c).ID, c.Param("name"), c.Param("label"))
+	if err != nil {
+		log.Printf("[Catalog] Error reading label values: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read label values"})
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+// CardinalityPrometheus serves each catalog metric's estimated series and memory
+// in the Prometheus text format, so Prometheus can scrape and alert on them.
+// The estimate is the product of the values seen for each label across all
+// evaluations; the metric query parameter limits it to one metric.
+func (h *Handler) CardinalityPrometheus(c *gin.Context) {
+	tenantID := middleware.CurrentTenant(c).ID
+	catalog, err := h.history.Catalog(tenantID)
+	if err != nil {
+		log.Printf("[Catalog] Error reading metric catalog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read metric catalog"})
+		return
+	}
+
+	series := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "goodtelemetry",
+		Name:      "metric_estimated_series",
+		Help:      "Series an evaluated metric is estimated to create, from the label values seen across evaluations.",
+	}, []string{"metric_name"})
+	memory := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "goodtelemetry",
+		Name:      "metric_memory_bytes",
+		Help:      "Prometheus memory an evaluated metric's estimated series take.",
+	}, []string{"metric_name"})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(series, memory)
+
+	want := c.Query("metric")
+	found := false
+	for _, m := range catalog {
+		if want != "" && m.Metric != want {
+			continue
+		}
+		found = true
+		estimate := 1.0
+		for _, label := range m.Labels {
+			values, err := h.history.LabelValues(tenantID, m.Metric, label)
+			if err != nil {
+				log.Printf("[Catalog] Error reading label values: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read label values"})
+				return
+			}
+			estimate = min(estimate*float64(max(len(values), 1)), maxCatalogSeries)
+		}
+		series.WithLabelValues(m.Metric).Set(estimate)
+		memory.WithLabelValues(m.Metric).Set(float64(cardinality.MemoryBytes(int(estimate))))
+	}
+	if want != "" && !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "metric not in catalog"})
+		return
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,102 @@
+// ABOUTME: Tests for the catalog's cardinality in Prometheus format - one estimate per metric from the label values seen
+// ABOUTME: The output parses as exposition text, the metric parameter narrows it, and unknown metrics are a 404
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+func TestCardinalityPrometheus(t *testing.T) {
+	h := newTestHandler(t, "http://127.0.0.1:1")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/cardinality/prometheus", h.CardinalityPrometheus)
+
+	sample := func(name string, labels map[string]string) history.Sample {
+		return history.Sample{Name: name, Type: "counter", Labels: labels}
+	}
+	records := []*history.Record{
+		{Samples: []history.Sample{
+			sample("http_requests_total", map[string]string{"method": "GET", "pod": "a"}),
+			sample("http_requests_total", map[string]string{"method": "POST", "pod": "b"}),
+		}},
+		// Values from another evaluation add to the estimate
+		{Samples: []history.Sample{
+			sample("http_requests_total", map[string]string{"method": "GET", "pod": "c"}),
+			sample("up", nil),
+		}},
+		{Tenant: "payments", Samples: []history.Sample{sample("payments_total", map[string]string{"method": "GET"})}},
+	}
+	for _, rec := range records {
+		if rec.Tenant == "" {
+			rec.Tenant = tenant.DefaultID
+		}
+		rec.CreatedAt = time.Now()
+		if err := h.history.Add(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		// Estimated series per metric_name
+		want map[string]int
+	}{
+		{"", http.StatusOK, map[string]int{"http_requests_total": 2 * 3, "up": 1}},
+		{"?metric=http_requests_total", http.StatusOK, map[string]int{"http_requests_total": 6}},
+		{"?metric=payments_total", http.StatusNotFound, nil},
+		{"?metric=nope", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cardinality/prometheus"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.want == nil {
+				return
+			}
+
+			parser := expfmt.NewTextParser(model.LegacyValidation)
+			families, err := parser.TextToMetricFamilies(strings.NewReader(rec.Body.String()))
+			if err != nil {
+				t.Fatalf("not exposition text (%v):\n%s", err, rec.Body.String())
+			}
+			for family, scale := range map[string]int64{
+				"goodtelemetry_metric_estimated_series": 1,
+				"goodtelemetry_metric_memory_bytes":     cardinality.MemoryBytes(1),
+			} {
+				mf, ok := families[family]
+				if !ok || mf.GetHelp() == "" {
+					t.Fatalf("no %s family with help in:\n%s", family, rec.Body.String())
+				}
+				got := make(map[string]int)
+				for _, m := range mf.GetMetric() {
+					got[m.GetLabel()[0].GetValue()] = int(int64(m.GetGauge().GetValue()) / scale)
+				}
+				if len(got) != len(tt.want) {
+					t.Errorf("%s = %v, want %v", family, got, tt.want)
+				}
+				for metric, series := range tt.want {
+					if got[metric] != series {
+						t.Errorf("%s{metric_name=%q} = %d, want %d", family, metric, got[metric], series)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// ABOUTME: Duplicate evaluation suppression - a double-click or refresh within seconds reuses the first evaluation
+// ABOUTME: Unlike the example cache this only spans a few seconds; it saves the LLM call and budget, not results
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/audit"
+)
+
+// DefaultDeduplicationWindow is how long an evaluation is reused for an identical submission
+const DefaultDeduplicationWindow = 5 * time.Second
+
+// deduplicationKey identifies submissions that would get the same answer:
+// the same tenant, model, result format and metric fingerprint, judged by the
+// same instructions after the same scrape config, label bounds and runtime
+// metric handling
+func deduplicationKey(p evaluationLLMPhase, forPage bool) string {
+	page := "api"
+	if forPage {
+		page = "page"
+	}
+	submission := strings.Join([]string{p.input, p.instructions, p.scrapeConfig, p.labelBounds.String(), strconv.FormatBool(p.includeRuntime)}, "\x00")
+	return strings.Join([]string{p.tenant, p.model, page, audit.Fingerprint(submission)}, "\x00")
+}
+
+// evaluationJob queues the LLM phase, returning the job's ID. An identical
+// submission's job is reused instead while it is queued or running, or when
+// it was submitted within DeduplicationWindow and is done; deduplicationKey
+// is the job's idempotency key. A failed job isn't reused, so retrying after
+// an error calls the LLM again. Only a new job spends the tenant's LLM
+// budget. forPage adds what the result page's LLM fragment needs; polled
+// jobs are fetched later rather than waited on. It writes the response
+// itself when it reports false.
+func (h *Handler) evaluationJob(c *gin.Context, llmPhase evaluationLLMPhase, forPage, polled bool) (string, bool) {
+	key := ""
+	if h.DeduplicationWindow > 0 {
+		key = deduplicationKey(llmPhase, forPage)
+	}
+
+	// Held from the lookup through queueing, so simultaneous duplicates
+	// queue one job and spend the budget once
+	h.enqueueMu.Lock()
+	defer h.enqueueMu.Unlock()
+	job, reused, err := h.jobs.reuse(key, time.Now().Add(-h.DeduplicationWindow), polled)
+	if err != nil {
+		log.Printf("[Evaluate] Error looking up duplicate jobs: %v", err)
+	}
+	if reused {
+		log.Printf("[Evaluate] Duplicate submission, reusing job %s", job.ID)
+		return job.ID, true
+	}
+	if !h.spendLLMBudgetOrReject(c) {
+		return "", false
+	}
+
+	payload := jobPayload{
+		Tenant:            llmPhase.tenant,
+		Evaluated:         llmPhase.evaluated,
+		Parsed:            llmPhase.parsed,
+		Findings:          llmPhase.findings,
+		Instructions:      llmPhase.instructions,
+		EngineFingerprint: llmPhase.fingerprint,
+		Model:             llmPhase.model,
+		Input:             llmPhase.redactor.Redact(llmPhase.input),
+		InputFingerprint:  audit.Fingerprint(llmPhase.input),
+		ShareConsent:      llmPhase.shareConsent,
+		LabelBounds:       llmPhase.labelBounds,
+		Audit:             llmPhase.audit,
+		ForPage:           forPage,
+	}
+	id, err := h.jobs.enqueue(llmPhase.tenant, key, polled, payload)
+	if err != nil {
+		log.Printf("[Evaluate] Error queueing the LLM phase: %v", err)
+		renderError(c, http.StatusInternalServerError, "error.evaluate", "Failed to evaluate metrics: "+err.Error())
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,98 @@
+// ABOUTME: Tests for duplicate evaluation suppression - simultaneous duplicates share one LLM job
+// ABOUTME: and submissions that differ in scrape config, label bounds or runtime handling don't
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// testLLMPhase is the LLM phase of evaluating input for the default tenant
+func testLLMPhase(t *testing.T, h *Handler, input string) evaluationLLMPhase {
+	t.Helper()
+	parsed, err := h.Profile().Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return evaluationLLMPhase{
+		tenant:    "default",
+		evaluated: parsed,
+		parsed:    parsed,
+		findings:  h.Profile().Check(parsed),
+		input:     input,
+		redactor:  h.Redactor(),
+	}
+}
+
+func TestSimultaneousDuplicatesStartOneJob(t *testing.T) {
+	release := make(chan struct{})
+	ollama := newStubOllama(t, release)
+	h := newTestHandler(t, ollama.URL)
+	phase := testLLMPhase(t, h, `http_requests_total{method="GET"} 1`)
+
+	const clicks = 8
+	ids := make([]string, clicks)
+	var wg sync.WaitGroup
+	for i := range clicks {
+		wg.Go(func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/evaluate", nil)
+			id, ok := h.evaluationJob(c, phase, false, true)
+			if !ok {
+				t.Error("evaluationJob rejected the submission")
+			}
+			ids[i] = id
+		})
+	}
+	wg.Wait()
+	close(release)
+
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			t.Fatalf("simultaneous duplicates got jobs %v, want one shared job", ids)
+		}
+	}
+	if job, _ := h.jobs.wait(context.Background(), ids[0]); job.Status != history.JobDone {
+		t.Fatalf("job %s, want done: %s", job.Status, job.Error)
+	}
+	if calls := ollama.calls.Load(); calls != 1 {
+		t.Errorf("the LLM was called %d times, want 1", calls)
+	}
+}
+
+func TestDeduplicationKeyTellsSubmissionsApart(t *testing.T) {
+	base := evaluationLLMPhase{tenant: "default", input: `http_requests_total{method="GET"} 1`, instructions: "be brief"}
+	variants := map[string]func(p *evaluationLLMPhase){
+		"scrape config":   func(p *evaluationLLMPhase) { p.scrapeConfig = "job_name: api" },
+		"label bounds":    func(p *evaluationLLMPhase) { p.labelBounds = api.LabelBounds{"method": 5} },
+		"include runtime": func(p *evaluationLLMPhase) { p.includeRuntime = true },
+		"tenant":          func(p *evaluationLLMPhase) { p.tenant = "payments" },
+		"model":           func(p *evaluationLLMPhase) { p.model = "llama3.1:8b" },
+		"input":           func(p *evaluationLLMPhase) { p.input += "\n" + `http_requests_total{method="PUT"} 1` },
+		"instructions":    func(p *evaluationLLMPhase) { p.instructions = "be thorough" },
+	}
+	for name, change := range variants {
+		t.Run(name, func(t *testing.T) {
+			changed := base
+			change(&changed)
+			if deduplicationKey(base, false) == deduplicationKey(changed, false) {
+				t.Errorf("a different %s gives the same deduplication key", name)
+			}
+		})
+	}
+
+	if deduplicationKey(base, false) != deduplicationKey(base, false) {
+		t.Error("identical submissions give different keys")
+	}
+	if deduplicationKey(base, false) == deduplicationKey(base, true) {
+		t.Error("page and API submissions share a key")
+	}
+}
@@ -0,0 +1,46 @@
+// ABOUTME: Stored evaluation lookup - lets API clients read back an evaluation by the ID it returned
+// ABOUTME: Serves the history record in the shared pkg/api wire format
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+func (h *Handler) GetEvaluation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.ErrorResponse{Error: "id must be an evaluation number"})
+		return
+	}
+
+	// Another tenant's evaluation reads as missing
+	record, err := h.history.Get(middleware.CurrentTenant(c).ID, id)
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, api.ErrorResponse{Error: "evaluation not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Evaluations] Error reading evaluation %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, api.ErrorResponse{Error: "failed to read evaluation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.Result{
+		ID:           record.ID,
+		CreatedAt:    record.CreatedAt,
+		Input:        record.Input,
+		Verdict:      record.Verdict,
+		Model:        record.Model,
+		FindingCodes: record.FindingCodes,
+		LabelBounds:  record.LabelBounds,
+	})
+}
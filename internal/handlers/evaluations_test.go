@@ -0,0 +1,67 @@
+// ABOUTME: Tests for reading stored evaluations back - each tenant sees only its own
+// ABOUTME: Another tenant's evaluation ID answers 404, exactly like an ID that was never used
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+func TestGetEvaluationIsTenantScoped(t *testing.T) {
+	h := newTestHandler(t, "http://127.0.0.1:1")
+	registry := tenant.NewRegistry([]tenant.Tenant{
+		{ID: "payments", APIKeys: []string{"pay-key"}},
+		{ID: "search", APIKeys: []string{"search-key"}},
+	})
+	r := gin.New()
+	r.Use(middleware.ResolveTenant(registry))
+	r.GET("/api/v1/evaluations/:id", middleware.APIKeyOrTenant("admin-key"), h.GetEvaluation)
+
+	record := &history.Record{Tenant: "payments", CreatedAt: time.Now(), Input: "up 1", Verdict: "Good"}
+	if err := h.history.Add(record); err != nil {
+		t.Fatal(err)
+	}
+	own := "/api/v1/evaluations/" + strconv.FormatInt(record.ID, 10)
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+	}{
+		{"own tenant", own, "pay-key", http.StatusOK},
+		{"another tenant", own, "search-key", http.StatusNotFound},
+		{"never used", "/api/v1/evaluations/999", "search-key", http.StatusNotFound},
+		{"no key", own, "", http.StatusUnauthorized},
+	}
+	get := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := get(tt.path, tt.key); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	// Another tenant's ID mustn't be told apart from a missing one
+	if theirs, missing := get(own, "search-key").Body.String(), get("/api/v1/evaluations/999", "search-key").Body.String(); theirs != missing {
+		t.Errorf("another tenant's evaluation answered %s, a missing one %s", theirs, missing)
+	}
+}
@@ -0,0 +1,145 @@
+// ABOUTME: Live example runs - evaluates a showcase example on demand and compares it with the curated verdict
+// ABOUTME: Results are cached per example and engine fingerprint, so repeated clicks don't reach the LLM
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/examples"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// How long a live run is served from the cache; examples don't change between releases
+const exampleRunTTL = time.Hour
+
+// exampleRun is the live evaluation of one example
+type exampleRun struct {
+	Evaluation *llm.Evaluation
+	Problems   []rules.Finding
+	// The verdict the static findings alone imply: Good without errors or warnings
+	StaticVerdict string
+	ranAt         time.Time
+}
+
+type exampleRuns struct {
+	mu   sync.Mutex
+	runs map[string]exampleRun
+}
+
+func (r *exampleRuns) get(key string) (exampleRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[key]
+	return run, ok && time.Since(run.ranAt) < exampleRunTTL
+}
+
+// size counts the runs still served from the cache
+func (r *exampleRuns) size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, run := range r.runs {
+		if time.Since(run.ranAt) < exampleRunTTL {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *exampleRuns) put(key string, run exampleRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runs == nil {
+		r.runs = make(map[string]exampleRun)
+	}
+	run.ranAt = time.Now()
+	// Runs under a retired fingerprint are never looked up again, so expired
+	// runs are dropped rather than left to pile up
+	for k, cached := range r.runs {
+		if time.Since(cached.ranAt) >= exampleRunTTL {
+			delete(r.runs, k)
+		}
+	}
+	r.runs[key] = run
+}
+
+// RunExample evaluates a showcase example's metrics with the static checks and
+// the LLM, for rendering beneath the curated result
+func (h *Handler) RunExample(c *gin.Context) {
+	example, ok := examples.Get(c.Param("id"))
+	if !ok {
+		renderError(c, http.StatusNotFound, "error.unknown_example", "No example with ID "+c.Param("id"))
+		return
+	}
+
+	profile := h.requestProfile(c)
+	key := example.ID + "\x00" + h.engineFingerprint(profile)
+	run, cached := h.exampleRuns.get(key)
+	if !cached {
+		parsed, err := profile.Parse(example.Metrics)
+		if err != nil {
+			log.Printf("[RunExample] Error parsing example %s: %v", example.ID, err)
+			renderError(c, http.StatusInternalServerError, "error.parse", err.Error())
+			return
+		}
+		evaluated, _ := naming.ExcludeRuntime(parsed)
+		problems := rules.Problems(profile.Check(evaluated))
+
+		if !h.spendLLMBudget(middleware.CurrentTenant(c)) {
+			renderError(c, http.StatusTooManyRequests, "error.tenant_budget", "Today's LLM evaluation budget is used up; try again tomorrow")
+			return
+		}
+
+		evaluation, err := h.llmClient.Evaluate(c.Request.Context(), evaluated, profile.PromptInstructions, h.engineFingerprint(profile), "")
+		if err != nil {
+			log.Printf("[RunExample] Error calling LLM: %v", err)
+			renderError(c, http.StatusInternalServerError, "error.evaluate", "Failed to evaluate metrics: "+err.Error())
+			return
+		}
+
+		serious := 0
+		for _, f := range problems {
+			if f.Severity == rules.SeverityError || f.Severity == rules.SeverityWarning {
+				serious++
+			}
+		}
+		evaluation.CompareStatic(serious)
+		staticVerdict := "Good"
+		if serious > 0 {
+			staticVerdict = "Needs Improvement"
+		}
+
+		run = exampleRun{Evaluation: evaluation, Problems: problems, StaticVerdict: staticVerdict}
+		h.exampleRuns.put(key, run)
+	}
+
+	llmAgrees := strings.EqualFold(run.Evaluation.Verdict, example.Verdict)
+	staticAgrees := (run.StaticVerdict == "Good") == strings.EqualFold(example.Verdict, "Good")
+	respond(c, http.StatusOK, "example_run.html", gin.H{
+		"example":       example,
+		"evaluation":    run.Evaluation,
+		"problems":      run.Problems,
+		"cached":        cached,
+		"llmAgrees":     llmAgrees,
+		"staticVerdict": run.StaticVerdict,
+		"staticAgrees":  staticAgrees,
+	}, api.ExampleRun{
+		Example:       example.ID,
+		Evaluation:    apiEvaluation(run.Evaluation),
+		Problems:      apiFindings(run.Problems),
+		StaticVerdict: run.StaticVerdict,
+		LLMAgrees:     llmAgrees,
+		StaticAgrees:  staticAgrees,
+		Cached:        cached,
+	})
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Tests for live example runs against the stub LLM - each curated verdict agrees with the static checks
+// ABOUTME: Repeated runs are served from the cache, and unknown IDs are a 404
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/examples"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+func runExample(t *testing.T, r *gin.Engine, id string) (*httptest.ResponseRecorder, api.ExampleRun) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/examples/"+id+"/run", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var body api.ExampleRun
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, body
+}
+
+// Examples whose curated verdict rests on judgement no static rule makes, so
+// the live result shows the static checks disagreeing
+var llmJudgement = map[string]string{
+	// A stored ratio is in the base unit; computing it in queries is advice only the LLM gives
+	"cache-hit-ratio": "ratio stored instead of its inputs",
+}
+
+func TestRunExampleAgreesWithEachCuratedVerdict(t *testing.T) {
+	ollama := newStubOllama(t, nil)
+	h := newTestHandler(t, ollama.URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/examples/:id/run", h.RunExample)
+
+	seen := map[string]bool{}
+	for _, example := range examples.All() {
+		t.Run(example.ID, func(t *testing.T) {
+			if example.ID == "" || seen[example.ID] {
+				t.Fatalf("example ID %q is empty or not unique", example.ID)
+			}
+			seen[example.ID] = true
+
+			rec, body := runExample(t, r, example.ID)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			_, judgement := llmJudgement[example.ID]
+			if body.StaticAgrees != !judgement {
+				t.Errorf("static verdict %s against the curated %q: agrees = %v", body.StaticVerdict, example.Verdict, body.StaticAgrees)
+			}
+			if body.Cached {
+				t.Error("the first run was served from the cache")
+			}
+
+			calls := ollama.calls.Load()
+			rec, body = runExample(t, r, example.ID)
+			if rec.Code != http.StatusOK || !body.Cached {
+				t.Errorf("second run = %d, cached %v, want a cached 200", rec.Code, body.Cached)
+			}
+			if ollama.calls.Load() != calls {
+				t.Error("a repeated run reached the LLM")
+			}
+		})
+	}
+
+	if rec, _ := runExample(t, r, "no-such-example"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown example = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,43 @@
+// ABOUTME: Quick-fix endpoint - the text edits that resolve one static finding, for editors and review bots
+// ABOUTME: Issues only the LLM raises have no deterministic fix and get a 422 explaining why
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/fix"
+)
+
+// Fix returns the edits resolving the finding named by code, with byte offsets
+// into metrics, and the text with them applied. Metric and label pick one
+// finding when the code is raised more than once.
+func (h *Handler) Fix(c *gin.Context) {
+	var req struct {
+		Metrics string `form:"metrics" binding:"required"`
+		Code    string `form:"code" binding:"required"`
+		Metric  string `form:"metric"`
+		Label   string `form:"label"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metrics and code are required"})
+		return
+	}
+
+	edits, text, err := fix.Fix(req.Metrics, h.requestProfile(c), fix.Target{Code: req.Code, Metric: req.Metric, Label: req.Label})
+	switch {
+	case errors.Is(err, fix.ErrNoFix):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "supported_codes": fix.Codes()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if edits == nil {
+		edits = []fix.Edit{}
+	}
+	c.JSON(http.StatusOK, gin.H{"edits": edits, "text": text})
+}
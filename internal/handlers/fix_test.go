@@ -0,0 +1,71 @@
+// ABOUTME: Tests for POST /api/v1/fix - edits for a static finding, 422 for issues without a deterministic fix
+// ABOUTME: Offsets in the response are bytes into the submitted text
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/fix"
+)
+
+func TestFixEndpoint(t *testing.T) {
+	h := newTestHandler(t, newStubOllama(t, nil).URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/fix", h.Fix)
+	input := "# TYPE cafés_orders counter\n# HELP checkoutRequests Zürich ☕\ncheckoutRequests 1\n"
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+	}{
+		{"static finding", url.Values{"metrics": {input}, "code": {"camel-case"}}, http.StatusOK},
+		{"LLM issue", url.Values{"metrics": {input}, "code": {"vague-description"}}, http.StatusUnprocessableEntity},
+		{"no code", url.Values{"metrics": {input}}, http.StatusBadRequest},
+		{"unparseable metrics", url.Values{"metrics": {"up{"}, "code": {"camel-case"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/fix", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			var body struct {
+				Edits          []fix.Edit
+				Text           string
+				Error          string
+				SupportedCodes []string `json:"supported_codes"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			switch tt.status {
+			case http.StatusOK:
+				for _, e := range body.Edits {
+					if input[e.Start:e.End] != "checkoutRequests" {
+						t.Errorf("edit %+v covers %q", e, input[e.Start:e.End])
+					}
+				}
+				if len(body.Edits) != 2 || !strings.Contains(body.Text, "checkout_requests 1\n") {
+					t.Errorf("edits %+v give %q", body.Edits, body.Text)
+				}
+			case http.StatusUnprocessableEntity:
+				if body.Error == "" || len(body.SupportedCodes) == 0 {
+					t.Errorf("body = %s, want the reason and the supported codes", rec.Body.String())
+				}
+			}
+		})
+	}
+}
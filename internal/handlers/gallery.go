@@ -0,0 +1,168 @@
+// ABOUTME: Public gallery - admins publish consented evaluations after anonymizing them
+// ABOUTME: Re-analyzes the scrubbed text so published findings and numbers match what is shown
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/anonymize"
+	"github.com/wbollock/good_telemetry/internal/audit"
+	"github.com/wbollock/good_telemetry/internal/history"
+)
+
+// Verdicts in the order the gallery groups them; anything else follows
+var galleryVerdicts = []string{"Good", "Needs Improvement", "Poor"}
+
+type galleryGroup struct {
+	Verdict string                 `json:"verdict"`
+	Entries []history.GalleryEntry `json:"entries"`
+}
+
+// galleryResponse is the gallery as GET /gallery returns it to JSON clients
+type galleryResponse struct {
+	Groups []galleryGroup `json:"groups"`
+}
+
+func (h *Handler) Anonymizer() *anonymize.Anonymizer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.anonymizer
+}
+
+// SetAnonymizer changes the scrub patterns used for new publications, e.g. on config reload
+func (h *Handler) SetAnonymizer(a *anonymize.Anonymizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.anonymizer = a
+}
+
+func (h *Handler) Gallery(c *gin.Context) {
+	entries, err := h.history.Gallery()
+	if err != nil {
+		log.Printf("[Gallery] Error reading gallery: %v", err)
+		renderError(c, http.StatusInternalServerError, "error.gallery", "Failed to load the gallery")
+		return
+	}
+
+	byVerdict := make(map[string][]history.GalleryEntry)
+	var order []string
+	for _, e := range entries {
+		if _, ok := byVerdict[e.Verdict]; !ok {
+			order = append(order, e.Verdict)
+		}
+		byVerdict[e.Verdict] = append(byVerdict[e.Verdict], e)
+	}
+
+	var groups []galleryGroup
+	for _, verdict := range galleryVerdicts {
+		if len(byVerdict[verdict]) > 0 {
+			groups = append(groups, galleryGroup{Verdict: verdict, Entries: byVerdict[verdict]})
+			delete(byVerdict, verdict)
+		}
+	}
+	for _, verdict := range order {
+		if len(byVerdict[verdict]) > 0 {
+			groups = append(groups, galleryGroup{Verdict: verdict, Entries: byVerdict[verdict]})
+		}
+	}
+
+	respond(c, http.StatusOK, "gallery.html", gin.H{
+		"title":    "Gallery - Good Telemetry",
+		"subtitle": "Published Evaluations",
+		"groups":   groups,
+	}, galleryResponse{Groups: groups})
+}
+
+func (h *Handler) ShareCandidates(c *gin.Context) {
+	records, err := h.history.ShareCandidates(history.AnyTenant)
+	if err != nil {
+		log.Printf("[Gallery] Error reading share candidates: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read share candidates"})
+		return
+	}
+
+	candidates := make([]gin.H, 0, len(records))
+	for _, r := range records {
+		candidates = append(candidates, gin.H{
+			"id":         r.ID,
+			"created_at": r.CreatedAt,
+			"verdict":    r.Verdict,
+			"input":      r.Input,
+		})
+	}
+	c.JSON(http.StatusOK, candidates)
+}
+
+// PublishEvaluation anonymizes a stored evaluation and adds it to the gallery
+func (h *Handler) PublishEvaluation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be an evaluation number"})
+		return
+	}
+
+	record, err := h.history.Get(history.AnyTenant, id)
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "evaluation not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Gallery] Error reading evaluation %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read evaluation"})
+		return
+	}
+	if !record.ShareConsent {
+		c.JSON(http.StatusConflict, gin.H{"error": "the submitter did not consent to publication"})
+		return
+	}
+
+	scrubbed, err := h.Anonymizer().Scrub(record.Input)
+	if err != nil {
+		log.Printf("[Gallery] Refusing to publish evaluation %d: %v", id, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Analyze the scrubbed text rather than reuse the original figures, which
+	// could disagree with what is published
+	profile := h.Profile()
+	parsed, err := profile.Parse(scrubbed)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "scrubbed input no longer parses: " + err.Error()})
+		return
+	}
+
+	entry := &history.GalleryEntry{
+		EvaluationID: id,
+		PublishedAt:  time.Now(),
+		Input:        scrubbed,
+		Verdict:      record.Verdict,
+	}
+	if ca := parsed.CardinalityAnalysis; ca != nil {
+		entry.CardinalityLevel = ca.CardinalityLevel
+		entry.EstimatedSeries = ca.EstimatedSeries
+	}
+	for _, f := range profile.Check(parsed) {
+		entry.Findings = append(entry.Findings, history.GalleryFinding{Severity: string(f.Severity), Message: f.Message})
+	}
+
+	if err := h.history.Publish(entry); err != nil {
+		log.Printf("[Gallery] Error publishing evaluation %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish evaluation"})
+		return
+	}
+
+	h.recordAudit(c, audit.AuditEvent{
+		Timestamp:         entry.PublishedAt,
+		MetricFingerprint: audit.Fingerprint(record.Input),
+		Verdict:           record.Verdict,
+		Action:            audit.ActionShare,
+	})
+	c.JSON(http.StatusOK, entry)
+}
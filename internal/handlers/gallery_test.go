@@ -0,0 +1,126 @@
+// ABOUTME: Tests for publishing to the gallery - configured patterns never reach the published entry or page
+// ABOUTME: Findings and cardinality are recomputed from the scrubbed text, and only consented evaluations publish
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/anonymize"
+	"github.com/wbollock/good_telemetry/internal/history"
+)
+
+const galleryInput = `# HELP acme_billing_requests_total Requests to the acme billing API.
+# TYPE acme_billing_requests_total counter
+acme_billing_requests_total{instance="billing1.acme.internal:9100",client="10.1.2.3",team="proj-phoenix"} 3
+acme_billing_requests_total{instance="billing2.acme.internal:9100",client="10.1.2.4",team="proj-phoenix"} 5
+acme_billing_requests_total{instance="billing2.acme.internal:9100",client="10.1.2.5",team="proj-hydra"} 1
+`
+
+func galleryRouter(t *testing.T) (*gin.Engine, *Handler) {
+	t.Helper()
+	h := newTestHandler(t, newStubOllama(t, nil).URL)
+	a, err := anonymize.New([]string{`acme`, `proj-[a-z]+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetAnonymizer(a)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/gallery/:id", h.PublishEvaluation)
+	r.GET("/gallery", h.Gallery)
+	return r, h
+}
+
+func publish(r http.Handler, id int64) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/gallery/%d", id), nil))
+	return rec
+}
+
+func TestPublishedEvaluationsLeaveNoTrace(t *testing.T) {
+	r, h := galleryRouter(t)
+	record := &history.Record{Tenant: "default", CreatedAt: time.Now(), Input: galleryInput, Verdict: "Bad", ShareConsent: true}
+	if err := h.history.Add(record); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := publish(r, record.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("publish = %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry history.GalleryEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/gallery", nil)
+	req.Header.Set("Accept", "application/json")
+	page := httptest.NewRecorder()
+	r.ServeHTTP(page, req)
+	if !strings.Contains(page.Body.String(), "redacted_1_billing_requests_total") {
+		t.Fatalf("gallery = %s, want the published entry", page.Body.String())
+	}
+
+	secrets := []string{"acme", "proj-", "phoenix", "hydra", "billing1", "10.1.2.", ".internal"}
+	for _, published := range map[string]string{"entry": rec.Body.String(), "gallery": page.Body.String()} {
+		for _, secret := range secrets {
+			if strings.Contains(published, secret) {
+				t.Errorf("published output holds %q", secret)
+			}
+		}
+	}
+
+	// Same shape as the original: as many distinct values per label
+	distinct := func(input string) map[string]int {
+		parsed, err := h.Profile().Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make(map[string]map[string]bool)
+		for _, m := range parsed.Metrics {
+			for label, value := range m.Labels {
+				if values[label] == nil {
+					values[label] = make(map[string]bool)
+				}
+				values[label][value] = true
+			}
+		}
+		counts := make(map[string]int)
+		for label, v := range values {
+			counts[label] = len(v)
+		}
+		return counts
+	}
+	if got, want := distinct(entry.Input), distinct(galleryInput); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("distinct values per label = %v, want the original's %v", got, want)
+	}
+	if len(entry.Findings) == 0 {
+		t.Error("the scrubbed text was published without findings")
+	}
+}
+
+func TestPublishNeedsConsent(t *testing.T) {
+	r, h := galleryRouter(t)
+	record := &history.Record{Tenant: "default", CreatedAt: time.Now(), Input: galleryInput, Verdict: "Bad"}
+	if err := h.history.Add(record); err != nil {
+		t.Fatal(err)
+	}
+	if rec := publish(r, record.ID); rec.Code != http.StatusConflict {
+		t.Errorf("publish without consent = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := publish(r, record.ID+1); rec.Code != http.StatusNotFound {
+		t.Errorf("publish of a missing evaluation = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if entries, _ := h.history.Gallery(); len(entries) != 0 {
+		t.Errorf("gallery = %+v, want it empty", entries)
+	}
+}
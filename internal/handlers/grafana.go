@@ -0,0 +1,91 @@
+// ABOUTME: Grafana dashboard endpoint - turns submitted metrics into importable dashboard JSON
+// ABOUTME: Groups samples into families and describes each panel with the verdict and static findings
+
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/grafana"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// GrafanaDashboard returns a dashboard for the metrics form field. An optional
+// verdict, e.g. from an earlier evaluation, heads every panel description.
+func (h *Handler) GrafanaDashboard(c *gin.Context) {
+	var req struct {
+		Metrics string `form:"metrics" binding:"required"`
+		Verdict string `form:"verdict"`
+		// Set by the result page so the browser saves the file
+		Download bool `form:"download"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metrics is required"})
+		return
+	}
+
+	profile := h.requestProfile(c)
+	parsed, err := profile.Parse(req.Metrics)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dashboard, err := grafana.GenerateGrafanaDashboard(dashboardMetrics(parsed, rules.Problems(profile.Check(parsed)), req.Verdict))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Download {
+		c.Header("Content-Disposition", `attachment; filename="good-telemetry-dashboard.json"`)
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", dashboard)
+}
+
+// dashboardMetrics groups samples into one entry per family, in submission order
+func dashboardMetrics(parsed *metrics.ParsedMetrics, problems []rules.Finding, verdict string) []grafana.Metric {
+	var families []grafana.Metric
+	index := make(map[string]int)
+	for _, m := range parsed.Metrics {
+		typ := parsed.TypeOf(m.Name)
+		name := m.Name
+		if typ == "histogram" || typ == "summary" {
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				name = strings.TrimSuffix(name, suffix)
+			}
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(families)
+			index[name] = i
+			families = append(families, grafana.Metric{Name: name, Type: typ})
+		}
+		for label := range m.Labels {
+			if label != "le" && label != "quantile" && !slices.Contains(families[i].Labels, label) {
+				families[i].Labels = append(families[i].Labels, label)
+			}
+		}
+	}
+
+	for i := range families {
+		slices.Sort(families[i].Labels)
+
+		var description []string
+		if verdict != "" {
+			description = append(description, "Verdict: "+verdict)
+		}
+		for _, f := range problems {
+			if f.Metric == families[i].Name || strings.HasPrefix(f.Metric, families[i].Name+"_") {
+				description = append(description, f.Message)
+			}
+		}
+		families[i].Verdict = strings.Join(description, "\n")
+	}
+	return families
+}
@@ -4,26 +4,158 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/abuse"
+	"github.com/wbollock/good_telemetry/internal/anonymize"
+	"github.com/wbollock/good_telemetry/internal/audit"
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/cost"
+	"github.com/wbollock/good_telemetry/internal/examples"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/improve"
 	"github.com/wbollock/good_telemetry/internal/llm"
 	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/monitor"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/quota"
+	"github.com/wbollock/good_telemetry/internal/redact"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/scrapeconfig"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+	"github.com/wbollock/good_telemetry/internal/usage"
+	"github.com/wbollock/good_telemetry/pkg/api"
 )
 
 type Handler struct {
 	llmClient *llm.Client
+	history   history.Store
+	audit     *audit.Logger // nil when auditing is disabled
+	guard     *abuse.Guard  // nil when abuse protection is disabled
+	tenants   *tenant.Registry
+	budgets   *quota.Limiter // counts tenants' LLM evaluations against their daily budget
+	usage     *usage.Recorder
+
+	mu         sync.RWMutex
+	pricing    cost.Pricing
+	profile    rules.NamingProfile
+	anonymizer *anonymize.Anonymizer
+	// Applied to inputs before they are logged or stored
+	redactor *redact.Redactor
+	// Profiles of tenants that choose their own, by tenant ID
+	tenantProfiles map[string]rules.NamingProfile
+	// Set while a re-evaluation runs
+	cancelReevaluation func()
+	settings           settingsControl
+	// Scrapes the endpoints registered through the admin API
+	monitors *monitor.Scheduler
+
+	exampleRuns exampleRuns
+	// Runs the LLM halves of evaluations; see StartJobs
+	jobs      *jobQueue
+	enqueueMu sync.Mutex
+
+	// How long an identical submission reuses the first one's evaluation
+	// instead of calling the LLM again; 0 disables it
+	DeduplicationWindow time.Duration
+	// Largest label bound a submission may assert; larger ones are as good
+	// as unbounded and are rejected
+	MaxLabelBound int
 }
 
-func NewHandler(llmClient *llm.Client) *Handler {
-	return &Handler{
-		llmClient: llmClient,
+func NewHandler(llmClient *llm.Client, store history.Store, pricing cost.Pricing, profile rules.NamingProfile, anonymizer *anonymize.Anonymizer, redactor *redact.Redactor, auditLog *audit.Logger, guard *abuse.Guard, tenants *tenant.Registry, budgets *quota.Limiter, recorder *usage.Recorder) *Handler {
+	h := &Handler{
+		llmClient:  llmClient,
+		history:    store,
+		pricing:    pricing,
+		profile:    profile,
+		anonymizer: anonymizer,
+		redactor:   redactor,
+		audit:      auditLog,
+		guard:      guard,
+		tenants:    tenants,
+		budgets:    budgets,
+		usage:      recorder,
+
+		DeduplicationWindow: DefaultDeduplicationWindow,
+		MaxLabelBound:       DefaultMaxLabelBound,
 	}
+	h.jobs = newJobQueue(store, hostWorkerPrefix(), h.Redactor, h.runEvaluationJob)
+	return h
+}
+
+// StartJobs requeues the evaluation jobs this host was running when it last
+// stopped and starts the workers that run queued jobs
+func (h *Handler) StartJobs() {
+	h.jobs.start(jobWorkers)
+}
+
+func (h *Handler) Pricing() cost.Pricing {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.pricing
+}
+
+// SetPricing changes the token rates used for new evaluations, e.g. on config reload
+func (h *Handler) SetPricing(p cost.Pricing) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pricing = p
+}
+
+func (h *Handler) Profile() rules.NamingProfile {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.profile
+}
+
+// SetProfile changes the naming profile used for new evaluations, e.g. on config reload
+func (h *Handler) SetProfile(p rules.NamingProfile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.profile = p
+}
+
+func (h *Handler) Redactor() *redact.Redactor {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.redactor
+}
+
+// SetRedactor changes the patterns redacted from logged and stored inputs, e.g. on config reload
+func (h *Handler) SetRedactor(r *redact.Redactor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.redactor = r
+}
+
+// Time windows selectable on the stats page
+var statsWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
 }
 
 func (h *Handler) Index(c *gin.Context) {
-	c.HTML(http.StatusOK, "index.html", gin.H{
+	// Start a session so the evaluation cap counts from zero for visitors using the page
+	if h.guard != nil {
+		if _, err := c.Cookie(evalCookie); err != nil {
+			c.SetCookie(evalCookie, h.guard.SessionCookie(0), evalCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+		}
+	}
+
+	render(c, http.StatusOK, "index.html", gin.H{
 		"title": "Good Telemetry",
 	})
 }
@@ -31,131 +163,489 @@ func (h *Handler) Index(c *gin.Context) {
 func (h *Handler) Evaluate(c *gin.Context) {
 	log.Println("[Evaluate] Received evaluation request")
 
-	var req struct {
-		Metrics string `form:"metrics" binding:"required"`
+	req, static, llmPhase, ok := h.staticEvaluation(c, true)
+	if !ok {
+		return
+	}
+
+	// htmx pages and API clients sending wait=false get the static results
+	// right away and fetch the LLM's part from the job when it is ready
+	isJSON := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+	background := (!isJSON && c.GetHeader("HX-Request") == "true") || (isJSON && req.Wait != nil && !*req.Wait)
+	id, ok := h.evaluationJob(c, llmPhase, background && !isJSON, background)
+	if !ok {
+		return
+	}
+	if background {
+		static.page["job"], static.response.Job = id, id
+		status := http.StatusOK
+		if isJSON {
+			status = http.StatusAccepted
+		}
+		respond(c, status, "result.html", static.page, static.response)
+		return
+	}
+
+	// A client that hangs up stops the LLM call, unless another request
+	// still wants the job
+	job, ok := h.jobs.wait(c.Request.Context(), id)
+	if !ok {
+		log.Printf("[Evaluate] Client went away before job %s finished", id)
+		return
+	}
+	result, err := decodeJobResult(job)
+	if job.Status == history.JobFailed {
+		err = errors.New(job.Error)
+	}
+	if err != nil {
+		renderError(c, http.StatusInternalServerError, "error.evaluate", "Failed to evaluate metrics: "+err.Error())
+		return
+	}
+	maps.Copy(static.page, result.page())
+	static.response.ID, static.response.Evaluation = result.RecordID, apiEvaluation(result.Evaluation)
+
+	// Return evaluation result (htmx will swap this into the page)
+	respond(c, http.StatusOK, "result.html", static.page, static.response)
+}
+
+// EvaluateQuick returns the static results alone, without calling the LLM
+func (h *Handler) EvaluateQuick(c *gin.Context) {
+	log.Println("[Evaluate] Received quick evaluation request")
+
+	if _, static, _, ok := h.staticEvaluation(c, false); ok {
+		respond(c, http.StatusOK, "result.html", static.page, static.response)
 	}
+}
 
+// EvaluateFull returns the static results with the job the LLM's verdict
+// will be in, like Evaluate with wait=false
+func (h *Handler) EvaluateFull(c *gin.Context) {
+	log.Println("[Evaluate] Received full evaluation request")
+
+	_, static, llmPhase, ok := h.staticEvaluation(c, true)
+	if !ok {
+		return
+	}
+	if static.response.Job, ok = h.evaluationJob(c, llmPhase, false, true); ok {
+		static.page["job"] = static.response.Job
+		respond(c, http.StatusAccepted, "result.html", static.page, static.response)
+	}
+}
+
+// spendLLMBudgetOrReject is spendLLMBudget for the request's tenant, rejecting
+// the request once the budget is used up
+func (h *Handler) spendLLMBudgetOrReject(c *gin.Context) bool {
+	if !h.spendLLMBudget(middleware.CurrentTenant(c)) {
+		renderError(c, http.StatusTooManyRequests, "error.tenant_budget", "Today's LLM evaluation budget is used up; try again tomorrow")
+		return false
+	}
+	return true
+}
+
+// staticResult is the static part of an evaluation, as the result page's
+// template data and as the API response
+type staticResult struct {
+	page     gin.H
+	response api.EvaluateResponse
+}
+
+// staticEvaluation reads an evaluation request and runs everything short of
+// the LLM, returning its results and what the LLM phase needs.
+// Abuse protection guards the LLM, so only requests that go on to it are
+// screened. It writes the response itself when it reports false.
+func (h *Handler) staticEvaluation(c *gin.Context, screen bool) (api.EvaluateRequest, staticResult, evaluationLLMPhase, bool) {
+	var req api.EvaluateRequest
 	if err := c.ShouldBind(&req); err != nil {
 		log.Printf("[Evaluate] Error binding request: %v", err)
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"error": "Please provide metrics to evaluate",
-		})
-		return
+		renderError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+	if err := req.Validate(); err != nil {
+		renderError(c, http.StatusBadRequest, requestErrorKey(err), err.Error())
+		return req, staticResult{}, evaluationLLMPhase{}, false
 	}
 
-	log.Printf("[Evaluate] Input metrics:\n%s", req.Metrics)
+	// Results are per-request and not worth indexing
+	c.Header("X-Robots-Tag", "noindex")
+
+	if req.Model != "" && !h.llmClient.AllowsModel(req.Model) {
+		renderError(c, http.StatusBadRequest, "error.model_not_allowed",
+			fmt.Sprintf("Model %q is not allowed; choose one of %s", req.Model, strings.Join(h.llmClient.AllowedModels(), ", ")))
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+
+	var scrapeConfig *scrapeconfig.ScrapeConfig
+	if req.ScrapeConfig != "" {
+		var err error
+		if scrapeConfig, err = scrapeconfig.Parse(req.ScrapeConfig); err != nil {
+			renderError(c, http.StatusBadRequest, "error.scrape_config", err.Error())
+			return req, staticResult{}, evaluationLLMPhase{}, false
+		}
+	}
+
+	// Formatting isn't an evaluation, so it skips abuse protection
+	if req.Canonicalize {
+		h.canonicalize(c, req.Metrics)
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+
+	if screen && !h.screenEvaluation(c, req) {
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+
+	redactor := h.Redactor()
+	log.Printf("[Evaluate] Input metrics:\n%s", redactor.Redact(req.Metrics))
+
+	if len(req.LabelBounds) > 0 && !h.checkLabelBounds(c, req.LabelBounds) {
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+	profile := h.requestProfile(c).WithLabelBounds(req.LabelBounds)
 
 	// Parse metrics
-	parsed, err := metrics.Parse(req.Metrics)
+	parsed, err := profile.Parse(req.Metrics)
 	if err != nil {
-		log.Printf("[Evaluate] Error parsing metrics: %v", err)
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"error": err.Error(),
-		})
+		log.Printf("[Evaluate] Error parsing metrics: %s", redactor.Redact(err.Error()))
+		renderError(c, http.StatusBadRequest, "error.parse", err.Error())
+		return req, staticResult{}, evaluationLLMPhase{}, false
+	}
+
+	if scrapeConfig != nil {
+		if parsed, err = scrape(profile, parsed, scrapeConfig); err != nil {
+			log.Printf("[Evaluate] Error simulating the scrape: %v", err)
+			renderError(c, http.StatusBadRequest, "error.scrape_config", err.Error())
+			return req, staticResult{}, evaluationLLMPhase{}, false
+		}
+	}
+
+	deploysPerDay := req.DeploysPerDay
+	if deploysPerDay <= 0 {
+		deploysPerDay = cardinality.DefaultDeploysPerDay
+	}
+	parsed.CardinalityAnalysis.ApplyChurn(deploysPerDay)
+
+	// Standard runtime metrics still count towards cardinality but aren't judged
+	// unless asked for. Textfiles keep them, as there they collide with node_exporter's own.
+	evaluated, runtime := parsed, []string(nil)
+	if !req.IncludeRuntime && req.Source != rules.TextfileSource {
+		evaluated, runtime = naming.ExcludeRuntime(parsed)
+	}
+
+	findings := profile.Check(evaluated)
+	if scrapeConfig != nil {
+		findings = append(findings, scrapeConfig.Check()...)
+	}
+	instructions := profile.PromptInstructions
+	if req.Source == rules.TextfileSource {
+		findings = append(findings, rules.CheckTextfile(parsed)...)
+		if instructions != "" {
+			instructions += "\n\n"
+		}
+		instructions += rules.TextfilePromptInstructions
+	}
+	if req.PushGatewayMode {
+		if instructions != "" {
+			instructions += "\n\n"
+		}
+		instructions += llm.PushgatewayInstructions
+	}
+	if len(req.LabelBounds) > 0 {
+		if instructions != "" {
+			instructions += "\n\n"
+		}
+		instructions += llm.LabelBoundsInstructions(req.LabelBounds)
+	}
+
+	h.usage.RecordEvaluation(findings, len(req.Metrics))
+
+	log.Printf("[Evaluate] Parsed %d metric(s), %d runtime metric name(s) excluded, %d static finding(s)",
+		len(parsed.Metrics), len(runtime), len(findings))
+
+	// The static rewrites and namespace tree follow Prometheus naming, which
+	// VictoriaMetrics and Mimir share
+	var staticExample string
+	var namespaces []rules.NamespaceGroup
+	var summaryMigrations []rules.SummaryMigration
+	var recordingRules string
+	if profile.Name == rules.DefaultProfile || profile.Name == rules.VictoriaMetricsProfile || profile.Name == rules.MimirProfile {
+		staticExample, _ = improve.Example(evaluated, profile.LabelOrder())
+		recordingRules = improve.RecordingRules(evaluated)
+		namespaces = rules.NamespaceTree(evaluated)
+		summaryMigrations = rules.SummaryMigrations(evaluated)
+	}
+	if profile.Name == rules.VictoriaMetricsProfile {
+		if queries := improve.MetricsQL(evaluated); staticExample == "" {
+			staticExample = queries
+		} else if queries != "" {
+			staticExample += "\n\n" + queries
+		}
+	}
+
+	praise, problems := rules.Praise(findings), rules.Problems(findings)
+	static := staticResult{
+		page: gin.H{
+			"metrics":         evaluated,
+			"runtimeExcluded": len(runtime),
+			"praise":          praise,
+			"problems":        problems,
+			"staticExample":   staticExample,
+			"namespaces":      namespaces,
+			"summaries":       summaryMigrations,
+			"recordingRules":  recordingRules,
+			"histograms":      brokenHistograms(evaluated),
+			"heatMap":         labelHeatMap(evaluated.CardinalityAnalysis),
+		},
+		response: api.EvaluateResponse{
+			Problems:        apiFindings(problems),
+			Praise:          apiFindings(praise),
+			Cardinality:     apiCardinality(evaluated.CardinalityAnalysis),
+			StaticExample:   staticExample,
+			RecordingRules:  recordingRules,
+			RuntimeExcluded: len(runtime),
+		},
+	}
+
+	// Read from the request now, as the LLM phase may outlive it
+	llmPhase := evaluationLLMPhase{
+		tenant:         middleware.CurrentTenant(c).ID,
+		evaluated:      evaluated,
+		parsed:         parsed,
+		findings:       findings,
+		instructions:   instructions,
+		fingerprint:    h.engineFingerprint(profile),
+		model:          req.Model,
+		input:          req.Metrics,
+		shareConsent:   req.ShareConsent,
+		labelBounds:    req.LabelBounds,
+		scrapeConfig:   req.ScrapeConfig,
+		includeRuntime: req.IncludeRuntime,
+		redactor:       redactor,
+		audit:          h.auditIdentity(c, audit.AuditEvent{Action: audit.ActionEvaluate}),
+	}
+	return req, static, llmPhase, true
+}
+
+// canonicalize responds with input rewritten with every sample's labels in
+// the profile's order, as text or as {"canonical": ...} for JSON clients
+func (h *Handler) canonicalize(c *gin.Context, input string) {
+	profile := h.requestProfile(c)
+	parsed, err := profile.Parse(input)
+	if err == nil && parsed.Selector {
+		err = errors.New("only samples can be canonicalized, not PromQL selectors")
+	}
+	if err != nil {
+		renderError(c, http.StatusBadRequest, "error.parse", err.Error())
+		return
+	}
+
+	canonical := profile.LabelOrder().Canonical(parsed)
+	if c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, gin.H{"canonical": canonical})
 		return
 	}
+	c.String(http.StatusOK, canonical)
+}
+
+// requestErrorKey is the message catalog key for an EvaluateRequest validation error
+func requestErrorKey(err error) string {
+	var reqErr *api.RequestError
+	switch {
+	case errors.Is(err, api.ErrNoMetrics):
+		return "error.no_metrics"
+	case errors.As(err, &reqErr) && reqErr.Field == "source":
+		return "error.unknown_source"
+	}
+	return "error.invalid_request"
+}
+
+// DefaultMaxLabelBound is the largest label bound accepted unless configured otherwise
+const DefaultMaxLabelBound = 100_000
+
+// checkLabelBounds rejects bounds above MaxLabelBound, writing the response
+// itself when it reports false
+func (h *Handler) checkLabelBounds(c *gin.Context, bounds api.LabelBounds) bool {
+	for _, name := range slices.Sorted(maps.Keys(bounds)) {
+		if bounds[name] > h.MaxLabelBound {
+			renderError(c, http.StatusBadRequest, "error.label_bound", fmt.Sprintf(
+				"The bound of %d on label %s is above the limit of %d; a label with that many values is effectively unbounded, so reduce its values rather than asserting a bound",
+				bounds[name], name, h.MaxLabelBound))
+			return false
+		}
+	}
+	return true
+}
 
-	log.Printf("[Evaluate] Parsed %d metric(s), sending to LLM...", len(parsed.Metrics))
+// evaluationLLMPhase is what the LLM half of an evaluation needs from the request
+type evaluationLLMPhase struct {
+	tenant            string
+	evaluated, parsed *metrics.ParsedMetrics
+	findings          []rules.Finding
+	instructions      string
+	// Engine fingerprint of the profile that judged the input
+	fingerprint  string
+	model        string
+	input        string
+	shareConsent bool
+	labelBounds  api.LabelBounds
+	// Only tell duplicate submissions apart; the static phase applied them
+	scrapeConfig   string
+	includeRuntime bool
+	redactor       *redact.Redactor
+	// Carries the requester's identity
+	audit audit.AuditEvent
+}
 
-	// Evaluate with LLM
-	evaluation, err := h.llmClient.Evaluate(parsed)
+// runEvaluationJob asks the LLM for its verdict and records the evaluation,
+// returning the record's id and evaluation for the result page
+func (h *Handler) runEvaluationJob(ctx context.Context, p jobPayload) (jobResult, error) {
+	log.Printf("[Evaluate] Sending %d metric(s) to the LLM...", len(p.Evaluated.Metrics))
+
+	evaluation, err := h.llmClient.Evaluate(ctx, p.Evaluated, p.Instructions, p.EngineFingerprint, p.Model)
 	if err != nil {
 		log.Printf("[Evaluate] Error calling LLM: %v", err)
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": "Failed to evaluate metrics: " + err.Error(),
-		})
-		return
+		return jobResult{}, err
 	}
 
-	log.Printf("[Evaluate] LLM evaluation complete. Verdict: %s", evaluation.Verdict)
+	problems := 0
+	for _, f := range p.Findings {
+		if f.Severity == rules.SeverityError || f.Severity == rules.SeverityWarning {
+			problems++
+		}
+	}
+	evaluation.CompareStatic(problems)
 
-	// Return evaluation result (htmx will swap this into the page)
-	c.HTML(http.StatusOK, "result.html", gin.H{
-		"evaluation": evaluation,
-		"metrics":    parsed,
-	})
+	log.Printf("[Evaluate] LLM evaluation complete. Verdict: %s (%s confidence)", evaluation.Verdict, evaluation.Confidence.Level)
+	h.usage.RecordVerdict(evaluation.Verdict)
+
+	record := &history.Record{
+		CreatedAt:       time.Now(),
+		Tenant:          p.Tenant,
+		Input:           p.Input,
+		Verdict:         evaluation.Verdict,
+		Model:           evaluation.Model,
+		PromptChars:     evaluation.PromptChars,
+		ResponseChars:   evaluation.ResponseChars,
+		PromptTokens:    evaluation.PromptTokens,
+		ResponseTokens:  evaluation.ResponseTokens,
+		TokensEstimated: evaluation.TokensEstimated,
+		Cost:            h.Pricing().Cost(evaluation.PromptTokens, evaluation.ResponseTokens),
+		Samples:         catalogSamples(p.Parsed, h.Redactor()),
+		ShareConsent:    p.ShareConsent,
+		FindingCodes:    findingCodes(p.Findings),
+		LabelBounds:     p.LabelBounds,
+	}
+	if err := h.history.Add(record); err != nil {
+		// History is bookkeeping; the user still gets their result
+		log.Printf("[Evaluate] Error recording history: %v", err)
+	}
+
+	event := p.Audit
+	event.Timestamp = record.CreatedAt
+	event.MetricFingerprint = p.InputFingerprint
+	event.Verdict = evaluation.Verdict
+	event.Score = evaluation.OverallScore
+	h.logAudit(event)
+
+	result := jobResult{RecordID: record.ID, Evaluation: evaluation}
+	if p.ForPage {
+		// The LLM fragment's Grafana export and strengths heading need these
+		redactor := h.Redactor()
+		result.ExportLines = make([]string, len(p.Evaluated.Metrics))
+		for i, m := range p.Evaluated.Metrics {
+			result.ExportLines[i] = redactor.Redact(m.Raw)
+		}
+		result.Praised = len(rules.Praise(p.Findings)) > 0
+	}
+	return result, nil
 }
 
-func (h *Handler) Examples(c *gin.Context) {
-	examples := getHardcodedExamples()
-	c.HTML(http.StatusOK, "examples.html", gin.H{
-		"examples": examples,
-	})
+// scrape re-parses metrics as the series the scrape job would store after relabeling
+func scrape(profile rules.NamingProfile, parsed *metrics.ParsedMetrics, sc *scrapeconfig.ScrapeConfig) (*metrics.ParsedMetrics, error) {
+	scraped := sc.Scrape(parsed.Metrics)
+	if len(scraped) == 0 {
+		return nil, errors.New("the scrape config's relabeling drops every target or sample, so nothing would be stored")
+	}
+
+	var sb strings.Builder
+	if err := metrics.WriteText(&sb, scraped, parsed.Types, parsed.Help); err != nil {
+		return nil, err
+	}
+	scrapedParsed, err := profile.Parse(sb.String())
+	if err != nil {
+		return nil, err
+	}
+	scrapedParsed.Warnings = parsed.Warnings
+	scrapedParsed.Cleaned = parsed.Cleaned
+	scrapedParsed.Normalized = parsed.Normalized
+	return scrapedParsed, nil
 }
 
-// Hardcoded showcase examples
-func getHardcodedExamples() []Example {
-	return []Example{
-		{
-			Metrics: `http_requests_total{method="GET", handler="/api/users", status="200"} 1027`,
-			Verdict: "Good",
-			Issues:  []string{},
-			Recommendations: []string{
-				"This is a well-structured counter metric",
-				"Uses appropriate _total suffix",
-				"Labels are low-cardinality and meaningful",
-			},
-			CardinalityEstimate: "Low (3 methods × ~10 handlers × 5 status codes = ~150 series)",
-			MemoryEstimate:      "~3KB RAM per series = ~450KB total",
-		},
-		{
-			Metrics: `api_response_time{user_id="12345", endpoint="/profile"} 0.234`,
-			Verdict: "Needs Improvement",
-			Issues: []string{
-				"user_id is unbounded high-cardinality label",
-				"Missing _seconds suffix for time measurement",
-				"Should be a histogram, not gauge",
-			},
-			Recommendations: []string{
-				"Remove user_id label - use it in logs instead",
-				"Rename to api_response_duration_seconds",
-				"Convert to histogram for percentile calculations",
-			},
-			CardinalityEstimate: "CRITICAL: Unbounded (1 series per user × endpoints = potentially millions)",
-			MemoryEstimate:      "Could easily exceed 10GB+ with 100k users",
-		},
-		{
-			Metrics: `cache_hit_ratio 0.87`,
-			Verdict: "Needs Improvement",
-			Issues: []string{
-				"Ratio should be calculated in queries, not stored as metric",
-				"Missing labels to identify which cache",
-			},
-			Recommendations: []string{
-				"Store cache_hits_total and cache_misses_total instead",
-				"Add cache_name label",
-				"Calculate ratio: cache_hits_total / (cache_hits_total + cache_misses_total)",
-			},
-			CardinalityEstimate: "N/A - antipattern",
-			MemoryEstimate:      "N/A",
-		},
-		{
-			Metrics: `volume_attachment{vol="vol-abc123", inode="1048576", timestamp="1729783200", cluster="prod-east"} 1`,
-			Verdict: "Poor",
-			Issues: []string{
-				"vol label creates series per volume (2566+ unique values)",
-				"inode label is extremely high-cardinality (529+ unique values)",
-				"timestamp as label is a cardinal sin - creates infinite series",
-				"Combines multiple unbounded labels = cardinality explosion",
-			},
-			Recommendations: []string{
-				"Remove vol label - aggregate at pool/cluster level instead",
-				"Remove inode completely - use logs for per-inode tracking",
-				"NEVER use timestamp as a label - Prometheus already timestamps samples",
-				"Keep only cluster/pool labels for aggregation",
-				"Real example: 2566 vols × 529 inodes × 1606 timestamps = 2.18 BILLION series",
-			},
-			CardinalityEstimate: "CATASTROPHIC: 2.18+ billion potential series (2566 vol × 529 inode × 1606 timestamp)",
-			MemoryEstimate:      "6.5+ TB RAM required (likely to crash Prometheus entirely)",
-		},
+// findingCodes lists the codes of the findings that aren't praise, in order
+func findingCodes(findings []rules.Finding) []string {
+	codes := []string{}
+	for _, f := range rules.Problems(findings) {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+// catalogSamples lists the samples for the metric catalog, with label values redacted
+func catalogSamples(parsed *metrics.ParsedMetrics, redactor *redact.Redactor) []history.Sample {
+	samples := make([]history.Sample, 0, len(parsed.Metrics))
+	for _, m := range parsed.Metrics {
+		labels := make(map[string]string, len(m.Labels))
+		for name, value := range m.Labels {
+			labels[name] = redactor.Redact(value)
+		}
+		samples = append(samples, history.Sample{
+			Name:   m.Name,
+			Type:   parsed.TypeOf(m.Name),
+			Labels: labels,
+		})
+	}
+	return samples
+}
+
+func (h *Handler) Stats(c *gin.Context) {
+	window := c.DefaultQuery("window", "7d")
+	duration, ok := statsWindows[window]
+	if !ok {
+		window, duration = "7d", statsWindows["7d"]
+	}
+
+	t := middleware.CurrentTenant(c)
+	stats, err := h.history.Stats(t.ID, time.Now().Add(-duration))
+	if err != nil {
+		log.Printf("[Stats] Error aggregating history: %v", err)
+		renderError(c, http.StatusInternalServerError, "error.stats", "Failed to load usage statistics")
+		return
+	}
+
+	// Re-evaluations span every tenant, so only the default tenant's page reports them
+	var reevaluation *reevaluationReport
+	if t.ID == tenant.DefaultID {
+		if reevaluation, err = h.latestReevaluation(); err != nil {
+			// The usage figures are still worth showing
+			log.Printf("[Stats] Error reading the latest re-evaluation: %v", err)
+		}
 	}
+
+	render(c, http.StatusOK, "stats.html", gin.H{
+		"reevaluation":  reevaluation,
+		"tenant":        t.ID,
+		"title":         "Usage Stats - Good Telemetry",
+		"subtitle":      "Usage and Prompt Cost",
+		"window":        window,
+		"windows":       []string{"24h", "7d", "30d"},
+		"stats":         stats,
+		"projectedCost": cost.ProjectMonthly(stats.Cost, duration.Hours()/24),
+		"pricing":       h.Pricing(),
+	})
 }
 
-type Example struct {
-	Metrics             string
-	Verdict             string
-	Issues              []string
-	Recommendations     []string
-	CardinalityEstimate string
-	MemoryEstimate      string
+func (h *Handler) Examples(c *gin.Context) {
+	showcase := examples.All()
+	render(c, http.StatusOK, "examples.html", gin.H{
+		"title":    "Example Evaluations - Good Telemetry",
+		"examples": showcase,
+	})
 }
@@ -0,0 +1,75 @@
+// ABOUTME: Label heat map layout - places the label pair matrix in SVG units for the result page
+// ABOUTME: Hovering a cell shows its exact combination count below the matrix, with CSS alone
+
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+)
+
+const (
+	// Room for the row and column labels, in SVG units
+	heatMapMargin = 110
+	heatMapCell   = 28
+	// Room below the matrix for the hovered cell's count
+	heatMapFooter = 24
+)
+
+type heatMapLabel struct {
+	Name string
+	// Position of the label left of its row and above its column
+	RowY    int
+	ColumnX int
+}
+
+type heatMapRect struct {
+	cardinality.HeatMapCell
+	X, Y    int
+	Opacity string
+}
+
+type heatMap struct {
+	Width, Height int
+	CellSize      int
+	// Where the labels end, left of the rows and above the columns
+	LabelEdge int
+	// Baseline of the hovered cell's count
+	FooterY int
+	Labels  []heatMapLabel
+	Cells   []heatMapRect
+}
+
+// labelHeatMap lays out the label pairs of analysis, or returns nil when there
+// are too few labels for pairs
+func labelHeatMap(analysis *cardinality.Analysis) *heatMap {
+	cells := cardinality.GenerateHeatMapData(analysis)
+	if cells == nil {
+		return nil
+	}
+	side := heatMapMargin + len(cells)*heatMapCell
+	m := &heatMap{
+		Width:     side,
+		Height:    side + heatMapFooter,
+		CellSize:  heatMapCell,
+		LabelEdge: heatMapMargin - 6,
+		FooterY:   side + heatMapFooter - 6,
+	}
+	for i, row := range cells {
+		m.Labels = append(m.Labels, heatMapLabel{
+			Name:    row[0].Row,
+			RowY:    heatMapMargin + i*heatMapCell + heatMapCell/2,
+			ColumnX: heatMapMargin + i*heatMapCell + heatMapCell/2,
+		})
+		for j, cell := range row {
+			m.Cells = append(m.Cells, heatMapRect{
+				HeatMapCell: cell,
+				X:           heatMapMargin + j*heatMapCell,
+				Y:           heatMapMargin + i*heatMapCell,
+				Opacity:     fmt.Sprintf("%.2f", cell.Intensity),
+			})
+		}
+	}
+	return m
+}
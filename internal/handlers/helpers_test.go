@@ -0,0 +1,83 @@
+// ABOUTME: Shared test fixtures for the handlers - a Handler over in-memory history and a stub Ollama
+// ABOUTME: The stub answers every generate call with one canned evaluation and counts the calls
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cost"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/redact"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+	"github.com/wbollock/good_telemetry/internal/usage"
+)
+
+const stubEvaluation = `VERDICT: Good
+SCORE: 90
+STRENGTHS:
+- Uses the _total suffix
+ISSUES:
+- None
+RECOMMENDATIONS:
+- Keep it up
+IMPROVED EXAMPLE:
+# HELP http_requests_total Total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 1
+`
+
+// stubOllama serves generate calls with stubEvaluation, after release is
+// closed when it isn't nil
+type stubOllama struct {
+	*httptest.Server
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func newStubOllama(t *testing.T, release chan struct{}) *stubOllama {
+	t.Helper()
+	s := &stubOllama{release: release}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		s.calls.Add(1)
+		if s.release != nil {
+			select {
+			case <-s.release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"response": stubEvaluation, "done": true, "prompt_eval_count": 300, "eval_count": 50})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestHandler builds a Handler with the default profile and in-memory
+// history, calling the LLM at llmURL, with its job workers running
+func newTestHandler(t *testing.T, llmURL string) *Handler {
+	t.Helper()
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(llm.NewClient(llmURL, "llama3.2:3b"), history.NewMemoryStore(history.DefaultMemorySize), cost.Pricing{},
+		profile, nil, redactor, nil, nil, tenant.NewRegistry(nil), nil, usage.NewRecorder())
+	h.StartJobs()
+	t.Cleanup(h.jobs.shutdown)
+	return h
+}
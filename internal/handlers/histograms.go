@@ -0,0 +1,44 @@
+// ABOUTME: Broken histogram view model - the bucket table of each histogram series that breaks an le invariant
+// ABOUTME: Rows keep exposition order and carry the message keys of what they break, for highlighting
+
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+type histogramRow struct {
+	LE    string
+	Count string
+	// Catalog keys of the invariants the bucket breaks
+	Violations []string
+}
+
+type histogramTable struct {
+	Series     string
+	MissingInf bool
+	Rows       []histogramRow
+}
+
+// brokenHistograms lays out the buckets of every histogram series that
+// breaks an invariant; sound series need no table
+func brokenHistograms(parsed *metrics.ParsedMetrics) []histogramTable {
+	var tables []histogramTable
+	for _, s := range parsed.Histograms() {
+		if s.Valid() {
+			continue
+		}
+		table := histogramTable{Series: s.String(), MissingInf: s.MissingInf}
+		for _, b := range s.Buckets {
+			row := histogramRow{LE: b.LE, Count: strconv.FormatFloat(b.Count, 'g', -1, 64)}
+			for _, v := range b.Violations {
+				row.Violations = append(row.Violations, "histogram."+string(v))
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
@@ -0,0 +1,495 @@
+// ABOUTME: Evaluation jobs - the LLM half of an evaluation, queued in the history store and run by workers
+// ABOUTME: Jobs survive a restart; htmx polls a job until its verdict is ready, API clients opt in with wait=false
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/audit"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/redact"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const (
+	// How long a finished job's result can be fetched
+	evaluationJobTTL = 10 * time.Minute
+	// Workers running jobs in each process
+	jobWorkers = 4
+	// How long a claimed job stays its worker's without a renewal; a worker
+	// renews it three times per lease while the LLM call runs
+	jobLease = time.Minute
+	// Claims of one job before it fails, so a job that keeps taking its
+	// worker down isn't retried forever
+	maxJobAttempts = 3
+	// How often idle workers look for jobs whose lease ran out, and waiting
+	// requests look for jobs finished by another process
+	jobPollInterval = time.Second
+)
+
+// jobPayload is what a worker needs to run a job's LLM phase
+type jobPayload struct {
+	Tenant            string
+	Evaluated, Parsed *metrics.ParsedMetrics
+	Findings          []rules.Finding
+	Instructions      string
+	EngineFingerprint string
+	Model             string
+	// Redacted, as the raw input is only needed for its fingerprint
+	Input            string
+	InputFingerprint string
+	ShareConsent     bool
+	LabelBounds      api.LabelBounds
+	Audit            audit.AuditEvent
+	// The result page's LLM fragment is rendered from the result
+	ForPage bool
+}
+
+// jobResult is a finished job's outcome
+type jobResult struct {
+	RecordID   int64
+	Evaluation *llm.Evaluation
+	// For the result page's LLM fragment: the redacted sample lines its
+	// Grafana export posts, and whether the static results had any praise
+	ExportLines []string
+	Praised     bool
+}
+
+// jobQueue runs evaluation jobs from the history store's queue. The store
+// holds each job's state, so a job outlives the request and the process that
+// queued it; the queue tracks what only this process knows, which requests
+// wait on a job, how to stop the worker running it, and the payloads it
+// queued before redaction.
+//
+// Payloads and results are stored with secrets redacted, so the database
+// never holds them in plaintext. A job recovered after a restart therefore
+// runs on the redacted input.
+type jobQueue struct {
+	store history.Store
+	run   func(ctx context.Context, p jobPayload) (jobResult, error)
+	// Redacts what goes into the store; nil stores it as is
+	redactor func() *redact.Redactor
+	// Starts the names of this process's workers
+	workerPrefix string
+	// Tells an idle worker a job was queued
+	wake chan struct{}
+	// Ends the workers; a job they are running stays claimed until a
+	// restart requeues it or its lease runs out
+	ctx     context.Context
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+
+	mu    sync.Mutex
+	local map[string]*localJob
+	// Payloads this process queued, as submitted, until they run or expire
+	payloads map[string]queuedPayload
+}
+
+type queuedPayload struct {
+	payload  jobPayload
+	queuedAt time.Time
+}
+
+// localJob is a job as seen by this process
+type localJob struct {
+	// Requests blocked in wait
+	waiters int
+	// Closed once a worker of this process finishes the job
+	done chan struct{}
+	// Set while a worker of this process runs the job
+	cancel context.CancelFunc
+}
+
+// hostWorkerPrefix names the workers of this host, so a restarted process
+// requeues the jobs it was running before. Processes sharing a database
+// must run on different hosts.
+func hostWorkerPrefix() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return host + "/"
+}
+
+func newJobQueue(store history.Store, workerPrefix string, redactor func() *redact.Redactor, run func(ctx context.Context, p jobPayload) (jobResult, error)) *jobQueue {
+	ctx, stop := context.WithCancel(context.Background())
+	return &jobQueue{
+		store:        store,
+		run:          run,
+		redactor:     redactor,
+		workerPrefix: workerPrefix,
+		wake:         make(chan struct{}, 1),
+		ctx:          ctx,
+		stop:         stop,
+		local:        make(map[string]*localJob),
+		payloads:     make(map[string]queuedPayload),
+	}
+}
+
+// start requeues the jobs this host's workers were running when the process
+// last stopped, then starts n workers
+func (q *jobQueue) start(n int) {
+	requeued, err := q.store.RequeueJobs(q.workerPrefix)
+	if err != nil {
+		log.Printf("[Jobs] Error recovering jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("[Jobs] Requeued %d job(s) left running by the last run", requeued)
+		selfmetrics.JobsRecovered.Add(float64(requeued))
+	}
+
+	q.workers.Add(n)
+	for i := range n {
+		go q.work(fmt.Sprintf("%s%d/%d", q.workerPrefix, os.Getpid(), i))
+	}
+}
+
+// shutdown stops the workers and waits for them to return
+func (q *jobQueue) shutdown() {
+	q.stop()
+	q.workers.Wait()
+}
+
+func (q *jobQueue) work(worker string) {
+	defer q.workers.Done()
+	for q.ctx.Err() == nil {
+		q.reportStats()
+		now := time.Now()
+		job, err := q.store.ClaimJob(worker, now, now.Add(jobLease), maxJobAttempts)
+		if err == nil {
+			q.runJob(worker, job)
+			continue
+		}
+		if !errors.Is(err, history.ErrNotFound) {
+			log.Printf("[Jobs] Error claiming a job: %v", err)
+		}
+		select {
+		case <-q.wake:
+		case <-time.After(jobPollInterval):
+		case <-q.ctx.Done():
+		}
+	}
+}
+
+func (q *jobQueue) reportStats() {
+	stats, err := q.store.JobQueueStats()
+	if err != nil {
+		log.Printf("[Jobs] Error reading the queue: %v", err)
+		return
+	}
+	selfmetrics.JobQueueDepth.Set(float64(stats.Depth))
+	age := 0.0
+	if !stats.OldestCreatedAt.IsZero() {
+		age = time.Since(stats.OldestCreatedAt).Seconds()
+	}
+	selfmetrics.JobQueueOldestAge.Set(age)
+}
+
+// runJob runs a claimed job and records its outcome, unless the worker lost
+// the job meanwhile
+func (q *jobQueue) runJob(worker string, job history.Job) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	q.mu.Lock()
+	lj := q.localJob(job.ID)
+	lj.cancel = cancel
+	q.mu.Unlock()
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(jobLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := q.store.RenewJobLease(job.ID, worker, time.Now().Add(jobLease)); err != nil {
+					// Cancelled, or taken over after a missed renewal
+					log.Printf("[Jobs] Stopping job %s: %v", job.ID, err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	var result []byte
+	var err error
+	q.mu.Lock()
+	queued, ok := q.payloads[job.ID]
+	q.mu.Unlock()
+	p := queued.payload
+	if !ok {
+		// Queued by another process, or before a restart
+		err = gob.NewDecoder(bytes.NewReader(job.Payload)).Decode(&p)
+	}
+	if err == nil {
+		var r jobResult
+		if r, err = q.run(ctx, p); err == nil {
+			result, err = q.encodeRedacted(&r, &jobResult{})
+		}
+	}
+	cancel()
+	<-renewed
+
+	if q.ctx.Err() != nil {
+		// Stopping: the job is left claimed for a restart to requeue
+		return
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if err := q.store.FinishJob(job.ID, worker, result, errMsg, time.Now()); err != nil {
+		if !errors.Is(err, history.ErrLeaseLost) {
+			log.Printf("[Jobs] Error recording job %s: %v", job.ID, err)
+		}
+	}
+
+	q.mu.Lock()
+	close(lj.done)
+	lj.cancel = nil
+	if q.local[job.ID] == lj {
+		delete(q.local, job.ID)
+	}
+	delete(q.payloads, job.ID)
+	q.mu.Unlock()
+}
+
+// localJob returns this process's view of a job, adding it if needed. q.mu
+// must be held.
+func (q *jobQueue) localJob(id string) *localJob {
+	lj, ok := q.local[id]
+	if !ok {
+		lj = &localJob{done: make(chan struct{})}
+		q.local[id] = lj
+	}
+	return lj
+}
+
+// reuse returns the job with idempotency key key that ReuseJob would, and
+// whether there was one. An empty key matches no job.
+func (q *jobQueue) reuse(key string, reuseSince time.Time, polled bool) (history.Job, bool, error) {
+	if key == "" {
+		return history.Job{}, false, nil
+	}
+	job, err := q.store.ReuseJob(key, reuseSince, polled)
+	if errors.Is(err, history.ErrNotFound) {
+		return history.Job{}, false, nil
+	}
+	return job, err == nil, err
+}
+
+// enqueue queues p for tenant under idempotency key key and returns the job's
+// ID. A polled job's result is fetched later, so it is never cancelled.
+func (q *jobQueue) enqueue(tenant, key string, polled bool, p jobPayload) (string, error) {
+	payload, err := q.encodeRedacted(&p, &jobPayload{})
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	now := time.Now()
+	job := history.Job{
+		ID:        hex.EncodeToString(b),
+		Tenant:    tenant,
+		Key:       key,
+		Payload:   payload,
+		Status:    history.JobQueued,
+		Polled:    polled,
+		CreatedAt: now,
+	}
+
+	if err := q.store.PruneJobs(now.Add(-evaluationJobTTL)); err != nil {
+		log.Printf("[Jobs] Error pruning jobs: %v", err)
+	}
+	q.mu.Lock()
+	// Payloads of jobs another process ran or that were cancelled elsewhere
+	for id, queued := range q.payloads {
+		if queued.queuedAt.Before(now.Add(-evaluationJobTTL)) {
+			delete(q.payloads, id)
+		}
+	}
+	q.payloads[job.ID] = queuedPayload{payload: p, queuedAt: now}
+	q.mu.Unlock()
+	if err := q.store.EnqueueJob(&job); err != nil {
+		q.mu.Lock()
+		delete(q.payloads, job.ID)
+		q.mu.Unlock()
+		return "", err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job.ID, nil
+}
+
+// cancel fails a job nobody polls and stops it if it runs here
+func (q *jobQueue) cancel(id, reason string) {
+	cancelled, err := q.store.CancelJob(id, reason, time.Now())
+	if err != nil {
+		log.Printf("[Jobs] Error cancelling job %s: %v", id, err)
+	}
+	if !cancelled {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.payloads, id)
+	if lj, ok := q.local[id]; ok && lj.cancel != nil {
+		lj.cancel()
+	}
+}
+
+// wait blocks until the job is finished and returns it, reporting false when
+// ctx ends first. When the last waiting request gives up on a job nobody
+// polls for, the job is cancelled, as no one is left to read its verdict.
+func (q *jobQueue) wait(ctx context.Context, id string) (history.Job, bool) {
+	q.mu.Lock()
+	lj := q.localJob(id)
+	lj.waiters++
+	q.mu.Unlock()
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	done := lj.done
+	for {
+		job, err := q.store.Job(id)
+		if err != nil {
+			job = history.Job{ID: id, Status: history.JobFailed, Error: err.Error()}
+		}
+		finished := job.Status == history.JobDone || job.Status == history.JobFailed
+		if !finished {
+			select {
+			case <-done:
+				done = nil
+				continue
+			case <-ticker.C:
+				continue
+			case <-ctx.Done():
+			}
+		}
+
+		q.mu.Lock()
+		lj.waiters--
+		last := lj.waiters == 0
+		if last && lj.cancel == nil && q.local[id] == lj {
+			delete(q.local, id)
+		}
+		q.mu.Unlock()
+		if !finished && last {
+			q.cancel(id, "cancelled: every request waiting for it went away")
+		}
+		return job, finished
+	}
+}
+
+func encodeGob(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeRedacted gob-encodes v with every string redacted, leaving v as it
+// is: a copy decoded into scratch is redacted and encoded instead
+func (q *jobQueue) encodeRedacted(v, scratch any) ([]byte, error) {
+	data, err := encodeGob(v)
+	if err != nil || q.redactor == nil {
+		return data, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(scratch); err != nil {
+		return nil, err
+	}
+	q.redactor().RedactAll(scratch)
+	return encodeGob(scratch)
+}
+
+func decodeJobResult(job history.Job) (jobResult, error) {
+	var r jobResult
+	if err := gob.NewDecoder(bytes.NewReader(job.Result)).Decode(&r); err != nil {
+		return r, fmt.Errorf("reading job result: %w", err)
+	}
+	return r, nil
+}
+
+// page is what a finished job adds to the result page: the id and
+// evaluation, and for page jobs what the LLM fragment needs from the static
+// results
+func (r jobResult) page() gin.H {
+	data := gin.H{"id": r.RecordID, "evaluation": r.Evaluation}
+	if r.ExportLines != nil {
+		exported := &metrics.ParsedMetrics{Metrics: make([]metrics.Metric, len(r.ExportLines))}
+		for i, line := range r.ExportLines {
+			exported.Metrics[i].Raw = line
+		}
+		data["metrics"], data["praise"] = exported, r.Praised
+	}
+	return data
+}
+
+// EvaluationJob returns the LLM part of an evaluation once it is ready: 204
+// while htmx should keep polling, 202 for API clients
+func (h *Handler) EvaluationJob(c *gin.Context) {
+	job, err := h.history.Job(c.Param("id"))
+	// Another tenant's job reads as missing
+	if err != nil || job.Tenant != middleware.CurrentTenant(c).ID {
+		renderError(c, http.StatusNotFound, "error.unknown_job", "evaluation job not found or expired")
+		return
+	}
+
+	isJSON := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+	if job.Status == history.JobQueued || job.Status == history.JobRunning {
+		if isJSON {
+			c.JSON(http.StatusAccepted, api.JobResponse{Status: api.JobPending})
+		} else {
+			c.Status(http.StatusNoContent)
+		}
+		return
+	}
+
+	result, err := decodeJobResult(job)
+	if job.Status == history.JobFailed {
+		err = errors.New(job.Error)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if !isJSON {
+			// htmx only swaps successful responses, and the error replaces the pending block
+			status = http.StatusOK
+		}
+		renderError(c, status, "error.evaluate", "Failed to evaluate metrics: "+err.Error())
+		return
+	}
+	if isJSON {
+		evaluation := apiEvaluation(result.Evaluation)
+		c.JSON(http.StatusOK, api.JobResponse{Status: api.JobDone, ID: result.RecordID, Evaluation: &evaluation})
+		return
+	}
+	data := result.page()
+	// The verdict replaces its placeholder at the top of the result
+	data["oob"] = true
+	render(c, http.StatusOK, "result_job.html", data)
+}
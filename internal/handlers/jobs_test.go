@@ -0,0 +1,195 @@
+// ABOUTME: Tests for evaluation jobs - a shared job runs under its own context, not a request's
+// ABOUTME: Covers waiters leaving one by one, polled jobs, and a worker killed mid-job recovered on restart
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/history"
+)
+
+// blockingQueue starts a queue with one worker whose jobs run until their
+// context is cancelled or release is closed
+func blockingQueue(t *testing.T, release chan struct{}) *jobQueue {
+	t.Helper()
+	q := newJobQueue(history.NewMemoryStore(0), "test/", nil, func(ctx context.Context, p jobPayload) (jobResult, error) {
+		select {
+		case <-ctx.Done():
+			return jobResult{}, ctx.Err()
+		case <-release:
+			return jobResult{RecordID: 1}, nil
+		}
+	})
+	q.start(1)
+	t.Cleanup(q.shutdown)
+	return q
+}
+
+func enqueueTestJob(t *testing.T, q *jobQueue, key string, polled bool) string {
+	t.Helper()
+	id, err := q.enqueue("default", key, polled, jobPayload{Tenant: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestSharedJobOutlivesFirstWaiter(t *testing.T) {
+	release := make(chan struct{})
+	q := blockingQueue(t, release)
+	id := enqueueTestJob(t, q, "", false)
+
+	first, leave := context.WithCancel(context.Background())
+	firstDone := make(chan bool)
+	go func() {
+		_, finished := q.wait(first, id)
+		firstDone <- finished
+	}()
+	secondDone := make(chan history.Job)
+	go func() {
+		job, _ := q.wait(context.Background(), id)
+		secondDone <- job
+	}()
+	waitForWaiters(t, q, id, 2)
+
+	leave()
+	if <-firstDone {
+		t.Error("wait reported the job finished for the request that left")
+	}
+	close(release)
+	if job := <-secondDone; job.Status != history.JobDone {
+		t.Errorf("job %s with %q after its first requester left, want done", job.Status, job.Error)
+	}
+}
+
+func TestLastWaiterLeavingCancelsJob(t *testing.T) {
+	q := blockingQueue(t, make(chan struct{}))
+	id := enqueueTestJob(t, q, "key", false)
+
+	ctx, leave := context.WithCancel(context.Background())
+	leave()
+	if _, finished := q.wait(ctx, id); finished {
+		t.Fatal("wait reported the job finished")
+	}
+	job, err := q.store.Job(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != history.JobFailed {
+		t.Errorf("job %s after its last waiter left, want failed", job.Status)
+	}
+	if _, reused, _ := q.reuse("key", time.Now(), false); reused {
+		t.Error("a cancelled job can still be reused")
+	}
+}
+
+func TestPolledJobIsNeverCancelled(t *testing.T) {
+	release := make(chan struct{})
+	q := blockingQueue(t, release)
+	id := enqueueTestJob(t, q, "key", false)
+	if _, reused, err := q.reuse("key", time.Now(), true); !reused || err != nil {
+		t.Fatalf("a running job can't be reused: %v", err)
+	}
+
+	ctx, leave := context.WithCancel(context.Background())
+	leave()
+	q.wait(ctx, id)
+	close(release)
+	if job, _ := q.wait(context.Background(), id); job.Status != history.JobDone {
+		t.Errorf("polled job %s with %q after a waiter left, want done", job.Status, job.Error)
+	}
+}
+
+func TestKilledWorkersJobIsRecoveredAndFinishesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := history.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first process's worker hangs in the LLM call until the process dies
+	started := make(chan struct{})
+	first := newJobQueue(store, "host/", nil, func(ctx context.Context, p jobPayload) (jobResult, error) {
+		close(started)
+		<-ctx.Done()
+		return jobResult{}, ctx.Err()
+	})
+	first.start(1)
+	id := enqueueTestJob(t, first, "key", true)
+	<-started
+	claimed, err := store.Job(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.shutdown()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarted process reopens the database and requeues the job
+	store, err = history.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	var runs atomic.Int32
+	restarted := newJobQueue(store, "host/", nil, func(ctx context.Context, p jobPayload) (jobResult, error) {
+		runs.Add(1)
+		return jobResult{RecordID: 7}, nil
+	})
+	restarted.start(2)
+	t.Cleanup(restarted.shutdown)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, finished := restarted.wait(ctx, id)
+	if !finished || job.Status != history.JobDone {
+		t.Fatalf("recovered job %s with %q, want done", job.Status, job.Error)
+	}
+	if job.Attempts != 2 {
+		t.Errorf("job claimed %d times, want 2", job.Attempts)
+	}
+	if job.Payload != nil {
+		t.Error("a finished job keeps its payload")
+	}
+	if result, err := decodeJobResult(job); err != nil || result.RecordID != 7 {
+		t.Errorf("job result = %+v, %v, want record 7", result, err)
+	}
+
+	// The killed worker coming back to finish its claim is turned away
+	err = store.FinishJob(id, claimed.Worker, nil, "late", time.Now())
+	if !errors.Is(err, history.ErrLeaseLost) {
+		t.Errorf("finishing a recovered job from the killed worker = %v, want %v", err, history.ErrLeaseLost)
+	}
+	time.Sleep(2 * jobPollInterval)
+	if n := runs.Load(); n != 1 {
+		t.Errorf("the restarted process ran the job %d times, want 1", n)
+	}
+	if job, _ := store.Job(id); job.Status != history.JobDone || job.Error != "" {
+		t.Errorf("job %s with %q after the late finish, want done", job.Status, job.Error)
+	}
+}
+
+func waitForWaiters(t *testing.T, q *jobQueue, id string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		waiters := 0
+		if lj, ok := q.local[id]; ok {
+			waiters = lj.waiters
+		}
+		q.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job never had %d waiters", n)
+}
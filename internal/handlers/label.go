@@ -0,0 +1,71 @@
+// ABOUTME: Single-label check - judges one label and its value count without a full metric
+// ABOUTME: Static by default so it answers at once; llm=true adds a one-paragraph model opinion
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// EvaluateLabel runs the label rules and cardinality math for one label on a
+// hypothetical metric, with its memory at several target counts
+func (h *Handler) EvaluateLabel(c *gin.Context) {
+	var req api.LabelCheckRequest
+	if err := c.ShouldBind(&req); err != nil {
+		renderError(c, http.StatusBadRequest, "error.label_check", "Please provide a label name")
+		return
+	}
+	if req.Values < 0 {
+		renderError(c, http.StatusBadRequest, "error.label_check", "values must be at least 0")
+		return
+	}
+
+	var samples []string
+	for line := range strings.Lines(req.Samples) {
+		if v := strings.TrimSpace(line); v != "" {
+			samples = append(samples, v)
+		}
+	}
+
+	profile := h.requestProfile(c)
+	check, err := profile.CheckLabel(strings.TrimSpace(req.Label), req.Values, samples)
+	if err != nil {
+		renderError(c, http.StatusBadRequest, "error.label_check", err.Error())
+		return
+	}
+	data := gin.H{"check": check}
+	response := api.LabelCheckResponse{
+		Label:    check.Label,
+		Values:   check.Values,
+		Verdict:  check.Verdict,
+		Findings: apiFindings(check.Findings),
+		Impact:   make([]api.LabelImpact, len(check.Impact)),
+	}
+	for i, impact := range check.Impact {
+		response.Impact[i] = api.LabelImpact{Targets: impact.Targets, Series: impact.Series, MemoryBytes: impact.MemoryBytes}
+	}
+
+	if req.LLM {
+		if !h.spendLLMBudgetOrReject(c) {
+			return
+		}
+		findings := make([]string, len(check.Findings))
+		for i, f := range check.Findings {
+			findings[i] = f.Message
+		}
+		opinion, err := h.llmClient.LabelOpinion(c.Request.Context(), check.Label, check.Values, samples, findings, check.Verdict, profile.PromptInstructions)
+		if err != nil {
+			log.Printf("[Label] Error getting the LLM's opinion: %v", err)
+			renderError(c, http.StatusInternalServerError, "error.evaluate", "Failed to evaluate metrics: "+err.Error())
+			return
+		}
+		data["opinion"], response.Opinion = opinion, opinion
+	}
+
+	respond(c, http.StatusOK, "label_check.html", data, response)
+}
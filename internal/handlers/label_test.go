@@ -0,0 +1,69 @@
+// ABOUTME: Tests for the single-label endpoint - static by default, reaching the LLM only when llm=true asks for it
+// ABOUTME: Missing names, negative counts and invalid names are a 400
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+func TestEvaluateLabel(t *testing.T) {
+	ollama := newStubOllama(t, nil)
+	h := newTestHandler(t, ollama.URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/evaluate/label", h.EvaluateLabel)
+
+	tests := []struct {
+		name        string
+		form        url.Values
+		wantStatus  int
+		wantVerdict string
+		wantLLM     bool
+	}{
+		{"static", url.Values{"label": {"team_id"}, "values": {"200"}}, http.StatusOK, "review", false},
+		{"samples", url.Values{"label": {"method"}, "samples": {"GET\n\n POST \n"}}, http.StatusOK, "ok", false},
+		{"with an opinion", url.Values{"label": {"team_id"}, "values": {"200"}, "llm": {"true"}}, http.StatusOK, "review", true},
+		{"no label", url.Values{"values": {"5"}}, http.StatusBadRequest, "", false},
+		{"a negative count", url.Values{"label": {"method"}, "values": {"-1"}}, http.StatusBadRequest, "", false},
+		{"an invalid name", url.Values{"label": {"team-id"}}, http.StatusBadRequest, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := ollama.calls.Load()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/evaluate/label", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if reached := ollama.calls.Load() != calls; reached != tt.wantLLM {
+				t.Errorf("reached the LLM = %v, want %v", reached, tt.wantLLM)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body api.LabelCheckResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Verdict != tt.wantVerdict || len(body.Impact) != 3 || body.Findings == nil {
+				t.Errorf("response = %+v, want verdict %s with three impacts and a findings list", body, tt.wantVerdict)
+			}
+			if wantOpinion := strings.TrimSpace(stubEvaluation); (body.Opinion == wantOpinion) != tt.wantLLM {
+				t.Errorf("opinion = %q, want the LLM's only when asked for", body.Opinion)
+			}
+		})
+	}
+}
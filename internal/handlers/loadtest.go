@@ -0,0 +1,137 @@
+// ABOUTME: Load test endpoint - drives the evaluation pipeline at a fixed rate for capacity planning
+// ABOUTME: Calls the parser, rules and LLM directly so HTTP overhead doesn't skew the latencies
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"golang.org/x/time/rate"
+)
+
+// Upper bounds so one call can't tie up the LLM backend indefinitely
+const (
+	maxLoadTestRPS      = 100
+	maxLoadTestDuration = 5 * time.Minute
+)
+
+type loadTestResult struct {
+	Requests        int     `json:"requests"`
+	Errors          int     `json:"errors"`
+	ErrorRate       float64 `json:"error_rate"`
+	P50Ms           float64 `json:"p50_ms"`
+	P95Ms           float64 `json:"p95_ms"`
+	P99Ms           float64 `json:"p99_ms"`
+	TokensPerSecond float64 `json:"llm_tokens_per_second"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+}
+
+// LoadTest evaluates the metric query parameter rps times a second for duration
+// and reports latency percentiles, error rate and LLM throughput. Evaluations
+// still in flight when duration ends are waited for and counted.
+func (h *Handler) LoadTest(c *gin.Context) {
+	rps, err := strconv.ParseFloat(c.DefaultQuery("rps", "10"), 64)
+	if err != nil || rps <= 0 || rps > maxLoadTestRPS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rps must be a number above 0 and at most %d", maxLoadTestRPS)})
+		return
+	}
+	duration, err := time.ParseDuration(c.DefaultQuery("duration", "30s"))
+	if err != nil || duration <= 0 || duration > maxLoadTestDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("duration must be a Go duration above 0 and at most %s", maxLoadTestDuration)})
+		return
+	}
+	input := c.Query("metric")
+	if input == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric is required"})
+		return
+	}
+
+	// Reject input that could never succeed instead of reporting a 100% error rate
+	profile := h.Profile()
+	if _, err := profile.Parse(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), duration)
+	defer cancel()
+	limiter := rate.NewLimiter(rate.Limit(rps), 1)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		errs      int
+		tokens    int
+	)
+	start := time.Now()
+	for limiter.Wait(ctx) == nil {
+		wg.Go(func() {
+			began := time.Now()
+			// Calls in flight when the duration ends still finish; a dropped request stops them
+			used, err := h.evaluateOnce(c.Request.Context(), input)
+			elapsed := time.Since(began)
+
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, elapsed)
+			tokens += used
+			if err != nil {
+				errs++
+			}
+		})
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	slices.Sort(latencies)
+	result := loadTestResult{
+		Requests:        len(latencies),
+		Errors:          errs,
+		P50Ms:           percentileMs(latencies, 0.50),
+		P95Ms:           percentileMs(latencies, 0.95),
+		P99Ms:           percentileMs(latencies, 0.99),
+		TokensPerSecond: float64(tokens) / elapsed.Seconds(),
+		ElapsedSeconds:  elapsed.Seconds(),
+	}
+	if result.Requests > 0 {
+		result.ErrorRate = float64(errs) / float64(result.Requests)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// evaluateOnce runs the parse, rules and LLM steps of an evaluation without
+// recording history or audit events, returning the LLM tokens used
+func (h *Handler) evaluateOnce(ctx context.Context, input string) (int, error) {
+	profile := h.Profile()
+	parsed, err := profile.Parse(input)
+	if err != nil {
+		return 0, err
+	}
+	parsed, _ = naming.ExcludeRuntime(parsed)
+	profile.Check(parsed)
+
+	evaluation, err := h.llmClient.Evaluate(ctx, parsed, profile.PromptInstructions, h.engineFingerprint(profile), "")
+	if err != nil {
+		return 0, err
+	}
+	return evaluation.PromptTokens + evaluation.ResponseTokens, nil
+}
+
+// percentileMs uses the nearest-rank method over sorted latencies
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return float64(sorted[max(rank, 0)].Microseconds()) / 1000
+}
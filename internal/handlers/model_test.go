@@ -0,0 +1,64 @@
+// ABOUTME: Tests for the per-request model override - only allowlisted models may be named
+// ABOUTME: An allowed model is the one the evaluation reports; any other is a 400 listing the choices
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/llm"
+)
+
+func TestRequestedModelMustBeAllowed(t *testing.T) {
+	h := newTestHandler(t, newStubOllama(t, nil).URL)
+	h.llmClient.SetRouting(llm.Routing{FastModel: "fast:1b", AllowedModels: []string{"mistral:7b"}})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/evaluate", h.Evaluate)
+
+	tests := []struct {
+		model  string
+		status int
+		// Model on the evaluation, or the error
+		want string
+	}{
+		{"mistral:7b", http.StatusOK, "mistral:7b"},
+		{"fast:1b", http.StatusOK, "fast:1b"},
+		{"gpt-4", http.StatusBadRequest, `Model "gpt-4" is not allowed; choose one of llama3.2:3b, fast:1b, mistral:7b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			form := url.Values{"metrics": {"http_requests_total 1"}, "model": {tt.model}}
+			req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			var body struct {
+				Evaluation llm.Evaluation
+				Error      string
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.status != http.StatusOK {
+				if body.Error != tt.want {
+					t.Errorf("error = %q, want %q", body.Error, tt.want)
+				}
+				return
+			}
+			if body.Evaluation.Model != tt.want {
+				t.Errorf("evaluation model = %q, want %q", body.Evaluation.Model, tt.want)
+			}
+		})
+	}
+}
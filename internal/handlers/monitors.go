@@ -0,0 +1,102 @@
+// ABOUTME: Endpoint monitor routes - register and remove monitors through the admin API and show their runs
+// ABOUTME: The scheduler in internal/monitor does the scraping; these handlers only manage and display it
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/monitor"
+)
+
+// Runs shown per monitor on the monitors page
+const monitorPageRuns = 10
+
+type registerMonitorRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Sent as the Authorization header of each scrape, e.g. "Bearer <token>"
+	Authorization string `json:"authorization"`
+	// A Go duration such as 30s or 5m
+	Interval string `json:"interval" binding:"required"`
+}
+
+// SetMonitors sets the scheduler the monitor routes manage
+func (h *Handler) SetMonitors(s *monitor.Scheduler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.monitors = s
+}
+
+func (h *Handler) monitorScheduler() *monitor.Scheduler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.monitors
+}
+
+// Monitors shows every monitored endpoint with its latest runs
+func (h *Handler) Monitors(c *gin.Context) {
+	statuses, err := h.monitorScheduler().Status(monitorPageRuns)
+	if err != nil {
+		log.Printf("[Monitor] Error reading monitors: %v", err)
+		renderError(c, http.StatusInternalServerError, "error.monitors", "Failed to load monitors")
+		return
+	}
+	render(c, http.StatusOK, "monitors.html", gin.H{
+		"title":    "Monitors - Good Telemetry",
+		"subtitle": "Endpoint Monitors",
+		"monitors": statuses,
+	})
+}
+
+// RegisterMonitor starts scraping an endpoint on an interval
+func (h *Handler) RegisterMonitor(c *gin.Context) {
+	var req registerMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url and interval are required"})
+		return
+	}
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be a duration such as 30s or 5m"})
+		return
+	}
+
+	m, err := h.monitorScheduler().Register(req.URL, req.Authorization, interval)
+	if errors.Is(err, monitor.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[Monitor] Error registering %s: %v", req.URL, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store monitor"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": m.ID, "url": m.URL, "interval": m.Interval.String()})
+}
+
+// DeleteMonitor stops scraping an endpoint and drops its runs
+func (h *Handler) DeleteMonitor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a monitor number"})
+		return
+	}
+
+	err = h.monitorScheduler().Remove(id)
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "monitor not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Monitor] Error removing monitor %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove monitor"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+// ABOUTME: Metric set endpoint - statically evaluates related groups of metrics and compares their labels
+// ABOUTME: Each group gets its own findings; label names the groups disagree on are reported across them
+
+package handlers
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// EvaluateMultiSet runs the static checks on each group of {"groups": {name:
+// metrics}} and reports label names that differ between the groups
+func (h *Handler) EvaluateMultiSet(c *gin.Context) {
+	var req struct {
+		Groups map[string]string `json:"groups" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Groups) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `groups is required, e.g. {"groups": {"http": "...", "db": "..."}}`})
+		return
+	}
+	size := 0
+	for _, input := range req.Groups {
+		size += len(input)
+	}
+	if size > api.MaxMetricsBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Metrics are %d bytes; at most %d are accepted", size, api.MaxMetricsBytes)})
+		return
+	}
+
+	parsed, err := metrics.ParseMultipleMetricSets(req.Groups)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	analysis := metrics.MultiSetAnalysis{
+		Groups:           parsed,
+		CrossGroupIssues: metrics.CheckLabelConsistency(parsed),
+	}
+
+	profile := h.requestProfile(c)
+	response := api.MultiSetResponse{
+		Groups:           make(map[string]api.MultiSetGroup, len(parsed)),
+		CrossGroupIssues: make([]api.ConsistencyIssue, len(analysis.CrossGroupIssues)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(analysis.Groups)) {
+		group := analysis.Groups[name]
+		group.ReanalyzeWithOptions(profile.CardinalityOptions())
+		findings := profile.Check(group)
+		response.Groups[name] = api.MultiSetGroup{
+			Problems:    apiFindings(rules.Problems(findings)),
+			Praise:      apiFindings(rules.Praise(findings)),
+			Cardinality: apiCardinality(group.CardinalityAnalysis),
+		}
+	}
+	for i, issue := range analysis.CrossGroupIssues {
+		response.CrossGroupIssues[i] = api.ConsistencyIssue(issue)
+	}
+	c.JSON(http.StatusOK, response)
+}
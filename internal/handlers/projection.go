@@ -0,0 +1,105 @@
+// ABOUTME: Cardinality projection - series and memory growth over time as labels gain new values
+// ABOUTME: Drawn as an inline SVG line so the result page needs no charting library
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const (
+	defaultProjectionDays = 30
+	maxProjectionDays     = 365
+
+	// Size of the chart's drawing area in SVG units
+	chartWidth  = 400
+	chartHeight = 120
+)
+
+// CardinalityProjection projects the submitted metrics' series count over the
+// coming days from the new values per day given for each label as growth[label]
+func (h *Handler) CardinalityProjection(c *gin.Context) {
+	input := c.PostForm("metrics")
+	if strings.TrimSpace(input) == "" {
+		renderError(c, http.StatusBadRequest, "error.no_metrics", "Please provide metrics to evaluate")
+		return
+	}
+	days := defaultProjectionDays
+	if raw := c.PostForm("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxProjectionDays {
+			renderError(c, http.StatusBadRequest, "error.projection_days", fmt.Sprintf("days must be between 1 and %d", maxProjectionDays))
+			return
+		}
+		days = n
+	}
+
+	growth := make(map[string]int)
+	for label, raw := range c.PostFormMap("growth") {
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			renderError(c, http.StatusBadRequest, "error.projection_growth", fmt.Sprintf("new values per day for %s must be a whole number of at least 0", label))
+			return
+		}
+		growth[label] = n
+	}
+
+	parsed, err := h.requestProfile(c).Parse(input)
+	if err != nil {
+		renderError(c, http.StatusBadRequest, "error.parse", err.Error())
+		return
+	}
+
+	points := cardinality.ProjectCardinality(parsed.CardinalityAnalysis, growth, days)
+	last := points[len(points)-1]
+	response := api.Projection{
+		Days:             days,
+		Points:           make([]api.ProjectionPoint, len(points)),
+		FinalSeries:      last.EstimatedSeries,
+		FinalMemoryBytes: cardinality.MemoryBytes(last.EstimatedSeries),
+	}
+	for i, p := range points {
+		response.Points[i] = api.ProjectionPoint{Day: p.Day, EstimatedSeries: p.EstimatedSeries, MemoryBytes: p.MemoryBytes}
+	}
+	respond(c, http.StatusOK, "cardinality_projection.html", gin.H{
+		"points":      points,
+		"days":        days,
+		"finalSeries": last.EstimatedSeries,
+		"finalMemory": cardinality.EstimateSimple(last.EstimatedSeries),
+		"path":        chartPath(points),
+		"width":       chartWidth,
+		"height":      chartHeight,
+	}, response)
+}
+
+// chartPath draws the series counts as an SVG path scaled to the chart, with
+// day 0 on the left and the largest count at the top
+func chartPath(points []cardinality.CardinalityPoint) string {
+	peak := 1
+	for _, p := range points {
+		peak = max(peak, p.EstimatedSeries)
+	}
+	lastDay := max(points[len(points)-1].Day, 1)
+
+	var sb strings.Builder
+	for i, p := range points {
+		x := float64(p.Day) / float64(lastDay) * chartWidth
+		y := chartHeight - float64(p.EstimatedSeries)/float64(peak)*chartHeight
+		if i == 0 {
+			fmt.Fprintf(&sb, "M%.1f,%.1f", x, y)
+		} else {
+			fmt.Fprintf(&sb, " L%.1f,%.1f", x, y)
+		}
+	}
+	return sb.String()
+}
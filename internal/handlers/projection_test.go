@@ -0,0 +1,106 @@
+// ABOUTME: Tests for the cardinality projection endpoint and its SVG line, scaled to the chart's drawing area
+// ABOUTME: Growth is read per label from the form, and out-of-range days or rates are a 400
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+func TestChartPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []int
+		want   string
+	}{
+		{"rising", []int{10, 15, 20}, "M0.0,60.0 L200.0,30.0 L400.0,0.0"},
+		{"flat", []int{5, 5}, "M0.0,0.0 L400.0,0.0"},
+		{"no series", []int{0, 0}, "M0.0,120.0 L400.0,120.0"},
+		// A single day is drawn from the left edge
+		{"today only", []int{8}, "M0.0,0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := make([]cardinality.CardinalityPoint, len(tt.series))
+			for i, n := range tt.series {
+				points[i] = cardinality.CardinalityPoint{Day: i, EstimatedSeries: n}
+			}
+			if got := chartPath(points); got != tt.want {
+				t.Errorf("chartPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCardinalityProjection(t *testing.T) {
+	h := newTestHandler(t, "http://127.0.0.1:1")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/cardinality/projection", h.CardinalityProjection)
+
+	const input = "http_requests_total{method=\"GET\",pod=\"a\"} 1\nhttp_requests_total{method=\"POST\",pod=\"b\"} 1\n"
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantSeries []int
+	}{
+		{"growth per label", url.Values{"metrics": {input}, "days": {"2"}, "growth[pod]": {"3"}},
+			http.StatusOK, []int{4, 10, 16}},
+		{"blank rates are no growth", url.Values{"metrics": {input}, "days": {"1"}, "growth[pod]": {""}},
+			http.StatusOK, []int{4, 4}},
+		{"default days", url.Values{"metrics": {input}}, http.StatusOK, nil},
+		{"no metrics", url.Values{"days": {"2"}}, http.StatusBadRequest, nil},
+		{"zero days", url.Values{"metrics": {input}, "days": {"0"}}, http.StatusBadRequest, nil},
+		{"too many days", url.Values{"metrics": {input}, "days": {"366"}}, http.StatusBadRequest, nil},
+		{"a negative rate", url.Values{"metrics": {input}, "growth[pod]": {"-1"}}, http.StatusBadRequest, nil},
+		{"a rate that isn't a number", url.Values{"metrics": {input}, "growth[pod]": {"lots"}}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/cardinality/projection", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body api.Projection
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSeries == nil {
+				if body.Days != defaultProjectionDays || len(body.Points) != defaultProjectionDays+1 {
+					t.Errorf("days = %d with %d points, want %d days", body.Days, len(body.Points), defaultProjectionDays)
+				}
+				return
+			}
+			if len(body.Points) != len(tt.wantSeries) {
+				t.Fatalf("points = %+v, want %d", body.Points, len(tt.wantSeries))
+			}
+			for i, p := range body.Points {
+				if p.Day != i || p.EstimatedSeries != tt.wantSeries[i] {
+					t.Errorf("point %d = %+v, want %d series", i, p, tt.wantSeries[i])
+				}
+			}
+			last := tt.wantSeries[len(tt.wantSeries)-1]
+			if body.FinalSeries != last || body.FinalMemoryBytes != cardinality.MemoryBytes(last) {
+				t.Errorf("final = %d series in %d bytes, want %d", body.FinalSeries, body.FinalMemoryBytes, last)
+			}
+		})
+	}
+}
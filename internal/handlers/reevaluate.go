@@ -0,0 +1,306 @@
+// ABOUTME: Re-evaluation job - runs stored evaluations again under the current rules, prompt and model
+// ABOUTME: Stores the results as a revision run and reports changed verdicts and finding codes
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/naming"
+)
+
+const (
+	reevaluateStatic = "static"
+	reevaluateLLM    = "llm"
+
+	// LLM re-evaluations share the backend with live traffic, so only this many run at once
+	reevaluateLLMConcurrency = 2
+)
+
+type verdictChange struct {
+	EvaluationID int64  `json:"evaluation_id"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+}
+
+type codeChange struct {
+	Code        string `json:"code"`
+	Evaluations int    `json:"evaluations"`
+}
+
+// reevaluationReport compares a revision run with the original evaluations
+type reevaluationReport struct {
+	RunID       int64     `json:"run_id"`
+	Mode        string    `json:"mode"`
+	StartedAt   time.Time `json:"started_at"`
+	Cancelled   bool      `json:"cancelled"`
+	Evaluations int       `json:"evaluations"`
+	Errors      int       `json:"errors"`
+	// Originals without recorded finding codes, whose findings are not compared
+	Uncompared       int             `json:"uncompared"`
+	ChangedVerdicts  []verdictChange `json:"changed_verdicts"`
+	NewFindings      []codeChange    `json:"new_findings"`
+	ResolvedFindings []codeChange    `json:"resolved_findings"`
+}
+
+// Reevaluate runs the evaluations created within since (e.g. 7d or 12h) again.
+// mode=static re-runs the rules only; mode=llm also asks the LLM for a verdict.
+// The run stops early if the request is dropped or CancelReevaluation is
+// called, and whatever finished is still stored and reported.
+func (h *Handler) Reevaluate(c *gin.Context) {
+	window, err := parseWindow(c.DefaultQuery("since", "7d"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mode := c.DefaultQuery("mode", reevaluateStatic)
+	if mode != reevaluateStatic && mode != reevaluateLLM {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be static or llm"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	if !h.startReevaluation(cancel) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a re-evaluation is already running"})
+		return
+	}
+	defer h.finishReevaluation()
+
+	run := history.RevisionRun{StartedAt: time.Now(), Mode: mode, Since: time.Now().Add(-window)}
+	records, err := h.history.Records(history.AnyTenant, run.Since)
+	if err != nil {
+		log.Printf("[Reevaluate] Error reading history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read evaluations"})
+		return
+	}
+	log.Printf("[Reevaluate] Re-running %d evaluation(s) in %s mode", len(records), mode)
+
+	limit := runtime.NumCPU()
+	if mode == reevaluateLLM {
+		limit = reevaluateLLMConcurrency
+	}
+	sem := make(chan struct{}, limit)
+	revisions := make([]*history.Revision, len(records))
+	var wg sync.WaitGroup
+schedule:
+	for i, r := range records {
+		select {
+		case <-ctx.Done():
+			break schedule
+		case sem <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			rev := h.revise(ctx, r, mode)
+			// An LLM call cut short by the cancellation didn't finish
+			if ctx.Err() == nil || rev.Error == "" {
+				revisions[i] = &rev
+			}
+		})
+	}
+	wg.Wait()
+
+	run.FinishedAt = time.Now()
+	run.Cancelled = ctx.Err() != nil
+	run.Revisions = []history.Revision{}
+	originals := make(map[int64]history.Record)
+	for i, rev := range revisions {
+		if rev != nil {
+			run.Revisions = append(run.Revisions, *rev)
+			originals[rev.EvaluationID] = records[i]
+		}
+	}
+
+	if err := h.history.AddRevisionRun(&run); err != nil {
+		log.Printf("[Reevaluate] Error storing revision run: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store revision run"})
+		return
+	}
+	log.Printf("[Reevaluate] Run %d finished: %d of %d evaluation(s) re-run", run.ID, len(run.Revisions), len(records))
+
+	c.JSON(http.StatusOK, compareRevisions(run, originals))
+}
+
+// CancelReevaluation stops the running re-evaluation, keeping what has finished
+func (h *Handler) CancelReevaluation(c *gin.Context) {
+	h.mu.Lock()
+	cancel := h.cancelReevaluation
+	h.mu.Unlock()
+
+	if cancel == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no re-evaluation is running"})
+		return
+	}
+	cancel()
+	c.JSON(http.StatusAccepted, gin.H{"status": "cancelling"})
+}
+
+func (h *Handler) startReevaluation(cancel context.CancelFunc) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancelReevaluation != nil {
+		return false
+	}
+	h.cancelReevaluation = cancel
+	return true
+}
+
+func (h *Handler) finishReevaluation() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cancelReevaluation = nil
+}
+
+// revise runs one stored evaluation through the current profile and, in llm mode, the LLM
+func (h *Handler) revise(ctx context.Context, r history.Record, mode string) history.Revision {
+	rev := history.Revision{EvaluationID: r.ID, FindingCodes: []string{}}
+
+	profile := h.profileFor(r.Tenant)
+	parsed, err := profile.Parse(r.Input)
+	if err != nil {
+		rev.Error = err.Error()
+		return rev
+	}
+	// As Evaluate does by default
+	parsed, _ = naming.ExcludeRuntime(parsed)
+	rev.FindingCodes = findingCodes(profile.Check(parsed))
+
+	if mode == reevaluateLLM {
+		evaluation, err := h.llmClient.Evaluate(ctx, parsed, profile.PromptInstructions, h.engineFingerprint(profile), "")
+		if err != nil {
+			rev.Error = err.Error()
+			return rev
+		}
+		rev.Verdict = evaluation.Verdict
+	}
+	return rev
+}
+
+// latestReevaluation reports on the most recent revision run, or returns nil if there is none
+func (h *Handler) latestReevaluation() (*reevaluationReport, error) {
+	run, err := h.history.LatestRevisionRun()
+	if errors.Is(err, history.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	originals := make(map[int64]history.Record)
+	for _, rev := range run.Revisions {
+		r, err := h.history.Get(history.AnyTenant, rev.EvaluationID)
+		if errors.Is(err, history.ErrNotFound) {
+			// Evicted from in-memory history since the run
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		originals[rev.EvaluationID] = r
+	}
+	report := compareRevisions(run, originals)
+	return &report, nil
+}
+
+func compareRevisions(run history.RevisionRun, originals map[int64]history.Record) reevaluationReport {
+	report := reevaluationReport{
+		RunID:            run.ID,
+		Mode:             run.Mode,
+		StartedAt:        run.StartedAt,
+		Cancelled:        run.Cancelled,
+		Evaluations:      len(run.Revisions),
+		ChangedVerdicts:  []verdictChange{},
+		NewFindings:      []codeChange{},
+		ResolvedFindings: []codeChange{},
+	}
+
+	added := make(map[string]int)
+	resolved := make(map[string]int)
+	for _, rev := range run.Revisions {
+		if rev.Error != "" {
+			report.Errors++
+			continue
+		}
+		original, ok := originals[rev.EvaluationID]
+		if !ok {
+			continue
+		}
+		if rev.Verdict != "" && rev.Verdict != original.Verdict {
+			report.ChangedVerdicts = append(report.ChangedVerdicts, verdictChange{
+				EvaluationID: rev.EvaluationID,
+				Before:       original.Verdict,
+				After:        rev.Verdict,
+			})
+		}
+
+		if original.FindingCodes == nil {
+			report.Uncompared++
+			continue
+		}
+		for _, code := range uniqueCodes(rev.FindingCodes) {
+			if !slices.Contains(original.FindingCodes, code) {
+				added[code]++
+			}
+		}
+		for _, code := range uniqueCodes(original.FindingCodes) {
+			if !slices.Contains(rev.FindingCodes, code) {
+				resolved[code]++
+			}
+		}
+	}
+
+	report.NewFindings = codeChanges(added)
+	report.ResolvedFindings = codeChanges(resolved)
+	return report
+}
+
+func uniqueCodes(codes []string) []string {
+	unique := slices.Clone(codes)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
+
+// codeChanges sorts codes by the number of evaluations affected, most first
+func codeChanges(counts map[string]int) []codeChange {
+	changes := make([]codeChange, 0, len(counts))
+	for code, n := range counts {
+		changes = append(changes, codeChange{Code: code, Evaluations: n})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Evaluations != changes[j].Evaluations {
+			return changes[i].Evaluations > changes[j].Evaluations
+		}
+		return changes[i].Code < changes[j].Code
+	})
+	return changes
+}
+
+// parseWindow reads a Go duration, or a whole number of days such as 7d
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("since must be a positive duration such as 7d or 12h")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("since must be a positive duration such as 7d or 12h")
+	}
+	return d, nil
+}
@@ -0,0 +1,82 @@
+// ABOUTME: Template rendering helper shared by every HTML route
+// ABOUTME: Returns bare fragments to htmx, API types to JSON clients and the page layout for direct visits
+
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/i18n"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// respond writes response as JSON when the client only accepts JSON and
+// renders the named template with data otherwise
+func respond(c *gin.Context, status int, name string, data gin.H, response any) {
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(status, response)
+		return
+	}
+	render(c, status, name, data)
+}
+
+// render writes the named template as a fragment when htmx asked for it and
+// inside layout.html otherwise so deep links and bookmarks get a whole page.
+// Pages without a JSON form refuse clients that only accept JSON; routes with
+// one use respond.
+func render(c *gin.Context, status int, name string, data gin.H) {
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusNotAcceptable, api.ErrorResponse{Error: "This page is only available as HTML"})
+		return
+	}
+
+	data["lang"] = middleware.Language(c)
+	data["base"] = middleware.BasePath(c)
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(status, name, data)
+		return
+	}
+
+	data["content"] = name
+	data["languages"] = i18n.Names
+	if _, ok := data["title"]; !ok {
+		data["title"] = "Good Telemetry"
+	}
+	c.HTML(status, "layout.html", data)
+}
+
+// renderError shows the catalog message for key in the visitor's language,
+// with message beneath it as detail. API clients get message alone, as before.
+func renderError(c *gin.Context, status int, key, message string) {
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(status, api.ErrorResponse{Error: message})
+		return
+	}
+
+	data := gin.H{"errorKey": key}
+	// Repeating the English message as detail adds nothing
+	if message != i18n.T(i18n.DefaultLanguage, key) {
+		data["error"] = message
+	}
+	render(c, status, "error.html", data)
+}
+
+// SetLanguage stores an explicit language choice and returns to the page it was made on
+func (h *Handler) SetLanguage(c *gin.Context) {
+	lang := c.Query("lang")
+	if !i18n.Supported(lang) {
+		c.String(http.StatusBadRequest, "unsupported language")
+		return
+	}
+	c.SetCookie(middleware.LanguageCookie, lang, 365*24*60*60, "/", "", c.Request.TLS != nil, true)
+
+	// Only same-site paths, so the link can't redirect elsewhere
+	back := "/"
+	if ref, err := url.Parse(c.GetHeader("Referer")); err == nil && ref.Path != "" && (ref.Host == "" || ref.Host == c.Request.Host) {
+		back = ref.RequestURI()
+	}
+	c.Redirect(http.StatusSeeOther, back)
+}
@@ -0,0 +1,83 @@
+// ABOUTME: Tests that forms, multipart uploads and JSON bodies reach the same EvaluateRequest validation
+// ABOUTME: Each encoding of a request gets the same status and error message
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEveryEncodingIsValidatedAlike(t *testing.T) {
+	h := newTestHandler(t, newStubOllama(t, nil).URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/evaluate", h.Evaluate)
+
+	encodings := map[string]func(fields map[string]string) (string, *bytes.Buffer){
+		"form": func(fields map[string]string) (string, *bytes.Buffer) {
+			form := url.Values{}
+			for k, v := range fields {
+				form.Set(k, v)
+			}
+			return "application/x-www-form-urlencoded", bytes.NewBufferString(form.Encode())
+		},
+		"multipart": func(fields map[string]string) (string, *bytes.Buffer) {
+			body := &bytes.Buffer{}
+			w := multipart.NewWriter(body)
+			for k, v := range fields {
+				w.WriteField(k, v)
+			}
+			w.Close()
+			return w.FormDataContentType(), body
+		},
+		"json": func(fields map[string]string) (string, *bytes.Buffer) {
+			body, _ := json.Marshal(fields)
+			return "application/json", bytes.NewBuffer(body)
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		status int
+		// Substring of the error; empty for a successful evaluation
+		message string
+	}{
+		{"valid", map[string]string{"metrics": "  # TYPE up gauge\nup 1\n"}, http.StatusOK, ""},
+		{"blank metrics", map[string]string{"metrics": " \n "}, http.StatusBadRequest, "Please provide metrics to evaluate"},
+		{"oversized metrics", map[string]string{"metrics": strings.Repeat("a", 1<<20+1)}, http.StatusBadRequest, "at most 1048576 are accepted"},
+		{"unknown source", map[string]string{"metrics": "up 1", "source": "pushgateway"}, http.StatusBadRequest, `Unknown source "pushgateway"`},
+	}
+	for _, tt := range tests {
+		for encoding, encode := range encodings {
+			t.Run(tt.name+"/"+encoding, func(t *testing.T) {
+				contentType, body := encode(tt.fields)
+				req := httptest.NewRequest(http.MethodPost, "/evaluate", body)
+				req.Header.Set("Content-Type", contentType)
+				req.Header.Set("Accept", "application/json")
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+
+				if rec.Code != tt.status {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+				}
+				var resp struct{ Error string }
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(resp.Error, tt.message) {
+					t.Errorf("error = %q, want %q", resp.Error, tt.message)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// ABOUTME: API response types built from the engine's results
+// ABOUTME: Keeps the JSON the API returns apart from the data its templates render
+
+package handlers
+
+import (
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// apiEvaluation is the LLM's judgement as the API returns it; nil, for a job
+// without one, is the empty evaluation
+func apiEvaluation(e *llm.Evaluation) api.Evaluation {
+	if e == nil {
+		return api.Evaluation{}
+	}
+	return api.Evaluation{
+		Verdict:             e.Verdict,
+		Score:               e.Score,
+		LetterGrade:         e.LetterGrade,
+		OverallScore:        e.OverallScore,
+		Strengths:           e.Strengths,
+		Issues:              e.Issues,
+		Recommendations:     e.Recommendations,
+		ImprovedExample:     e.ImprovedExample,
+		ImprovedExampleHelp: e.ImprovedExampleHelp,
+		ImprovedExampleType: e.ImprovedExampleType,
+		CardinalityAnalysis: e.CardinalityAnalysis,
+		MemoryImpact:        e.MemoryImpact,
+		Confidence:          api.Confidence{Score: e.Confidence.Score, Level: e.Confidence.Level},
+		Model:               e.Model,
+		Cached:              e.Cached,
+	}
+}
+
+// apiFindings converts findings, as an empty list rather than null when there are none
+func apiFindings(findings []rules.Finding) []api.Finding {
+	out := make([]api.Finding, len(findings))
+	for i, f := range findings {
+		out[i] = api.Finding{Code: f.Code, Severity: string(f.Severity), Metric: f.Metric, Message: f.Message}
+	}
+	return out
+}
+
+func apiCardinality(a *cardinality.Analysis) api.Cardinality {
+	if a == nil {
+		return api.Cardinality{}
+	}
+	return api.Cardinality{
+		EstimatedSeries:      a.EstimatedSeries,
+		MemoryBytes:          a.MemoryEstimateBytes,
+		Level:                a.CardinalityLevel,
+		HighCardinalityRisks: a.HighCardinalityRisks,
+		Warnings:             a.Warnings,
+		ChurnLabels:          a.ChurnLabels,
+		ChurnMultiplier:      a.ChurnMultiplier,
+	}
+}
@@ -0,0 +1,99 @@
+// ABOUTME: Tests for the include_runtime toggle on /evaluate - runtime metrics stay out of findings and the prompt by default
+// ABOUTME: Either way the cardinality totals are the same, since runtime series are stored all the same
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const runtimeScrape = `# TYPE go_goroutines gauge
+go_goroutines 42
+# TYPE process_open_fds gauge
+process_open_fds{fd_type="socket"} 9
+process_open_fds{fd_type="file"} 3
+# TYPE checkoutRequests counter
+checkoutRequests{method="GET"} 3
+`
+
+func TestIncludeRuntimeToggle(t *testing.T) {
+	var mu sync.Mutex
+	var prompts []string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Prompt string }
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		prompts = append(prompts, req.Prompt)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"response": stubEvaluation, "done": true})
+	}))
+	defer ollama.Close()
+	h := newTestHandler(t, ollama.URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/evaluate", h.Evaluate)
+
+	type response = api.EvaluateResponse
+	evaluate := func(includeRuntime bool) (response, string) {
+		t.Helper()
+		form := url.Values{"metrics": {runtimeScrape}}
+		if includeRuntime {
+			form.Set("include_runtime", "true")
+		}
+		req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("evaluate = %d: %s", rec.Code, rec.Body.String())
+		}
+		var body response
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return body, prompts[len(prompts)-1]
+	}
+	judged := func(body response, name string) bool {
+		for _, f := range body.Problems {
+			if f.Metric == name || strings.Contains(f.Message, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	excluded, prompt := evaluate(false)
+	if excluded.RuntimeExcluded != 2 {
+		t.Errorf("by default: %d runtime metrics excluded, want 2", excluded.RuntimeExcluded)
+	}
+	if strings.Contains(prompt, "go_goroutines") || strings.Contains(prompt, "process_open_fds") || !strings.Contains(prompt, "checkoutRequests") {
+		t.Errorf("by default the prompt holds runtime metrics or lacks the user's:\n%s", prompt)
+	}
+	if judged(excluded, "process_open_fds") || !judged(excluded, "checkoutRequests") {
+		t.Errorf("by default the findings are %+v, want checkoutRequests' alone", excluded.Problems)
+	}
+
+	included, prompt := evaluate(true)
+	if included.RuntimeExcluded != 0 {
+		t.Errorf("with include_runtime: %d excluded, want 0", included.RuntimeExcluded)
+	}
+	if !strings.Contains(prompt, "go_goroutines") || !strings.Contains(prompt, "process_open_fds") {
+		t.Errorf("with include_runtime the prompt lacks the runtime metrics:\n%s", prompt)
+	}
+
+	if excluded.Cardinality.EstimatedSeries == 0 || excluded.Cardinality.EstimatedSeries != included.Cardinality.EstimatedSeries {
+		t.Errorf("estimated series = %d excluded, %d included, want them equal", excluded.Cardinality.EstimatedSeries, included.Cardinality.EstimatedSeries)
+	}
+}
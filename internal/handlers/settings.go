@@ -0,0 +1,149 @@
+// ABOUTME: Settings page - shows the running configuration and, when allowed, changes it without a restart
+// ABOUTME: Changes go through the admin API and the same reload as config file edits, lasting until the file next changes
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/buildinfo"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// SettingsUpdate is what the settings form may change
+type SettingsUpdate struct {
+	Model   string `form:"model"`
+	Profile string `form:"profile"`
+}
+
+// How long the settings page waits for the model list before falling back
+// to a free-text model field
+const modelListTimeout = 3 * time.Second
+
+// settingsControl is the settings page's view of the server
+type settingsControl struct {
+	llmURL string
+	// nil keeps the page read-only
+	apply func(SettingsUpdate) error
+}
+
+// SetSettings gives the settings page the LLM URL to show and, unless apply
+// is nil, a way to change the running configuration
+func (h *Handler) SetSettings(llmURL string, apply func(SettingsUpdate) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.settings = settingsControl{llmURL: llmURL, apply: apply}
+}
+
+// Settings shows the running configuration
+func (h *Handler) Settings(c *gin.Context) {
+	h.renderSettings(c, http.StatusOK, "")
+}
+
+// UpdateSettings changes the model and profile when runtime changes are
+// allowed. It is served on the admin API, so only admins can reach it.
+func (h *Handler) UpdateSettings(c *gin.Context) {
+	h.mu.RLock()
+	apply := h.settings.apply
+	h.mu.RUnlock()
+	if apply == nil {
+		renderError(c, http.StatusForbidden, "error.settings_read_only", "Set ALLOW_RUNTIME_CONFIG_CHANGES=true to change settings here")
+		return
+	}
+
+	var update SettingsUpdate
+	if err := c.ShouldBind(&update); err != nil {
+		renderError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return
+	}
+	update.Model = strings.TrimSpace(update.Model)
+	if update.Model == "" {
+		renderError(c, http.StatusBadRequest, "error.invalid_request", "model must not be empty")
+		return
+	}
+	if _, err := rules.Profile(update.Profile); err != nil {
+		renderError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return
+	}
+	if err := apply(update); err != nil {
+		renderError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return
+	}
+
+	log.Printf("[Settings] %s set model %s and profile %s", middleware.ClientIP(c), update.Model, update.Profile)
+	h.renderSettings(c, http.StatusOK, "Settings saved; they apply until the config file next changes or the server restarts.")
+}
+
+func (h *Handler) renderSettings(c *gin.Context, status int, saved string) {
+	h.mu.RLock()
+	settings := h.settings
+	h.mu.RUnlock()
+
+	profile := h.Profile()
+	model := h.llmClient.Model()
+	// Only an editable page needs the models to choose from
+	var models []string
+	if settings.apply != nil {
+		models = h.modelChoices(c.Request.Context(), model)
+	}
+
+	evalCache := "off"
+	switch n, err := h.llmClient.CacheLen(); {
+	case errors.Is(err, llm.ErrNoCache):
+	case err != nil:
+		log.Printf("[Settings] Error reading evaluation cache size: %v", err)
+		evalCache = "unavailable"
+	default:
+		evalCache = strconv.Itoa(n) + " cached evaluation(s)"
+	}
+
+	commit, buildTime := buildinfo.Info()
+	render(c, status, "settings.html", gin.H{
+		"title":       "Settings - Good Telemetry",
+		"subtitle":    "Running Configuration",
+		"editable":    settings.apply != nil,
+		"saved":       saved,
+		"llmURL":      settings.llmURL,
+		"model":       model,
+		"models":      models,
+		"profile":     profile.Name,
+		"profiles":    rules.ProfileNames(),
+		"thresholds":  profile.Thresholds,
+		"exampleRuns": h.exampleRuns.size(),
+		"exampleTTL":  exampleRunTTL.String(),
+		"evalCache":   evalCache,
+		"dedupWindow": h.DeduplicationWindow.String(),
+		"commit":      commit,
+		"buildTime":   buildTime,
+	})
+}
+
+// modelChoices lists the models the backend offers, with the current model
+// first even when it isn't installed. nil means the list is unavailable.
+func (h *Handler) modelChoices(ctx context.Context, current string) []string {
+	ctx, cancel := context.WithTimeout(ctx, modelListTimeout)
+	defer cancel()
+	infos, err := h.llmClient.Models(ctx)
+	if err != nil {
+		log.Printf("[Settings] Error listing models: %v", err)
+		return nil
+	}
+	models := []string{current}
+	for _, info := range infos {
+		// Ollama lists an untagged current model under :latest
+		if !slices.Contains(models, info.Name) && info.Name != current+":latest" {
+			models = append(models, info.Name)
+		}
+	}
+	return models
+}
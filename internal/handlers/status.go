@@ -0,0 +1,36 @@
+// ABOUTME: Engine fingerprint - identifies the prompt, rules, thresholds and model an evaluation was judged by
+// ABOUTME: Cached results are keyed by it, so changing any of them retires results judged the old way
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// engineFingerprint hashes the prompt version, the profile's fingerprint and
+// the model: everything besides the input that decides an evaluation
+func (h *Handler) engineFingerprint(profile rules.NamingProfile) string {
+	sum := sha256.Sum256([]byte(llm.PromptVersion() + "\x00" + profile.Fingerprint() + "\x00" + h.llmClient.Model()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AdminStatus reports the engine fingerprint of the server's profile and what
+// it covers, for telling whether a cached result predates a change
+func (h *Handler) AdminStatus(c *gin.Context) {
+	profile := h.Profile()
+	c.JSON(http.StatusOK, gin.H{
+		"fingerprint":    h.engineFingerprint(profile),
+		"prompt_version": llm.PromptVersion(),
+		"model":          h.llmClient.Model(),
+		"profile":        profile.Name,
+		"thresholds":     profile.Thresholds,
+		"rules":          profile.Rules(),
+		"cached_runs":    h.exampleRuns.size(),
+	})
+}
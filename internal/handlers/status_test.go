@@ -0,0 +1,99 @@
+// ABOUTME: Tests for the engine fingerprint - a config change misses the response cache and reverting it hits again
+// ABOUTME: GET /admin/status reports the fingerprint the cache is keyed by
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/config"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// applyConfig loads yaml as the config file and sets the profile it makes,
+// as the server does when the file changes
+func applyConfig(t *testing.T, h *Handler, yaml string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetProfile(profile.WithValueEstimates(f.LabelValueEstimates))
+}
+
+func adminFingerprint(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	var status struct {
+		Fingerprint string
+		Rules       []rules.RuleVersion
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Fingerprint == "" || len(status.Rules) == 0 {
+		t.Fatalf("status = %s, want the fingerprint and rule versions", rec.Body.String())
+	}
+	return status.Fingerprint
+}
+
+func TestConfigChangeRetiresCachedResults(t *testing.T) {
+	ollama := newStubOllama(t, nil)
+	h := newTestHandler(t, ollama.URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/examples/:id/run", h.RunExample)
+	r.GET("/admin/status", h.AdminStatus)
+
+	// run evaluates the same input each time, reporting whether it was served
+	// from the cache and whether the LLM was called for it
+	run := func() (cached, called bool) {
+		calls := ollama.calls.Load()
+		rec, body := runExample(t, r, "http-requests")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("run = %d: %s", rec.Code, rec.Body.String())
+		}
+		return body.Cached, ollama.calls.Load() != calls
+	}
+
+	applyConfig(t, h, "")
+	before := adminFingerprint(t, r)
+	if cached, called := run(); cached || !called {
+		t.Fatalf("first run: cached %v, LLM called %v", cached, called)
+	}
+	if cached, called := run(); !cached || called {
+		t.Fatalf("same config: cached %v, LLM called %v, want a hit", cached, called)
+	}
+
+	applyConfig(t, h, "label_value_estimates:\n  handler: 500\n")
+	changed := adminFingerprint(t, r)
+	if changed == before {
+		t.Error("the fingerprint ignores label_value_estimates")
+	}
+	if cached, called := run(); cached || !called {
+		t.Errorf("changed config: cached %v, LLM called %v, want a miss", cached, called)
+	}
+
+	applyConfig(t, h, "")
+	if got := adminFingerprint(t, r); got != before {
+		t.Errorf("reverted fingerprint = %s, want %s again", got, before)
+	}
+	if cached, called := run(); !cached || called {
+		t.Errorf("reverted config: cached %v, LLM called %v, want a hit", cached, called)
+	}
+}
@@ -0,0 +1,75 @@
+// ABOUTME: Per-tenant handler state - naming profiles, LLM budgets and the admin tenant listing
+// ABOUTME: Requests of the default tenant use the server-wide settings
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+// SetTenantProfiles sets the naming profiles of tenants that choose their own,
+// by tenant ID, e.g. on config reload
+func (h *Handler) SetTenantProfiles(profiles map[string]rules.NamingProfile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tenantProfiles = profiles
+}
+
+// profileFor returns the tenant's naming profile, or the server's when it has none
+func (h *Handler) profileFor(tenantID string) rules.NamingProfile {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if p, ok := h.tenantProfiles[tenantID]; ok {
+		return p
+	}
+	return h.profile
+}
+
+// requestProfile is profileFor the request's tenant
+func (h *Handler) requestProfile(c *gin.Context) rules.NamingProfile {
+	return h.profileFor(middleware.CurrentTenant(c).ID)
+}
+
+// spendLLMBudget counts one LLM evaluation against the tenant's daily budget,
+// reporting false once it is used up
+func (h *Handler) spendLLMBudget(t tenant.Tenant) bool {
+	if h.budgets == nil {
+		return true
+	}
+	d, ok := h.budgets.Allow(t.BudgetKey(), time.Now())
+	return !ok || d.Allowed
+}
+
+// Tenants lists the configured tenants, without their keys, and what each has
+// used over the last 24 hours
+func (h *Handler) Tenants(c *gin.Context) {
+	since := time.Now().Add(-24 * time.Hour)
+
+	listed := append([]tenant.Tenant{tenant.Default()}, h.tenants.All()...)
+	result := make([]gin.H, 0, len(listed))
+	for _, t := range listed {
+		stats, err := h.history.Stats(t.ID, since)
+		if err != nil {
+			log.Printf("[Tenants] Error aggregating history of %s: %v", t.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read tenant usage"})
+			return
+		}
+		result = append(result, gin.H{
+			"id":               t.ID,
+			"path_prefix":      t.PathPrefix,
+			"profile":          h.profileFor(t.ID).Name,
+			"daily_llm_budget": t.DailyLLMBudget,
+			"evaluations_24h":  stats.Evaluations,
+			"tokens_24h":       stats.TotalTokens(),
+			"cost_24h":         stats.Cost,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,15 @@
+// ABOUTME: Usage report preview - shows operators the aggregate counts opt-in reporting sends
+// ABOUTME: Served whether or not USAGE_REPORTING_URL is set, so the payload can be audited first
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageReport returns the report the next send would post, counted since the last one
+func (h *Handler) UsageReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.usage.Report())
+}
@@ -0,0 +1,67 @@
+// ABOUTME: Tests for the usage report preview - a seeded evaluation's names and values never reach the payload
+// ABOUTME: The stub LLM echoes the submission as its verdict, so even a made-up verdict must be counted without its text
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUsageReportHoldsNoSubmittedText(t *testing.T) {
+	const input = `# HELP acmeWidgetLatency Zanzibar checkout latency
+acmeWidgetLatency{customer_email="jane.doe@example.com",tenant_slug="globex-secret"} 0.25
+`
+	secrets := []string{"acmeWidgetLatency", "acme_widget_latency", "Zanzibar", "customer_email", "jane.doe@example.com", "tenant_slug", "globex-secret"}
+
+	// Answers with a verdict that repeats the prompt, submission included
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Prompt string }
+		json.NewDecoder(r.Body).Decode(&req)
+		verdict := strings.Join(strings.Fields(req.Prompt[strings.Index(req.Prompt, "acmeWidgetLatency"):]), " ")
+		json.NewEncoder(w).Encode(map[string]any{"response": "VERDICT: " + verdict + "\nSCORE: 40\nISSUES:\n- " + req.Prompt, "done": true})
+	}))
+	t.Cleanup(ollama.Close)
+
+	h := newTestHandler(t, ollama.URL)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/evaluate", h.Evaluate)
+	r.GET("/api/v1/admin/usage-report", h.UsageReport)
+
+	if rec, _ := postEvaluate(t, r, url.Values{"metrics": {input}}, "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("evaluate = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/usage-report", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("usage report = %d", rec.Code)
+	}
+	payload := rec.Body.String()
+	for _, secret := range secrets {
+		if strings.Contains(payload, secret) {
+			t.Errorf("the payload holds %q: %s", secret, payload)
+		}
+	}
+
+	// Counted all the same
+	var report struct {
+		Evaluations int
+		RuleCodes   map[string]int `json:"rule_codes"`
+		Verdicts    map[string]int
+		InputSizes  map[string]int `json:"input_sizes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Evaluations != 1 || report.RuleCodes["camel-case"] != 1 || report.Verdicts["other"] != 1 || report.InputSizes["<1KB"] != 1 {
+		t.Errorf("report = %s", payload)
+	}
+}
@@ -0,0 +1,265 @@
+// ABOUTME: Evaluation history - records every evaluation with its token usage and cost
+// ABOUTME: Backed by SQLite when DATABASE_PATH is set, otherwise kept in memory
+
+package history
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a record or gallery entry doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// AnyTenant in place of a tenant ID matches every tenant's records, for admin
+// queries. It can't be a tenant's ID, and an empty ID matches no records.
+const AnyTenant = "*"
+
+// Sample is one evaluated series, recorded to build the metric catalog
+type Sample struct {
+	Name   string
+	Type   string
+	Labels map[string]string
+}
+
+// CatalogMetric describes a metric name seen across all evaluations
+type CatalogMetric struct {
+	Metric string   `json:"metric"`
+	Labels []string `json:"labels"`
+	Type   string   `json:"type"`
+}
+
+type Record struct {
+	ID              int64
+	Tenant          string
+	CreatedAt       time.Time
+	Input           string
+	Verdict         string
+	Model           string
+	PromptChars     int
+	ResponseChars   int
+	PromptTokens    int
+	ResponseTokens  int
+	TokensEstimated bool
+	Cost            float64
+	Samples         []Sample
+	// The submitter allowed an anonymized copy to be published in the gallery
+	ShareConsent bool
+	// Codes of the static findings other than praise; nil when the record has
+	// no codes, as opposed to empty when it had no findings
+	FindingCodes []string
+	// Distinct values the submitter asserted labels are bounded by; nil when
+	// none were
+	LabelBounds map[string]int
+}
+
+// Revision is a stored evaluation run again under the configuration of the time
+type Revision struct {
+	EvaluationID int64    `json:"evaluation_id"`
+	Verdict      string   `json:"verdict,omitempty"` // empty for static runs
+	FindingCodes []string `json:"finding_codes"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// RevisionRun is one re-evaluation of the records created since Since
+type RevisionRun struct {
+	ID         int64      `json:"id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Mode       string     `json:"mode"`
+	Since      time.Time  `json:"since"`
+	Cancelled  bool       `json:"cancelled"`
+	Revisions  []Revision `json:"revisions"`
+}
+
+// Monitor is a metrics endpoint scraped on an interval to catch telemetry regressions
+type Monitor struct {
+	ID  int64
+	URL string
+	// Sent as the Authorization header of each scrape; empty sends none
+	Authorization string
+	Interval      time.Duration
+	CreatedAt     time.Time
+	// Scrapes that failed in a row; the next attempt backs off accordingly
+	ConsecutiveFailures int
+}
+
+// MonitorRun is one scrape of a monitor and what the static checks found in it
+type MonitorRun struct {
+	ID        int64     `json:"id"`
+	MonitorID int64     `json:"monitor_id"`
+	StartedAt time.Time `json:"started_at"`
+	// Why the scrape or its parse failed; the fields below are empty when set
+	Error  string `json:"error,omitempty"`
+	Series int    `json:"series"`
+	// Error-severity findings as "code metric", sorted
+	ErrorFindings []string `json:"error_findings"`
+	// New error findings or series growth since the last successful run raised an alert
+	Alerted bool `json:"alerted"`
+}
+
+// JobStatus is where an evaluation job is in the queue
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// ErrLeaseLost is returned to a worker finishing or renewing a job it no
+// longer holds: its lease ran out and another worker claimed the job, or the
+// job was cancelled
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Job is the LLM half of an evaluation, queued for a worker to run
+type Job struct {
+	ID     string
+	Tenant string
+	// Idempotency key: submissions with the same key share one job; empty
+	// never shares
+	Key string
+	// What the worker runs, opaque to the store; cleared once the job
+	// finishes, so the submission isn't kept longer than the job
+	Payload []byte
+	Status  JobStatus
+	// A client fetches the result later rather than waiting on the job, so
+	// it is never cancelled
+	Polled bool
+	// Times a worker claimed the job
+	Attempts int
+	// The worker holding a running job, until LeaseUntil unless renewed
+	Worker     string
+	LeaseUntil time.Time
+	// Set once the job is done, or failed with Error
+	Result     []byte
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt time.Time
+}
+
+// JobQueueStats describes the jobs not yet finished
+type JobQueueStats struct {
+	// Queued and running jobs
+	Depth int
+	// When the oldest of them was enqueued; zero when there are none
+	OldestCreatedAt time.Time
+}
+
+// GalleryFinding is a static finding as shown in the gallery
+type GalleryFinding struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// GalleryEntry is a published evaluation. Input is the anonymized text and the
+// findings and cardinality figures come from re-analyzing it.
+type GalleryEntry struct {
+	EvaluationID     int64            `json:"evaluation_id"`
+	PublishedAt      time.Time        `json:"published_at"`
+	Input            string           `json:"input"`
+	Verdict          string           `json:"verdict"`
+	Findings         []GalleryFinding `json:"findings"`
+	CardinalityLevel string           `json:"cardinality_level"`
+	EstimatedSeries  int              `json:"estimated_series"`
+}
+
+// Stats aggregates token usage and cost over a time window
+type Stats struct {
+	Since          time.Time
+	Evaluations    int
+	PromptTokens   int
+	ResponseTokens int
+	Cost           float64
+	// Estimated is true when any record in the window used character-based token estimates
+	Estimated bool
+}
+
+func (s Stats) TotalTokens() int {
+	return s.PromptTokens + s.ResponseTokens
+}
+
+func (s Stats) AverageTokens() float64 {
+	if s.Evaluations == 0 {
+		return 0
+	}
+	return float64(s.TotalTokens()) / float64(s.Evaluations)
+}
+
+// Store keeps every tenant's records apart: queries taking a tenant only see
+// that tenant's records, or every tenant's with AnyTenant
+type Store interface {
+	Add(r *Record) error
+	Stats(tenant string, since time.Time) (Stats, error)
+	// Catalog lists every metric evaluated so far, sorted by name
+	Catalog(tenant string) ([]CatalogMetric, error)
+	// LabelValues lists the unique values seen for a metric's label, sorted
+	LabelValues(tenant, metric, label string) ([]string, error)
+	// Get returns one record, or ErrNotFound
+	Get(tenant string, id int64) (Record, error)
+	// ShareCandidates lists records whose submitters consented to publication
+	// and that are not yet in the gallery, newest first
+	ShareCandidates(tenant string) ([]Record, error)
+	// Publish adds an entry to the gallery, replacing any earlier one for the same evaluation
+	Publish(e *GalleryEntry) error
+	// Gallery lists published entries, newest first
+	Gallery() ([]GalleryEntry, error)
+	// Records lists the records created at or after since, oldest first
+	Records(tenant string, since time.Time) ([]Record, error)
+	// AddRevisionRun stores a re-evaluation and its revisions, setting run.ID
+	AddRevisionRun(run *RevisionRun) error
+	// LatestRevisionRun returns the most recent re-evaluation, or ErrNotFound
+	LatestRevisionRun() (RevisionRun, error)
+	// AddMonitor stores a monitor, setting m.ID
+	AddMonitor(m *Monitor) error
+	// Monitors lists every monitor, oldest first
+	Monitors() ([]Monitor, error)
+	// DeleteMonitor removes a monitor and its runs, or returns ErrNotFound
+	DeleteMonitor(id int64) error
+	// SetMonitorFailures records how many scrapes of a monitor failed in a row
+	SetMonitorFailures(id int64, failures int) error
+	// AddMonitorRun stores a run, setting run.ID, and keeps only the
+	// monitor's newest keep runs
+	AddMonitorRun(run *MonitorRun, keep int) error
+	// MonitorRuns lists up to limit of a monitor's runs, newest first
+	MonitorRuns(id int64, limit int) ([]MonitorRun, error)
+	// ReuseJob returns the newest job with key that is queued, running, or
+	// was created and done since reuseSince, marking it polled if polled is
+	// set, or returns ErrNotFound
+	ReuseJob(key string, reuseSince time.Time, polled bool) (Job, error)
+	// EnqueueJob queues j
+	EnqueueJob(j *Job) error
+	// ClaimJob leases the oldest queued job, or running job whose lease ran
+	// out, to worker until leaseUntil. A job claimed maxAttempts times fails
+	// instead of being claimed again. It returns ErrNotFound when no job is
+	// waiting.
+	ClaimJob(worker string, now, leaseUntil time.Time, maxAttempts int) (Job, error)
+	// RenewJobLease extends worker's lease on a running job, or returns ErrLeaseLost
+	RenewJobLease(id, worker string, leaseUntil time.Time) error
+	// FinishJob records the outcome of a job worker still holds, failing it
+	// when errMsg is set, or returns ErrLeaseLost, so a job finishes once
+	FinishJob(id, worker string, result []byte, errMsg string, now time.Time) error
+	// CancelJob fails a queued or running job nobody polls with errMsg,
+	// reporting whether it did; other jobs are left as they are
+	CancelJob(id, errMsg string, now time.Time) (bool, error)
+	// Job returns one job, or ErrNotFound
+	Job(id string) (Job, error)
+	// RequeueJobs puts the running jobs of workers whose names start with
+	// workerPrefix back in the queue, for a restarted process to recover the
+	// jobs it held, and returns how many it put back
+	RequeueJobs(workerPrefix string) (int, error)
+	// PruneJobs deletes jobs that finished before before
+	PruneJobs(before time.Time) error
+	// JobQueueStats describes the jobs not yet finished
+	JobQueueStats() (JobQueueStats, error)
+	Close() error
+}
+
+// Open returns a SQLite store at path, or an in-memory store when path is empty
+func Open(path string, memorySize int) (Store, error) {
+	if path == "" {
+		return NewMemoryStore(memorySize), nil
+	}
+	return OpenSQLite(path)
+}
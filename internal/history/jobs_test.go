@@ -0,0 +1,201 @@
+// ABOUTME: Tests for the job queue of both stores - claims, leases, idempotency keys and recovery
+// ABOUTME: A job whose lease ran out is taken over, and only the worker holding a job can finish it
+
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// jobStores returns a fresh store of each kind
+func jobStores(t *testing.T) map[string]Store {
+	t.Helper()
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]Store{"memory": NewMemoryStore(0), "sqlite": sqlite}
+}
+
+var jobEpoch = time.UnixMilli(1_700_000_000_000)
+
+func enqueue(t *testing.T, s Store, id, key string, createdAt time.Time) {
+	t.Helper()
+	if err := s.EnqueueJob(&Job{ID: id, Tenant: "default", Key: key, Payload: []byte("payload"), Status: JobQueued, CreatedAt: createdAt}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpiredLeaseIsTakenOverAndFinishesOnce(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "a", "", jobEpoch)
+
+			first, err := s.ClaimJob("w1", jobEpoch, jobEpoch.Add(time.Minute), 3)
+			if err != nil || first.ID != "a" || first.Attempts != 1 {
+				t.Fatalf("first claim = %+v, %v", first, err)
+			}
+			if _, err := s.ClaimJob("w2", jobEpoch.Add(30*time.Second), jobEpoch.Add(time.Minute), 3); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("claiming a leased job = %v, want %v", err, ErrNotFound)
+			}
+
+			second, err := s.ClaimJob("w2", jobEpoch.Add(2*time.Minute), jobEpoch.Add(3*time.Minute), 3)
+			if err != nil || second.Worker != "w2" || second.Attempts != 2 {
+				t.Fatalf("claim after the lease ran out = %+v, %v", second, err)
+			}
+			if err := s.RenewJobLease("a", "w1", jobEpoch.Add(4*time.Minute)); !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("renewing a lost lease = %v, want %v", err, ErrLeaseLost)
+			}
+			if err := s.FinishJob("a", "w1", []byte("stale"), "", jobEpoch); !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("finishing a lost job = %v, want %v", err, ErrLeaseLost)
+			}
+			if err := s.FinishJob("a", "w2", []byte("result"), "", jobEpoch.Add(3*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.FinishJob("a", "w2", []byte("again"), "", jobEpoch.Add(3*time.Minute)); !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("finishing a job twice = %v, want %v", err, ErrLeaseLost)
+			}
+
+			job, err := s.Job("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != JobDone || string(job.Result) != "result" || job.Payload != nil || job.Worker != "" {
+				t.Errorf("finished job = %+v, want done with w2's result and no payload", job)
+			}
+		})
+	}
+}
+
+func TestJobFailsAfterMaxAttempts(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "a", "", jobEpoch)
+			now := jobEpoch
+			for range 2 {
+				if _, err := s.ClaimJob("w", now, now.Add(time.Second), 2); err != nil {
+					t.Fatal(err)
+				}
+				now = now.Add(time.Minute)
+			}
+			if _, err := s.ClaimJob("w", now, now.Add(time.Second), 2); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("third claim = %v, want %v", err, ErrNotFound)
+			}
+			if job, _ := s.Job("a"); job.Status != JobFailed || job.Error == "" {
+				t.Errorf("job = %s with %q, want failed with a reason", job.Status, job.Error)
+			}
+		})
+	}
+}
+
+func TestRequeueJobsRecoversOnlyThePrefixesJobs(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "mine", "", jobEpoch)
+			enqueue(t, s, "theirs", "", jobEpoch.Add(time.Second))
+			lease := jobEpoch.Add(time.Hour)
+			if _, err := s.ClaimJob("host-a/1/0", jobEpoch, lease, 3); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.ClaimJob("host-b/1/0", jobEpoch, lease, 3); err != nil {
+				t.Fatal(err)
+			}
+
+			if n, err := s.RequeueJobs("host-a/"); err != nil || n != 1 {
+				t.Fatalf("RequeueJobs = %d, %v, want 1", n, err)
+			}
+			job, err := s.ClaimJob("host-a/2/0", jobEpoch, lease, 3)
+			if err != nil || job.ID != "mine" || job.Attempts != 2 {
+				t.Errorf("claim after requeue = %+v, %v, want job mine on its second attempt", job, err)
+			}
+			if job, _ := s.Job("theirs"); job.Status != JobRunning || job.Worker != "host-b/1/0" {
+				t.Errorf("another host's job = %+v, want still running there", job)
+			}
+		})
+	}
+}
+
+func TestReuseJobByKey(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "a", "key", jobEpoch)
+			if _, err := s.ReuseJob("other", jobEpoch, false); !errors.Is(err, ErrNotFound) {
+				t.Errorf("reusing another key = %v, want %v", err, ErrNotFound)
+			}
+
+			// A queued job is reused however old, and reusing it for a poller
+			// keeps it from being cancelled
+			job, err := s.ReuseJob("key", jobEpoch.Add(time.Hour), true)
+			if err != nil || job.ID != "a" || !job.Polled {
+				t.Fatalf("ReuseJob = %+v, %v, want polled job a", job, err)
+			}
+			if cancelled, err := s.CancelJob("a", "gone", jobEpoch); cancelled || err != nil {
+				t.Errorf("CancelJob on a polled job = %v, %v, want left alone", cancelled, err)
+			}
+
+			// A done job is reused only within the window
+			if _, err := s.ClaimJob("w", jobEpoch, jobEpoch.Add(time.Minute), 3); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.FinishJob("a", "w", nil, "", jobEpoch.Add(time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.ReuseJob("key", jobEpoch, false); err != nil {
+				t.Errorf("reusing a done job within the window = %v", err)
+			}
+			if _, err := s.ReuseJob("key", jobEpoch.Add(time.Millisecond), false); !errors.Is(err, ErrNotFound) {
+				t.Errorf("reusing a done job past the window = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestCancelledJobIsNeitherReusedNorClaimed(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "a", "key", jobEpoch)
+			if cancelled, err := s.CancelJob("a", "gone", jobEpoch); !cancelled || err != nil {
+				t.Fatalf("CancelJob = %v, %v", cancelled, err)
+			}
+			if _, err := s.ReuseJob("key", jobEpoch, false); !errors.Is(err, ErrNotFound) {
+				t.Errorf("reusing a cancelled job = %v, want %v", err, ErrNotFound)
+			}
+			if _, err := s.ClaimJob("w", jobEpoch, jobEpoch.Add(time.Minute), 3); !errors.Is(err, ErrNotFound) {
+				t.Errorf("claiming a cancelled job = %v, want %v", err, ErrNotFound)
+			}
+			if _, err := s.CancelJob("missing", "gone", jobEpoch); !errors.Is(err, ErrNotFound) {
+				t.Errorf("cancelling a missing job = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestJobQueueStatsAndPrune(t *testing.T) {
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			enqueue(t, s, "old", "", jobEpoch)
+			enqueue(t, s, "new", "", jobEpoch.Add(time.Minute))
+			stats, err := s.JobQueueStats()
+			if err != nil || stats.Depth != 2 || !stats.OldestCreatedAt.Equal(jobEpoch) {
+				t.Fatalf("JobQueueStats = %+v, %v, want 2 jobs from %v", stats, err, jobEpoch)
+			}
+
+			if _, err := s.CancelJob("old", "gone", jobEpoch.Add(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.PruneJobs(jobEpoch.Add(2 * time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Job("old"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("a finished job survived pruning: %v", err)
+			}
+			if _, err := s.Job("new"); err != nil {
+				t.Errorf("pruning removed a queued job: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,568 @@
+// ABOUTME: In-memory history store - keeps the most recent records in a fixed-size ring buffer
+// ABOUTME: Used when no database is configured; the oldest record, and what only it kept alive, is evicted once the buffer is full
+
+package history
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+)
+
+// DefaultMemorySize is how many records the in-memory store keeps
+const DefaultMemorySize = 100
+
+// Past this share of the buffer, a warning that eviction is near is logged once
+const nearCapacity = 0.8
+
+type MemoryStore struct {
+	mu sync.RWMutex
+	// Ring buffer allocated once at its full size: head is the oldest record,
+	// tail the slot the next one goes into, and count how many slots are used
+	records    []Record
+	head, tail int
+	count      int
+	warnedFull bool
+	nextID     int64
+	// tenant -> metric name -> entry, counting the samples of records still
+	// in the ring so eviction can take them back out
+	catalog map[string]map[string]*catalogEntry
+	// Newest published last, at most as many as the ring holds records
+	gallery []GalleryEntry
+	// Only the latest revision run is ever read
+	latestRun *RevisionRun
+	runCount  int64
+
+	monitors      []Monitor
+	nextMonitorID int64
+	// monitor ID -> runs, oldest first, only for monitors that exist
+	monitorRuns      map[int64][]MonitorRun
+	nextMonitorRunID int64
+
+	jobs map[string]*Job
+}
+
+type catalogEntry struct {
+	metricType string
+	// Samples of this metric in the ring
+	samples int
+	// label name -> value -> samples in the ring carrying it
+	labels map[string]map[string]int
+}
+
+// NewMemoryStore keeps up to size records, or DefaultMemorySize when size isn't positive
+func NewMemoryStore(size int) *MemoryStore {
+	if size <= 0 {
+		size = DefaultMemorySize
+	}
+	return &MemoryStore{
+		records: make([]Record, size),
+		nextID:  1,
+		catalog: make(map[string]map[string]*catalogEntry),
+	}
+}
+
+// push stores r in the ring, evicting the oldest record when it is full
+func (s *MemoryStore) push(r Record) {
+	if s.count == len(s.records) {
+		s.uncatalog(s.records[s.head])
+		s.records[s.head] = Record{}
+		s.head = (s.head + 1) % len(s.records)
+		s.count--
+		selfmetrics.HistoryEvictions.Inc()
+	}
+	s.records[s.tail] = r
+	s.tail = (s.tail + 1) % len(s.records)
+	s.count++
+
+	if !s.warnedFull && float64(s.count) > nearCapacity*float64(len(s.records)) {
+		s.warnedFull = true
+		log.Printf("[History] In-memory history holds %d of %d evaluations; the oldest are evicted once it is full. "+
+			"Set DATABASE_PATH to keep them all, or raise HISTORY_SIZE", s.count, len(s.records))
+	}
+}
+
+// at returns the i-th oldest record
+func (s *MemoryStore) at(i int) Record {
+	return s.records[(s.head+i)%len(s.records)]
+}
+
+func (s *MemoryStore) Add(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = s.nextID
+	s.nextID++
+	s.push(*r)
+
+	if s.catalog[r.Tenant] == nil {
+		s.catalog[r.Tenant] = make(map[string]*catalogEntry)
+	}
+	for _, sample := range r.Samples {
+		entry, ok := s.catalog[r.Tenant][sample.Name]
+		if !ok {
+			entry = &catalogEntry{labels: make(map[string]map[string]int)}
+			s.catalog[r.Tenant][sample.Name] = entry
+		}
+		entry.metricType = sample.Type
+		entry.samples++
+		for label, value := range sample.Labels {
+			if entry.labels[label] == nil {
+				entry.labels[label] = make(map[string]int)
+			}
+			entry.labels[label][value]++
+		}
+	}
+	return nil
+}
+
+// uncatalog takes an evicted record's samples back out of the catalog,
+// dropping metrics, labels and values no remaining record has
+func (s *MemoryStore) uncatalog(r Record) {
+	catalog := s.catalog[r.Tenant]
+	for _, sample := range r.Samples {
+		entry, ok := catalog[sample.Name]
+		if !ok {
+			continue
+		}
+		for label, value := range sample.Labels {
+			if entry.labels[label][value]--; entry.labels[label][value] <= 0 {
+				delete(entry.labels[label], value)
+			}
+			if len(entry.labels[label]) == 0 {
+				delete(entry.labels, label)
+			}
+		}
+		if entry.samples--; entry.samples <= 0 {
+			delete(catalog, sample.Name)
+		}
+	}
+	if len(catalog) == 0 {
+		delete(s.catalog, r.Tenant)
+	}
+}
+
+// ofTenant reports whether r belongs to tenant, or tenant is AnyTenant
+func ofTenant(r Record, tenant string) bool {
+	return tenant == AnyTenant || r.Tenant == tenant
+}
+
+func (s *MemoryStore) Stats(tenant string, since time.Time) (Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{Since: since}
+	for i := range s.count {
+		r := s.at(i)
+		if !ofTenant(r, tenant) || r.CreatedAt.Before(since) {
+			continue
+		}
+		stats.Evaluations++
+		stats.PromptTokens += r.PromptTokens
+		stats.ResponseTokens += r.ResponseTokens
+		stats.Cost += r.Cost
+		stats.Estimated = stats.Estimated || r.TokensEstimated
+	}
+	return stats, nil
+}
+
+// tenantCatalog returns tenant's catalog, merging every tenant's for AnyTenant
+func (s *MemoryStore) tenantCatalog(tenant string) map[string]*catalogEntry {
+	if tenant != AnyTenant {
+		return s.catalog[tenant]
+	}
+	merged := make(map[string]*catalogEntry)
+	for _, catalog := range s.catalog {
+		for name, entry := range catalog {
+			m, ok := merged[name]
+			if !ok {
+				m = &catalogEntry{metricType: entry.metricType, labels: make(map[string]map[string]int)}
+				merged[name] = m
+			}
+			for label, values := range entry.labels {
+				if m.labels[label] == nil {
+					m.labels[label] = make(map[string]int)
+				}
+				for value, n := range values {
+					m.labels[label][value] += n
+				}
+			}
+		}
+	}
+	return merged
+}
+
+func (s *MemoryStore) Catalog(tenant string) ([]CatalogMetric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.tenantCatalog(tenant)
+	catalog := make([]CatalogMetric, 0, len(entries))
+	for name, entry := range entries {
+		catalog = append(catalog, CatalogMetric{
+			Metric: name,
+			Labels: sortedKeys(entry.labels),
+			Type:   entry.metricType,
+		})
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Metric < catalog[j].Metric })
+	return catalog, nil
+}
+
+func (s *MemoryStore) LabelValues(tenant, metric, label string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.tenantCatalog(tenant)[metric]
+	if !ok {
+		return []string{}, nil
+	}
+	return sortedKeys(entry.labels[label]), nil
+}
+
+func (s *MemoryStore) Get(tenant string, id int64) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := range s.count {
+		if r := s.at(i); r.ID == id && ofTenant(r, tenant) {
+			return r, nil
+		}
+	}
+	return Record{}, ErrNotFound
+}
+
+func (s *MemoryStore) ShareCandidates(tenant string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	published := make(map[int64]bool)
+	for _, e := range s.gallery {
+		published[e.EvaluationID] = true
+	}
+
+	candidates := []Record{}
+	for i := s.count - 1; i >= 0; i-- {
+		if r := s.at(i); ofTenant(r, tenant) && r.ShareConsent && !published[r.ID] {
+			candidates = append(candidates, r)
+		}
+	}
+	return candidates, nil
+}
+
+func (s *MemoryStore) Publish(e *GalleryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.gallery {
+		if existing.EvaluationID == e.EvaluationID {
+			s.gallery = append(s.gallery[:i], s.gallery[i+1:]...)
+			break
+		}
+	}
+	s.gallery = append(s.gallery, *e)
+	if over := len(s.gallery) - len(s.records); over > 0 {
+		s.gallery = slices.Delete(s.gallery, 0, over)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Gallery() ([]GalleryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]GalleryEntry, len(s.gallery))
+	copy(entries, s.gallery)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].PublishedAt.After(entries[j].PublishedAt) })
+	return entries, nil
+}
+
+func (s *MemoryStore) Records(tenant string, since time.Time) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := []Record{}
+	for i := range s.count {
+		if r := s.at(i); ofTenant(r, tenant) && !r.CreatedAt.Before(since) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) AddRevisionRun(run *RevisionRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runCount++
+	run.ID = s.runCount
+	latest := *run
+	s.latestRun = &latest
+	return nil
+}
+
+func (s *MemoryStore) LatestRevisionRun() (RevisionRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.latestRun == nil {
+		return RevisionRun{}, ErrNotFound
+	}
+	return *s.latestRun, nil
+}
+
+func (s *MemoryStore) AddMonitor(m *Monitor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMonitorID++
+	m.ID = s.nextMonitorID
+	s.monitors = append(s.monitors, *m)
+	return nil
+}
+
+func (s *MemoryStore) Monitors() ([]Monitor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Monitor{}, s.monitors...), nil
+}
+
+func (s *MemoryStore) DeleteMonitor(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.monitors, func(m Monitor) bool { return m.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.monitors = slices.Delete(s.monitors, i, i+1)
+	delete(s.monitorRuns, id)
+	return nil
+}
+
+func (s *MemoryStore) SetMonitorFailures(id int64, failures int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.monitors, func(m Monitor) bool { return m.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.monitors[i].ConsecutiveFailures = failures
+	return nil
+}
+
+func (s *MemoryStore) AddMonitorRun(run *MonitorRun, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A run finishing after its monitor was deleted would otherwise be kept forever
+	if !slices.ContainsFunc(s.monitors, func(m Monitor) bool { return m.ID == run.MonitorID }) {
+		return ErrNotFound
+	}
+	if s.monitorRuns == nil {
+		s.monitorRuns = make(map[int64][]MonitorRun)
+	}
+	s.nextMonitorRunID++
+	run.ID = s.nextMonitorRunID
+	runs := append(s.monitorRuns[run.MonitorID], *run)
+	if len(runs) > keep {
+		runs = slices.Clone(runs[len(runs)-keep:])
+	}
+	s.monitorRuns[run.MonitorID] = runs
+	return nil
+}
+
+func (s *MemoryStore) MonitorRuns(id int64, limit int) ([]MonitorRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.monitorRuns[id]
+	runs := make([]MonitorRun, 0, min(limit, len(stored)))
+	for i := len(stored) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, stored[i])
+	}
+	return runs, nil
+}
+
+func (s *MemoryStore) ReuseJob(key string, reuseSince time.Time, polled bool) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var newest *Job
+	for _, j := range s.jobs {
+		reusable := j.Status == JobQueued || j.Status == JobRunning || (j.Status == JobDone && !j.CreatedAt.Before(reuseSince))
+		if j.Key == key && reusable && (newest == nil || j.CreatedAt.After(newest.CreatedAt)) {
+			newest = j
+		}
+	}
+	if newest == nil {
+		return Job{}, ErrNotFound
+	}
+	newest.Polled = newest.Polled || polled
+	return cloneJob(*newest), nil
+}
+
+func (s *MemoryStore) EnqueueJob(j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs == nil {
+		s.jobs = make(map[string]*Job)
+	}
+	stored := cloneJob(*j)
+	s.jobs[j.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) ClaimJob(worker string, now, leaseUntil time.Time, maxAttempts int) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		var oldest *Job
+		for _, j := range s.jobs {
+			claimable := j.Status == JobQueued || (j.Status == JobRunning && j.LeaseUntil.Before(now))
+			if claimable && (oldest == nil || j.CreatedAt.Before(oldest.CreatedAt)) {
+				oldest = j
+			}
+		}
+		if oldest == nil {
+			return Job{}, ErrNotFound
+		}
+		if oldest.Attempts >= maxAttempts {
+			oldest.finish(nil, fmt.Sprintf("gave up after %d attempts", oldest.Attempts), now)
+			continue
+		}
+		oldest.Status, oldest.Worker, oldest.LeaseUntil = JobRunning, worker, leaseUntil
+		oldest.Attempts++
+		return cloneJob(*oldest), nil
+	}
+}
+
+// held returns job id while worker holds it, or ErrLeaseLost
+func (s *MemoryStore) held(id, worker string) (*Job, error) {
+	j, ok := s.jobs[id]
+	if !ok || j.Status != JobRunning || j.Worker != worker {
+		return nil, ErrLeaseLost
+	}
+	return j, nil
+}
+
+func (s *MemoryStore) RenewJobLease(id, worker string, leaseUntil time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.held(id, worker)
+	if err != nil {
+		return err
+	}
+	j.LeaseUntil = leaseUntil
+	return nil
+}
+
+func (s *MemoryStore) FinishJob(id, worker string, result []byte, errMsg string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.held(id, worker)
+	if err != nil {
+		return err
+	}
+	j.finish(result, errMsg, now)
+	return nil
+}
+
+// finish records a job's outcome, dropping its payload
+func (j *Job) finish(result []byte, errMsg string, now time.Time) {
+	j.Status, j.Result, j.Error = JobDone, slices.Clone(result), errMsg
+	if errMsg != "" {
+		j.Status = JobFailed
+	}
+	j.Payload, j.Worker, j.LeaseUntil, j.FinishedAt = nil, "", time.Time{}, now
+}
+
+func (s *MemoryStore) CancelJob(id, errMsg string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if j.Polled || (j.Status != JobQueued && j.Status != JobRunning) {
+		return false, nil
+	}
+	j.finish(nil, errMsg, now)
+	return true, nil
+}
+
+func (s *MemoryStore) Job(id string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return cloneJob(*j), nil
+}
+
+func (s *MemoryStore) RequeueJobs(workerPrefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requeued := 0
+	for _, j := range s.jobs {
+		if j.Status == JobRunning && strings.HasPrefix(j.Worker, workerPrefix) {
+			j.Status, j.Worker, j.LeaseUntil = JobQueued, "", time.Time{}
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (s *MemoryStore) PruneJobs(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, j := range s.jobs {
+		if (j.Status == JobDone || j.Status == JobFailed) && j.FinishedAt.Before(before) {
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) JobQueueStats() (JobQueueStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var stats JobQueueStats
+	for _, j := range s.jobs {
+		if j.Status != JobQueued && j.Status != JobRunning {
+			continue
+		}
+		stats.Depth++
+		if stats.OldestCreatedAt.IsZero() || j.CreatedAt.Before(stats.OldestCreatedAt) {
+			stats.OldestCreatedAt = j.CreatedAt
+		}
+	}
+	return stats, nil
+}
+
+// cloneJob copies j so callers can't change the stored job through its slices
+func cloneJob(j Job) Job {
+	j.Payload, j.Result = slices.Clone(j.Payload), slices.Clone(j.Result)
+	return j
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+// ABOUTME: Tests for the in-memory store's bounds - nothing outlives the ring's capacity
+// ABOUTME: Evicted records take their catalog entries along, and the gallery and run lists stay capped
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func addSample(t *testing.T, s *MemoryStore, name, label, value string) {
+	t.Helper()
+	r := &Record{Tenant: "default", CreatedAt: time.Now(), Samples: []Sample{{Name: name, Type: "counter", Labels: map[string]string{label: value}}}}
+	if err := s.Add(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvictedRecordsLeaveTheCatalog(t *testing.T) {
+	s := NewMemoryStore(2)
+	addSample(t, s, "old_total", "path", "/a")
+	addSample(t, s, "kept_total", "path", "/b")
+	addSample(t, s, "kept_total", "path", "/c")
+
+	catalog, err := s.Catalog("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog) != 1 || catalog[0].Metric != "kept_total" {
+		t.Fatalf("catalog = %+v, want only kept_total once old_total's record is evicted", catalog)
+	}
+
+	// Values go with the records that carried them
+	addSample(t, s, "kept_total", "path", "/d")
+	values, err := s.LabelValues("default", "kept_total", "path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(values, []string{"/c", "/d"}) {
+		t.Errorf("values = %v, want those of the two records left", values)
+	}
+}
+
+func TestEvictionDropsATenantsEmptyCatalog(t *testing.T) {
+	s := NewMemoryStore(1)
+	if err := s.Add(&Record{Tenant: "acme", Samples: []Sample{{Name: "up"}}}); err != nil {
+		t.Fatal(err)
+	}
+	addSample(t, s, "up", "job", "api")
+	if _, ok := s.catalog["acme"]; ok {
+		t.Error("a tenant with no records left keeps its catalog")
+	}
+}
+
+func TestGalleryIsCappedAtTheRingSize(t *testing.T) {
+	s := NewMemoryStore(3)
+	for id := range int64(5) {
+		if err := s.Publish(&GalleryEntry{EvaluationID: id, PublishedAt: time.Unix(id, 0)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := s.Gallery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, e := range entries {
+		ids = append(ids, e.EvaluationID)
+	}
+	if !slices.Equal(ids, []int64{4, 3, 2}) {
+		t.Errorf("gallery = %v, want the 3 newest", ids)
+	}
+}
+
+func TestOnlyTheLatestRevisionRunIsKept(t *testing.T) {
+	s := NewMemoryStore(0)
+	for i := range 3 {
+		run := &RevisionRun{Mode: fmt.Sprint(i)}
+		if err := s.AddRevisionRun(run); err != nil {
+			t.Fatal(err)
+		}
+		if run.ID != int64(i+1) {
+			t.Errorf("run %d got ID %d", i, run.ID)
+		}
+	}
+	latest, err := s.LatestRevisionRun()
+	if err != nil || latest.ID != 3 || latest.Mode != "2" {
+		t.Errorf("LatestRevisionRun = %+v, %v, want run 3", latest, err)
+	}
+}
+
+func TestRunsOfDeletedMonitorsAreNotKept(t *testing.T) {
+	s := NewMemoryStore(0)
+	m := &Monitor{URL: "http://example.com/metrics"}
+	if err := s.AddMonitor(m); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := s.AddMonitorRun(&MonitorRun{MonitorID: m.ID}, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runs, _ := s.MonitorRuns(m.ID, 10); len(runs) != 2 {
+		t.Errorf("kept %d runs, want 2", len(runs))
+	}
+
+	if err := s.DeleteMonitor(m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddMonitorRun(&MonitorRun{MonitorID: m.ID}, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddMonitorRun for a deleted monitor = %v, want %v", err, ErrNotFound)
+	}
+	if len(s.monitorRuns) != 0 {
+		t.Errorf("runs kept for deleted monitors: %v", s.monitorRuns)
+	}
+}
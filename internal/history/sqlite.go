@@ -0,0 +1,857 @@
+// ABOUTME: SQLite history store - persists evaluations across restarts
+// ABOUTME: Applies schema migrations on open and aggregates usage with SQL
+
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Each entry upgrades the schema by one version (tracked in PRAGMA user_version)
+var migrations = []string{
+	`CREATE TABLE evaluations (
+		id               INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at       INTEGER NOT NULL,
+		input            TEXT    NOT NULL,
+		verdict          TEXT    NOT NULL,
+		model            TEXT    NOT NULL,
+		prompt_chars     INTEGER NOT NULL,
+		response_chars   INTEGER NOT NULL,
+		prompt_tokens    INTEGER NOT NULL,
+		response_tokens  INTEGER NOT NULL,
+		tokens_estimated INTEGER NOT NULL,
+		cost             REAL    NOT NULL
+	);
+	CREATE INDEX evaluations_created_at ON evaluations (created_at);`,
+	// One row per (metric, label, value) ever evaluated; label and value are
+	// empty for metrics without labels so they still appear in the catalog
+	`CREATE TABLE catalog (
+		metric TEXT NOT NULL,
+		type   TEXT NOT NULL,
+		label  TEXT NOT NULL,
+		value  TEXT NOT NULL,
+		PRIMARY KEY (metric, label, value)
+	);`,
+	// Findings are stored as a JSON array of GalleryFinding
+	`ALTER TABLE evaluations ADD COLUMN share_consent INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE gallery (
+		evaluation_id     INTEGER PRIMARY KEY REFERENCES evaluations (id),
+		published_at      INTEGER NOT NULL,
+		input             TEXT    NOT NULL,
+		verdict           TEXT    NOT NULL,
+		findings          TEXT    NOT NULL,
+		cardinality_level TEXT    NOT NULL,
+		estimated_series  INTEGER NOT NULL
+	);`,
+	// finding_codes is a JSON array of strings, NULL for evaluations made before it existed
+	`ALTER TABLE evaluations ADD COLUMN finding_codes TEXT;
+	CREATE TABLE revision_runs (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at  INTEGER NOT NULL,
+		finished_at INTEGER NOT NULL,
+		mode        TEXT    NOT NULL,
+		since       INTEGER NOT NULL,
+		cancelled   INTEGER NOT NULL
+	);
+	CREATE TABLE revisions (
+		run_id        INTEGER NOT NULL REFERENCES revision_runs (id),
+		evaluation_id INTEGER NOT NULL REFERENCES evaluations (id),
+		verdict       TEXT    NOT NULL,
+		finding_codes TEXT    NOT NULL,
+		error         TEXT    NOT NULL,
+		PRIMARY KEY (run_id, evaluation_id)
+	);`,
+	// Records made before tenants existed belong to the default tenant
+	// (tenant.DefaultID); the catalog gains the tenant in its key
+	`ALTER TABLE evaluations ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+	CREATE INDEX evaluations_tenant_created_at ON evaluations (tenant, created_at);
+	ALTER TABLE catalog RENAME TO catalog_before_tenants;
+	CREATE TABLE catalog (
+		tenant TEXT NOT NULL,
+		metric TEXT NOT NULL,
+		type   TEXT NOT NULL,
+		label  TEXT NOT NULL,
+		value  TEXT NOT NULL,
+		PRIMARY KEY (tenant, metric, label, value)
+	);
+	INSERT INTO catalog SELECT 'default', metric, type, label, value FROM catalog_before_tenants;
+	DROP TABLE catalog_before_tenants;`,
+	// error_findings is a JSON array of strings
+	`CREATE TABLE monitors (
+		id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+		url                  TEXT    NOT NULL,
+		auth_header          TEXT    NOT NULL,
+		interval_seconds     INTEGER NOT NULL,
+		created_at           INTEGER NOT NULL,
+		consecutive_failures INTEGER NOT NULL
+	);
+	CREATE TABLE monitor_runs (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		monitor_id     INTEGER NOT NULL REFERENCES monitors (id),
+		started_at     INTEGER NOT NULL,
+		error          TEXT    NOT NULL,
+		series         INTEGER NOT NULL,
+		error_findings TEXT    NOT NULL,
+		alerted        INTEGER NOT NULL
+	);
+	CREATE INDEX monitor_runs_monitor_id ON monitor_runs (monitor_id, id);`,
+	// label_bounds is a JSON object of label names to bounds, NULL when none were asserted
+	`ALTER TABLE evaluations ADD COLUMN label_bounds TEXT;`,
+	// Times are Unix milliseconds, as leases run for seconds; lease_until and
+	// finished_at are 0 when unset
+	`CREATE TABLE jobs (
+		id          TEXT    PRIMARY KEY,
+		tenant      TEXT    NOT NULL,
+		key         TEXT    NOT NULL,
+		payload     BLOB,
+		status      TEXT    NOT NULL,
+		polled      INTEGER NOT NULL,
+		attempts    INTEGER NOT NULL,
+		worker      TEXT    NOT NULL,
+		lease_until INTEGER NOT NULL,
+		result      BLOB,
+		error       TEXT    NOT NULL,
+		created_at  INTEGER NOT NULL,
+		finished_at INTEGER NOT NULL
+	);
+	CREATE INDEX jobs_status_created_at ON jobs (status, created_at);
+	CREATE INDEX jobs_key ON jobs (key);`,
+}
+
+// tenantFilter matches the tenant column against one argument pair from
+// tenantArgs: every tenant for AnyTenant, otherwise that tenant alone
+const tenantFilter = `(? OR tenant = ?)`
+
+func tenantArgs(tenant string) []any {
+	return []any{tenant == AnyTenant, tenant}
+}
+
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database at path, creating it if needed. The file
+// holds submitted metrics and monitors' Authorization headers as given, so it
+// is made readable by its owner alone; SQLite's journal files follow its mode.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if path != ":memory:" && !strings.HasPrefix(path, "file:") {
+		f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		f.Close()
+		if err := os.Chmod(path, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to restrict database permissions: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; serializing through one connection avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := migrateStep(db, i+1, migrations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateStep runs one migration and records its version in one transaction,
+// so a failure part way leaves the schema at the previous version
+func migrateStep(db *sql.DB, version int, migration string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migration %d failed: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration); err != nil {
+		return fmt.Errorf("migration %d failed: %w", version, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version)); err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %d failed: %w", version, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Add(r *Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var codes sql.NullString
+	if r.FindingCodes != nil {
+		encoded, err := json.Marshal(r.FindingCodes)
+		if err != nil {
+			return err
+		}
+		codes = sql.NullString{String: string(encoded), Valid: true}
+	}
+	var bounds sql.NullString
+	if len(r.LabelBounds) > 0 {
+		encoded, err := json.Marshal(r.LabelBounds)
+		if err != nil {
+			return err
+		}
+		bounds = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	res, err := tx.Exec(`INSERT INTO evaluations
+		(tenant, created_at, input, verdict, model, prompt_chars, response_chars, prompt_tokens, response_tokens, tokens_estimated, cost, share_consent, finding_codes, label_bounds)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Tenant, r.CreatedAt.Unix(), r.Input, r.Verdict, r.Model, r.PromptChars, r.ResponseChars,
+		r.PromptTokens, r.ResponseTokens, r.TokensEstimated, r.Cost, r.ShareConsent, codes, bounds)
+	if err != nil {
+		return fmt.Errorf("failed to insert evaluation: %w", err)
+	}
+
+	for _, sample := range r.Samples {
+		if err := addToCatalog(tx, r.Tenant, sample); err != nil {
+			return err
+		}
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit evaluation: %w", err)
+	}
+	r.ID = id
+	return nil
+}
+
+func addToCatalog(tx *sql.Tx, tenant string, sample Sample) error {
+	insert := func(label, value string) error {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO catalog (tenant, metric, type, label, value) VALUES (?, ?, ?, ?, ?)`,
+			tenant, sample.Name, sample.Type, label, value)
+		return err
+	}
+
+	if len(sample.Labels) == 0 {
+		if err := insert("", ""); err != nil {
+			return fmt.Errorf("failed to update metric catalog: %w", err)
+		}
+	}
+	for label, value := range sample.Labels {
+		if err := insert(label, value); err != nil {
+			return fmt.Errorf("failed to update metric catalog: %w", err)
+		}
+	}
+
+	// The most recent evaluation decides the type shown for the metric
+	if _, err := tx.Exec(`UPDATE catalog SET type = ? WHERE tenant = ? AND metric = ?`, sample.Type, tenant, sample.Name); err != nil {
+		return fmt.Errorf("failed to update metric catalog: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Stats(tenant string, since time.Time) (Stats, error) {
+	stats := Stats{Since: since}
+	err := s.db.QueryRow(`SELECT
+			COUNT(*),
+			COALESCE(SUM(prompt_tokens), 0),
+			COALESCE(SUM(response_tokens), 0),
+			COALESCE(SUM(cost), 0),
+			COALESCE(MAX(tokens_estimated), 0)
+		FROM evaluations WHERE `+tenantFilter+` AND created_at >= ?`, append(tenantArgs(tenant), since.Unix())...).
+		Scan(&stats.Evaluations, &stats.PromptTokens, &stats.ResponseTokens, &stats.Cost, &stats.Estimated)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to aggregate evaluations: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *SQLiteStore) Catalog(tenant string) ([]CatalogMetric, error) {
+	rows, err := s.db.Query(`SELECT metric, type, label FROM catalog WHERE `+tenantFilter+`
+		GROUP BY metric, type, label ORDER BY metric, label`, tenantArgs(tenant)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metric catalog: %w", err)
+	}
+	defer rows.Close()
+
+	catalog := []CatalogMetric{}
+	for rows.Next() {
+		var metric, metricType, label string
+		if err := rows.Scan(&metric, &metricType, &label); err != nil {
+			return nil, fmt.Errorf("failed to read metric catalog: %w", err)
+		}
+
+		if n := len(catalog); n == 0 || catalog[n-1].Metric != metric {
+			catalog = append(catalog, CatalogMetric{Metric: metric, Labels: []string{}, Type: metricType})
+		}
+		if label != "" {
+			last := &catalog[len(catalog)-1]
+			last.Labels = append(last.Labels, label)
+		}
+	}
+	return catalog, rows.Err()
+}
+
+func (s *SQLiteStore) LabelValues(tenant, metric, label string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT value FROM catalog
+		WHERE `+tenantFilter+` AND metric = ? AND label = ? AND label != '' ORDER BY value`, append(tenantArgs(tenant), metric, label)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query label values: %w", err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to read label values: %w", err)
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+const recordColumns = `id, tenant, created_at, input, verdict, model, prompt_chars, response_chars,
+	prompt_tokens, response_tokens, tokens_estimated, cost, share_consent, finding_codes, label_bounds`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRecord reads recordColumns into a Record; samples are not loaded
+func scanRecord(row scanner) (Record, error) {
+	var r Record
+	var createdAt int64
+	var codes, bounds sql.NullString
+	err := row.Scan(&r.ID, &r.Tenant, &createdAt, &r.Input, &r.Verdict, &r.Model, &r.PromptChars, &r.ResponseChars,
+		&r.PromptTokens, &r.ResponseTokens, &r.TokensEstimated, &r.Cost, &r.ShareConsent, &codes, &bounds)
+	if err != nil {
+		return r, err
+	}
+	r.CreatedAt = time.Unix(createdAt, 0)
+	if codes.Valid {
+		if err := json.Unmarshal([]byte(codes.String), &r.FindingCodes); err != nil {
+			return r, fmt.Errorf("failed to decode finding codes: %w", err)
+		}
+	}
+	if bounds.Valid {
+		if err := json.Unmarshal([]byte(bounds.String), &r.LabelBounds); err != nil {
+			return r, fmt.Errorf("failed to decode label bounds: %w", err)
+		}
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) Get(tenant string, id int64) (Record, error) {
+	r, err := scanRecord(s.db.QueryRow(`SELECT `+recordColumns+` FROM evaluations WHERE `+tenantFilter+` AND id = ?`, append(tenantArgs(tenant), id)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to read evaluation: %w", err)
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) ShareCandidates(tenant string) ([]Record, error) {
+	rows, err := s.db.Query(`SELECT `+recordColumns+` FROM evaluations
+		WHERE `+tenantFilter+` AND share_consent AND id NOT IN (SELECT evaluation_id FROM gallery)
+		ORDER BY id DESC`, tenantArgs(tenant)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []Record{}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read share candidate: %w", err)
+		}
+		candidates = append(candidates, r)
+	}
+	return candidates, rows.Err()
+}
+
+func (s *SQLiteStore) Publish(e *GalleryEntry) error {
+	findings, err := json.Marshal(e.Findings)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO gallery
+		(evaluation_id, published_at, input, verdict, findings, cardinality_level, estimated_series)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.EvaluationID, e.PublishedAt.Unix(), e.Input, e.Verdict, string(findings), e.CardinalityLevel, e.EstimatedSeries)
+	if err != nil {
+		return fmt.Errorf("failed to publish evaluation: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Gallery() ([]GalleryEntry, error) {
+	rows, err := s.db.Query(`SELECT evaluation_id, published_at, input, verdict, findings, cardinality_level, estimated_series
+		FROM gallery ORDER BY published_at DESC, evaluation_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query gallery: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []GalleryEntry{}
+	for rows.Next() {
+		var e GalleryEntry
+		var publishedAt int64
+		var findings string
+		if err := rows.Scan(&e.EvaluationID, &publishedAt, &e.Input, &e.Verdict, &findings, &e.CardinalityLevel, &e.EstimatedSeries); err != nil {
+			return nil, fmt.Errorf("failed to read gallery: %w", err)
+		}
+		if err := json.Unmarshal([]byte(findings), &e.Findings); err != nil {
+			return nil, fmt.Errorf("failed to decode gallery findings: %w", err)
+		}
+		e.PublishedAt = time.Unix(publishedAt, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteStore) Records(tenant string, since time.Time) ([]Record, error) {
+	rows, err := s.db.Query(`SELECT `+recordColumns+` FROM evaluations WHERE `+tenantFilter+` AND created_at >= ? ORDER BY id`, append(tenantArgs(tenant), since.Unix())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read evaluation: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) AddRevisionRun(run *RevisionRun) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO revision_runs (started_at, finished_at, mode, since, cancelled) VALUES (?, ?, ?, ?, ?)`,
+		run.StartedAt.Unix(), run.FinishedAt.Unix(), run.Mode, run.Since.Unix(), run.Cancelled)
+	if err != nil {
+		return fmt.Errorf("failed to insert revision run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, rev := range run.Revisions {
+		codes, err := json.Marshal(rev.FindingCodes)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO revisions (run_id, evaluation_id, verdict, finding_codes, error) VALUES (?, ?, ?, ?, ?)`,
+			id, rev.EvaluationID, rev.Verdict, string(codes), rev.Error)
+		if err != nil {
+			return fmt.Errorf("failed to insert revision: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit revision run: %w", err)
+	}
+	run.ID = id
+	return nil
+}
+
+func (s *SQLiteStore) LatestRevisionRun() (RevisionRun, error) {
+	var run RevisionRun
+	var startedAt, finishedAt, since int64
+	err := s.db.QueryRow(`SELECT id, started_at, finished_at, mode, since, cancelled
+		FROM revision_runs ORDER BY id DESC LIMIT 1`).
+		Scan(&run.ID, &startedAt, &finishedAt, &run.Mode, &since, &run.Cancelled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RevisionRun{}, ErrNotFound
+	}
+	if err != nil {
+		return RevisionRun{}, fmt.Errorf("failed to read revision run: %w", err)
+	}
+	run.StartedAt, run.FinishedAt, run.Since = time.Unix(startedAt, 0), time.Unix(finishedAt, 0), time.Unix(since, 0)
+
+	rows, err := s.db.Query(`SELECT evaluation_id, verdict, finding_codes, error
+		FROM revisions WHERE run_id = ? ORDER BY evaluation_id`, run.ID)
+	if err != nil {
+		return RevisionRun{}, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	run.Revisions = []Revision{}
+	for rows.Next() {
+		var rev Revision
+		var codes string
+		if err := rows.Scan(&rev.EvaluationID, &rev.Verdict, &codes, &rev.Error); err != nil {
+			return RevisionRun{}, fmt.Errorf("failed to read revision: %w", err)
+		}
+		if err := json.Unmarshal([]byte(codes), &rev.FindingCodes); err != nil {
+			return RevisionRun{}, fmt.Errorf("failed to decode revision finding codes: %w", err)
+		}
+		run.Revisions = append(run.Revisions, rev)
+	}
+	return run, rows.Err()
+}
+
+func (s *SQLiteStore) AddMonitor(m *Monitor) error {
+	res, err := s.db.Exec(`INSERT INTO monitors (url, auth_header, interval_seconds, created_at, consecutive_failures) VALUES (?, ?, ?, ?, ?)`,
+		m.URL, m.Authorization, int64(m.Interval/time.Second), m.CreatedAt.Unix(), m.ConsecutiveFailures)
+	if err != nil {
+		return fmt.Errorf("failed to insert monitor: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	return nil
+}
+
+func (s *SQLiteStore) Monitors() ([]Monitor, error) {
+	rows, err := s.db.Query(`SELECT id, url, auth_header, interval_seconds, created_at, consecutive_failures
+		FROM monitors ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monitors: %w", err)
+	}
+	defer rows.Close()
+
+	monitors := []Monitor{}
+	for rows.Next() {
+		var m Monitor
+		var interval, createdAt int64
+		if err := rows.Scan(&m.ID, &m.URL, &m.Authorization, &interval, &createdAt, &m.ConsecutiveFailures); err != nil {
+			return nil, fmt.Errorf("failed to read monitor: %w", err)
+		}
+		m.Interval, m.CreatedAt = time.Duration(interval)*time.Second, time.Unix(createdAt, 0)
+		monitors = append(monitors, m)
+	}
+	return monitors, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteMonitor(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM monitor_runs WHERE monitor_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete monitor runs: %w", err)
+	}
+	res, err := tx.Exec(`DELETE FROM monitors WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete monitor: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) SetMonitorFailures(id int64, failures int) error {
+	res, err := s.db.Exec(`UPDATE monitors SET consecutive_failures = ? WHERE id = ?`, failures, id)
+	if err != nil {
+		return fmt.Errorf("failed to update monitor: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddMonitorRun(run *MonitorRun, keep int) error {
+	findings, err := json.Marshal(run.ErrorFindings)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO monitor_runs (monitor_id, started_at, error, series, error_findings, alerted) VALUES (?, ?, ?, ?, ?, ?)`,
+		run.MonitorID, run.StartedAt.Unix(), run.Error, run.Series, string(findings), run.Alerted)
+	if err != nil {
+		return fmt.Errorf("failed to insert monitor run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM monitor_runs WHERE monitor_id = ? AND id NOT IN
+		(SELECT id FROM monitor_runs WHERE monitor_id = ? ORDER BY id DESC LIMIT ?)`, run.MonitorID, run.MonitorID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune monitor runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit monitor run: %w", err)
+	}
+	run.ID = id
+	return nil
+}
+
+func (s *SQLiteStore) MonitorRuns(id int64, limit int) ([]MonitorRun, error) {
+	rows, err := s.db.Query(`SELECT id, monitor_id, started_at, error, series, error_findings, alerted
+		FROM monitor_runs WHERE monitor_id = ? ORDER BY id DESC LIMIT ?`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monitor runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []MonitorRun{}
+	for rows.Next() {
+		var run MonitorRun
+		var startedAt int64
+		var findings string
+		if err := rows.Scan(&run.ID, &run.MonitorID, &startedAt, &run.Error, &run.Series, &findings, &run.Alerted); err != nil {
+			return nil, fmt.Errorf("failed to read monitor run: %w", err)
+		}
+		run.StartedAt = time.Unix(startedAt, 0)
+		if err := json.Unmarshal([]byte(findings), &run.ErrorFindings); err != nil {
+			return nil, fmt.Errorf("failed to decode monitor run findings: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+const jobColumns = `id, tenant, key, payload, status, polled, attempts, worker, lease_until, result, error, created_at, finished_at`
+
+func scanJob(row scanner) (Job, error) {
+	var j Job
+	var status string
+	var leaseUntil, createdAt, finishedAt int64
+	if err := row.Scan(&j.ID, &j.Tenant, &j.Key, &j.Payload, &status, &j.Polled, &j.Attempts, &j.Worker, &leaseUntil, &j.Result, &j.Error, &createdAt, &finishedAt); err != nil {
+		return Job{}, err
+	}
+	j.Status, j.CreatedAt = JobStatus(status), time.UnixMilli(createdAt)
+	if leaseUntil != 0 {
+		j.LeaseUntil = time.UnixMilli(leaseUntil)
+	}
+	if finishedAt != 0 {
+		j.FinishedAt = time.UnixMilli(finishedAt)
+	}
+	return j, nil
+}
+
+// unixMilli is t in Unix milliseconds, or 0 for the zero time
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func (s *SQLiteStore) ReuseJob(key string, reuseSince time.Time, polled bool) (Job, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	j, err := scanJob(tx.QueryRow(`SELECT `+jobColumns+` FROM jobs
+		WHERE key = ? AND (status IN (?, ?) OR (status = ? AND created_at >= ?))
+		ORDER BY created_at DESC LIMIT 1`, key, JobQueued, JobRunning, JobDone, reuseSince.UnixMilli()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to look up job: %w", err)
+	}
+	if polled && !j.Polled {
+		if _, err := tx.Exec(`UPDATE jobs SET polled = 1 WHERE id = ?`, j.ID); err != nil {
+			return Job{}, fmt.Errorf("failed to mark job polled: %w", err)
+		}
+		j.Polled = true
+	}
+	if err := tx.Commit(); err != nil {
+		return Job{}, fmt.Errorf("failed to commit job: %w", err)
+	}
+	return j, nil
+}
+
+func (s *SQLiteStore) EnqueueJob(j *Job) error {
+	_, err := s.db.Exec(`INSERT INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		j.ID, j.Tenant, j.Key, j.Payload, string(j.Status), j.Polled, j.Attempts, j.Worker, unixMilli(j.LeaseUntil), j.Result, j.Error, j.CreatedAt.UnixMilli(), unixMilli(j.FinishedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert job: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ClaimJob(worker string, now, leaseUntil time.Time, maxAttempts int) (Job, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for {
+		j, err := scanJob(tx.QueryRow(`SELECT `+jobColumns+` FROM jobs
+			WHERE status = ? OR (status = ? AND lease_until < ?)
+			ORDER BY created_at LIMIT 1`, JobQueued, JobRunning, now.UnixMilli()))
+		if errors.Is(err, sql.ErrNoRows) {
+			// Keeps the jobs failed on the way
+			if err := tx.Commit(); err != nil {
+				return Job{}, fmt.Errorf("failed to commit failed jobs: %w", err)
+			}
+			return Job{}, ErrNotFound
+		}
+		if err != nil {
+			return Job{}, fmt.Errorf("failed to find a job to claim: %w", err)
+		}
+
+		if j.Attempts >= maxAttempts {
+			_, err = tx.Exec(`UPDATE jobs SET status = ?, error = ?, payload = NULL, worker = '', lease_until = 0, finished_at = ? WHERE id = ?`,
+				JobFailed, fmt.Sprintf("gave up after %d attempts", j.Attempts), now.UnixMilli(), j.ID)
+			if err != nil {
+				return Job{}, fmt.Errorf("failed to fail job: %w", err)
+			}
+			continue
+		}
+
+		j.Status, j.Worker, j.LeaseUntil = JobRunning, worker, leaseUntil
+		j.Attempts++
+		_, err = tx.Exec(`UPDATE jobs SET status = ?, worker = ?, lease_until = ?, attempts = ? WHERE id = ?`,
+			string(j.Status), j.Worker, j.LeaseUntil.UnixMilli(), j.Attempts, j.ID)
+		if err != nil {
+			return Job{}, fmt.Errorf("failed to claim job: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return Job{}, fmt.Errorf("failed to commit job claim: %w", err)
+		}
+		return j, nil
+	}
+}
+
+// updateHeldJob runs an update of a running job worker holds, returning
+// ErrLeaseLost when it no longer does
+func (s *SQLiteStore) updateHeldJob(id, worker, set string, args ...any) error {
+	args = append(args, id, JobRunning, worker)
+	res, err := s.db.Exec(`UPDATE jobs SET `+set+` WHERE id = ? AND status = ? AND worker = ?`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RenewJobLease(id, worker string, leaseUntil time.Time) error {
+	return s.updateHeldJob(id, worker, `lease_until = ?`, leaseUntil.UnixMilli())
+}
+
+func (s *SQLiteStore) FinishJob(id, worker string, result []byte, errMsg string, now time.Time) error {
+	status := JobDone
+	if errMsg != "" {
+		status = JobFailed
+	}
+	return s.updateHeldJob(id, worker, `status = ?, result = ?, error = ?, payload = NULL, worker = '', lease_until = 0, finished_at = ?`,
+		string(status), result, errMsg, now.UnixMilli())
+}
+
+func (s *SQLiteStore) CancelJob(id, errMsg string, now time.Time) (bool, error) {
+	res, err := s.db.Exec(`UPDATE jobs SET status = ?, error = ?, payload = NULL, worker = '', lease_until = 0, finished_at = ?
+		WHERE id = ? AND status IN (?, ?) AND NOT polled`, JobFailed, errMsg, now.UnixMilli(), id, JobQueued, JobRunning)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n == 0 {
+		// Polled, finished already, or missing
+		_, err := s.Job(id)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) Job(id string) (Job, error) {
+	j, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to read job: %w", err)
+	}
+	return j, nil
+}
+
+func (s *SQLiteStore) RequeueJobs(workerPrefix string) (int, error) {
+	res, err := s.db.Exec(`UPDATE jobs SET status = ?, worker = '', lease_until = 0
+		WHERE status = ? AND substr(worker, 1, length(?)) = ?`, JobQueued, JobRunning, workerPrefix, workerPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLiteStore) PruneJobs(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM jobs WHERE status IN (?, ?) AND finished_at < ?`, JobDone, JobFailed, before.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to prune jobs: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) JobQueueStats() (JobQueueStats, error) {
+	var stats JobQueueStats
+	var oldest sql.NullInt64
+	if err := s.db.QueryRow(`SELECT COUNT(*), MIN(created_at) FROM jobs WHERE status IN (?, ?)`, JobQueued, JobRunning).Scan(&stats.Depth, &oldest); err != nil {
+		return JobQueueStats{}, fmt.Errorf("failed to query job queue: %w", err)
+	}
+	if oldest.Valid {
+		stats.OldestCreatedAt = time.UnixMilli(oldest.Int64)
+	}
+	return stats, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
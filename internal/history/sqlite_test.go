@@ -0,0 +1,85 @@
+// ABOUTME: Tests for the SQLite store's file handling and schema migrations
+// ABOUTME: Only the database's owner may read it, and a failed migration leaves the schema at the previous version
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatabaseFileIsOwnerOnly(t *testing.T) {
+	for name, mode := range map[string]os.FileMode{"new file": 0, "existing readable file": 0o644} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history.db")
+			if mode != 0 {
+				if err := os.WriteFile(path, nil, mode); err != nil {
+					t.Fatal(err)
+				}
+				os.Chmod(path, mode)
+			}
+			store, err := OpenSQLite(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			m := Monitor{URL: "http://app:9100/metrics", Authorization: "Bearer secret", Interval: time.Minute, CreatedAt: time.Now()}
+			if err := store.AddMonitor(&m); err != nil {
+				t.Fatal(err)
+			}
+
+			matches, _ := filepath.Glob(path + "*")
+			for _, file := range matches {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm&0o077 != 0 {
+					t.Errorf("%s has mode %v, want it readable by its owner alone", filepath.Base(file), perm)
+				}
+			}
+		})
+	}
+}
+
+func TestFailedMigrationLeavesPreviousVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// A migration failing after its first statement
+	original := migrations
+	migrations = append(slices.Clone(original), `CREATE TABLE half_done (id INTEGER); INSERT INTO no_such_table VALUES (1);`)
+	defer func() { migrations = original }()
+	if _, err := OpenSQLite(path); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("migration %d failed", len(migrations))) {
+		t.Fatalf("OpenSQLite() error = %v, want the last migration to fail", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version, tables int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(original) {
+		t.Errorf("user_version = %d, want %d", version, len(original))
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Error("the failed migration's first statement was kept")
+	}
+}
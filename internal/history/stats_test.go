@@ -0,0 +1,52 @@
+// ABOUTME: Tests for the usage aggregates of both stores - token totals, cost and estimates within a time window
+// ABOUTME: Records before the window and other tenants' records are left out, and an empty tenant ID sees none
+
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	records := []Record{
+		{Tenant: "default", CreatedAt: now.Add(-10 * 24 * time.Hour), PromptTokens: 1000, ResponseTokens: 1000, Cost: 5, TokensEstimated: true},
+		{Tenant: "default", CreatedAt: now.Add(-2 * 24 * time.Hour), PromptTokens: 300, ResponseTokens: 100, Cost: 0.25},
+		{Tenant: "default", CreatedAt: now, PromptTokens: 500, ResponseTokens: 100, Cost: 0.5},
+		{Tenant: "acme", CreatedAt: now, PromptTokens: 50, ResponseTokens: 50, Cost: 1, TokensEstimated: true},
+	}
+	for name, s := range jobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, r := range records {
+				if err := s.Add(&r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			week, err := s.Stats("default", now.Add(-7*24*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if week.Evaluations != 2 || week.TotalTokens() != 1000 || week.AverageTokens() != 500 || week.Cost != 0.75 || week.Estimated {
+				t.Errorf("last week = %+v, want 2 exact evaluations of 1000 tokens costing 0.75", week)
+			}
+
+			all, err := s.Stats(AnyTenant, now.Add(-30*24*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if all.Evaluations != 4 || all.PromptTokens != 1850 || all.ResponseTokens != 1250 || !all.Estimated {
+				t.Errorf("last month = %+v, want every tenant's 4 evaluations, some estimated", all)
+			}
+
+			if none, err := s.Stats("", now.Add(-30*24*time.Hour)); err != nil || none.Evaluations != 0 {
+				t.Errorf("an empty tenant ID = %+v, %v, want no tenant's evaluations", none, err)
+			}
+
+			if empty, err := s.Stats("default", now.Add(time.Hour)); err != nil || empty.Evaluations != 0 || empty.AverageTokens() != 0 {
+				t.Errorf("an empty window = %+v, %v", empty, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// ABOUTME: German UI catalog - translations of the English catalog's keys
+// ABOUTME: Keys missing here fall back to English
+
+package i18n
+
+var german = map[string]string{
+	// layout.html
+	"layout.subtitle":    "Qualitätsprüfung für Prometheus-Metriken",
+	"layout.theme":       "Design",
+	"layout.toggle_dark": "Dunkelmodus umschalten",
+	"layout.footer":      "Mit Ollama | Schwerpunkte: Benennung, Labels, Kardinalität, Struktur",
+	"nav.evaluate":       "Bewerten",
+	"nav.examples":       "Beispiele",
+	"nav.gallery":        "Galerie",
+	"nav.usage":          "Nutzung",
+	"nav.settings":       "Einstellungen",
+	"nav.monitors":       "Überwachung",
+	"nav.language":       "Sprache",
+
+	// index.html
+	"index.heading":          "Metriken bewerten",
+	"index.intro":            "Fügen Sie unten eine oder mehrere Prometheus-Metriken zur Analyse durch ein LLM ein:",
+	"index.metrics_label":    "Ihre Metriken:",
+	"index.honeypot":         "Dieses Feld leer lassen",
+	"index.textfile":         "Dies ist eine Datei für den Textfile-Collector des node_exporter",
+	"index.include_runtime":  "Auch Standard-Laufzeitmetriken bewerten (go_, process_, promhttp_, python_gc_, jvm_)",
+	"index.pushgateway":      "Diese Metriken werden an ein Pushgateway gesendet (job und instance müssen explizit gesetzt sein)",
+	"index.label_bounds":     "Labels mit bekannter Obergrenze und ihrer Höchstzahl an Werten, z. B. customer_id=80",
+	"index.deploys_per_day":  "Deployments pro Tag, um den Serien-Churn durch Labels wie pod abzuschätzen",
+	"index.scrape_config":    "Eine Prometheus-scrape_config anwenden (relabel_configs und metric_relabel_configs)",
+	"index.share_consent":    "Betreuern erlauben, eine anonymisierte Kopie in der öffentlichen Galerie zu zeigen",
+	"index.random":           "🎲 Zufälliges Beispiel",
+	"index.submit":           "Metriken bewerten",
+	"index.loading":          "Analyse durch das LLM läuft...",
+	"index.placeholder":      "Senden Sie oben Metriken ab, um hier die Ergebnisse zu sehen.",
+	"index.examples_heading": "Beispielbewertungen",
+	"index.examples_intro":   "So werden verschiedene Metriken bewertet:",
+	"index.examples_loading": "Beispiele werden geladen...",
+
+	// result.html
+	"result.verdict":              "Urteil: %s",
+	"result.verdict_pending":      "Urteil: wartet auf das LLM",
+	"result.llm_pending":          "Das LLM prüft Ihre Metriken; sein Urteil erscheint hier, sobald es vorliegt...",
+	"result.confidence.high":      "hohe Zuverlässigkeit",
+	"result.confidence.medium":    "mittlere Zuverlässigkeit",
+	"result.confidence.low":       "geringe Zuverlässigkeit",
+	"result.analyzed":             "Analysierte Metrik(en):",
+	"result.normalized":           "Eingabe an %d Stelle(n) normalisiert: umbrochene Label-Sets zusammengefügt und nachgestellte Kommentare entfernt",
+	"result.cleaned":              "%d Nicht-Metrik-Zeile(n) aus der Eingabe bereinigt, etwa Shell-Prompts und Zeilennummern",
+	"result.runtime_excluded":     "%d Standard-Laufzeitmetriken von der Bewertung ausgenommen (zum Einbeziehen umschalten)",
+	"result.cached":               "Aus dem Bewertungscache geliefert; das LLM wurde nicht erneut aufgerufen und es fielen keine Kosten an",
+	"result.redacted":             "Geheimnisähnliche Werte wurden geschwärzt, bevor die Metriken an das LLM gingen",
+	"result.label_suggestions":    "Häufig ergänzte Labels:",
+	"result.label_hint":           "Klicken Sie auf ein Label, um es Ihren Metriken hinzuzufügen, tragen Sie den Wert ein und bewerten Sie erneut.",
+	"result.namespaces":           "Namensräume:",
+	"result.memory_by_metric":     "Speicher pro Metrik:",
+	"result.column_metric":        "Metrik",
+	"result.column_series":        "Geschätzte Serien",
+	"result.column_memory":        "Speicher",
+	"result.cardinality":          "Kardinalitätsanalyse",
+	"result.churn":                "Churn durch Deployments:",
+	"result.churn_detail":         "~%d Serien werden pro Deployment ersetzt; der Head-Block hält das %.1f-Fache der momentanen Serienzahl",
+	"result.level":                "Stufe:",
+	"result.memory_impact":        "Speicherbedarf:",
+	"result.strengths":            "Was gut ist:",
+	"result.static_checks":        "Statische Prüfungen:",
+	"result.issues":               "Gefundene Probleme:",
+	"result.recommendations":      "Empfehlungen:",
+	"result.summary_vs_histogram": "Summary oder Histogramm:",
+	"result.static_fixes":         "Angewendete statische Korrekturen:",
+	"result.recording_rules":      "Recording Rules:",
+	"result.recording_rules_hint": "Diese Metriken stammen von mehreren Instanzen. Die flottenweite Sicht vorab berechnen, damit Dashboards und Alerts nicht bei jeder Abfrage alle Instanzen aggregieren:",
+	"result.improved":             "Verbesserte Version:",
+	"result.raw_response":         "Vollständige LLM-Antwort anzeigen (%s)",
+	"result.grafana":              "Grafana-Dashboard herunterladen",
+
+	// cardinality_projection.html
+	"projection.heading": "Wachstum hochrechnen",
+	"projection.hint":    "Neue Werte pro Tag je Label, z. B. für Pods, die bei jedem Deployment neue Namen erhalten:",
+	"projection.days":    "Tage:",
+	"projection.button":  "Hochrechnen",
+	"projection.today":   "heute",
+	"projection.day":     "Tag %d",
+	"projection.summary": "Nach %d Tagen: %d Serien, %s",
+
+	// result.html histogram bucket table
+	"histogram.heading":        "Histogramm-Buckets:",
+	"histogram.hint":           "Diese Histogramme verletzen die Bucket-Struktur, auf die histogram_quantile() angewiesen ist; hervorgehobene Zeilen sind die fehlerhaften Buckets.",
+	"histogram.count":          "Anzahl",
+	"histogram.problem":        "Problem",
+	"histogram.missing_inf":    "Fehlt: Jedes Histogramm braucht einen +Inf-Bucket, der alle Beobachtungen zählt",
+	"histogram.invalid-le":     "le ist keine Zahl",
+	"histogram.unsorted":       "falsche Reihenfolge oder doppelt",
+	"histogram.non-cumulative": "weniger als ein Bucket mit kleinerem le",
+	"histogram.negative":       "negative Grenze für eine Einheit, die nicht negativ sein kann",
+
+	// result.html label heat map
+	"heatmap.heading":    "Label-Kombinationen",
+	"heatmap.hint":       "Labels multiplizieren sich: Jede Zelle zeigt, wie viele Serien ein Label-Paar zusammen erzeugen kann, die Diagonale ein Label allein. Dunklere Paare treiben die Serienzahl; für die genaue Zahl mit der Maus über eine Zelle fahren.",
+	"heatmap.cell":       "%s × %s: %d Kombinationen",
+	"heatmap.label_cell": "%s: %d Werte",
+
+	// label_check.html
+	"label_check.heading":         "Einzelnes Label prüfen",
+	"label_check.intro":           "Lohnt sich ein Label, bevor es eine Metrik dafür gibt? Gib seinen Namen und die Anzahl seiner Werte an.",
+	"label_check.label":           "Label:",
+	"label_check.values":          "Unterschiedliche Werte:",
+	"label_check.samples":         "Beispielwerte, einer pro Zeile (optional):",
+	"label_check.llm":             "Auch das LLM um seine Einschätzung bitten",
+	"label_check.button":          "Label prüfen",
+	"label_check.verdict":         "Urteil:",
+	"label_check.verdict_ok":      "ist als Label in Ordnung",
+	"label_check.verdict_monitor": "ist vorerst in Ordnung; behalte die Anzahl der Werte im Auge",
+	"label_check.verdict_review":  "hat viele Werte; prüfe, ob es die Serien wert ist",
+	"label_check.verdict_avoid":   "sollte kein Label sein",
+	"label_check.targets":         "Targets",
+	"label_check.series":          "Serien",
+	"label_check.memory":          "Speicher",
+
+	// error.html
+	"error.title":              "Fehler",
+	"error.details":            "Details:",
+	"error.settings_read_only": "Die Einstellungen sind schreibgeschützt",
+	"error.invalid_request":    "Die Bewertungsanfrage ist ungültig",
+	"error.no_metrics":         "Bitte geben Sie Metriken zur Bewertung ein",
+	"error.model_not_allowed":  "Dieses Modell ist nicht erlaubt",
+	"error.unknown_source":     "Unbekannte Metrikquelle",
+	"error.scrape_config":      "Die Scrape-Konfiguration konnte nicht verwendet werden",
+	"error.label_bound":        "Eine Label-Obergrenze ist zu groß, um ihr zu vertrauen",
+	"error.parse":              "Die Metriken konnten nicht gelesen werden",
+	"error.evaluate":           "Die Metriken konnten nicht bewertet werden",
+	"error.retry":              "Die Metriken konnten nicht bewertet werden, bitte versuchen Sie es erneut",
+	"error.stats":              "Die Nutzungsstatistik konnte nicht geladen werden",
+	"error.monitors":           "Die Überwachungen konnten nicht geladen werden",
+	"error.gallery":            "Die Galerie konnte nicht geladen werden",
+	"error.unknown_example":    "Dieses Beispiel gibt es nicht",
+	"error.unknown_job":        "Diese Bewertung ist abgelaufen oder hat nie existiert",
+	"error.projection_days":    "Die Anzahl der Tage für die Hochrechnung liegt außerhalb des erlaubten Bereichs",
+	"error.projection_growth":  "Neue Werte pro Tag müssen ganze Zahlen sein",
+	"error.label_check":        "Das Label konnte nicht geprüft werden",
+	"error.tenant_budget":      "Das heutige Budget für LLM-Bewertungen ist aufgebraucht; versuchen Sie es morgen erneut",
+}
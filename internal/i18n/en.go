@@ -0,0 +1,142 @@
+// ABOUTME: English UI catalog - the reference every other catalog is checked against
+// ABOUTME: Keys are grouped by the template or handler that uses them
+
+package i18n
+
+var english = map[string]string{
+	// layout.html
+	"layout.subtitle":    "Prometheus Metric Quality Evaluator",
+	"layout.theme":       "Theme",
+	"layout.toggle_dark": "Toggle dark mode",
+	"layout.footer":      "Powered by Ollama | Focus: Naming, Labels, Cardinality, Structure",
+	"nav.evaluate":       "Evaluate",
+	"nav.examples":       "Examples",
+	"nav.gallery":        "Gallery",
+	"nav.usage":          "Usage",
+	"nav.settings":       "Settings",
+	"nav.monitors":       "Monitors",
+	"nav.language":       "Language",
+
+	// index.html
+	"index.heading":          "Evaluate Your Metrics",
+	"index.intro":            "Paste one or more Prometheus metrics below for LLM-powered analysis:",
+	"index.metrics_label":    "Paste your metrics:",
+	"index.honeypot":         "Leave this field empty",
+	"index.textfile":         "This is a file for node_exporter's textfile collector",
+	"index.include_runtime":  "Also evaluate standard runtime metrics (go_, process_, promhttp_, python_gc_, jvm_)",
+	"index.pushgateway":      "These metrics are pushed to a Pushgateway (job and instance must be set explicitly)",
+	"index.label_bounds":     "Labels you know are bounded, with their most values, e.g. customer_id=80",
+	"index.deploys_per_day":  "Deploys per day, for estimating series churn from labels such as pod",
+	"index.scrape_config":    "Apply a Prometheus scrape_config (relabel_configs and metric_relabel_configs)",
+	"index.share_consent":    "Allow maintainers to publish an anonymized copy in the public gallery",
+	"index.random":           "🎲 Try Random Example",
+	"index.submit":           "Evaluate Metrics",
+	"index.loading":          "Analyzing with LLM...",
+	"index.placeholder":      "Submit metrics above to see evaluation results here.",
+	"index.examples_heading": "Example Evaluations",
+	"index.examples_intro":   "See how different metrics are evaluated:",
+	"index.examples_loading": "Loading examples...",
+
+	// result.html
+	"result.verdict":              "Verdict: %s",
+	"result.verdict_pending":      "Verdict: waiting for the LLM",
+	"result.llm_pending":          "The LLM is reviewing your metrics; its verdict appears here when ready...",
+	"result.confidence.high":      "high confidence",
+	"result.confidence.medium":    "medium confidence",
+	"result.confidence.low":       "low confidence",
+	"result.analyzed":             "Analyzed Metric(s):",
+	"result.normalized":           "Normalized your input in %d place(s), joining wrapped label sets and dropping trailing comments",
+	"result.cleaned":              "Cleaned %d non-metric line(s) from your paste, such as shell prompts and line numbers",
+	"result.runtime_excluded":     "%d standard runtime metrics excluded from evaluation (toggle to include)",
+	"result.cached":               "Served from the evaluation cache; the LLM wasn't called again and nothing was spent",
+	"result.redacted":             "Secret-looking values were redacted before the metrics were sent to the LLM",
+	"result.label_suggestions":    "Commonly Added Labels:",
+	"result.label_hint":           "Click a label to add it to your metrics, then fill in its value and evaluate again.",
+	"result.namespaces":           "Namespaces:",
+	"result.memory_by_metric":     "Memory by Metric:",
+	"result.column_metric":        "Metric",
+	"result.column_series":        "Estimated series",
+	"result.column_memory":        "Memory",
+	"result.cardinality":          "Cardinality Analysis",
+	"result.churn":                "Deploy Churn:",
+	"result.churn_detail":         "~%d series replaced per deploy; the head block holds %.1fx the instant series count",
+	"result.level":                "Level:",
+	"result.memory_impact":        "Memory Impact:",
+	"result.strengths":            "Why This Is Good:",
+	"result.static_checks":        "Static Checks:",
+	"result.issues":               "Issues Found:",
+	"result.recommendations":      "Recommendations:",
+	"result.summary_vs_histogram": "Summary vs Histogram:",
+	"result.static_fixes":         "Static Fixes Applied:",
+	"result.recording_rules":      "Recording Rules:",
+	"result.recording_rules_hint": "These metrics come from several instances. Precompute the fleet-wide view so dashboards and alerts don't aggregate every instance on each query:",
+	"result.improved":             "Improved Version:",
+	"result.raw_response":         "View Full LLM Response (%s)",
+	"result.grafana":              "Download Grafana Dashboard",
+
+	// cardinality_projection.html
+	"projection.heading": "Project Growth",
+	"projection.hint":    "New values per day for each label, such as pods that get new names on every deploy:",
+	"projection.days":    "Days:",
+	"projection.button":  "Project",
+	"projection.today":   "today",
+	"projection.day":     "day %d",
+	"projection.summary": "After %d days: %d series, %s",
+
+	// result.html histogram bucket table
+	"histogram.heading":        "Histogram Buckets:",
+	"histogram.hint":           "These histograms break the bucket structure histogram_quantile() relies on; highlighted rows are the broken buckets.",
+	"histogram.count":          "Count",
+	"histogram.problem":        "Problem",
+	"histogram.missing_inf":    "Missing: every histogram needs a +Inf bucket counting all observations",
+	"histogram.invalid-le":     "le is not a number",
+	"histogram.unsorted":       "out of order or repeated",
+	"histogram.non-cumulative": "fewer than a bucket with a lower le",
+	"histogram.negative":       "negative bound for a unit that can't be negative",
+
+	// result.html label heat map
+	"heatmap.heading":    "Label Interactions",
+	"heatmap.hint":       "Labels multiply: each cell is how many series a pair of labels can produce together, the diagonal a label on its own. Darker pairs drive the series count; hover a cell for its count.",
+	"heatmap.cell":       "%s × %s: %d combinations",
+	"heatmap.label_cell": "%s: %d values",
+
+	// label_check.html
+	"label_check.heading":         "Check a Single Label",
+	"label_check.intro":           "Is a label worth adding before there is a metric for it? Give its name and how many values it takes.",
+	"label_check.label":           "Label:",
+	"label_check.values":          "Distinct values:",
+	"label_check.samples":         "Sample values, one per line (optional):",
+	"label_check.llm":             "Ask the LLM for its opinion too",
+	"label_check.button":          "Check Label",
+	"label_check.verdict":         "Verdict:",
+	"label_check.verdict_ok":      "is fine as a label",
+	"label_check.verdict_monitor": "is fine for now; keep an eye on its value count",
+	"label_check.verdict_review":  "has many values; check it is worth the series",
+	"label_check.verdict_avoid":   "should not be a label",
+	"label_check.targets":         "Targets",
+	"label_check.series":          "Series",
+	"label_check.memory":          "Memory",
+
+	// error.html
+	"error.title":              "Error",
+	"error.details":            "Details:",
+	"error.settings_read_only": "Settings are read-only",
+	"error.invalid_request":    "The evaluation request is invalid",
+	"error.no_metrics":         "Please provide metrics to evaluate",
+	"error.model_not_allowed":  "That model is not allowed",
+	"error.unknown_source":     "Unknown metrics source",
+	"error.scrape_config":      "The scrape config could not be used",
+	"error.label_bound":        "A label bound is too large to trust",
+	"error.parse":              "The metrics could not be parsed",
+	"error.evaluate":           "Failed to evaluate metrics",
+	"error.retry":              "Failed to evaluate metrics, please try again",
+	"error.stats":              "Failed to load usage statistics",
+	"error.monitors":           "Failed to load monitors",
+	"error.gallery":            "Failed to load the gallery",
+	"error.unknown_example":    "There is no such example",
+	"error.unknown_job":        "This evaluation has expired or never existed",
+	"error.projection_days":    "The number of days to project is out of range",
+	"error.projection_growth":  "New values per day must be whole numbers",
+	"error.label_check":        "The label could not be checked",
+	"error.tenant_budget":      "Today's LLM evaluation budget is used up; try again tomorrow",
+}
@@ -0,0 +1,95 @@
+// ABOUTME: UI translations - message catalogs keyed by language, with lookup and Accept-Language negotiation
+// ABOUTME: Missing keys fall back to English, then to the key itself, so a gap never breaks a page
+
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"de": german,
+}
+
+// Names are the languages as written in themselves, for the language switcher
+var Names = map[string]string{
+	"en": "English",
+	"de": "Deutsch",
+}
+
+// Languages lists the supported language codes, English first
+func Languages() []string {
+	langs := []string{DefaultLanguage}
+	for lang := range catalogs {
+		if lang != DefaultLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Supported reports whether lang has a catalog
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// T returns the message for key in lang, formatted with args when given
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Negotiate picks the supported language an Accept-Language header prefers
+// most, matching on the primary subtag so de-AT selects de
+func Negotiate(header string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if Supported(primary) && q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// MissingKeys lists, per language, the English keys its catalog lacks
+func MissingKeys() map[string][]string {
+	missing := make(map[string][]string)
+	for lang, catalog := range catalogs {
+		for key := range catalogs[DefaultLanguage] {
+			if _, ok := catalog[key]; !ok {
+				missing[lang] = append(missing[lang], key)
+			}
+		}
+		slices.Sort(missing[lang])
+	}
+	return missing
+}
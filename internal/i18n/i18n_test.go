@@ -0,0 +1,123 @@
+// ABOUTME: Tests for the UI catalogs - every catalog is complete, and every key the templates and handlers use exists
+// ABOUTME: Lookups fall back to English and then to the key, and Accept-Language negotiation honours quality values
+
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for lang, keys := range MissingKeys() {
+		if len(keys) > 0 {
+			t.Errorf("%s catalog is missing %s", lang, strings.Join(keys, ", "))
+		}
+	}
+	verbs := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	for lang, catalog := range catalogs {
+		if _, ok := Names[lang]; !ok {
+			t.Errorf("%s has no name for the language switcher", lang)
+		}
+		for key, msg := range catalog {
+			english, ok := catalogs[DefaultLanguage][key]
+			if !ok {
+				t.Errorf("%s has %s, which English lacks", lang, key)
+				continue
+			}
+			// Translations take the same arguments
+			if got, want := verbs.FindAllString(msg, -1), verbs.FindAllString(english, -1); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("%s %s formats %v, English %v", lang, key, got, want)
+			}
+		}
+	}
+}
+
+func TestUsedKeysExist(t *testing.T) {
+	uses := []struct {
+		glob string
+		re   *regexp.Regexp
+	}{
+		{"../../web/templates/*.html", regexp.MustCompile(`\bt \$?\.lang "([^"]+)"`)},
+		{"../handlers/*.go", regexp.MustCompile(`renderError\(c, [^,]+, "([^"]+)"`)},
+	}
+	found := 0
+	for _, use := range uses {
+		files, err := filepath.Glob(use.glob)
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no files match %s: %v", use.glob, err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range use.re.FindAllStringSubmatch(string(data), -1) {
+				found++
+				if _, ok := catalogs[DefaultLanguage][m[1]]; !ok {
+					t.Errorf("%s uses %s, which isn't in the catalog", filepath.Base(file), m[1])
+				}
+			}
+		}
+	}
+	for _, level := range []string{"high", "medium", "low"} {
+		if _, ok := catalogs[DefaultLanguage]["result.confidence."+level]; !ok {
+			t.Errorf("no message for %s confidence", level)
+		}
+	}
+	if found == 0 {
+		t.Error("found no keys in use, so the patterns are out of date")
+	}
+}
+
+func TestTFallsBack(t *testing.T) {
+	catalogs["en"]["test.only_english"] = "Only in English"
+	catalogs["en"]["test.greeting"] = "Hello %s"
+	catalogs["de"]["test.greeting"] = "Hallo %s"
+	t.Cleanup(func() {
+		delete(catalogs["en"], "test.only_english")
+		delete(catalogs["en"], "test.greeting")
+		delete(catalogs["de"], "test.greeting")
+	})
+
+	tests := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{"de", "test.greeting", []any{"Welt"}, "Hallo Welt"},
+		{"en", "test.greeting", []any{"world"}, "Hello world"},
+		{"de", "test.only_english", nil, "Only in English"},
+		{"fr", "test.greeting", []any{"monde"}, "Hello monde"},
+		{"de", "test.missing", nil, "test.missing"},
+	}
+	for _, tt := range tests {
+		if got := T(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%s, %s) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,de;q=0.9,en;q=0.8", "de"},
+		{"en-US,en;q=0.9,de;q=0.8", "en"},
+		{"fr-FR,fr;q=0.9,de;q=0.5", "de"},
+		{"fr, it", "en"},
+		{"en;q=0.2, de;q=0.7", "de"},
+		{"de;q=bad, en;q=0.1", "en"},
+		{"DE-ch", "de"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// ABOUTME: Static improved-example generator - rewrites a submission with deterministic fixes applied
+// ABOUTME: Aligns names with declared TYPEs, converts units to base units and splits packed labels
+
+package improve
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/units"
+)
+
+// Example returns the submission rewritten with static fixes, labels written
+// in order, and false when there was nothing to fix. Each rewritten family is
+// preceded by a comment explaining the change.
+func Example(parsed *metrics.ParsedMetrics, order metrics.LabelOrder) (string, bool) {
+	var sb strings.Builder
+	changed := false
+	noted := make(map[string]bool)
+	described := make(map[string]bool)
+
+	writeNote := func(note string) {
+		if note != "" && !noted[note] {
+			noted[note] = true
+			changed = true
+			sb.WriteString("# " + note + "\n")
+		}
+	}
+
+	packed := rules.PackedLabels(parsed)
+
+	for _, m := range parsed.Metrics {
+		fixed, typeNote := fixTypeSuffix(parsed, m)
+		fixed, unitNote := convertUnits(fixed)
+		fixed, splitNotes := splitPackedLabels(packed, fixed)
+		writeNote(typeNote)
+		writeNote(unitNote)
+		for _, note := range splitNotes {
+			writeNote(note)
+		}
+
+		family, typ, declared := parsed.DeclaredFamily(m.Name)
+		if !declared {
+			family = m.Name
+		}
+		if described[family] {
+			sb.WriteString(order.FormatSample(fixed) + "\n")
+			continue
+		}
+		described[family] = true
+
+		name := renameFamily(family, typ)
+		if help, ok := parsed.Help[family]; ok {
+			sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, help))
+		}
+		if declared {
+			if typ == "summary" && hasLabel(parsed, family, "le") {
+				writeNote(fmt.Sprintf("%s declared as histogram: le buckets are histogram series", name))
+				typ = "histogram"
+			}
+			sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", name, typ))
+		}
+		sb.WriteString(order.FormatSample(fixed) + "\n")
+	}
+
+	if !changed {
+		return "", false
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
+
+// fixTypeSuffix makes a sample's name agree with its declared TYPE, trusting the
+// declaration: counters gain _total, gauges lose it, and le-labelled summary
+// samples become the histogram buckets they really are
+func fixTypeSuffix(parsed *metrics.ParsedMetrics, m metrics.Metric) (metrics.Metric, string) {
+	family, typ, ok := parsed.DeclaredFamily(m.Name)
+	if !ok {
+		return m, ""
+	}
+
+	fixed := m
+	switch {
+	case typ == "counter" && !strings.HasSuffix(m.Name, "_total"):
+		fixed.Name = m.Name + "_total"
+		return fixed, fmt.Sprintf("%s renamed to %s: counters end in _total", m.Name, fixed.Name)
+	case typ == "gauge" && strings.HasSuffix(m.Name, "_total"):
+		fixed.Name = strings.TrimSuffix(m.Name, "_total")
+		return fixed, fmt.Sprintf("%s renamed to %s: _total is reserved for counters", m.Name, fixed.Name)
+	case typ == "summary" && m.Name == family && m.Labels["le"] != "":
+		fixed.Name = m.Name + "_bucket"
+		return fixed, fmt.Sprintf("%s renamed to %s: le-labelled samples are histogram buckets", m.Name, fixed.Name)
+	}
+	return m, ""
+}
+
+// renameFamily applies the sample renames to a # HELP/# TYPE family name
+func renameFamily(family, typ string) string {
+	switch {
+	case typ == "counter" && !strings.HasSuffix(family, "_total"):
+		family += "_total"
+	case typ == "gauge":
+		family = strings.TrimSuffix(family, "_total")
+	}
+	if renamed, _, ok := units.Rename(family); ok {
+		return renamed
+	}
+	return family
+}
+
+func hasLabel(parsed *metrics.ParsedMetrics, family, label string) bool {
+	for _, m := range parsed.Metrics {
+		if f, _, ok := parsed.DeclaredFamily(m.Name); ok && f == family {
+			if _, ok := m.Labels[label]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertUnits renames a sample to its base unit and rescales its value. The
+// _bucket and _count series of a histogram or summary are event counts, so only
+// their names change; bucket boundaries in le are rescaled with the observations.
+func convertUnits(m metrics.Metric) (metrics.Metric, string) {
+	name, conv, ok := units.Rename(m.Name)
+	if !ok {
+		return m, ""
+	}
+
+	fixed := metrics.Metric{Name: name, Value: m.Value, Labels: m.Labels}
+	if !strings.HasSuffix(m.Name, "_count") && !strings.HasSuffix(m.Name, "_bucket") {
+		if v, err := units.ConvertValue(m.Value, conv); err == nil {
+			fixed.Value = v
+		}
+	}
+
+	if le, ok := m.Labels["le"]; ok {
+		if v, err := units.ConvertValue(le, conv); err == nil {
+			fixed.Labels = make(map[string]string, len(m.Labels))
+			for k, val := range m.Labels {
+				fixed.Labels[k] = val
+			}
+			fixed.Labels["le"] = v
+		}
+	}
+
+	note := fmt.Sprintf("%s converted from %s to %s (values %s)", familyOf(m.Name), conv.From, conv.To, conv.Describe())
+	return fixed, note
+}
+
+// splitPackedLabels replaces each label that packs several dimensions with one
+// label per dimension. Samples whose value breaks the pattern, or that already
+// carry one of the suggested labels, are left alone.
+func splitPackedLabels(packed []rules.PackedLabel, m metrics.Metric) (metrics.Metric, []string) {
+	var notes []string
+	for _, p := range packed {
+		value, ok := m.Labels[p.Label]
+		if !ok {
+			continue
+		}
+		parts, ok := p.Split(value)
+		if !ok {
+			continue
+		}
+		clash := false
+		for name := range parts {
+			if _, exists := m.Labels[name]; exists && name != p.Label {
+				clash = true
+			}
+		}
+		if clash {
+			continue
+		}
+
+		labels := make(map[string]string, len(m.Labels)+len(parts))
+		for k, v := range m.Labels {
+			if k != p.Label {
+				labels[k] = v
+			}
+		}
+		for k, v := range parts {
+			labels[k] = v
+		}
+		m.Labels = labels
+		notes = append(notes, fmt.Sprintf("%s split into %s: one dimension per label", p.Label, strings.Join(p.Parts, ", ")))
+	}
+	return m, notes
+}
+
+// familyOf strips the series suffixes histograms, summaries and counters expose
+func familyOf(name string) string {
+	for _, s := range []string{"_bucket", "_sum", "_count", "_created"} {
+		if base := strings.TrimSuffix(name, s); base != name {
+			return base
+		}
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+// ABOUTME: Tests for the improved example's unit conversion - renamed samples carry rescaled values
+// ABOUTME: A comment notes the factor, histogram bounds move with the observations and counts stay as they are
+
+package improve
+
+import (
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestExampleConvertsUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "gauge in milliseconds",
+			input: "# TYPE response_time_ms gauge\nresponse_time_ms 234",
+			want:  "# response_time_ms converted from milliseconds to seconds (values / 1000)\n# TYPE response_time_seconds gauge\nresponse_time_seconds 0.234",
+		},
+		{
+			name:  "percent",
+			input: "# TYPE disk_used_percent gauge\ndisk_used_percent 87.5",
+			want:  "# disk_used_percent converted from percent to ratio (values / 100)\n# TYPE disk_used_ratio gauge\ndisk_used_ratio 0.875",
+		},
+		{
+			name:  "mebibytes",
+			input: "# TYPE rss_mib gauge\nrss_mib 1.5",
+			want:  "# rss_mib converted from mebibytes to bytes (values * 1048576)\n# TYPE rss_bytes gauge\nrss_bytes 1572864",
+		},
+		{
+			name:  "NaN passes through",
+			input: "# TYPE queue_wait_ms gauge\nqueue_wait_ms NaN",
+			want:  "# queue_wait_ms converted from milliseconds to seconds (values / 1000)\n# TYPE queue_wait_seconds gauge\nqueue_wait_seconds NaN",
+		},
+		{
+			name: "histogram",
+			input: "# TYPE latency_ms histogram\n" +
+				"latency_ms_bucket{le=\"250\"} 3\nlatency_ms_bucket{le=\"+Inf\"} 4\nlatency_ms_sum 900\nlatency_ms_count 4",
+			want: "# latency_ms converted from milliseconds to seconds (values / 1000)\n# TYPE latency_seconds histogram\n" +
+				"latency_seconds_bucket{le=\"0.25\"} 3\nlatency_seconds_bucket{le=\"+Inf\"} 4\nlatency_seconds_sum 0.9\nlatency_seconds_count 4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := Example(parsed, metrics.LabelOrder{})
+			if !ok || got != tt.want {
+				t.Errorf("Example =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleWithNothingToFix(t *testing.T) {
+	parsed, err := metrics.Parse("# TYPE latency_seconds gauge\nlatency_seconds 0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Example(parsed, metrics.LabelOrder{}); ok {
+		t.Errorf("Example = %q, want nothing to fix", got)
+	}
+}
@@ -0,0 +1,92 @@
+// ABOUTME: MetricsQL query examples for VictoriaMetrics users, written as exposition comments
+// ABOUTME: Picks rollup(), rollup_rate(), aggr_over_time() and histogram queries by metric type
+
+package improve
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// MetricsQL returns example VictoriaMetrics queries for each family in the
+// submission, as # comments so they can follow the improved example. Names
+// are the improved ones, so the queries match the rewritten metrics.
+func MetricsQL(parsed *metrics.ParsedMetrics) string {
+	var sb strings.Builder
+	seen := make(map[string]bool)
+
+	for _, m := range parsed.Metrics {
+		family, typ, declared := parsed.DeclaredFamily(m.Name)
+		if !declared {
+			family, typ = familyOf(m.Name), parsed.TypeOf(m.Name)
+		}
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		name := renameFamily(family, typ)
+		by := groupingLabels(parsed, family)
+
+		if sb.Len() == 0 {
+			sb.WriteString("# MetricsQL examples:\n")
+		}
+		for _, q := range metricsqlQueries(name, typ, by) {
+			sb.WriteString("#   " + q + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func metricsqlQueries(name, typ, by string) []string {
+	switch typ {
+	case "counter":
+		return []string{
+			// MetricsQL picks the lookbehind window when it is omitted
+			fmt.Sprintf("sum(rate(%s))%s", name, by),
+			fmt.Sprintf("rollup_rate(%s[1h])", name),
+			fmt.Sprintf(`aggr_over_time(("min_over_time", "max_over_time"), rate(%s)[1h:])`, name),
+		}
+	case "histogram":
+		return []string{
+			fmt.Sprintf("histogram_quantiles(\"phi\", 0.5, 0.9, 0.99, sum(rate(%s_bucket)) by (le))", name),
+			fmt.Sprintf("histogram_share(0.5, sum(rate(%s_bucket)) by (le))", name),
+		}
+	case "summary":
+		return []string{
+			fmt.Sprintf("rollup(%s[1h])", name),
+			fmt.Sprintf("sum(rate(%s_sum)) / sum(rate(%s_count))", name, name),
+		}
+	default:
+		return []string{
+			fmt.Sprintf("rollup(%s[1h])", name),
+			fmt.Sprintf(`aggr_over_time(("min_over_time", "avg_over_time", "max_over_time"), %s[1h])`, name),
+		}
+	}
+}
+
+// groupingLabels returns a " by (...)" clause over the family's labels, or
+// nothing when it has none
+func groupingLabels(parsed *metrics.ParsedMetrics, family string) string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, m := range parsed.Metrics {
+		if familyOf(m.Name) != family {
+			continue
+		}
+		for label := range m.Labels {
+			if label != "le" && label != "quantile" && !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	sort.Strings(labels)
+	return " by (" + strings.Join(labels, ", ") + ")"
+}
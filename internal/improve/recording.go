@@ -0,0 +1,131 @@
+// ABOUTME: Aggregation recording rules for metrics pasted from a fleet of instances
+// ABOUTME: Names follow level:metric:operation; counters are rated before summing, gauges are summed and averaged
+
+package improve
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// Labels naming one scrape target or process; a fleet-wide view aggregates
+// them away and keeps the topology (job, cluster, region, namespace, ...)
+var instanceLabels = []string{
+	"instance", "pod", "pod_name", "container", "container_id", "host", "hostname",
+	"node", "nodename", "ip", "address", "server", "machine", "replica",
+}
+
+// Window the rate of a counter is taken over
+const recordingRateWindow = "5m"
+
+// Name of the generated rule group
+const recordingGroup = "fleet_aggregations"
+
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string          `yaml:"name"`
+	Rules []recordingRule `yaml:"rules"`
+}
+
+type recordingRule struct {
+	Record string `yaml:"record"`
+	Expr   string `yaml:"expr"`
+}
+
+// RecordingRules returns a Prometheus rules file precomputing the fleet-wide
+// aggregations of every series submitted from several instances, or "" when
+// none was. Names are the submitted ones, so the rules work before any
+// rename in the improved example is deployed.
+func RecordingRules(parsed *metrics.ParsedMetrics) string {
+	var names []string
+	labels := make(map[string]map[string]map[string]bool)
+	for _, m := range parsed.Metrics {
+		// Selectors aren't samples of a fleet
+		if m.Name == "" || m.Matchers != nil {
+			continue
+		}
+		if labels[m.Name] == nil {
+			names = append(names, m.Name)
+			labels[m.Name] = make(map[string]map[string]bool)
+		}
+		for l, v := range m.Labels {
+			if labels[m.Name][l] == nil {
+				labels[m.Name][l] = make(map[string]bool)
+			}
+			labels[m.Name][l][v] = true
+		}
+	}
+
+	var rules []recordingRule
+	for _, name := range names {
+		var without, kept []string
+		fleet := false
+		for _, l := range slices.Sorted(maps.Keys(labels[name])) {
+			switch {
+			case slices.Contains(instanceLabels, l):
+				without = append(without, l)
+				fleet = fleet || len(labels[name][l]) > 1
+			case l != "job":
+				kept = append(kept, l)
+			}
+		}
+		if !fleet {
+			continue
+		}
+		rules = append(rules, aggregationRules(parsed, name, without, kept)...)
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	// Two-space indents, as in the Prometheus documentation
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(2)
+	if err := enc.Encode(ruleFile{Groups: []ruleGroup{{Name: recordingGroup, Rules: rules}}}); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// aggregationRules records name summed without the instance labels. The
+// level is what remains: job, which the scrape adds, and the kept labels
+// other than a histogram's le. Summary quantiles can't be aggregated, and
+// _created timestamps and info and stateset series aren't amounts, so they
+// get none.
+func aggregationRules(parsed *metrics.ParsedMetrics, name string, without, kept []string) []recordingRule {
+	level := "job"
+	for _, l := range kept {
+		if l != "le" {
+			level += "_" + l
+		}
+	}
+	sum := "sum without (" + strings.Join(without, ", ") + ") "
+
+	typ := parsed.TypeOf(name)
+	switch {
+	case strings.HasSuffix(name, "_created"):
+		return nil
+	case typ == "counter", typ == "histogram":
+		// Rates add up across instances; raw counters reset independently
+		rate := fmt.Sprintf("rate(%s[%s])", name, recordingRateWindow)
+		return []recordingRule{{
+			Record: fmt.Sprintf("%s:%s:rate%s", level, strings.TrimSuffix(name, "_total"), recordingRateWindow),
+			Expr:   sum + "(" + rate + ")",
+		}}
+	case typ == "gauge", typ == "untyped", typ == "unknown":
+		return []recordingRule{
+			{Record: fmt.Sprintf("%s:%s:sum", level, name), Expr: sum + "(" + name + ")"},
+			{Record: fmt.Sprintf("%s:%s:avg", level, name), Expr: "avg without (" + strings.Join(without, ", ") + ") (" + name + ")"},
+		}
+	}
+	return nil
+}
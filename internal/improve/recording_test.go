@@ -0,0 +1,86 @@
+// ABOUTME: Tests for the fleet aggregation recording rules - every generated file loads in Prometheus's rules parser
+// ABOUTME: Counters and histograms are rated before summing, gauges summed and averaged, single instances get no rules
+
+package improve
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestRecordingRulesParseInPrometheus(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// Record and expr of each rule, in order
+		want [][2]string
+	}{
+		{
+			name: "gauge across instances",
+			input: "# TYPE node_memory_usage_bytes gauge\n" +
+				"node_memory_usage_bytes{instance=\"a:9100\",cluster=\"eu\"} 1\n" +
+				"node_memory_usage_bytes{instance=\"b:9100\",cluster=\"eu\"} 2",
+			want: [][2]string{
+				{"job_cluster:node_memory_usage_bytes:sum", "sum without (instance) (node_memory_usage_bytes)"},
+				{"job_cluster:node_memory_usage_bytes:avg", "avg without (instance) (node_memory_usage_bytes)"},
+			},
+		},
+		{
+			name: "counter across pods",
+			input: "# TYPE http_requests_total counter\n" +
+				"http_requests_total{pod=\"api-1\",code=\"200\"} 1\n" +
+				"http_requests_total{pod=\"api-2\",code=\"200\"} 2",
+			want: [][2]string{
+				{"job_code:http_requests:rate5m", "sum without (pod) (rate(http_requests_total[5m]))"},
+			},
+		},
+		{
+			name: "histogram across hosts",
+			input: "# TYPE request_duration_seconds histogram\n" +
+				"request_duration_seconds_bucket{host=\"a\",le=\"0.5\"} 1\n" +
+				"request_duration_seconds_bucket{host=\"a\",le=\"+Inf\"} 2\n" +
+				"request_duration_seconds_bucket{host=\"b\",le=\"0.5\"} 3\n" +
+				"request_duration_seconds_bucket{host=\"b\",le=\"+Inf\"} 4",
+			want: [][2]string{
+				{"job:request_duration_seconds_bucket:rate5m", "sum without (host) (rate(request_duration_seconds_bucket[5m]))"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := RecordingRules(parsed)
+			groups, errs := rulefmt.Parse([]byte(out), false)
+			if len(errs) > 0 {
+				t.Fatalf("Prometheus rejects the rules: %v\n%s", errs, out)
+			}
+			if len(groups.Groups) != 1 || groups.Groups[0].Name != recordingGroup {
+				t.Fatalf("groups = %+v, want one named %s", groups.Groups, recordingGroup)
+			}
+			rules := groups.Groups[0].Rules
+			if len(rules) != len(tt.want) {
+				t.Fatalf("%d rules, want %d:\n%s", len(rules), len(tt.want), out)
+			}
+			for i, want := range tt.want {
+				if rules[i].Record != want[0] || rules[i].Expr != want[1] {
+					t.Errorf("rule %d = %s: %s, want %s: %s", i, rules[i].Record, rules[i].Expr, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestNoRecordingRulesForOneInstance(t *testing.T) {
+	parsed, err := metrics.Parse("# TYPE queue_depth gauge\nqueue_depth{instance=\"a:9100\",queue=\"emails\"} 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := RecordingRules(parsed); out != "" {
+		t.Errorf("RecordingRules = %q, want none for a single instance", out)
+	}
+}
@@ -0,0 +1,304 @@
+// ABOUTME: Label schemas - which labels metric families must carry and which values those labels may take
+// ABOUTME: Parses the schema YAML, reporting every mistake with its line, and checks samples against it
+
+package labelschema
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// A schema file looks like:
+//
+//	schemas:
+//	  - name: http
+//	    metrics: ["http_*"]
+//	    required: [method, code]
+//	    values:
+//	      method: "GET|POST|PUT|DELETE"
+//	      code: "[1-5][0-9][0-9]"
+//
+// metrics are globs in path.Match syntax. Value patterns are RE2 and, as in
+// Prometheus relabeling, must match the whole value.
+
+// Schema constrains the labels of the metrics it matches
+type Schema struct {
+	Name string
+	// Line of the schema in its file
+	Line int
+	// Globs of the metric names it applies to
+	Metrics []string
+	// Labels every matching sample must carry
+	Required []string
+	// Label name to the pattern its values must match
+	Values map[string]Pattern
+}
+
+// Pattern is a value pattern as written and compiled
+type Pattern struct {
+	Source string
+	regex  *regexp.Regexp
+}
+
+// Matches reports whether the pattern matches the whole of value
+func (p Pattern) Matches(value string) bool {
+	return p.regex.MatchString(value)
+}
+
+// Issue is a mistake in a schema file
+type Issue struct {
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+var (
+	labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// What's left of a metric name glob once its wildcards and classes are removed
+	globLiteralRegex = regexp.MustCompile(`^[a-zA-Z0-9_:]*$`)
+	globSyntaxRegex  = regexp.MustCompile(`\[[^\]]*\]|[*?]`)
+)
+
+// Parse reads a schema file, returning the schemas it could make sense of
+// and every issue found. A file with issues shouldn't be used.
+func Parse(data []byte) ([]Schema, []Issue) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []Issue{yamlIssue(err)}
+	}
+	if len(doc.Content) == 0 {
+		return nil, []Issue{{Line: 1, Message: "the file is empty; it needs a schemas list"}}
+	}
+
+	var issues []Issue
+	report := func(n *yaml.Node, format string, args ...any) {
+		issues = append(issues, Issue{Line: n.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		report(root, "the file must be a mapping with a schemas list")
+		return nil, issues
+	}
+	var list *yaml.Node
+	for key, value := range pairs(root) {
+		if key.Value != "schemas" {
+			report(key, "unknown key %q; the only top-level key is schemas", key.Value)
+			continue
+		}
+		list = value
+	}
+	if list == nil {
+		report(root, "no schemas list")
+		return nil, issues
+	}
+	if list.Kind != yaml.SequenceNode {
+		report(list, "schemas must be a list")
+		return nil, issues
+	}
+
+	var schemas []Schema
+	for _, item := range list.Content {
+		if s, ok := parseSchema(item, report); ok {
+			schemas = append(schemas, s)
+		}
+	}
+	return schemas, issues
+}
+
+func parseSchema(item *yaml.Node, report func(n *yaml.Node, format string, args ...any)) (Schema, bool) {
+	s := Schema{Line: item.Line, Values: map[string]Pattern{}}
+	if item.Kind != yaml.MappingNode {
+		report(item, "a schema must be a mapping with metrics and required or values")
+		return s, false
+	}
+
+	ok := true
+	for key, value := range pairs(item) {
+		switch key.Value {
+		case "name":
+			if value.Kind != yaml.ScalarNode {
+				report(value, "name must be a string")
+				ok = false
+			}
+			s.Name = value.Value
+		case "metrics":
+			globs, valid := stringList(value, "metrics", report)
+			ok = ok && valid
+			for i, glob := range globs {
+				if msg := checkGlob(glob); msg != "" {
+					report(value.Content[i], "%s", msg)
+					ok = false
+				}
+			}
+			s.Metrics = globs
+		case "required":
+			names, valid := stringList(value, "required", report)
+			ok = ok && valid
+			for i, name := range names {
+				if !labelNameRegex.MatchString(name) {
+					report(value.Content[i], "required label %q isn't a valid label name", name)
+					ok = false
+				} else if slices.Contains(names[:i], name) {
+					report(value.Content[i], "required label %s is listed twice", name)
+				}
+			}
+			s.Required = names
+		case "values":
+			if value.Kind != yaml.MappingNode {
+				report(value, "values must map label names to patterns")
+				ok = false
+				continue
+			}
+			for label, pattern := range pairs(value) {
+				if !labelNameRegex.MatchString(label.Value) {
+					report(label, "%q isn't a valid label name", label.Value)
+					ok = false
+				}
+				if pattern.Kind != yaml.ScalarNode {
+					report(pattern, "the pattern for %s must be a string", label.Value)
+					ok = false
+					continue
+				}
+				re, err := regexp.Compile("^(?:" + pattern.Value + ")$")
+				if err != nil {
+					report(pattern, "invalid pattern for %s: %v", label.Value, err)
+					ok = false
+					continue
+				}
+				s.Values[label.Value] = Pattern{Source: pattern.Value, regex: re}
+			}
+		default:
+			report(key, "unknown key %q; a schema has name, metrics, required and values", key.Value)
+			ok = false
+		}
+	}
+
+	if len(s.Metrics) == 0 {
+		report(item, "schema %s has no metrics globs, so it applies to nothing", s.describe())
+		ok = false
+	}
+	if len(s.Required) == 0 && len(s.Values) == 0 {
+		report(item, "schema %s has neither required labels nor values, so it checks nothing", s.describe())
+	}
+	return s, ok
+}
+
+// stringList reads a list of strings
+func stringList(n *yaml.Node, field string, report func(n *yaml.Node, format string, args ...any)) ([]string, bool) {
+	if n.Kind != yaml.SequenceNode {
+		report(n, "%s must be a list", field)
+		return nil, false
+	}
+	var values []string
+	ok := true
+	for _, item := range n.Content {
+		if item.Kind != yaml.ScalarNode {
+			report(item, "%s must only hold strings", field)
+			ok = false
+		}
+		values = append(values, item.Value)
+	}
+	return values, ok
+}
+
+// checkGlob describes what is wrong with a metric name glob, or returns ""
+func checkGlob(glob string) string {
+	if glob == "" {
+		return "empty metric glob"
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return fmt.Sprintf("invalid metric glob %q: %v", glob, err)
+	}
+	if literal := globSyntaxRegex.ReplaceAllString(glob, ""); !globLiteralRegex.MatchString(literal) {
+		return fmt.Sprintf("metric glob %q can't match a metric name, which only has letters, digits, _ and :", glob)
+	}
+	return ""
+}
+
+// yamlIssue turns a YAML syntax error into an issue, keeping the line it names
+func yamlIssue(err error) Issue {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	var line int
+	if _, scanErr := fmt.Sscanf(msg, "line %d:", &line); scanErr == nil {
+		msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+	return Issue{Line: line, Message: "invalid YAML: " + msg}
+}
+
+// pairs iterates over the keys and values of a mapping node
+func pairs(n *yaml.Node) func(yield func(key, value *yaml.Node) bool) {
+	return func(yield func(key, value *yaml.Node) bool) {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !yield(n.Content[i], n.Content[i+1]) {
+				return
+			}
+		}
+	}
+}
+
+func (s Schema) describe() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("at line %d", s.Line)
+}
+
+// Matches reports whether the schema applies to a metric name
+func (s Schema) Matches(name string) bool {
+	for _, glob := range s.Metrics {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules describes what the schema checks, one line per rule
+func (s Schema) Rules() []string {
+	var rules []string
+	if len(s.Required) > 0 {
+		rules = append(rules, "requires labels "+strings.Join(s.Required, ", "))
+	}
+	for _, label := range slices.Sorted(maps.Keys(s.Values)) {
+		rules = append(rules, fmt.Sprintf("%s must match %s", label, s.Values[label].Source))
+	}
+	return rules
+}
+
+// Check returns how a sample breaks the schema, empty when it doesn't
+func (s Schema) Check(m metrics.Metric) []string {
+	var violations []string
+	for _, label := range s.Required {
+		if _, ok := m.Labels[label]; !ok {
+			violations = append(violations, "missing required label "+label)
+		}
+	}
+	for _, label := range slices.Sorted(maps.Keys(s.Values)) {
+		if value, ok := m.Labels[label]; ok && !s.Values[label].Matches(value) {
+			violations = append(violations, fmt.Sprintf("%s=%q doesn't match %s", label, value, s.Values[label].Source))
+		}
+	}
+	return violations
+}
+
+// Matching returns the schemas that apply to a metric name
+func Matching(schemas []Schema, name string) []Schema {
+	var matched []Schema
+	for _, s := range schemas {
+		if s.Matches(name) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
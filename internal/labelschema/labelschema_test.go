@@ -0,0 +1,106 @@
+// ABOUTME: Tests for label schemas - each kind of mistake is reported on its line
+// ABOUTME: and samples are matched by glob and checked for required labels and value patterns
+
+package labelschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+const validSchema = `schemas:
+  - name: http
+    metrics: ["http_*", "grpc_server_handled_total"]
+    required: [method, code]
+    values:
+      method: "GET|POST"
+      code: "[1-5][0-9][0-9]"
+`
+
+func TestParseValidSchema(t *testing.T) {
+	schemas, issues := Parse([]byte(validSchema))
+	if len(issues) > 0 {
+		t.Fatalf("issues in a valid schema: %v", issues)
+	}
+	if len(schemas) != 1 || schemas[0].Name != "http" || schemas[0].Line != 2 {
+		t.Fatalf("schemas = %+v, want http at line 2", schemas)
+	}
+	want := []string{"requires labels method, code", "code must match [1-5][0-9][0-9]", "method must match GET|POST"}
+	if got := schemas[0].Rules(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Rules() = %q, want %q", got, want)
+	}
+}
+
+func TestParseReportsIssuesWithLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		line   int
+		want   string
+	}{
+		{"invalid pattern", "schemas:\n  - metrics: [foo]\n    values:\n      method: \"(GET\"\n", 4, "invalid pattern for method"},
+		{"invalid glob", "schemas:\n  - metrics:\n      - foo\n      - \"bar[\"\n    required: [a]\n", 4, "invalid metric glob"},
+		{"glob with characters no metric name has", "schemas:\n  - metrics: [http-requests]\n    required: [a]\n", 2, "can't match a metric name"},
+		{"invalid label name", "schemas:\n  - metrics: [foo]\n    required: [ok, 2bad]\n", 3, `"2bad" isn't a valid label name`},
+		{"unknown key", "schemas:\n  - metrics: [foo]\n    requried: [a]\n", 3, `unknown key "requried"`},
+		{"no metrics", "schemas:\n  - name: x\n    required: [a]\n", 2, "has no metrics globs"},
+		{"checks nothing", "schemas:\n  - metrics: [foo]\n", 2, "checks nothing"},
+		{"not a list", "schemas: foo\n", 1, "schemas must be a list"},
+		{"unknown top-level key", "schema:\n  - metrics: [foo]\n", 1, `unknown key "schema"`},
+		{"YAML syntax", "schemas:\n  - metrics: [foo]\n\tname: x\n", 3, "invalid YAML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, issues := Parse([]byte(tt.schema))
+			for _, issue := range issues {
+				if strings.Contains(issue.Message, tt.want) {
+					if issue.Line != tt.line {
+						t.Errorf("issue %q on line %d, want %d", issue.Message, issue.Line, tt.line)
+					}
+					return
+				}
+			}
+			t.Errorf("issues = %v, want one containing %q", issues, tt.want)
+		})
+	}
+}
+
+func TestCheckSample(t *testing.T) {
+	schemas, issues := Parse([]byte(validSchema))
+	if len(issues) > 0 {
+		t.Fatal(issues)
+	}
+	tests := []struct {
+		sample  string
+		matched bool
+		want    []string
+	}{
+		{`http_requests_total{method="GET",code="200"} 1`, true, nil},
+		{`http_requests_total{method="GET"} 1`, true, []string{"missing required label code"}},
+		{`http_requests_total{method="PATCH",code="200"} 1`, true, []string{`method="PATCH" doesn't match GET|POST`}},
+		// Patterns match the whole value
+		{`grpc_server_handled_total{method="GETS",code="2000"} 1`, true, []string{`code="2000" doesn't match [1-5][0-9][0-9]`, `method="GETS" doesn't match GET|POST`}},
+		{`process_cpu_seconds_total 1`, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sample, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sample := parsed.Metrics[0]
+			matched := Matching(schemas, sample.Name)
+			if (len(matched) == 1) != tt.matched {
+				t.Fatalf("matched %d schemas, want match %v", len(matched), tt.matched)
+			}
+			if !tt.matched {
+				return
+			}
+			if got := matched[0].Check(sample); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// ABOUTME: Evaluation cache - keeps parsed LLM evaluations by model and prompt hash across requests
+// ABOUTME: BoltCache stores them in a single bbolt file, so a restart doesn't lose them
+
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ErrNoCache is ClearCache's error when no evaluation cache is configured
+var ErrNoCache = errors.New("no evaluation cache is configured")
+
+// EvaluationCache stores evaluations under CacheKey. Get and Put failures
+// only cost an LLM call, so implementations log rather than return them.
+type EvaluationCache interface {
+	Get(key string) (*Evaluation, bool)
+	Put(key string, evaluation *Evaluation)
+	// Clear evicts every entry and reports how many there were
+	Clear() (int, error)
+	// Len reports how many entries the cache holds
+	Len() (int, error)
+	Close() error
+}
+
+// CacheKey identifies a generate call: the SHA-256 of the model, the rules
+// engine fingerprint and the prompt. The fingerprint covers what the prompt
+// doesn't show, so changing the rules or thresholds retires older entries.
+func CacheKey(model, fingerprint, prompt string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + fingerprint + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetCache serves evaluations of a prompt the model has already answered
+// from cache instead of calling the LLM again
+func (c *Client) SetCache(cache EvaluationCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = cache
+}
+
+func (c *Client) evaluationCache() EvaluationCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cache
+}
+
+// ClearCache evicts every cached evaluation
+func (c *Client) ClearCache() (int, error) {
+	cache := c.evaluationCache()
+	if cache == nil {
+		return 0, ErrNoCache
+	}
+	return cache.Clear()
+}
+
+// CacheLen reports how many evaluations are cached
+func (c *Client) CacheLen() (int, error) {
+	cache := c.evaluationCache()
+	if cache == nil {
+		return 0, ErrNoCache
+	}
+	return cache.Len()
+}
+
+// Bucket holding evaluations by CacheKey
+var evaluationsBucket = []byte("evaluations")
+
+// BoltCache is an EvaluationCache in a bbolt file, with evaluations
+// gob-encoded by key
+type BoltCache struct {
+	db *bbolt.DB
+}
+
+// OpenBoltCache opens or creates the cache file at path
+func OpenBoltCache(path string) (*BoltCache, error) {
+	// bbolt locks the file; a second server on the same path fails instead of hanging
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open evaluation cache: %w", err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(evaluationsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create evaluation cache: %w", err)
+	}
+	return &BoltCache{db: db}, nil
+}
+
+func (b *BoltCache) Get(key string) (*Evaluation, bool) {
+	var evaluation *Evaluation
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(evaluationsBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		// data is only valid inside the transaction; decoding copies it
+		var decoded Evaluation
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+			return err
+		}
+		evaluation = &decoded
+		return nil
+	})
+	if err != nil {
+		// Written by a version with an incompatible Evaluation; the next Put replaces it
+		log.Printf("[LLM] Error reading cached evaluation %s: %v", key, err)
+		return nil, false
+	}
+	return evaluation, evaluation != nil
+}
+
+func (b *BoltCache) Put(key string, evaluation *Evaluation) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(evaluation); err != nil {
+		log.Printf("[LLM] Error encoding evaluation for the cache: %v", err)
+		return
+	}
+	if err := b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(evaluationsBucket).Put([]byte(key), buf.Bytes())
+	}); err != nil {
+		log.Printf("[LLM] Error writing evaluation cache: %v", err)
+	}
+}
+
+func (b *BoltCache) Clear() (int, error) {
+	var n int
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(evaluationsBucket).Stats().KeyN
+		if err := tx.DeleteBucket(evaluationsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(evaluationsBucket)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear evaluation cache: %w", err)
+	}
+	return n, nil
+}
+
+func (b *BoltCache) Len() (int, error) {
+	var n int
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(evaluationsBucket).Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+func (b *BoltCache) Close() error {
+	return b.db.Close()
+}
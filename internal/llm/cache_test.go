@@ -0,0 +1,99 @@
+// ABOUTME: Tests for the evaluation cache - entries survive reopening the bbolt file and clearing counts them
+// ABOUTME: A cache hit is marked and records no tokens, and a different engine fingerprint misses
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestBoltCacheSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	cache, err := OpenBoltCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put("key", &Evaluation{Verdict: "Good", PromptTokens: 300})
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err = OpenBoltCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	evaluation, ok := cache.Get("key")
+	if !ok || evaluation.Verdict != "Good" || evaluation.PromptTokens != 300 {
+		t.Fatalf("Get after reopening = %+v, %v", evaluation, ok)
+	}
+	if _, ok := cache.Get("missing"); ok {
+		t.Error("Get found a key that was never put")
+	}
+	if n, err := cache.Len(); n != 1 || err != nil {
+		t.Errorf("Len = %d, %v, want 1", n, err)
+	}
+	if n, err := cache.Clear(); n != 1 || err != nil {
+		t.Errorf("Clear = %d, %v, want 1", n, err)
+	}
+	if n, _ := cache.Len(); n != 0 {
+		t.Errorf("Len after Clear = %d, want 0", n)
+	}
+}
+
+func TestCachedEvaluationRecordsNoTokens(t *testing.T) {
+	var calls atomic.Int32
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"response": "VERDICT: Good\nSCORE: 90", "done": true, "prompt_eval_count": 300, "eval_count": 50})
+	}))
+	defer ollama.Close()
+
+	cache, err := OpenBoltCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	client := NewClient(ollama.URL, "llama3.2:3b")
+	client.SetCache(cache)
+
+	parsed, err := metrics.Parse("http_requests_total 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluate := func(fingerprint string) *Evaluation {
+		t.Helper()
+		evaluation, err := client.Evaluate(context.Background(), parsed, "", fingerprint, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return evaluation
+	}
+
+	if first := evaluate("engine-a"); first.Cached || first.PromptTokens != 300 {
+		t.Fatalf("first evaluation = cached %v with %d prompt tokens, want a call with 300", first.Cached, first.PromptTokens)
+	}
+	hit := evaluate("engine-a")
+	if !hit.Cached || hit.PromptTokens != 0 || hit.ResponseTokens != 0 || hit.TokensEstimated {
+		t.Errorf("repeat evaluation = cached %v with %d/%d tokens, want cached with none", hit.Cached, hit.PromptTokens, hit.ResponseTokens)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("LLM called %d times for a repeated prompt, want 1", n)
+	}
+
+	// Changed rules or thresholds don't show in the prompt but retire the entry
+	if miss := evaluate("engine-b"); miss.Cached {
+		t.Error("a different engine fingerprint was served from cache")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("LLM called %d times, want 2 after the fingerprint changed", n)
+	}
+}
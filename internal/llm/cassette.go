@@ -0,0 +1,119 @@
+// ABOUTME: LLM cassettes - records Ollama generate responses to files keyed by prompt hash and replays them
+// ABOUTME: Makes evaluations deterministic and runnable without Ollama; a changed prompt misses on purpose
+
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CassetteMode picks whether cassettes are written from the backend or served instead of it
+type CassetteMode string
+
+const (
+	// CassetteReplay serves generate calls from cassettes and fails on a miss
+	CassetteReplay CassetteMode = "replay"
+	// CassetteRecord sends generate calls to the backend and saves each response
+	CassetteRecord CassetteMode = "record"
+)
+
+// cassette is one recorded generate call. The prompt is kept so a changed
+// cassette can be reviewed in a diff.
+type cassette struct {
+	Model    string          `json:"model"`
+	Prompt   string          `json:"prompt"`
+	Response json.RawMessage `json:"response"`
+}
+
+// cassetteTransport intercepts POST /api/generate; every other request goes to next
+type cassetteTransport struct {
+	dir  string
+	mode CassetteMode
+	next http.RoundTripper
+}
+
+// SetCassettes records generate calls to dir, or replays them from it, keyed
+// by a hash of the prompt. Other requests, such as listing models, still go
+// to the backend.
+func (c *Client) SetCassettes(dir string, mode CassetteMode) error {
+	if mode != CassetteReplay && mode != CassetteRecord {
+		return fmt.Errorf("unknown cassette mode %q (choose %s or %s)", mode, CassetteReplay, CassetteRecord)
+	}
+	next := c.httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c.httpClient.Transport = &cassetteTransport{dir: dir, mode: mode, next: next}
+	return nil
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/api/generate") {
+		return t.next.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var generate ollamaRequest
+	if err := json.Unmarshal(body, &generate); err != nil {
+		return nil, fmt.Errorf("reading generate request: %w", err)
+	}
+	sum := sha256.Sum256([]byte(generate.Prompt))
+	hash := hex.EncodeToString(sum[:])
+	path := filepath.Join(t.dir, hash[:16]+".json")
+
+	if t.mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("no cassette for prompt sha256:%s at %s. The prompt or input changed, or this input "+
+				"was never recorded: re-record with LLM_CASSETTE_MODE=%s against a running Ollama, then review and commit %s",
+				hash, path, CassetteRecord, path)
+		}
+		var recorded cassette
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			return nil, fmt.Errorf("reading cassette %s: %w", path, err)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(recorded.Response)),
+			Request:    req,
+		}, nil
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	response, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(cassette{Model: generate.Model, Prompt: generate.Prompt, Response: response}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("writing cassette: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(response))
+	return resp, nil
+}
@@ -5,32 +5,78 @@ package llm
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/cost"
 	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/redact"
 )
 
 type Client struct {
 	baseURL    string
-	model      string
+	apiKey     string
 	httpClient *http.Client
+
+	mu      sync.RWMutex
+	model   string
+	routing Routing
+	// Applied to logged prompts and responses
+	redactor *redact.Redactor
+	// Also redact the prompt sent to the LLM
+	redactPrompt bool
+	// nil calls the LLM for every evaluation
+	cache EvaluationCache
 }
 
 type Evaluation struct {
 	Verdict             string
 	OverallScore        string
+	Strengths           []string
 	Issues              []string
 	Recommendations     []string
 	ImprovedExample     string
 	CardinalityAnalysis string
 	MemoryImpact        string
 	RawResponse         string
+
+	// 0-100 from the SCORE line, graded A to F. LetterGrade is empty when the
+	// response had no score; OverallScore repeats it for older clients.
+	Score       int
+	LetterGrade string
+
+	// HELP text and TYPE of the first family annotated in ImprovedExample
+	ImprovedExampleHelp string
+	ImprovedExampleType string
+
+	// How far the verdict can be trusted, scored from ConfidenceSignals
+	ConfidenceSignals ConfidenceSignals
+	Confidence        Confidence
+
+	// Secret-looking values were replaced in the prompt before it was sent
+	Redacted bool
+	// Served from the evaluation cache without calling the LLM
+	Cached bool
+
+	// Usage metadata for cost accounting
+	Model           string
+	PromptChars     int
+	ResponseChars   int
+	PromptTokens    int
+	ResponseTokens  int
+	TokensEstimated bool
 }
 
 type ollamaRequest struct {
@@ -40,8 +86,22 @@ type ollamaRequest struct {
 }
 
 type ollamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+// TagsResponse is Ollama's GET /api/tags listing of installed models
+type TagsResponse struct {
+	Models []OllamaModel `json:"models"`
+}
+
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
 }
 
 // ============================================================================
@@ -106,18 +166,44 @@ OFFICIAL PROMETHEUS NAMING CONVENTIONS:
    - Histogram: Observations in buckets (request_duration_seconds)
    - Summary: Like histogram but with quantiles
 
-5. COMMON ANTIPATTERNS:
+5. SUMMARIES VS HISTOGRAMS:
+   A summary computes its quantiles (quantile="0.99") inside each process, so they cannot be
+   averaged or summed across instances; the result is not a quantile of anything. A histogram
+   exposes bucket counters (_bucket with le) that can be summed across instances first and turned
+   into any quantile with histogram_quantile. Recommend histograms when the metric is aggregated
+   across instances; a summary is only acceptable for a single instance with fixed quantiles.
+   Never suggest averaging summary quantiles.
+
+6. COMMON ANTIPATTERNS:
    - Storing ratios/percentages as metrics (calculate in queries instead)
    - Using milliseconds instead of seconds for time
    - Combining multiple UNBOUNDED labels (multiplication effect causes cardinality explosion)
    - Missing _total suffix on counters
    - Using camelCase or UPPERCASE`
 
+// PushgatewayInstructions marks metrics pushed to a Pushgateway, where nothing
+// adds job and instance; passing it drops scrapeLabelsNote from the prompt
+const PushgatewayInstructions = "Note: this metric will be pushed to Pushgateway — job and instance labels must be set explicitly"
+
+// LabelBoundsInstructions tells the LLM the submitter has justified labels by
+// bounding their distinct values, so it doesn't recommend removing them
+func LabelBoundsInstructions(bounds map[string]int) string {
+	var sb strings.Builder
+	sb.WriteString("Note: the submitter asserts these labels are bounded, and the cardinality estimate uses the bounds:\n")
+	for _, name := range slices.Sorted(maps.Keys(bounds)) {
+		fmt.Fprintf(&sb, "- %s: at most %d distinct values\n", name, bounds[name])
+	}
+	sb.WriteString("Do not recommend removing these labels for being unbounded; judge them by their bounded cardinality, and remind the submitter to re-evaluate if the number of values grows.")
+	return sb.String()
+}
+
+const scrapeLabelsNote = `- Missing "instance" or "job" labels (added automatically by Prometheus during scraping)
+`
+
 const evaluationInstructions = `
 IMPORTANT - DO NOT flag these as issues:
 - Missing # TYPE or # HELP comments (not required for evaluation)
-- Missing "instance" or "job" labels (added automatically by Prometheus during scraping)
-- Single sample cardinality estimation (expected - users typically submit one metric)
+` + scrapeLabelsNote + `- Single sample cardinality estimation (expected - users typically submit one metric)
 - Missing metric value (values are optional in the exposition format)
 - endpoint/handler/route labels (these are SAFE and CRITICAL for web apps)
 - method/status labels (these are ALWAYS SAFE)
@@ -133,16 +219,20 @@ When providing IMPROVED EXAMPLE:
 - KEEP bounded labels like method, status, endpoint (these are correct)
 - Use concise names (e.g., http_requests_total, NOT requests_sent_by_get_request)
 - Only change what's actually broken
+- Precede each metric family with its # HELP and # TYPE lines
 
 Provide your evaluation in this EXACT format:
 
 VERDICT: [Good/Needs Improvement/Poor]
+SCORE: [0-100 integer]
+STRENGTHS:
+- [list what the metrics already do well, one per line]
 ISSUES:
 - [list specific issues, one per line]
 RECOMMENDATIONS:
 - [list specific recommendations, one per line]
 IMPROVED EXAMPLE:
-[show corrected metric with proper naming and labels]`
+[show corrected metric with proper naming and labels, with # HELP and # TYPE lines]`
 
 // ============================================================================
 
@@ -150,22 +240,142 @@ func NewClient(baseURL, model string) *Client {
 	return &Client{
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		model:   model,
+		routing: DefaultRouting,
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
 	}
 }
 
-func (c *Client) Evaluate(parsed *metrics.ParsedMetrics) (*Evaluation, error) {
-	log.Printf("[LLM] Starting evaluation with model %s at %s", c.model, c.baseURL)
+// PromptVersion identifies the evaluation prompt, changing whenever it is edited
+func PromptVersion() string {
+	sum := sha256.Sum256([]byte(systemPrompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Model returns the model evaluations currently use
+func (c *Client) Model() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.model
+}
+
+// SetModel switches the model for subsequent evaluations, e.g. on config reload
+func (c *Client) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.model = model
+}
+
+// SetRedactor changes the patterns redacted from logged prompts and responses, e.g. on config reload
+func (c *Client) SetRedactor(r *redact.Redactor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactor = r
+}
+
+// SetRedactPrompt redacts the prompt before it is sent as well, so the LLM
+// never sees the matched values
+func (c *Client) SetRedactPrompt(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactPrompt = enabled
+}
+
+func (c *Client) redaction() (*redact.Redactor, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.redactor, c.redactPrompt
+}
+
+// SetAPIKey sends key as a bearer token, for backends behind an authenticating proxy
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// do sends a request with the API key attached when one is configured
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpClient.Do(req)
+}
 
+// Evaluate asks the LLM to judge the metrics. instructions, when set, follow
+// the system prompt, so a naming profile can adjust the Prometheus guidance.
+// fingerprint identifies the rules engine that judged the input statically
+// and is part of the cache key.
+// An empty model is chosen by the routing rules; callers check a requested
+// model with AllowsModel first. Cancelling ctx abandons the backend call.
+func (c *Client) Evaluate(ctx context.Context, parsed *metrics.ParsedMetrics, instructions, fingerprint, model string) (*Evaluation, error) {
 	// Build the prompt
-	prompt := c.buildPrompt(parsed)
-	log.Printf("[LLM] Built prompt (%d chars):\n%s\n---END PROMPT---", len(prompt), prompt)
+	prompt := c.buildPrompt(parsed, instructions)
+	redactor, redactPrompt := c.redaction()
+	redacted := false
+	if redactPrompt {
+		clean := redactor.Redact(prompt)
+		redacted = clean != prompt
+		prompt = clean
+	}
+	log.Printf("[LLM] Built prompt (%d chars):\n%s\n---END PROMPT---", len(prompt), redactor.Redact(prompt))
+
+	reason := "requested"
+	if model == "" {
+		model, reason = c.Routing().route(c.Model(), parsed, prompt)
+	}
+	cache, key := c.evaluationCache(), CacheKey(model, fingerprint, prompt)
+	if cache != nil {
+		if evaluation, ok := cache.Get(key); ok {
+			log.Printf("[LLM] Serving cached evaluation %s for model %s", key[:16], model)
+			// No call was made, so there are no tokens to account for
+			evaluation.Cached = true
+			evaluation.PromptTokens, evaluation.ResponseTokens, evaluation.TokensEstimated = 0, 0, false
+			return evaluation, nil
+		}
+	}
+	log.Printf("[LLM] Starting evaluation with model %s (%s) at %s", model, reason, c.baseURL)
+
+	ollamaResp, err := c.generate(ctx, model, prompt)
+	if err != nil {
+		return nil, err
+	}
 
-	// Call Ollama API
+	log.Printf("[LLM] Received response (%d chars):\n%s\n---END RESPONSE---",
+		len(ollamaResp.Response), redactor.Redact(ollamaResp.Response))
+
+	// Parse the LLM response into structured evaluation
+	evaluation := c.parseResponse(ollamaResp.Response, parsed.CardinalityAnalysis)
+	log.Printf("[LLM] Parsed evaluation: Verdict=%s, Issues=%d, Recommendations=%d",
+		evaluation.Verdict, len(evaluation.Issues), len(evaluation.Recommendations))
+
+	evaluation.ConfidenceSignals.PartialResponse = !ollamaResp.Done
+	evaluation.ConfidenceSignals.SingleSample = len(parsed.Metrics) == 1
+	evaluation.Confidence = ScoreConfidence(evaluation.ConfidenceSignals)
+
+	evaluation.Model = model
+	evaluation.Redacted = redacted
+	evaluation.PromptChars = len(prompt)
+	evaluation.ResponseChars = len(ollamaResp.Response)
+	evaluation.PromptTokens = ollamaResp.PromptEvalCount
+	evaluation.ResponseTokens = ollamaResp.EvalCount
+	// Older Ollama versions don't report token counts
+	if evaluation.PromptTokens == 0 || evaluation.ResponseTokens == 0 {
+		evaluation.PromptTokens = cost.EstimateTokens(prompt)
+		evaluation.ResponseTokens = cost.EstimateTokens(ollamaResp.Response)
+		evaluation.TokensEstimated = true
+	}
+
+	// A cut-off response is worth asking for again rather than keeping
+	if cache != nil && ollamaResp.Done {
+		cache.Put(key, evaluation)
+	}
+	return evaluation, nil
+}
+
+// generate sends prompt to model and returns Ollama's whole response
+func (c *Client) generate(ctx context.Context, model, prompt string) (*ollamaResponse, error) {
 	reqBody := ollamaRequest{
-		Model:  c.model,
+		Model:  model,
 		Prompt: prompt,
 		Stream: false,
 	}
@@ -179,12 +389,14 @@ func (c *Client) Evaluate(parsed *metrics.ParsedMetrics) (*Evaluation, error) {
 	apiURL := c.baseURL + "/api/generate"
 	log.Printf("[LLM] Calling Ollama API: POST %s", apiURL)
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
 	start := time.Now()
-	resp, err := c.httpClient.Post(
-		apiURL,
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(req)
 	if err != nil {
 		log.Printf("[LLM] Error calling Ollama API: %v", err)
 		return nil, fmt.Errorf("failed to call Ollama API: %w", err)
@@ -203,25 +415,60 @@ func (c *Client) Evaluate(parsed *metrics.ParsedMetrics) (*Evaluation, error) {
 		log.Printf("[LLM] Error decoding response: %v", err)
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return &ollamaResp, nil
+}
 
-	log.Printf("[LLM] Received response (%d chars):\n%s\n---END RESPONSE---",
-		len(ollamaResp.Response), ollamaResp.Response)
+// Tags lists the models installed on the Ollama backend. Cancelling ctx
+// abandons the call.
+func (c *Client) Tags(ctx context.Context) (*TagsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
 
-	// Parse the LLM response into structured evaluation
-	evaluation := c.parseResponse(ollamaResp.Response, parsed.CardinalityAnalysis)
-	log.Printf("[LLM] Parsed evaluation: Verdict=%s, Issues=%d, Recommendations=%d",
-		evaluation.Verdict, len(evaluation.Issues), len(evaluation.Recommendations))
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama API: %w", err)
+	}
+	defer resp.Body.Close()
 
-	return evaluation, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama API returned status %d", resp.StatusCode)
+	}
+
+	var tags TagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &tags, nil
 }
 
-func (c *Client) buildPrompt(parsed *metrics.ParsedMetrics) string {
+// HasModel reports whether name is installed, treating a missing tag as ":latest"
+func (t *TagsResponse) HasModel(name string) bool {
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	for _, m := range t.Models {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) buildPrompt(parsed *metrics.ParsedMetrics, instructions string) string {
 	var sb strings.Builder
 
 	// System prompt with Prometheus best practices
 	sb.WriteString(systemPrompt)
 	sb.WriteString("\n\n")
 
+	// Backend-specific conventions from the naming profile
+	if instructions != "" {
+		sb.WriteString(instructions)
+		sb.WriteString("\n\n")
+	}
+
 	// TODO(RAG): Add retrieval-augmented generation here
 	// Before evaluating, search docs/ directory for:
 	// 1. Similar good metric examples from real production systems
@@ -237,6 +484,10 @@ func (c *Client) buildPrompt(parsed *metrics.ParsedMetrics) string {
 		sb.WriteString(fmt.Sprintf("%s\n", m.Raw))
 	}
 	sb.WriteString("\n")
+	if parsed.Selector {
+		sb.WriteString(metrics.SelectorNote)
+		sb.WriteString("\n\n")
+	}
 
 	// Cardinality analysis from our calculator
 	if parsed.CardinalityAnalysis != nil {
@@ -251,10 +502,34 @@ func (c *Client) buildPrompt(parsed *metrics.ParsedMetrics) string {
 			}
 		}
 		sb.WriteString("\n")
+
+		// Churn is a separate cost from the instant series count above
+		if a := parsed.CardinalityAnalysis; len(a.ChurnLabels) > 0 {
+			sb.WriteString("DEPLOY CHURN (separate from instant cardinality):\n")
+			sb.WriteString(fmt.Sprintf("Labels replaced on every deploy: %s\n", strings.Join(a.ChurnLabels, ", ")))
+			if a.ChurnSeriesPerDeploy > 0 {
+				sb.WriteString(fmt.Sprintf("Series replaced per deploy: ~%d at %g deploys a day\n", a.ChurnSeriesPerDeploy, a.DeploysPerDay))
+				sb.WriteString(fmt.Sprintf("Head block series: ~%.1fx the instant count (included in the memory estimate)\n", a.ChurnMultiplier))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	// Problems with the input itself, so the LLM doesn't mistake them for naming issues
+	if len(parsed.Warnings) > 0 {
+		sb.WriteString("INPUT WARNINGS:\n")
+		for _, warning := range parsed.Warnings {
+			sb.WriteString(fmt.Sprintf("- %s\n", warning))
+		}
+		sb.WriteString("\n")
 	}
 
 	// Output format instructions
-	sb.WriteString(evaluationInstructions)
+	if strings.Contains(instructions, PushgatewayInstructions) {
+		sb.WriteString(strings.Replace(evaluationInstructions, scrapeLabelsNote, "", 1))
+	} else {
+		sb.WriteString(evaluationInstructions)
+	}
 
 	return sb.String()
 }
@@ -275,6 +550,20 @@ func (c *Client) parseResponse(response string, cardAnalysis *cardinality.Analys
 			continue
 		}
 
+		if strings.HasPrefix(line, "SCORE:") {
+			if score, ok := parseScore(strings.TrimPrefix(line, "SCORE:")); ok {
+				eval.Score = score
+				eval.LetterGrade = letterGrade(score)
+				eval.OverallScore = eval.LetterGrade
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "STRENGTHS:") {
+			currentSection = "strengths"
+			continue
+		}
+
 		if strings.HasPrefix(line, "ISSUES:") {
 			currentSection = "issues"
 			continue
@@ -294,12 +583,15 @@ func (c *Client) parseResponse(response string, cardAnalysis *cardinality.Analys
 		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
 			item := strings.TrimPrefix(strings.TrimPrefix(line, "- "), "* ")
 			switch currentSection {
+			case "strengths":
+				eval.Strengths = append(eval.Strengths, item)
 			case "issues":
 				eval.Issues = append(eval.Issues, item)
 			case "recommendations":
 				eval.Recommendations = append(eval.Recommendations, item)
 			}
 		} else if currentSection == "example" && line != "" {
+			parseExampleComment(eval, line)
 			if eval.ImprovedExample != "" {
 				eval.ImprovedExample += "\n"
 			}
@@ -316,6 +608,7 @@ func (c *Client) parseResponse(response string, cardAnalysis *cardinality.Analys
 	}
 
 	// Set defaults if parsing failed
+	eval.ConfidenceSignals.DefaultedResponse = eval.Verdict == "" || len(eval.Issues) == 0
 	if eval.Verdict == "" {
 		eval.Verdict = "Analysis Completed"
 	}
@@ -325,3 +618,47 @@ func (c *Client) parseResponse(response string, cardAnalysis *cardinality.Analys
 
 	return eval
 }
+
+// parseExampleComment records the first # HELP and # TYPE lines of the improved example
+func parseExampleComment(eval *Evaluation, line string) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 4 || fields[0] != "#" {
+		return
+	}
+	switch {
+	case fields[1] == "HELP" && eval.ImprovedExampleHelp == "":
+		eval.ImprovedExampleHelp = strings.TrimSpace(fields[3])
+	case fields[1] == "TYPE" && eval.ImprovedExampleType == "":
+		eval.ImprovedExampleType = strings.TrimSpace(fields[3])
+	}
+}
+
+// parseScore reads the integer at the start of a SCORE value, tolerating
+// forms such as "85/100", and clamps it to 0-100
+func parseScore(value string) (int, bool) {
+	value = strings.TrimSpace(strings.Trim(strings.TrimSpace(value), "[]"))
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	score, err := strconv.Atoi(value[:end])
+	if err != nil {
+		return 0, false
+	}
+	return min(score, 100), true
+}
+
+// letterGrade maps a 0-100 score to A (90 and up) through F (below 60)
+func letterGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
@@ -0,0 +1,111 @@
+// ABOUTME: Tests for the prompt and the LLM response - the SCORE line becomes a 0-100 score and an A to F letter grade
+// ABOUTME: The improved example keeps its # HELP and # TYPE lines, and pasted selectors get the label-design-only note
+
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestOutputFormatAsksForScoreAfterVerdict(t *testing.T) {
+	if !strings.Contains(evaluationInstructions, "\nVERDICT: [Good/Needs Improvement/Poor]\nSCORE: [0-100 integer]\n") {
+		t.Errorf("evaluationInstructions lacks a SCORE line straight after VERDICT:\n%s", evaluationInstructions)
+	}
+}
+
+func TestParseResponseScore(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantScore int
+		wantGrade string
+	}{
+		{"SCORE: 95", 95, "A"},
+		{"SCORE: 90", 90, "A"},
+		{"SCORE: 89", 89, "B"},
+		{"SCORE: 80", 80, "B"},
+		{"SCORE: 79", 79, "C"},
+		{"SCORE: 70", 70, "C"},
+		{"SCORE: 69", 69, "D"},
+		{"SCORE: 60", 60, "D"},
+		{"SCORE: 59", 59, "F"},
+		{"SCORE: 0", 0, "F"},
+		{"SCORE: 85/100", 85, "B"},
+		{"SCORE: [72]", 72, "C"},
+		{"  SCORE:100  ", 100, "A"},
+		// Past the scale is the top of it
+		{"SCORE: 250", 100, "A"},
+		{"SCORE: -5", 0, ""},
+		{"SCORE: high", 0, ""},
+		{"SCORE:", 0, ""},
+		{"", 0, ""},
+	}
+	c := NewClient("", "")
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			eval := c.parseResponse("VERDICT: Good\n"+tt.line+"\nISSUES:\n- None\n", nil)
+			if eval.Score != tt.wantScore || eval.LetterGrade != tt.wantGrade {
+				t.Errorf("Score, LetterGrade = %d, %q, want %d, %q", eval.Score, eval.LetterGrade, tt.wantScore, tt.wantGrade)
+			}
+			if eval.OverallScore != eval.LetterGrade {
+				t.Errorf("OverallScore = %q, want the letter grade %q", eval.OverallScore, eval.LetterGrade)
+			}
+			if eval.Verdict != "Good" || len(eval.Issues) != 1 || eval.Issues[0] != "None" {
+				t.Errorf("the SCORE line disturbed the other sections: %+v", eval)
+			}
+		})
+	}
+}
+
+func TestParseResponseImprovedExampleComments(t *testing.T) {
+	if !strings.Contains(evaluationInstructions, "# HELP and # TYPE lines") {
+		t.Error("evaluationInstructions doesn't ask for # HELP and # TYPE lines in the improved example")
+	}
+
+	tests := []struct {
+		name               string
+		example            string
+		wantHelp, wantType string
+	}{
+		{"both", "# HELP http_requests_total Total HTTP requests.\n# TYPE http_requests_total counter\nhttp_requests_total 1",
+			"Total HTTP requests.", "counter"},
+		{"the first of each", "# HELP a_total First.\n# TYPE a_total counter\na_total 1\n# HELP b Second.\n# TYPE b gauge\nb 1",
+			"First.", "counter"},
+		{"neither", "http_requests_total 1", "", ""},
+		{"a bare HELP", "# HELP http_requests_total\nhttp_requests_total 1", "", ""},
+		{"other comments", "# improved names\nhttp_requests_total 1", "", ""},
+	}
+	c := NewClient("", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := c.parseResponse("VERDICT: Good\nISSUES:\n- None\nIMPROVED EXAMPLE:\n"+tt.example+"\n", nil)
+			if eval.ImprovedExampleHelp != tt.wantHelp || eval.ImprovedExampleType != tt.wantType {
+				t.Errorf("help, type = %q, %q, want %q, %q", eval.ImprovedExampleHelp, eval.ImprovedExampleType, tt.wantHelp, tt.wantType)
+			}
+			if eval.ImprovedExample != tt.example {
+				t.Errorf("ImprovedExample = %q, want the comments kept: %q", eval.ImprovedExample, tt.example)
+			}
+		})
+	}
+}
+
+func TestPromptNotesSelectors(t *testing.T) {
+	c := NewClient("", "")
+	for _, tt := range []struct {
+		input    string
+		wantNote bool
+	}{
+		{`{job="myapp", method=~"GET|POST"}`, true},
+		{`http_requests_total{method="GET"} 1`, false},
+	} {
+		parsed, err := metrics.Parse(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(c.buildPrompt(parsed, ""), metrics.SelectorNote); got != tt.wantNote {
+			t.Errorf("%s: prompt has the selector note = %v, want %v", tt.input, got, tt.wantNote)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Verdict confidence - scores how far an LLM verdict can be trusted from signals around it
+// ABOUTME: Each signal present subtracts a fixed weight from 1, so equal signals always give equal scores
+
+package llm
+
+import "strings"
+
+// ConfidenceSignals are the reasons to doubt a verdict
+type ConfidenceSignals struct {
+	// The backend stopped generating before it finished
+	PartialResponse bool
+	// The response had no VERDICT or no issues, so defaults were filled in
+	DefaultedResponse bool
+	// A Good verdict despite static errors or warnings, or Poor despite none
+	StaticDisagreement bool
+	// A single sample gives the LLM little to judge
+	SingleSample bool
+}
+
+// Weights subtracted from a confidence of 1 for each signal present:
+//   - partial response 0.4: whatever was cut off may have changed the verdict
+//   - defaulted response 0.3: the verdict or issues weren't in the expected format
+//   - static disagreement 0.25: the deterministic rules point the other way
+//   - single sample 0.1: fine on its own, but little context for the judgement
+const (
+	weightPartialResponse    = 0.4
+	weightDefaultedResponse  = 0.3
+	weightStaticDisagreement = 0.25
+	weightSingleSample       = 0.1
+)
+
+// ConfidenceFactor is a signal that lowered the score, with the weight it cost
+type ConfidenceFactor struct {
+	Reason string
+	Weight float64
+}
+
+type Confidence struct {
+	// Score is between 0 and 1
+	Score   float64
+	Level   string // high, medium or low
+	Factors []ConfidenceFactor
+}
+
+// ScoreConfidence turns signals into a score, in a fixed factor order
+func ScoreConfidence(s ConfidenceSignals) Confidence {
+	c := Confidence{Score: 1}
+	add := func(present bool, reason string, weight float64) {
+		if present {
+			c.Factors = append(c.Factors, ConfidenceFactor{Reason: reason, Weight: weight})
+			c.Score -= weight
+		}
+	}
+	add(s.PartialResponse, "the LLM response was cut off", weightPartialResponse)
+	add(s.DefaultedResponse, "the LLM response was missing its verdict or issues", weightDefaultedResponse)
+	add(s.StaticDisagreement, "the verdict disagrees with the static checks", weightStaticDisagreement)
+	add(s.SingleSample, "only one sample was submitted", weightSingleSample)
+
+	// Round away float noise so equal signals compare equal, e.g. 0.35 not 0.35000000000000003
+	c.Score = float64(int(max(c.Score, 0)*100+0.5)) / 100
+
+	switch {
+	case c.Score >= 0.8:
+		c.Level = "high"
+	case c.Score >= 0.5:
+		c.Level = "medium"
+	default:
+		c.Level = "low"
+	}
+	return c
+}
+
+// CompareStatic records whether the verdict disagrees with the static checks,
+// given how many errors and warnings they found, and rescores confidence
+func (e *Evaluation) CompareStatic(problems int) {
+	verdict := strings.ToLower(e.Verdict)
+	e.ConfidenceSignals.StaticDisagreement = (strings.HasPrefix(verdict, "good") && problems > 0) ||
+		(strings.HasPrefix(verdict, "poor") && problems == 0)
+	e.Confidence = ScoreConfidence(e.ConfidenceSignals)
+}
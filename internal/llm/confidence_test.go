@@ -0,0 +1,98 @@
+// ABOUTME: Tests for the confidence score - every combination of signals, with its score, level and factors
+// ABOUTME: Equal signals always give an equal score, and a verdict against the static checks lowers it
+
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScoreConfidenceCombinations(t *testing.T) {
+	tests := []struct {
+		partial, defaulted, disagree, single bool
+		score                                float64
+		level                                string
+	}{
+		{false, false, false, false, 1, "high"},
+		{false, false, false, true, 0.9, "high"},
+		{false, false, true, false, 0.75, "medium"},
+		{false, false, true, true, 0.65, "medium"},
+		{false, true, false, false, 0.7, "medium"},
+		{false, true, false, true, 0.6, "medium"},
+		{false, true, true, false, 0.45, "low"},
+		{false, true, true, true, 0.35, "low"},
+		{true, false, false, false, 0.6, "medium"},
+		{true, false, false, true, 0.5, "medium"},
+		{true, false, true, false, 0.35, "low"},
+		{true, false, true, true, 0.25, "low"},
+		{true, true, false, false, 0.3, "low"},
+		{true, true, false, true, 0.2, "low"},
+		{true, true, true, false, 0.05, "low"},
+		// The weights add up to more than 1; the score stops at 0
+		{true, true, true, true, 0, "low"},
+	}
+	for _, tt := range tests {
+		signals := ConfidenceSignals{
+			PartialResponse:    tt.partial,
+			DefaultedResponse:  tt.defaulted,
+			StaticDisagreement: tt.disagree,
+			SingleSample:       tt.single,
+		}
+		got := ScoreConfidence(signals)
+		if got.Score != tt.score || got.Level != tt.level {
+			t.Errorf("%+v = %v %s, want %v %s", signals, got.Score, got.Level, tt.score, tt.level)
+		}
+
+		var want []float64
+		for _, f := range []struct {
+			present bool
+			weight  float64
+		}{
+			{tt.partial, weightPartialResponse},
+			{tt.defaulted, weightDefaultedResponse},
+			{tt.disagree, weightStaticDisagreement},
+			{tt.single, weightSingleSample},
+		} {
+			if f.present {
+				want = append(want, f.weight)
+			}
+		}
+		var weights []float64
+		for _, f := range got.Factors {
+			weights = append(weights, f.Weight)
+		}
+		if !reflect.DeepEqual(weights, want) {
+			t.Errorf("%+v factors = %+v, want weights %v in order", signals, got.Factors, want)
+		}
+		if again := ScoreConfidence(signals); !reflect.DeepEqual(again, got) {
+			t.Errorf("%+v scored %+v, then %+v", signals, got, again)
+		}
+	}
+}
+
+func TestCompareStatic(t *testing.T) {
+	tests := []struct {
+		verdict  string
+		problems int
+		disagree bool
+	}{
+		{"Good", 0, false},
+		{"Good", 2, true},
+		{"good with caveats", 1, true},
+		{"Poor", 0, true},
+		{"Poor", 3, false},
+		{"Needs Improvement", 0, false},
+		{"Needs Improvement", 5, false},
+	}
+	for _, tt := range tests {
+		e := &Evaluation{Verdict: tt.verdict}
+		e.CompareStatic(tt.problems)
+		if e.ConfidenceSignals.StaticDisagreement != tt.disagree {
+			t.Errorf("%s with %d problems: disagreement = %v, want %v", tt.verdict, tt.problems, e.ConfidenceSignals.StaticDisagreement, tt.disagree)
+		}
+		if want := ScoreConfidence(e.ConfidenceSignals); !reflect.DeepEqual(e.Confidence, want) {
+			t.Errorf("%s with %d problems: confidence = %+v, want it rescored to %+v", tt.verdict, tt.problems, e.Confidence, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// ABOUTME: Label opinion - a one-paragraph LLM take on a single label checked without a metric
+// ABOUTME: Kept to plain prose so the quick label check stays short
+
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LabelOpinion asks the routed model for one paragraph on whether label, with
+// values distinct values such as samples, belongs on a metric. findings are the
+// static findings' messages and verdict the static verdict, so the model can
+// confirm or challenge them; instructions are a naming profile's. Cancelling
+// ctx abandons the backend call.
+func (c *Client) LabelOpinion(ctx context.Context, label string, values int, samples, findings []string, verdict, instructions string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("You are a Prometheus metrics expert. A developer wants to add one label to a metric and asks if it is a good idea.\n\n")
+	if instructions != "" {
+		sb.WriteString(instructions + "\n\n")
+	}
+	fmt.Fprintf(&sb, "LABEL: %s\nDISTINCT VALUES: %d\n", label, values)
+	if len(samples) > 0 {
+		fmt.Fprintf(&sb, "SAMPLE VALUES: %s\n", strings.Join(samples, ", "))
+	}
+	fmt.Fprintf(&sb, "STATIC VERDICT: %s\n", verdict)
+	for _, f := range findings {
+		fmt.Fprintf(&sb, "STATIC FINDING: %s\n", f)
+	}
+	sb.WriteString("\nAnswer in one short paragraph of plain prose, without headings or lists: is the label bounded, " +
+		"what it costs as targets scale, and what to use instead if it should not be a label.")
+
+	prompt := sb.String()
+	redactor, redactPrompt := c.redaction()
+	if redactPrompt {
+		prompt = redactor.Redact(prompt)
+	}
+	log.Printf("[LLM] Built label prompt (%d chars):\n%s\n---END PROMPT---", len(prompt), redactor.Redact(prompt))
+
+	resp, err := c.generate(ctx, c.Model(), prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Response), nil
+}
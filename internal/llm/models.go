@@ -0,0 +1,108 @@
+// ABOUTME: Model listing - summarizes Ollama's installed models with capability hints for a model picker
+// ABOUTME: Context window and JSON mode support are guessed from the model family in the name
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// ModelInfo is one installed model as offered for selection
+type ModelInfo struct {
+	Name   string  `json:"name"`
+	SizeGB float64 `json:"size_gb"`
+	// Tokens the model family is trained for; Ollama itself defaults to fewer
+	// unless num_ctx is raised
+	ContextWindow int `json:"context_window"`
+	// Follows Ollama's format=json reliably
+	JSONMode bool `json:"json_mode"`
+}
+
+// modelFamily holds the hints for models whose name starts with prefix
+type modelFamily struct {
+	prefix        string
+	contextWindow int
+	jsonMode      bool
+}
+
+// Longer prefixes first, so llama3.1 isn't taken for llama3
+var modelFamilies = []modelFamily{
+	{"llama3.1", 131072, true},
+	{"llama3.2", 131072, true},
+	{"llama3.3", 131072, true},
+	{"llama3", 8192, true},
+	{"llama2", 4096, false},
+	{"codellama", 16384, false},
+	{"mixtral", 32768, true},
+	{"mistral", 32768, true},
+	{"qwen2.5", 32768, true},
+	{"qwen", 32768, true},
+	{"gemma2", 8192, true},
+	{"gemma", 8192, false},
+	{"phi3", 4096, true},
+	{"phi", 2048, false},
+	{"deepseek", 16384, true},
+}
+
+// Ollama's own default context for a model it knows nothing about
+const defaultContextWindow = 2048
+
+// Models lists the models offered for selection from the backend's /models
+// endpoint, or from Ollama's tags when the client talks to Ollama directly
+func (c *Client) Models(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var body struct {
+			Models []ModelInfo `json:"models"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("failed to decode models: %w", err)
+		}
+		return body.Models, nil
+	case http.StatusNotFound:
+		tags, err := c.Tags(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tags.ModelInfos(), nil
+	default:
+		return nil, fmt.Errorf("models endpoint returned status %d", resp.StatusCode)
+	}
+}
+
+// ModelInfos lists the installed models with their size in GB and capability hints
+func (t *TagsResponse) ModelInfos() []ModelInfo {
+	infos := make([]ModelInfo, 0, len(t.Models))
+	for _, m := range t.Models {
+		info := ModelInfo{
+			Name:          m.Name,
+			SizeGB:        math.Round(float64(m.Size)/1e8) / 10,
+			ContextWindow: defaultContextWindow,
+		}
+		// Namespaced models such as library/llama3 or user/mistral-tuned
+		name := strings.ToLower(m.Name[strings.LastIndex(m.Name, "/")+1:])
+		for _, f := range modelFamilies {
+			if strings.HasPrefix(name, f.prefix) {
+				info.ContextWindow, info.JSONMode = f.contextWindow, f.jsonMode
+				break
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
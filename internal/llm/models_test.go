@@ -0,0 +1,101 @@
+// ABOUTME: Tests for model listing - sizes in GB and capability hints guessed from the model family
+// ABOUTME: Models reads the backend's /models and falls back to Ollama's tags when the endpoint is missing
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestModelInfos(t *testing.T) {
+	tags := &TagsResponse{Models: []OllamaModel{
+		{Name: "llama2:latest", Size: 3_825_819_519},
+		{Name: "llama3.1:8b", Size: 4_920_753_328},
+		{Name: "llama3:8b", Size: 4_661_224_676},
+		{Name: "Mistral:7b", Size: 4_109_865_159},
+		{Name: "library/gemma2:9b", Size: 5_443_152_417},
+		{Name: "someone/phi:2.7b", Size: 1_602_463_378},
+		{Name: "tinymodel", Size: 0},
+	}}
+	want := []ModelInfo{
+		{Name: "llama2:latest", SizeGB: 3.8, ContextWindow: 4096},
+		{Name: "llama3.1:8b", SizeGB: 4.9, ContextWindow: 131072, JSONMode: true},
+		{Name: "llama3:8b", SizeGB: 4.7, ContextWindow: 8192, JSONMode: true},
+		{Name: "Mistral:7b", SizeGB: 4.1, ContextWindow: 32768, JSONMode: true},
+		{Name: "library/gemma2:9b", SizeGB: 5.4, ContextWindow: 8192, JSONMode: true},
+		{Name: "someone/phi:2.7b", SizeGB: 1.6, ContextWindow: 2048},
+		{Name: "tinymodel", SizeGB: 0, ContextWindow: defaultContextWindow},
+	}
+	if got := tags.ModelInfos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ModelInfos() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := (&TagsResponse{}).ModelInfos(); got == nil || len(got) != 0 {
+		t.Errorf("ModelInfos() with no models = %#v, want an empty list", got)
+	}
+}
+
+func TestModels(t *testing.T) {
+	tags := `{"models":[{"name":"mistral:7b","size":4109865159,"digest":"f974a74358d6","modified_at":"2024-05-01T10:00:00Z"}]}`
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    []ModelInfo
+		wantErr bool
+	}{
+		{
+			name: "backend models endpoint",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/models" {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string][]ModelInfo{"models": {{Name: "llama2", SizeGB: 3.8, ContextWindow: 4096}}})
+			},
+			want: []ModelInfo{{Name: "llama2", SizeGB: 3.8, ContextWindow: 4096}},
+		},
+		{
+			name: "ollama without the endpoint",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/tags" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(tags))
+			},
+			want: []ModelInfo{{Name: "mistral:7b", SizeGB: 4.1, ContextWindow: 32768, JSONMode: true}},
+		},
+		{
+			name: "backend error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantErr: true,
+		},
+		{
+			name: "undecodable models",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("not json"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			got, err := NewClient(server.URL, "").Models(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Models() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Models() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
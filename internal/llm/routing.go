@@ -0,0 +1,80 @@
+// ABOUTME: Model routing - sends small submissions to a fast model and large or risky ones to the thorough model
+// ABOUTME: Decides from metric count, estimated prompt tokens and critical cardinality
+
+package llm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cost"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Routing chooses between FastModel and the client's model, which serves as the
+// thorough one. An empty FastModel sends everything to the thorough model.
+type Routing struct {
+	FastModel string
+	// Submissions over either limit go to the thorough model
+	FastMaxMetrics      int
+	FastMaxPromptTokens int
+	// Models a request may name explicitly, besides the fast and thorough ones
+	AllowedModels []string
+}
+
+// DefaultRouting leaves routing off until a fast model is configured
+var DefaultRouting = Routing{
+	FastMaxMetrics:      5,
+	FastMaxPromptTokens: 2500,
+}
+
+// route returns the model for a submission and why it was chosen
+func (r Routing) route(thorough string, parsed *metrics.ParsedMetrics, prompt string) (string, string) {
+	if r.FastModel == "" {
+		return thorough, "no fast model configured"
+	}
+	if ca := parsed.CardinalityAnalysis; ca != nil && strings.HasPrefix(ca.CardinalityLevel, "CRITICAL") {
+		return thorough, "critical cardinality detected"
+	}
+	if n := len(parsed.Metrics); n > r.FastMaxMetrics {
+		return thorough, fmt.Sprintf("%d metrics exceed the fast limit of %d", n, r.FastMaxMetrics)
+	}
+	if tokens := cost.EstimateTokens(prompt); tokens > r.FastMaxPromptTokens {
+		return thorough, fmt.Sprintf("~%d prompt tokens exceed the fast limit of %d", tokens, r.FastMaxPromptTokens)
+	}
+	return r.FastModel, "small submission"
+}
+
+// Routing returns the current routing rules
+func (c *Client) Routing() Routing {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.routing
+}
+
+// SetRouting changes the routing rules for subsequent evaluations, e.g. on config reload
+func (c *Client) SetRouting(r Routing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routing = r
+}
+
+// AllowedModels lists the models a request may ask for by name
+func (c *Client) AllowedModels() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	allowed := []string{c.model}
+	for _, m := range append([]string{c.routing.FastModel}, c.routing.AllowedModels...) {
+		if m != "" && !slices.Contains(allowed, m) {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// AllowsModel reports whether a request may ask for model by name
+func (c *Client) AllowsModel(model string) bool {
+	return slices.Contains(c.AllowedModels(), model)
+}
@@ -0,0 +1,134 @@
+// ABOUTME: Tests for model routing - each branch between the fast and thorough models, and the per-request override
+// ABOUTME: The chosen model is the one called, recorded on the evaluation and part of the cache key
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestRoute(t *testing.T) {
+	routing := Routing{FastModel: "fast", FastMaxMetrics: 2, FastMaxPromptTokens: 10}
+	small := &metrics.ParsedMetrics{Metrics: []metrics.Metric{{Name: "up"}}}
+
+	tests := []struct {
+		name    string
+		routing Routing
+		parsed  *metrics.ParsedMetrics
+		prompt  string
+		want    string
+		reason  string
+	}{
+		{"no fast model", Routing{FastMaxMetrics: 2, FastMaxPromptTokens: 10}, small, "short", "thorough", "no fast model configured"},
+		{"small submission", routing, small, "short", "fast", "small submission"},
+		{"at the limits", routing, &metrics.ParsedMetrics{Metrics: make([]metrics.Metric, 2)}, strings.Repeat("x", 40), "fast", "small submission"},
+		{"too many metrics", routing, &metrics.ParsedMetrics{Metrics: make([]metrics.Metric, 3)}, "short", "thorough", "3 metrics exceed the fast limit of 2"},
+		{"long prompt", routing, small, strings.Repeat("x", 41), "thorough", "~11 prompt tokens exceed the fast limit of 10"},
+		{
+			"critical cardinality", routing,
+			&metrics.ParsedMetrics{Metrics: small.Metrics, CardinalityAnalysis: &cardinality.Analysis{CardinalityLevel: "CRITICAL - Potentially Unbounded"}},
+			"short", "thorough", "critical cardinality detected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, reason := tt.routing.route("thorough", tt.parsed, tt.prompt)
+			if model != tt.want || reason != tt.reason {
+				t.Errorf("route = %q (%s), want %q (%s)", model, reason, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAllowedModels(t *testing.T) {
+	c := NewClient("http://127.0.0.1:1", "thorough")
+	c.SetRouting(Routing{FastModel: "fast", AllowedModels: []string{"extra", "thorough", ""}})
+	if got := c.AllowedModels(); !slices.Equal(got, []string{"thorough", "fast", "extra"}) {
+		t.Errorf("AllowedModels = %v", got)
+	}
+	for model, want := range map[string]bool{"thorough": true, "fast": true, "extra": true, "other": false, "": false} {
+		if got := c.AllowsModel(model); got != want {
+			t.Errorf("AllowsModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestEvaluateUsesTheChosenModel(t *testing.T) {
+	var mu sync.Mutex
+	var called []string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		called = append(called, req.Model)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"response": "VERDICT: Good\nSCORE: 90", "done": true, "prompt_eval_count": 300, "eval_count": 50})
+	}))
+	defer ollama.Close()
+
+	cache, err := OpenBoltCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	client := NewClient(ollama.URL, "thorough")
+	client.SetCache(cache)
+	client.SetRouting(Routing{FastModel: "fast", FastMaxMetrics: 1, FastMaxPromptTokens: 1_000_000})
+
+	small, err := metrics.Parse("http_requests_total 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := metrics.Parse("http_requests_total 1\nhttp_errors_total 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		parsed    *metrics.ParsedMetrics
+		requested string
+		want      string
+		cached    bool
+	}{
+		{"routed to fast", small, "", "fast", false},
+		{"routed to thorough", large, "", "thorough", false},
+		// The cached fast evaluation of the same prompt isn't served for another model
+		{"override", small, "thorough", "thorough", false},
+		{"same prompt and model again", small, "", "fast", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			before := len(called)
+			mu.Unlock()
+			evaluation, err := client.Evaluate(context.Background(), tt.parsed, "", "engine", tt.requested)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if evaluation.Model != tt.want || evaluation.Cached != tt.cached {
+				t.Errorf("evaluation model %q cached %v, want %q cached %v", evaluation.Model, evaluation.Cached, tt.want, tt.cached)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.cached {
+				if len(called) != before {
+					t.Errorf("a cached evaluation called %v", called[before:])
+				}
+			} else if len(called) != before+1 || called[before] != tt.want {
+				t.Errorf("called %v, want %s", called[before:], tt.want)
+			}
+		})
+	}
+}
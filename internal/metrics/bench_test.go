@@ -0,0 +1,165 @@
+// ABOUTME: Parser and calculator benchmarks on synthetic scrapes, with a check against stored baselines
+// ABOUTME: Run with make bench; make bench-check fails when a benchmark regresses past the tolerance
+
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+)
+
+const baselinePath = "testdata/bench_baseline.json"
+
+// Slowdowns and allocation growth past these fractions of the baseline fail
+// the baseline check. Time varies more between runs than allocations do.
+const (
+	timeTolerance   = 0.25
+	allocsTolerance = 0.05
+)
+
+var benchmarks = map[string]func(*testing.B){
+	"BenchmarkParseLargeScrape":  BenchmarkParseLargeScrape,
+	"BenchmarkAnalyze100kSeries": BenchmarkAnalyze100kSeries,
+	"BenchmarkCanonicalHash":     BenchmarkCanonicalHash,
+}
+
+func BenchmarkParseLargeScrape(b *testing.B) {
+	input := SyntheticScrape(10_000, 10, 3)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Parse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAnalyze100kSeries(b *testing.B) {
+	parsed := benchParse(b, SyntheticScrape(100_000, 10, 3))
+	b.ReportAllocs()
+	for b.Loop() {
+		parsed.ReanalyzeWithOptions(cardinality.Options{Thresholds: cardinality.DefaultThresholds})
+	}
+}
+
+func BenchmarkCanonicalHash(b *testing.B) {
+	parsed := benchParse(b, SyntheticScrape(10_000, 10, 3))
+	b.ReportAllocs()
+	for b.Loop() {
+		sha256.Sum256([]byte(DefaultLabelOrder.Canonical(parsed)))
+	}
+}
+
+func benchParse(b *testing.B, input string) *ParsedMetrics {
+	b.Helper()
+	parsed, err := Parse(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return parsed
+}
+
+// benchResult is one benchmark's stored baseline
+type benchResult struct {
+	NsPerOp     int64 `json:"ns_per_op"`
+	AllocsPerOp int64 `json:"allocs_per_op"`
+	BytesPerOp  int64 `json:"bytes_per_op"`
+}
+
+// TestBenchmarkBaseline runs the benchmarks and compares them with the stored
+// baseline when BENCH_BASELINE=check, or rewrites the baseline when
+// BENCH_BASELINE=update. Timings depend on the machine, so the baseline is
+// only meaningful on the machine that recorded it.
+func TestBenchmarkBaseline(t *testing.T) {
+	mode := os.Getenv("BENCH_BASELINE")
+	if mode == "" {
+		t.Skip("set BENCH_BASELINE=check or BENCH_BASELINE=update to run the benchmarks")
+	}
+
+	results := make(map[string]benchResult)
+	for _, name := range slices.Sorted(maps.Keys(benchmarks)) {
+		r := testing.Benchmark(benchmarks[name])
+		results[name] = benchResult{NsPerOp: r.NsPerOp(), AllocsPerOp: r.AllocsPerOp(), BytesPerOp: r.AllocedBytesPerOp()}
+		t.Logf("%s: %d ns/op, %d allocs/op, %d B/op", name, r.NsPerOp(), r.AllocsPerOp(), r.AllocedBytesPerOp())
+	}
+
+	switch mode {
+	case "update":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(baselinePath, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	case "check":
+		data, err := os.ReadFile(baselinePath)
+		if err != nil {
+			t.Fatalf("reading the baseline (record one with make bench-baseline): %v", err)
+		}
+		var baseline map[string]benchResult
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			t.Fatal(err)
+		}
+		for name, got := range results {
+			want, ok := baseline[name]
+			if !ok {
+				t.Errorf("%s has no baseline; record one with make bench-baseline", name)
+				continue
+			}
+			for _, msg := range compareToBaseline(got, want) {
+				t.Errorf("%s: %s", name, msg)
+			}
+		}
+	default:
+		t.Fatalf("BENCH_BASELINE=%q; use check or update", mode)
+	}
+}
+
+// compareToBaseline describes each way got regressed from want past the tolerances
+func compareToBaseline(got, want benchResult) []string {
+	var regressions []string
+	if exceeds(got.NsPerOp, want.NsPerOp, timeTolerance) {
+		regressions = append(regressions, fmt.Sprintf("%d ns/op is over %.0f%% slower than the baseline's %d", got.NsPerOp, timeTolerance*100, want.NsPerOp))
+	}
+	if exceeds(got.AllocsPerOp, want.AllocsPerOp, allocsTolerance) {
+		regressions = append(regressions, fmt.Sprintf("%d allocs/op is over %.0f%% above the baseline's %d", got.AllocsPerOp, allocsTolerance*100, want.AllocsPerOp))
+	}
+	if exceeds(got.BytesPerOp, want.BytesPerOp, allocsTolerance) {
+		regressions = append(regressions, fmt.Sprintf("%d B/op is over %.0f%% above the baseline's %d", got.BytesPerOp, allocsTolerance*100, want.BytesPerOp))
+	}
+	return regressions
+}
+
+func exceeds(got, baseline int64, tolerance float64) bool {
+	return float64(got) > float64(baseline)*(1+tolerance)
+}
+
+func TestCompareToBaseline(t *testing.T) {
+	base := benchResult{NsPerOp: 1000, AllocsPerOp: 100, BytesPerOp: 10_000}
+	tests := []struct {
+		name string
+		got  benchResult
+		want int
+	}{
+		{"equal", base, 0},
+		{"faster and leaner", benchResult{NsPerOp: 500, AllocsPerOp: 50, BytesPerOp: 5000}, 0},
+		{"slower within tolerance", benchResult{NsPerOp: 1250, AllocsPerOp: 100, BytesPerOp: 10_000}, 0},
+		{"slower past tolerance", benchResult{NsPerOp: 1251, AllocsPerOp: 100, BytesPerOp: 10_000}, 1},
+		{"more allocations past tolerance", benchResult{NsPerOp: 1000, AllocsPerOp: 106, BytesPerOp: 10_501}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareToBaseline(tt.got, base); len(got) != tt.want {
+				t.Errorf("compareToBaseline = %q, want %d regression(s)", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// ABOUTME: OpenMetrics _created companion series - when a counter, histogram or summary started counting
+// ABOUTME: Folds them into the family's samples so they aren't evaluated as metrics of their own
+
+package metrics
+
+import (
+	"maps"
+	"strings"
+)
+
+// attachCreated removes foo_created samples whose family (foo_total, foo_bucket,
+// foo_count or foo_sum) is also present, recording the timestamp on the family's
+// samples with the same labels. A _created sample with no family in the
+// submission is kept, since it may be a gauge such as kube_pod_created.
+func attachCreated(ms []Metric) []Metric {
+	byName := make(map[string][]int)
+	for i, m := range ms {
+		byName[m.Name] = append(byName[m.Name], i)
+	}
+
+	companion := make([]bool, len(ms))
+	for j, m := range ms {
+		base, ok := strings.CutSuffix(m.Name, "_created")
+		if !ok || base == "" {
+			continue
+		}
+
+		// Both foo_total_created and the OpenMetrics foo_created name a counter's companion
+		var family []int
+		for _, name := range []string{base, base + "_total", base + "_bucket", base + "_count", base + "_sum"} {
+			family = append(family, byName[name]...)
+		}
+		if len(family) == 0 {
+			continue
+		}
+
+		companion[j] = true
+		for _, i := range family {
+			if sameSeries(ms[i].Labels, m.Labels) {
+				ms[i].CreatedTimestamp = m.FloatValue
+			}
+		}
+	}
+
+	kept := make([]Metric, 0, len(ms))
+	for j, m := range ms {
+		if !companion[j] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// sameSeries compares label sets, ignoring the le and quantile labels that
+// _created samples don't carry
+func sameSeries(sample, created map[string]string) bool {
+	labels := maps.Clone(sample)
+	delete(labels, "le")
+	delete(labels, "quantile")
+	return maps.Equal(labels, created)
+}
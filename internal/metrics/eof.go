@@ -0,0 +1,38 @@
+// ABOUTME: OpenMetrics # EOF terminator check - the spec requires every exposition to end with it
+// ABOUTME: A missing terminator means a tool stripped it or the exposition was cut short
+
+package metrics
+
+import (
+	"strings"
+)
+
+const openMetricsEOF = "# EOF"
+
+// LooksLikeOpenMetrics reports markers only OpenMetrics exposition has: a # EOF
+// or # UNIT line, or an exemplar after a sample
+func LooksLikeOpenMetrics(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == openMetricsEOF || strings.HasPrefix(line, "# UNIT ") ||
+			(!strings.HasPrefix(line, "#") && strings.Contains(line, " # {")) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckEOF describes what is wrong with the # EOF terminator of OpenMetrics
+// input, or returns "" when the input ends with it
+func CheckEOF(input string) string {
+	lines := strings.Split(strings.TrimRight(input, " \t\r\n"), "\n")
+	if strings.TrimSpace(lines[len(lines)-1]) == openMetricsEOF {
+		return ""
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == openMetricsEOF {
+			return "content follows # EOF; OpenMetrics exposition must end there"
+		}
+	}
+	return "the input does not end with # EOF, which OpenMetrics requires; a tool may have stripped it or the exposition was cut short"
+}
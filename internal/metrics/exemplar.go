@@ -0,0 +1,56 @@
+// ABOUTME: OpenMetrics exemplar parsing - the # {trace_id="..."} 0.57 suffix a sample can carry
+// ABOUTME: Exemplar labels are stored apart from the series, so they are kept separate from Labels
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matches: {label1="value1"} value [timestamp]
+var exemplarRegex = regexp.MustCompile(`^\{([^}]*)\}\s+(\S+)(?:\s+(\S+))?$`)
+
+// splitExemplar cuts a sample line at the " # " that starts its exemplar,
+// ignoring any inside quoted label values. exemplar is "" when there is none.
+func splitExemplar(line string) (sample, exemplar string) {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case '#':
+			if !inQuotes && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t') {
+				return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return line, ""
+}
+
+// parseExemplar reads an exemplar's label set, checking its value and
+// timestamp are numbers
+func parseExemplar(exemplar string) (map[string]string, error) {
+	matches := exemplarRegex.FindStringSubmatch(exemplar)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid exemplar: # %s", exemplar)
+	}
+	labels, err := parseLabels(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid exemplar: %w", err)
+	}
+	if _, err := parseValue(matches[2]); err != nil {
+		return nil, fmt.Errorf("invalid exemplar: %w", err)
+	}
+	if matches[3] != "" {
+		if _, err := parseValue(matches[3]); err != nil {
+			return nil, fmt.Errorf("invalid exemplar timestamp %q", matches[3])
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,69 @@
+// ABOUTME: Tests for OpenMetrics exemplar parsing - the labels after " # " are kept apart from the series labels
+// ABOUTME: A # inside a quoted label value isn't an exemplar, and malformed exemplars fail the line
+
+package metrics
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseLineExemplars(t *testing.T) {
+	tests := []struct {
+		line      string
+		labels    map[string]string
+		value     string
+		exemplar  map[string]string
+		timestamp string
+	}{
+		{`http_request_duration_seconds_bucket{le="1.0"} 50 # {trace_id="EpTxM"} 0.573 1571096`,
+			map[string]string{"le": "1.0"}, "50", map[string]string{"trace_id": "EpTxM"}, ""},
+		{`http_requests_total{method="GET"} 7 # {trace_id="a",span_id="b"} 1`,
+			map[string]string{"method": "GET"}, "7", map[string]string{"trace_id": "a", "span_id": "b"}, ""},
+		{`http_requests_total 7 1700000000 # {trace_id="a"} 1`,
+			map[string]string{}, "7", map[string]string{"trace_id": "a"}, "1700000000"},
+		{`http_requests_total 7 # {} 1`, map[string]string{}, "7", map[string]string{}, ""},
+		{`http_requests_total{path="/a#b"} 7`, map[string]string{"path": "/a#b"}, "7", nil, ""},
+		// Label values are kept as written, escapes included
+		{`http_requests_total{path="a \" # b"} 7`, map[string]string{"path": `a \" # b`}, "7", nil, ""},
+		{`up 1`, map[string]string{}, "1", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			m, err := ParseLine(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(m.Labels, tt.labels) || m.Value != tt.value || m.Timestamp != tt.timestamp {
+				t.Errorf("sample = %v %q %q, want %v %q %q", m.Labels, m.Value, m.Timestamp, tt.labels, tt.value, tt.timestamp)
+			}
+			if (m.ExemplarLabels == nil) != (tt.exemplar == nil) || !maps.Equal(m.ExemplarLabels, tt.exemplar) {
+				t.Errorf("ExemplarLabels = %v, want %v", m.ExemplarLabels, tt.exemplar)
+			}
+			if m.Raw != tt.line {
+				t.Errorf("Raw = %q, want the whole line", m.Raw)
+			}
+		})
+	}
+}
+
+func TestParseLineRejectsMalformedExemplars(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{`up 1 # trace_id="a" 1`, "invalid exemplar"},
+		{`up 1 # {trace_id="a"}`, "invalid exemplar"},
+		{`up 1 # {trace_id} 1`, "invalid exemplar"},
+		{`up 1 # {trace_id="a"} fast`, "invalid exemplar"},
+		{`up 1 # {trace_id="a"} 1 yesterday`, `invalid exemplar timestamp "yesterday"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := ParseLine(tt.line)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// ABOUTME: Histogram structure - groups _bucket samples into series and checks their le buckets
+// ABOUTME: A missing +Inf bucket, out-of-order or negative bounds and falling counts all break histogram_quantile
+
+package metrics
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BucketViolation names an invariant a histogram bucket breaks
+type BucketViolation string
+
+const (
+	// The le value isn't a number
+	BucketInvalidLE BucketViolation = "invalid-le"
+	// The bound is not above the bound of the bucket exposed before it
+	BucketUnsorted BucketViolation = "unsorted"
+	// The count is below the count of a bucket with a lower bound
+	BucketNonCumulative BucketViolation = "non-cumulative"
+	// The bound is negative though the family's unit can't be
+	BucketNegative BucketViolation = "negative"
+)
+
+// Units whose measurements are never negative, so neither are their bucket bounds
+var nonNegativeUnits = []string{"_seconds", "_bytes", "_meters", "_grams", "_joules", "_size", "_length"}
+
+// HistogramBucket is one le bucket, in exposition order
+type HistogramBucket struct {
+	LE    string
+	Bound float64
+	Count float64
+	// Invariants this bucket breaks; empty when it is sound
+	Violations []BucketViolation
+}
+
+// HistogramSeries is the buckets of one histogram label set
+type HistogramSeries struct {
+	Family string
+	// The series' labels other than le
+	Labels  map[string]string
+	Buckets []HistogramBucket
+	// No le="+Inf" bucket, so histogram_quantile has no total to rank against
+	MissingInf bool
+}
+
+// String names the series by its family and labels, as in http_request_duration_seconds{method="GET"}
+func (s HistogramSeries) String() string {
+	return strings.TrimSuffix(FormatSample(Metric{Name: s.Family, Labels: s.Labels}), " ")
+}
+
+// Valid reports whether the series breaks no invariant
+func (s HistogramSeries) Valid() bool {
+	if s.MissingInf {
+		return false
+	}
+	for _, b := range s.Buckets {
+		if len(b.Violations) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Histograms groups the submission's _bucket samples by family and label set,
+// in submission order, and checks each series' buckets: le must parse, rise
+// with every bucket, end at +Inf and stay at or above zero for units that
+// can't be negative, and counts must not fall as le rises
+func (p *ParsedMetrics) Histograms() []HistogramSeries {
+	var series []HistogramSeries
+	index := make(map[string]int)
+	for _, m := range p.Metrics {
+		le, ok := m.Labels["le"]
+		if !ok || m.Matchers != nil || !strings.HasSuffix(m.Name, "_bucket") {
+			continue
+		}
+		family, _, declared := p.DeclaredFamily(m.Name)
+		if !declared {
+			family = strings.TrimSuffix(m.Name, "_bucket")
+		}
+		labels := maps.Clone(m.Labels)
+		delete(labels, "le")
+		key := HistogramSeries{Family: family, Labels: labels}.String()
+		i, ok := index[key]
+		if !ok {
+			i = len(series)
+			index[key] = i
+			series = append(series, HistogramSeries{Family: family, Labels: labels})
+		}
+		series[i].Buckets = append(series[i].Buckets, HistogramBucket{LE: le, Count: m.FloatValue})
+	}
+
+	for i := range series {
+		checkBuckets(&series[i])
+	}
+	return series
+}
+
+func checkBuckets(s *HistogramSeries) {
+	nonNegative := slices.ContainsFunc(nonNegativeUnits, func(unit string) bool {
+		return strings.HasSuffix(s.Family, unit)
+	})
+
+	s.MissingInf = true
+	var valid []int
+	for i := range s.Buckets {
+		b := &s.Buckets[i]
+		bound, err := strconv.ParseFloat(b.LE, 64)
+		if err != nil || math.IsNaN(bound) {
+			b.Violations = append(b.Violations, BucketInvalidLE)
+			continue
+		}
+		b.Bound = bound
+		if math.IsInf(bound, 1) {
+			s.MissingInf = false
+		}
+		if len(valid) > 0 && bound <= s.Buckets[valid[len(valid)-1]].Bound {
+			b.Violations = append(b.Violations, BucketUnsorted)
+		}
+		if bound < 0 && nonNegative {
+			b.Violations = append(b.Violations, BucketNegative)
+		}
+		valid = append(valid, i)
+	}
+
+	// Counts are compared in le order, so an unsorted exposition isn't also
+	// reported as non-cumulative
+	slices.SortStableFunc(valid, func(a, b int) int {
+		return cmp.Compare(s.Buckets[a].Bound, s.Buckets[b].Bound)
+	})
+	highest := math.Inf(-1)
+	for _, i := range valid {
+		b := &s.Buckets[i]
+		if b.Count < highest {
+			b.Violations = append(b.Violations, BucketNonCumulative)
+		}
+		highest = max(highest, b.Count)
+	}
+}
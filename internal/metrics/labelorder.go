@@ -0,0 +1,126 @@
+// ABOUTME: Label ordering - the order labels are written in: topology first, then request dimensions
+// ABOUTME: Prometheus ignores label order, but one order across a codebase keeps exposition and diffs readable
+
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LabelOrder writes the labels of Groups first, group by group, then labels
+// in no group, then those in Last. Labels are alphabetical within each.
+type LabelOrder struct {
+	Groups [][]string `yaml:"groups"`
+	Last   []string   `yaml:"last"`
+}
+
+// DefaultLabelOrder puts where a series comes from before what it measures,
+// and histogram and summary labels at the end, as client libraries write them
+var DefaultLabelOrder = LabelOrder{
+	Groups: [][]string{
+		// Topology
+		{"cluster", "container", "datacenter", "env", "environment", "host", "instance", "job", "namespace", "node", "pod", "region", "service", "zone"},
+		// Request dimensions
+		{"code", "endpoint", "grpc_code", "grpc_method", "grpc_service", "handler", "method", "operation", "path", "route", "status", "status_code"},
+	},
+	Last: []string{"le", "quantile"},
+}
+
+// rank is the position of name's group: its index in Groups, then one for
+// labels in no group, then one for Last
+func (o LabelOrder) rank(name string) int {
+	for i, group := range o.Groups {
+		if slices.Contains(group, name) {
+			return i
+		}
+	}
+	if slices.Contains(o.Last, name) {
+		return len(o.Groups) + 1
+	}
+	return len(o.Groups)
+}
+
+// Sort puts names in the order's order
+func (o LabelOrder) Sort(names []string) {
+	slices.SortFunc(names, func(a, b string) int {
+		if ra, rb := o.rank(a), o.rank(b); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// FormatSample renders a metric as a single exposition line with its labels in order
+func (o LabelOrder) FormatSample(m Metric) string {
+	var sb strings.Builder
+	sb.WriteString(m.Name)
+
+	if len(m.Labels) > 0 {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		o.Sort(keys)
+
+		sb.WriteString("{")
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(fmt.Sprintf(`%s="%s"`, k, labelValueEscaper.Replace(m.Labels[k])))
+		}
+		sb.WriteString("}")
+	}
+
+	sb.WriteString(" ")
+	sb.WriteString(m.Value)
+	return sb.String()
+}
+
+// Canonical rewrites parsed as exposition text with every sample's labels in
+// order, keeping # HELP and # TYPE lines, timestamps and exemplars. Other
+// comments are dropped.
+func (o LabelOrder) Canonical(parsed *ParsedMetrics) string {
+	var sb strings.Builder
+	described := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		family := familyName(m.Name, parsed.Types)
+		if !described[family] {
+			described[family] = true
+			if h, ok := parsed.Help[family]; ok {
+				fmt.Fprintf(&sb, "# HELP %s %s\n", family, helpEscaper.Replace(h))
+			}
+			if t, ok := parsed.Types[family]; ok {
+				fmt.Fprintf(&sb, "# TYPE %s %s\n", family, t)
+			}
+		}
+
+		sb.WriteString(o.FormatSample(m))
+		if m.Timestamp != "" {
+			sb.WriteString(" " + m.Timestamp)
+		}
+		if _, exemplar := splitExemplar(m.Raw); exemplar != "" {
+			sb.WriteString(" # " + exemplar)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// WrittenLabelNames lists m's label names in the order its line wrote them,
+// or nil when m has no labels or wasn't parsed from a sample line
+func WrittenLabelNames(m Metric) []string {
+	sample, _ := splitExemplar(m.Raw)
+	matches := metricWithLabelsRegex.FindStringSubmatch(sample)
+	if matches == nil {
+		return nil
+	}
+	var names []string
+	for _, pair := range splitLabels(matches[2]) {
+		name, _, _ := strings.Cut(pair, "=")
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
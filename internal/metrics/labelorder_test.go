@@ -0,0 +1,79 @@
+// ABOUTME: Tests for label ordering - groups first, then labels in no group, then Last, alphabetical within each
+// ABOUTME: Ties break alphabetically, and labels an order names but a line lacks leave no gaps
+
+package metrics
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLabelOrderSort(t *testing.T) {
+	order := LabelOrder{
+		Groups: [][]string{{"region", "cluster", "pod"}, {"method", "code"}},
+		Last:   []string{"le", "quantile"},
+	}
+	tests := []struct {
+		name  string
+		order LabelOrder
+		in    []string
+		want  []string
+	}{
+		{"groups then ungrouped then last", order,
+			[]string{"le", "tenant", "code", "pod"},
+			[]string{"pod", "code", "tenant", "le"}},
+		{"ties within a group are alphabetical", order,
+			[]string{"region", "pod", "cluster"},
+			[]string{"cluster", "pod", "region"}},
+		{"ties among ungrouped labels are alphabetical", order,
+			[]string{"zeta", "alpha", "mid"},
+			[]string{"alpha", "mid", "zeta"}},
+		{"ties within last are alphabetical", order,
+			[]string{"quantile", "le"},
+			[]string{"le", "quantile"}},
+		{"a missing first group", order,
+			[]string{"le", "method", "extra"},
+			[]string{"method", "extra", "le"}},
+		{"missing groups and last", order,
+			[]string{"b", "a"},
+			[]string{"a", "b"}},
+		{"a label in two groups takes the first", LabelOrder{Groups: [][]string{{"job"}, {"job", "code"}}},
+			[]string{"code", "job"},
+			[]string{"job", "code"}},
+		{"the zero order is alphabetical", LabelOrder{},
+			[]string{"pod", "le", "code"},
+			[]string{"code", "le", "pod"}},
+		{"no labels", order, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Clone(tt.in)
+			tt.order.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Sort(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelOrderFormatSample(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Metric
+		want string
+	}{
+		{"default order", Metric{Name: "http_requests_total", Value: "1",
+			Labels: map[string]string{"status": "200", "job": "api", "le": "0.5", "tenant": "a"}},
+			`http_requests_total{job="api",status="200",tenant="a",le="0.5"} 1`},
+		{"no labels", Metric{Name: "up", Value: "1"}, "up 1"},
+		{"escaped values", Metric{Name: "up", Value: "1", Labels: map[string]string{"path": `a"b\c`}},
+			`up{path="a\"b\\c"} 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultLabelOrder.FormatSample(tt.m); got != tt.want {
+				t.Errorf("FormatSample = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// ABOUTME: Metric sets - parses related groups of metrics, such as all HTTP and all database metrics, together
+// ABOUTME: Compares label names across groups so one dimension isn't called status in one group and code in another
+
+package metrics
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MultiSetAnalysis is every group's parse with the label names the groups
+// disagree on
+type MultiSetAnalysis struct {
+	Groups           map[string]*ParsedMetrics
+	CrossGroupIssues []ConsistencyIssue
+}
+
+// ConsistencyIssue is one dimension labeled with different names in different groups
+type ConsistencyIssue struct {
+	// Each label name used for the dimension, to the groups using it
+	Labels  map[string][]string `json:"labels"`
+	Message string              `json:"message"`
+}
+
+// Label names for one dimension, on top of names that only differ in case or
+// underscores (statusCode and status_code). grpc_ labels are left to
+// themselves, as gRPC's own names are the convention there.
+var labelSynonyms = [][]string{
+	{"method", "http_method", "verb"},
+	{"status", "code", "status_code", "http_status"},
+	{"endpoint", "handler", "route", "path"},
+	{"operation", "op", "query_type"},
+	{"table", "collection"},
+	{"env", "environment"},
+	{"instance", "host", "hostname"},
+}
+
+// ParseMultipleMetricSets parses each group of metrics on its own, by group
+// name. The first group that fails to parse fails them all.
+func ParseMultipleMetricSets(groups map[string]string) (map[string]*ParsedMetrics, error) {
+	parsed := make(map[string]*ParsedMetrics, len(groups))
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		p, err := Parse(groups[name])
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", name, err)
+		}
+		parsed[name] = p
+	}
+	return parsed, nil
+}
+
+// CheckLabelConsistency finds dimensions the groups label with different
+// names, in label name order
+func CheckLabelConsistency(groups map[string]*ParsedMetrics) []ConsistencyIssue {
+	// Label name to the groups using it
+	users := make(map[string][]string)
+	for _, group := range slices.Sorted(maps.Keys(groups)) {
+		for _, m := range groups[group].Metrics {
+			for label := range m.Labels {
+				if !slices.Contains(users[label], group) {
+					users[label] = append(users[label], group)
+				}
+			}
+		}
+	}
+
+	labels := slices.Sorted(maps.Keys(users))
+	issues := []ConsistencyIssue{}
+	used := make(map[string]bool)
+	for i, label := range labels {
+		if used[label] {
+			continue
+		}
+		// Grown until no other name matches any in it, so statusCode joins
+		// status through status_code
+		same := []string{label}
+		for grown := true; grown; {
+			grown = false
+			for _, other := range labels[i+1:] {
+				if !used[other] && !slices.Contains(same, other) &&
+					slices.ContainsFunc(same, func(l string) bool { return sameDimension(l, other) }) {
+					same = append(same, other)
+					grown = true
+				}
+			}
+		}
+		slices.Sort(same)
+		if len(same) == 1 || len(groupsUsing(users, same)) < 2 {
+			continue
+		}
+		issue := ConsistencyIssue{Labels: make(map[string][]string)}
+		var described []string
+		for _, l := range same {
+			used[l] = true
+			issue.Labels[l] = users[l]
+			described = append(described, fmt.Sprintf("%s (%s)", l, strings.Join(users[l], ", ")))
+		}
+		issue.Message = fmt.Sprintf("one dimension is labeled %s; use one name in every group so queries can join and aggregate them",
+			strings.Join(described, ", "))
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// groupsUsing lists the groups using any of labels
+func groupsUsing(users map[string][]string, labels []string) []string {
+	var groups []string
+	for _, l := range labels {
+		for _, g := range users[l] {
+			if !slices.Contains(groups, g) {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// sameDimension reports whether two label names look like names for one dimension
+func sameDimension(a, b string) bool {
+	squash := func(s string) string { return strings.ReplaceAll(strings.ToLower(s), "_", "") }
+	if squash(a) == squash(b) {
+		return true
+	}
+	for _, names := range labelSynonyms {
+		if slices.Contains(names, a) && slices.Contains(names, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// ABOUTME: Input normalization - joins label sets wrapped over several lines and drops trailing # comments
+// ABOUTME: OpenMetrics exemplars ("# {...}") are kept; every change is noted for the result page
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxJoinedLines caps the lines one wrapped sample may span, so an unclosed
+// brace can't swallow the rest of the input
+const maxJoinedLines = 20
+
+// NormalizeInput rewrites hand-written samples the line-oriented parser would
+// reject. A sample whose label set is wrapped over several lines is joined
+// onto its first line, and a # comment after a sample is dropped, unless it
+// opens with "{" and so is an exemplar. A brace still unclosed after
+// maxJoinedLines lines is left alone, so its parse error points at what was
+// written. Joined lines become blank, keeping line numbers in parse errors
+// right. It returns the normalized input and a note for each change.
+func NormalizeInput(input string) (string, []string) {
+	lines := strings.Split(input, "\n")
+	var notes []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if unclosedBrace(line) {
+			joined, end := line, i
+			for end+1 < len(lines) && end-i+1 < maxJoinedLines && unclosedBrace(joined) {
+				end++
+				joined += strings.TrimSpace(lines[end])
+			}
+			if !unclosedBrace(joined) {
+				lines[i] = joined
+				for j := i + 1; j <= end; j++ {
+					lines[j] = ""
+				}
+				notes = append(notes, fmt.Sprintf("line %d: joined the label set wrapped over lines %d-%d", i+1, i+1, end+1))
+				line = joined
+			}
+		}
+
+		if sample, comment := splitExemplar(line); comment != "" && !strings.HasPrefix(comment, "{") {
+			lines[i] = sample
+			notes = append(notes, fmt.Sprintf("line %d: dropped the trailing comment \"# %s\"", i+1, comment))
+		}
+	}
+	if len(notes) == 0 {
+		return input, nil
+	}
+	return strings.Join(lines, "\n"), notes
+}
+
+// unclosedBrace reports whether line opens more braces than it closes,
+// ignoring any inside quoted label values
+func unclosedBrace(line string) bool {
+	depth, inQuotes := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case '{':
+			if !inQuotes {
+				depth++
+			}
+		case '}':
+			if !inQuotes {
+				depth--
+			}
+		}
+	}
+	return depth > 0
+}
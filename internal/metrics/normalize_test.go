@@ -0,0 +1,96 @@
+// ABOUTME: Tests for input normalization - wrapped label sets are joined and trailing comments dropped, with a note each
+// ABOUTME: Exemplars, # inside label values and braces left open past the join limit are kept as written
+
+package metrics
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		notes             []string
+	}{
+		{
+			name:  "three-line label set",
+			input: "# TYPE http_requests_total counter\nhttp_requests_total{\n  method=\"GET\",\n  status=\"200\"} 1027\nup 1",
+			want:  "# TYPE http_requests_total counter\nhttp_requests_total{method=\"GET\",status=\"200\"} 1027\n\n\nup 1",
+			notes: []string{"line 2: joined the label set wrapped over lines 2-4"},
+		},
+		{
+			name:  "trailing comment",
+			input: "http_requests_total{method=\"GET\"} 1027 # this one is for the API",
+			want:  "http_requests_total{method=\"GET\"} 1027",
+			notes: []string{`line 1: dropped the trailing comment "# this one is for the API"`},
+		},
+		{
+			name:  "wrapped with a trailing comment",
+			input: "jobs_total{queue=\"a\",\nkind=\"b\"} 3 # from the docs",
+			want:  "jobs_total{queue=\"a\",kind=\"b\"} 3\n",
+			notes: []string{
+				"line 1: joined the label set wrapped over lines 1-2",
+				`line 1: dropped the trailing comment "# from the docs"`,
+			},
+		},
+		{
+			name:  "exemplar",
+			input: `http_request_duration_seconds_bucket{le="0.5"} 129 # {trace_id="KOO5S4vxi0o"} 0.3 1520879607.789`,
+			want:  `http_request_duration_seconds_bucket{le="0.5"} 129 # {trace_id="KOO5S4vxi0o"} 0.3 1520879607.789`,
+		},
+		{
+			name:  "# and braces inside a label value",
+			input: `issues_total{title="fix #12 {soon"} 1`,
+			want:  `issues_total{title="fix #12 {soon"} 1`,
+		},
+		{
+			name:  "brace never closed",
+			input: "up{job=\"api\",\n" + strings.Repeat("x=\"1\",\n", maxJoinedLines) + "} 1",
+			want:  "up{job=\"api\",\n" + strings.Repeat("x=\"1\",\n", maxJoinedLines) + "} 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes := NormalizeInput(tt.input)
+			if got != tt.want {
+				t.Errorf("input = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(notes, tt.notes) {
+				t.Errorf("notes = %q, want %q", notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestParseNormalizesInput(t *testing.T) {
+	input := "# TYPE http_requests_total counter\n" +
+		"http_requests_total{method=\"GET\",\n" +
+		"    handler=\"/api/users\",\n" +
+		"    status=\"200\"} 1027 # this one is for the API\n"
+	parsed, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Metrics) != 1 {
+		t.Fatalf("parsed %d samples, want 1", len(parsed.Metrics))
+	}
+	m := parsed.Metrics[0]
+	if m.Labels["handler"] != "/api/users" || m.Labels["status"] != "200" || m.FloatValue != 1027 {
+		t.Errorf("sample = %+v", m)
+	}
+	if len(parsed.Normalized) != 2 {
+		t.Errorf("notes = %q, want the join and the dropped comment", parsed.Normalized)
+	}
+
+	exemplar, err := Parse(`req_seconds_bucket{le="0.5"} 129 # {trace_id="KOO5S4vxi0o"} 0.3`)
+	if err != nil || exemplar.Metrics[0].ExemplarLabels["trace_id"] != "KOO5S4vxi0o" || exemplar.Normalized != nil {
+		t.Errorf("exemplar = %+v, notes %q, %v, want it parsed and not normalized", exemplar.Metrics, exemplar.Normalized, err)
+	}
+
+	clean, err := Parse("# TYPE up gauge\nup 1\n")
+	if err != nil || clean.Normalized != nil {
+		t.Errorf("clean input: notes %q, %v", clean.Normalized, err)
+	}
+}
@@ -6,6 +6,8 @@ package metrics
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/wbollock/good_telemetry/internal/cardinality"
@@ -14,59 +16,200 @@ import (
 type Metric struct {
 	Name   string
 	Labels map[string]string
-	Value  string
-	Raw    string
+	// The sample value as written, and as a number
+	Value      string
+	FloatValue float64
+	Raw        string
+	// Unix time from the series' _created companion, 0 when there is none
+	CreatedTimestamp float64
+	// Explicit sample timestamp in milliseconds, empty when the scraper assigns one
+	Timestamp string
+	// Every matcher of a pasted PromQL selector, nil for samples
+	Matchers []LabelMatcher
+	// Labels of the sample's OpenMetrics exemplar, nil when it has none
+	ExemplarLabels map[string]string
 }
 
 type ParsedMetrics struct {
 	Metrics             []Metric
+	Help                map[string]string
+	Types               map[string]string // declared by # TYPE, keyed by family name
 	CardinalityAnalysis *cardinality.Analysis
+	// Per metric name estimates, largest memory first
+	MemoryBreakdown []MetricMemory
+	// Conventional labels the metrics lack, filled in by the naming package
+	LabelSuggestions []LabelSuggestion
+	// Naming problems caught without the LLM, such as camelCase names; filled
+	// in by the naming package
+	NamingIssues []string
+	// Problems with the input that didn't stop it parsing, such as OpenMetrics
+	// exposition missing its # EOF terminator
+	Warnings []string
+	// The input was PromQL selectors rather than samples
+	Selector bool
+	// Lines of a pasted shell transcript that CleanTranscript removed or
+	// rewrote, without their color codes
+	Cleaned []string
+	// How NormalizeInput rewrote the input, such as joining a wrapped label set
+	Normalized []string
+}
+
+// LabelSuggestion is a label a metric is usually split by but doesn't carry
+type LabelSuggestion struct {
+	Metric string
+	Label  string
+	Reason string
+}
+
+// MetricMemory is the cardinality estimate for one metric name
+type MetricMemory struct {
+	MetricName      string
+	EstimatedSeries int
+	MemoryBytes     int64
+	MemoryHuman     string
 }
 
 var (
-	// Matches: metric_name{label1="value1",label2="value2"} value (with optional value)
-	metricWithLabelsRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)\{([^}]*)\}(?:\s+([0-9.eE+-]+))?`)
-	// Matches: metric_name value (no labels, with optional value)
-	simpleMetricRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\s+([0-9.eE+-]+))?$`)
+	// Matches: metric_name{label1="value1",label2="value2"} value [timestamp] (with optional value).
+	// Dots are accepted in names so dot.separated vendor names can be judged rather than rejected.
+	// The value is taken up to the next space and checked by parseValue.
+	metricWithLabelsRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:.]*)\{([^}]*)\}(?:\s+(\S+)(?:\s+(-?[0-9]+))?)?`)
+	// Matches: metric_name value [timestamp] (no labels, with optional value)
+	simpleMetricRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:.]*)(?:\s+(\S+)(?:\s+(-?[0-9]+))?)?$`)
 )
 
 func Parse(input string) (*ParsedMetrics, error) {
+	input, cleaned := CleanTranscript(input)
+	input, normalized := NormalizeInput(input)
+	if LooksLikeSelector(input) {
+		selectors, err := ParseSelector(input)
+		if err != nil {
+			return nil, err
+		}
+		parsed := &ParsedMetrics{
+			Metrics:    selectors,
+			Help:       make(map[string]string),
+			Types:      make(map[string]string),
+			Selector:   true,
+			Cleaned:    cleaned,
+			Normalized: normalized,
+		}
+		parsed.Reanalyze(cardinality.DefaultThresholds)
+		return parsed, nil
+	}
+
 	lines := strings.Split(strings.TrimSpace(input), "\n")
 	var metrics []Metric
+	help := make(map[string]string)
+	types := make(map[string]string)
 
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			// Keep HELP text for rules that judge documentation quality, and
+			// TYPE declarations for rules that compare them with the samples
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && fields[1] == "HELP" {
+				help[fields[2]] = helpUnescaper.Replace(strings.Join(fields[3:], " "))
+			}
+			if len(fields) == 4 && fields[1] == "TYPE" {
+				types[fields[2]] = strings.ToLower(fields[3])
+			}
 			continue
 		}
 
-		metric, err := parseLine(line)
+		metric, err := ParseLine(line)
 		if err != nil {
 			return nil, fmt.Errorf("line %d: %w", i+1, err)
 		}
 		metrics = append(metrics, metric)
 	}
 
+	metrics = attachCreated(metrics)
+
 	if len(metrics) == 0 {
 		return nil, fmt.Errorf("no valid metrics found")
 	}
 
+	parsed := &ParsedMetrics{
+		Metrics:    metrics,
+		Help:       help,
+		Types:      types,
+		Cleaned:    cleaned,
+		Normalized: normalized,
+	}
+	if LooksLikeOpenMetrics(input) {
+		if warning := CheckEOF(input); warning != "" {
+			parsed.Warnings = append(parsed.Warnings, warning)
+		}
+	}
+	parsed.Reanalyze(cardinality.DefaultThresholds)
+	return parsed, nil
+}
+
+// Reanalyze recalculates the cardinality analysis against other thresholds
+func (p *ParsedMetrics) Reanalyze(t cardinality.Thresholds) {
+	p.ReanalyzeWithOptions(cardinality.Options{Thresholds: t})
+}
+
+// ReanalyzeWithOptions recalculates the cardinality analysis with other
+// thresholds and label value estimates
+func (p *ParsedMetrics) ReanalyzeWithOptions(o cardinality.Options) {
 	// Extract labels for cardinality analysis
 	var allLabels []map[string]string
-	for _, m := range metrics {
+	for _, m := range p.Metrics {
 		allLabels = append(allLabels, m.Labels)
 	}
 
 	// Calculate cardinality
-	analysis := cardinality.Analyze(allLabels)
+	p.CardinalityAnalysis = cardinality.AnalyzeWithOptions(allLabels, o)
+
+	// And again per metric name, so the largest can be singled out
+	byName := make(map[string][]map[string]string)
+	var names []string
+	for _, m := range p.Metrics {
+		if _, ok := byName[m.Name]; !ok {
+			names = append(names, m.Name)
+		}
+		byName[m.Name] = append(byName[m.Name], m.Labels)
+	}
+	p.MemoryBreakdown = make([]MetricMemory, 0, len(names))
+	for _, name := range names {
+		a := cardinality.AnalyzeWithOptions(byName[name], o)
+		p.MemoryBreakdown = append(p.MemoryBreakdown, MetricMemory{
+			MetricName:      name,
+			EstimatedSeries: a.EstimatedSeries,
+			MemoryBytes:     a.MemoryEstimateBytes,
+			MemoryHuman:     a.MemoryEstimateHuman,
+		})
+	}
+	sort.SliceStable(p.MemoryBreakdown, func(i, j int) bool {
+		return p.MemoryBreakdown[i].MemoryBytes > p.MemoryBreakdown[j].MemoryBytes
+	})
+}
 
-	return &ParsedMetrics{
-		Metrics:             metrics,
-		CardinalityAnalysis: analysis,
-	}, nil
+// ParseLine parses a single exposition sample line (no comments or blank lines).
+// The value must be a decimal float64, NaN, +Inf or -Inf: 1e-300, -0.0 and +0
+// are accepted, while 1e+e5, 1.2.3, 1_000, hex floats such as 0x1p4, inf,
+// infinity and values beyond ±1.8e308 are errors. A missing value reads as 0.
+// An OpenMetrics exemplar after the sample is parsed into ExemplarLabels.
+func ParseLine(line string) (Metric, error) {
+	sample, exemplar := splitExemplar(line)
+	metric, err := parseSample(sample)
+	if err != nil || exemplar == "" {
+		return metric, err
+	}
+	if metric.ExemplarLabels, err = parseExemplar(exemplar); err != nil {
+		return Metric{}, err
+	}
+	metric.Raw = line
+	return metric, nil
 }
 
-func parseLine(line string) (Metric, error) {
+func parseSample(line string) (Metric, error) {
 	// Try parsing with labels first
 	if matches := metricWithLabelsRegex.FindStringSubmatch(line); matches != nil {
 		labels, err := parseLabels(matches[2])
@@ -78,12 +221,18 @@ func parseLine(line string) (Metric, error) {
 		if len(matches) > 3 && matches[3] != "" {
 			value = matches[3]
 		}
+		number, err := parseValue(value)
+		if err != nil {
+			return Metric{}, err
+		}
 
 		return Metric{
-			Name:   matches[1],
-			Labels: labels,
-			Value:  value,
-			Raw:    line,
+			Name:       matches[1],
+			Labels:     labels,
+			Value:      value,
+			FloatValue: number,
+			Raw:        line,
+			Timestamp:  matches[4],
 		}, nil
 	}
 
@@ -93,18 +242,45 @@ func parseLine(line string) (Metric, error) {
 		if len(matches) > 2 && matches[2] != "" {
 			value = matches[2]
 		}
+		number, err := parseValue(value)
+		if err != nil {
+			return Metric{}, err
+		}
 
 		return Metric{
-			Name:   matches[1],
-			Labels: make(map[string]string),
-			Value:  value,
-			Raw:    line,
+			Name:       matches[1],
+			Labels:     make(map[string]string),
+			Value:      value,
+			FloatValue: number,
+			Raw:        line,
+			Timestamp:  matches[3],
 		}, nil
 	}
 
 	return Metric{}, fmt.Errorf("invalid metric format: %s", line)
 }
 
+// parseValue reads a sample value as the exposition format writes it.
+// ParseFloat alone would also take hex floats, underscores and spellings such
+// as inf or infinity, so apart from NaN and ±Inf only decimal digits, points,
+// signs and exponents get that far.
+func parseValue(value string) (float64, error) {
+	switch value {
+	case "NaN", "Inf", "+Inf", "-Inf":
+	default:
+		if strings.IndexFunc(value, func(r rune) bool {
+			return !strings.ContainsRune("0123456789.eE+-", r)
+		}) >= 0 {
+			return 0, fmt.Errorf("invalid sample value %q", value)
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample value %q", value)
+	}
+	return number, nil
+}
+
 func parseLabels(labelStr string) (map[string]string, error) {
 	labels := make(map[string]string)
 	if labelStr == "" {
@@ -121,6 +297,9 @@ func parseLabels(labelStr string) (map[string]string, error) {
 		}
 
 		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			return nil, fmt.Errorf("empty label name: %s", pair)
+		}
 		value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
 
 		labels[key] = value
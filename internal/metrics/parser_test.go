@@ -0,0 +1,95 @@
+// ABOUTME: Tests for sample line parsing - which value spellings are float64s as the exposition format writes them
+// ABOUTME: Edge cases of scientific notation pass, while hex floats, underscores, inf spellings and empty label names are errors
+
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseLineValues(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"1e-300", 1e-300},
+		{"1.23456789e+308", 1.23456789e+308},
+		{"-0.0", math.Copysign(0, -1)},
+		{"+0", 0},
+		{".5", 0.5},
+		{"5.", 5},
+		{"1E3", 1000},
+		{"+Inf", math.Inf(1)},
+		{"-Inf", math.Inf(-1)},
+		{"Inf", math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			m, err := ParseLine(`up{job="api"} ` + tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.FloatValue != tt.want || math.Signbit(m.FloatValue) != math.Signbit(tt.want) {
+				t.Errorf("FloatValue = %v, want %v", m.FloatValue, tt.want)
+			}
+			if m.Value != tt.value {
+				t.Errorf("Value = %q, want the raw %q", m.Value, tt.value)
+			}
+		})
+	}
+
+	m, err := ParseLine("up NaN")
+	if err != nil || !math.IsNaN(m.FloatValue) {
+		t.Errorf("NaN = %v, %v", m.FloatValue, err)
+	}
+	if m, err := ParseLine("up"); err != nil || m.FloatValue != 0 {
+		t.Errorf("a missing value = %v, %v, want 0", m.FloatValue, err)
+	}
+}
+
+func TestParseLineRejectsMalformedValues(t *testing.T) {
+	for _, value := range []string{
+		"1e+e5",
+		"1.2.3",
+		"1_000",
+		"0x1p4",
+		"0X1P4",
+		"0x10",
+		"1p4",
+		"0x_1p4",
+		"inf",
+		"+inf",
+		"infinity",
+		"-Infinity",
+		"nan",
+		"1.8e309",
+		"-1.8e309",
+		"e5",
+		"--1",
+	} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := ParseLine("up " + value); err == nil {
+				t.Errorf("ParseLine accepted %q", value)
+			}
+			if _, err := ParseLine(`up{job="api"} ` + value); err == nil {
+				t.Errorf("ParseLine accepted %q after labels", value)
+			}
+		})
+	}
+}
+
+func TestParseLineRejectsEmptyLabelNames(t *testing.T) {
+	for _, line := range []string{
+		`up{="x"} 1`,
+		`up{ ="x"} 1`,
+		`up{job="api",="x"} 1`,
+	} {
+		if _, err := ParseLine(line); err == nil {
+			t.Errorf("ParseLine accepted %s", line)
+		}
+	}
+	if m, err := ParseLine(`up{job="api"} 1`); err != nil || m.Labels["job"] != "api" {
+		t.Errorf("ParseLine = %+v, %v", m.Labels, err)
+	}
+}
@@ -0,0 +1,158 @@
+// ABOUTME: PromQL selector parser - reads {job="x", method=~"GET|POST"} pasted instead of samples
+// ABOUTME: Keeps every matcher with its type; only equality matchers become labels
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MatchType is how a selector matcher compares a label's value
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher is one matcher of a selector, such as method=~"GET|POST"
+type LabelMatcher struct {
+	Name      string
+	Value     string
+	MatchType MatchType
+}
+
+// SelectorNote tells the LLM a selector has no values or series to judge
+const SelectorNote = "Input is a metric selector, not a sample. Evaluate label design only."
+
+var (
+	// An optional metric name and a brace block with nothing after it
+	selectorRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:.]*)?\{(.*)\}$`)
+	// One matcher: label name, operator, then a quoted value
+	matcherRegex = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*`)
+)
+
+// LooksLikeSelector reports whether input reads as PromQL selectors rather
+// than exposition: a line starts with a brace, or uses a matcher other than =
+func LooksLikeSelector(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := selectorRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if match[1] == "" {
+			return true
+		}
+		matchers, err := parseMatchers(match[2])
+		if err != nil {
+			continue
+		}
+		for _, m := range matchers {
+			if m.MatchType != MatchEqual {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseSelector reads one selector per line. Each becomes a Metric carrying
+// all its matchers, with only the equality matchers as labels. The name
+// comes from before the braces or an equality __name__ matcher.
+func ParseSelector(input string) ([]Metric, error) {
+	var ms []Metric
+	for i, line := range strings.Split(strings.TrimSpace(input), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := selectorRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: invalid selector: %s", i+1, line)
+		}
+		matchers, err := parseMatchers(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		m := Metric{Name: match[1], Labels: make(map[string]string), Value: "0", Raw: line, Matchers: matchers}
+		for _, matcher := range matchers {
+			if matcher.MatchType != MatchEqual {
+				continue
+			}
+			if matcher.Name == "__name__" {
+				m.Name = matcher.Value
+				continue
+			}
+			m.Labels[matcher.Name] = matcher.Value
+		}
+		ms = append(ms, m)
+	}
+	if len(ms) == 0 {
+		return nil, fmt.Errorf("no valid selectors found")
+	}
+	return ms, nil
+}
+
+// parseMatchers reads the comma-separated matchers between a selector's braces
+func parseMatchers(s string) ([]LabelMatcher, error) {
+	var matchers []LabelMatcher
+	for rest := strings.TrimSpace(s); rest != ""; {
+		loc := matcherRegex.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			return nil, fmt.Errorf("invalid matcher: %s", rest)
+		}
+		name, op := rest[loc[2]:loc[3]], MatchType(rest[loc[4]:loc[5]])
+		rest = rest[loc[1]:]
+
+		value, n, err := unquotePrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("matcher %s: %w", name, err)
+		}
+		matchers = append(matchers, LabelMatcher{Name: name, Value: value, MatchType: op})
+
+		rest = strings.TrimSpace(rest[n:])
+		if after, ok := strings.CutPrefix(rest, ","); ok {
+			rest = strings.TrimSpace(after)
+		} else if rest != "" {
+			return nil, fmt.Errorf("expected , after matcher %s", name)
+		}
+	}
+	return matchers, nil
+}
+
+// unquotePrefix reads the string literal s starts with, in any of PromQL's
+// double, single or backtick quotes, returning it and its length in s
+func unquotePrefix(s string) (string, int, error) {
+	if s == "" || !strings.ContainsRune("\"'`", rune(s[0])) {
+		return "", 0, fmt.Errorf("value must be quoted")
+	}
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote:
+			literal := s[:i+1]
+			if quote == '\'' {
+				// Go only quotes strings with " and `
+				literal = `"` + strings.ReplaceAll(literal[1:i], `"`, `\"`) + `"`
+			}
+			value, err := strconv.Unquote(literal)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid quoted value %s", s[:i+1])
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated value %s", s)
+}
@@ -0,0 +1,111 @@
+// ABOUTME: Tests for the PromQL selector parser - every matcher type and quote style, and which matchers become labels
+// ABOUTME: Samples aren't mistaken for selectors, and malformed selectors name the line that failed
+
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		labels   map[string]string
+		matchers []LabelMatcher
+	}{
+		{`{job="myapp", method=~"GET|POST", status!="500", path!~"/debug.*"}`, "",
+			map[string]string{"job": "myapp"},
+			[]LabelMatcher{
+				{"job", "myapp", MatchEqual},
+				{"method", "GET|POST", MatchRegexp},
+				{"status", "500", MatchNotEqual},
+				{"path", "/debug.*", MatchNotRegexp},
+			}},
+		{`http_requests_total{code=~"5.."}`, "http_requests_total",
+			map[string]string{},
+			[]LabelMatcher{{"code", "5..", MatchRegexp}}},
+		{`{__name__="up", job="api"}`, "up",
+			map[string]string{"job": "api"},
+			[]LabelMatcher{{"__name__", "up", MatchEqual}, {"job", "api", MatchEqual}}},
+		{`{__name__=~"http_.*"}`, "",
+			map[string]string{},
+			[]LabelMatcher{{"__name__", "http_.*", MatchRegexp}}},
+		{`{a='single "quoted"', b=` + "`back\\tick`" + `, c="esc\"aped",}`, "",
+			map[string]string{"a": `single "quoted"`, "b": `back\tick`, "c": `esc"aped`},
+			[]LabelMatcher{{"a", `single "quoted"`, MatchEqual}, {"b", `back\tick`, MatchEqual}, {"c", `esc"aped`, MatchEqual}}},
+		{`up{}`, "up", map[string]string{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ms, err := ParseSelector(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ms) != 1 {
+				t.Fatalf("got %d selectors, want 1", len(ms))
+			}
+			m := ms[0]
+			if m.Name != tt.name || !maps.Equal(m.Labels, tt.labels) || !slices.Equal(m.Matchers, tt.matchers) {
+				t.Errorf("got %q %v %+v, want %q %v %+v", m.Name, m.Labels, m.Matchers, tt.name, tt.labels, tt.matchers)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"{job=\"a\"}\njob=\"b\"", "line 2: invalid selector"},
+		{`{job=a}`, "matcher job: value must be quoted"},
+		{`{job="a}`, "unterminated value"},
+		{`{job="a" method="GET"}`, "expected , after matcher job"},
+		{`{job=="a"}`, "matcher job: value must be quoted"},
+		{`{1job="a"}`, "invalid matcher"},
+		{`{job="a"} 1`, "invalid selector"},
+		{"# only a comment", "no valid selectors found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseSelector(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReadsSelectors(t *testing.T) {
+	tests := []struct {
+		input    string
+		selector bool
+	}{
+		{`{job="myapp"}`, true},
+		{`http_requests_total{method=~"GET|POST"}`, true},
+		{"# a comment\n" + `up{job!="api"}`, true},
+		{`http_requests_total{method="GET"}`, false},
+		{`http_requests_total{method="GET"} 1`, false},
+		{`up 1`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := LooksLikeSelector(tt.input); got != tt.selector {
+				t.Errorf("LooksLikeSelector = %v, want %v", got, tt.selector)
+			}
+			parsed, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Selector != tt.selector {
+				t.Errorf("Selector = %v, want %v", parsed.Selector, tt.selector)
+			}
+			if tt.selector && (len(parsed.Metrics) != 1 || len(parsed.Metrics[0].Matchers) == 0) {
+				t.Errorf("metrics = %+v, want one selector with its matchers", parsed.Metrics)
+			}
+		})
+	}
+}
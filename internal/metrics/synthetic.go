@@ -0,0 +1,41 @@
+// ABOUTME: Synthetic scrapes - generated exposition text of a chosen size with every series distinct
+// ABOUTME: Feeds the bench subcommand and the parser and calculator benchmarks
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values per label before the next label steps, so every series is unique
+const syntheticLabelValues = 100
+
+// SyntheticScrape builds an exposition of counters with series spread evenly
+// over families and every label combination distinct
+func SyntheticScrape(series, families, labels int) string {
+	var sb strings.Builder
+	for f := range families {
+		name := fmt.Sprintf("bench_requests_%d_total", f)
+		fmt.Fprintf(&sb, "# HELP %s Synthetic requests handled.\n# TYPE %s counter\n", name, name)
+		for i := f; i < series; i += families {
+			sb.WriteString(name)
+			sb.WriteByte('{')
+			n := i / families
+			for l := range labels {
+				if l > 0 {
+					sb.WriteByte(',')
+				}
+				value := n % syntheticLabelValues
+				if l == labels-1 {
+					// The last label takes whatever the others can't tell apart
+					value = n
+				}
+				fmt.Fprintf(&sb, `shard_%d="s%d"`, l, value)
+				n /= syntheticLabelValues
+			}
+			fmt.Fprintf(&sb, "} %d\n", i)
+		}
+	}
+	return sb.String()
+}
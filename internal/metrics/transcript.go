@@ -0,0 +1,71 @@
+// ABOUTME: Transcript cleaning - strips shell prompts, grep separators, line numbers and colors from pasted output
+// ABOUTME: Lines that already parse are never touched; every line removed or rewritten is kept for the result page
+
+package metrics
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Terminal color and cursor sequences, such as grep --color output
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	// A shell prompt and the command after it: "$ curl -s host/metrics",
+	// "user@host:~$ curl ..." or zsh's "% curl ..."
+	promptLine = regexp.MustCompile(`^(?:[\w.-]+@[\w.-]+(?::\S*)?\s*)?[$%❯]\s+\S`)
+	// Editor and grep -n line numbers, "12:" or "12-" for grep context lines;
+	// metric names can't start with a digit, so a sample never matches
+	lineNumber = regexp.MustCompile(`^\s*\d+[:-]\s?`)
+)
+
+// CleanTranscript strips terminal decoration from a paste of shell output:
+// prompt and command lines, grep's "--" group separators, line number
+// prefixes and color codes. A line that parses as a sample or is a comment is
+// left exactly as it is, and so is a line that still doesn't parse after
+// cleaning, so its parse error points at what was pasted. Removed lines
+// become blank, keeping line numbers in parse errors right. It returns the
+// cleaned input and every line it removed or rewrote, as pasted but without
+// color codes.
+func CleanTranscript(input string) (string, []string) {
+	lines := strings.Split(input, "\n")
+	var cleaned []string
+	for i, line := range lines {
+		if keepAsPasted(line) {
+			continue
+		}
+
+		uncolored := ansiEscape.ReplaceAllString(line, "")
+		trimmed := strings.TrimSpace(uncolored)
+		if trimmed == "--" || promptLine.MatchString(trimmed) {
+			cleaned = append(cleaned, uncolored)
+			lines[i] = ""
+			continue
+		}
+		fixed := strings.TrimSpace(lineNumber.ReplaceAllString(trimmed, ""))
+		if fixed == "" {
+			cleaned = append(cleaned, uncolored)
+			lines[i] = ""
+			continue
+		}
+		if keepAsPasted(fixed) {
+			cleaned = append(cleaned, uncolored)
+			lines[i] = fixed
+		}
+	}
+	if len(cleaned) == 0 {
+		return input, nil
+	}
+	return strings.Join(lines, "\n"), cleaned
+}
+
+// keepAsPasted reports whether a line needs no cleaning: it is blank, a
+// comment or a sample that parses
+func keepAsPasted(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return true
+	}
+	_, err := ParseLine(line)
+	return err == nil
+}
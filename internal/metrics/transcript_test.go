@@ -0,0 +1,89 @@
+// ABOUTME: Tests for transcript cleaning - valid fixtures come back untouched, alone or inside a pasted transcript
+// ABOUTME: Decoration around them is stripped and reported line by line
+
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/examples"
+)
+
+// validFixtures is every exposition the repo ships: the .prom files and the
+// showcase examples
+func validFixtures(t *testing.T) map[string]string {
+	t.Helper()
+	fixtures := make(map[string]string)
+	for _, pattern := range []string{"../../examples/*/*.prom", "../server/testdata/e2e/fixtures/*.prom"} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixtures[path] = string(data)
+		}
+	}
+	for _, e := range examples.All() {
+		fixtures["example "+e.ID] = e.Metrics
+	}
+	if len(fixtures) < 10 {
+		t.Fatalf("found %d fixtures, want the repo's .prom files and examples", len(fixtures))
+	}
+	return fixtures
+}
+
+func TestCleaningLeavesValidFixturesAlone(t *testing.T) {
+	for name, input := range validFixtures(t) {
+		if got, cleaned := CleanTranscript(input); got != input || cleaned != nil {
+			t.Errorf("%s: cleaning changed the input, removing %q", name, cleaned)
+		}
+	}
+}
+
+// Every line that parses keeps its place and text when the fixture is pasted
+// as a terminal transcript, and only the decoration is reported
+func TestCleaningKeepsParsedLinesInATranscript(t *testing.T) {
+	decoration := []string{"\x1b[32muser@host:~$\x1b[0m curl -s localhost:9100/metrics | grep -n http", "--", "% head metrics.txt"}
+	for name, input := range validFixtures(t) {
+		lines := strings.Split(input, "\n")
+		transcript := append(slices.Clone(decoration), lines...)
+		got, cleaned := CleanTranscript(strings.Join(transcript, "\n"))
+		gotLines := strings.Split(got, "\n")
+		if len(gotLines) != len(transcript) {
+			t.Errorf("%s: %d lines after cleaning, want %d", name, len(gotLines), len(transcript))
+			continue
+		}
+		for i, line := range lines {
+			if _, err := ParseLine(strings.TrimSpace(line)); err == nil && gotLines[len(decoration)+i] != line {
+				t.Errorf("%s: line %q became %q", name, line, gotLines[len(decoration)+i])
+			}
+		}
+		if len(cleaned) != len(decoration) {
+			t.Errorf("%s: cleaned %q, want the %d decoration lines alone", name, cleaned, len(decoration))
+		}
+	}
+}
+
+func TestCleaningStripsDecoration(t *testing.T) {
+	input := "$ curl -s host/metrics | grep -n http\n" +
+		"12:\x1b[1;31mhttp_requests_total\x1b[0m{code=\"200\"} 3\n" +
+		"--\n" +
+		"14:http_requests_total{code=\"500\"} 1"
+	got, cleaned := CleanTranscript(input)
+	want := "\nhttp_requests_total{code=\"200\"} 3\n\nhttp_requests_total{code=\"500\"} 1"
+	if got != want {
+		t.Errorf("cleaned input = %q, want %q", got, want)
+	}
+	wantCleaned := []string{"$ curl -s host/metrics | grep -n http", "12:http_requests_total{code=\"200\"} 3", "--", "14:http_requests_total{code=\"500\"} 1"}
+	if !slices.Equal(cleaned, wantCleaned) {
+		t.Errorf("cleaned lines = %q, want %q", cleaned, wantCleaned)
+	}
+}
@@ -0,0 +1,71 @@
+// ABOUTME: Metric type resolution - reads # TYPE declarations and otherwise guesses from sample names
+// ABOUTME: Inference covers counter/histogram/summary where the submission carries no # TYPE metadata
+
+package metrics
+
+import "strings"
+
+// Sample suffixes that belong to a declared family, e.g. foo_bucket to # TYPE foo histogram
+var familySuffixes = []string{"_bucket", "_sum", "_count", "_total", "_created"}
+
+// DeclaredFamily finds the # TYPE declaration covering a sample name
+func (p *ParsedMetrics) DeclaredFamily(name string) (family, typ string, ok bool) {
+	if typ, ok := p.Types[name]; ok {
+		return name, typ, true
+	}
+	for _, suffix := range familySuffixes {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			if typ, ok := p.Types[base]; ok {
+				return base, typ, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// TypeOf returns the metric type for a sample name in this submission, taken
+// from its # TYPE declaration or inferred from naming conventions and the
+// presence of sibling _bucket, _sum and _count series
+func (p *ParsedMetrics) TypeOf(name string) string {
+	if _, typ, ok := p.DeclaredFamily(name); ok {
+		return typ
+	}
+
+	switch {
+	case strings.HasSuffix(name, "_total"):
+		return "counter"
+	case strings.HasSuffix(name, "_bucket"):
+		return "histogram"
+	case strings.HasSuffix(name, "_sum"), strings.HasSuffix(name, "_count"):
+		// Only a _sum and _count pair, or buckets beside them, make a
+		// family; a lone thread_count is just a name ending in count
+		base, sibling := strings.TrimSuffix(name, "_sum"), "_count"
+		if base == name {
+			base, sibling = strings.TrimSuffix(name, "_count"), "_sum"
+		}
+		if p.hasSample(base + "_bucket") {
+			return "histogram"
+		}
+		if p.hasSample(base + sibling) {
+			return "summary"
+		}
+	}
+
+	for _, m := range p.Metrics {
+		if m.Name == name {
+			if _, ok := m.Labels["quantile"]; ok {
+				return "summary"
+			}
+		}
+	}
+	return "untyped"
+}
+
+func (p *ParsedMetrics) hasSample(name string) bool {
+	for _, m := range p.Metrics {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+// ABOUTME: Prometheus text exposition writer - renders parsed metrics back into scrapeable text
+// ABOUTME: Orders labels by DefaultLabelOrder and escapes values so the output is stable and valid
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	helpUnescaper     = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+// FormatSample renders a metric as a single exposition line with labels in
+// DefaultLabelOrder
+func FormatSample(m Metric) string {
+	return DefaultLabelOrder.FormatSample(m)
+}
+
+// SerializeWithComments renders one sample as an exposition block, preceded
+// by # HELP and # TYPE lines for its family when helpText and metricType are set
+func SerializeWithComments(m Metric, helpText, metricType string) string {
+	family := m.Name
+	if metricType == "histogram" || metricType == "summary" {
+		for _, suffix := range []string{"_sum", "_count", "_bucket"} {
+			if base := strings.TrimSuffix(m.Name, suffix); base != m.Name {
+				family = base
+				break
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if helpText != "" {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", family, helpEscaper.Replace(helpText))
+	}
+	if metricType != "" {
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", family, metricType)
+	}
+	sb.WriteString(FormatSample(m))
+	return sb.String()
+}
+
+// WriteText writes metrics in Prometheus text format, emitting # HELP and # TYPE
+// lines before the first sample of every family present in help or types
+// (both keyed by family name, either may be nil)
+func WriteText(w io.Writer, ms []Metric, types, help map[string]string) error {
+	described := make(map[string]bool)
+
+	for _, m := range ms {
+		family := familyName(m.Name, types)
+		if !described[family] {
+			if h, ok := help[family]; ok {
+				if _, err := fmt.Fprintf(w, "# HELP %s %s\n", family, helpEscaper.Replace(h)); err != nil {
+					return err
+				}
+			}
+			if t, ok := types[family]; ok {
+				if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", family, t); err != nil {
+					return err
+				}
+			}
+			described[family] = true
+		}
+
+		if _, err := fmt.Fprintln(w, FormatSample(m)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// familyName resolves the family a sample belongs to, accounting for the
+// _sum/_count/_bucket series that histograms and summaries expose
+func familyName(name string, types map[string]string) string {
+	if _, ok := types[name]; ok {
+		return name
+	}
+	for _, suffix := range []string{"_sum", "_count", "_bucket"} {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			if _, ok := types[base]; ok {
+				return base
+			}
+		}
+	}
+	return name
+}
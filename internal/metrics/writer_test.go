@@ -0,0 +1,58 @@
+// ABOUTME: Tests for the exposition writer - a sample serialized with its family's # HELP and # TYPE lines
+// ABOUTME: Histogram and summary series are described under the family name, and the output parses back unchanged
+
+package metrics
+
+import "testing"
+
+func TestSerializeWithComments(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          Metric
+		help, kind string
+		want       string
+		family     string
+	}{
+		{"counter", Metric{Name: "http_requests_total", Value: "3", Labels: map[string]string{"method": "GET"}},
+			"Total HTTP requests.", "counter",
+			"# HELP http_requests_total Total HTTP requests.\n# TYPE http_requests_total counter\nhttp_requests_total{method=\"GET\"} 3",
+			"http_requests_total"},
+		{"histogram bucket", Metric{Name: "request_seconds_bucket", Value: "7", Labels: map[string]string{"le": "0.5"}},
+			"Request latency.", "histogram",
+			"# HELP request_seconds Request latency.\n# TYPE request_seconds histogram\nrequest_seconds_bucket{le=\"0.5\"} 7",
+			"request_seconds"},
+		{"summary count", Metric{Name: "rpc_seconds_count", Value: "2"},
+			"RPC latency.", "summary",
+			"# HELP rpc_seconds RPC latency.\n# TYPE rpc_seconds summary\nrpc_seconds_count 2",
+			"rpc_seconds"},
+		{"a gauge keeps a suffix-like name", Metric{Name: "queue_count", Value: "1"},
+			"", "gauge",
+			"# TYPE queue_count gauge\nqueue_count 1",
+			"queue_count"},
+		{"escaped help", Metric{Name: "up", Value: "1"},
+			"Line one\nback\\slash", "",
+			"# HELP up Line one\\nback\\\\slash\nup 1",
+			"up"},
+		{"no comments", Metric{Name: "up", Value: "1"}, "", "", "up 1", "up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SerializeWithComments(tt.m, tt.help, tt.kind)
+			if got != tt.want {
+				t.Fatalf("SerializeWithComments =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			parsed, err := Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Help[tt.family] != tt.help || parsed.Types[tt.family] != tt.kind {
+				t.Errorf("parsed back HELP %q, TYPE %q for %s, want %q, %q",
+					parsed.Help[tt.family], parsed.Types[tt.family], tt.family, tt.help, tt.kind)
+			}
+			if len(parsed.Metrics) != 1 || parsed.Metrics[0].Name != tt.m.Name || parsed.Metrics[0].Value != tt.m.Value {
+				t.Errorf("parsed back samples %+v, want %s %s", parsed.Metrics, tt.m.Name, tt.m.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// ABOUTME: API key middleware - protects admin and API endpoints with shared secrets
+// ABOUTME: Accepts the key as a bearer token or in the X-API-Key header
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKey admits requests carrying any of keys
+func APIKey(keys ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := providedKey(c)
+
+		valid := false
+		for _, key := range keys {
+			// Every key is compared so timing doesn't reveal which one matched
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				valid = true
+			}
+		}
+		if provided == "" || !valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// providedKey reads the API key from a bearer token or the X-API-Key header
+func providedKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return c.GetHeader("X-API-Key")
+}
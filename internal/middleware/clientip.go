@@ -0,0 +1,63 @@
+// ABOUTME: Client IP resolution shared by logging, auditing, abuse protection and the admin allowlist
+// ABOUTME: Honours the trusted proxy settings and warns when forwarded headers arrive with none configured
+
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const clientIPKey = "client_ip"
+
+// ResolveClientIP records the client address for ClientIP. gin's resolution
+// applies, which only believes X-Forwarded-For from the engine's trusted
+// proxies; a proxyDepth above zero instead takes the address that many hops
+// from the right of X-Forwarded-For, again only from trusted proxies.
+func ResolveClientIP(proxyDepth int, trustedProxies []string) gin.HandlerFunc {
+	var warnOnce sync.Once
+	trusted := parsePrefixes("trusted proxy", trustedProxies)
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if proxyDepth > 0 {
+			if addr, ok := clientAddr(c.Request, proxyDepth, trusted); ok {
+				ip = addr.String()
+			}
+		}
+
+		if len(trustedProxies) == 0 && c.GetHeader("X-Forwarded-For") != "" {
+			warnOnce.Do(func() {
+				log.Printf("[ClientIP] X-Forwarded-For received from %s but no trusted proxies are configured; "+
+					"client IPs are the proxy's address. Set TRUSTED_PROXIES to the proxy's CIDR", c.RemoteIP())
+			})
+		}
+
+		c.Set(clientIPKey, ip)
+		c.Next()
+	}
+}
+
+// ClientIP returns the address ResolveClientIP recorded for the request
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
+
+// Logger is gin's request log with the client address from ClientIP
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		ip := p.ClientIP
+		if resolved, ok := p.Keys[clientIPKey].(string); ok {
+			ip = resolved
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"), p.StatusCode, p.Latency.Truncate(time.Microsecond),
+			ip, p.Method, p.Path, p.ErrorMessage)
+	})
+}
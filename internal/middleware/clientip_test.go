@@ -0,0 +1,116 @@
+// ABOUTME: Tests for client IP resolution with spoofed X-Forwarded-For from untrusted and trusted sources
+// ABOUTME: The request log, ClientIP (used by abuse protection and auditing) and the admin allowlist must all agree
+
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPBehindProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		trusted    []string
+		proxyDepth int
+		remote     string
+		xff        string
+		want       string
+		allowed    bool
+	}{
+		{"no proxies and a spoofed header", nil, 0, "203.0.113.9", "198.51.100.7", "203.0.113.9", false},
+		{"trusted proxy", []string{"192.0.2.0/24"}, 0, "192.0.2.10", "198.51.100.7", "198.51.100.7", true},
+		{"trusted proxy passing on a spoofed entry", []string{"192.0.2.0/24"}, 0, "192.0.2.10", "198.51.100.66, 203.0.113.9", "203.0.113.9", false},
+		{"untrusted source claiming a forwarded client", []string{"192.0.2.0/24"}, 0, "203.0.113.9", "198.51.100.7", "203.0.113.9", false},
+		{"proxy depth", []string{"192.0.2.0/24"}, 1, "192.0.2.10", "198.51.100.66, 198.51.100.7", "198.51.100.7", true},
+		{"proxy depth with a spoofed entry", []string{"192.0.2.0/24"}, 1, "192.0.2.10", "198.51.100.7, 203.0.113.9", "203.0.113.9", false},
+		{"proxy depth from an untrusted source", []string{"192.0.2.0/24"}, 1, "203.0.113.9", "198.51.100.7", "203.0.113.9", false},
+		{"proxy depth without trusted proxies", nil, 1, "203.0.113.9", "198.51.100.7", "203.0.113.9", false},
+		{"fewer hops than the proxy depth", []string{"192.0.2.0/24"}, 2, "192.0.2.10", "198.51.100.7", "192.0.2.10", false},
+		{"two proxies deep", []string{"192.0.2.0/24"}, 2, "192.0.2.10", "203.0.113.9, 198.51.100.7, 192.0.2.11", "198.51.100.7", true},
+		{"no header", []string{"192.0.2.0/24"}, 0, "198.51.100.7", "", "198.51.100.7", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestLog bytes.Buffer
+			gin.DefaultWriter = &requestLog
+			t.Cleanup(func() { gin.DefaultWriter = os.Stdout })
+
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(ResolveClientIP(tt.proxyDepth, tt.trusted), Logger())
+			var seen string
+			r.GET("/ip", func(c *gin.Context) { seen = ClientIP(c) })
+			r.GET("/admin", IPAllowlistBehindProxies([]string{"198.51.100.0/24"}, tt.proxyDepth, tt.trusted), func(c *gin.Context) {})
+
+			serve := func(path string) int {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.RemoteAddr = tt.remote + ":41000"
+				if tt.xff != "" {
+					req.Header.Set("X-Forwarded-For", tt.xff)
+				}
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				return rec.Code
+			}
+
+			serve("/ip")
+			if seen != tt.want {
+				t.Errorf("ClientIP = %q, want %q", seen, tt.want)
+			}
+			if !strings.Contains(requestLog.String(), " "+tt.want+" ") {
+				t.Errorf("request log %q doesn't name %s", requestLog.String(), tt.want)
+			}
+			if allowed := serve("/admin") == http.StatusOK; allowed != tt.allowed {
+				t.Errorf("allowlist let the request through = %v, want %v", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestForwardedHeaderWithoutTrustedProxiesWarnsOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	serve := func(r *gin.Engine, xff string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	r := gin.New()
+	r.Use(ResolveClientIP(0, nil))
+	r.GET("/", func(c *gin.Context) {})
+	serve(r, "")
+	if logs.Len() != 0 {
+		t.Errorf("warned without a forwarded header: %s", logs.String())
+	}
+	serve(r, "198.51.100.7")
+	serve(r, "198.51.100.8")
+	if n := strings.Count(logs.String(), "no trusted proxies are configured"); n != 1 {
+		t.Errorf("warned %d times, want once: %s", n, logs.String())
+	}
+
+	logs.Reset()
+	configured := gin.New()
+	configured.Use(ResolveClientIP(0, []string{"192.0.2.0/24"}))
+	configured.GET("/", func(c *gin.Context) {})
+	serve(configured, "198.51.100.7")
+	if logs.Len() != 0 {
+		t.Errorf("warned with trusted proxies configured: %s", logs.String())
+	}
+}
@@ -0,0 +1,117 @@
+// ABOUTME: IP allowlist middleware - restricts admin endpoints to internal networks
+// ABOUTME: Resolves the client through a fixed number of trusted proxies and rejects unknown addresses
+
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPAllowlist allows only clients connecting directly from allowedCIDRs
+func IPAllowlist(allowedCIDRs []string) gin.HandlerFunc {
+	return IPAllowlistBehindProxies(allowedCIDRs, 0, nil)
+}
+
+// IPAllowlistBehindProxies allows only clients in allowedCIDRs, reading the
+// client address from X-Forwarded-For when proxyDepth proxies sit in front of
+// the server and the connection comes from one of trustedProxies. Entries
+// left of the trusted hops are client-supplied and ignored. Blocked requests
+// get an empty 403 so the endpoint's existence isn't confirmed.
+func IPAllowlistBehindProxies(allowedCIDRs []string, proxyDepth int, trustedProxies []string) gin.HandlerFunc {
+	allowed := parsePrefixes("allowed", allowedCIDRs)
+	trusted := parsePrefixes("trusted proxy", trustedProxies)
+
+	return func(c *gin.Context) {
+		ip, ok := clientAddr(c.Request, proxyDepth, trusted)
+		if proxyDepth == 0 {
+			// Trusted proxies, when configured, are resolved by ClientIP
+			ip, ok = parseAddr(ClientIP(c))
+		}
+		if ok {
+			for _, prefix := range allowed {
+				if prefix.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+// parsePrefixes parses CIDRs or bare addresses, logging and skipping invalid ones
+func parsePrefixes(kind string, cidrs []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			log.Printf("[IPAllowlist] Ignoring invalid %s CIDR %q: %v", kind, cidr, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// parsePrefix accepts CIDR notation or a bare address
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// clientAddr is the address proxyDepth hops from the right of
+// X-Forwarded-For when the connection comes from a trusted proxy, and the
+// connection's address otherwise. A header with fewer hops than proxyDepth
+// didn't pass through every proxy, so its entries are all client-supplied
+// and the connection's address is used.
+func clientAddr(r *http.Request, proxyDepth int, trusted []netip.Prefix) (netip.Addr, bool) {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	addr, ok := parseAddr(remote)
+	if !ok || proxyDepth == 0 || !slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+		return addr, ok
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) < proxyDepth {
+		return addr, ok
+	}
+	// The nearest proxy is the remote address and appended the last hop,
+	// so the client is proxyDepth entries from the right
+	return parseAddr(hops[len(hops)-proxyDepth])
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
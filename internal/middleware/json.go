@@ -0,0 +1,14 @@
+// ABOUTME: JSON-only routes - answers in JSON whatever the request's Accept header says
+// ABOUTME: For API paths served by handlers that also render HTML
+
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// AcceptJSON makes content negotiation pick JSON
+func AcceptJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.SetAccepted(gin.MIMEJSON)
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+// ABOUTME: Language detection - picks the UI language from an override cookie or Accept-Language
+// ABOUTME: Stores it on the context for templates and error messages
+
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/i18n"
+)
+
+// LanguageCookie holds an explicit language choice, which beats Accept-Language
+const LanguageCookie = "lang"
+
+const languageKey = "language"
+
+// DetectLanguage resolves the request's UI language
+func DetectLanguage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		if cookie, err := c.Cookie(LanguageCookie); err == nil && i18n.Supported(cookie) {
+			lang = cookie
+		}
+		c.Set(languageKey, lang)
+		c.Next()
+	}
+}
+
+// Language returns the language DetectLanguage chose, or English without it
+func Language(c *gin.Context) string {
+	if lang := c.GetString(languageKey); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage
+}
@@ -0,0 +1,46 @@
+// ABOUTME: Tests for UI language detection - Accept-Language picks the language and the cookie overrides it
+// ABOUTME: Unsupported choices fall back rather than reaching the templates
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(DetectLanguage())
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, Language(c)) })
+
+	tests := []struct {
+		name, header, cookie, want string
+	}{
+		{"nothing", "", "", "en"},
+		{"header", "de-DE,de;q=0.9", "", "de"},
+		{"cookie overrides the header", "de-DE", "en", "en"},
+		{"cookie alone", "", "de", "de"},
+		{"unsupported cookie", "de", "fr", "de"},
+		{"unsupported header", "fr-FR", "", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: LanguageCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Body.String() != tt.want {
+				t.Errorf("language = %q, want %q", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// ABOUTME: Quota middleware - enforces per-API-key hourly and daily request limits
+// ABOUTME: Reports the remaining allowance in X-RateLimit headers and rejects requests over it with 429
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/quota"
+)
+
+// Quota counts requests carrying an API key against the limiter's policies.
+// Requests without a key, or whose key matches no policy, pass untouched.
+func Quota(limiter *quota.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := providedKey(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		d, limited := limiter.Allow(key, now)
+		if !limited {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(d.Reset.Unix(), 10))
+		if !d.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(d.Reset.Sub(now).Seconds()+1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "quota exceeded for this API key"})
+			return
+		}
+		c.Next()
+	}
+}
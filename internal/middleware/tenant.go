@@ -0,0 +1,78 @@
+// ABOUTME: Tenant resolution - decides which tenant a request belongs to from its API key or path prefix
+// ABOUTME: Stores the tenant on the context; requests claimed by neither get the default tenant
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+const (
+	tenantKey      = "tenant"
+	tenantByKeyKey = "tenantByKey"
+	basePathKey    = "basePath"
+)
+
+// ResolveTenant picks the request's tenant. A path prefix, already stripped by
+// the server and left in the request context, and a tenant API key both name
+// one; a key for a different tenant than the prefix gets a 404, as the
+// prefix's resources don't exist for it.
+func ResolveTenant(tenants *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, byPath := tenant.FromContext(c.Request.Context())
+		if byPath {
+			c.Set(basePathKey, t.PathPrefix)
+		}
+
+		if key := providedKey(c); key != "" {
+			if byKey, ok := tenants.ByKey(key); ok {
+				if byPath && byKey.ID != t.ID {
+					c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+					return
+				}
+				t = byKey
+				c.Set(tenantByKeyKey, true)
+			}
+		}
+
+		if t.ID == "" {
+			t = tenant.Default()
+		}
+		c.Set(tenantKey, t)
+		c.Next()
+	}
+}
+
+// APIKeyOrTenant admits requests carrying any of keys or a tenant's API key.
+// It must run after ResolveTenant.
+func APIKeyOrTenant(keys ...string) gin.HandlerFunc {
+	byKeys := APIKey(keys...)
+	return func(c *gin.Context) {
+		if TenantByKey(c) {
+			c.Next()
+			return
+		}
+		byKeys(c)
+	}
+}
+
+// CurrentTenant returns the tenant ResolveTenant chose, or the default tenant without it
+func CurrentTenant(c *gin.Context) tenant.Tenant {
+	if t, ok := c.Get(tenantKey); ok {
+		return t.(tenant.Tenant)
+	}
+	return tenant.Default()
+}
+
+// TenantByKey reports whether the tenant was named by one of its API keys
+func TenantByKey(c *gin.Context) bool {
+	return c.GetBool(tenantByKeyKey)
+}
+
+// BasePath is the path prefix the request came in under, for links that must keep it
+func BasePath(c *gin.Context) string {
+	return c.GetString(basePathKey)
+}
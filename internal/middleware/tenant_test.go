@@ -0,0 +1,59 @@
+// ABOUTME: Tests for tenant resolution - a tenant is picked by API key or path prefix, defaulting without either
+// ABOUTME: A key of one tenant under another tenant's prefix is a cross-tenant attempt and reads as 404
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+)
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	payments := tenant.Tenant{ID: "payments", APIKeys: []string{"pay-key"}, PathPrefix: "/teams/payments"}
+	search := tenant.Tenant{ID: "search", APIKeys: []string{"search-key"}, PathPrefix: "/teams/search"}
+	registry := tenant.NewRegistry([]tenant.Tenant{payments, search})
+
+	r := gin.New()
+	r.Use(ResolveTenant(registry))
+	r.GET("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"tenant": CurrentTenant(c).ID, "by_key": TenantByKey(c), "base": BasePath(c)})
+	})
+
+	tests := []struct {
+		name string
+		// Tenant the path prefix named, if any
+		byPath *tenant.Tenant
+		key    string
+		status int
+		want   string
+	}{
+		{"no key or prefix", nil, "", http.StatusOK, `{"base":"","by_key":false,"tenant":"default"}`},
+		{"unknown key", nil, "guess", http.StatusOK, `{"base":"","by_key":false,"tenant":"default"}`},
+		{"key alone", nil, "pay-key", http.StatusOK, `{"base":"","by_key":true,"tenant":"payments"}`},
+		{"prefix alone", &search, "", http.StatusOK, `{"base":"/teams/search","by_key":false,"tenant":"search"}`},
+		{"matching key and prefix", &payments, "pay-key", http.StatusOK, `{"base":"/teams/payments","by_key":true,"tenant":"payments"}`},
+		{"key of another tenant than the prefix", &search, "pay-key", http.StatusNotFound, `{"error":"not found"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.byPath != nil {
+				// As the server's path router does after stripping the prefix
+				req = req.WithContext(tenant.NewContext(req.Context(), *tt.byPath))
+			}
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.status || rec.Body.String() != tt.want {
+				t.Errorf("got %d %s, want %d %s", rec.Code, rec.Body.String(), tt.status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// ABOUTME: One monitor run - scrapes the endpoint, runs the static checks and compares with the last good run
+// ABOUTME: New error findings or series growth beyond the configured percentage raise an alert
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// run scrapes m once, stores the run and schedules the next one
+func (s *Scheduler) run(m history.Monitor) {
+	run := history.MonitorRun{MonitorID: m.ID, StartedAt: s.now(), ErrorFindings: []string{}}
+	failures := 0
+	if err := s.check(m, &run); err != nil {
+		run.Error = err.Error()
+		failures = m.ConsecutiveFailures + 1
+		log.Printf("[Monitor] Monitor %d: %v (%d failure(s) in a row)", m.ID, err, failures)
+	}
+	s.schedule(m.ID, run.StartedAt.Add(backoff(m.Interval, failures)))
+
+	// A monitor removed while it was scraped has nowhere to keep its run
+	if err := s.store.SetMonitorFailures(m.ID, failures); errors.Is(err, history.ErrNotFound) {
+		return
+	} else if err != nil {
+		log.Printf("[Monitor] Error storing monitor %d: %v", m.ID, err)
+	}
+	if err := s.store.AddMonitorRun(&run, s.opts.HistorySize); err != nil {
+		log.Printf("[Monitor] Error storing run of monitor %d: %v", m.ID, err)
+	}
+}
+
+// check scrapes m and fills in run, alerting when it regressed from the last
+// successful run
+func (s *Scheduler) check(m history.Monitor, run *history.MonitorRun) error {
+	body, err := s.scrape(m)
+	if err != nil {
+		return err
+	}
+	profile := s.profile()
+	parsed, err := profile.Parse(body)
+	if err != nil {
+		return fmt.Errorf("parsing scrape: %w", err)
+	}
+
+	// Runtime metrics are judged by the client library, not the team, as in evaluations
+	evaluated, _ := naming.ExcludeRuntime(parsed)
+	run.Series = len(parsed.Metrics)
+	for _, f := range profile.Check(evaluated) {
+		if f.Severity == rules.SeverityError {
+			run.ErrorFindings = append(run.ErrorFindings, strings.TrimSpace(f.Code+" "+f.Metric))
+		}
+	}
+	slices.Sort(run.ErrorFindings)
+	run.ErrorFindings = slices.Compact(run.ErrorFindings)
+
+	previous, ok, err := s.lastSuccess(m.ID)
+	if err != nil || !ok {
+		return err
+	}
+	alert := s.compare(m, previous, *run)
+	if alert == nil {
+		return nil
+	}
+	run.Alerted = true
+	log.Printf("[Monitor] Monitor %d: %s", m.ID, alert.Text)
+	if s.opts.WebhookURL != "" {
+		if err := post(s.client, s.opts.WebhookURL, alert); err != nil {
+			log.Printf("[Monitor] Error sending alert for monitor %d: %v", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// scrape fetches m's metrics in the text exposition format
+func (s *Scheduler) scrape(m history.Monitor) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, m.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	if m.Authorization != "" {
+		req.Header.Set("Authorization", m.Authorization)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status %d", m.URL, resp.StatusCode)
+	}
+	// Held to the same limit as submitted metrics
+	body, err := io.ReadAll(io.LimitReader(resp.Body, api.MaxMetricsBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > api.MaxMetricsBytes {
+		return "", fmt.Errorf("%s returned more than %d bytes", m.URL, api.MaxMetricsBytes)
+	}
+	return string(body), nil
+}
+
+// lastSuccess returns the monitor's latest run that didn't fail, if any
+func (s *Scheduler) lastSuccess(id int64) (history.MonitorRun, bool, error) {
+	runs, err := s.store.MonitorRuns(id, s.opts.HistorySize)
+	if err != nil {
+		return history.MonitorRun{}, false, err
+	}
+	for _, r := range runs {
+		if r.Error == "" {
+			return r, true, nil
+		}
+	}
+	return history.MonitorRun{}, false, nil
+}
+
+// compare returns the alert for run's regressions since previous, or nil
+func (s *Scheduler) compare(m history.Monitor, previous, run history.MonitorRun) *Alert {
+	var added []string
+	for _, f := range run.ErrorFindings {
+		if !slices.Contains(previous.ErrorFindings, f) {
+			added = append(added, f)
+		}
+	}
+	grown := previous.Series > 0 &&
+		float64(run.Series-previous.Series) > float64(previous.Series)*s.opts.SeriesGrowthPercent/100
+	if len(added) == 0 && !grown {
+		return nil
+	}
+	return newAlert(m, previous, run, added, grown)
+}
@@ -0,0 +1,226 @@
+// ABOUTME: Endpoint monitoring - scrapes registered metrics endpoints on their interval and runs the static checks
+// ABOUTME: Runs are stored per endpoint; endpoints that fail back off until they answer again
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+const (
+	// MinInterval is the shortest interval an endpoint can be scraped at
+	MinInterval = 10 * time.Second
+	// MaxBackoff caps the wait after failed scrapes, unless the interval is longer
+	MaxBackoff = time.Hour
+	// DefaultSeriesGrowthPercent is how much the series count may grow between
+	// runs before it is alerted on
+	DefaultSeriesGrowthPercent = 20.0
+	// DefaultHistorySize is how many runs are kept per endpoint
+	DefaultHistorySize = 100
+
+	scrapeTimeout = 30 * time.Second
+	// How long to wait before reading the monitors again after the store failed
+	retryStore = time.Minute
+)
+
+// ErrInvalid is wrapped by Register's errors for a URL or interval it won't accept
+var ErrInvalid = errors.New("invalid monitor")
+
+// Options configure alerting and run history
+type Options struct {
+	// Alerts are posted here as JSON Slack also accepts; empty only logs them
+	WebhookURL string
+	// Series growth between runs, in percent, that raises an alert
+	SeriesGrowthPercent float64
+	// Runs kept per endpoint
+	HistorySize int
+}
+
+// Scheduler scrapes every registered endpoint on its interval, one at a time
+type Scheduler struct {
+	store   history.Store
+	profile func() rules.NamingProfile
+	opts    Options
+	client  *http.Client
+
+	// The clock, replaceable so scheduling can be driven by a fake one
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	mu sync.Mutex
+	// Monitor ID to when it is next scraped; unknown monitors are due at once
+	next map[int64]time.Time
+	// Tells the loop monitors were added or removed
+	wake chan struct{}
+}
+
+// Status is a monitor as the API and the monitors page show it, without its
+// Authorization header
+type Status struct {
+	ID                  int64                `json:"id"`
+	URL                 string               `json:"url"`
+	Interval            string               `json:"interval"`
+	CreatedAt           time.Time            `json:"created_at"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+	NextRun             time.Time            `json:"next_run"`
+	Runs                []history.MonitorRun `json:"runs"`
+}
+
+// New returns a scheduler judging scrapes by the profile it returns at the time
+func New(store history.Store, profile func() rules.NamingProfile, opts Options) *Scheduler {
+	if opts.SeriesGrowthPercent <= 0 {
+		opts.SeriesGrowthPercent = DefaultSeriesGrowthPercent
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = DefaultHistorySize
+	}
+	return &Scheduler{
+		store:   store,
+		profile: profile,
+		opts:    opts,
+		client:  &http.Client{Timeout: scrapeTimeout},
+		now:     time.Now,
+		after:   time.After,
+		next:    make(map[int64]time.Time),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Start scrapes every stored monitor once, then each on its interval
+func (s *Scheduler) Start() {
+	go s.loop()
+}
+
+func (s *Scheduler) loop() {
+	for {
+		wait, err := s.runDue()
+		if err != nil {
+			log.Printf("[Monitor] Error reading monitors: %v", err)
+			wait = retryStore
+		}
+		select {
+		case <-s.after(wait):
+		case <-s.wake:
+		}
+	}
+}
+
+// runDue scrapes the monitors that are due and returns how long until the
+// next one is. With no monitors it waits MaxBackoff, or until one is registered.
+func (s *Scheduler) runDue() (time.Duration, error) {
+	monitors, err := s.store.Monitors()
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range monitors {
+		if !s.nextRun(m.ID).After(s.now()) {
+			s.run(m)
+		}
+	}
+
+	wait := MaxBackoff
+	now := s.now()
+	for _, m := range monitors {
+		wait = min(wait, s.nextRun(m.ID).Sub(now))
+	}
+	return max(wait, 0), nil
+}
+
+func (s *Scheduler) nextRun(id int64) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if next, ok := s.next[id]; ok {
+		return next
+	}
+	return s.now()
+}
+
+func (s *Scheduler) schedule(id int64, at time.Time) {
+	s.mu.Lock()
+	s.next[id] = at
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// backoff is how long after a run the next one starts: the interval, doubled
+// for each failure in a row up to MaxBackoff, though never below the interval
+func backoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 0; i < failures && wait < MaxBackoff; i++ {
+		wait *= 2
+	}
+	return max(min(wait, MaxBackoff), interval)
+}
+
+// Register stores a monitor for rawURL and scrapes it right away, so later
+// runs have a baseline to compare with
+func (s *Scheduler) Register(rawURL, authorization string, interval time.Duration) (history.Monitor, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return history.Monitor{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+	if interval < MinInterval {
+		return history.Monitor{}, fmt.Errorf("%w: interval must be at least %s", ErrInvalid, MinInterval)
+	}
+
+	m := history.Monitor{URL: rawURL, Authorization: authorization, Interval: interval, CreatedAt: s.now()}
+	if err := s.store.AddMonitor(&m); err != nil {
+		return history.Monitor{}, err
+	}
+	log.Printf("[Monitor] Registered monitor %d for %s every %s", m.ID, m.URL, m.Interval)
+	s.poke()
+	return m, nil
+}
+
+// Remove deletes a monitor and its runs, or returns history.ErrNotFound
+func (s *Scheduler) Remove(id int64) error {
+	if err := s.store.DeleteMonitor(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.next, id)
+	s.mu.Unlock()
+	log.Printf("[Monitor] Removed monitor %d", id)
+	s.poke()
+	return nil
+}
+
+// Status lists every monitor with up to runs of its latest runs
+func (s *Scheduler) Status(runs int) ([]Status, error) {
+	monitors, err := s.store.Monitors()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(monitors))
+	for _, m := range monitors {
+		latest, err := s.store.MonitorRuns(m.ID, runs)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, Status{
+			ID:                  m.ID,
+			URL:                 m.URL,
+			Interval:            m.Interval.String(),
+			CreatedAt:           m.CreatedAt,
+			ConsecutiveFailures: m.ConsecutiveFailures,
+			NextRun:             s.nextRun(m.ID),
+			Runs:                latest,
+		})
+	}
+	return statuses, nil
+}
@@ -0,0 +1,243 @@
+// ABOUTME: Tests for the monitor scheduler driven by a fake clock - due times, backoff, growth alerts and run pruning
+// ABOUTME: Endpoints and the alert webhook are httptest servers; history is the in-memory store
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// fakeClock is the scheduler's now, moved only by the test
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// endpoint serves series samples of a counter, or a 500 while failing is set
+type endpoint struct {
+	*httptest.Server
+	series  atomic.Int64
+	failing atomic.Bool
+	scrapes atomic.Int64
+}
+
+func newEndpoint(t *testing.T, series int) *endpoint {
+	t.Helper()
+	e := &endpoint{}
+	e.series.Store(int64(series))
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.scrapes.Add(1)
+		if e.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, "# HELP http_requests_total Requests handled.\n# TYPE http_requests_total counter")
+		for i := range e.series.Load() {
+			fmt.Fprintf(w, "http_requests_total{code=\"%d\"} 1\n", 200+i)
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+func newTestScheduler(t *testing.T, opts Options) (*Scheduler, *fakeClock, history.Store) {
+	t.Helper()
+	profile, err := rules.Profile(rules.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := history.NewMemoryStore(10)
+	s := New(store, func() rules.NamingProfile { return profile }, opts)
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s.now = clock.now
+	return s, clock, store
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{time.Minute, 0, time.Minute},
+		{time.Minute, 1, 2 * time.Minute},
+		{time.Minute, 3, 8 * time.Minute},
+		{time.Minute, 6, MaxBackoff},
+		{time.Minute, 1000, MaxBackoff},
+		// An interval past the cap is never shortened
+		{2 * time.Hour, 0, 2 * time.Hour},
+		{2 * time.Hour, 3, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.interval, tt.failures); got != tt.want {
+			t.Errorf("backoff(%s, %d) = %s, want %s", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRunDueScrapesOnlyDueMonitors(t *testing.T) {
+	s, clock, _ := newTestScheduler(t, Options{})
+	wait, err := s.runDue()
+	if err != nil || wait != MaxBackoff {
+		t.Fatalf("runDue without monitors = %s, %v, want %s", wait, err, MaxBackoff)
+	}
+
+	e := newEndpoint(t, 2)
+	if _, err := s.Register(e.URL, "", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// A new monitor is due at once
+	if wait, _ = s.runDue(); wait != time.Minute || e.scrapes.Load() != 1 {
+		t.Fatalf("first runDue waits %s after %d scrape(s), want %s after 1", wait, e.scrapes.Load(), time.Minute)
+	}
+
+	clock.advance(40 * time.Second)
+	if wait, _ = s.runDue(); wait != 20*time.Second || e.scrapes.Load() != 1 {
+		t.Errorf("runDue before the interval waits %s after %d scrape(s), want 20s and no new scrape", wait, e.scrapes.Load())
+	}
+	clock.advance(20 * time.Second)
+	if wait, _ = s.runDue(); wait != time.Minute || e.scrapes.Load() != 2 {
+		t.Errorf("runDue at the interval waits %s after %d scrape(s), want %s after 2", wait, e.scrapes.Load(), time.Minute)
+	}
+}
+
+func TestFailingEndpointBacksOff(t *testing.T) {
+	s, clock, store := newTestScheduler(t, Options{})
+	e := newEndpoint(t, 2)
+	e.failing.Store(true)
+	m, err := s.Register(e.URL, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for failures, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute} {
+		wait, _ := s.runDue()
+		if wait != want {
+			t.Errorf("after %d failure(s) runDue waits %s, want %s", failures+1, wait, want)
+		}
+		clock.advance(wait)
+	}
+	monitors, _ := store.Monitors()
+	if len(monitors) != 1 || monitors[0].ConsecutiveFailures != 3 {
+		t.Fatalf("monitors = %+v, want 3 failures in a row", monitors)
+	}
+
+	// Answering again resets the wait to the interval
+	e.failing.Store(false)
+	if wait, _ := s.runDue(); wait != time.Minute {
+		t.Errorf("runDue after recovering waits %s, want %s", wait, time.Minute)
+	}
+	monitors, _ = store.Monitors()
+	runs, _ := store.MonitorRuns(m.ID, 10)
+	if monitors[0].ConsecutiveFailures != 0 || len(runs) != 4 || runs[0].Error != "" || runs[1].Error == "" {
+		t.Errorf("after recovering: %d failures, runs %+v, want 0 and the latest run successful", monitors[0].ConsecutiveFailures, runs)
+	}
+}
+
+func TestSeriesGrowthAlerts(t *testing.T) {
+	alerts := make(chan Alert, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		json.NewDecoder(r.Body).Decode(&a)
+		alerts <- a
+	}))
+	defer webhook.Close()
+
+	s, clock, store := newTestScheduler(t, Options{WebhookURL: webhook.URL, SeriesGrowthPercent: 50})
+	e := newEndpoint(t, 4)
+	m, err := s.Register(e.URL, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.runDue()
+
+	// 4 to 6 series is exactly 50%, within the allowance
+	e.series.Store(6)
+	clock.advance(time.Minute)
+	s.runDue()
+	// 6 to 10 is 67%
+	e.series.Store(10)
+	clock.advance(time.Minute)
+	s.runDue()
+
+	// Newest first
+	runs, _ := store.MonitorRuns(m.ID, 10)
+	if len(runs) != 3 || !runs[0].Alerted || runs[1].Alerted || runs[2].Alerted {
+		t.Fatalf("runs = %+v, want the last one alone alerted", runs)
+	}
+	select {
+	case a := <-alerts:
+		if a.MonitorID != m.ID || a.SeriesBefore != 6 || a.SeriesAfter != 10 || !strings.Contains(a.Text, "series grew from 6 to 10") {
+			t.Errorf("alert = %+v, want growth from 6 to 10 series", a)
+		}
+	default:
+		t.Fatal("no alert was posted to the webhook")
+	}
+	if len(alerts) != 0 {
+		t.Errorf("%d more alert(s) posted, want 1", len(alerts))
+	}
+}
+
+func TestRunHistoryIsPruned(t *testing.T) {
+	s, clock, store := newTestScheduler(t, Options{HistorySize: 3})
+	e := newEndpoint(t, 2)
+	m, err := s.Register(e.URL, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started []time.Time
+	for range 5 {
+		started = append(started, clock.now())
+		s.runDue()
+		clock.advance(time.Minute)
+	}
+
+	runs, err := store.MonitorRuns(m.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("kept %d runs, want 3", len(runs))
+	}
+	for i, r := range runs {
+		if want := started[len(started)-1-i]; !r.StartedAt.Equal(want) {
+			t.Errorf("run %d started at %s, want the latest runs kept (%s)", i, r.StartedAt, want)
+		}
+	}
+}
+
+func TestLoopWaitsForTheNextDueMonitor(t *testing.T) {
+	s, _, _ := newTestScheduler(t, Options{})
+	e := newEndpoint(t, 2)
+	if _, err := s.Register(e.URL, "", 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	waits := make(chan time.Duration)
+	s.after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		// Never fires; the test ends with the loop blocked
+		return make(chan time.Time)
+	}
+	// Drain the wake-up from Register so the loop blocks on after
+	<-s.wake
+	s.Start()
+
+	select {
+	case d := <-waits:
+		if d != 90*time.Second || e.scrapes.Load() != 1 {
+			t.Errorf("loop waits %s after %d scrape(s), want 1m30s after 1", d, e.scrapes.Load())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the loop never waited")
+	}
+}
@@ -0,0 +1,59 @@
+// ABOUTME: Monitor alerts - the JSON posted to the webhook when an endpoint's telemetry regresses
+// ABOUTME: The text field makes the payload a valid Slack incoming webhook message as well
+
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/history"
+)
+
+// Alert reports a run that regressed from the monitor's last successful run
+type Alert struct {
+	Text             string   `json:"text"`
+	MonitorID        int64    `json:"monitor_id"`
+	URL              string   `json:"url"`
+	NewErrorFindings []string `json:"new_error_findings,omitempty"`
+	SeriesBefore     int      `json:"series_before"`
+	SeriesAfter      int      `json:"series_after"`
+}
+
+func newAlert(m history.Monitor, previous, run history.MonitorRun, added []string, grown bool) *Alert {
+	var problems []string
+	if len(added) > 0 {
+		problems = append(problems, fmt.Sprintf("%d new error finding(s): %s", len(added), strings.Join(added, ", ")))
+	}
+	if grown {
+		problems = append(problems, fmt.Sprintf("series grew from %d to %d (+%.0f%%)",
+			previous.Series, run.Series, float64(run.Series-previous.Series)/float64(previous.Series)*100))
+	}
+	return &Alert{
+		Text:             fmt.Sprintf("Telemetry regression at %s: %s", m.URL, strings.Join(problems, "; ")),
+		MonitorID:        m.ID,
+		URL:              m.URL,
+		NewErrorFindings: added,
+		SeriesBefore:     previous.Series,
+		SeriesAfter:      run.Series,
+	}
+}
+
+func post(client *http.Client, url string, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// ABOUTME: camelCase detection - finds metric names that aren't snake_case and converts them
+// ABOUTME: Catches httpRequestsTotal-style names deterministically instead of leaving them to the LLM
+
+package naming
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+var (
+	camelBoundaryRegex   = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	acronymBoundaryRegex = regexp.MustCompile(`([A-Z]+)([A-Z][a-z])`)
+)
+
+// IsCamelCase reports whether name has an uppercase letter that neither
+// starts the name nor follows an underscore
+func IsCamelCase(name string) bool {
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' && i > 0 && name[i-1] != '_' {
+			return true
+		}
+	}
+	return false
+}
+
+// ToSnakeCase splits name at camelCase and acronym boundaries and lowercases
+// it, e.g. HTTPRequestsTotal becomes http_requests_total
+func ToSnakeCase(name string) string {
+	name = acronymBoundaryRegex.ReplaceAllString(name, "${1}_${2}")
+	name = camelBoundaryRegex.ReplaceAllString(name, "${1}_${2}")
+	return strings.ToLower(name)
+}
+
+// CamelCaseIssue describes the problem with a camelCase metric name, reporting
+// false when the name isn't camelCase
+func CamelCaseIssue(name string) (string, bool) {
+	if !IsCamelCase(name) {
+		return "", false
+	}
+	return fmt.Sprintf("%s is camelCase; Prometheus names are snake_case, so rename it %s", name, ToSnakeCase(name)), true
+}
+
+// Issues lists the naming problems found without the LLM, one per metric name
+func Issues(parsed *metrics.ParsedMetrics) []string {
+	var issues []string
+	seen := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		if seen[m.Name] {
+			continue
+		}
+		seen[m.Name] = true
+		if issue, ok := CamelCaseIssue(m.Name); ok {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,71 @@
+// ABOUTME: Tests for camelCase detection and snake_case conversion of metric names
+// ABOUTME: Covers acronyms, digits, leading capitals and names that are already snake_case
+
+package naming
+
+import (
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct {
+		name  string
+		camel bool
+		snake string
+	}{
+		{"httpRequestsTotal", true, "http_requests_total"},
+		{"HTTPRequestsTotal", true, "http_requests_total"},
+		{"requestDurationSeconds", true, "request_duration_seconds"},
+		{"cpuUsage", true, "cpu_usage"},
+		{"myAPIServerRequests", true, "my_api_server_requests"},
+		{"nodeCPUSeconds", true, "node_cpu_seconds"},
+		{"http2Requests", true, "http2_requests"},
+		{"ioWaitTime", true, "io_wait_time"},
+		{"a", false, "a"},
+		{"aB", true, "a_b"},
+		{"http_requestsTotal", true, "http_requests_total"},
+		{"process_cpuSeconds_total", true, "process_cpu_seconds_total"},
+		{"getURLCount", true, "get_url_count"},
+		{"XMLParserErrors", true, "xml_parser_errors"},
+		{"userID", true, "user_id"},
+		{"s3BucketObjects", true, "s3_bucket_objects"},
+		{"dbQueryP99", true, "db_query_p99"},
+		{"queueLength", true, "queue_length"},
+		{"ABC", true, "abc"},
+		{"http_requests_total", false, "http_requests_total"},
+		{"node_cpu_seconds_total", false, "node_cpu_seconds_total"},
+		{"up", false, "up"},
+		{"go_gc_duration_seconds", false, "go_gc_duration_seconds"},
+		{"Up", false, "up"},
+		{"Http_requests", false, "http_requests"},
+		{"http_Requests", false, "http_requests"},
+		{"process_open_fds", false, "process_open_fds"},
+		{"http2_requests", false, "http2_requests"},
+		{"_private", false, "_private"},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCamelCase(tt.name); got != tt.camel {
+				t.Errorf("IsCamelCase(%q) = %v, want %v", tt.name, got, tt.camel)
+			}
+			if got := ToSnakeCase(tt.name); got != tt.snake {
+				t.Errorf("ToSnakeCase(%q) = %q, want %q", tt.name, got, tt.snake)
+			}
+		})
+	}
+}
+
+func TestIssuesReportEachCamelCaseNameOnce(t *testing.T) {
+	parsed, err := metrics.Parse("httpRequestsTotal{code=\"200\"} 1\nhttpRequestsTotal{code=\"500\"} 1\nup 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := Issues(parsed)
+	want := "httpRequestsTotal is camelCase; Prometheus names are snake_case, so rename it http_requests_total"
+	if len(issues) != 1 || issues[0] != want {
+		t.Errorf("issues = %q, want [%q]", issues, want)
+	}
+}
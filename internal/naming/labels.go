@@ -0,0 +1,108 @@
+// ABOUTME: Label suggestions - the dimensions well-known metric families are usually split by
+// ABOUTME: Looks up http_, db_ and grpc_ prefixes and reports the conventional labels a metric lacks
+
+package naming
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+type expectedLabel struct {
+	name string
+	// Other names for the same dimension; having any of them counts
+	aliases []string
+	reason  string
+}
+
+type labelPattern struct {
+	prefix string
+	labels []expectedLabel
+}
+
+var labelPatterns = []labelPattern{
+	{prefix: "http_", labels: []expectedLabel{
+		{name: "method", reason: "HTTP traffic is usually split by request method"},
+		{name: "status", aliases: []string{"code", "status_code"}, reason: "errors can't be told from successes without the response status"},
+		{name: "endpoint", aliases: []string{"handler", "route", "path"}, reason: "a busy or failing endpoint can't be singled out without a route label (use the route template, not the raw URL)"},
+	}},
+	{prefix: "db_", labels: []expectedLabel{
+		{name: "operation", aliases: []string{"op", "query_type"}, reason: "reads and writes behave differently and are usually split by operation"},
+		{name: "table", aliases: []string{"collection"}, reason: "a slow or hot table can't be singled out without a table label"},
+	}},
+	{prefix: "grpc_", labels: []expectedLabel{
+		{name: "grpc_method", reason: "gRPC metrics are conventionally split by method"},
+		{name: "grpc_service", reason: "gRPC metrics are conventionally split by service"},
+		{name: "grpc_code", reason: "failed calls can't be told apart without the status code"},
+	}},
+}
+
+// SuggestMissingLabels lists the conventional labels for m's name prefix that
+// m doesn't carry under any of their usual names
+func SuggestMissingLabels(m metrics.Metric) []metrics.LabelSuggestion {
+	var suggestions []metrics.LabelSuggestion
+	for _, p := range labelPatterns {
+		if !strings.HasPrefix(m.Name, p.prefix) {
+			continue
+		}
+		for _, l := range p.labels {
+			if !hasAny(m.Labels, append([]string{l.name}, l.aliases...)) {
+				suggestions = append(suggestions, metrics.LabelSuggestion{
+					Metric: m.Name,
+					Label:  l.name,
+					Reason: l.reason,
+				})
+			}
+		}
+	}
+	return suggestions
+}
+
+// Suggest collects suggestions across a submission, once per family and label,
+// and only where no sample of the family has the label. Histogram and summary
+// series are grouped under their family name.
+func Suggest(parsed *metrics.ParsedMetrics) []metrics.LabelSuggestion {
+	var families []string
+	byFamily := make(map[string][]metrics.LabelSuggestion)
+	for _, m := range parsed.Metrics {
+		m.Name = familyOf(m.Name)
+		suggestions := SuggestMissingLabels(m)
+		existing, seen := byFamily[m.Name]
+		if !seen {
+			families = append(families, m.Name)
+			byFamily[m.Name] = suggestions
+			continue
+		}
+		// Keep only labels every sample lacks
+		byFamily[m.Name] = slices.DeleteFunc(existing, func(s metrics.LabelSuggestion) bool {
+			return !slices.ContainsFunc(suggestions, func(o metrics.LabelSuggestion) bool { return o.Label == s.Label })
+		})
+	}
+
+	var all []metrics.LabelSuggestion
+	for _, family := range families {
+		all = append(all, byFamily[family]...)
+	}
+	return all
+}
+
+// familyOf strips the series suffixes histograms, summaries and counters expose
+func familyOf(name string) string {
+	for _, s := range []string{"_bucket", "_sum", "_count", "_created"} {
+		if base := strings.TrimSuffix(name, s); base != name {
+			return base
+		}
+	}
+	return name
+}
+
+func hasAny(labels map[string]string, names []string) bool {
+	for _, name := range names {
+		if _, ok := labels[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+// ABOUTME: Standard runtime metrics - the go_, process_, promhttp_, python_gc_ and jvm_ families client libraries expose
+// ABOUTME: Separates them from a submission so findings and the LLM prompt cover only the metrics the user owns
+
+package naming
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// RuntimePrefixes are the name prefixes of metrics client libraries register on their own
+var RuntimePrefixes = []string{"go_", "process_", "promhttp_", "python_gc_", "jvm_"}
+
+// IsRuntimeMetric reports whether name belongs to a client library's standard metrics
+func IsRuntimeMetric(name string) bool {
+	return slices.ContainsFunc(RuntimePrefixes, func(prefix string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+// ExcludeRuntime returns parsed without its standard runtime metrics, and
+// their names. The cardinality analysis and memory breakdown still cover
+// every sample, since the runtime series are stored all the same. When
+// nothing but runtime metrics was submitted, parsed is returned unchanged.
+func ExcludeRuntime(parsed *metrics.ParsedMetrics) (*metrics.ParsedMetrics, []string) {
+	var kept []metrics.Metric
+	var excluded []string
+	for _, m := range parsed.Metrics {
+		if !IsRuntimeMetric(m.Name) {
+			kept = append(kept, m)
+		} else if !slices.Contains(excluded, m.Name) {
+			excluded = append(excluded, m.Name)
+		}
+	}
+	if len(excluded) == 0 || len(kept) == 0 {
+		return parsed, nil
+	}
+
+	evaluated := *parsed
+	evaluated.Metrics = kept
+	evaluated.LabelSuggestions = Suggest(&evaluated)
+	evaluated.NamingIssues = Issues(&evaluated)
+	return &evaluated, excluded
+}
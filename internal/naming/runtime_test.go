@@ -0,0 +1,81 @@
+// ABOUTME: Tests for separating standard runtime metrics - they leave the evaluated set but not the cardinality totals
+// ABOUTME: A submission of nothing but runtime metrics is evaluated as it is
+
+package naming
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+const scrapeWithRuntime = `# TYPE go_goroutines gauge
+go_goroutines 42
+# TYPE go_gc_duration_seconds summary
+go_gc_duration_seconds{quantile="0.5"} 0.001
+go_gc_duration_seconds_sum 0.2
+go_gc_duration_seconds_count 100
+process_cpu_seconds_total 12.5
+promhttp_metric_handler_requests_total{code="200"} 10
+promhttp_metric_handler_requests_total{code="500"} 0
+# TYPE checkoutRequests counter
+checkoutRequests{method="GET"} 3
+checkoutRequests{method="POST"} 1
+`
+
+func TestIsRuntimeMetric(t *testing.T) {
+	for name, want := range map[string]bool{
+		"go_goroutines":                 true,
+		"process_cpu_seconds_total":     true,
+		"promhttp_metric_handler_total": true,
+		"python_gc_objects_collected":   true,
+		"jvm_memory_bytes_used":         true,
+		"golang_requests_total":         false,
+		"my_process_jobs":               false,
+		"http_requests_total":           false,
+	} {
+		if got := IsRuntimeMetric(name); got != want {
+			t.Errorf("IsRuntimeMetric(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExcludeRuntime(t *testing.T) {
+	parsed, err := metrics.Parse(scrapeWithRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluated, excluded := ExcludeRuntime(parsed)
+
+	want := []string{"go_goroutines", "go_gc_duration_seconds", "go_gc_duration_seconds_sum", "go_gc_duration_seconds_count", "process_cpu_seconds_total", "promhttp_metric_handler_requests_total"}
+	if !slices.Equal(excluded, want) {
+		t.Errorf("excluded = %v, want %v", excluded, want)
+	}
+	for _, m := range evaluated.Metrics {
+		if m.Name != "checkoutRequests" {
+			t.Errorf("%s was kept", m.Name)
+		}
+	}
+	if len(evaluated.Metrics) != 2 {
+		t.Errorf("kept %d samples, want checkoutRequests' 2", len(evaluated.Metrics))
+	}
+	if len(evaluated.NamingIssues) != 1 || len(parsed.Metrics) != 9 {
+		t.Errorf("naming issues = %v, and the original has %d samples, want the issues redone and the original left alone", evaluated.NamingIssues, len(parsed.Metrics))
+	}
+
+	// Runtime series are stored all the same
+	if evaluated.CardinalityAnalysis != parsed.CardinalityAnalysis || !slices.Equal(evaluated.MemoryBreakdown, parsed.MemoryBreakdown) {
+		t.Error("the cardinality totals no longer cover the runtime series")
+	}
+}
+
+func TestExcludeRuntimeKeepsRuntimeOnlySubmissions(t *testing.T) {
+	parsed, err := metrics.Parse("go_goroutines 42\nprocess_open_fds 9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluated, excluded := ExcludeRuntime(parsed); evaluated != parsed || excluded != nil {
+		t.Errorf("ExcludeRuntime = %d samples, %v, want the submission unchanged", len(evaluated.Metrics), excluded)
+	}
+}
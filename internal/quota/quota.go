@@ -0,0 +1,184 @@
+// ABOUTME: Per-API-key evaluation quotas - hourly and daily request limits chosen by key pattern
+// ABOUTME: Counts live in a shared store such as Redis, falling back to this process when it is unreachable
+
+package quota
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QuotaStore counts requests per key within fixed windows
+type QuotaStore interface {
+	// Increment adds one to key and returns the count, expiring the key after
+	// window so each window starts from zero
+	Increment(key string, window time.Duration) (count int, err error)
+}
+
+// QuotaPolicy limits the API keys matching Pattern, a glob such as "pro_*".
+// A zero limit leaves that window unlimited.
+type QuotaPolicy struct {
+	Pattern         string `yaml:"pattern"`
+	RequestsPerHour int    `yaml:"requests_per_hour"`
+	RequestsPerDay  int    `yaml:"requests_per_day"`
+}
+
+// Validate rejects malformed patterns and negative limits
+func (p QuotaPolicy) Validate() error {
+	if _, err := path.Match(p.Pattern, ""); err != nil || p.Pattern == "" {
+		return fmt.Errorf("quota pattern %q is not a valid glob", p.Pattern)
+	}
+	if p.RequestsPerHour < 0 || p.RequestsPerDay < 0 {
+		return fmt.Errorf("quota for %q has a negative limit", p.Pattern)
+	}
+	return nil
+}
+
+// Decision is the outcome of counting one request
+type Decision struct {
+	Allowed bool
+	// Requests left in the tighter window, and when that window ends
+	Remaining int
+	Reset     time.Time
+}
+
+// Limiter applies the first matching policy to each API key
+type Limiter struct {
+	store    QuotaStore
+	fallback *MemoryStore
+
+	mu       sync.RWMutex
+	policies []QuotaPolicy
+	// Whether the last store call failed, so only changes are logged
+	degraded bool
+}
+
+// NewLimiter counts in store, or only in this process when store is nil
+func NewLimiter(store QuotaStore, policies []QuotaPolicy) *Limiter {
+	l := &Limiter{store: store, fallback: NewMemoryStore()}
+	l.SetPolicies(policies)
+	return l
+}
+
+// SetPolicies replaces the policies, e.g. on config reload
+func (l *Limiter) SetPolicies(policies []QuotaPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policies = policies
+}
+
+func (l *Limiter) policy(apiKey string) (QuotaPolicy, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, p := range l.policies {
+		if ok, _ := path.Match(p.Pattern, apiKey); ok {
+			return p, true
+		}
+	}
+	return QuotaPolicy{}, false
+}
+
+// Allow counts a request for apiKey. ok is false when no policy matches, and
+// the request isn't limited.
+func (l *Limiter) Allow(apiKey string, now time.Time) (d Decision, ok bool) {
+	p, ok := l.policy(apiKey)
+	if !ok {
+		return Decision{}, false
+	}
+
+	// The raw key never leaves the process
+	sum := sha256.Sum256([]byte(apiKey))
+	id := hex.EncodeToString(sum[:8])
+
+	d = Decision{Allowed: true, Remaining: -1}
+	for _, w := range []struct {
+		name   string
+		length time.Duration
+		limit  int
+	}{
+		{"hour", time.Hour, p.RequestsPerHour},
+		{"day", 24 * time.Hour, p.RequestsPerDay},
+	} {
+		if w.limit == 0 {
+			continue
+		}
+		start := now.Truncate(w.length)
+		count := l.increment("goodtelemetry:quota:"+id+":"+w.name+":"+strconv.FormatInt(start.Unix(), 10), w.length)
+		remaining := max(w.limit-count, 0)
+		if count > w.limit {
+			d.Allowed = false
+		}
+		// Report the tighter window; on a tie the later reset is when requests are allowed again
+		if d.Remaining < 0 || remaining <= d.Remaining {
+			d.Remaining = remaining
+			d.Reset = start.Add(w.length)
+		}
+	}
+	if d.Remaining < 0 {
+		// Both windows unlimited
+		return Decision{Allowed: true}, false
+	}
+	return d, true
+}
+
+func (l *Limiter) increment(key string, window time.Duration) int {
+	if l.store != nil {
+		count, err := l.store.Increment(key, window)
+		l.mu.Lock()
+		wasDegraded := l.degraded
+		l.degraded = err != nil
+		l.mu.Unlock()
+		if err == nil {
+			if wasDegraded {
+				log.Printf("[Quota] Store reachable again, counting there")
+			}
+			return count
+		}
+		if !wasDegraded {
+			log.Printf("[Quota] Store unavailable, counting in this process: %v", err)
+		}
+	}
+	count, _ := l.fallback.Increment(key, window)
+	return count
+}
+
+// MemoryStore counts in this process only
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	count   int
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Increment(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e.expires = now.Add(window)
+	}
+	e.count++
+	s.entries[key] = e
+	return e.count, nil
+}
@@ -0,0 +1,159 @@
+// ABOUTME: Redis quota store - INCR and EXPIRE over a single connection speaking RESP
+// ABOUTME: Reconnects on the next call after any error, so a Redis restart heals without a server restart
+
+package quota
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisTimeout = 2 * time.Second
+
+// RedisStore counts in Redis, shared by every server replica
+type RedisStore struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedisStore reads a redis:// or rediss:// URL such as
+// redis://:password@localhost:6379/0. Nothing is dialled until first use.
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("REDIS_URL: scheme must be redis or rediss, got %q", u.Scheme)
+	}
+
+	s := &RedisStore{addr: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("REDIS_URL: database %q is not a number", db)
+		}
+	}
+	return s, nil
+}
+
+func (s *RedisStore) Increment(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, err := s.increment(key, window)
+	if err != nil && s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return count, err
+}
+
+func (s *RedisStore) increment(key string, window time.Duration) (int, error) {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return 0, err
+	}
+
+	count, err := s.do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	// Only the first request of a window sets the expiry, so it isn't pushed back by later ones
+	if count == 1 {
+		if _, err := s.do("EXPIRE", key, strconv.Itoa(int(window.Seconds()))); err != nil {
+			return 0, err
+		}
+	}
+	return int(count), nil
+}
+
+func (s *RedisStore) connect() error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if s.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn, s.rd = conn, bufio.NewReader(conn)
+	if err := conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return err
+	}
+
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err := s.do(args...); err != nil {
+			return err
+		}
+	}
+	if s.db != 0 {
+		if _, err := s.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a command and reads its reply, returning integer replies as the
+// count and treating simple strings as success
+func (s *RedisStore) do(args ...string) (int64, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := s.conn.Write([]byte(sb.String())); err != nil {
+		return 0, err
+	}
+
+	line, err := s.rd.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return 0, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return 0, nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '-':
+		return 0, fmt.Errorf("redis: %s", line[1:])
+	}
+	return 0, fmt.Errorf("redis: unexpected reply to %s: %q", args[0], line)
+}
@@ -0,0 +1,263 @@
+// ABOUTME: Tests for the Redis quota store against a fake RESP server - INCR, EXPIRE, AUTH and SELECT on the wire
+// ABOUTME: A dropped connection heals on the next call, and a Limiter counts in-process while Redis is down
+
+package quota
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis speaks enough RESP for RedisStore: AUTH, SELECT, INCR and EXPIRE
+type fakeRedis struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	counts   map[string]int
+	commands [][]string
+	conns    []net.Conn
+	dials    int
+	// While down, connections are closed as soon as they arrive
+	down bool
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, counts: make(map[string]int)}
+	t.Cleanup(func() {
+		ln.Close()
+		f.drop()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.dials++
+			if f.down {
+				f.mu.Unlock()
+				conn.Close()
+				continue
+			}
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url(userinfo, db string) string {
+	return "redis://" + userinfo + f.ln.Addr().String() + db
+}
+
+// drop closes every open connection, as a Redis restart would
+func (f *fakeRedis) drop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.conns {
+		c.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeRedis) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+	if down {
+		f.drop()
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	rd := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH", "SELECT":
+			reply = "+OK\r\n"
+		case "INCR":
+			f.counts[args[1]]++
+			reply = fmt.Sprintf(":%d\r\n", f.counts[args[1]])
+		case "EXPIRE":
+			reply = ":1\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("not an array: %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) sent() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.commands)
+}
+
+func (f *fakeRedis) dialCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dials
+}
+
+func TestNewRedisStore(t *testing.T) {
+	type conn struct {
+		addr, username, password string
+		tls                      bool
+		db                       int
+	}
+	tests := []struct {
+		url     string
+		want    conn
+		wantErr bool
+	}{
+		{url: "redis://localhost", want: conn{addr: "localhost:6379"}},
+		{url: "redis://:secret@cache:6380/2", want: conn{addr: "cache:6380", password: "secret", db: 2}},
+		{url: "rediss://user:pw@cache:6380", want: conn{addr: "cache:6380", username: "user", password: "pw", tls: true}},
+		{url: "http://localhost", wantErr: true},
+		{url: "redis://localhost/zero", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			s, err := NewRedisStore(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := (conn{s.addr, s.username, s.password, s.useTLS, s.db}); got != tt.want {
+				t.Errorf("store = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisStoreIncrement(t *testing.T) {
+	f := newFakeRedis(t)
+	s, err := NewRedisStore(f.url("user:pw@", "/3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		count, err := s.Increment("k", time.Hour)
+		if err != nil || count != want {
+			t.Fatalf("Increment = %d, %v, want %d", count, err, want)
+		}
+	}
+
+	want := [][]string{
+		{"AUTH", "user", "pw"},
+		{"SELECT", "3"},
+		{"INCR", "k"},
+		// Only the window's first request sets the expiry
+		{"EXPIRE", "k", "3600"},
+		{"INCR", "k"},
+		{"INCR", "k"},
+	}
+	if got := f.sent(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if f.dialCount() != 1 {
+		t.Errorf("dialled %d times, want one connection reused", f.dialCount())
+	}
+}
+
+func TestRedisStoreReconnectsAfterAnError(t *testing.T) {
+	f := newFakeRedis(t)
+	s, err := NewRedisStore(f.url("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Increment("k", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	f.drop()
+	if _, err := s.Increment("k", time.Minute); err == nil {
+		t.Fatal("Increment on a dropped connection succeeded")
+	}
+	count, err := s.Increment("k", time.Minute)
+	if err != nil || count != 2 {
+		t.Fatalf("Increment after reconnecting = %d, %v, want 2", count, err)
+	}
+	if f.dialCount() != 2 {
+		t.Errorf("dialled %d times, want 2", f.dialCount())
+	}
+}
+
+func TestLimiterFallsBackWhileRedisIsDown(t *testing.T) {
+	f := newFakeRedis(t)
+	s, err := NewRedisStore(f.url("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLimiter(s, []QuotaPolicy{{Pattern: "free_*", RequestsPerHour: 2}})
+	now := time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	if d, _ := l.Allow("free_a", now); !d.Allowed || d.Remaining != 1 {
+		t.Fatalf("first request = %+v", d)
+	}
+
+	f.setDown(true)
+	// Counting restarts in this process, which still enforces the limit
+	for i, want := range []bool{true, true, false} {
+		if d, ok := l.Allow("free_a", now); !ok || d.Allowed != want {
+			t.Errorf("request %d while down = %+v, %v, want allowed %v", i+1, d, ok, want)
+		}
+	}
+
+	f.setDown(false)
+	if d, _ := l.Allow("free_a", now); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("request once Redis is back = %+v, want Redis's count of 2", d)
+	}
+}
@@ -0,0 +1,56 @@
+// ABOUTME: Label allowlist - for organizations that define their whole label vocabulary centrally
+// ABOUTME: Flags every label name outside the profile's approved list, and builds a list from existing metrics
+
+package rules
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Labels the exposition format itself adds to histograms and summaries
+var exporterLabels = []string{"le", "quantile"}
+
+// checkAllowedLabels flags label names missing from allowed, once each, on
+// the first metric carrying them. An empty list allows every label.
+func checkAllowedLabels(profile string, allowed []string, parsed *metrics.ParsedMetrics) []Finding {
+	if len(allowed) == 0 {
+		return nil
+	}
+	var findings []Finding
+	flagged := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		for _, label := range sortedKeys(m.Labels) {
+			if flagged[label] || slices.Contains(allowed, label) || slices.Contains(exporterLabels, label) {
+				continue
+			}
+			flagged[label] = true
+			findings = append(findings, Finding{
+				Code:     "label-not-allowed",
+				Severity: SeverityWarning,
+				Metric:   m.Name,
+				Message:  fmt.Sprintf("Label '%s' is not in the approved label allowlist for profile '%s'.", label, profile),
+			})
+		}
+	}
+	return findings
+}
+
+// LabelNames lists the label names used across parsed, sorted, as a starting
+// allowlist. Labels the exposition format adds are left out.
+func LabelNames(parsed ...*metrics.ParsedMetrics) []string {
+	var names []string
+	for _, p := range parsed {
+		for _, m := range p.Metrics {
+			for label := range m.Labels {
+				if !slices.Contains(names, label) && !slices.Contains(exporterLabels, label) {
+					names = append(names, label)
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,112 @@
+// ABOUTME: Datadog rules - key:value tag format and the custom metric count Datadog bills for
+// ABOUTME: Used by the datadog naming profile alongside its dot.separated lowercase name check
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/formats"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Custom metrics per host included in Datadog's Pro plan
+const datadogIncludedCustomMetrics = 100
+
+// Datadog tag keys start with a letter and are lowercase
+var datadogTagKey = regexp.MustCompile(`^[a-z][a-z0-9_.\-/]*$`)
+
+// parseDatadog reads DogStatsD lines as written, and anything else as exposition text
+func parseDatadog(input string) (*metrics.ParsedMetrics, error) {
+	if f, err := formats.Detect(input); err == nil && (f == formats.DogStatsD || f == formats.StatsD) {
+		return formats.ParseDogStatsD(input)
+	}
+	return metrics.Parse(input)
+}
+
+func checkDatadogTags(parsed *metrics.ParsedMetrics) []Finding {
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, m := range parsed.Metrics {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if m.Labels[key] == "" {
+				findings = append(findings, Finding{
+					Code:     "tag-format",
+					Severity: SeverityWarning,
+					Metric:   m.Name,
+					Message: fmt.Sprintf("Tag %q has no value; Datadog tags should be colon-delimited key:value pairs (env:prod) so they can be grouped by key",
+						key),
+				})
+			}
+			if !datadogTagKey.MatchString(key) {
+				findings = append(findings, Finding{
+					Code:     "tag-format",
+					Severity: SeverityWarning,
+					Metric:   m.Name,
+					Message:  fmt.Sprintf("Tag key %q should start with a letter and be lowercase for Datadog (%s)", key, strings.ToLower(key)),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkCustomMetrics counts what Datadog would bill: every unique combination
+// of metric name and tag values is a separate custom metric
+func checkCustomMetrics(parsed *metrics.ParsedMetrics) []Finding {
+	observed := make(map[string]bool)
+	tagValues := make(map[string]map[string]map[string]bool)
+	for _, m := range parsed.Metrics {
+		observed[metrics.FormatSample(metrics.Metric{Name: m.Name, Labels: m.Labels})] = true
+
+		if tagValues[m.Name] == nil {
+			tagValues[m.Name] = make(map[string]map[string]bool)
+		}
+		for k, v := range m.Labels {
+			if tagValues[m.Name][k] == nil {
+				tagValues[m.Name][k] = make(map[string]bool)
+			}
+			tagValues[m.Name][k][v] = true
+		}
+	}
+
+	// Each tag's values can combine with every other tag's values
+	possible := 0
+	for _, tags := range tagValues {
+		combinations := 1
+		for _, values := range tags {
+			combinations *= len(values)
+		}
+		possible += combinations
+	}
+
+	if possible > datadogIncludedCustomMetrics {
+		return []Finding{{
+			Code:     "custom-metrics",
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("Tag values seen here combine into up to %d custom metrics, more than the %d per host included in Datadog Pro; every unique metric name and tag combination is billed",
+				possible, datadogIncludedCustomMetrics),
+		}}
+	}
+	return []Finding{{
+		Code:     "custom-metrics",
+		Severity: SeverityInfo,
+		Message: fmt.Sprintf("%d custom metrics observed (up to %d as tag values combine); Datadog bills every unique metric name and tag combination",
+			len(observed), possible),
+	}}
+}
@@ -0,0 +1,65 @@
+// ABOUTME: Exemplar rules - judges the label sets OpenMetrics exemplars attach to samples
+// ABOUTME: Trace IDs belong there rather than in metric labels; the spec caps each set at 128 characters
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// OpenMetrics limit on the combined length of an exemplar's label names and values
+const maxExemplarLabelRunes = 128
+
+func checkExemplarLabels(parsed *metrics.ParsedMetrics) []Finding {
+	var sets []map[string]string
+	var findings []Finding
+	tooLong := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		if m.ExemplarLabels == nil {
+			continue
+		}
+		sets = append(sets, m.ExemplarLabels)
+
+		length := 0
+		for name, value := range m.ExemplarLabels {
+			length += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+		}
+		if length > maxExemplarLabelRunes && !tooLong[m.Name] {
+			tooLong[m.Name] = true
+			findings = append(findings, Finding{
+				Code:     "exemplar-too-long",
+				Severity: SeverityWarning,
+				Metric:   m.Name,
+				Message: fmt.Sprintf("%s has an exemplar whose labels total %d characters; OpenMetrics allows %d, so scrapers may reject it. Keep exemplars to a trace or span ID",
+					m.Name, length, maxExemplarLabelRunes),
+			})
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	// The labels that would be unbounded on a series are the ones exemplars are for
+	var names []string
+	for name, info := range cardinality.Analyze(sets).LabelAnalysis {
+		if info.IsHighCardinality {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		findings = append(findings, Finding{
+			Code:     "exemplar-labels",
+			Severity: SeverityPraise,
+			Message: fmt.Sprintf("High-cardinality labels (%s) are correctly placed in exemplars, not metric labels - good practice: exemplars are stored apart from the series and add none",
+				strings.Join(names, ", ")),
+		})
+	}
+	return findings
+}
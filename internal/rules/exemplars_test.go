@@ -0,0 +1,68 @@
+// ABOUTME: Tests for the exemplar rules - praise for trace IDs kept in exemplars, warnings for oversized label sets
+// ABOUTME: Bounded exemplar labels and samples without exemplars raise nothing
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestCheckExemplarLabels(t *testing.T) {
+	long := strings.Repeat("a", 130)
+
+	tests := []struct {
+		name  string
+		input string
+		// Codes of the findings, in order, and text each message must hold
+		wantCodes []string
+		want      []string
+	}{
+		{name: "trace ids in exemplars", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET"} 7 # {trace_id="4bf92f3577b34da6"} 1
+http_requests_total{method="POST"} 2 # {trace_id="00f067aa0ba902b7"} 1
+`, wantCodes: []string{"exemplar-labels"}, want: []string{"High-cardinality labels (trace_id) are correctly placed in exemplars"}},
+		{name: "bounded exemplar labels", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET"} 7 # {region="eu"} 1
+`},
+		{name: "no exemplars", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET"} 7
+`},
+		{name: "oversized exemplar warned once per metric", input: `# TYPE jobs_total counter
+jobs_total{queue="a"} 1 # {note="` + long + `"} 1
+jobs_total{queue="b"} 1 # {note="` + long + `"} 1
+`, wantCodes: []string{"exemplar-too-long"}, want: []string{"jobs_total has an exemplar whose labels total 134 characters; OpenMetrics allows 128"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings := checkExemplarLabels(parsed)
+			var codes []string
+			for _, f := range findings {
+				codes = append(codes, f.Code)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.wantCodes, ",") {
+				t.Fatalf("codes = %v, want %v", codes, tt.wantCodes)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(findings[i].Message, want) {
+					t.Errorf("message = %q, want it to hold %q", findings[i].Message, want)
+				}
+			}
+			for _, f := range findings {
+				wantSeverity := SeverityWarning
+				if f.Code == "exemplar-labels" {
+					wantSeverity = SeverityPraise
+				}
+				if f.Severity != wantSeverity {
+					t.Errorf("%s severity = %s, want %s", f.Code, f.Severity, wantSeverity)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// ABOUTME: Tests for profile fingerprints - every setting that changes a verdict changes the fingerprint
+// ABOUTME: Equal settings give equal fingerprints, so reverting a change matches results cached before it
+
+package rules
+
+import "testing"
+
+func TestFingerprintFollowsSettings(t *testing.T) {
+	base, err := Profile(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _ := Profile(DefaultProfile)
+	if base.Fingerprint() != same.Fingerprint() {
+		t.Fatal("two default profiles have different fingerprints")
+	}
+
+	threshold := base
+	threshold.Thresholds.SeriesHigh++
+	allowed := base
+	allowed.AllowedLabels = []string{"job"}
+	changes := map[string]NamingProfile{
+		"threshold":       threshold,
+		"value estimates": base.WithValueEstimates(map[string]int{"handler": 500}),
+		"lexicon":         base.WithLexicon(Lexicon{Forbidden: []string{"zanzibar"}}),
+		"renames":         base.WithRenames(map[string]string{"jobs": "jobs_total"}),
+		"allowed labels":  allowed,
+	}
+	for name, changed := range changes {
+		if changed.Fingerprint() == base.Fingerprint() {
+			t.Errorf("changing the %s leaves the fingerprint alone", name)
+		}
+	}
+}
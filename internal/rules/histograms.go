@@ -0,0 +1,71 @@
+// ABOUTME: Histogram bucket rules - report each bucket that breaks the le structure histogram_quantile relies on
+// ABOUTME: Hand-rolled expositions miss +Inf, list le out of order, let counts fall or use impossible negative bounds
+
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// What a broken bucket does to queries, appended to every finding
+const histogramImpact = "histogram_quantile() over it returns wrong quantiles or NaN"
+
+func checkHistogramBuckets(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, s := range parsed.Histograms() {
+		if s.MissingInf {
+			findings = append(findings, Finding{
+				Code:     "histogram-missing-inf",
+				Severity: SeverityError,
+				Metric:   s.Family,
+				Message: fmt.Sprintf(`%s has no le="+Inf" bucket; every histogram needs one counting all observations, equal to %s_count, or %s`,
+					s, s.Family, histogramImpact),
+			})
+		}
+		findings = append(findings, bucketFindings(s)...)
+	}
+	return findings
+}
+
+// bucketFindings reports each kind of violation once per series, naming every
+// bucket that breaks it
+func bucketFindings(s metrics.HistogramSeries) []Finding {
+	offenders := make(map[metrics.BucketViolation][]string)
+	for i, b := range s.Buckets {
+		for _, v := range b.Violations {
+			detail := fmt.Sprintf(`le="%s"`, b.LE)
+			switch v {
+			case metrics.BucketUnsorted:
+				detail += fmt.Sprintf(` after le="%s"`, s.Buckets[i-1].LE)
+			case metrics.BucketNonCumulative:
+				detail += fmt.Sprintf(" with %s", strconv.FormatFloat(b.Count, 'g', -1, 64))
+			}
+			offenders[v] = append(offenders[v], detail)
+		}
+	}
+
+	var findings []Finding
+	add := func(v metrics.BucketViolation, code string, severity Severity, problem, rule string) {
+		if buckets := offenders[v]; len(buckets) > 0 {
+			findings = append(findings, Finding{
+				Code:     code,
+				Severity: severity,
+				Metric:   s.Family,
+				Message:  fmt.Sprintf("%s has %s at %s (%s); %s", s, problem, strings.Join(buckets, ", "), rule, histogramImpact),
+			})
+		}
+	}
+	add(metrics.BucketInvalidLE, "histogram-invalid-le", SeverityError,
+		"le values that aren't numbers", "le must be a float or +Inf")
+	add(metrics.BucketNonCumulative, "histogram-non-cumulative", SeverityError,
+		"counts that fall as le rises", "buckets are cumulative, each counting every observation up to its le")
+	add(metrics.BucketUnsorted, "histogram-unsorted", SeverityWarning,
+		"buckets out of order or repeated", "each le must appear once, in increasing order")
+	add(metrics.BucketNegative, "histogram-negative-bucket", SeverityWarning,
+		"negative bounds", fmt.Sprintf("%s can't be negative, so these buckets stay empty", s.Family))
+	return findings
+}
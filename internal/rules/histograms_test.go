@@ -0,0 +1,62 @@
+// ABOUTME: Tests for the histogram bucket rules over examples/histograms, one fixture per broken invariant and a clean one
+// ABOUTME: Each fixture expects its one finding, naming the offending buckets
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestHistogramBucketViolations(t *testing.T) {
+	tests := []struct {
+		fixture string
+		// Code and severity of the one finding, and text its message must hold;
+		// an empty code expects no finding
+		code     string
+		severity Severity
+		detail   string
+	}{
+		{fixture: "clean.prom"},
+		{"missing_inf.prom", "histogram-missing-inf", SeverityError, `has no le="+Inf" bucket`},
+		{"invalid_le.prom", "histogram-invalid-le", SeverityError, `le values that aren't numbers at le="0,1"`},
+		{"unsorted_le.prom", "histogram-unsorted", SeverityWarning, `buckets out of order or repeated at le="0.1" after le="0.5"`},
+		{"non_cumulative.prom", "histogram-non-cumulative", SeverityError,
+			`counts that fall as le rises at le="0.1" with 9390, le="0.5" with 28997, le="1" with 4599, le="+Inf" with 10332`},
+		{"negative_bucket.prom", "histogram-negative-bucket", SeverityWarning, `negative bounds at le="-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("..", "..", "examples", "histograms", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := metrics.Parse(string(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings := checkHistogramBuckets(parsed)
+			if tt.code == "" {
+				if len(findings) > 0 {
+					t.Errorf("findings = %+v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %+v, want one %s", findings, tt.code)
+			}
+			f := findings[0]
+			if f.Code != tt.code || f.Severity != tt.severity || f.Metric != "http_request_duration_seconds" {
+				t.Errorf("finding = %s %s on %s, want %s %s on http_request_duration_seconds", f.Severity, f.Code, f.Metric, tt.severity, tt.code)
+			}
+			if !strings.Contains(f.Message, `http_request_duration_seconds{method="GET"}`) ||
+				!strings.Contains(f.Message, tt.detail) || !strings.Contains(f.Message, histogramImpact) {
+				t.Errorf("message = %q, want the series, %q and the impact", f.Message, tt.detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// ABOUTME: Counter initialization rule - label combinations a counter is missing until their first event
+// ABOUTME: rate() can't see the 0→1 increase of a series that appears at 1, so known combinations start at 0
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+const (
+	// Combinations beyond this are too many to pre-initialize, or to check
+	maxInitCombinations = 1000
+	// Missing combinations listed in a finding
+	maxListedCombinations = 5
+)
+
+// Labels whose values come from a small known set
+var enumerableLabels = map[string]bool{
+	"method":       true,
+	"status_class": true,
+	"code_class":   true,
+	"outcome":      true,
+	"result":       true,
+	"success":      true,
+}
+
+// Values that mark a label as enumerable whatever it is called: HTTP methods and status classes
+var enumerableValue = regexp.MustCompile(`^(GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS|CONNECT|TRACE|[1-5]xx)$`)
+
+// checkCounterInitialization flags counters split by enumerable labels that
+// expose only some combinations of the values they were seen with
+func checkCounterInitialization(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		if strings.HasSuffix(name, "_created") || parsed.TypeOf(name) != "counter" {
+			continue
+		}
+
+		var samples []metrics.Metric
+		for _, m := range parsed.Metrics {
+			if m.Name == name {
+				samples = append(samples, m)
+			}
+		}
+		labels, values := enumerableValues(parsed, samples)
+		if len(labels) < 2 {
+			// One label's missing values can't be told from the samples
+			continue
+		}
+
+		total := 1
+		for _, label := range labels {
+			total *= len(values[label])
+			if total > maxInitCombinations {
+				break
+			}
+		}
+		if total > maxInitCombinations {
+			continue
+		}
+
+		present := make(map[string]bool)
+		for _, m := range samples {
+			present[comboKey(labels, m.Labels)] = true
+		}
+		missing := missingCombinations(labels, values, present)
+		if len(missing) == 0 {
+			continue
+		}
+
+		listed := make([]string, 0, maxListedCombinations)
+		for _, combo := range missing[:min(len(missing), maxListedCombinations)] {
+			listed = append(listed, strings.TrimSuffix(metrics.FormatSample(metrics.Metric{Labels: combo}), " "))
+		}
+		more := ""
+		if len(missing) > maxListedCombinations {
+			more = fmt.Sprintf(" and %d more", len(missing)-maxListedCombinations)
+		}
+		args := make([]string, len(labels))
+		for i, label := range labels {
+			args[i] = fmt.Sprintf("%q", missing[0][label])
+		}
+
+		findings = append(findings, Finding{
+			Code:     "counter-not-initialized",
+			Severity: SeverityInfo,
+			Metric:   name,
+			Message: fmt.Sprintf("%s exposes %d of the %d %s combinations its values allow; a series that first appears "+
+				"at 1 hides its first increase from rate(), so initialize known combinations at 0, e.g. "+
+				".WithLabelValues(%s).Add(0) in client_golang. Missing: %s%s",
+				name, total-len(missing), total, strings.Join(labels, "/"), strings.Join(args, ", "), strings.Join(listed, ", "), more),
+		})
+	}
+	return findings
+}
+
+// enumerableValues returns the enumerable labels of samples in name order and
+// the values each was seen with. High-cardinality labels never count.
+func enumerableValues(parsed *metrics.ParsedMetrics, samples []metrics.Metric) ([]string, map[string][]string) {
+	values := make(map[string][]string)
+	for _, m := range samples {
+		for label, value := range m.Labels {
+			if !slices.Contains(values[label], value) {
+				values[label] = append(values[label], value)
+			}
+		}
+	}
+
+	var labels []string
+	for label, seen := range values {
+		if isHighCardinality(parsed, label) {
+			continue
+		}
+		enumerable := enumerableLabels[label]
+		if !enumerable {
+			enumerable = true
+			for _, value := range seen {
+				if !enumerableValue.MatchString(value) {
+					enumerable = false
+					break
+				}
+			}
+		}
+		if enumerable {
+			slices.Sort(seen)
+			labels = append(labels, label)
+		}
+	}
+	slices.Sort(labels)
+	return labels, values
+}
+
+func comboKey(labels []string, set map[string]string) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = set[label]
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// missingCombinations walks the product of values in order, returning the
+// combinations not present
+func missingCombinations(labels []string, values map[string][]string, present map[string]bool) []map[string]string {
+	var missing []map[string]string
+	index := make([]int, len(labels))
+	for {
+		combo := make(map[string]string, len(labels))
+		for i, label := range labels {
+			combo[label] = values[label][index[i]]
+		}
+		if !present[comboKey(labels, combo)] {
+			missing = append(missing, combo)
+		}
+
+		i := len(labels) - 1
+		for ; i >= 0; i-- {
+			index[i]++
+			if index[i] < len(values[labels[i]]) {
+				break
+			}
+			index[i] = 0
+		}
+		if i < 0 {
+			return missing
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// ABOUTME: Tests for the counter initialization rule - which counters miss label combinations, and how many are listed
+// ABOUTME: Full grids, single labels, gauges and oversized products raise nothing
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestCounterInitialization(t *testing.T) {
+	var oversized strings.Builder
+	oversized.WriteString("# TYPE jobs_total counter\n")
+	for i := range 40 {
+		fmt.Fprintf(&oversized, "jobs_total{outcome=\"o%d\",result=\"r%d\"} 1\n", i, i%30)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		// Text the one finding's message must hold; none expects no finding
+		want []string
+	}{
+		{name: "every combination present", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET",status_class="2xx"} 1
+http_requests_total{method="GET",status_class="5xx"} 0
+http_requests_total{method="POST",status_class="2xx"} 4
+http_requests_total{method="POST",status_class="5xx"} 0
+`},
+		{name: "one combination missing", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET",status_class="2xx"} 1
+http_requests_total{method="GET",status_class="5xx"} 2
+http_requests_total{method="POST",status_class="2xx"} 4
+`, want: []string{
+			"exposes 3 of the 4 method/status_class combinations",
+			`.WithLabelValues("POST", "5xx").Add(0)`,
+			`Missing: {method="POST",status_class="5xx"}`,
+		}},
+		{name: "labels enumerable by their values", input: `# TYPE api_calls_total counter
+api_calls_total{class="2xx",verb="GET"} 1
+api_calls_total{class="4xx",verb="PUT"} 1
+`, want: []string{
+			"exposes 2 of the 4 class/verb combinations",
+			`Missing: {class="2xx",verb="PUT"}, {class="4xx",verb="GET"}`,
+		}},
+		{name: "listing stops at five", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET",outcome="ok"} 1
+http_requests_total{method="POST",outcome="error"} 1
+http_requests_total{method="PUT",outcome="timeout"} 1
+`, want: []string{"exposes 3 of the 9 method/outcome combinations", " and 1 more"}},
+		{name: "one enumerable label", input: `# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a"} 1
+http_requests_total{method="POST",path="/b"} 1
+`},
+		{name: "a gauge", input: `# TYPE queue_depth gauge
+queue_depth{method="GET",status_class="2xx"} 1
+queue_depth{method="POST",status_class="5xx"} 1
+`},
+		{name: "more combinations than can be initialized", input: oversized.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings := checkCounterInitialization(parsed)
+			if len(tt.want) == 0 {
+				if len(findings) > 0 {
+					t.Errorf("findings = %+v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %+v, want one", findings)
+			}
+			f := findings[0]
+			if f.Code != "counter-not-initialized" || f.Severity != SeverityInfo {
+				t.Errorf("finding = %s %s, want info counter-not-initialized", f.Severity, f.Code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(f.Message, want) {
+					t.Errorf("message = %q, want it to hold %q", f.Message, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// ABOUTME: Known renames - metric and label names well-known exporters have retired, with their replacements
+// ABOUTME: Catches names copied from old dashboards; the config file can add entries for in-house exporters
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// KnownRename is the replacement for a retired metric name
+type KnownRename struct {
+	Replacement string
+	// The release that retired the name
+	Source string
+}
+
+const (
+	nodeExporter016    = "node_exporter 0.16"
+	kubeStateMetricsV2 = "kube-state-metrics v2"
+)
+
+// KnownRenames maps retired metric names to their replacements
+var KnownRenames = map[string]KnownRename{
+	// node_exporter 0.16 moved to base units and _total suffixes
+	"node_cpu":                    {"node_cpu_seconds_total", nodeExporter016},
+	"node_boot_time":              {"node_boot_time_seconds", nodeExporter016},
+	"node_time":                   {"node_time_seconds", nodeExporter016},
+	"node_context_switches":       {"node_context_switches_total", nodeExporter016},
+	"node_forks":                  {"node_forks_total", nodeExporter016},
+	"node_intr":                   {"node_intr_total", nodeExporter016},
+	"node_memory_MemTotal":        {"node_memory_MemTotal_bytes", nodeExporter016},
+	"node_memory_MemFree":         {"node_memory_MemFree_bytes", nodeExporter016},
+	"node_memory_MemAvailable":    {"node_memory_MemAvailable_bytes", nodeExporter016},
+	"node_memory_Buffers":         {"node_memory_Buffers_bytes", nodeExporter016},
+	"node_memory_Cached":          {"node_memory_Cached_bytes", nodeExporter016},
+	"node_filesystem_size":        {"node_filesystem_size_bytes", nodeExporter016},
+	"node_filesystem_free":        {"node_filesystem_free_bytes", nodeExporter016},
+	"node_filesystem_avail":       {"node_filesystem_avail_bytes", nodeExporter016},
+	"node_network_receive_bytes":  {"node_network_receive_bytes_total", nodeExporter016},
+	"node_network_transmit_bytes": {"node_network_transmit_bytes_total", nodeExporter016},
+	"node_disk_reads_completed":   {"node_disk_reads_completed_total", nodeExporter016},
+	"node_disk_writes_completed":  {"node_disk_writes_completed_total", nodeExporter016},
+	"node_disk_bytes_read":        {"node_disk_read_bytes_total", nodeExporter016},
+	"node_disk_bytes_written":     {"node_disk_written_bytes_total", nodeExporter016},
+	"node_disk_io_time_ms":        {"node_disk_io_time_seconds_total", nodeExporter016},
+
+	// kube-state-metrics v2 folded per-resource metrics into one with a resource label and spelled out hpa
+	"kube_node_status_capacity_cpu_cores":               {`kube_node_status_capacity{resource="cpu"}`, kubeStateMetricsV2},
+	"kube_node_status_capacity_memory_bytes":            {`kube_node_status_capacity{resource="memory"}`, kubeStateMetricsV2},
+	"kube_node_status_allocatable_cpu_cores":            {`kube_node_status_allocatable{resource="cpu"}`, kubeStateMetricsV2},
+	"kube_node_status_allocatable_memory_bytes":         {`kube_node_status_allocatable{resource="memory"}`, kubeStateMetricsV2},
+	"kube_pod_container_resource_requests_cpu_cores":    {`kube_pod_container_resource_requests{resource="cpu"}`, kubeStateMetricsV2},
+	"kube_pod_container_resource_requests_memory_bytes": {`kube_pod_container_resource_requests{resource="memory"}`, kubeStateMetricsV2},
+	"kube_pod_container_resource_limits_cpu_cores":      {`kube_pod_container_resource_limits{resource="cpu"}`, kubeStateMetricsV2},
+	"kube_pod_container_resource_limits_memory_bytes":   {`kube_pod_container_resource_limits{resource="memory"}`, kubeStateMetricsV2},
+	"kube_hpa_spec_max_replicas":                        {"kube_horizontalpodautoscaler_spec_max_replicas", kubeStateMetricsV2},
+	"kube_hpa_spec_min_replicas":                        {"kube_horizontalpodautoscaler_spec_min_replicas", kubeStateMetricsV2},
+	"kube_hpa_status_current_replicas":                  {"kube_horizontalpodautoscaler_status_current_replicas", kubeStateMetricsV2},
+	"kube_hpa_status_desired_replicas":                  {"kube_horizontalpodautoscaler_status_desired_replicas", kubeStateMetricsV2},
+}
+
+// Labels cAdvisor renamed on its container_ metrics in Kubernetes 1.16
+var cadvisorLabelRenames = map[string]string{
+	"container_name": "container",
+	"pod_name":       "pod",
+}
+
+// WithRenames returns the profile with extra retired names, old name to
+// replacement, flagged on top of KnownRenames. Extra entries win over built-in ones.
+func (p NamingProfile) WithRenames(extra map[string]string) NamingProfile {
+	p.renames = extra
+	return p
+}
+
+// checkKnownRenames flags retired metric names and the cAdvisor labels that
+// container_ metrics no longer carry
+func checkKnownRenames(extra map[string]string, parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		if replacement, ok := extra[name]; ok {
+			findings = append(findings, Finding{
+				Code:     "renamed-metric",
+				Severity: SeverityWarning,
+				Metric:   name,
+				Message:  fmt.Sprintf("%s is retired; use %s", name, replacement),
+			})
+		} else if known, ok := KnownRenames[name]; ok {
+			findings = append(findings, Finding{
+				Code:     "renamed-metric",
+				Severity: SeverityWarning,
+				Metric:   name,
+				Message:  fmt.Sprintf("%s was renamed to %s in %s; queries using the old name match nothing", name, known.Replacement, known.Source),
+			})
+		}
+
+		if !strings.HasPrefix(name, "container_") {
+			continue
+		}
+		for _, label := range familyLabels(parsed, name) {
+			if replacement, ok := cadvisorLabelRenames[label]; ok {
+				findings = append(findings, Finding{
+					Code:     "renamed-label",
+					Severity: SeverityWarning,
+					Metric:   name,
+					Message:  fmt.Sprintf("cAdvisor renamed the %s label on %s to %s in Kubernetes 1.16", label, name, replacement),
+				})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,96 @@
+// ABOUTME: Tests for the known-renames rule - retired exporter names and cAdvisor labels are flagged with their replacement
+// ABOUTME: Current names pass, and entries from the config file add to or override the built-in table
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func renameFindings(t *testing.T, p NamingProfile, fixture string) []string {
+	t.Helper()
+	parsed, err := metrics.Parse(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range p.Check(parsed) {
+		if f.Code == "renamed-metric" || f.Code == "renamed-label" {
+			got = append(got, f.Message)
+		}
+	}
+	return got
+}
+
+func TestKnownRenames(t *testing.T) {
+	p, err := Profile(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		fixture string
+		// Finding messages, in order; empty expects none
+		want []string
+	}{
+		{
+			name:    "node_exporter 0.16",
+			fixture: `node_cpu{cpu="0",mode="idle"} 1`,
+			want:    []string{"node_cpu was renamed to node_cpu_seconds_total in node_exporter 0.16; queries using the old name match nothing"},
+		},
+		{
+			name:    "kube-state-metrics v2",
+			fixture: `kube_node_status_capacity_cpu_cores{node="a"} 4`,
+			want:    []string{`kube_node_status_capacity_cpu_cores was renamed to kube_node_status_capacity{resource="cpu"} in kube-state-metrics v2; queries using the old name match nothing`},
+		},
+		{
+			name:    "cAdvisor labels",
+			fixture: `container_cpu_usage_seconds_total{pod_name="api-1",container_name="api"} 1`,
+			want: []string{
+				"cAdvisor renamed the container_name label on container_cpu_usage_seconds_total to container in Kubernetes 1.16",
+				"cAdvisor renamed the pod_name label on container_cpu_usage_seconds_total to pod in Kubernetes 1.16",
+			},
+		},
+		{name: "current node name", fixture: `node_cpu_seconds_total{cpu="0",mode="idle"} 1`},
+		{name: "current kube-state-metrics name", fixture: `kube_node_status_capacity{node="a",resource="cpu"} 4`},
+		{name: "a prefix of a retired name", fixture: "node_cp 1"},
+		{name: "current cAdvisor labels", fixture: `container_cpu_usage_seconds_total{pod="api-1",container="api"} 1`},
+		{name: "pod_name outside cAdvisor", fixture: `app_restarts_total{pod_name="api-1"} 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renameFindings(t, p, tt.fixture)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfiguredRenames(t *testing.T) {
+	p, err := Profile(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = p.WithRenames(map[string]string{
+		"billing_jobs": "billing_jobs_processed_total",
+		"node_cpu":     "node_cpu_seconds_total, via the platform team's recording rules",
+	})
+
+	fixture := "billing_jobs 3\nnode_cpu 1\nnode_forks 2\n"
+	want := []string{
+		"billing_jobs is retired; use billing_jobs_processed_total",
+		"node_cpu is retired; use node_cpu_seconds_total, via the platform team's recording rules",
+		"node_forks was renamed to node_forks_total in node_exporter 0.16; queries using the old name match nothing",
+	}
+	if got := renameFindings(t, p, fixture); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings = %q, want %q", got, want)
+	}
+
+	if got := renameFindings(t, p, "billing_jobs_processed_total 3"); len(got) > 0 {
+		t.Errorf("the configured replacement was flagged: %q", got)
+	}
+}
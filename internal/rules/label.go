@@ -0,0 +1,112 @@
+// ABOUTME: Single-label check - runs the label rules over one label put on a hypothetical metric
+// ABOUTME: Answers "is this label OK with N values" without a full metric, with its memory at several target counts
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// LabelCheckMetric is the gauge a checked label is put on
+const LabelCheckMetric = "label_check"
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Findings about the labels themselves rather than the metric carrying them
+var labelCodes = map[string]bool{
+	"high-cardinality-label": true,
+	"packed-label":           true,
+	"lookalike-values":       true,
+	"label-not-allowed":      true,
+	"forbidden-word":         true,
+	"metricsql":              true,
+	"tag-format":             true,
+}
+
+// LabelTargets are the instance counts a label's memory is given for
+var LabelTargets = []int{1, 10, 100}
+
+// LabelImpact is the series and memory a label adds when every one of
+// Targets instances exposes each of its values
+type LabelImpact struct {
+	Targets     int
+	Series      int
+	MemoryBytes int64
+	Memory      string
+}
+
+type LabelCheck struct {
+	Label  string
+	Values int
+	// ok, monitor, review or avoid
+	Verdict  string
+	Findings []Finding
+	Impact   []LabelImpact
+}
+
+// CheckLabel judges label with values distinct values, samples being some of
+// them. The label rules run on LabelCheckMetric carrying the samples, or one
+// placeholder value when there are none.
+func (p NamingProfile) CheckLabel(label string, values int, samples []string) (*LabelCheck, error) {
+	if !labelNamePattern.MatchString(label) {
+		return nil, fmt.Errorf("%q is not a valid label name", label)
+	}
+	if len(samples) == 0 {
+		samples = []string{"example"}
+	}
+	values = max(values, len(samples))
+
+	ms := make([]metrics.Metric, len(samples))
+	for i, v := range samples {
+		ms[i] = metrics.Metric{Name: LabelCheckMetric, Labels: map[string]string{label: v}, Value: "1"}
+	}
+	var sb strings.Builder
+	if err := metrics.WriteText(&sb, ms, map[string]string{LabelCheckMetric: "gauge"}, nil); err != nil {
+		return nil, err
+	}
+	parsed, err := p.Parse(sb.String())
+	if err != nil {
+		return nil, err
+	}
+
+	check := &LabelCheck{Label: label, Values: values, Verdict: "ok"}
+	for _, f := range p.Check(parsed) {
+		if labelCodes[f.Code] {
+			check.Findings = append(check.Findings, f)
+		}
+	}
+
+	switch {
+	case hasSeverity(check.Findings, SeverityError):
+		check.Verdict = "avoid"
+	case values > p.Thresholds.LabelValuesReview:
+		check.Verdict = "review"
+	case values > p.Thresholds.LabelValuesMonitor || hasSeverity(check.Findings, SeverityWarning):
+		check.Verdict = "monitor"
+	}
+
+	for _, targets := range LabelTargets {
+		series := values * targets
+		check.Impact = append(check.Impact, LabelImpact{
+			Targets:     targets,
+			Series:      series,
+			MemoryBytes: cardinality.MemoryBytes(series),
+			Memory:      cardinality.EstimateSimple(series),
+		})
+	}
+	return check, nil
+}
+
+func hasSeverity(findings []Finding, severity Severity) bool {
+	for _, f := range findings {
+		if f.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
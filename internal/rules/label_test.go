@@ -0,0 +1,84 @@
+// ABOUTME: Tests for the single-label check - verdicts from value counts, thresholds and the label rules
+// ABOUTME: Memory grows with the target count, samples raise the value count, and invalid names are rejected
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+)
+
+func TestCheckLabel(t *testing.T) {
+	tests := []struct {
+		profile     string
+		label       string
+		values      int
+		samples     []string
+		wantValues  int
+		wantVerdict string
+		wantCode    string
+	}{
+		{DefaultProfile, "method", 5, nil, 5, "ok", ""},
+		{DefaultProfile, "method", 20, nil, 20, "ok", ""},
+		{DefaultProfile, "method", 21, nil, 21, "monitor", ""},
+		{DefaultProfile, "region", 100, nil, 100, "monitor", ""},
+		{DefaultProfile, "team_id", 200, nil, 200, "review", ""},
+		// An unbounded name is an error whatever the count
+		{DefaultProfile, "user_id", 0, nil, 1, "avoid", "high-cardinality-label"},
+		{DefaultProfile, "path", 0, []string{"/users/123", "/users/456"}, 2, "avoid", "high-cardinality-label"},
+		// More samples than the stated count raise it
+		{DefaultProfile, "status", 1, []string{"200", "404", "500"}, 3, "ok", ""},
+		// Thresholds come from the profile
+		{"datadog", "region", 30, nil, 30, "monitor", ""},
+		{"datadog", "region", 51, nil, 51, "review", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile+"/"+tt.label, func(t *testing.T) {
+			p, err := Profile(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check, err := p.CheckLabel(tt.label, tt.values, tt.samples)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if check.Values != tt.wantValues || check.Verdict != tt.wantVerdict {
+				t.Errorf("values, verdict = %d, %s, want %d, %s", check.Values, check.Verdict, tt.wantValues, tt.wantVerdict)
+			}
+			var codes []string
+			for _, f := range check.Findings {
+				if !labelCodes[f.Code] {
+					t.Errorf("finding %s is about the metric, not the label", f.Code)
+				}
+				codes = append(codes, f.Code)
+			}
+			if (tt.wantCode == "") != (len(codes) == 0) || (tt.wantCode != "" && codes[0] != tt.wantCode) {
+				t.Errorf("findings = %v, want %q", codes, tt.wantCode)
+			}
+
+			if len(check.Impact) != len(LabelTargets) {
+				t.Fatalf("impact = %+v, want one per target count", check.Impact)
+			}
+			for i, impact := range check.Impact {
+				series := tt.wantValues * LabelTargets[i]
+				if impact.Targets != LabelTargets[i] || impact.Series != series || impact.MemoryBytes != cardinality.MemoryBytes(series) ||
+					impact.Memory != cardinality.EstimateSimple(series) {
+					t.Errorf("impact %d = %+v, want %d series on %d targets", i, impact, series, LabelTargets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckLabelRejectsInvalidNames(t *testing.T) {
+	p, err := Profile(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"", "bad-name", "1st", "has space"} {
+		if _, err := p.CheckLabel(label, 1, nil); err == nil {
+			t.Errorf("CheckLabel(%q) succeeded", label)
+		}
+	}
+}
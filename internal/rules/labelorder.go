@@ -0,0 +1,50 @@
+// ABOUTME: Label order rule - one metric family writing its labels in different orders on different lines
+// ABOUTME: Prometheus doesn't care, but mixed orders make exposition output and diffs hard to read
+
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// checkLabelOrder flags families whose samples write their shared labels in
+// different orders, once per family, suggesting order's order
+func checkLabelOrder(order metrics.LabelOrder, parsed *metrics.ParsedMetrics) []Finding {
+	// Family to the label order of its first sample with labels
+	first := make(map[string][]string)
+	flagged := make(map[string]bool)
+	var findings []Finding
+	for _, m := range parsed.Metrics {
+		family := baseName(m.Name)
+		written := metrics.WrittenLabelNames(m)
+		if len(written) < 2 || flagged[family] {
+			continue
+		}
+		reference, ok := first[family]
+		if !ok {
+			first[family] = written
+			continue
+		}
+
+		a := slices.DeleteFunc(slices.Clone(reference), func(l string) bool { return !slices.Contains(written, l) })
+		b := slices.DeleteFunc(slices.Clone(written), func(l string) bool { return !slices.Contains(reference, l) })
+		if slices.Equal(a, b) {
+			continue
+		}
+		flagged[family] = true
+		suggested := slices.Clone(a)
+		order.Sort(suggested)
+		findings = append(findings, Finding{
+			Code:     "label-order",
+			Severity: SeverityInfo,
+			Metric:   family,
+			Message: fmt.Sprintf("%s writes its labels in different orders (%s, then %s); write them in one order, such as %s, so output and diffs stay readable",
+				family, strings.Join(a, ", "), strings.Join(b, ", "), strings.Join(suggested, ", ")),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,193 @@
+// ABOUTME: Word lexicon rules - vague words that say nothing about what a metric measures, and forbidden words
+// ABOUTME: Both lists are configurable; forbidden words keep internal codenames out of metric and label names
+
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// DefaultVagueWords name no measured quantity, so the metric's name carries no meaning
+var DefaultVagueWords = []string{"data", "info", "value", "number", "metric", "temp"}
+
+// Lexicon lists words flagged in metric names. A nil Vague means DefaultVagueWords.
+type Lexicon struct {
+	Vague []string
+	// Words that must never appear in metric or label names, such as project codenames
+	Forbidden []string
+}
+
+// What each default vague word usually stands for
+var vagueHints = map[string]string{
+	"data":   "the bytes or records it counts (_bytes, _records_total)",
+	"info":   "what it describes; info metrics end in _info with the value 1",
+	"value":  "the quantity and its unit",
+	"number": "what is counted (_total for events, a plural noun for a gauge)",
+	"metric": "what is measured; every name is a metric",
+	"temp":   "temperature_celsius, or nothing if it means temporary",
+}
+
+// Labels that hint at the quantity a metric split by them measures
+var labelQuantities = map[string]string{
+	"queue":     "queue_depth",
+	"pool":      "pool_connections",
+	"cache":     "cache_hits_total",
+	"disk":      "disk_used_bytes",
+	"device":    "device_temperature_celsius",
+	"file":      "file_size_bytes",
+	"table":     "table_rows",
+	"topic":     "topic_messages_total",
+	"partition": "partition_lag",
+	"sensor":    "sensor_temperature_celsius",
+	"worker":    "worker_busy",
+	"endpoint":  "requests_total",
+	"handler":   "requests_total",
+	"status":    "responses_total",
+}
+
+// WithLexicon returns the profile with vague and forbidden words checked against l
+func (p NamingProfile) WithLexicon(l Lexicon) NamingProfile {
+	p.lexicon = l
+	return p
+}
+
+func (l Lexicon) vague() []string {
+	if l.Vague == nil {
+		return DefaultVagueWords
+	}
+	return l.Vague
+}
+
+// checkLexicon flags vague and forbidden words in metric names, and forbidden
+// words in label names. Label values are never checked.
+func checkLexicon(l Lexicon, parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		// An _info metric's suffix is the convention, not a vague word
+		if !strings.HasSuffix(name, "_info") {
+			for _, word := range l.vague() {
+				if !containsWord(name, word) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Code:     "vague-name",
+					Severity: SeverityInfo,
+					Metric:   name,
+					Message:  vagueMessage(name, word, familyLabels(parsed, name)),
+				})
+			}
+		}
+		for _, word := range l.Forbidden {
+			if containsWord(name, word) {
+				findings = append(findings, Finding{
+					Code:     "forbidden-word",
+					Severity: SeverityError,
+					Metric:   name,
+					Message:  fmt.Sprintf("%s contains %q, which must not appear in metric names", name, word),
+				})
+			}
+		}
+	}
+
+	flagged := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		for label := range m.Labels {
+			for _, word := range l.Forbidden {
+				if key := label + "\x00" + word; !flagged[key] && containsWord(label, word) {
+					flagged[key] = true
+					findings = append(findings, Finding{
+						Code:     "forbidden-word",
+						Severity: SeverityError,
+						Metric:   m.Name,
+						Message:  fmt.Sprintf("label %s on %s contains %q, which must not appear in label names", label, m.Name, word),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func vagueMessage(name, word string, labels []string) string {
+	msg := fmt.Sprintf("%s contains %q, which says nothing about what is measured; name the quantity instead", name, word)
+	if hint, ok := vagueHints[strings.ToLower(word)]; ok {
+		msg += ": " + hint
+	}
+
+	var examples []string
+	for _, label := range labels {
+		quantity, ok := labelQuantities[label]
+		if !ok {
+			continue
+		}
+		if example := replaceWord(name, word, quantity); !slices.Contains(examples, example) {
+			examples = append(examples, example)
+		}
+	}
+	if len(examples) > 0 {
+		msg += fmt.Sprintf(" (e.g. %s)", strings.Join(examples, ", "))
+	}
+	return msg
+}
+
+// familyLabels lists the label names the series of a metric carry, sorted
+func familyLabels(parsed *metrics.ParsedMetrics, name string) []string {
+	var labels []string
+	for _, m := range parsed.Metrics {
+		if m.Name != name {
+			continue
+		}
+		for label := range m.Labels {
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	slices.Sort(labels)
+	return labels
+}
+
+// words splits a name on the separators of every naming profile
+func words(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '.' || r == ':'
+	})
+}
+
+// containsWord reports whether word, which may span separators itself, appears
+// in name as whole words, ignoring case
+func containsWord(name, word string) bool {
+	want := words(word)
+	if len(want) == 0 {
+		return false
+	}
+	have := words(name)
+	for i := 0; i+len(want) <= len(have); i++ {
+		if slices.Equal(have[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceWord swaps the first occurrence of word in name for replacement,
+// dropping words replacement repeats from just before it
+func replaceWord(name, word, replacement string) string {
+	have, want, with := words(name), words(word), words(replacement)
+	for i := 0; i+len(want) <= len(have); i++ {
+		if !slices.Equal(have[i:i+len(want)], want) {
+			continue
+		}
+		before := have[:i]
+		if n := len(before); n > 0 && before[n-1] == with[0] {
+			before = before[:n-1]
+		}
+		out := slices.Concat(before, with, have[i+len(want):])
+		return strings.Join(out, "_")
+	}
+	return name
+}
@@ -0,0 +1,107 @@
+// ABOUTME: Log and exemplar alternatives for high-cardinality labels flagged for removal
+// ABOUTME: Shows the removed fields as a structured JSON log line, and as an exemplar where one fits
+
+package rules
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Labels that identify a trace, which exemplars exist to carry
+var traceLabels = []string{"trace_id", "traceid", "span_id", "spanid"}
+
+// Trace ID used in exemplars when the submission has none
+const exampleTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// LogAlternative shows where the values of removed labels should go instead
+type LogAlternative struct {
+	// Removed labels, merged into one example
+	Labels []string
+	// JSON log line with the removed fields next to the metric's remaining labels
+	LogLine string
+	// OpenMetrics sample with an exemplar; empty unless the metric is a counter or histogram
+	Exemplar string
+}
+
+// logAlternative builds the alternative for one sample, moving every removed
+// label it carries out of the series
+func logAlternative(parsed *metrics.ParsedMetrics, m metrics.Metric, removed []string) *LogAlternative {
+	alt := &LogAlternative{}
+	kept := make(map[string]string)
+	for name, value := range m.Labels {
+		if slices.Contains(removed, name) {
+			alt.Labels = append(alt.Labels, name)
+		} else {
+			kept[name] = value
+		}
+	}
+	slices.Sort(alt.Labels)
+
+	family := baseName(m.Name)
+	fields := [][2]string{{"level", "info"}, {"msg", strings.ReplaceAll(family, "_", " ")}, {"metric", family}}
+	for _, name := range sortedKeys(kept) {
+		if name != "le" && name != "quantile" {
+			fields = append(fields, [2]string{name, kept[name]})
+		}
+	}
+	for _, name := range alt.Labels {
+		fields = append(fields, [2]string{name, m.Labels[name]})
+	}
+	alt.LogLine = jsonObject(fields)
+
+	switch parsed.TypeOf(m.Name) {
+	case "counter", "histogram":
+		alt.Exemplar = exemplarLine(m, kept, alt.Labels)
+	}
+	return alt
+}
+
+// exemplarLine writes the sample without the removed labels and with an
+// exemplar carrying the trace the value came from
+func exemplarLine(m metrics.Metric, kept map[string]string, removed []string) string {
+	trace := make(map[string]string)
+	for _, name := range removed {
+		if slices.Contains(traceLabels, name) {
+			trace[name] = m.Labels[name]
+		}
+	}
+	if len(trace) == 0 {
+		trace["trace_id"] = exampleTraceID
+	}
+
+	value := m.Value
+	if value == "" {
+		value = "1"
+	}
+	// Counter exemplars record one increment; bucket exemplars an observation within the bucket
+	observed := "1"
+	if le, ok := kept["le"]; ok && le != "+Inf" {
+		observed = le
+	}
+
+	sample := metrics.FormatSample(metrics.Metric{Name: m.Name, Labels: kept, Value: value})
+	exemplar := metrics.FormatSample(metrics.Metric{Labels: trace, Value: observed})
+	return sample + " # " + exemplar
+}
+
+// jsonObject encodes fields in order, escaping keys and values
+func jsonObject(fields [][2]string) string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, f := range fields {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		key, _ := json.Marshal(f[0])
+		value, _ := json.Marshal(f[1])
+		sb.Write(key)
+		sb.WriteString(":")
+		sb.Write(value)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+// ABOUTME: Lookalike label values - values that render the same but are distinct series
+// ABOUTME: Catches composed and decomposed accents, zero-width characters and NBSP from upstream data
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// checkLookalikeValues flags each group of a label's values that only differ
+// in Unicode normalization or invisible characters
+func checkLookalikeValues(parsed *metrics.ParsedMetrics) []Finding {
+	if parsed.CardinalityAnalysis == nil {
+		return nil
+	}
+
+	var labels []string
+	for name, info := range parsed.CardinalityAnalysis.LabelAnalysis {
+		if len(info.LookalikeValues) > 0 {
+			labels = append(labels, name)
+		}
+	}
+	sort.Strings(labels)
+
+	var findings []Finding
+	for _, label := range labels {
+		for _, group := range parsed.CardinalityAnalysis.LabelAnalysis[label].LookalikeValues {
+			// Escaped, as printed they would look the same
+			quoted := make([]string, len(group))
+			for i, v := range group {
+				quoted[i] = strconv.QuoteToASCII(v)
+			}
+			findings = append(findings, Finding{
+				Code:     "lookalike-values",
+				Severity: SeverityWarning,
+				Metric:   firstMetricWith(parsed, label, group[0]),
+				Message: fmt.Sprintf("these %d values of label %s are visually identical but distinct series: %s; normalize them (NFC, no invisible characters) where they are produced",
+					len(group), label, strings.Join(quoted, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+// firstMetricWith returns the first metric whose label has value
+func firstMetricWith(parsed *metrics.ParsedMetrics, label, value string) string {
+	for _, m := range parsed.Metrics {
+		if v, ok := m.Labels[label]; ok && v == value {
+			return m.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+// ABOUTME: Tests for the lookalike-values rule on fixtures with composed/decomposed pairs and zero-width characters
+// ABOUTME: The finding escapes the values, and parsing keeps each value's raw bytes
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestLookalikeValues(t *testing.T) {
+	fixture := "orders_total{city=\"café\"} 1\n" +
+		"orders_total{city=\"cafe\u0301\"} 1\n" +
+		"orders_total{city=\"café\u200b\"} 1\n" +
+		"orders_total{city=\"berlin\"} 1\n"
+	parsed, err := metrics.Parse(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Metrics[1].Labels["city"]; got != "cafe\u0301" {
+		t.Errorf("parsed value = %+q, want the decomposed bytes kept", got)
+	}
+
+	findings := checkLookalikeValues(parsed)
+	want := `these 3 values of label city are visually identical but distinct series: "cafe\u0301", "caf\u00e9", "caf\u00e9\u200b"; normalize them (NFC, no invisible characters) where they are produced`
+	if len(findings) != 1 || findings[0].Message != want || findings[0].Metric != "orders_total" || findings[0].Severity != SeverityWarning {
+		t.Fatalf("findings = %+v, want one warning: %s", findings, want)
+	}
+
+	parsed, err = metrics.Parse("orders_total{city=\"café\"} 1\norders_total{city=\"cafe\"} 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findings := checkLookalikeValues(parsed); len(findings) > 0 {
+		t.Errorf("distinct values flagged: %s", findings[0].Message)
+	}
+}
@@ -0,0 +1,135 @@
+// ABOUTME: VictoriaMetrics MetricsQL checks - names that clash with functions or keywords
+// ABOUTME: and names that can't be written bare inside rollup() or aggr_over_time()
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+const VictoriaMetricsProfile = "victoriametrics-metricsql"
+
+// MetricsQL identifiers may contain dots, unlike PromQL ones
+var metricsqlIdent = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:.]*$`)
+
+// MetricsQL rollup, transform and aggregate functions. A metric sharing one of
+// these names reads as a function call once a query wraps it.
+var metricsqlFunctions = []string{
+	// rollup
+	"aggr_over_time", "ascent_over_time", "avg_over_time", "changes", "changes_prometheus",
+	"count_eq_over_time", "count_gt_over_time", "count_le_over_time", "count_ne_over_time",
+	"count_over_time", "decreases_over_time", "default_rollup", "delta", "delta_prometheus",
+	"deriv", "deriv_fast", "descent_over_time", "distinct_over_time", "duration_over_time",
+	"first_over_time", "geomean_over_time", "histogram_over_time", "hoeffding_bound_lower",
+	"hoeffding_bound_upper", "holt_winters", "idelta", "ideriv", "increase", "increase_prometheus",
+	"increase_pure", "increases_over_time", "integrate", "irate", "lag", "last_over_time",
+	"lifetime", "mad_over_time", "max_over_time", "median_over_time", "min_over_time",
+	"mode_over_time", "predict_linear", "present_over_time", "quantile_over_time",
+	"quantiles_over_time", "range_over_time", "rate", "rate_over_sum", "resets", "rollup",
+	"rollup_candlestick", "rollup_delta", "rollup_deriv", "rollup_increase", "rollup_rate",
+	"rollup_scrape_interval", "scrape_interval", "share_gt_over_time", "share_le_over_time",
+	"stale_samples_over_time", "stddev_over_time", "stdvar_over_time", "sum_over_time",
+	"sum2_over_time", "timestamp", "timestamp_with_name", "tfirst_over_time", "tlast_change_over_time",
+	"tlast_over_time", "tmax_over_time", "tmin_over_time", "zscore_over_time",
+	// transform
+	"abs", "absent", "ceil", "clamp", "clamp_max", "clamp_min", "day_of_month", "day_of_week",
+	"days_in_month", "drop_common_labels", "end", "exp", "floor", "histogram_avg",
+	"histogram_quantile", "histogram_quantiles", "histogram_share", "histogram_stddev",
+	"histogram_stdvar", "hour", "interpolate", "keep_last_value", "keep_next_value",
+	"label_copy", "label_del", "label_graphite_group", "label_join", "label_keep",
+	"label_lowercase", "label_map", "label_match", "label_mismatch", "label_move",
+	"label_replace", "label_set", "label_transform", "label_uppercase", "label_value",
+	"labels_equal", "ln", "log2", "log10", "minute", "month", "now", "pi", "rand",
+	"range_avg", "range_first", "range_last", "range_max", "range_median", "range_min",
+	"range_quantile", "range_sum", "remove_resets", "round", "running_avg", "running_max",
+	"running_min", "running_sum", "scalar", "sgn", "smooth_exponential", "sort",
+	"sort_by_label", "sort_by_label_desc", "sort_desc", "sqrt", "start", "step", "time",
+	"union", "vector", "year",
+	// aggregate
+	"any", "avg", "bottomk", "bottomk_avg", "bottomk_max", "bottomk_min", "count",
+	"count_values", "distinct", "geomean", "group", "histogram", "limitk", "mad", "max",
+	"median", "min", "mode", "outliers_iqr", "outliers_mad", "outliersk", "quantile",
+	"quantiles", "share", "stddev", "stdvar", "sum", "sum2", "topk", "topk_avg",
+	"topk_last", "topk_max", "topk_min", "zscore",
+}
+
+// MetricsQL keywords and modifiers, which read as syntax in grouping clauses
+var metricsqlKeywords = []string{
+	"and", "atan2", "bool", "by", "default", "group_left", "group_right", "if", "ifnot",
+	"ignoring", "inf", "keep_metric_names", "limit", "nan", "offset", "on", "or",
+	"prefix", "unless", "with", "without",
+}
+
+// ValidateVMMetricsQL returns the problems querying metricName with MetricsQL
+// would hit. labelsJSON is either an object of label names to values or an
+// array of label names; empty means no labels.
+func ValidateVMMetricsQL(metricName, labelsJSON string) []string {
+	var labels []string
+	if labelsJSON != "" {
+		var byName map[string]string
+		if err := json.Unmarshal([]byte(labelsJSON), &byName); err == nil {
+			labels = sortedKeys(byName)
+		} else if err := json.Unmarshal([]byte(labelsJSON), &labels); err != nil {
+			return []string{fmt.Sprintf("labels must be a JSON object or array of label names: %v", err)}
+		}
+	}
+	return validateMetricsQL(metricName, labels)
+}
+
+func validateMetricsQL(name string, labels []string) []string {
+	var problems []string
+
+	if slices.Contains(metricsqlFunctions, name) {
+		problems = append(problems, fmt.Sprintf("%s shares its name with a MetricsQL function, so %s(...) in a query calls the function; rename it or query {__name__=%q}",
+			name, name, name))
+	}
+	if !metricsqlIdent.MatchString(name) {
+		problems = append(problems, fmt.Sprintf("%s is not a valid MetricsQL identifier, so it can't be written bare in rollup(%s[5m]) or aggr_over_time(...); rename it or query {__name__=%q}",
+			name, name, name))
+	}
+
+	for _, label := range labels {
+		if slices.Contains(metricsqlKeywords, label) {
+			problems = append(problems, fmt.Sprintf("label %q on %s is a MetricsQL keyword and is misread in clauses such as sum(...) by (%s); rename it", label, name, label))
+		}
+	}
+	return problems
+}
+
+func checkMetricsQL(parsed *metrics.ParsedMetrics) []Finding {
+	// Histogram and summary series share their family's labels, so check each family once
+	labels := make(map[string]map[string]bool)
+	var families []string
+	for _, m := range parsed.Metrics {
+		family := baseName(m.Name)
+		if labels[family] == nil {
+			labels[family] = make(map[string]bool)
+			families = append(families, family)
+		}
+		for label := range m.Labels {
+			if label != "le" && label != "quantile" {
+				labels[family][label] = true
+			}
+		}
+	}
+	sort.Strings(families)
+
+	var findings []Finding
+	for _, family := range families {
+		for _, problem := range validateMetricsQL(family, sortedKeys(labels[family])) {
+			findings = append(findings, Finding{
+				Code:     "metricsql",
+				Severity: SeverityWarning,
+				Metric:   family,
+				Message:  problem,
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,45 @@
+// ABOUTME: Grafana Mimir checks - tenant labels that duplicate Mimir's own multi-tenancy
+// ABOUTME: Mimir separates tenants by the X-Scope-OrgID header, so a tenant_id label only adds series
+
+package rules
+
+import (
+	"fmt"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+const MimirProfile = "mimir"
+
+const (
+	// A label doing by hand what Mimir's X-Scope-OrgID header does
+	mimirTenantLabel = "tenant_id"
+	// Added by Mimir to results of queries federated across tenants
+	mimirReservedTenantLabel = "__tenant_id__"
+)
+
+// checkMimirTenantLabels flags tenant_id and __tenant_id__ labels, once each,
+// on the first metric carrying them
+func checkMimirTenantLabels(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	flagged := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		for _, label := range []string{mimirTenantLabel, mimirReservedTenantLabel} {
+			if _, ok := m.Labels[label]; !ok || flagged[label] {
+				continue
+			}
+			flagged[label] = true
+			f := Finding{Code: "mimir-tenant-label", Severity: SeverityWarning, Metric: m.Name}
+			if label == mimirTenantLabel {
+				f.Message = fmt.Sprintf("Label '%s' on %s keeps tenants apart inside one Mimir tenant and multiplies its series by the number of tenants. "+
+					"Use Mimir's built-in multi-tenancy header X-Scope-OrgID instead, writing each tenant's series under its own org ID", label, m.Name)
+			} else {
+				f.Code, f.Severity = "mimir-reserved-label", SeverityError
+				f.Message = fmt.Sprintf("Label '%s' on %s is reserved: Mimir adds it to queries across tenants, and Prometheus drops labels starting with __ before remote write, so it never arrives. "+
+					"Send the tenant as the X-Scope-OrgID header instead", label, m.Name)
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
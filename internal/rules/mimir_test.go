@@ -0,0 +1,79 @@
+// ABOUTME: Tests for the Mimir tenant label checks - tenant_id is a warning and __tenant_id__ an error, once each
+// ABOUTME: Only the mimir profile runs them; other profiles leave tenant labels alone
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestCheckMimirTenantLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// Code, severity and metric of each finding, in order
+		want []string
+	}{
+		{name: "no tenant labels", input: `http_requests_total{method="GET"} 1`},
+		{name: "tenant_id on every series", input: `http_requests_total{tenant_id="a"} 1
+http_requests_total{tenant_id="b"} 1
+queue_depth{tenant_id="a"} 3
+`, want: []string{"mimir-tenant-label warning http_requests_total"}},
+		{name: "reserved tenant label", input: `http_requests_total{__tenant_id__="a"} 1`,
+			want: []string{"mimir-reserved-label error http_requests_total"}},
+		{name: "both", input: `queue_depth{__tenant_id__="a",tenant_id="a"} 1`,
+			want: []string{"mimir-tenant-label warning queue_depth", "mimir-reserved-label error queue_depth"}},
+		{name: "similar names", input: `http_requests_total{tenant="a",tenant_identifier="b"} 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range checkMimirTenantLabels(parsed) {
+				got = append(got, string(f.Code)+" "+string(f.Severity)+" "+f.Metric)
+				if !strings.Contains(f.Message, "X-Scope-OrgID") {
+					t.Errorf("message = %q, want it to point to X-Scope-OrgID", f.Message)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMimirProfileFlagsTenantLabels(t *testing.T) {
+	const input = `http_requests_total{tenant_id="a"} 1`
+	for _, tt := range []struct {
+		profile string
+		want    bool
+	}{
+		{MimirProfile, true},
+		{DefaultProfile, false},
+		{VictoriaMetricsProfile, false},
+	} {
+		t.Run(tt.profile, func(t *testing.T) {
+			p, err := Profile(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := p.Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flagged := false
+			for _, f := range p.Check(parsed) {
+				flagged = flagged || f.Code == "mimir-tenant-label"
+			}
+			if flagged != tt.want {
+				t.Errorf("tenant_id flagged = %v, want %v", flagged, tt.want)
+			}
+		})
+	}
+}
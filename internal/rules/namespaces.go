@@ -0,0 +1,181 @@
+// ABOUTME: Namespace consistency rule - decomposes names into namespace_subsystem_name like client libraries build them
+// ABOUTME: Reports spelling variants of one namespace and feeds the namespace tree shown on the result page
+
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Namespaces exposed by client libraries themselves, expected alongside an application's own
+var wellKnownNamespaces = []string{"go", "process", "promhttp"}
+
+// NameParts is a metric name split the way client libraries assemble it
+type NameParts struct {
+	Namespace string
+	Subsystem string
+	Name      string
+}
+
+// NamespaceGroup is one namespace of the tree view, with its subsystems in name order
+type NamespaceGroup struct {
+	Namespace  string
+	Subsystems []SubsystemGroup
+}
+
+// SubsystemGroup lists the metric families under one subsystem ("" for none)
+type SubsystemGroup struct {
+	Subsystem string
+	Metrics   []string
+}
+
+// Decompose splits a family name on its own: the first word is the namespace and,
+// when at least two words precede the unit and type suffixes, the next word is the subsystem
+func Decompose(name string) NameParts {
+	return decompose(name, nil)
+}
+
+// decompose splits a family name, treating the first two words as the
+// namespace when they spell a namespace used elsewhere (my_app next to myapp)
+func decompose(name string, known map[string]bool) NameParts {
+	words := strings.Split(name, "_")
+	if len(words) < 2 {
+		return NameParts{Name: name}
+	}
+
+	nsWords := 1
+	if len(words) > 2 && known[words[0]+words[1]] {
+		nsWords = 2
+	}
+	parts := NameParts{Namespace: strings.Join(words[:nsWords], "_")}
+	rest := words[nsWords:]
+
+	// Unit and type suffixes belong to the name, never the subsystem
+	core := len(rest)
+	for core > 0 && (rest[core-1] == "total" || rest[core-1] == "info" || slices.Contains(baseUnits, rest[core-1])) {
+		core--
+	}
+	if core >= 2 {
+		parts.Subsystem = rest[0]
+		rest = rest[1:]
+	}
+	parts.Name = strings.Join(rest, "_")
+	return parts
+}
+
+// NamespaceTree groups the submission's metric families by inferred namespace
+// and subsystem. Single-family submissions return nil.
+func NamespaceTree(parsed *metrics.ParsedMetrics) []NamespaceGroup {
+	families := namespaceFamilies(parsed)
+	if len(families) < 2 {
+		return nil
+	}
+
+	byNamespace := make(map[string]map[string][]string)
+	for family, parts := range decomposeFamilies(families) {
+		if byNamespace[parts.Namespace] == nil {
+			byNamespace[parts.Namespace] = make(map[string][]string)
+		}
+		byNamespace[parts.Namespace][parts.Subsystem] = append(byNamespace[parts.Namespace][parts.Subsystem], family)
+	}
+
+	var tree []NamespaceGroup
+	for _, ns := range sortedKeys(byNamespace) {
+		group := NamespaceGroup{Namespace: ns}
+		for _, sub := range sortedKeys(byNamespace[ns]) {
+			names := byNamespace[ns][sub]
+			sort.Strings(names)
+			group.Subsystems = append(group.Subsystems, SubsystemGroup{Subsystem: sub, Metrics: names})
+		}
+		tree = append(tree, group)
+	}
+	return tree
+}
+
+func checkNamespaces(parsed *metrics.ParsedMetrics) []Finding {
+	families := namespaceFamilies(parsed)
+	if len(families) < 2 {
+		return nil
+	}
+
+	// Spellings of each namespace, keyed by the namespace with underscores removed
+	counts := make(map[string]map[string]int)
+	for _, parts := range decomposeFamilies(families) {
+		if parts.Namespace == "" || slices.Contains(wellKnownNamespaces, parts.Namespace) {
+			continue
+		}
+		key := strings.ReplaceAll(parts.Namespace, "_", "")
+		if counts[key] == nil {
+			counts[key] = make(map[string]int)
+		}
+		counts[key][parts.Namespace]++
+	}
+
+	var findings []Finding
+	for _, key := range sortedKeys(counts) {
+		spellings := counts[key]
+		if len(spellings) < 2 {
+			continue
+		}
+
+		variants := sortedKeys(spellings)
+		// Majority wins; ties go to the spelling sorted first for a stable suggestion
+		sort.SliceStable(variants, func(i, j int) bool { return spellings[variants[i]] > spellings[variants[j]] })
+
+		var described []string
+		for _, v := range variants {
+			described = append(described, fmt.Sprintf("%s_ (%d)", v, spellings[v]))
+		}
+		findings = append(findings, Finding{
+			Code:     "namespace-inconsistent",
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("Metrics use several spellings of one namespace: %s; use %s_ for all of them",
+				strings.Join(described, ", "), variants[0]),
+		})
+	}
+	return findings
+}
+
+// namespaceFamilies returns unique family names with series and _total suffixes removed
+func namespaceFamilies(parsed *metrics.ParsedMetrics) []string {
+	seen := make(map[string]bool)
+	var families []string
+	for _, name := range familyNames(parsed) {
+		family := baseName(name)
+		if !seen[family] {
+			seen[family] = true
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+func decomposeFamilies(families []string) map[string]NameParts {
+	// Single-word namespaces seen in the submission, for spotting my_app beside myapp
+	known := make(map[string]bool)
+	for _, family := range families {
+		if first, _, ok := strings.Cut(family, "_"); ok {
+			known[first] = true
+		}
+	}
+
+	parts := make(map[string]NameParts, len(families))
+	for _, family := range families {
+		parts[family] = decompose(family, known)
+	}
+	return parts
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,276 @@
+// ABOUTME: Packed-dimension rule - spots labels whose values join several dimensions with -, / or :
+// ABOUTME: Suggests label names from token patterns and exposes the split for the improved example
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Observed values needed before a shared pattern counts as deliberate
+const minPackedValues = 3
+
+// Separators tried in order; the first one every value splits on evenly wins
+var packedSeparators = []string{"/", ":", "-"}
+
+// Labels whose values are composite by convention (host:port, URLs, generated names)
+var unsplittableLabels = []string{"instance", "le", "quantile", "path", "url", "pod", "container", "node", "version"}
+
+var (
+	// Cloud availability zones: us-east-1a is region us-east-1 plus zone a
+	availabilityZone = regexp.MustCompile(`^([a-z]{2}-[a-z]+-\d+)([a-z])$`)
+	// tenant42 is tenant=42
+	prefixedNumber = regexp.MustCompile(`^([a-z]+)(\d+)$`)
+	// us-east, eu-west-2
+	regionName = regexp.MustCompile(`^(us|eu|ap|sa|ca|me|af)-[a-z]+(-\d+)?$`)
+	plainWord  = regexp.MustCompile(`^[a-z]+$`)
+)
+
+var environmentNames = []string{"prod", "production", "staging", "stage", "dev", "development", "test", "qa"}
+
+// PackedLabel is a label whose values hold several dimensions, with the labels
+// they should be split into
+type PackedLabel struct {
+	Label string
+	// Suggested label names, in value order
+	Parts []string
+	// Distinct values of the packed label and of each suggested label
+	Values     int
+	PartValues []int
+	Example    string
+
+	separator string
+	// Token positions kept as labels, and a prefix each drops (tenant42 -> 42)
+	keep     []int
+	prefixes []string
+}
+
+// Split returns the suggested labels for one value of the packed label, or
+// false when the value doesn't follow the observed pattern
+func (p PackedLabel) Split(value string) (map[string]string, bool) {
+	tokens := p.tokens(value)
+	if tokens == nil {
+		return nil, false
+	}
+
+	labels := make(map[string]string, len(p.Parts))
+	for i, pos := range p.keep {
+		if pos >= len(tokens) {
+			return nil, false
+		}
+		labels[p.Parts[i]] = strings.TrimPrefix(tokens[pos], p.prefixes[i])
+	}
+	return labels, true
+}
+
+func (p PackedLabel) tokens(value string) []string {
+	if p.separator == "" {
+		if m := availabilityZone.FindStringSubmatch(value); m != nil {
+			return m[1:]
+		}
+		return nil
+	}
+	return strings.Split(value, p.separator)
+}
+
+// PackedLabels returns the submission's labels that pack several dimensions
+// into each value, in label name order
+func PackedLabels(parsed *metrics.ParsedMetrics) []PackedLabel {
+	values := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, m := range parsed.Metrics {
+		for name, value := range m.Labels {
+			if key := name + "=" + value; !seen[key] {
+				seen[key] = true
+				values[name] = append(values[name], value)
+			}
+		}
+	}
+
+	var packed []PackedLabel
+	for _, name := range sortedKeys(values) {
+		if slices.Contains(unsplittableLabels, name) || isHighCardinality(parsed, name) {
+			continue
+		}
+		sort.Strings(values[name])
+		if p, ok := detectPacked(name, values[name]); ok {
+			packed = append(packed, p)
+		}
+	}
+	return packed
+}
+
+func checkPackedLabels(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, p := range PackedLabels(parsed) {
+		counts := make([]string, len(p.PartValues))
+		for i, n := range p.PartValues {
+			counts[i] = fmt.Sprint(n)
+		}
+		findings = append(findings, Finding{
+			Code:     "packed-label",
+			Severity: SeverityInfo,
+			Message: fmt.Sprintf("%s packs several dimensions into one value (%q); split it into %s so queries can aggregate by each. "+
+				"Series count is unchanged: each of the %d values maps to one combination of %s values",
+				p.Label, p.Example, strings.Join(p.Parts, ", "), p.Values, strings.Join(counts, "/")),
+		})
+	}
+	return findings
+}
+
+func detectPacked(label string, values []string) (PackedLabel, bool) {
+	if len(values) < minPackedValues {
+		return PackedLabel{}, false
+	}
+
+	p := PackedLabel{Label: label, Values: len(values), Example: values[0]}
+	columns, ok := tokenColumns(values, "")
+	for _, sep := range packedSeparators {
+		if ok {
+			break
+		}
+		columns, ok = tokenColumns(values, sep)
+		p.separator = sep
+	}
+	if !ok {
+		return PackedLabel{}, false
+	}
+
+	// Constant tokens are decoration ("shard" in tenant42-shard-7), not dimensions
+	varying := 0
+	repeated := false
+	for _, col := range columns {
+		if n := distinct(col); n > 1 {
+			varying++
+			repeated = repeated || n < len(values)
+		}
+	}
+	// One varying token is a decorated value, and no repeats at all looks like an ID
+	if varying < 2 || !repeated {
+		return PackedLabel{}, false
+	}
+
+	var unnamed []int
+	for pos, col := range columns {
+		if distinct(col) == 1 {
+			continue
+		}
+		name, prefix := partName(columns, pos)
+		if p.separator == "" {
+			name, prefix = []string{"region", "zone"}[pos], ""
+		}
+		if name == "" {
+			unnamed = append(unnamed, len(p.Parts))
+		}
+		p.keep = append(p.keep, pos)
+		p.Parts = append(p.Parts, name)
+		p.prefixes = append(p.prefixes, prefix)
+	}
+
+	// Leftover positions take the words of the label name when they line up
+	// (env_region), otherwise the first keeps the label name itself
+	words := strings.Split(label, "_")
+	for i, idx := range unnamed {
+		switch {
+		case len(words) == len(p.Parts):
+			p.Parts[idx] = words[idx]
+		case i == 0:
+			p.Parts[idx] = label
+		default:
+			p.Parts[idx] = fmt.Sprintf("%s_%d", label, p.keep[idx]+1)
+		}
+	}
+	for i := range p.Parts {
+		if slices.Index(p.Parts, p.Parts[i]) != i {
+			p.Parts[i] = fmt.Sprintf("%s_%d", p.Parts[i], p.keep[i]+1)
+		}
+	}
+
+	for _, pos := range p.keep {
+		p.PartValues = append(p.PartValues, distinct(columns[pos]))
+	}
+	return p, true
+}
+
+// tokenColumns splits every value into the same number of non-empty tokens and
+// returns them by position. An empty separator matches availability zones.
+func tokenColumns(values []string, sep string) ([][]string, bool) {
+	var columns [][]string
+	for _, v := range values {
+		var tokens []string
+		if sep == "" {
+			m := availabilityZone.FindStringSubmatch(v)
+			if m == nil {
+				return nil, false
+			}
+			tokens = m[1:]
+		} else {
+			tokens = strings.Split(v, sep)
+		}
+
+		if len(tokens) < 2 || (columns != nil && len(tokens) != len(columns)) || slices.Contains(tokens, "") {
+			return nil, false
+		}
+		if columns == nil {
+			columns = make([][]string, len(tokens))
+		}
+		for i, tok := range tokens {
+			columns[i] = append(columns[i], tok)
+		}
+	}
+	return columns, true
+}
+
+// partName derives a label name from the tokens at one position, returning
+// the prefix to strip from each token when the name comes from the tokens themselves
+func partName(columns [][]string, pos int) (string, string) {
+	col := columns[pos]
+
+	if pos > 0 && distinct(columns[pos-1]) == 1 && plainWord.MatchString(columns[pos-1][0]) {
+		return columns[pos-1][0], ""
+	}
+	if m := prefixedNumber.FindStringSubmatch(col[0]); m != nil && allMatch(col, func(tok string) bool {
+		n := prefixedNumber.FindStringSubmatch(tok)
+		return n != nil && n[1] == m[1]
+	}) {
+		return m[1], m[1]
+	}
+	if allMatch(col, func(tok string) bool { return slices.Contains(environmentNames, tok) }) {
+		return "env", ""
+	}
+	if allMatch(col, regionName.MatchString) {
+		return "region", ""
+	}
+	return "", ""
+}
+
+func allMatch(tokens []string, match func(string) bool) bool {
+	for _, tok := range tokens {
+		if !match(tok) {
+			return false
+		}
+	}
+	return true
+}
+
+func distinct(tokens []string) int {
+	seen := make(map[string]bool)
+	for _, tok := range tokens {
+		seen[tok] = true
+	}
+	return len(seen)
+}
+
+func isHighCardinality(parsed *metrics.ParsedMetrics, label string) bool {
+	if parsed.CardinalityAnalysis == nil {
+		return false
+	}
+	info, ok := parsed.CardinalityAnalysis.LabelAnalysis[label]
+	return ok && info.IsHighCardinality
+}
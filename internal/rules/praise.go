@@ -0,0 +1,147 @@
+// ABOUTME: Positive-finding rules - recognize conventions the submission already follows
+// ABOUTME: Covers _total suffixes, base units, bounded labels, complete histograms and useful HELP text
+
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Base units recommended by the Prometheus naming guidelines
+var baseUnits = []string{"seconds", "bytes", "ratio", "celsius", "meters", "volts", "amperes", "joules", "grams"}
+
+// Minimum number of words before HELP text counts as a real description
+const minHelpWords = 3
+
+func praiseTotalSuffix(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		if strings.HasSuffix(name, "_total") && name != "_total" {
+			findings = append(findings, Finding{
+				Code:     "total-suffix",
+				Severity: SeverityPraise,
+				Metric:   name,
+				Message:  fmt.Sprintf("%s uses the _total suffix expected for counters", name),
+			})
+		}
+	}
+	return findings
+}
+
+func praiseBaseUnits(parsed *metrics.ParsedMetrics) []Finding {
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, name := range familyNames(parsed) {
+		family := strings.TrimSuffix(baseName(name), "_total")
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		for _, unit := range baseUnits {
+			if strings.HasSuffix(family, "_"+unit) {
+				findings = append(findings, Finding{
+					Code:     "base-unit",
+					Severity: SeverityPraise,
+					Metric:   family,
+					Message:  fmt.Sprintf("%s is expressed in the base unit (%s)", family, unit),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func praiseBoundedLabels(parsed *metrics.ParsedMetrics) []Finding {
+	analysis := parsed.CardinalityAnalysis
+	if analysis == nil || len(analysis.LabelAnalysis) == 0 {
+		return nil
+	}
+
+	var names []string
+	for name, info := range analysis.LabelAnalysis {
+		// An empty name would read as "Labels ()"
+		if name == "" || name == "le" || name == "quantile" {
+			continue
+		}
+		if info.IsHighCardinality || info.CardinalityRisk != "LOW" {
+			return nil
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	return []Finding{{
+		Code:     "bounded-labels",
+		Severity: SeverityPraise,
+		Message:  fmt.Sprintf("Labels (%s) are bounded, low-cardinality dimensions", strings.Join(names, ", ")),
+	}}
+}
+
+func praiseHistogramStructure(parsed *metrics.ParsedMetrics) []Finding {
+	present := make(map[string]bool)
+	hasInfBucket := make(map[string]bool)
+	var histograms []string
+
+	for _, m := range parsed.Metrics {
+		present[m.Name] = true
+		if base := strings.TrimSuffix(m.Name, "_bucket"); base != m.Name {
+			if !slices.Contains(histograms, base) {
+				histograms = append(histograms, base)
+			}
+			if m.Labels["le"] == "+Inf" {
+				hasInfBucket[base] = true
+			}
+		}
+	}
+
+	// Buckets that break checkHistogramBuckets' invariants aren't complete either
+	broken := make(map[string]bool)
+	for _, s := range parsed.Histograms() {
+		broken[s.Family] = broken[s.Family] || !s.Valid()
+	}
+
+	var findings []Finding
+	for _, base := range histograms {
+		if hasInfBucket[base] && present[base+"_sum"] && present[base+"_count"] && !broken[base] {
+			findings = append(findings, Finding{
+				Code:     "histogram-structure",
+				Severity: SeverityPraise,
+				Metric:   base,
+				Message:  fmt.Sprintf("%s is a complete histogram (_bucket with +Inf, _sum and _count)", base),
+			})
+		}
+	}
+	return findings
+}
+
+func praiseHelpText(parsed *metrics.ParsedMetrics) []Finding {
+	var families []string
+	for family := range parsed.Help {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	var findings []Finding
+	for _, family := range families {
+		if len(strings.Fields(parsed.Help[family])) >= minHelpWords {
+			findings = append(findings, Finding{
+				Code:     "help-text",
+				Severity: SeverityPraise,
+				Metric:   family,
+				Message:  fmt.Sprintf("%s has HELP text describing what it measures", family),
+			})
+		}
+	}
+	return findings
+}
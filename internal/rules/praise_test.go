@@ -0,0 +1,38 @@
+// ABOUTME: Tests for the praise rules - bounded labels are named, and a nameless label isn't
+// ABOUTME: An empty label name must never print as "Labels () are bounded"
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestPraiseBoundedLabels(t *testing.T) {
+	low := cardinality.LabelInfo{CardinalityRisk: "LOW"}
+	tests := []struct {
+		name   string
+		labels map[string]cardinality.LabelInfo
+		want   string
+	}{
+		{"bounded labels", map[string]cardinality.LabelInfo{"method": low, "code": low, "le": low}, "Labels (code, method) are bounded, low-cardinality dimensions"},
+		{"empty name skipped", map[string]cardinality.LabelInfo{"": low, "method": low}, "Labels (method) are bounded, low-cardinality dimensions"},
+		{"only an empty name", map[string]cardinality.LabelInfo{"": low}, ""},
+		{"a risky label", map[string]cardinality.LabelInfo{"method": low, "user": {CardinalityRisk: "HIGH", IsHighCardinality: true}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := &metrics.ParsedMetrics{CardinalityAnalysis: &cardinality.Analysis{LabelAnalysis: tt.labels}}
+			findings := praiseBoundedLabels(parsed)
+			got := ""
+			if len(findings) > 0 {
+				got = findings[0].Message
+			}
+			if got != tt.want {
+				t.Errorf("praise = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
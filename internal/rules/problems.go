@@ -0,0 +1,119 @@
+// ABOUTME: Problem-finding rules - naming and cardinality checks that flag things to fix
+// ABOUTME: Wraps the calculator's name validation and high-cardinality detection, and flags non-base units
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/naming"
+	"github.com/wbollock/good_telemetry/internal/units"
+)
+
+func checkMetricNames(parsed *metrics.ParsedMetrics) []Finding {
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, name := range familyNames(parsed) {
+		// Judge the family name so _bucket/_sum/_count/_total series don't hide a unit suffix
+		family := strings.TrimSuffix(baseName(name), "_total")
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		for _, issue := range cardinality.ValidateMetricName(family) {
+			findings = append(findings, Finding{
+				Code:     "metric-name",
+				Severity: SeverityWarning,
+				Metric:   name,
+				Message:  fmt.Sprintf("%s: %s", name, issue),
+			})
+		}
+	}
+	return findings
+}
+
+func checkCamelCase(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		if issue, ok := naming.CamelCaseIssue(name); ok {
+			findings = append(findings, Finding{
+				Code:     "camel-case",
+				Severity: SeverityWarning,
+				Metric:   name,
+				Message:  issue,
+			})
+		}
+	}
+	return findings
+}
+
+func checkHighCardinalityLabels(parsed *metrics.ParsedMetrics) []Finding {
+	if parsed.CardinalityAnalysis == nil {
+		return nil
+	}
+
+	var labelNames []string
+	for name, info := range parsed.CardinalityAnalysis.LabelAnalysis {
+		if info.IsHighCardinality {
+			labelNames = append(labelNames, name)
+		}
+	}
+	sort.Strings(labelNames)
+
+	// The first sample carrying a label shows where its value goes instead,
+	// together with any other removed labels on that sample
+	covered := make(map[string]bool)
+	var findings []Finding
+	for _, name := range labelNames {
+		f := Finding{
+			Code:     "high-cardinality-label",
+			Severity: SeverityError,
+			Message:  parsed.CardinalityAnalysis.LabelAnalysis[name].RecommendedAction,
+		}
+		if !covered[name] {
+			for _, m := range parsed.Metrics {
+				if _, ok := m.Labels[name]; ok {
+					f.LogAlternative = logAlternative(parsed, m, labelNames)
+					for _, l := range f.LogAlternative.Labels {
+						covered[l] = true
+					}
+					break
+				}
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func checkBaseUnits(parsed *metrics.ParsedMetrics) []Finding {
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, name := range familyNames(parsed) {
+		family := baseName(name)
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		renamed, conv, ok := units.Rename(family)
+		if !ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Code:     "non-base-unit",
+			Severity: SeverityWarning,
+			Metric:   family,
+			Message: fmt.Sprintf("%s is measured in %s; rename to %s and convert values (%s) to the base unit",
+				family, conv.From, renamed, conv.Describe()),
+		})
+	}
+	return findings
+}
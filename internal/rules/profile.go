@@ -0,0 +1,309 @@
+// ABOUTME: Naming profiles - per-backend rule sets, cardinality limits and LLM guidance
+// ABOUTME: Prometheus is the default; New Relic and Datadog expect dot.separated names, VictoriaMetrics and Mimir add their own checks
+
+package rules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/naming"
+)
+
+const DefaultProfile = "prometheus"
+
+// NamingProfile is one backend's metric conventions: the rules that enforce
+// them, the cardinality it copes with, and what the LLM is told about it
+type NamingProfile struct {
+	Name       string
+	Thresholds cardinality.Thresholds
+	// Added to the LLM prompt after the Prometheus best practices
+	PromptInstructions string
+
+	rules []rule
+	// Vague and forbidden words, checked whatever the backend; see WithLexicon
+	lexicon Lexicon
+	// Retired names on top of KnownRenames; see WithRenames
+	renames map[string]string
+	// When set, the only label names allowed
+	AllowedLabels []string
+	// Typical distinct values by label name, used for single-sample
+	// submissions; see WithValueEstimates
+	valueEstimates map[string]int
+	// Distinct values the submitter asserts labels are bounded by; see WithLabelBounds
+	labelBounds map[string]int
+	// The order labels are written in; nil means metrics.DefaultLabelOrder. See WithLabelOrder.
+	labelOrder *metrics.LabelOrder
+	// Reads submissions; nil means Prometheus exposition text
+	parse func(input string) (*metrics.ParsedMetrics, error)
+}
+
+var profiles = map[string]NamingProfile{
+	"prometheus": {
+		Name:       "prometheus",
+		Thresholds: cardinality.DefaultThresholds,
+		rules:      registry,
+	},
+	"newrelic": {
+		Name: "newrelic",
+		// New Relic's default limit is 100k unique attribute combinations per metric per day
+		Thresholds: cardinality.Thresholds{
+			LabelValuesMonitor: 200,
+			LabelValuesReview:  1000,
+			SeriesLow:          1000,
+			SeriesMedium:       10000,
+			SeriesHigh:         100000,
+		},
+		PromptInstructions: `NAMING CONVENTION: These metrics are sent to New Relic and queried with NRQL, not PromQL.
+Judge names by New Relic conventions instead of the Prometheus naming rules above:
+- Names are dot.separated (myapp.http.requests); underscore_separated names are non-standard
+- Do NOT require _total, _seconds or other Prometheus suffixes; New Relic records units as metadata
+- New Relic allows about 100,000 unique attribute combinations per metric per day, so only flag genuinely unbounded attributes`,
+		rules: []rule{
+			{"dotted-names", 1, checkDottedNames("New Relic", false)},
+			registered("high-cardinality-labels"),
+			registered("packed-labels"),
+			registered("value-labels"),
+			registered("praise-bounded-labels"),
+			registered("praise-help-text"),
+		},
+	},
+	"datadog": {
+		Name: "datadog",
+		// Every tag combination is a billed custom metric, so call out growth early
+		Thresholds: cardinality.Thresholds{
+			LabelValuesMonitor: 10,
+			LabelValuesReview:  50,
+			SeriesLow:          50,
+			SeriesMedium:       100,
+			SeriesHigh:         1000,
+		},
+		PromptInstructions: `NAMING CONVENTION: These metrics are sent to Datadog (DogStatsD, key:value tags), not Prometheus.
+Judge them by Datadog conventions instead of the Prometheus naming rules above:
+- Names are dot.separated.lowercase (myapp.http.requests); underscores in names are non-standard for Datadog
+- Tags are colon-delimited key:value pairs (env:prod); bare tags without a value cannot be grouped by key
+- Do NOT require _total, _seconds or other Prometheus suffixes; the metric type and unit are metadata
+- Datadog bills every unique combination of metric name and tag values as a custom metric, so judge cardinality by cost`,
+		rules: []rule{
+			{"dotted-names", 1, checkDottedNames("Datadog", true)},
+			{"datadog-tags", 1, checkDatadogTags},
+			{"custom-metrics", 1, checkCustomMetrics},
+			registered("high-cardinality-labels"),
+			registered("packed-labels"),
+			registered("value-labels"),
+			registered("praise-bounded-labels"),
+		},
+		parse: parseDatadog,
+	},
+	VictoriaMetricsProfile: {
+		Name:       VictoriaMetricsProfile,
+		Thresholds: cardinality.DefaultThresholds,
+		PromptInstructions: `QUERY LANGUAGE: These metrics are stored in VictoriaMetrics and queried with MetricsQL, a PromQL superset.
+The Prometheus naming rules above still apply. In addition:
+- Flag metric names that equal MetricsQL functions (rate, rollup, sum, count...) and label names that equal keywords (by, on, offset, if, default...)
+- MetricsQL adds rollup functions such as rollup(), rollup_rate() and aggr_over_time(); when suggesting queries, prefer them over PromQL workarounds
+- MetricsQL lets the lookbehind window be omitted (rate(http_requests_total)), so don't require [5m] in query examples`,
+		rules: append(slices.Clone(registry), rule{"metricsql", 1, checkMetricsQL}),
+	},
+	MimirProfile: {
+		Name:       MimirProfile,
+		Thresholds: cardinality.DefaultThresholds,
+		PromptInstructions: `MULTI-TENANCY: These metrics are stored in Grafana Mimir, which keeps tenants apart by the X-Scope-OrgID header sent with every write and query.
+The Prometheus naming rules above still apply. In addition:
+- A tenant_id label does by hand what X-Scope-OrgID does and multiplies every series by the number of tenants; recommend writing each tenant under its own org ID instead
+- __tenant_id__ is reserved: Mimir adds it to the results of queries across tenants`,
+		rules: append(slices.Clone(registry), rule{"mimir-tenant-labels", 1, checkMimirTenantLabels}),
+	},
+}
+
+// Profile looks up a naming profile by name
+func Profile(name string) (NamingProfile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return NamingProfile{}, fmt.Errorf("unknown naming profile %q (choose from %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// ProfileNames lists the available profiles in name order
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads a submission in the formats the profile's backend accepts and
+// analyzes its cardinality against the profile's thresholds
+func (p NamingProfile) Parse(input string) (*metrics.ParsedMetrics, error) {
+	parse := metrics.Parse
+	if p.parse != nil {
+		parse = p.parse
+	}
+
+	parsed, err := parse(input)
+	if err != nil {
+		return nil, err
+	}
+	parsed.ReanalyzeWithOptions(p.CardinalityOptions())
+	parsed.LabelSuggestions = naming.Suggest(parsed)
+	parsed.NamingIssues = naming.Issues(parsed)
+	return parsed, nil
+}
+
+// Check runs the profile's rules
+func (p NamingProfile) Check(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, r := range p.rules {
+		findings = append(findings, r.check(parsed)...)
+	}
+	findings = append(findings, checkLexicon(p.lexicon, parsed)...)
+	findings = append(findings, checkLookalikeValues(parsed)...)
+	findings = append(findings, checkKnownRenames(p.renames, parsed)...)
+	findings = append(findings, checkAllowedLabels(p.Name, p.AllowedLabels, parsed)...)
+	findings = append(findings, checkLabelOrder(p.LabelOrder(), parsed)...)
+
+	return ensurePraise(findings)
+}
+
+// WithValueEstimates returns the profile with estimates overriding
+// cardinality.DefaultValueEstimators label by label; an estimate of 0 drops
+// the built-in one
+func (p NamingProfile) WithValueEstimates(estimates map[string]int) NamingProfile {
+	merged := maps.Clone(cardinality.DefaultValueEstimators)
+	maps.Copy(merged, estimates)
+	p.valueEstimates = merged
+	return p
+}
+
+// WithLabelBounds returns the profile analyzing each label in bounds as
+// having at most that many distinct values, rather than as unbounded
+func (p NamingProfile) WithLabelBounds(bounds map[string]int) NamingProfile {
+	p.labelBounds = bounds
+	return p
+}
+
+// WithLabelOrder returns the profile writing labels in order, in the improved
+// example and canonical output; nil restores metrics.DefaultLabelOrder
+func (p NamingProfile) WithLabelOrder(order *metrics.LabelOrder) NamingProfile {
+	p.labelOrder = order
+	return p
+}
+
+// LabelOrder is the order the profile writes labels in
+func (p NamingProfile) LabelOrder() metrics.LabelOrder {
+	if p.labelOrder == nil {
+		return metrics.DefaultLabelOrder
+	}
+	return *p.labelOrder
+}
+
+// CardinalityOptions are the profile's thresholds, label value estimates and label bounds
+func (p NamingProfile) CardinalityOptions() cardinality.Options {
+	return cardinality.Options{
+		Thresholds:     p.Thresholds,
+		ValueEstimator: cardinality.EstimateFrom(p.estimates()),
+		Bounds:         p.labelBounds,
+	}
+}
+
+func (p NamingProfile) estimates() map[string]int {
+	if p.valueEstimates == nil {
+		return cardinality.DefaultValueEstimators
+	}
+	return p.valueEstimates
+}
+
+// RuleVersion is a check's ID and the version of its behavior
+type RuleVersion struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+// The checks Check runs for every profile, configured by its lexicon, renames,
+// allowed labels and label order
+var profileChecks = []RuleVersion{
+	{"lexicon", 1},
+	{"lookalike-values", 1},
+	{"known-renames", 1},
+	{"allowed-labels", 1},
+	{"label-order", 1},
+}
+
+// Rules lists the checks the profile runs, with their versions
+func (p NamingProfile) Rules() []RuleVersion {
+	versions := make([]RuleVersion, 0, len(p.rules)+len(profileChecks))
+	for _, r := range p.rules {
+		versions = append(versions, RuleVersion{ID: r.id, Version: r.version})
+	}
+	return append(versions, profileChecks...)
+}
+
+// Fingerprint identifies how the profile judges a submission: its rules and
+// their versions, cardinality thresholds and value estimates, LLM
+// instructions, lexicon, renames and allowed labels. Whenever the same input could be judged differently,
+// the fingerprint differs.
+func (p NamingProfile) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%+v\x00%s\x00%+v\x00%v\x00%v\x00%v\x00%v\x00%v", p.Name, p.Thresholds, p.PromptInstructions, p.lexicon, p.renames, p.AllowedLabels, p.estimates(), p.labelBounds, p.LabelOrder())
+	for _, r := range p.Rules() {
+		fmt.Fprintf(h, "\x00%s@%d", r.ID, r.Version)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkDottedNames enforces dot.separated names for backends that use them,
+// optionally requiring lowercase
+func checkDottedNames(backend string, lowercase bool) ruleFunc {
+	return func(parsed *metrics.ParsedMetrics) []Finding {
+		var findings []Finding
+		for _, name := range familyNames(parsed) {
+			switch {
+			case strings.Contains(name, "_"):
+				dotted := strings.ReplaceAll(strings.Trim(strings.TrimSuffix(name, "_total"), "_"), "_", ".")
+				if lowercase {
+					dotted = strings.ToLower(dotted)
+				}
+				findings = append(findings, Finding{
+					Code:     "name-separator",
+					Severity: SeverityWarning,
+					Metric:   name,
+					Message:  fmt.Sprintf("%s uses underscores, which is non-standard for %s; separate words with dots (%s)", name, backend, dotted),
+				})
+			case strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, ".."):
+				findings = append(findings, Finding{
+					Code:     "name-separator",
+					Severity: SeverityError,
+					Metric:   name,
+					Message:  fmt.Sprintf("%s has an empty dot-separated segment", name),
+				})
+			case !strings.Contains(name, "."):
+				findings = append(findings, Finding{
+					Code:     "name-separator",
+					Severity: SeverityInfo,
+					Metric:   name,
+					Message:  fmt.Sprintf("%s is a single word; prefix it with a namespace such as myapp.%s", name, name),
+				})
+			}
+
+			if lowercase && name != strings.ToLower(name) {
+				findings = append(findings, Finding{
+					Code:     "name-case",
+					Severity: SeverityWarning,
+					Metric:   name,
+					Message:  fmt.Sprintf("%s should be lowercase for %s (%s)", name, backend, strings.ToLower(name)),
+				})
+			}
+		}
+		return findings
+	}
+}
@@ -0,0 +1,52 @@
+// ABOUTME: Tests for naming profiles - label value estimates merge over the built-in ones
+// ABOUTME: The merged estimates drive single-sample series estimates and change the fingerprint
+
+package rules
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/cardinality"
+)
+
+func TestWithValueEstimates(t *testing.T) {
+	base, err := Profile("prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := maps.Clone(cardinality.DefaultValueEstimators)
+
+	tests := []struct {
+		name      string
+		estimates map[string]int
+		input     string
+		want      int
+	}{
+		{name: "built-in estimates", input: `http_requests_total{method="GET",status="200"} 1`, want: 8 * 25},
+		{name: "an override", estimates: map[string]int{"method": 4}, input: `http_requests_total{method="GET",status="200"} 1`, want: 4 * 25},
+		{name: "a new label", estimates: map[string]int{"tenant": 40}, input: `http_requests_total{method="GET",tenant="a"} 1`, want: 8 * 40},
+		{name: "0 drops a built-in estimate", estimates: map[string]int{"status": 0}, input: `http_requests_total{method="GET",status="200"} 1`, want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			if tt.estimates != nil {
+				p = base.WithValueEstimates(tt.estimates)
+				if p.Fingerprint() == base.Fingerprint() {
+					t.Error("fingerprint unchanged by the estimates")
+				}
+			}
+			parsed, err := p.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parsed.CardinalityAnalysis.EstimatedSeries; got != tt.want {
+				t.Errorf("EstimatedSeries = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if !maps.Equal(cardinality.DefaultValueEstimators, defaults) {
+		t.Errorf("DefaultValueEstimators changed to %v", cardinality.DefaultValueEstimators)
+	}
+}
@@ -0,0 +1,135 @@
+// ABOUTME: Static rule engine - deterministic checks over parsed metrics that need no LLM
+// ABOUTME: Runs every registered rule and collects findings from errors down to praise
+
+package rules
+
+import (
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	SeverityPraise  Severity = "praise"
+)
+
+type Finding struct {
+	Code     string
+	Severity Severity
+	Metric   string
+	Message  string
+	// Set on high-cardinality labels flagged for removal
+	LogAlternative *LogAlternative
+}
+
+type ruleFunc func(parsed *metrics.ParsedMetrics) []Finding
+
+// rule is a registered check. Bump version whenever the rule's findings for
+// the same input change, so results cached under the old behavior are retired.
+type rule struct {
+	id      string
+	version int
+	check   ruleFunc
+}
+
+var registry = []rule{
+	{"metric-names", 1, checkMetricNames},
+	{"camel-case", 1, checkCamelCase},
+	{"high-cardinality-labels", 2, checkHighCardinalityLabels},
+	{"base-units", 1, checkBaseUnits},
+	{"type-consistency", 2, checkTypeConsistency},
+	{"histogram-buckets", 1, checkHistogramBuckets},
+	{"summaries", 1, checkSummaries},
+	{"counter-initialization", 1, checkCounterInitialization},
+	{"namespaces", 1, checkNamespaces},
+	{"packed-labels", 1, checkPackedLabels},
+	{"value-labels", 1, checkValueLabels},
+	{"exemplar-labels", 1, checkExemplarLabels},
+	{"praise-total-suffix", 1, praiseTotalSuffix},
+	{"praise-base-units", 1, praiseBaseUnits},
+	{"praise-bounded-labels", 1, praiseBoundedLabels},
+	{"praise-histogram-structure", 2, praiseHistogramStructure},
+	{"praise-help-text", 1, praiseHelpText},
+}
+
+// registered looks up a registry rule by ID, for profiles that run a subset
+func registered(id string) rule {
+	for _, r := range registry {
+		if r.id == id {
+			return r
+		}
+	}
+	panic("rules: no registered rule " + id)
+}
+
+// Check runs the Prometheus rules; see NamingProfile for other backends
+func Check(parsed *metrics.ParsedMetrics) []Finding {
+	return profiles[DefaultProfile].Check(parsed)
+}
+
+// Praise returns only the positive findings
+func Praise(findings []Finding) []Finding {
+	var praise []Finding
+	for _, f := range findings {
+		if f.Severity == SeverityPraise {
+			praise = append(praise, f)
+		}
+	}
+	return praise
+}
+
+// Problems returns every finding that is not praise
+func Problems(findings []Finding) []Finding {
+	var problems []Finding
+	for _, f := range findings {
+		if f.Severity != SeverityPraise {
+			problems = append(problems, f)
+		}
+	}
+	return problems
+}
+
+// ensurePraise guarantees a clean submission always lists at least one strength,
+// since a good verdict with nothing listed looks broken
+func ensurePraise(findings []Finding) []Finding {
+	for _, f := range findings {
+		if f.Severity == SeverityError || f.Severity == SeverityWarning || f.Severity == SeverityPraise {
+			return findings
+		}
+	}
+
+	return append(findings, Finding{
+		Code:     "clean",
+		Severity: SeverityPraise,
+		Message:  "No naming or cardinality problems detected by the static checks",
+	})
+}
+
+// familyNames returns the unique metric names in submission order. Selectors
+// without a metric name have none to judge.
+func familyNames(parsed *metrics.ParsedMetrics) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range parsed.Metrics {
+		if m.Name != "" && !seen[m.Name] {
+			seen[m.Name] = true
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// baseName strips the series suffixes histograms, summaries and counters expose
+func baseName(name string) string {
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_created"} {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			return base
+		}
+	}
+	return name
+}
@@ -0,0 +1,241 @@
+// ABOUTME: Summary rules - quantile label checks and the cost of migrating a summary to a histogram
+// ABOUTME: Summary quantiles can't be aggregated across instances, so averaged quantiles are flagged
+
+package rules
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+var (
+	// A recording rule (level:metric:operations) that averages or sums its input
+	aggregatedRecording = regexp.MustCompile(`^[a-zA-Z_]*:[a-zA-Z0-9_]+:.*\b(avg|mean|sum)\b`)
+	// Names that say they hold a mean of percentiles, e.g. latency_p99_avg
+	averagedPercentile = regexp.MustCompile(`(^|_)(avg|mean|average)_(p\d+|percentile|quantile)(_|$)|(^|_)(p\d+|percentile|quantile)_(avg|mean|average)(_|$)`)
+)
+
+// SummaryMigration compares a summary with the histogram that would replace it
+type SummaryMigration struct {
+	Family string
+	// Distinct label combinations, ignoring quantile
+	LabelCombinations int
+	Quantiles         int
+	SummarySeries     int
+	HistogramSeries   int
+	// client_golang definition of the replacement histogram
+	GoSnippet string
+}
+
+// SummaryMigrations returns one migration estimate per summary family, in name order
+func SummaryMigrations(parsed *metrics.ParsedMetrics) []SummaryMigration {
+	families := summaryFamilies(parsed)
+
+	var migrations []SummaryMigration
+	for _, family := range sortedKeys(families) {
+		// Recording rule output isn't instrumented code, so there is nothing to migrate
+		if strings.Contains(family, ":") {
+			continue
+		}
+		samples := families[family]
+		combos := make(map[string]bool)
+		quantiles := make(map[string]bool)
+		var labelNames []string
+		for _, m := range samples {
+			if q, ok := m.Labels["quantile"]; ok {
+				quantiles[q] = true
+			}
+			rest := withoutLabel(m.Labels, "quantile")
+			combos[metrics.FormatSample(metrics.Metric{Labels: rest})] = true
+			for name := range rest {
+				if !slices.Contains(labelNames, name) {
+					labelNames = append(labelNames, name)
+				}
+			}
+		}
+		sort.Strings(labelNames)
+
+		// Summaries expose each quantile plus _sum and _count; histograms
+		// expose each bucket plus _sum and _count
+		buckets, bucketSeries := histogramBuckets(family)
+		migrations = append(migrations, SummaryMigration{
+			Family:            family,
+			LabelCombinations: len(combos),
+			Quantiles:         len(quantiles),
+			SummarySeries:     len(combos) * (len(quantiles) + 2),
+			HistogramSeries:   len(combos) * (bucketSeries + 2),
+			GoSnippet:         histogramSnippet(family, parsed.Help[family], buckets, labelNames),
+		})
+	}
+	return migrations
+}
+
+func checkSummaries(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+
+	families := summaryFamilies(parsed)
+	for _, family := range sortedKeys(families) {
+		invalid := false
+		// Quantiles in exposition order per label combination
+		order := make(map[string][]float64)
+		var combos []string
+		for _, m := range families[family] {
+			raw, ok := m.Labels["quantile"]
+			if !ok {
+				continue
+			}
+			q, err := strconv.ParseFloat(raw, 64)
+			if err != nil || math.IsNaN(q) || q < 0 || q > 1 {
+				findings = append(findings, Finding{
+					Code:     "summary-quantile",
+					Severity: SeverityError,
+					Metric:   family,
+					Message:  fmt.Sprintf("%s has quantile=%q; quantiles are numbers between 0 and 1 (0.99, not 99)", family, raw),
+				})
+				invalid = true
+				continue
+			}
+			key := metrics.FormatSample(metrics.Metric{Labels: withoutLabel(m.Labels, "quantile")})
+			if _, ok := order[key]; !ok {
+				combos = append(combos, key)
+			}
+			order[key] = append(order[key], q)
+		}
+		if invalid {
+			continue
+		}
+
+		for _, key := range combos {
+			if !sort.Float64sAreSorted(order[key]) {
+				findings = append(findings, Finding{
+					Code:     "summary-quantile",
+					Severity: SeverityWarning,
+					Metric:   family,
+					Message:  fmt.Sprintf("%s lists its quantiles out of order; client libraries expose them ascending, so check how these series are produced", family),
+				})
+				break
+			}
+		}
+	}
+
+	return append(findings, checkAveragedQuantiles(parsed)...)
+}
+
+// checkAveragedQuantiles flags series whose name says they average or sum
+// quantiles, which gives a number that is no quantile of anything
+func checkAveragedQuantiles(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		quantileSeries := false
+		for _, m := range parsed.Metrics {
+			if _, ok := m.Labels["quantile"]; ok && m.Name == name {
+				quantileSeries = true
+				break
+			}
+		}
+
+		if (quantileSeries && aggregatedRecording.MatchString(name)) || averagedPercentile.MatchString(name) {
+			findings = append(findings, Finding{
+				Code:     "averaged-quantile",
+				Severity: SeverityWarning,
+				Metric:   name,
+				Message: fmt.Sprintf("%s looks like an average or sum of quantiles; quantiles can't be aggregated across instances, "+
+					"so the result is not a quantile of anything. Use a histogram and aggregate its buckets with histogram_quantile", name),
+			})
+		}
+	}
+	return findings
+}
+
+// summaryFamilies groups the samples of each summary family, declared or inferred
+func summaryFamilies(parsed *metrics.ParsedMetrics) map[string][]metrics.Metric {
+	families := make(map[string][]metrics.Metric)
+	for _, m := range parsed.Metrics {
+		family, typ, ok := parsed.DeclaredFamily(m.Name)
+		if !ok {
+			family, typ = baseName(m.Name), parsed.TypeOf(m.Name)
+		}
+		if typ == "summary" {
+			families[family] = append(families[family], m)
+		}
+	}
+	return families
+}
+
+// histogramBuckets picks client_golang buckets for a family and returns how
+// many bucket series they expose, counting +Inf
+func histogramBuckets(family string) (string, int) {
+	if strings.HasSuffix(family, "_bytes") {
+		// 256B to 64MiB
+		return "prometheus.ExponentialBuckets(256, 4, 10)", 11
+	}
+	// 5ms to 10s
+	return "prometheus.DefBuckets", 12
+}
+
+func histogramSnippet(family, help, buckets string, labels []string) string {
+	if help == "" {
+		help = familyHelp(family)
+	}
+	opts := fmt.Sprintf(`prometheus.HistogramOpts{
+	Name:    %q,
+	Help:    %q,
+	Buckets: %s,
+}`, family, help, buckets)
+
+	if len(labels) == 0 {
+		return fmt.Sprintf("var %s = prometheus.NewHistogram(%s)", goIdentifier(family), opts)
+	}
+	quoted := make([]string, len(labels))
+	for i, l := range labels {
+		quoted[i] = strconv.Quote(l)
+	}
+	return fmt.Sprintf("var %s = prometheus.NewHistogramVec(%s, []string{%s})", goIdentifier(family), opts, strings.Join(quoted, ", "))
+}
+
+// goIdentifier turns a metric name into a camelCase Go variable name
+func goIdentifier(name string) string {
+	var sb strings.Builder
+	for i, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == ':' || r == '.' }) {
+		if i == 0 {
+			sb.WriteString(strings.ToLower(word))
+			continue
+		}
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return sb.String()
+}
+
+func withoutLabel(labels map[string]string, drop string) map[string]string {
+	rest := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != drop {
+			rest[k] = v
+		}
+	}
+	return rest
+}
+
+// familyHelp spells out a family name as help text for a family without any,
+// naming its unit apart: http_request_duration_seconds is "Http request
+// duration in seconds."
+func familyHelp(family string) string {
+	words := strings.FieldsFunc(family, func(r rune) bool { return r == '_' })
+	if len(words) == 0 {
+		return family
+	}
+	unit := ""
+	if len(words) > 1 && slices.Contains([]string{"seconds", "bytes", "meters", "grams", "joules"}, words[len(words)-1]) {
+		unit = " in " + words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	help := strings.Join(words, " ")
+	return strings.ToUpper(help[:1]) + help[1:] + unit + "."
+}
@@ -0,0 +1,55 @@
+// ABOUTME: Tests for the summary migration snippets - the histogram's help comes from the summary or its name
+// ABOUTME: A family without help gets its name spelled out, with the unit apart
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestHistogramSnippetHelp(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "declared help", input: `# HELP rpc_duration_seconds RPC latency.
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.1
+rpc_duration_seconds_sum 1
+rpc_duration_seconds_count 10
+`, want: `Help:    "RPC latency.",`},
+		{name: "no help, with a unit", input: `# TYPE http_request_duration_seconds summary
+http_request_duration_seconds{quantile="0.5"} 0.1
+http_request_duration_seconds_sum 1
+http_request_duration_seconds_count 10
+`, want: `Help:    "Http request duration in seconds.",`},
+		{name: "no help, no unit", input: `# TYPE queue_wait summary
+queue_wait{quantile="0.9"} 3
+queue_wait_sum 30
+queue_wait_count 10
+`, want: `Help:    "Queue wait.",`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrations := SummaryMigrations(parsed)
+			if len(migrations) != 1 {
+				t.Fatalf("migrations = %+v, want one", migrations)
+			}
+			snippet := migrations[0].GoSnippet
+			if !strings.Contains(snippet, tt.want) {
+				t.Errorf("snippet =\n%s\nwant it to hold %s", snippet, tt.want)
+			}
+			if strings.Contains(snippet, "TODO") {
+				t.Errorf("snippet holds a TODO:\n%s", snippet)
+			}
+		})
+	}
+}
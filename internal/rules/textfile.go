@@ -0,0 +1,134 @@
+// ABOUTME: node_exporter textfile collector checks - timestamps, staleness, collisions and partial writes
+// ABOUTME: Enabled with source=textfile, since these only matter for files the collector reads
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+// TextfileSource marks a submission as a file for node_exporter's textfile collector
+const TextfileSource = api.SourceTextfile
+
+// TextfilePromptInstructions is appended to the profile's LLM instructions for textfile submissions
+const TextfilePromptInstructions = `SOURCE: The metrics are a file for node_exporter's textfile collector, written by a batch job or cron script:
+- Judge them as batch job results (last success, duration, items processed), not as a long-running service
+- The file is only as fresh as its last write, so a *_last_updated_timestamp_seconds gauge is expected`
+
+// Prefixes of metrics node_exporter and its Go client expose themselves
+var collectorPrefixes = []string{"node_", "go_", "process_", "promhttp_"}
+
+const lastUpdatedSuffix = "_last_updated_timestamp_seconds"
+
+// ValidSource reports whether source is empty (a scrape endpoint) or TextfileSource
+func ValidSource(source string) bool {
+	return source == "" || source == TextfileSource
+}
+
+// CheckTextfile runs the textfile collector rules over a parsed file
+func CheckTextfile(parsed *metrics.ParsedMetrics) []Finding {
+	var findings []Finding
+
+	timestamped := make(map[string]bool)
+	series := make(map[string]bool)
+	duplicated := make(map[string]bool)
+	hasLastUpdated := false
+	for _, m := range parsed.Metrics {
+		if m.Timestamp != "" && !timestamped[m.Name] {
+			timestamped[m.Name] = true
+			findings = append(findings, Finding{
+				Code:     "textfile-timestamp",
+				Severity: SeverityError,
+				Metric:   m.Name,
+				Message: fmt.Sprintf("%s carries an explicit timestamp; the textfile collector rejects files with timestamps, "+
+					"so drop it and expose the time as a *_timestamp_seconds gauge instead", m.Name),
+			})
+		}
+
+		key := metrics.FormatSample(metrics.Metric{Name: m.Name, Labels: m.Labels})
+		if series[key] && !duplicated[key] {
+			duplicated[key] = true
+			findings = append(findings, Finding{
+				Code:     "textfile-duplicate",
+				Severity: SeverityError,
+				Metric:   m.Name,
+				Message: fmt.Sprintf("%s appears more than once with the same labels; the collector fails on duplicate series, "+
+					"so write each series once", strings.TrimSpace(key)),
+			})
+		}
+		series[key] = true
+
+		if strings.HasSuffix(m.Name, lastUpdatedSuffix) {
+			hasLastUpdated = true
+		}
+	}
+
+	for _, name := range familyNames(parsed) {
+		for _, prefix := range collectorPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				findings = append(findings, Finding{
+					Code:     "textfile-collision",
+					Severity: SeverityWarning,
+					Metric:   name,
+					Message: fmt.Sprintf("%s uses the %s prefix of metrics node_exporter exposes itself; a name already "+
+						"collected elsewhere fails the scrape, so use your job's own namespace", name, prefix),
+				})
+				break
+			}
+		}
+	}
+
+	if !hasLastUpdated {
+		findings = append(findings, Finding{
+			Code:     "textfile-stale",
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("nothing shows when the file was last written, so a job that stops running keeps "+
+				"exporting its final values; add %s%s set to the write time (date +%%s) and alert when it gets old",
+				textfileNamespace(parsed), lastUpdatedSuffix),
+		})
+	}
+
+	return findings
+}
+
+// CheckTextfileWrite looks for signs that a file on disk was read mid-write
+func CheckTextfileWrite(content string) []Finding {
+	if content == "" || strings.HasSuffix(content, "\n") {
+		return nil
+	}
+	return []Finding{{
+		Code:     "textfile-partial",
+		Severity: SeverityWarning,
+		Message: "the file doesn't end in a newline, which is what a partly written file looks like; write to a temporary file " +
+			"in the same directory and mv it into place so the collector never reads half a file",
+	}}
+}
+
+// textfileNamespace picks the most common first name segment, e.g. backup from backup_duration_seconds
+func textfileNamespace(parsed *metrics.ParsedMetrics) string {
+	counts := make(map[string]int)
+	for _, name := range familyNames(parsed) {
+		ns, _, _ := strings.Cut(name, "_")
+		counts[ns]++
+	}
+
+	namespaces := make([]string, 0, len(counts))
+	for ns := range counts {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if counts[namespaces[i]] != counts[namespaces[j]] {
+			return counts[namespaces[i]] > counts[namespaces[j]]
+		}
+		return namespaces[i] < namespaces[j]
+	})
+	if len(namespaces) == 0 {
+		return "job"
+	}
+	return namespaces[0]
+}
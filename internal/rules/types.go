@@ -0,0 +1,141 @@
+// ABOUTME: TYPE consistency rules - compare # TYPE declarations with the samples actually exposed
+// ABOUTME: Flags counters without _total, broken histograms, _total gauges and summaries with le labels
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func checkTypeConsistency(parsed *metrics.ParsedMetrics) []Finding {
+	families := make([]string, 0, len(parsed.Types))
+	for family := range parsed.Types {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	var findings []Finding
+	for _, family := range families {
+		samples := declaredSamples(parsed, family)
+		if len(samples) == 0 {
+			continue
+		}
+
+		switch parsed.Types[family] {
+		case "counter":
+			findings = append(findings, checkCounter(family, samples)...)
+		case "gauge":
+			if strings.HasSuffix(family, "_total") {
+				findings = append(findings, Finding{
+					Code:     "type-mismatch",
+					Severity: SeverityWarning,
+					Metric:   family,
+					Message: fmt.Sprintf("%s is declared a gauge but named like a counter; drop the _total suffix, "+
+						"or declare it a counter if it only ever increases", family),
+				})
+			}
+		case "histogram":
+			findings = append(findings, checkHistogram(family, samples)...)
+		case "summary":
+			for _, m := range samples {
+				if _, ok := m.Labels["le"]; ok {
+					findings = append(findings, Finding{
+						Code:     "type-mismatch",
+						Severity: SeverityWarning,
+						Metric:   family,
+						Message: fmt.Sprintf("%s is declared a summary but its samples carry le labels; "+
+							"le buckets belong to histograms, so declare it # TYPE %s histogram", family, family),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	return append(findings, checkUntyped(parsed)...)
+}
+
+func checkCounter(family string, samples []metrics.Metric) []Finding {
+	for _, m := range samples {
+		// foo_created is the counter's start time, not a counter itself
+		if !strings.HasSuffix(m.Name, "_total") && !strings.HasSuffix(m.Name, "_created") {
+			return []Finding{{
+				Code:     "type-mismatch",
+				Severity: SeverityWarning,
+				Metric:   family,
+				Message: fmt.Sprintf("%s is declared a counter but lacks the _total suffix; rename it to %s_total "+
+					"(if the value can go down, it is a gauge and the TYPE is wrong instead)", m.Name, m.Name),
+			}}
+		}
+	}
+	return nil
+}
+
+// checkHistogram flags a declared histogram without buckets; checkHistogramBuckets
+// judges the buckets it has
+func checkHistogram(family string, samples []metrics.Metric) []Finding {
+	for _, m := range samples {
+		if m.Name == family+"_bucket" {
+			return nil
+		}
+	}
+	return []Finding{{
+		Code:     "type-mismatch",
+		Severity: SeverityError,
+		Metric:   family,
+		Message: fmt.Sprintf("%s is declared a histogram but exposes no %s_bucket series; "+
+			"expose buckets, or declare it a summary or gauge to match what is emitted", family, family),
+	}}
+}
+
+// checkUntyped suggests a TYPE for every family the submission doesn't declare
+func checkUntyped(parsed *metrics.ParsedMetrics) []Finding {
+	// Selectors carry no # TYPE lines to add
+	if parsed.Selector {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, name := range familyNames(parsed) {
+		if _, _, ok := parsed.DeclaredFamily(name); ok {
+			continue
+		}
+
+		family, guess := name, parsed.TypeOf(name)
+		if guess == "histogram" || guess == "summary" {
+			family = baseName(name)
+		}
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		message := fmt.Sprintf("%s has no # TYPE line; declare counter, gauge, histogram or summary", family)
+		if guess != "untyped" {
+			message = fmt.Sprintf("%s has no # TYPE line; it looks like a %s, so add # TYPE %s %s", family, guess, family, guess)
+		}
+		findings = append(findings, Finding{
+			Code:     "type-missing",
+			Severity: SeverityInfo,
+			Metric:   family,
+			Message:  message,
+		})
+	}
+	return findings
+}
+
+// declaredSamples returns the samples a # TYPE declaration covers
+func declaredSamples(parsed *metrics.ParsedMetrics, family string) []metrics.Metric {
+	var samples []metrics.Metric
+	for _, m := range parsed.Metrics {
+		if f, _, ok := parsed.DeclaredFamily(m.Name); ok && f == family {
+			samples = append(samples, m)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,73 @@
+// ABOUTME: Tests for the TYPE consistency rules on small exposition fixtures
+// ABOUTME: type-missing names the family a histogram or summary belongs to, and leaves lone _count names whole
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestTypeMissingFamilies(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		// type-missing messages, in order
+		want []string
+	}{
+		{
+			name:    "count label on a name ending in count",
+			fixture: `thread_count{count="42"} 1`,
+			want:    []string{"thread_count has no # TYPE line; declare counter, gauge, histogram or summary"},
+		},
+		{
+			name:    "lone _count",
+			fixture: "foo_count 1",
+			want:    []string{"foo_count has no # TYPE line; declare counter, gauge, histogram or summary"},
+		},
+		{
+			name:    "lone _sum",
+			fixture: "payload_sum 10",
+			want:    []string{"payload_sum has no # TYPE line; declare counter, gauge, histogram or summary"},
+		},
+		{
+			name:    "summary pair",
+			fixture: "rpc_duration_seconds_sum 10\nrpc_duration_seconds_count 4",
+			want:    []string{"rpc_duration_seconds has no # TYPE line; it looks like a summary, so add # TYPE rpc_duration_seconds summary"},
+		},
+		{
+			name: "histogram",
+			fixture: "req_seconds_bucket{le=\"1\"} 2\nreq_seconds_bucket{le=\"+Inf\"} 3\n" +
+				"req_seconds_sum 1.5\nreq_seconds_count 3",
+			want: []string{"req_seconds has no # TYPE line; it looks like a histogram, so add # TYPE req_seconds histogram"},
+		},
+		{
+			name:    "counter",
+			fixture: "jobs_total 3",
+			want:    []string{"jobs_total has no # TYPE line; it looks like a counter, so add # TYPE jobs_total counter"},
+		},
+		{
+			name:    "declared",
+			fixture: "# TYPE thread_count gauge\nthread_count 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range checkTypeConsistency(parsed) {
+				if f.Code == "type-missing" {
+					got = append(got, f.Message)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("type-missing = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
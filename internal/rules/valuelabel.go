@@ -0,0 +1,159 @@
+// ABOUTME: Value-in-label rule - spots measurements written into a label while the sample value stays constant
+// ABOUTME: thread_count{count="42"} 1 makes a series per number; the fix moves the number into the sample value
+
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+// Plain decimal numbers; ParseFloat also accepts Inf, NaN and hex, which aren't measurements
+var numericValue = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// HTTP-style status codes are categories whatever the label is called
+var statusCodeValue = regexp.MustCompile(`^[1-5]\d\d$`)
+
+// Labels whose numeric values come from a bounded vocabulary: each number
+// names a category, a bucket or a position, not an amount
+var categoricalNumericLabels = []string{
+	"le", "quantile", "status", "code", "status_code", "grpc_code", "exit_code",
+	"port", "cpu", "core", "gpu", "numa_node", "node", "worker", "slot", "index", "shard", "partition", "replica",
+	"priority", "version",
+}
+
+// Labels whose numbers identify something, like customer_id="2". They are
+// names, not amounts; whether the IDs are bounded is the cardinality rules' call.
+var identifierLabels = []string{"id", "pid", "tid", "uid", "gid", "uuid", "guid"}
+
+var identifierSuffixes = []string{"_id", "_uid", "_uuid", "_guid", "_pid", "_number"}
+
+// Numbers needed when the constant value isn't 1, since a few series can
+// match by chance
+const minValueLabelValues = 3
+
+// Example values listed in the finding
+const maxValueLabelExamples = 3
+
+func checkValueLabels(parsed *metrics.ParsedMetrics) []Finding {
+	series := make(map[string][]metrics.Metric)
+	for _, m := range parsed.Metrics {
+		// Selectors have no sample values to compare
+		if m.Name != "" && m.Matchers == nil {
+			series[m.Name] = append(series[m.Name], m)
+		}
+	}
+
+	var findings []Finding
+	for _, name := range familyNames(parsed) {
+		ms := series[name]
+		// Info and stateset metrics are 1 by design; their labels are the payload
+		if len(ms) == 0 || strings.HasSuffix(name, "_info") || !constantValue(ms) {
+			continue
+		}
+		if typ := parsed.TypeOf(name); typ == "info" || typ == "stateset" {
+			continue
+		}
+
+		labels := make(map[string]bool)
+		for _, m := range ms {
+			for l := range m.Labels {
+				labels[l] = true
+			}
+		}
+		for _, label := range slices.Sorted(maps.Keys(labels)) {
+			if f, ok := valueLabelFinding(name, label, ms); ok {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// valueLabelFinding flags label when its values are numbers that change
+// between series of a metric whose value is always 1, or at least
+// minValueLabelValues numbers when the constant is another value. A single
+// series is flagged when the label repeats a word of the metric name, as in
+// queue_size{size="17"} 1.
+func valueLabelFinding(name, label string, ms []metrics.Metric) (Finding, bool) {
+	var values []string
+	for _, m := range ms {
+		v, ok := m.Labels[label]
+		if !ok || !numericValue.MatchString(v) {
+			return Finding{}, false
+		}
+		if !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	if isCategoricalNumeric(label, values) || isIdentifier(label) {
+		return Finding{}, false
+	}
+	constant := ms[0].Value
+	switch {
+	case ms[0].FloatValue != 1 && len(values) < minValueLabelValues:
+		return Finding{}, false
+	case len(values) < 2 && (ms[0].FloatValue != 1 || !slices.Contains(strings.Split(name, "_"), label)):
+		return Finding{}, false
+	}
+
+	// The first series with the label moved into its sample value
+	fixed := metrics.Metric{Name: name, Labels: maps.Clone(ms[0].Labels), Value: ms[0].Labels[label]}
+	delete(fixed.Labels, label)
+
+	examples := values
+	if len(examples) > maxValueLabelExamples {
+		examples = examples[:maxValueLabelExamples]
+	}
+	return Finding{
+		Code:     "value-in-label",
+		Severity: SeverityWarning,
+		Metric:   name,
+		Message: fmt.Sprintf("%s records a number in label %s (%s) while the sample value is always %s, so every new number starts a series "+
+			"and PromQL can't graph or compare it; put the number in the sample value instead: %s",
+			name, label, strings.Join(examples, ", "), constant, metrics.FormatSample(fixed)),
+	}, true
+}
+
+// isCategoricalNumeric reports whether a label's numbers name categories,
+// by its name or because every value is a status code
+func isCategoricalNumeric(label string, values []string) bool {
+	if slices.Contains(categoricalNumericLabels, label) || strings.HasSuffix(label, "_code") {
+		return true
+	}
+	for _, v := range values {
+		if !statusCodeValue.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifier reports whether a label names an ID by its name, as in
+// customer_id or pid
+func isIdentifier(label string) bool {
+	label = strings.ToLower(label)
+	if slices.Contains(identifierLabels, label) {
+		return true
+	}
+	for _, suffix := range identifierSuffixes {
+		if strings.HasSuffix(label, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func constantValue(ms []metrics.Metric) bool {
+	for _, m := range ms[1:] {
+		if m.FloatValue != ms[0].FloatValue {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+// ABOUTME: Tests for the value-in-label rule on fixtures with the antipattern and with legitimate numeric labels
+// ABOUTME: Status codes, buckets, positions and IDs such as customer_id stay unflagged
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+)
+
+func TestValueInLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		// Substring of the finding's message; empty expects no finding
+		want string
+	}{
+		{"count in a label", `thread_count{count="42"} 1`, "put the number in the sample value instead: thread_count 42"},
+		{"size in a label", `queue_size{size="17"} 1`, "queue_size 17"},
+		{"changing numbers", "jobs{running=\"3\"} 1\njobs{running=\"5\"} 1", "label running (3, 5)"},
+		{"constant other than 1", "temp{reading=\"20\"} 0\ntemp{reading=\"21\"} 0\ntemp{reading=\"22\"} 0", "always 0"},
+		{"status codes", "http_requests{status=\"200\"} 1\nhttp_requests{status=\"500\"} 1", ""},
+		{"status codes under any name", "http_requests{result=\"200\"} 1\nhttp_requests{result=\"404\"} 1", ""},
+		{"histogram buckets", "req_bucket{le=\"1\"} 1\nreq_bucket{le=\"5\"} 1", ""},
+		{"cpu positions", "cpu_busy{cpu=\"0\"} 1\ncpu_busy{cpu=\"1\"} 1", ""},
+		{"customer IDs", "orders{customer_id=\"1\"} 1\norders{customer_id=\"2\"} 1", ""},
+		{"bare id", "sessions{id=\"7\"} 1\nsessions{id=\"9\"} 1", ""},
+		{"process IDs", "proc_up{pid=\"100\"} 1\nproc_up{pid=\"200\"} 1", ""},
+		{"values that change", "jobs{running=\"3\"} 1\njobs{running=\"5\"} 2", ""},
+		{"single series with an unrelated label", `build{number="42"} 1`, ""},
+		{"info metric", "app_info{build=\"1\"} 1\napp_info{build=\"2\"} 1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := metrics.Parse(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings := checkValueLabels(parsed)
+			if tt.want == "" {
+				if len(findings) > 0 {
+					t.Errorf("flagged a legitimate label: %s", findings[0].Message)
+				}
+				return
+			}
+			if len(findings) != 1 || !strings.Contains(findings[0].Message, tt.want) {
+				t.Errorf("findings = %+v, want one containing %q", findings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// ABOUTME: Prometheus scrape_config parsing - the job, targets and relabeling rules of one scrape job
+// ABOUTME: Fills in Prometheus' defaults so the simulator sees the same config Prometheus would
+
+package scrapeconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Relabel actions Prometheus supports
+const (
+	ActionReplace   = "replace"
+	ActionKeep      = "keep"
+	ActionDrop      = "drop"
+	ActionKeepEqual = "keepequal"
+	ActionDropEqual = "dropequal"
+	ActionHashMod   = "hashmod"
+	ActionLabelMap  = "labelmap"
+	ActionLabelDrop = "labeldrop"
+	ActionLabelKeep = "labelkeep"
+	ActionLowercase = "lowercase"
+	ActionUppercase = "uppercase"
+)
+
+// Actions that write to target_label and so require it
+var targetActions = []string{ActionReplace, ActionKeepEqual, ActionDropEqual, ActionHashMod, ActionLowercase, ActionUppercase}
+
+// ScrapeConfig is the part of a scrape_config block that shapes the stored series
+type ScrapeConfig struct {
+	JobName              string              `yaml:"job_name"`
+	MetricsPath          string              `yaml:"metrics_path"`
+	Scheme               string              `yaml:"scheme"`
+	ScrapeInterval       string              `yaml:"scrape_interval"`
+	ScrapeTimeout        string              `yaml:"scrape_timeout"`
+	HonorLabels          bool                `yaml:"honor_labels"`
+	Params               map[string][]string `yaml:"params"`
+	StaticConfigs        []StaticConfig      `yaml:"static_configs"`
+	RelabelConfigs       []RelabelConfig     `yaml:"relabel_configs"`
+	MetricRelabelConfigs []RelabelConfig     `yaml:"metric_relabel_configs"`
+}
+
+// StaticConfig is a list of targets sharing extra labels
+type StaticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// RelabelConfig is one relabeling step. Unmarshalling fills in Prometheus'
+// defaults for fields left out.
+type RelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    string   `yaml:"separator"`
+	Regex        string   `yaml:"regex"`
+	Modulus      uint64   `yaml:"modulus"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  string   `yaml:"replacement"`
+	Action       string   `yaml:"action"`
+
+	regex *regexp.Regexp
+}
+
+// DefaultRelabelConfig holds the values Prometheus uses for fields left out
+var DefaultRelabelConfig = RelabelConfig{
+	Separator:   ";",
+	Regex:       "(.*)",
+	Replacement: "$1",
+	Action:      ActionReplace,
+}
+
+func (c *RelabelConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = DefaultRelabelConfig
+	type plain RelabelConfig
+	if err := value.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	c.Action = strings.ToLower(c.Action)
+	return nil
+}
+
+// compiled returns the regex anchored at both ends, as Prometheus matches it,
+// or nil if it doesn't compile
+func (c *RelabelConfig) compiled() *regexp.Regexp {
+	if c.regex == nil {
+		c.regex, _ = regexp.Compile("^(?:" + c.Regex + ")$")
+	}
+	return c.regex
+}
+
+func (c *RelabelConfig) validate() error {
+	if c.compiled() == nil {
+		return fmt.Errorf("invalid regex %q", c.Regex)
+	}
+	switch c.Action {
+	case ActionReplace, ActionKeep, ActionDrop, ActionKeepEqual, ActionDropEqual, ActionHashMod,
+		ActionLabelMap, ActionLabelDrop, ActionLabelKeep, ActionLowercase, ActionUppercase:
+	default:
+		return fmt.Errorf("unknown relabel action %q", c.Action)
+	}
+	if slices.Contains(targetActions, c.Action) && c.TargetLabel == "" {
+		return fmt.Errorf("relabel action %s requires target_label", c.Action)
+	}
+	if c.Action == ActionHashMod && c.Modulus == 0 {
+		return errors.New("relabel action hashmod requires a modulus above 0")
+	}
+	return nil
+}
+
+// Parse reads a single scrape_config block. A block copied from a
+// scrape_configs list, starting with "- job_name:", is accepted too.
+func Parse(input string) (*ScrapeConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &doc); err != nil {
+		return nil, fmt.Errorf("parsing scrape config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, errors.New("the scrape config is empty")
+	}
+	node := doc.Content[0]
+	if node.Kind == yaml.SequenceNode {
+		if len(node.Content) != 1 {
+			return nil, fmt.Errorf("expected one scrape config, got %d", len(node.Content))
+		}
+		node = node.Content[0]
+	}
+
+	sc := ScrapeConfig{MetricsPath: "/metrics", Scheme: "http", ScrapeInterval: "1m", ScrapeTimeout: "10s"}
+	if err := node.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("parsing scrape config: %w", err)
+	}
+	if sc.JobName == "" {
+		return nil, errors.New("the scrape config has no job_name")
+	}
+
+	for i := range sc.RelabelConfigs {
+		if err := sc.RelabelConfigs[i].validate(); err != nil {
+			return nil, fmt.Errorf("relabel_configs[%d]: %w", i, err)
+		}
+	}
+	for i := range sc.MetricRelabelConfigs {
+		if err := sc.MetricRelabelConfigs[i].validate(); err != nil {
+			return nil, fmt.Errorf("metric_relabel_configs[%d]: %w", i, err)
+		}
+	}
+	return &sc, nil
+}
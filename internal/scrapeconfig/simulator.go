@@ -0,0 +1,276 @@
+// ABOUTME: Relabeling simulator - applies relabel_configs and metric_relabel_configs as Prometheus does
+// ABOUTME: Turns exposed samples into the series a scrape job would store, one set per target
+
+package scrapeconfig
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// Labels Prometheus sets on a target before relabeling
+const (
+	addressLabel        = "__address__"
+	schemeLabel         = "__scheme__"
+	metricsPathLabel    = "__metrics_path__"
+	scrapeIntervalLabel = "__scrape_interval__"
+	scrapeTimeoutLabel  = "__scrape_timeout__"
+	paramLabelPrefix    = "__param_"
+	nameLabel           = "__name__"
+	reservedLabelPrefix = "__"
+)
+
+// Stands in for targets found by service discovery, which can't be simulated
+const placeholderTarget = "target:80"
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SimulateRelabeling runs labels through relabelConfigs in order and returns
+// the result, or nil when a keep or drop step discards them. The input map is
+// left unchanged.
+func SimulateRelabeling(metricLabels map[string]string, relabelConfigs []RelabelConfig) map[string]string {
+	labels := maps.Clone(metricLabels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	for i := range relabelConfigs {
+		if !relabel(labels, &relabelConfigs[i]) {
+			return nil
+		}
+	}
+	return labels
+}
+
+// relabel applies one step to labels in place, reporting false when they are dropped
+func relabel(labels map[string]string, c *RelabelConfig) bool {
+	re := c.compiled()
+	if re == nil {
+		return true
+	}
+
+	values := make([]string, len(c.SourceLabels))
+	for i, name := range c.SourceLabels {
+		values[i] = labels[name]
+	}
+	val := strings.Join(values, c.Separator)
+
+	switch c.Action {
+	case ActionDrop:
+		if re.MatchString(val) {
+			return false
+		}
+	case ActionKeep:
+		if !re.MatchString(val) {
+			return false
+		}
+	case ActionDropEqual:
+		if labels[c.TargetLabel] == val {
+			return false
+		}
+	case ActionKeepEqual:
+		if labels[c.TargetLabel] != val {
+			return false
+		}
+	case ActionReplace:
+		indexes := re.FindStringSubmatchIndex(val)
+		if indexes == nil {
+			break
+		}
+		target := string(re.ExpandString(nil, c.TargetLabel, val, indexes))
+		if !labelNameRegex.MatchString(target) {
+			break
+		}
+		if res := string(re.ExpandString(nil, c.Replacement, val, indexes)); res != "" {
+			labels[target] = res
+		} else {
+			delete(labels, target)
+		}
+	case ActionLowercase:
+		labels[c.TargetLabel] = strings.ToLower(val)
+	case ActionUppercase:
+		labels[c.TargetLabel] = strings.ToUpper(val)
+	case ActionHashMod:
+		hash := md5.Sum([]byte(val))
+		labels[c.TargetLabel] = fmt.Sprint(binary.BigEndian.Uint64(hash[8:]) % c.Modulus)
+	case ActionLabelMap:
+		// Matches are made against the labels as they were before this step
+		original := maps.Clone(labels)
+		for _, name := range sortedNames(original) {
+			if re.MatchString(name) {
+				labels[re.ReplaceAllString(name, c.Replacement)] = original[name]
+			}
+		}
+	case ActionLabelDrop:
+		for _, name := range sortedNames(labels) {
+			if re.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case ActionLabelKeep:
+		for _, name := range sortedNames(labels) {
+			if !re.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+// target is one scrape target before and after relabel_configs
+type target struct {
+	// Labels after relabeling, including the __ labels Prometheus strips
+	relabeled map[string]string
+	// Labels attached to every series scraped from the target
+	labels map[string]string
+}
+
+// targets relabels the static targets, or a placeholder when there are none
+func (sc *ScrapeConfig) targets() []target {
+	statics := sc.StaticConfigs
+	if len(statics) == 0 {
+		statics = []StaticConfig{{Targets: []string{placeholderTarget}}}
+	}
+
+	var targets []target
+	for _, static := range statics {
+		for _, address := range static.Targets {
+			labels := map[string]string{
+				"job":               sc.JobName,
+				addressLabel:        address,
+				schemeLabel:         sc.Scheme,
+				metricsPathLabel:    sc.MetricsPath,
+				scrapeIntervalLabel: sc.ScrapeInterval,
+				scrapeTimeoutLabel:  sc.ScrapeTimeout,
+			}
+			for name, values := range sc.Params {
+				if len(values) > 0 {
+					labels[paramLabelPrefix+name] = values[0]
+				}
+			}
+			for name, value := range static.Labels {
+				labels[name] = value
+			}
+
+			relabeled := SimulateRelabeling(labels, sc.RelabelConfigs)
+			if relabeled == nil || relabeled[addressLabel] == "" {
+				continue
+			}
+
+			kept := make(map[string]string)
+			for name, value := range relabeled {
+				if !strings.HasPrefix(name, reservedLabelPrefix) && value != "" {
+					kept[name] = value
+				}
+			}
+			if _, ok := kept["instance"]; !ok {
+				kept["instance"] = relabeled[addressLabel]
+			}
+			targets = append(targets, target{relabeled: relabeled, labels: kept})
+		}
+	}
+	return targets
+}
+
+// Scrape returns the series Prometheus would store for ms from every target:
+// target labels attached, metric_relabel_configs applied and dropped samples left out
+func (sc *ScrapeConfig) Scrape(ms []metrics.Metric) []metrics.Metric {
+	var scraped []metrics.Metric
+	for _, t := range sc.targets() {
+		for _, m := range ms {
+			labels := map[string]string{nameLabel: m.Name}
+			for name, value := range m.Labels {
+				labels[name] = value
+			}
+			for name, value := range t.labels {
+				if existing, ok := labels[name]; ok && existing != "" {
+					if sc.HonorLabels {
+						continue
+					}
+					labels["exported_"+name] = existing
+				}
+				labels[name] = value
+			}
+
+			labels = SimulateRelabeling(labels, sc.MetricRelabelConfigs)
+			if labels == nil || labels[nameLabel] == "" {
+				continue
+			}
+
+			stored := metrics.Metric{Name: labels[nameLabel], Labels: make(map[string]string), Value: m.Value}
+			for name, value := range labels {
+				if name != nameLabel && value != "" {
+					stored.Labels[name] = value
+				}
+			}
+			stored.Raw = metrics.FormatSample(stored)
+			scraped = append(scraped, stored)
+		}
+	}
+	return scraped
+}
+
+// Check reports relabeling that changes the stored series in ways worth a second look
+func (sc *ScrapeConfig) Check() []rules.Finding {
+	var findings []rules.Finding
+	if len(sc.StaticConfigs) == 0 {
+		findings = append(findings, rules.Finding{
+			Code:     "scrape-config-discovery",
+			Severity: rules.SeverityInfo,
+			Message: fmt.Sprintf("%s has no static_configs, so a single target %s was simulated; "+
+				"labels from service discovery (__meta_*) are not known here", sc.JobName, placeholderTarget),
+		})
+	}
+
+	targets := sc.targets()
+	if len(targets) == 0 {
+		return append(findings, rules.Finding{
+			Code:     "scrape-config-no-targets",
+			Severity: rules.SeverityError,
+			Message:  fmt.Sprintf("relabel_configs drop every target of %s, so nothing would be scraped", sc.JobName),
+		})
+	}
+
+	// A label copied from a URL parameter, such as a probe's __param_target,
+	// has a different value per target and multiplies every series by it
+	flagged := make(map[string]bool)
+	for _, t := range targets {
+		for _, name := range sortedNames(t.labels) {
+			if name == "instance" || flagged[name] {
+				continue
+			}
+			for _, param := range sortedNames(t.relabeled) {
+				if strings.HasPrefix(param, paramLabelPrefix) && t.relabeled[param] == t.labels[name] {
+					flagged[name] = true
+					findings = append(findings, rules.Finding{
+						Code:     "relabel-param-label",
+						Severity: rules.SeverityWarning,
+						Metric:   name,
+						Message: fmt.Sprintf("relabel_configs copy %s into the %s label on every series of %s; it differs for "+
+							"every probed target, and instance usually identifies the target already, so drop %s or write it to instance",
+							param, name, sc.JobName, name),
+					})
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func sortedNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,74 @@
+// ABOUTME: Secrets loading - resolves API keys and other secrets from env vars, files or Vault
+// ABOUTME: Sources are chained so NAME_FILE and Vault can replace plain NAME environment variables
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretsLoader looks up a secret by its environment variable name. It returns
+// "" without error when the source doesn't hold the secret.
+type SecretsLoader interface {
+	Load(name string) (string, error)
+}
+
+// EnvLoader reads secrets straight from environment variables
+type EnvLoader struct{}
+
+func (EnvLoader) Load(name string) (string, error) {
+	return os.Getenv(name), nil
+}
+
+// FileLoader follows the NAME_FILE convention used by Docker and Kubernetes
+// secrets: the variable holds a path whose contents are the secret
+type FileLoader struct{}
+
+func (FileLoader) Load(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s_FILE: %w", name, err)
+	}
+	// Editors and echo leave a trailing newline that isn't part of the secret
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Chain tries each loader in order and returns the first non-empty secret
+type Chain []SecretsLoader
+
+func (c Chain) Load(name string) (string, error) {
+	for _, loader := range c {
+		value, err := loader.Load(name)
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// FromEnv builds the loader chain for this process: NAME_FILE first, then
+// Vault when VAULT_ADDR is set, then plain environment variables
+func FromEnv() SecretsLoader {
+	chain := Chain{FileLoader{}}
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		chain = append(chain, NewVaultLoader(VaultConfig{
+			Address:    addr,
+			Mount:      os.Getenv("VAULT_KV_MOUNT"),
+			SecretPath: os.Getenv("VAULT_SECRET_PATH"),
+			Token:      os.Getenv("VAULT_TOKEN"),
+			RoleID:     os.Getenv("VAULT_ROLE_ID"),
+			SecretID:   os.Getenv("VAULT_SECRET_ID"),
+		}))
+	}
+	return append(chain, EnvLoader{})
+}
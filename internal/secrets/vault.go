@@ -0,0 +1,150 @@
+// ABOUTME: HashiCorp Vault secrets source - reads a KV v2 secret over Vault's HTTP API
+// ABOUTME: Authenticates with a static token or AppRole and caches the secret after the first read
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type VaultConfig struct {
+	Address string
+	// KV v2 engine mount, "secret" when empty
+	Mount string
+	// Path of the secret within the mount, "good-telemetry" when empty; its
+	// keys are the environment variable names, e.g. LLM_API_KEY
+	SecretPath string
+
+	// Token auth, or AppRole auth when RoleID is set
+	Token    string
+	RoleID   string
+	SecretID string
+}
+
+type VaultLoader struct {
+	cfg        VaultConfig
+	httpClient *http.Client
+
+	once sync.Once
+	data map[string]string
+	err  error
+}
+
+func NewVaultLoader(cfg VaultConfig) *VaultLoader {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.SecretPath == "" {
+		cfg.SecretPath = "good-telemetry"
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+
+	return &VaultLoader{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (v *VaultLoader) Load(name string) (string, error) {
+	// Secrets are read once at startup; a single fetch serves every name
+	v.once.Do(func() {
+		v.data, v.err = v.fetch()
+	})
+	if v.err != nil {
+		return "", v.err
+	}
+	return v.data[name], nil
+}
+
+func (v *VaultLoader) fetch() (map[string]string, error) {
+	token, err := v.token()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.cfg.Address, v.cfg.Mount, strings.TrimPrefix(v.cfg.SecretPath, "/"))
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(req, &body); err != nil {
+		return nil, fmt.Errorf("reading Vault secret %s/%s: %w", v.cfg.Mount, v.cfg.SecretPath, err)
+	}
+
+	data := make(map[string]string, len(body.Data.Data))
+	for k, val := range body.Data.Data {
+		if s, ok := val.(string); ok {
+			data[k] = s
+		}
+	}
+	return data, nil
+}
+
+// token returns the configured token, or logs in with AppRole to get one
+func (v *VaultLoader) token() (string, error) {
+	if v.cfg.RoleID == "" {
+		if v.cfg.Token == "" {
+			return "", fmt.Errorf("VAULT_ADDR is set but neither VAULT_TOKEN nor VAULT_ROLE_ID is")
+		}
+		return v.cfg.Token, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"role_id":   v.cfg.RoleID,
+		"secret_id": v.cfg.SecretID,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, v.cfg.Address+"/v1/auth/approle/login", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var body struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := v.do(req, &body); err != nil {
+		return "", fmt.Errorf("Vault AppRole login: %w", err)
+	}
+	if body.Auth.ClientToken == "" {
+		return "", fmt.Errorf("Vault AppRole login returned no token")
+	}
+	return body.Auth.ClientToken, nil
+}
+
+func (v *VaultLoader) do(req *http.Request, out any) error {
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&vaultErr)
+		if len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
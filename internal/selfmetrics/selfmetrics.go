@@ -0,0 +1,62 @@
+// ABOUTME: Self-instrumentation - Prometheus metrics describing Good Telemetry itself
+// ABOUTME: Registered on a dedicated registry that the server exposes on /metrics
+
+package selfmetrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every self-metric; kept separate from the global default so
+// only metrics we define (plus Go and process collectors) are exposed
+var Registry = prometheus.NewRegistry()
+
+var AbuseBlocked = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "goodtelemetry",
+	Name:      "abuse_blocked_total",
+	Help:      "Evaluation requests blocked by abuse protection, by reason.",
+}, []string{"reason"})
+
+var HistoryEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "goodtelemetry",
+	Name:      "history_evictions_total",
+	Help:      "Evaluations dropped from the full in-memory history to make room for new ones.",
+})
+
+var JobQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "goodtelemetry",
+	Name:      "job_queue_depth",
+	Help:      "Evaluation jobs queued or running.",
+})
+
+var JobQueueOldestAge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "goodtelemetry",
+	Name:      "job_queue_oldest_age_seconds",
+	Help:      "Age of the oldest evaluation job queued or running; 0 when there are none.",
+})
+
+var JobsRecovered = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "goodtelemetry",
+	Name:      "jobs_recovered_total",
+	Help:      "Evaluation jobs left running by a previous run of the process and put back in the queue on startup.",
+})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		AbuseBlocked,
+		HistoryEvictions,
+		JobQueueDepth,
+		JobQueueOldestAge,
+		JobsRecovered,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,119 @@
+// ABOUTME: Golden end-to-end tests - POSTs each fixture through /evaluate with the LLM replayed from cassettes
+// ABOUTME: A changed prompt misses its cassette on purpose; the failure says how to re-record and update snapshots
+
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+var (
+	record = flag.Bool("record", false, "record cassettes from the Ollama at LLM_BACKEND_URL instead of replaying them")
+	update = flag.Bool("update", false, "rewrite the evaluation snapshots from this run")
+)
+
+const (
+	e2eDir        = "testdata/e2e"
+	rerecordSteps = "re-record with a running Ollama: LLM_BACKEND_URL=http://localhost:11434 go test ./internal/server -run TestEvaluateGolden -record -update, then review and commit the changes under internal/server/" + e2eDir
+)
+
+func TestEvaluateGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join(e2eDir, "fixtures", "*.prom"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixtures in %s/fixtures: %v", e2eDir, err)
+	}
+	router := e2eServer(t)
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".prom")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			form := url.Values{"metrics": {string(input)}}
+			req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("/evaluate returned %d: %s\n%s", rec.Code, rec.Body.String(), rerecordSteps)
+			}
+
+			var body struct {
+				Evaluation *api.Evaluation `json:"evaluation"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Evaluation == nil {
+				t.Fatalf("response has no evaluation (%v): %s", err, rec.Body.String())
+			}
+			got, err := json.MarshalIndent(body.Evaluation, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			snapshot := filepath.Join(e2eDir, "snapshots", name+".json")
+			if *update {
+				if err := os.WriteFile(snapshot, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(snapshot)
+			if err != nil {
+				t.Fatalf("reading snapshot: %v; write it with go test ./internal/server -run TestEvaluateGolden -update", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("evaluation differs from %s.\ngot:\n%s\nwant:\n%s\nIf the change is intended, %s", snapshot, got, want, rerecordSteps)
+			}
+		})
+	}
+}
+
+// e2eServer builds the server with everything but the LLM: history in
+// memory, no cache, no session cap, and the LLM served from cassettes.
+// configure adjusts the config before the server is built.
+func e2eServer(t *testing.T, configure ...func(*Config)) http.Handler {
+	t.Helper()
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConfigFile, cfg.KubernetesConfigMapMountPath = "", ""
+	cfg.DatabasePath, cfg.EvalCacheBackend, cfg.AuditLogPath = "", "", ""
+	cfg.SessionEvaluationLimit = 0
+	cfg.OIDC.Issuer = ""
+	cfg.Profile = rules.DefaultProfile
+	cfg.Model = "llama3.2:3b"
+	cfg.TemplatesDir = filepath.Join("..", "..", defaultTemplatesDir)
+	cfg.LLMCassetteDir = filepath.Join(e2eDir, "cassettes")
+	cfg.LLMCassetteMode = llm.CassetteReplay
+	if *record {
+		cfg.LLMCassetteMode = llm.CassetteRecord
+	} else {
+		// Replay never reaches the backend; nothing listens on port 1
+		cfg.LLMURL = "http://127.0.0.1:1"
+	}
+	for _, f := range configure {
+		f(&cfg)
+	}
+
+	router, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
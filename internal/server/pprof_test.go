@@ -0,0 +1,53 @@
+// ABOUTME: Tests for the /debug/pprof routes - served only with the admin key from an allowed address
+// ABOUTME: Without an admin key they aren't registered at all
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProfilesAreGatedLikeTheAdminAPI(t *testing.T) {
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.AdminAPIKey = "admin-key"
+		cfg.AdminAllowedCIDRs = []string{"192.0.2.0/24"}
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		remote string
+		key    string
+		status int
+	}{
+		{"index", "/debug/pprof/", "192.0.2.10", "admin-key", http.StatusOK},
+		{"named profile", "/debug/pprof/goroutine", "192.0.2.10", "admin-key", http.StatusOK},
+		{"without the key", "/debug/pprof/", "192.0.2.10", "", http.StatusUnauthorized},
+		{"outside the allowed addresses", "/debug/pprof/heap", "203.0.113.9", "admin-key", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remote + ":41000"
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestProfilesNeedAnAdminKey(t *testing.T) {
+	router := e2eServer(t, func(cfg *Config) { cfg.AdminAPIKey = "" })
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d without an admin key, want 404", rec.Code)
+	}
+}
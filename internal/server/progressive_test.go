@@ -0,0 +1,158 @@
+// ABOUTME: Tests for the two-phase evaluation - static results first, the LLM's verdict from a job once it answers
+// ABOUTME: A stub LLM held on a channel keeps each job pending until the test releases it
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const progressiveMetrics = `# TYPE requestCount counter
+requestCount 1
+`
+
+// heldLLMServer is the server with an LLM that answers once release is closed
+func heldLLMServer(t *testing.T) (http.Handler, chan struct{}) {
+	t.Helper()
+	release := make(chan struct{})
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			json.NewEncoder(w).Encode(map[string]any{"models": []map[string]string{{"name": "llama3.2:3b"}}})
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"response": "VERDICT: Bad\nSCORE: 20\nISSUES:\n- requestCount is camelCase", "done": true})
+	}))
+	t.Cleanup(ollama.Close)
+	// Unblock the stub before it closes, in case a test failed holding it
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.LLMCassetteDir = ""
+		cfg.LLMURL = ollama.URL
+	})
+	return router, release
+}
+
+// pollJob fetches path until it answers other than pending, failing after a few seconds
+func pollJob(t *testing.T, router http.Handler, path string, header http.Header, pending int) *httptest.ResponseRecorder {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header = header.Clone()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != pending {
+			return rec
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s still pending", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestPageShowsStaticResultsBeforeTheVerdict(t *testing.T) {
+	router, release := heldLLMServer(t)
+	htmx := http.Header{"Hx-Request": {"true"}}
+
+	req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(url.Values{"metrics": {progressiveMetrics}}.Encode()))
+	req.Header = htmx.Clone()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("evaluate = %d: %s", rec.Code, rec.Body.String())
+	}
+	page := rec.Body.String()
+	if !strings.Contains(page, "requestCount is camelCase") {
+		t.Error("the first phase lacks the static findings")
+	}
+	job := regexp.MustCompile(`hx-get="/evaluate/jobs/([0-9a-f]+)"`).FindStringSubmatch(page)
+	if job == nil {
+		t.Fatalf("the first phase has no pending verdict to poll:\n%s", page)
+	}
+
+	pending := httptest.NewRequest(http.MethodGet, "/evaluate/jobs/"+job[1], nil)
+	pending.Header = htmx.Clone()
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, pending)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("job while the LLM runs = %d, want 204 so htmx keeps polling", rec.Code)
+	}
+
+	close(release)
+	rec = pollJob(t, router, "/evaluate/jobs/"+job[1], htmx, http.StatusNoContent)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("finished job = %d: %s", rec.Code, rec.Body.String())
+	}
+	verdict := rec.Body.String()
+	if !strings.Contains(verdict, `id="result-verdict" hx-swap-oob="true"`) || !strings.Contains(verdict, "verdict-bad") {
+		t.Errorf("finished job doesn't swap in the verdict:\n%s", verdict)
+	}
+}
+
+func TestWaitFalseReturnsAJobToPoll(t *testing.T) {
+	router, release := heldLLMServer(t)
+	jsonOnly := http.Header{"Accept": {"application/json"}}
+
+	form := url.Values{"metrics": {progressiveMetrics}, "wait": {"false"}}
+	req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(form.Encode()))
+	req.Header = jsonOnly.Clone()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("evaluate with wait=false = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+	var static api.EvaluateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &static); err != nil {
+		t.Fatal(err)
+	}
+	if static.Job == "" || len(static.Problems) == 0 || static.Evaluation.Verdict != "" {
+		t.Fatalf("wait=false = %+v, want the static findings and a job without a verdict", static)
+	}
+
+	path := "/evaluate/jobs/" + static.Job
+	pending := httptest.NewRequest(http.MethodGet, path, nil)
+	pending.Header = jsonOnly.Clone()
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, pending)
+	var job api.JobResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusAccepted || job.Status != api.JobPending || job.Evaluation != nil {
+		t.Fatalf("job while the LLM runs = %d %+v, want a pending 202", rec.Code, job)
+	}
+
+	close(release)
+	rec = pollJob(t, router, path, jsonOnly, http.StatusAccepted)
+	job = api.JobResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || job.Status != api.JobDone || job.Evaluation == nil || job.Evaluation.Verdict != "Bad" || job.ID == 0 {
+		t.Errorf("finished job = %d %+v, want the stored verdict", rec.Code, job)
+	}
+}
@@ -0,0 +1,108 @@
+// ABOUTME: Tests that every HTML route answers htmx with a bare fragment and direct visits with the whole page
+// ABOUTME: The layout's doctype and site navigation are the chrome a fragment must leave out
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoutesRenderFragmentsForHtmx(t *testing.T) {
+	router := e2eServer(t)
+	fixture, err := os.ReadFile(filepath.Join(e2eDir, "fixtures", "good_counter.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		form   url.Values
+	}{
+		{"index", http.MethodGet, "/", nil},
+		{"settings", http.MethodGet, "/settings", nil},
+		{"examples", http.MethodGet, "/examples", nil},
+		{"stats", http.MethodGet, "/stats", nil},
+		{"gallery", http.MethodGet, "/gallery", nil},
+		{"monitors", http.MethodGet, "/monitors", nil},
+		{"evaluate", http.MethodPost, "/evaluate", url.Values{"metrics": {string(fixture)}}},
+		{"label check", http.MethodPost, "/evaluate/label", url.Values{"label": {"user_id"}, "values": {"100000"}}},
+		{"cardinality projection", http.MethodPost, "/cardinality/projection", url.Values{"metrics": {"http_requests_total{path=\"/\"} 1"}, "growth[path]": {"10"}}},
+		{"error", http.MethodGet, "/evaluate/jobs/missing", nil},
+		{"unknown example", http.MethodPost, "/examples/missing/run", url.Values{}},
+	}
+	for _, tt := range tests {
+		for _, htmx := range []bool{true, false} {
+			name := tt.name + " direct"
+			if htmx {
+				name = tt.name + " htmx"
+			}
+			t.Run(name, func(t *testing.T) {
+				var body *strings.Reader
+				if tt.form != nil {
+					body = strings.NewReader(tt.form.Encode())
+				} else {
+					body = strings.NewReader("")
+				}
+				req := httptest.NewRequest(tt.method, tt.path, body)
+				if tt.form != nil {
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				}
+				if htmx {
+					req.Header.Set("HX-Request", "true")
+				}
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+				if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+					t.Fatalf("Content-Type = %q (status %d): %s", rec.Header().Get("Content-Type"), rec.Code, rec.Body.String())
+				}
+
+				page := rec.Body.String()
+				chrome := strings.HasPrefix(page, "<!DOCTYPE html>") && strings.Contains(page, `<nav class="site-nav">`)
+				if htmx && (strings.Contains(page, "<!DOCTYPE html>") || strings.Contains(page, `<nav class="site-nav">`)) {
+					t.Errorf("the htmx fragment (status %d) carries the layout chrome", rec.Code)
+				}
+				if !htmx && !chrome {
+					t.Errorf("the direct visit (status %d) has no layout chrome: %.200s", rec.Code, page)
+				}
+			})
+		}
+	}
+}
+
+func TestChallengeIsAForbiddenFragment(t *testing.T) {
+	router := e2eServer(t, func(cfg *Config) { cfg.SessionEvaluationLimit = 1 })
+	fixture, err := os.ReadFile(filepath.Join(e2eDir, "fixtures", "good_counter.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cookies []*http.Cookie
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(url.Values{"metrics": {string(fixture)}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		cookies = rec.Result().Cookies()
+		return rec
+	}
+
+	if rec := post(); rec.Code != http.StatusOK {
+		t.Fatalf("first evaluation = %d", rec.Code)
+	}
+	rec := post()
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), `name="challenge_token"`) || strings.Contains(rec.Body.String(), "<!DOCTYPE html>") {
+		t.Errorf("evaluation past the cap = %d:\n%s\nwant the challenge fragment with 403", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,799 @@
+// ABOUTME: Web server assembly - builds the gin router with templates, static files and routes
+// ABOUTME: Shared by the standalone web binary and the goodtelemetry serve subcommand
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wbollock/good_telemetry/internal/abuse"
+	"github.com/wbollock/good_telemetry/internal/anonymize"
+	"github.com/wbollock/good_telemetry/internal/audit"
+	"github.com/wbollock/good_telemetry/internal/auth"
+	"github.com/wbollock/good_telemetry/internal/config"
+	"github.com/wbollock/good_telemetry/internal/cost"
+	"github.com/wbollock/good_telemetry/internal/handlers"
+	"github.com/wbollock/good_telemetry/internal/history"
+	"github.com/wbollock/good_telemetry/internal/i18n"
+	"github.com/wbollock/good_telemetry/internal/llm"
+	"github.com/wbollock/good_telemetry/internal/metrics"
+	"github.com/wbollock/good_telemetry/internal/middleware"
+	"github.com/wbollock/good_telemetry/internal/monitor"
+	"github.com/wbollock/good_telemetry/internal/quota"
+	"github.com/wbollock/good_telemetry/internal/redact"
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/secrets"
+	"github.com/wbollock/good_telemetry/internal/selfmetrics"
+	"github.com/wbollock/good_telemetry/internal/tenant"
+	"github.com/wbollock/good_telemetry/internal/usage"
+)
+
+type Config struct {
+	Port         string
+	LLMURL       string
+	LLMAPIKey    string
+	Model        string
+	DatabasePath string
+
+	// Directory of recorded LLM responses; when set, evaluations are recorded
+	// to it or, by default, replayed from it instead of calling the LLM
+	LLMCassetteDir  string
+	LLMCassetteMode llm.CassetteMode
+
+	// Fast model for small submissions and the limits that route to it; Model
+	// handles everything else
+	Routing llm.Routing
+
+	AuditLogPath       string
+	AuditRetentionDays int
+	// Empty leaves the admin API unregistered
+	AdminAPIKey string
+	// Empty allows the admin API from any address
+	AdminAllowedCIDRs []string
+	// Number of reverse proxies in front of the server whose X-Forwarded-For entries are trusted
+	TrustedProxyDepth int
+	// Proxy addresses or CIDRs whose X-Forwarded-For is believed; empty trusts none
+	TrustedProxies []string
+
+	// Empty Issuer leaves the web UI open to everyone
+	OIDC auth.OIDCConfig
+
+	// Evaluations kept when history is in memory; the oldest are evicted beyond it
+	HistorySize int
+
+	// Where LLM evaluations are cached: empty for nowhere, or disk for the
+	// bbolt file at EvalCachePath
+	EvalCacheBackend string
+	EvalCachePath    string
+
+	// Evaluations per browser session before a challenge; 0 disables the cap
+	SessionEvaluationLimit int
+	// Keys that let API clients skip abuse protection
+	APIKeys []string
+	// Shared store for API key quotas; empty counts in this process only
+	RedisURL string
+	// Hourly and daily limits by API key pattern; only settable in the config file
+	QuotaPolicies []quota.QuotaPolicy
+
+	Pricing cost.Pricing
+
+	// Naming convention metrics are judged by (see rules.ProfileNames)
+	Profile string
+	// Vague and forbidden words flagged in names; only settable in the config file
+	Lexicon rules.Lexicon
+	// Retired metric names to their replacements, on top of rules.KnownRenames;
+	// only settable in the config file
+	Renames map[string]string
+	// When set, the only label names allowed; only settable in the config file
+	AllowedLabels []string
+	// Typical distinct values by label name for single-sample submissions, on
+	// top of cardinality.DefaultValueEstimators; only settable in the config file
+	ValueEstimates map[string]int
+	// The order labels are written in; nil means metrics.DefaultLabelOrder.
+	// Only settable in the config file.
+	LabelOrder *metrics.LabelOrder
+
+	// Teams sharing the instance; only settable in the config file
+	Tenants []tenant.Tenant
+
+	// Regexes scrubbed from evaluations published to the gallery, on top of
+	// hostnames, IPs and emails; only settable in the config file
+	GalleryScrubPatterns []string
+
+	// Regexes by name redacted from logs and stored inputs, on top of the
+	// built-in token patterns; only settable in the config file
+	RedactPatterns map[string]string
+	// Redact the LLM prompt too, so the LLM never sees matched values
+	RedactBeforeLLM bool
+
+	// Opt-in endpoint aggregate usage counts are posted to every
+	// UsageReportingInterval; empty sends nothing
+	UsageReportingURL      string
+	UsageReportingInterval time.Duration
+
+	// Where monitor alerts are posted; empty only logs them
+	MonitorWebhookURL string
+	// Series growth between monitor runs, in percent, that raises an alert
+	MonitorSeriesGrowthPercent float64
+	// Runs kept per monitored endpoint
+	MonitorHistorySize int
+
+	// Largest label bound a submission may assert
+	MaxLabelBound int
+
+	// Directory the page templates are read from
+	TemplatesDir string
+	// Re-read templates on every request, for template development; never
+	// enabled unless set explicitly
+	TemplateAutoReload bool
+
+	// Let the settings page change the model and profile of the running server
+	AllowRuntimeConfigChanges bool
+
+	// Optional YAML file whose settings override the above and are reloaded on change
+	ConfigFile string
+	// ConfigMap mount directory; when set the config is read from its
+	// config.yaml key and reloaded on ConfigMap updates
+	KubernetesConfigMapMountPath string
+}
+
+// ConfigMap key holding the YAML config under KubernetesConfigMapMountPath
+const kubernetesConfigKey = "config.yaml"
+
+const (
+	// EVAL_CACHE_BACKEND for the bbolt file cache
+	evalCacheDisk = "disk"
+	// Cache file when EVAL_CACHE_PATH is unset
+	defaultEvalCachePath = "./cache.db"
+)
+
+// ConfigFromEnv loads configuration from environment variables, applying
+// defaults. Secrets may instead come from NAME_FILE or Vault (see internal/secrets).
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Port:   os.Getenv("WEB_PORT"),
+		LLMURL: os.Getenv("LLM_BACKEND_URL"),
+		Model:  os.Getenv("OLLAMA_MODEL"),
+		// Empty calls the LLM as usual
+		LLMCassetteDir:  os.Getenv("LLM_CASSETTE_DIR"),
+		LLMCassetteMode: llm.CassetteReplay,
+		// Routing stays off until a fast model is set
+		Routing: llm.DefaultRouting,
+		// Empty keeps evaluation history in memory only
+		DatabasePath: os.Getenv("DATABASE_PATH"),
+		HistorySize:  history.DefaultMemorySize,
+		// Empty calls the LLM for every evaluation
+		EvalCacheBackend: os.Getenv("EVAL_CACHE_BACKEND"),
+		EvalCachePath:    defaultEvalCachePath,
+		// Empty disables audit logging
+		AuditLogPath:                 os.Getenv("AUDIT_LOG_PATH"),
+		AuditRetentionDays:           90,
+		SessionEvaluationLimit:       20,
+		Pricing:                      cost.PricingFromEnv(),
+		Profile:                      os.Getenv("NAMING_PROFILE"),
+		RedactBeforeLLM:              os.Getenv("REDACT_BEFORE_LLM") == "1",
+		UsageReportingURL:            os.Getenv("USAGE_REPORTING_URL"),
+		UsageReportingInterval:       usage.DefaultInterval,
+		MonitorWebhookURL:            os.Getenv("MONITOR_WEBHOOK_URL"),
+		MonitorSeriesGrowthPercent:   monitor.DefaultSeriesGrowthPercent,
+		MonitorHistorySize:           monitor.DefaultHistorySize,
+		MaxLabelBound:                handlers.DefaultMaxLabelBound,
+		TemplatesDir:                 defaultTemplatesDir,
+		TemplateAutoReload:           os.Getenv("TEMPLATE_AUTO_RELOAD") == "1",
+		AllowRuntimeConfigChanges:    os.Getenv("ALLOW_RUNTIME_CONFIG_CHANGES") == "true",
+		ConfigFile:                   os.Getenv("CONFIG_FILE"),
+		KubernetesConfigMapMountPath: os.Getenv("KUBERNETES_CONFIG_MAP_MOUNT_PATH"),
+		OIDC: auth.OIDCConfig{
+			Issuer:      os.Getenv("OIDC_ISSUER"),
+			ClientID:    os.Getenv("OIDC_CLIENT_ID"),
+			RedirectURL: os.Getenv("OIDC_REDIRECT_URL"),
+		},
+	}
+
+	loader := secrets.FromEnv()
+	var apiKeys, sessionKey string
+	for _, secret := range []struct {
+		name string
+		dest *string
+	}{
+		{"LLM_API_KEY", &cfg.LLMAPIKey},
+		{"ADMIN_API_KEY", &cfg.AdminAPIKey},
+		{"API_KEYS", &apiKeys},
+		{"OIDC_CLIENT_SECRET", &cfg.OIDC.ClientSecret},
+		{"OIDC_SESSION_KEY", &sessionKey},
+		{"REDIS_URL", &cfg.RedisURL},
+	} {
+		value, err := loader.Load(secret.name)
+		if err != nil {
+			return Config{}, fmt.Errorf("loading %s: %w", secret.name, err)
+		}
+		*secret.dest = value
+	}
+	if apiKeys != "" {
+		cfg.APIKeys = strings.Split(apiKeys, ",")
+	}
+	cfg.OIDC.SessionKey = []byte(sessionKey)
+
+	cfg.Routing.FastModel = os.Getenv("OLLAMA_FAST_MODEL")
+	if models := os.Getenv("ALLOWED_MODELS"); models != "" {
+		cfg.Routing.AllowedModels = strings.Split(models, ",")
+	}
+	for _, limit := range []struct {
+		name string
+		dest *int
+	}{
+		{"ROUTING_FAST_MAX_METRICS", &cfg.Routing.FastMaxMetrics},
+		{"ROUTING_FAST_MAX_PROMPT_TOKENS", &cfg.Routing.FastMaxPromptTokens},
+	} {
+		if value := os.Getenv(limit.name); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*limit.dest = n
+			} else {
+				log.Printf("Invalid %s %q, using %d", limit.name, value, *limit.dest)
+			}
+		}
+	}
+
+	if cidrs := os.Getenv("ADMIN_ALLOWED_CIDRS"); cidrs != "" {
+		cfg.AdminAllowedCIDRs = strings.Split(cidrs, ",")
+	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, p := range strings.Split(proxies, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.TrustedProxies = append(cfg.TrustedProxies, p)
+			}
+		}
+	}
+	if depth := os.Getenv("TRUSTED_PROXY_DEPTH"); depth != "" {
+		if n, err := strconv.Atoi(depth); err == nil && n >= 0 {
+			cfg.TrustedProxyDepth = n
+		} else {
+			log.Printf("Invalid TRUSTED_PROXY_DEPTH %q, using %d", depth, cfg.TrustedProxyDepth)
+		}
+	}
+
+	if mode := os.Getenv("LLM_CASSETTE_MODE"); mode != "" {
+		cfg.LLMCassetteMode = llm.CassetteMode(mode)
+	}
+	if path := os.Getenv("EVAL_CACHE_PATH"); path != "" {
+		cfg.EvalCachePath = path
+	}
+	if dir := os.Getenv("TEMPLATES_DIR"); dir != "" {
+		cfg.TemplatesDir = dir
+	}
+
+	if interval := os.Getenv("USAGE_REPORTING_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.UsageReportingInterval = d
+		} else {
+			log.Printf("Invalid USAGE_REPORTING_INTERVAL %q, using %s", interval, cfg.UsageReportingInterval)
+		}
+	}
+
+	if growth := os.Getenv("MONITOR_SERIES_GROWTH_PERCENT"); growth != "" {
+		if p, err := strconv.ParseFloat(growth, 64); err == nil && p > 0 {
+			cfg.MonitorSeriesGrowthPercent = p
+		} else {
+			log.Printf("Invalid MONITOR_SERIES_GROWTH_PERCENT %q, using %g", growth, cfg.MonitorSeriesGrowthPercent)
+		}
+	}
+	if size := os.Getenv("MONITOR_HISTORY_SIZE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil && n > 0 {
+			cfg.MonitorHistorySize = n
+		} else {
+			log.Printf("Invalid MONITOR_HISTORY_SIZE %q, using %d", size, cfg.MonitorHistorySize)
+		}
+	}
+
+	if bound := os.Getenv("MAX_LABEL_BOUND"); bound != "" {
+		if n, err := strconv.Atoi(bound); err == nil && n > 0 {
+			cfg.MaxLabelBound = n
+		} else {
+			log.Printf("Invalid MAX_LABEL_BOUND %q, using %d", bound, cfg.MaxLabelBound)
+		}
+	}
+
+	if size := os.Getenv("HISTORY_SIZE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil && n > 0 {
+			cfg.HistorySize = n
+		} else {
+			log.Printf("Invalid HISTORY_SIZE %q, using %d", size, cfg.HistorySize)
+		}
+	}
+
+	if limit := os.Getenv("SESSION_EVALUATION_LIMIT"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil && n >= 0 {
+			cfg.SessionEvaluationLimit = n
+		} else {
+			log.Printf("Invalid SESSION_EVALUATION_LIMIT %q, using %d", limit, cfg.SessionEvaluationLimit)
+		}
+	}
+
+	if days := os.Getenv("AUDIT_RETENTION_DAYS"); days != "" {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			cfg.AuditRetentionDays = n
+		} else {
+			log.Printf("Invalid AUDIT_RETENTION_DAYS %q, using %d", days, cfg.AuditRetentionDays)
+		}
+	}
+
+	if cfg.LLMURL == "" {
+		cfg.LLMURL = "http://localhost:11434" // Default Ollama local URL
+	}
+	if cfg.Model == "" {
+		cfg.Model = "llama2"
+	}
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = rules.DefaultProfile
+	}
+
+	return cfg, nil
+}
+
+// ConfigFilePath returns the YAML config location, if any
+func (cfg Config) ConfigFilePath() string {
+	if cfg.KubernetesConfigMapMountPath != "" {
+		return filepath.Join(cfg.KubernetesConfigMapMountPath, kubernetesConfigKey)
+	}
+	return cfg.ConfigFile
+}
+
+// applyFile overlays the settings a config file sets
+func (cfg *Config) applyFile(f *config.File) {
+	if f.Model != "" {
+		cfg.Model = f.Model
+	}
+	if f.FastModel != "" {
+		cfg.Routing.FastModel = f.FastModel
+	}
+	if f.Profile != "" {
+		cfg.Profile = f.Profile
+	}
+	if f.SessionEvaluationLimit != nil {
+		cfg.SessionEvaluationLimit = *f.SessionEvaluationLimit
+	}
+	if f.Gallery.ScrubPatterns != nil {
+		cfg.GalleryScrubPatterns = f.Gallery.ScrubPatterns
+	}
+	if f.Redact.Patterns != nil {
+		cfg.RedactPatterns = f.Redact.Patterns
+	}
+	if f.Naming.VagueWords != nil {
+		cfg.Lexicon.Vague = f.Naming.VagueWords
+	}
+	if f.Naming.ForbiddenWords != nil {
+		cfg.Lexicon.Forbidden = f.Naming.ForbiddenWords
+	}
+	if f.Naming.Renames != nil {
+		cfg.Renames = f.Naming.Renames
+	}
+	if f.Naming.AllowedLabels != nil {
+		cfg.AllowedLabels = f.Naming.AllowedLabels
+	}
+	if f.Naming.LabelOrder != nil {
+		cfg.LabelOrder = f.Naming.LabelOrder
+	}
+	if f.LabelValueEstimates != nil {
+		cfg.ValueEstimates = f.LabelValueEstimates
+	}
+	if f.Quotas != nil {
+		cfg.QuotaPolicies = f.Quotas
+	}
+	if f.Tenants != nil {
+		cfg.Tenants = f.Tenants
+	}
+	if f.Cost.PromptPer1K != nil {
+		cfg.Pricing.PromptPer1K = *f.Cost.PromptPer1K
+	}
+	if f.Cost.ResponsePer1K != nil {
+		cfg.Pricing.ResponsePer1K = *f.Cost.ResponsePer1K
+	}
+}
+
+// New builds the server's handler: the router, behind the stripping of tenant path prefixes
+func New(cfg Config) (http.Handler, error) {
+	// Reloads start from the environment so removing a key from the file reverts it
+	base := cfg
+	file := &config.File{}
+	if path := cfg.ConfigFilePath(); path != "" {
+		f, err := config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.applyFile(f)
+		file = f
+	}
+
+	// A default pattern that stopped matching would leak silently, so refuse to start
+	if err := redact.SelfTest(); err != nil {
+		return nil, err
+	}
+	redactor, err := redact.New(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize LLM client
+	llmClient := llm.NewClient(cfg.LLMURL, cfg.Model)
+	llmClient.SetAPIKey(cfg.LLMAPIKey)
+	llmClient.SetRouting(cfg.Routing)
+	llmClient.SetRedactor(redactor)
+	llmClient.SetRedactPrompt(cfg.RedactBeforeLLM)
+	if cfg.LLMCassetteDir != "" {
+		if err := llmClient.SetCassettes(cfg.LLMCassetteDir, cfg.LLMCassetteMode); err != nil {
+			return nil, fmt.Errorf("LLM_CASSETTE_MODE: %w", err)
+		}
+		log.Printf("[LLM] Using cassettes in %s (%s)", cfg.LLMCassetteDir, cfg.LLMCassetteMode)
+	}
+	switch cfg.EvalCacheBackend {
+	case "":
+	case evalCacheDisk:
+		cache, err := llm.OpenBoltCache(cfg.EvalCachePath)
+		if err != nil {
+			return nil, fmt.Errorf("EVAL_CACHE_PATH: %w", err)
+		}
+		llmClient.SetCache(cache)
+		log.Printf("[LLM] Caching evaluations in %s", cfg.EvalCachePath)
+	default:
+		return nil, fmt.Errorf("EVAL_CACHE_BACKEND: unknown backend %q (only %s is supported)", cfg.EvalCacheBackend, evalCacheDisk)
+	}
+
+	store, err := history.Open(cfg.DatabasePath, cfg.HistorySize)
+	if err != nil {
+		return nil, err
+	}
+
+	var auditLog *audit.Logger
+	if cfg.AuditLogPath != "" {
+		auditLog, err = audit.Open(cfg.AuditLogPath, cfg.AuditRetentionDays)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	guard, err := abuse.NewGuard(cfg.SessionEvaluationLimit, cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := rules.Profile(cfg.Profile)
+	if err != nil {
+		return nil, err
+	}
+	profile = profile.WithLexicon(cfg.Lexicon).WithRenames(cfg.Renames).WithValueEstimates(cfg.ValueEstimates).WithLabelOrder(cfg.LabelOrder)
+	profile.AllowedLabels = cfg.AllowedLabels
+
+	anonymizer, err := anonymize.New(cfg.GalleryScrubPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotaStore quota.QuotaStore
+	if cfg.RedisURL != "" {
+		if quotaStore, err = quota.NewRedisStore(cfg.RedisURL); err != nil {
+			return nil, err
+		}
+	}
+	limiter := quota.NewLimiter(quotaStore, cfg.QuotaPolicies)
+
+	tenants := tenant.NewRegistry(cfg.Tenants)
+	budgets := quota.NewLimiter(quotaStore, tenant.BudgetPolicies(cfg.Tenants))
+	tenantProfiles, err := tenantProfiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up gin router. gin trusts every X-Forwarded-For by default, so the
+	// trusted proxies are always set, even to none.
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	r.Use(middleware.ResolveClientIP(cfg.TrustedProxyDepth, cfg.TrustedProxies), middleware.Logger(), gin.Recovery(),
+		middleware.DetectLanguage(), middleware.ResolveTenant(tenants), middleware.Quota(limiter))
+
+	// Register custom template functions
+	funcs := template.FuncMap{
+		"lower": strings.ToLower,
+		"join":  strings.Join,
+		"t":     i18n.T,
+	}
+	for lang, keys := range i18n.MissingKeys() {
+		log.Printf("[i18n] %s catalog is missing %d key(s), shown in English: %s", lang, len(keys), strings.Join(keys, ", "))
+	}
+
+	// Load HTML templates
+	if cfg.TemplateAutoReload {
+		log.Printf("[Templates] TEMPLATE_AUTO_RELOAD is on: re-reading %s on every request; don't use this in production", cfg.TemplatesDir)
+	}
+	templates, err := newTemplateRenderer(os.DirFS(cfg.TemplatesDir), funcs, cfg.TemplateAutoReload)
+	if err != nil {
+		return nil, fmt.Errorf("loading templates: %w", err)
+	}
+	r.HTMLRender = templates
+	r.Static("/static", "./web/static")
+
+	recorder := usage.NewRecorder()
+	if cfg.UsageReportingURL != "" {
+		log.Printf("[Usage] Sending aggregate usage counts to %s every %s", cfg.UsageReportingURL, cfg.UsageReportingInterval)
+		recorder.Start(cfg.UsageReportingURL, cfg.UsageReportingInterval)
+	}
+
+	// Initialize handlers
+	h := handlers.NewHandler(llmClient, store, cfg.Pricing, profile, anonymizer, redactor, auditLog, guard, tenants, budgets, recorder)
+	h.SetTenantProfiles(tenantProfiles)
+	h.MaxLabelBound = cfg.MaxLabelBound
+	h.StartJobs()
+
+	monitors := monitor.New(store, h.Profile, monitor.Options{
+		WebhookURL:          cfg.MonitorWebhookURL,
+		SeriesGrowthPercent: cfg.MonitorSeriesGrowthPercent,
+		HistorySize:         cfg.MonitorHistorySize,
+	})
+	monitors.Start()
+	h.SetMonitors(monitors)
+
+	if err := watchConfig(base, file, llmClient, guard, limiter, tenants, budgets, h); err != nil {
+		return nil, err
+	}
+
+	// Web UI routes, behind SSO when OIDC is configured
+	ui := r.Group("/")
+	if cfg.OIDC.Issuer != "" {
+		oidcAuth, err := auth.NewOIDC(context.Background(), cfg.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		r.GET(auth.CallbackPath, oidcAuth.Callback)
+		ui.Use(oidcAuth.Middleware())
+	}
+
+	ui.GET("/", h.Index)
+	ui.GET("/settings", h.Settings)
+	ui.POST("/evaluate", h.Evaluate)
+	ui.GET("/evaluate/jobs/:id", h.EvaluationJob)
+	ui.POST("/evaluate/label", h.EvaluateLabel)
+	ui.POST("/cardinality/projection", h.CardinalityProjection)
+	ui.GET("/examples", h.Examples)
+	ui.POST("/examples/:id/run", h.RunExample)
+	ui.GET("/stats", h.Stats)
+	ui.GET("/gallery", h.Gallery)
+	ui.GET("/monitors", h.Monitors)
+	ui.GET("/language", h.SetLanguage)
+
+	r.GET("/robots.txt", h.Robots)
+	r.GET("/metrics", gin.WrapH(selfmetrics.Handler()))
+
+	// Metric catalog for Grafana's metric browser
+	r.GET("/api/v1/metrics", h.MetricCatalog)
+	r.GET("/api/v1/metrics/:name/labels/:label/values", h.LabelValues)
+	r.GET("/api/v1/cardinality/prometheus", h.CardinalityPrometheus)
+
+	// Importable Grafana dashboard for submitted metrics
+	r.POST("/api/v1/grafana/dashboard", h.GrafanaDashboard)
+
+	// Evaluation in two phases: the static results right away, then the LLM's
+	// verdict from a job
+	evaluate := r.Group("/api/v1/evaluate", middleware.AcceptJSON())
+	evaluate.POST("/quick", h.EvaluateQuick)
+	evaluate.POST("/full", h.EvaluateFull)
+	evaluate.POST("/label", h.EvaluateLabel)
+	evaluate.POST("/multi-set", h.EvaluateMultiSet)
+	evaluate.GET("/jobs/:id", h.EvaluationJob)
+
+	// Quick-fix edits for a static finding
+	r.POST("/api/v1/fix", h.Fix)
+
+	// Stored evaluations hold submitted metrics, so reading them back needs an
+	// API or admin key, or a tenant's key for its own evaluations
+	readKeys := cfg.APIKeys
+	if cfg.AdminAPIKey != "" {
+		readKeys = append(slices.Clone(readKeys), cfg.AdminAPIKey)
+	}
+	if len(readKeys) > 0 || len(cfg.Tenants) > 0 {
+		r.GET("/api/v1/evaluations/:id", middleware.APIKeyOrTenant(readKeys...), h.GetEvaluation)
+	}
+
+	// Admin API, only exposed when a key is configured
+	if cfg.AdminAPIKey != "" {
+		var adminAuth []gin.HandlerFunc
+		if len(cfg.AdminAllowedCIDRs) > 0 {
+			adminAuth = append(adminAuth, middleware.IPAllowlistBehindProxies(cfg.AdminAllowedCIDRs, cfg.TrustedProxyDepth, cfg.TrustedProxies))
+		}
+		adminAuth = append(adminAuth, middleware.APIKey(cfg.AdminAPIKey))
+
+		admin := r.Group("/api/v1/admin", adminAuth...)
+		if auditLog != nil {
+			admin.GET("/audit", h.AuditEvents)
+		}
+		admin.POST("/cache/clear", h.ClearEvaluationCache)
+		admin.GET("/gallery/candidates", h.ShareCandidates)
+		admin.POST("/gallery/:id", h.PublishEvaluation)
+		admin.POST("/monitors", h.RegisterMonitor)
+		admin.DELETE("/monitors/:id", h.DeleteMonitor)
+		admin.POST("/reevaluate", h.Reevaluate)
+		admin.DELETE("/reevaluate", h.CancelReevaluation)
+		admin.POST("/settings", h.UpdateSettings)
+		admin.GET("/status", h.AdminStatus)
+		admin.GET("/tenants", h.Tenants)
+		admin.GET("/usage-report", h.UsageReport)
+
+		// Gated like the admin API; it spends LLM capacity
+		r.Group("/api/v1", adminAuth...).GET("/load-test", h.LoadTest)
+
+		// Profiles show the service's internals, so they are gated the same way
+		debug := r.Group("/debug/pprof", adminAuth...)
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// Index serves the named profiles, such as heap and goroutine
+		debug.GET("/:name", gin.WrapF(pprof.Index))
+	}
+
+	return tenantPaths(tenants, r), nil
+}
+
+// tenantProfiles builds the naming profiles of tenants that choose their own
+func tenantProfiles(cfg Config) (map[string]rules.NamingProfile, error) {
+	profiles := make(map[string]rules.NamingProfile)
+	for _, t := range cfg.Tenants {
+		if t.Profile == "" {
+			continue
+		}
+		profile, err := rules.Profile(t.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		profile = profile.WithLexicon(cfg.Lexicon).WithRenames(cfg.Renames).WithValueEstimates(cfg.ValueEstimates).WithLabelOrder(cfg.LabelOrder)
+		profile.AllowedLabels = cfg.AllowedLabels
+		profiles[t.ID] = profile
+	}
+	return profiles, nil
+}
+
+// tenantPaths routes requests under a tenant's path prefix as if the prefix
+// weren't there, leaving the tenant in the request context for ResolveTenant
+func tenantPaths(tenants *tenant.Registry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if t, rest, ok := tenants.ByPath(req.URL.Path); ok {
+			req = req.WithContext(tenant.NewContext(req.Context(), t))
+			u := *req.URL
+			u.Path, u.RawPath = rest, ""
+			req.URL = &u
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// watchConfig applies config file changes, and settings page changes when
+// allowed, to the running server. file is the config as loaded at startup.
+func watchConfig(base Config, file *config.File, llmClient *llm.Client, guard *abuse.Guard, limiter *quota.Limiter, tenants *tenant.Registry, budgets *quota.Limiter, h *handlers.Handler) error {
+	reload := func(f *config.File) {
+		next := base
+		next.applyFile(f)
+		llmClient.SetModel(next.Model)
+		llmClient.SetRouting(next.Routing)
+		guard.SetLimit(next.SessionEvaluationLimit)
+		limiter.SetPolicies(next.QuotaPolicies)
+		tenants.SetTenants(next.Tenants)
+		budgets.SetPolicies(tenant.BudgetPolicies(next.Tenants))
+		h.SetPricing(next.Pricing)
+		// config.Load has already rejected unknown profiles and invalid patterns
+		if profile, err := rules.Profile(next.Profile); err == nil {
+			profile = profile.WithLexicon(next.Lexicon).WithRenames(next.Renames).WithValueEstimates(next.ValueEstimates).WithLabelOrder(next.LabelOrder)
+			profile.AllowedLabels = next.AllowedLabels
+			h.SetProfile(profile)
+		}
+		if profiles, err := tenantProfiles(next); err == nil {
+			h.SetTenantProfiles(profiles)
+		}
+		if anonymizer, err := anonymize.New(next.GalleryScrubPatterns); err == nil {
+			h.SetAnonymizer(anonymizer)
+		}
+		if redactor, err := redact.New(next.RedactPatterns); err == nil {
+			llmClient.SetRedactor(redactor)
+			h.SetRedactor(redactor)
+		}
+	}
+
+	// Settings page changes apply on top of the file as last loaded, and a
+	// file change replaces them
+	var mu sync.Mutex
+	current := file
+	fileChanged := func(f *config.File) {
+		mu.Lock()
+		current = f
+		mu.Unlock()
+		reload(f)
+	}
+	// Changes are saved through the admin API, so without it the page stays read-only
+	var apply func(handlers.SettingsUpdate) error
+	if base.AllowRuntimeConfigChanges && base.AdminAPIKey == "" {
+		log.Printf("[Settings] ALLOW_RUNTIME_CONFIG_CHANGES needs ADMIN_API_KEY; the settings page stays read-only")
+	}
+	if base.AllowRuntimeConfigChanges && base.AdminAPIKey != "" {
+		apply = func(u handlers.SettingsUpdate) error {
+			mu.Lock()
+			next := *current
+			next.Model, next.Profile = u.Model, u.Profile
+			current = &next
+			mu.Unlock()
+			reload(&next)
+			return nil
+		}
+	}
+	h.SetSettings(base.LLMURL, apply)
+
+	var err error
+	switch {
+	case base.KubernetesConfigMapMountPath != "":
+		_, err = config.NewKubernetesConfigWatcher(base.KubernetesConfigMapMountPath, kubernetesConfigKey, fileChanged)
+	case base.ConfigFile != "":
+		_, err = config.NewConfigWatcher(base.ConfigFile, fileChanged)
+	}
+	return err
+}
+
+func Run(cfg Config) error {
+	r, err := New(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Starting Good Telemetry web server on :%s", cfg.Port)
+	log.Printf("LLM Backend: %s (model: %s)", cfg.LLMURL, cfg.Model)
+	if cfg.Routing.FastModel != "" {
+		log.Printf("Fast model: %s (up to %d metrics, ~%d prompt tokens)", cfg.Routing.FastModel, cfg.Routing.FastMaxMetrics, cfg.Routing.FastMaxPromptTokens)
+	}
+	log.Printf("Naming profile: %s", cfg.Profile)
+	if len(cfg.TrustedProxies) > 0 {
+		log.Printf("Trusted proxies: %s", strings.Join(cfg.TrustedProxies, ", "))
+	} else {
+		log.Printf("Trusted proxies: none (client IPs are connection addresses)")
+		if cfg.TrustedProxyDepth > 0 {
+			log.Printf("TRUSTED_PROXY_DEPTH is %d but TRUSTED_PROXIES is unset, so X-Forwarded-For is ignored", cfg.TrustedProxyDepth)
+		}
+	}
+	if cfg.DatabasePath != "" {
+		log.Printf("History database: %s", cfg.DatabasePath)
+	} else {
+		log.Printf("History database: none (in-memory only)")
+	}
+	if path := cfg.ConfigFilePath(); path != "" {
+		log.Printf("Config file: %s (reloaded on change)", path)
+	}
+	if cfg.OIDC.Issuer != "" {
+		log.Printf("SSO: OIDC via %s", cfg.OIDC.Issuer)
+	}
+	if cfg.AuditLogPath != "" {
+		log.Printf("Audit log: %s (retention: %d days)", cfg.AuditLogPath, cfg.AuditRetentionDays)
+	}
+	if cfg.RedisURL != "" {
+		log.Printf("API key quotas: counted in Redis")
+	}
+
+	if len(cfg.Tenants) > 0 {
+		log.Printf("Tenants: %d configured", len(cfg.Tenants))
+	}
+
+	return http.ListenAndServe(":"+cfg.Port, r)
+}
@@ -0,0 +1,67 @@
+// ABOUTME: Tests for the settings routes - changes are only accepted through the admin API
+// ABOUTME: The public UI path no longer takes a POST, and the admin path needs the admin key
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSettingsChangesNeedTheAdminAPI(t *testing.T) {
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.AdminAPIKey = "admin-key"
+		cfg.AdminAllowedCIDRs = nil
+		cfg.AllowRuntimeConfigChanges = true
+	})
+	form := url.Values{"model": {"mistral:7b"}, "profile": {"prometheus"}}.Encode()
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+	}{
+		{"public UI path", "/settings", "admin-key", http.StatusNotFound},
+		{"admin path without a key", "/api/v1/admin/settings", "", http.StatusUnauthorized},
+		{"admin path with a wrong key", "/api/v1/admin/settings", "guess", http.StatusUnauthorized},
+		{"admin path with the admin key", "/api/v1/admin/settings", "admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/settings", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `value="mistral:7b"`) {
+		t.Error("the settings page doesn't show the model saved through the admin API")
+	}
+}
+
+func TestSettingsReadOnlyWithoutAdminAPI(t *testing.T) {
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.AdminAPIKey = ""
+		cfg.AllowRuntimeConfigChanges = true
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/settings", nil))
+	if !strings.Contains(rec.Body.String(), "Read-only") {
+		t.Error("the settings page is editable without the admin API")
+	}
+}
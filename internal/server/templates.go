@@ -0,0 +1,103 @@
+// ABOUTME: HTML template loading - parses the page templates once at startup or, in development, on every request
+// ABOUTME: Reloaded templates that fail to parse or execute show the error and its location instead of a broken page
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// Directory the page templates are read from when TEMPLATES_DIR is unset
+const defaultTemplatesDir = "web/templates"
+
+// templateRenderer is gin's HTMLRender over a template source. Whether the
+// source is a directory on disk or a file system compiled in, it is read the
+// same way; reload only changes when.
+type templateRenderer struct {
+	source fs.FS
+	funcs  template.FuncMap
+	reload bool
+	// Parsed at startup when reload is off
+	templates *template.Template
+}
+
+// newTemplateRenderer parses every template in source, so a production
+// server with a broken template fails to start rather than on first use
+func newTemplateRenderer(source fs.FS, funcs template.FuncMap, reload bool) (*templateRenderer, error) {
+	r := &templateRenderer{source: source, funcs: funcs, reload: reload}
+	templates, err := r.parse()
+	if err != nil && !reload {
+		return nil, err
+	}
+	r.templates = templates
+	return r, nil
+}
+
+func (r *templateRenderer) parse() (*template.Template, error) {
+	return template.New("").Funcs(r.funcs).ParseFS(r.source, "*")
+}
+
+func (r *templateRenderer) Instance(name string, data any) render.Render {
+	if !r.reload {
+		return render.HTML{Template: r.templates, Name: name, Data: data}
+	}
+	templates, err := r.parse()
+	if err != nil {
+		return templateErrorPage{err: err}
+	}
+	return reloadedHTML{templates: templates, name: name, data: data}
+}
+
+// reloadedHTML executes into a buffer first, so a template that fails part
+// way shows the error page instead of half a page
+type reloadedHTML struct {
+	templates *template.Template
+	name      string
+	data      any
+}
+
+func (h reloadedHTML) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if err := h.templates.ExecuteTemplate(&buf, h.name, h.data); err != nil {
+		return templateErrorPage{err: err}.Render(w)
+	}
+	h.WriteContentType(w)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (h reloadedHTML) WriteContentType(w http.ResponseWriter) {
+	render.HTML{}.WriteContentType(w)
+}
+
+// templateErrorPage is the development error page. html/template errors
+// start with the file and line, such as "template: index.html:12: ...".
+type templateErrorPage struct {
+	err error
+}
+
+func (p templateErrorPage) Render(w http.ResponseWriter) error {
+	p.WriteContentType(w)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, err := fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Template error</title></head>
+<body style="font-family: sans-serif; margin: 2em;">
+<h1>Template error</h1>
+<pre style="background: #fee; padding: 1em; white-space: pre-wrap;">%s</pre>
+<p>TEMPLATE_AUTO_RELOAD is on: fix the template and reload this page.</p>
+</body>
+</html>
+`, template.HTMLEscapeString(p.err.Error()))
+	return err
+}
+
+func (p templateErrorPage) WriteContentType(w http.ResponseWriter) {
+	render.HTML{}.WriteContentType(w)
+}
@@ -0,0 +1,118 @@
+// ABOUTME: Tests for template reloading - with TEMPLATE_AUTO_RELOAD an edited template shows on the next request
+// ABOUTME: A template broken by the edit shows the error page with its location; without reloading edits wait for a restart
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyTemplates copies the page templates to a directory the test can edit
+func copyTemplates(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join("..", "..", defaultTemplatesDir)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func getIndex(t *testing.T, router http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec
+}
+
+func editIndex(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEditedTemplatesReload(t *testing.T) {
+	dir := copyTemplates(t)
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.TemplatesDir = dir
+		cfg.TemplateAutoReload = true
+	})
+	if rec := getIndex(t, router); rec.Code != http.StatusOK {
+		t.Fatalf("index = %d before any edit", rec.Code)
+	}
+
+	editIndex(t, dir, `<p>edited {{ t .lang "index.heading" }}</p>`)
+	rec := getIndex(t, router)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<p>edited ") {
+		t.Fatalf("index after the edit = %d:\n%s", rec.Code, rec.Body.String())
+	}
+	// Still inside the layout
+	if !strings.Contains(rec.Body.String(), "<!DOCTYPE html>") {
+		t.Error("the edited template is rendered without the layout")
+	}
+
+	editIndex(t, dir, "<p>line one</p>\n<p>{{ .lang | nosuchfunc }}</p>\n")
+	rec = getIndex(t, router)
+	body := rec.Body.String()
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(body, "<h1>Template error</h1>") {
+		t.Fatalf("index with a parse error = %d:\n%s", rec.Code, body)
+	}
+	if !strings.Contains(body, "index.html:2: function &#34;nosuchfunc&#34; not defined") {
+		t.Errorf("error page doesn't locate the error at index.html:2:\n%s", body)
+	}
+
+	// A template that parses but fails part way shows the error page, not half a page
+	editIndex(t, dir, `<p>before</p>{{ template "missing.html" . }}`)
+	rec = getIndex(t, router)
+	body = rec.Body.String()
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(body, "missing.html") || strings.Contains(body, "<p>before</p>") {
+		t.Fatalf("index failing to execute = %d:\n%s", rec.Code, body)
+	}
+
+	// Fixing it needs no restart
+	editIndex(t, dir, `<p>fixed</p>`)
+	if rec := getIndex(t, router); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<p>fixed</p>") {
+		t.Errorf("index after the fix = %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTemplatesParsedOnceWithoutReload(t *testing.T) {
+	dir := copyTemplates(t)
+	router := e2eServer(t, func(cfg *Config) {
+		cfg.TemplatesDir = dir
+		cfg.TemplateAutoReload = false
+	})
+	editIndex(t, dir, `<p>edited</p>`)
+	if rec := getIndex(t, router); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "<p>edited</p>") {
+		t.Errorf("index = %d, want the templates read at startup", rec.Code)
+	}
+
+	// A broken template stops the server from starting
+	editIndex(t, dir, "{{ if }")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TemplatesDir, cfg.TemplateAutoReload = dir, false
+	cfg.ConfigFile, cfg.KubernetesConfigMapMountPath, cfg.DatabasePath, cfg.EvalCacheBackend, cfg.AuditLogPath = "", "", "", "", ""
+	cfg.OIDC.Issuer = ""
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "index.html") {
+		t.Errorf("New with a broken template = %v, want its parse error", err)
+	}
+}
@@ -0,0 +1,176 @@
+// ABOUTME: Tenants - teams sharing one instance, each picked by API key or path prefix
+// ABOUTME: Holds the configured tenants and carries the one a request belongs to in its context
+
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/wbollock/good_telemetry/internal/quota"
+)
+
+// DefaultID is the tenant of every request no configured tenant claims
+const DefaultID = "default"
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Tenant is one team's slice of the instance: its own history, naming profile
+// and LLM budget
+type Tenant struct {
+	ID      string   `yaml:"id"`
+	APIKeys []string `yaml:"api_keys"`
+	// Requests under this path, such as /teams/payments, belong to the tenant
+	PathPrefix string `yaml:"path_prefix"`
+	// Naming profile; empty uses the server's
+	Profile string `yaml:"profile"`
+	// LLM evaluations per day; 0 is unlimited
+	DailyLLMBudget int `yaml:"daily_llm_budget"`
+}
+
+// Default is the tenant requests belong to without a configured one
+func Default() Tenant {
+	return Tenant{ID: DefaultID}
+}
+
+// Validate rejects malformed IDs and prefixes, and tenants no request could reach
+func (t Tenant) Validate() error {
+	if !validID.MatchString(t.ID) || t.ID == DefaultID {
+		return fmt.Errorf("tenant id %q must be lowercase letters, digits, _ and - and not %q", t.ID, DefaultID)
+	}
+	if t.PathPrefix != "" && (!strings.HasPrefix(t.PathPrefix, "/") || strings.HasSuffix(t.PathPrefix, "/")) {
+		return fmt.Errorf("tenant %s: path_prefix %q must start with / and not end with one", t.ID, t.PathPrefix)
+	}
+	if len(t.APIKeys) == 0 && t.PathPrefix == "" {
+		return fmt.Errorf("tenant %s needs api_keys or a path_prefix", t.ID)
+	}
+	if t.DailyLLMBudget < 0 {
+		return fmt.Errorf("tenant %s has a negative daily_llm_budget", t.ID)
+	}
+	return nil
+}
+
+// ValidateAll checks each tenant and that no two share an ID, API key or path prefix
+func ValidateAll(tenants []Tenant) error {
+	ids, keys, prefixes := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, t := range tenants {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if ids[t.ID] {
+			return fmt.Errorf("tenant %s is configured twice", t.ID)
+		}
+		ids[t.ID] = true
+		for _, key := range t.APIKeys {
+			if keys[key] {
+				return fmt.Errorf("tenant %s shares an API key with another tenant", t.ID)
+			}
+			keys[key] = true
+		}
+		if t.PathPrefix != "" {
+			if prefixes[t.PathPrefix] {
+				return fmt.Errorf("tenant %s shares path_prefix %s with another tenant", t.ID, t.PathPrefix)
+			}
+			prefixes[t.PathPrefix] = true
+		}
+	}
+	return nil
+}
+
+// BudgetKey is what a tenant's LLM evaluations are counted under in a quota.Limiter
+func (t Tenant) BudgetKey() string {
+	return "tenant:" + t.ID
+}
+
+// BudgetPolicies turns the tenants' daily LLM budgets into quota policies
+// matching their BudgetKey
+func BudgetPolicies(tenants []Tenant) []quota.QuotaPolicy {
+	var policies []quota.QuotaPolicy
+	for _, t := range tenants {
+		if t.DailyLLMBudget > 0 {
+			policies = append(policies, quota.QuotaPolicy{Pattern: t.BudgetKey(), RequestsPerDay: t.DailyLLMBudget})
+		}
+	}
+	return policies
+}
+
+// Registry holds the configured tenants
+type Registry struct {
+	mu      sync.RWMutex
+	tenants []Tenant
+}
+
+func NewRegistry(tenants []Tenant) *Registry {
+	r := &Registry{}
+	r.SetTenants(tenants)
+	return r
+}
+
+// SetTenants replaces the tenants, e.g. on config reload
+func (r *Registry) SetTenants(tenants []Tenant) {
+	sorted := slices.Clone(tenants)
+	slices.SortFunc(sorted, func(a, b Tenant) int { return strings.Compare(a.ID, b.ID) })
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants = sorted
+}
+
+// All lists the configured tenants by ID, without the default one
+func (r *Registry) All() []Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.tenants)
+}
+
+// ByKey finds the tenant an API key belongs to
+func (r *Registry) ByKey(key string) (Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found, match := Tenant{}, false
+	for _, t := range r.tenants {
+		for _, k := range t.APIKeys {
+			// Every key is compared so timing doesn't reveal which one matched
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				found, match = t, true
+			}
+		}
+	}
+	return found, match
+}
+
+// ByPath finds the tenant whose prefix path starts with, returning the path
+// with the prefix removed
+func (r *Registry) ByPath(path string) (Tenant, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tenants {
+		if t.PathPrefix == "" {
+			continue
+		}
+		if path == t.PathPrefix {
+			return t, "/", true
+		}
+		if rest, ok := strings.CutPrefix(path, t.PathPrefix); ok && strings.HasPrefix(rest, "/") {
+			return t, rest, true
+		}
+	}
+	return Tenant{}, "", false
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying t
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant NewContext stored in ctx
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
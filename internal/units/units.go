@@ -0,0 +1,112 @@
+// ABOUTME: Unit conversion table - maps non-base unit suffixes to Prometheus base units
+// ABOUTME: Renames metrics and rescales sample values so converted examples stay truthful
+
+package units
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Conversion rescales values in unit From to the base unit To.
+// Sub-unit conversions divide so decimal values stay exact (234ms is 0.234s, not 0.23400000000000001s).
+type Conversion struct {
+	From   string
+	To     string
+	Factor float64
+	Divide bool
+}
+
+// Apply converts a value expressed in c.From to c.To
+func (c Conversion) Apply(v float64) float64 {
+	if c.Divide {
+		return v / c.Factor
+	}
+	return v * c.Factor
+}
+
+// Describe explains the arithmetic, e.g. "/ 1000" or "* 1048576"
+func (c Conversion) Describe() string {
+	op := "*"
+	if c.Divide {
+		op = "/"
+	}
+	return op + " " + strconv.FormatFloat(c.Factor, 'f', -1, 64)
+}
+
+// Suffixes are matched against the last underscore-separated word of the name
+var table = map[string]Conversion{
+	"ms":           {From: "milliseconds", To: "seconds", Factor: 1e3, Divide: true},
+	"millis":       {From: "milliseconds", To: "seconds", Factor: 1e3, Divide: true},
+	"milliseconds": {From: "milliseconds", To: "seconds", Factor: 1e3, Divide: true},
+	"us":           {From: "microseconds", To: "seconds", Factor: 1e6, Divide: true},
+	"micros":       {From: "microseconds", To: "seconds", Factor: 1e6, Divide: true},
+	"microseconds": {From: "microseconds", To: "seconds", Factor: 1e6, Divide: true},
+	"ns":           {From: "nanoseconds", To: "seconds", Factor: 1e9, Divide: true},
+	"nanos":        {From: "nanoseconds", To: "seconds", Factor: 1e9, Divide: true},
+	"nanoseconds":  {From: "nanoseconds", To: "seconds", Factor: 1e9, Divide: true},
+	"kb":           {From: "kilobytes", To: "bytes", Factor: 1e3},
+	"kilobytes":    {From: "kilobytes", To: "bytes", Factor: 1e3},
+	"mb":           {From: "megabytes", To: "bytes", Factor: 1e6},
+	"megabytes":    {From: "megabytes", To: "bytes", Factor: 1e6},
+	"gb":           {From: "gigabytes", To: "bytes", Factor: 1e9},
+	"gigabytes":    {From: "gigabytes", To: "bytes", Factor: 1e9},
+	"kib":          {From: "kibibytes", To: "bytes", Factor: 1 << 10},
+	"kibibytes":    {From: "kibibytes", To: "bytes", Factor: 1 << 10},
+	"mib":          {From: "mebibytes", To: "bytes", Factor: 1 << 20},
+	"mebibytes":    {From: "mebibytes", To: "bytes", Factor: 1 << 20},
+	"gib":          {From: "gibibytes", To: "bytes", Factor: 1 << 30},
+	"gibibytes":    {From: "gibibytes", To: "bytes", Factor: 1 << 30},
+	"percent":      {From: "percent", To: "ratio", Factor: 100, Divide: true},
+	"pct":          {From: "percent", To: "ratio", Factor: 100, Divide: true},
+}
+
+// Series suffixes that follow the unit in a metric name
+var seriesSuffixes = []string{"_total", "_bucket", "_sum", "_count"}
+
+// Rename returns name with a non-base unit replaced by its base unit, keeping
+// any _total/_bucket/_sum/_count suffix, e.g. request_latency_ms_bucket becomes
+// request_latency_seconds_bucket
+func Rename(name string) (string, Conversion, bool) {
+	stem, suffix := name, ""
+	for _, s := range seriesSuffixes {
+		if trimmed := strings.TrimSuffix(name, s); trimmed != name {
+			stem, suffix = trimmed, s
+			break
+		}
+	}
+
+	i := strings.LastIndex(stem, "_")
+	if i <= 0 {
+		return name, Conversion{}, false
+	}
+
+	conv, ok := table[strings.ToLower(stem[i+1:])]
+	if !ok {
+		return name, Conversion{}, false
+	}
+	return stem[:i+1] + conv.To + suffix, conv, true
+}
+
+// ConvertValue rescales a sample value string. NaN and ±Inf pass through unchanged.
+func ConvertValue(value string, conv Conversion) (string, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return value, nil
+	}
+	return FormatValue(conv.Apply(v)), nil
+}
+
+// FormatValue renders a float with the shortest exact representation, using
+// plain decimals for everyday magnitudes and exponents only when they're shorter to read
+func FormatValue(v float64) string {
+	abs := math.Abs(v)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
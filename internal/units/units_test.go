@@ -0,0 +1,96 @@
+// ABOUTME: Tests for the unit conversion table - one case per unit pair, renaming and rescaling together
+// ABOUTME: NaN and Inf pass through, and converted values keep the shortest exact decimal
+
+package units
+
+import "testing"
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name, value       string
+		wantName, wantVal string
+		describe          string
+	}{
+		{"response_time_ms", "234", "response_time_seconds", "0.234", "/ 1000"},
+		{"response_time_millis", "1500", "response_time_seconds", "1.5", "/ 1000"},
+		{"response_time_milliseconds", "7", "response_time_seconds", "0.007", "/ 1000"},
+		{"gc_pause_us", "250", "gc_pause_seconds", "0.00025", "/ 1000000"},
+		{"gc_pause_micros", "1000000", "gc_pause_seconds", "1", "/ 1000000"},
+		{"gc_pause_microseconds", "12.5", "gc_pause_seconds", "0.0000125", "/ 1000000"},
+		{"syscall_ns", "1", "syscall_seconds", "1e-09", "/ 1000000000"},
+		{"syscall_nanos", "2500000000", "syscall_seconds", "2.5", "/ 1000000000"},
+		{"syscall_nanoseconds", "50000", "syscall_seconds", "0.00005", "/ 1000000000"},
+		{"payload_kb", "1.5", "payload_bytes", "1500", "* 1000"},
+		{"payload_kilobytes", "2", "payload_bytes", "2000", "* 1000"},
+		{"heap_mb", "256", "heap_bytes", "256000000", "* 1000000"},
+		{"heap_megabytes", "0.5", "heap_bytes", "500000", "* 1000000"},
+		{"disk_gb", "2", "disk_bytes", "2000000000", "* 1000000000"},
+		{"disk_gigabytes", "1.25", "disk_bytes", "1250000000", "* 1000000000"},
+		{"buffer_kib", "4", "buffer_bytes", "4096", "* 1024"},
+		{"buffer_kibibytes", "0.5", "buffer_bytes", "512", "* 1024"},
+		{"rss_mib", "512", "rss_bytes", "536870912", "* 1048576"},
+		{"rss_mebibytes", "1", "rss_bytes", "1048576", "* 1048576"},
+		{"volume_gib", "2", "volume_bytes", "2147483648", "* 1073741824"},
+		{"volume_gibibytes", "1.5", "volume_bytes", "1610612736", "* 1073741824"},
+		{"cpu_percent", "45", "cpu_ratio", "0.45", "/ 100"},
+		{"cpu_pct", "100", "cpu_ratio", "1", "/ 100"},
+		{"request_latency_ms_bucket", "3", "request_latency_seconds_bucket", "0.003", "/ 1000"},
+		{"bytes_sent_KB_total", "3", "bytes_sent_bytes_total", "3000", "* 1000"},
+		{"request_ms_sum", "-20", "request_seconds_sum", "-0.02", "/ 1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, conv, ok := Rename(tt.name)
+			if !ok || name != tt.wantName {
+				t.Fatalf("Rename = %q, %v, want %q", name, ok, tt.wantName)
+			}
+			if got := conv.Describe(); got != tt.describe {
+				t.Errorf("Describe = %q, want %q", got, tt.describe)
+			}
+			got, err := ConvertValue(tt.value, conv)
+			if err != nil || got != tt.wantVal {
+				t.Errorf("ConvertValue(%q) = %q, %v, want %q", tt.value, got, err, tt.wantVal)
+			}
+		})
+	}
+}
+
+func TestRenameLeavesBaseUnitsAlone(t *testing.T) {
+	for _, name := range []string{"response_time_seconds", "heap_bytes", "cpu_ratio", "requests_total", "ms", "up", "http_requests_count"} {
+		if got, _, ok := Rename(name); ok || got != name {
+			t.Errorf("Rename(%q) = %q, %v, want it unchanged", name, got, ok)
+		}
+	}
+}
+
+func TestConvertValueSpecials(t *testing.T) {
+	conv := table["ms"]
+	for _, value := range []string{"NaN", "+Inf", "-Inf"} {
+		if got, err := ConvertValue(value, conv); err != nil || got != value {
+			t.Errorf("ConvertValue(%q) = %q, %v, want it passed through", value, got, err)
+		}
+	}
+	if _, err := ConvertValue("fast", conv); err == nil {
+		t.Error("ConvertValue accepted a value that isn't a number")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{0.234, "0.234"},
+		{1e-6, "0.000001"},
+		{1e-7, "1e-07"},
+		{123456789, "123456789"},
+		{1e21, "1e+21"},
+		{-2.5, "-2.5"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.v); got != tt.want {
+			t.Errorf("FormatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// ABOUTME: Opt-in usage reporting - aggregate counts of rule codes, verdicts and input sizes
+// ABOUTME: Only counters keyed by fixed strings are kept, so no metric or label text can leave the host
+
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+// DefaultInterval is how often reports are sent
+const DefaultInterval = 24 * time.Hour
+
+// Verdicts the LLM is asked for; anything else it answers is counted as otherVerdict
+var knownVerdicts = map[string]bool{
+	"Good":               true,
+	"Needs Improvement":  true,
+	"Poor":               true,
+	"Analysis Completed": true,
+}
+
+const otherVerdict = "other"
+
+// Input size buckets by upper bound in bytes, the last catching the rest
+var sizeBuckets = []struct {
+	name  string
+	upper int
+}{
+	{"<1KB", 1 << 10},
+	{"<10KB", 10 << 10},
+	{"<100KB", 100 << 10},
+	{"<1MB", 1 << 20},
+	{">=1MB", -1},
+}
+
+// Report is exactly what is sent. Every map is keyed by a rule code, a known
+// verdict or a size bucket, never by anything from a submission.
+type Report struct {
+	Version string `json:"version"`
+	// Start of the counting window; counts restart after each report is sent
+	Since       time.Time      `json:"since"`
+	Evaluations int            `json:"evaluations"`
+	RuleCodes   map[string]int `json:"rule_codes"`
+	Verdicts    map[string]int `json:"verdicts"`
+	InputSizes  map[string]int `json:"input_sizes"`
+}
+
+// Recorder counts evaluations for reporting
+type Recorder struct {
+	mu     sync.Mutex
+	report Report
+}
+
+func NewRecorder() *Recorder {
+	r := &Recorder{}
+	r.reset()
+	return r
+}
+
+func (r *Recorder) reset() {
+	r.report = Report{
+		Version:    version(),
+		Since:      time.Now().UTC().Truncate(time.Second),
+		RuleCodes:  make(map[string]int),
+		Verdicts:   make(map[string]int),
+		InputSizes: make(map[string]int),
+	}
+}
+
+// RecordEvaluation counts an evaluation's finding codes and input size in bytes
+func (r *Recorder) RecordEvaluation(findings []rules.Finding, inputBytes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Evaluations++
+	for _, f := range findings {
+		r.report.RuleCodes[f.Code]++
+	}
+	for _, b := range sizeBuckets {
+		if b.upper < 0 || inputBytes < b.upper {
+			r.report.InputSizes[b.name]++
+			break
+		}
+	}
+}
+
+// RecordVerdict counts the LLM's verdict, or otherVerdict for one it made up
+func (r *Recorder) RecordVerdict(verdict string) {
+	if !knownVerdicts[verdict] {
+		verdict = otherVerdict
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Verdicts[verdict]++
+}
+
+// Report returns a copy of the counts so far
+func (r *Recorder) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := r.report
+	report.RuleCodes = copyCounts(r.report.RuleCodes)
+	report.Verdicts = copyCounts(r.report.Verdicts)
+	report.InputSizes = copyCounts(r.report.InputSizes)
+	return report
+}
+
+// Start posts the report to url every interval, restarting the counts after
+// each successful send. Empty reports aren't sent. A failed send is logged and retried with the counts
+// kept, at the next interval.
+func (r *Recorder) Start(url string, interval time.Duration) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	go func() {
+		for range time.Tick(interval) {
+			if err := r.send(client, url); err != nil {
+				log.Printf("[Usage] Error sending usage report: %v", err)
+			}
+		}
+	}()
+}
+
+func (r *Recorder) send(client *http.Client, url string) error {
+	r.mu.Lock()
+	report := r.report
+	// Nothing happened, so there is nothing to tell
+	if report.Evaluations == 0 && len(report.Verdicts) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.reset()
+	r.mu.Unlock()
+
+	if err := post(client, url, report); err != nil {
+		r.restore(report)
+		return err
+	}
+	log.Printf("[Usage] Sent usage report covering %d evaluations", report.Evaluations)
+	return nil
+}
+
+// restore adds an unsent report's counts back onto the current ones
+func (r *Recorder) restore(report Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Since = report.Since
+	r.report.Evaluations += report.Evaluations
+	addCounts(r.report.RuleCodes, report.RuleCodes)
+	addCounts(r.report.Verdicts, report.Verdicts)
+	addCounts(r.report.InputSizes, report.InputSizes)
+}
+
+func post(client *http.Client, url string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func addCounts(dst, src map[string]int) {
+	for k, v := range src {
+		dst[k] += v
+	}
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
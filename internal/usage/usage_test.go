@@ -0,0 +1,65 @@
+// ABOUTME: Tests for sending usage reports - the posted body is the previewed report, and counts restart after a send
+// ABOUTME: A failed send keeps the counts for the next attempt, and an empty window sends nothing
+
+package usage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wbollock/good_telemetry/internal/rules"
+)
+
+func TestSend(t *testing.T) {
+	status := http.StatusNoContent
+	var bodies []string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	defer sink.Close()
+
+	r := NewRecorder()
+	if err := r.send(sink.Client(), sink.URL); err != nil || len(bodies) != 0 {
+		t.Fatalf("empty send = %v with %d posts, want nothing posted", err, len(bodies))
+	}
+
+	r.RecordEvaluation([]rules.Finding{{Code: "camel-case"}, {Code: "camel-case"}, {Code: "type-missing"}}, 20<<10)
+	r.RecordVerdict("Poor")
+	preview, err := json.Marshal(r.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status = http.StatusInternalServerError
+	if err := r.send(sink.Client(), sink.URL); err == nil {
+		t.Fatal("a 500 from the endpoint wasn't an error")
+	}
+	if got := r.Report(); got.Evaluations != 1 || got.RuleCodes["camel-case"] != 2 || got.InputSizes["<100KB"] != 1 {
+		t.Errorf("counts after a failed send = %+v, want them kept", got)
+	}
+
+	status = http.StatusNoContent
+	if err := r.send(sink.Client(), sink.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[1] != string(preview) {
+		t.Errorf("posted %q, want the preview %s", bodies, preview)
+	}
+	if got := r.Report(); got.Evaluations != 0 || len(got.RuleCodes) != 0 || len(got.Verdicts) != 0 {
+		t.Errorf("counts after a send = %+v, want them restarted", got)
+	}
+}
+
+func TestUnknownVerdictsAreNotKept(t *testing.T) {
+	r := NewRecorder()
+	r.RecordVerdict("Good")
+	r.RecordVerdict("payments_total looks fine")
+	if got := r.Report().Verdicts; len(got) != 2 || got["Good"] != 1 || got[otherVerdict] != 1 {
+		t.Errorf("verdicts = %v", got)
+	}
+}
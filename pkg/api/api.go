@@ -0,0 +1,365 @@
+// ABOUTME: Wire types of the Good Telemetry HTTP API, shared by the server and pkg/client
+// ABOUTME: Evaluation requests, their JSON results, stored evaluations and the error envelope
+
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Largest metrics input accepted for evaluation
+const MaxMetricsBytes = 1 << 20
+
+// SourceTextfile is the Source of metrics written for node_exporter's textfile collector
+const SourceTextfile = "textfile"
+
+// EvaluateRequest is every evaluation's input, whether it arrives as a form,
+// multipart upload or JSON body (POST /evaluate) or from the command line.
+// Check it with Validate before evaluating.
+type EvaluateRequest struct {
+	Metrics      string `form:"metrics" json:"metrics"`
+	ShareConsent bool   `form:"share_consent" json:"share_consent"`
+	// Overrides model routing; must be one of the allowed models
+	Model string `form:"model" json:"model"`
+	// textfile adds the node_exporter textfile collector rules
+	Source string `form:"source" json:"source"`
+	// A Prometheus scrape_config block whose relabeling is applied before evaluating
+	ScrapeConfig string `form:"scrape_config" json:"scrape_config"`
+	// Evaluate go_, process_ and other standard runtime metrics too, which are
+	// otherwise only counted towards cardinality
+	IncludeRuntime bool `form:"include_runtime" json:"include_runtime"`
+	// The metrics are pushed to a Pushgateway, so job and instance must be set
+	// in them rather than added by the scrape
+	PushGatewayMode bool `form:"pushgateway" json:"pushgateway"`
+	// false returns the static results at once with a job to fetch the LLM's
+	// verdict from; unset or true waits for the verdict
+	Wait *bool `form:"wait" json:"wait"`
+	// How often the service is deployed, for estimating series churn from
+	// labels such as pod; unset assumes once a day
+	DeploysPerDay float64 `form:"deploys_per_day" json:"deploys_per_day"`
+	// Most distinct values the submitter asserts a label takes, such as
+	// customer_id bounded by the number of customers. A bounded label is
+	// estimated with its bound instead of being flagged as unbounded.
+	LabelBounds LabelBounds `form:"label_bounds" json:"label_bounds"`
+	// Return the metrics rewritten with labels in the profile's order instead
+	// of evaluating them
+	Canonicalize bool `form:"canonicalize" json:"canonicalize"`
+	// The answer to a Challenge, with the token it came with
+	ChallengeToken  string `form:"challenge_token" json:"challenge_token"`
+	ChallengeAnswer string `form:"challenge_answer" json:"challenge_answer"`
+}
+
+// LabelBounds maps label names to the most distinct values each takes. As a
+// form field it is written "customer_id=80, region=6".
+type LabelBounds map[string]int
+
+// UnmarshalParam reads the form encoding of the bounds
+func (b *LabelBounds) UnmarshalParam(param string) error {
+	bounds := LabelBounds{}
+	for _, pair := range strings.Split(param, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("label bound %q must be written label=count", pair)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("label bound %q must be written label=count", pair)
+		}
+		bounds[strings.TrimSpace(name)] = count
+	}
+	*b = bounds
+	return nil
+}
+
+// String is the form encoding of the bounds, in label order
+func (b LabelBounds) String() string {
+	pairs := make([]string, 0, len(b))
+	for _, name := range slices.Sorted(maps.Keys(b)) {
+		pairs = append(pairs, name+"="+strconv.Itoa(b[name]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// RequestError is a request field that failed validation
+type RequestError struct {
+	// Form and JSON name of the field
+	Field   string
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// ErrNoMetrics is Validate's error for a request without metrics
+var ErrNoMetrics = &RequestError{Field: "metrics", Message: "Please provide metrics to evaluate"}
+
+// Validate trims the request's text fields and checks every limit, returning
+// a *RequestError for the first field that fails
+func (r *EvaluateRequest) Validate() error {
+	r.Metrics = strings.TrimSpace(r.Metrics)
+	r.ScrapeConfig = strings.TrimSpace(r.ScrapeConfig)
+	r.Model = strings.TrimSpace(r.Model)
+	r.Source = strings.TrimSpace(r.Source)
+
+	switch {
+	case r.Metrics == "":
+		return ErrNoMetrics
+	case len(r.Metrics) > MaxMetricsBytes:
+		return &RequestError{Field: "metrics", Message: fmt.Sprintf("Metrics are %d bytes; at most %d are accepted", len(r.Metrics), MaxMetricsBytes)}
+	case r.Source != "" && r.Source != SourceTextfile:
+		return &RequestError{Field: "source", Message: fmt.Sprintf("Unknown source %q; only %s is supported", r.Source, SourceTextfile)}
+	case r.DeploysPerDay < 0:
+		return &RequestError{Field: "deploys_per_day", Message: "deploys_per_day must be at least 0"}
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.LabelBounds)) {
+		if name == "" {
+			return &RequestError{Field: "label_bounds", Message: "Every label bound needs a label name"}
+		}
+		if r.LabelBounds[name] < 1 {
+			return &RequestError{Field: "label_bounds", Message: fmt.Sprintf("The bound of label %s must be at least 1", name)}
+		}
+	}
+	return nil
+}
+
+// Form encodes the request, leaving out empty fields
+func (r EvaluateRequest) Form() url.Values {
+	form := url.Values{"metrics": {r.Metrics}}
+	if r.ShareConsent {
+		form.Set("share_consent", "true")
+	}
+	if r.IncludeRuntime {
+		form.Set("include_runtime", "true")
+	}
+	if r.PushGatewayMode {
+		form.Set("pushgateway", "true")
+	}
+	if r.Wait != nil {
+		form.Set("wait", strconv.FormatBool(*r.Wait))
+	}
+	if r.DeploysPerDay > 0 {
+		form.Set("deploys_per_day", strconv.FormatFloat(r.DeploysPerDay, 'g', -1, 64))
+	}
+	if len(r.LabelBounds) > 0 {
+		form.Set("label_bounds", r.LabelBounds.String())
+	}
+	if r.Canonicalize {
+		form.Set("canonicalize", "1")
+	}
+	for name, value := range map[string]string{"model": r.Model, "source": r.Source, "scrape_config": r.ScrapeConfig,
+		"challenge_token": r.ChallengeToken, "challenge_answer": r.ChallengeAnswer} {
+		if value != "" {
+			form.Set(name, value)
+		}
+	}
+	return form
+}
+
+// LabelCheckRequest is the form POST /api/v1/evaluate/label accepts
+type LabelCheckRequest struct {
+	Label string `form:"label" binding:"required"`
+	// Distinct values the label is expected to take
+	Values int `form:"values"`
+	// Some of its values, one per line
+	Samples string `form:"samples"`
+	// Adds a one-paragraph LLM opinion to the static verdict
+	LLM bool `form:"llm"`
+}
+
+// EvaluateResponse is the result of an evaluation
+type EvaluateResponse struct {
+	// Look the evaluation up later with GET /api/v1/evaluations/{id}; 0 when it wasn't stored
+	ID int64 `json:"id"`
+	// Empty when Job is set or for quick evaluations
+	Evaluation  Evaluation  `json:"evaluation"`
+	Problems    []Finding   `json:"problems"`
+	Praise      []Finding   `json:"praise"`
+	Cardinality Cardinality `json:"cardinality"`
+	// The metrics rewritten by the static rules; empty when none applied
+	StaticExample string `json:"static_example"`
+	// Prometheus rules file aggregating metrics submitted from several
+	// instances; empty when no metric was
+	RecordingRules string `json:"recording_rules,omitempty"`
+	// Standard runtime metrics left out of the evaluation, unless IncludeRuntime was set
+	RuntimeExcluded int `json:"runtime_excluded"`
+	// Set with wait=false: fetch ID and Evaluation from GET /api/v1/evaluate/jobs/{job}
+	Job string `json:"job,omitempty"`
+}
+
+// Evaluation is the LLM's judgement of the metrics
+type Evaluation struct {
+	Verdict string `json:"verdict"`
+	// 0-100, graded A to F; LetterGrade is empty when the model gave no score
+	Score               int        `json:"score"`
+	LetterGrade         string     `json:"letter_grade"`
+	OverallScore        string     `json:"overall_score"`
+	Strengths           []string   `json:"strengths"`
+	Issues              []string   `json:"issues"`
+	Recommendations     []string   `json:"recommendations"`
+	ImprovedExample     string     `json:"improved_example"`
+	ImprovedExampleHelp string     `json:"improved_example_help"`
+	ImprovedExampleType string     `json:"improved_example_type"`
+	CardinalityAnalysis string     `json:"cardinality_analysis"`
+	MemoryImpact        string     `json:"memory_impact"`
+	Confidence          Confidence `json:"confidence"`
+	Model               string     `json:"model"`
+	// Served from the evaluation cache without calling the LLM
+	Cached bool `json:"cached"`
+}
+
+type Confidence struct {
+	Score float64 `json:"score"`
+	Level string  `json:"level"` // high, medium or low
+}
+
+// Finding is one static check result
+type Finding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"` // error, warning, info or praise
+	Metric   string `json:"metric"`
+	Message  string `json:"message"`
+}
+
+// Cardinality is the static estimate of the series the metrics create
+type Cardinality struct {
+	EstimatedSeries int    `json:"estimated_series"`
+	MemoryBytes     int64  `json:"memory_bytes"`
+	Level           string `json:"level"`
+	// Labels that drive the series count up
+	HighCardinalityRisks []string `json:"high_cardinality_risks"`
+	Warnings             []string `json:"warnings"`
+	// Labels replaced on every deploy, and how many times EstimatedSeries the
+	// head block holds because of them; omitted without churn
+	ChurnLabels     []string `json:"churn_labels,omitempty"`
+	ChurnMultiplier float64  `json:"churn_multiplier,omitempty"`
+}
+
+// Status of an evaluation job
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+)
+
+// JobResponse is GET /api/v1/evaluate/jobs/{id}: ID and Evaluation are set
+// once Status is done
+type JobResponse struct {
+	Status     string      `json:"status"`
+	ID         int64       `json:"id,omitempty"`
+	Evaluation *Evaluation `json:"evaluation,omitempty"`
+}
+
+// LabelCheckResponse is the verdict on one label, from POST /api/v1/evaluate/label
+type LabelCheckResponse struct {
+	Label  string `json:"label"`
+	Values int    `json:"values"`
+	// ok, monitor, review or avoid
+	Verdict  string        `json:"verdict"`
+	Findings []Finding     `json:"findings"`
+	Impact   []LabelImpact `json:"impact"`
+	// The LLM's paragraph, when asked for with llm=true
+	Opinion string `json:"opinion,omitempty"`
+}
+
+// LabelImpact is the label's series and memory on a number of scrape targets
+type LabelImpact struct {
+	Targets     int   `json:"targets"`
+	Series      int   `json:"series"`
+	MemoryBytes int64 `json:"memory_bytes"`
+}
+
+// MultiSetResponse is POST /api/v1/evaluate/multi-set's result: each group's
+// findings, and label names the groups disagree on
+type MultiSetResponse struct {
+	Groups           map[string]MultiSetGroup `json:"groups"`
+	CrossGroupIssues []ConsistencyIssue       `json:"cross_group_issues"`
+}
+
+type MultiSetGroup struct {
+	Problems    []Finding   `json:"problems"`
+	Praise      []Finding   `json:"praise"`
+	Cardinality Cardinality `json:"cardinality"`
+}
+
+// ConsistencyIssue is one dimension labeled with different names in different groups
+type ConsistencyIssue struct {
+	// Each label name used for the dimension, to the groups using it
+	Labels  map[string][]string `json:"labels"`
+	Message string              `json:"message"`
+}
+
+// Projection is the series count expected over the coming days
+type Projection struct {
+	Days             int               `json:"days"`
+	Points           []ProjectionPoint `json:"points"`
+	FinalSeries      int               `json:"final_series"`
+	FinalMemoryBytes int64             `json:"final_memory_bytes"`
+}
+
+type ProjectionPoint struct {
+	Day             int   `json:"day"`
+	EstimatedSeries int   `json:"estimated_series"`
+	MemoryBytes     int64 `json:"memory_bytes"`
+}
+
+// ExampleRun is a showcase example evaluated live, set against its curated verdict
+type ExampleRun struct {
+	Example    string     `json:"example"`
+	Evaluation Evaluation `json:"evaluation"`
+	Problems   []Finding  `json:"problems"`
+	// Good, or Needs Improvement when a static check found an error or warning
+	StaticVerdict string `json:"static_verdict"`
+	// Whether each verdict matches the curated one
+	LLMAgrees    bool `json:"llm_agrees"`
+	StaticAgrees bool `json:"static_agrees"`
+	// Served from the run cache
+	Cached bool `json:"cached"`
+}
+
+// Result is a stored evaluation, as GET /api/v1/evaluations/{id} returns it
+type Result struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Input     string    `json:"input"`
+	Verdict   string    `json:"verdict"`
+	Model     string    `json:"model"`
+	// Codes of the static findings other than praise; nil when the evaluation
+	// has no codes recorded, as opposed to empty when it had no findings
+	FindingCodes []string `json:"finding_codes"`
+	// Label bounds the submitter asserted; omitted when there were none
+	LabelBounds map[string]int `json:"label_bounds,omitempty"`
+}
+
+// Codes of errors a client can act on
+const (
+	// The session used up its evaluations; answer Challenge to go on
+	ErrorChallengeRequired = "challenge_required"
+	// The answer to the challenge was wrong or expired; Challenge is a new one
+	ErrorChallengeFailed = "challenge_failed"
+)
+
+// ErrorResponse is the body of every API error
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// One of the Error codes above, for errors a client can act on
+	Code string `json:"code,omitempty"`
+	// Set with the challenge codes
+	Challenge *Challenge `json:"challenge,omitempty"`
+}
+
+// Challenge is the question a session answers once it reaches the evaluation
+// cap. Send the answer as challenge_answer and Token as challenge_token.
+type Challenge struct {
+	Question string `json:"question"`
+	Token    string `json:"token"`
+}
@@ -0,0 +1,99 @@
+// ABOUTME: Table-driven tests for EvaluateRequest.Validate - every limit an evaluation request is held to
+// ABOUTME: Text fields are trimmed, empty and oversized metrics are refused, and the field of each failure is named
+
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  EvaluateRequest
+		// Field of the *RequestError; empty expects the request to pass
+		field   string
+		message string
+		// The request after a successful Validate
+		want EvaluateRequest
+	}{
+		{
+			name: "trims text fields",
+			req:  EvaluateRequest{Metrics: "\n  up 1\n\t", ScrapeConfig: "  job_name: api\n", Model: " llama3.2:3b ", Source: " textfile "},
+			want: EvaluateRequest{Metrics: "up 1", ScrapeConfig: "job_name: api", Model: "llama3.2:3b", Source: "textfile"},
+		},
+		{name: "empty metrics", req: EvaluateRequest{}, field: "metrics", message: "Please provide metrics to evaluate"},
+		{name: "whitespace metrics", req: EvaluateRequest{Metrics: " \n\t "}, field: "metrics", message: "Please provide metrics to evaluate"},
+		{
+			name: "metrics at the size limit",
+			req:  EvaluateRequest{Metrics: strings.Repeat("a", MaxMetricsBytes)},
+			want: EvaluateRequest{Metrics: strings.Repeat("a", MaxMetricsBytes)},
+		},
+		{
+			name:    "metrics over the size limit",
+			req:     EvaluateRequest{Metrics: strings.Repeat("a", MaxMetricsBytes+1)},
+			field:   "metrics",
+			message: "Metrics are 1048577 bytes; at most 1048576 are accepted",
+		},
+		{
+			name: "surrounding whitespace doesn't count towards the limit",
+			req:  EvaluateRequest{Metrics: " " + strings.Repeat("a", MaxMetricsBytes) + "\n"},
+			want: EvaluateRequest{Metrics: strings.Repeat("a", MaxMetricsBytes)},
+		},
+		{name: "unknown source", req: EvaluateRequest{Metrics: "up 1", Source: "pushgateway"}, field: "source", message: `Unknown source "pushgateway"; only textfile is supported`},
+		{name: "negative deploys", req: EvaluateRequest{Metrics: "up 1", DeploysPerDay: -1}, field: "deploys_per_day", message: "deploys_per_day must be at least 0"},
+		{
+			name: "no deploys",
+			req:  EvaluateRequest{Metrics: "up 1", DeploysPerDay: 0},
+			want: EvaluateRequest{Metrics: "up 1"},
+		},
+		{name: "unnamed label bound", req: EvaluateRequest{Metrics: "up 1", LabelBounds: LabelBounds{"": 3}}, field: "label_bounds", message: "Every label bound needs a label name"},
+		{name: "zero label bound", req: EvaluateRequest{Metrics: "up 1", LabelBounds: LabelBounds{"region": 0}}, field: "label_bounds", message: "The bound of label region must be at least 1"},
+		{
+			name: "label bounds",
+			req:  EvaluateRequest{Metrics: "up 1", LabelBounds: LabelBounds{"region": 6, "customer_id": 80}},
+			want: EvaluateRequest{Metrics: "up 1", LabelBounds: LabelBounds{"region": 6, "customer_id": 80}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := req.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v", err)
+				}
+				if req.Metrics != tt.want.Metrics || req.ScrapeConfig != tt.want.ScrapeConfig || req.Model != tt.want.Model ||
+					req.Source != tt.want.Source || req.LabelBounds.String() != tt.want.LabelBounds.String() {
+					t.Errorf("request = %+v, want %+v", req, tt.want)
+				}
+				return
+			}
+
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("Validate = %v, want a *RequestError", err)
+			}
+			if reqErr.Field != tt.field || reqErr.Message != tt.message {
+				t.Errorf("error = %s: %s, want %s: %s", reqErr.Field, reqErr.Message, tt.field, tt.message)
+			}
+		})
+	}
+}
+
+func TestLabelBoundsForm(t *testing.T) {
+	var b LabelBounds
+	if err := b.UnmarshalParam(" customer_id=80, region = 6 ,"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "customer_id=80,region=6" {
+		t.Errorf("bounds = %s", b)
+	}
+	for _, param := range []string{"region", "region=six"} {
+		if err := b.UnmarshalParam(param); err == nil {
+			t.Errorf("UnmarshalParam accepted %q", param)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// ABOUTME: Go client for the Good Telemetry HTTP API, for tools that evaluate metrics remotely
+// ABOUTME: Wraps evaluation and stored results, and retries rate-limited calls after Retry-After
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const (
+	// LLM evaluations can be slow
+	defaultTimeout    = 2 * time.Minute
+	defaultMaxRetries = 3
+	// Used when a 429 carries no usable Retry-After
+	defaultRetryAfter = time.Second
+	// Scrape outputs larger than this are refused by EvaluateURL
+	maxScrapeBytes = 10 << 20
+)
+
+type Client struct {
+	BaseURL string
+	// One of the server's API_KEYS; evaluations skip the per-session cap and
+	// stored results can be read back
+	APIKey string
+	// Limits each attempt, not the retries as a whole; use the context for that
+	Timeout time.Duration
+	// How often a call answered with 429 Too Many Requests is tried again
+	MaxRetries int
+}
+
+// Error is a non-2xx answer from the server
+type Error struct {
+	StatusCode int
+	// The server's error message, or the status text when it sent none
+	Message string
+	// Requested wait before trying again, from Retry-After
+	RetryAfter time.Duration
+	// The server's error code, such as api.ErrorChallengeRequired
+	Code string
+	// The question to answer with EvaluateOptions.ChallengeAnswer, when the
+	// session reached its evaluation cap
+	Challenge *api.Challenge
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("good telemetry: %d %s", e.StatusCode, e.Message)
+}
+
+// EvaluateOptions are the optional evaluation settings
+type EvaluateOptions struct {
+	Model        string
+	Source       string
+	ScrapeConfig string
+	ShareConsent bool
+	// Evaluate standard runtime metrics (go_, process_, ...) too
+	IncludeRuntime bool
+	// Metrics pushed to a Pushgateway, which must carry job and instance themselves
+	PushGatewayMode bool
+	// The answer to the Challenge of a previous Error, with its token
+	ChallengeToken  string
+	ChallengeAnswer string
+}
+
+// Comparison is two evaluations of the same metrics, before and after a change
+type Comparison struct {
+	Before         *api.EvaluateResponse
+	After          *api.EvaluateResponse
+	VerdictChanged bool
+	// Finding codes After has and Before doesn't, and the other way round
+	NewFindings      []string
+	ResolvedFindings []string
+}
+
+// NewClient talks to the server at baseURL. apiKey should be one of the
+// server's API_KEYS so requests skip the per-session evaluation cap.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		Timeout:    defaultTimeout,
+		MaxRetries: defaultMaxRetries,
+	}
+}
+
+// Evaluate submits metrics in Prometheus text format
+func (c *Client) Evaluate(ctx context.Context, text string, opts EvaluateOptions) (*api.EvaluateResponse, error) {
+	form := api.EvaluateRequest{
+		Metrics:         text,
+		ShareConsent:    opts.ShareConsent,
+		Model:           opts.Model,
+		Source:          opts.Source,
+		ScrapeConfig:    opts.ScrapeConfig,
+		IncludeRuntime:  opts.IncludeRuntime,
+		PushGatewayMode: opts.PushGatewayMode,
+		ChallengeToken:  opts.ChallengeToken,
+		ChallengeAnswer: opts.ChallengeAnswer,
+	}.Form()
+
+	var result api.EvaluateResponse
+	if err := c.do(ctx, http.MethodPost, "/evaluate", []byte(form.Encode()), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EvaluateURL scrapes a metrics endpoint from where the client runs and
+// evaluates what it returns. The API key is not sent to the endpoint.
+func (c *Client) EvaluateURL(ctx context.Context, metricsURL string, opts EvaluateOptions) (*api.EvaluateResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scraping %s: %w", metricsURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scraping %s: %s", metricsURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("scraping %s: %w", metricsURL, err)
+	}
+	if len(body) > maxScrapeBytes {
+		return nil, fmt.Errorf("scraping %s: more than %d bytes", metricsURL, maxScrapeBytes)
+	}
+	return c.Evaluate(ctx, string(body), opts)
+}
+
+// Compare evaluates before and after and reports how the verdict and the
+// static findings differ
+func (c *Client) Compare(ctx context.Context, before, after string, opts EvaluateOptions) (*Comparison, error) {
+	b, err := c.Evaluate(ctx, before, opts)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating before: %w", err)
+	}
+	a, err := c.Evaluate(ctx, after, opts)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating after: %w", err)
+	}
+
+	beforeCodes, afterCodes := findingCodes(b.Problems), findingCodes(a.Problems)
+	return &Comparison{
+		Before:           b,
+		After:            a,
+		VerdictChanged:   !strings.EqualFold(b.Evaluation.Verdict, a.Evaluation.Verdict),
+		NewFindings:      missingFrom(afterCodes, beforeCodes),
+		ResolvedFindings: missingFrom(beforeCodes, afterCodes),
+	}, nil
+}
+
+// GetResult reads back a stored evaluation by the ID Evaluate returned
+func (c *Client) GetResult(ctx context.Context, id int64) (*api.Result, error) {
+	var result api.Result
+	if err := c.do(ctx, http.MethodGet, "/api/v1/evaluations/"+strconv.FormatInt(id, 10), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// do sends a request, form-encoding body when there is one, and decodes the
+// JSON answer into out. 429 answers are retried after their Retry-After.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, path, body, out)
+
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || attempt >= c.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(apiErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Good Telemetry at %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		var envelope api.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&envelope) == nil && envelope.Error != "" {
+			apiErr.Message = envelope.Error
+			apiErr.Code = envelope.Code
+			apiErr.Challenge = envelope.Challenge
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response from %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	return &http.Client{Timeout: c.Timeout}
+}
+
+// retryAfter reads Retry-After as seconds or an HTTP date
+func retryAfter(header string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return max(when.Sub(now), 0)
+	}
+	return defaultRetryAfter
+}
+
+func findingCodes(findings []api.Finding) []string {
+	codes := make([]string, 0, len(findings))
+	for _, f := range findings {
+		codes = append(codes, f.Code)
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+// missingFrom returns the codes in a that b lacks
+func missingFrom(a, b []string) []string {
+	missing := []string{}
+	for _, code := range a {
+		if !slices.Contains(b, code) {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,333 @@
+// ABOUTME: Contract tests - every client method against the real server handlers over httptest
+// ABOUTME: A stub Ollama answers the LLM calls; its verdict is Bad for camelCase metrics and Good otherwise
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wbollock/good_telemetry/internal/rules"
+	"github.com/wbollock/good_telemetry/internal/server"
+	"github.com/wbollock/good_telemetry/pkg/api"
+)
+
+const (
+	apiKey = "client-key"
+
+	goodMetrics = `# HELP http_requests_total Total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 1
+`
+	badMetrics = `# TYPE requestCount counter
+requestCount 1
+`
+)
+
+func newStubOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			json.NewEncoder(w).Encode(map[string]any{"models": []map[string]string{{"name": "llama3.2:3b"}}})
+			return
+		}
+		var req struct{ Prompt string }
+		json.NewDecoder(r.Body).Decode(&req)
+		verdict := "VERDICT: Good\nSCORE: 90\nISSUES:\n- none"
+		if strings.Contains(req.Prompt, "requestCount") {
+			verdict = "VERDICT: Bad\nSCORE: 20\nISSUES:\n- requestCount is camelCase"
+		}
+		json.NewEncoder(w).Encode(map[string]any{"response": verdict, "done": true})
+	}))
+	t.Cleanup(ollama.Close)
+	return ollama
+}
+
+// newServer serves the real router, with history in memory and the stub LLM
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConfigFile, cfg.KubernetesConfigMapMountPath = "", ""
+	cfg.DatabasePath, cfg.EvalCacheBackend, cfg.AuditLogPath = "", "", ""
+	cfg.LLMCassetteDir = ""
+	cfg.OIDC.Issuer = ""
+	cfg.Profile = rules.DefaultProfile
+	cfg.Model = "llama3.2:3b"
+	cfg.LLMURL = newStubOllama(t).URL
+	cfg.TemplatesDir = filepath.Join("..", "..", "web", "templates")
+	cfg.SessionEvaluationLimit = 1
+	cfg.APIKeys = []string{apiKey}
+
+	router, err := server.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEvaluateAndGetResult(t *testing.T) {
+	c := NewClient(newServer(t).URL+"/", apiKey)
+	ctx := context.Background()
+
+	// Past the session cap of one, which the API key skips
+	for range 2 {
+		result, err := c.Evaluate(ctx, badMetrics, EvaluateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Evaluation.Verdict != "Bad" || result.ID == 0 {
+			t.Fatalf("Evaluate = %+v, want a stored Bad verdict", result)
+		}
+	}
+
+	first, err := c.Evaluate(ctx, goodMetrics, EvaluateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := c.GetResult(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != first.ID || !strings.Contains(stored.Input, "http_requests_total") {
+		t.Errorf("GetResult = %+v, want evaluation %d", stored, first.ID)
+	}
+}
+
+func TestEvaluateURL(t *testing.T) {
+	c := NewClient(newServer(t).URL, apiKey)
+	var auth atomic.Value
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(badMetrics))
+	}))
+	defer exporter.Close()
+
+	result, err := c.EvaluateURL(context.Background(), exporter.URL, EvaluateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Evaluation.Verdict != "Bad" {
+		t.Errorf("verdict = %q, want the scraped metrics judged", result.Evaluation.Verdict)
+	}
+	if auth.Load() != "" {
+		t.Errorf("the exporter got Authorization %q, want the API key kept from it", auth.Load())
+	}
+
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+	if _, err := c.EvaluateURL(context.Background(), broken.URL, EvaluateOptions{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("EvaluateURL of a missing endpoint = %v, want the scrape's 404", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c := NewClient(newServer(t).URL, apiKey)
+	cmp, err := c.Compare(context.Background(), badMetrics, goodMetrics, EvaluateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.VerdictChanged || cmp.Before.Evaluation.Verdict != "Bad" || cmp.After.Evaluation.Verdict != "Good" {
+		t.Errorf("comparison = %+v, want Bad before and Good after", cmp)
+	}
+	if len(cmp.ResolvedFindings) == 0 {
+		t.Error("renaming requestCount resolved no findings")
+	}
+	if cmp.NewFindings == nil {
+		t.Error("NewFindings is nil, want an empty list")
+	}
+}
+
+func TestErrorsCarryTheServersMessage(t *testing.T) {
+	srv := newServer(t)
+
+	_, err := NewClient(srv.URL, apiKey).GetResult(context.Background(), 999)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "evaluation not found" {
+		t.Errorf("GetResult of a missing ID = %v, want a 404 Error with the server's message", err)
+	}
+
+	_, err = NewClient(srv.URL, "").GetResult(context.Background(), 1)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GetResult without a key = %v, want a 401 Error", err)
+	}
+
+	_, err = NewClient(srv.URL, apiKey).Evaluate(context.Background(), "", EvaluateOptions{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message == "" {
+		t.Errorf("Evaluate of nothing = %v, want a 400 Error", err)
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	// Without cookies every call starts a new session, so the cap of one never applies
+	c := NewClient(newServer(t).URL, "")
+	for range 2 {
+		if result, err := c.Evaluate(context.Background(), goodMetrics, EvaluateOptions{}); err != nil || result.Evaluation.Verdict != "Good" {
+			t.Fatalf("Evaluate without a key = %+v, %v, want the evaluation", result, err)
+		}
+	}
+
+	// A session past its cap is answered with a challenge
+	challenged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("challenge_token") == "token" && r.PostFormValue("challenge_answer") == "7" {
+			w.Write([]byte(`{"evaluation":{"Verdict":"Good"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(api.ErrorResponse{Error: "answer the challenge", Code: api.ErrorChallengeRequired,
+			Challenge: &api.Challenge{Question: "What is 3 + 4?", Token: "token"}})
+	}))
+	defer challenged.Close()
+
+	c = NewClient(challenged.URL, "")
+	_, err := c.Evaluate(context.Background(), goodMetrics, EvaluateOptions{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.Code != api.ErrorChallengeRequired ||
+		apiErr.Challenge == nil || apiErr.Challenge.Question != "What is 3 + 4?" {
+		t.Fatalf("Evaluate past the cap = %v, want an Error with the challenge", err)
+	}
+	result, err := c.Evaluate(context.Background(), goodMetrics, EvaluateOptions{ChallengeToken: apiErr.Challenge.Token, ChallengeAnswer: "7"})
+	if err != nil || result.Evaluation.Verdict != "Good" {
+		t.Errorf("Evaluate with the answer = %+v, %v", result, err)
+	}
+}
+
+// Every JSON response decodes into its pkg/api type with no field left over,
+// so the shared types are the whole of what the server sends
+func TestResponsesAreTheAPITypes(t *testing.T) {
+	srv := newServer(t)
+	call := func(method, path string, form url.Values, out any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s = %d", method, path, resp.StatusCode)
+		}
+		dec := json.NewDecoder(resp.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+
+	var evaluated api.EvaluateResponse
+	call(http.MethodPost, "/evaluate", url.Values{"metrics": {badMetrics}}, &evaluated)
+	if evaluated.Evaluation.Verdict != "Bad" || len(evaluated.Problems) == 0 || evaluated.Cardinality.Level == "" {
+		t.Errorf("/evaluate = %+v, want the verdict, findings and estimate", evaluated)
+	}
+
+	var quick api.EvaluateResponse
+	call(http.MethodPost, "/api/v1/evaluate/quick", url.Values{"metrics": {badMetrics}}, &quick)
+	if quick.Evaluation.Verdict != "" || len(quick.Problems) == 0 {
+		t.Errorf("quick evaluation = %+v, want findings without a verdict", quick)
+	}
+
+	var full api.EvaluateResponse
+	call(http.MethodPost, "/api/v1/evaluate/full", url.Values{"metrics": {goodMetrics}}, &full)
+	if full.Job == "" {
+		t.Fatalf("full evaluation = %+v, want a job", full)
+	}
+	var job api.JobResponse
+	for deadline := time.Now().Add(5 * time.Second); job.Status != api.JobDone && time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		job = api.JobResponse{}
+		call(http.MethodGet, "/api/v1/evaluate/jobs/"+full.Job, nil, &job)
+	}
+	if job.Status != api.JobDone || job.Evaluation == nil || job.Evaluation.Verdict != "Good" {
+		t.Errorf("job = %+v, want the finished verdict", job)
+	}
+
+	var label api.LabelCheckResponse
+	call(http.MethodPost, "/api/v1/evaluate/label", url.Values{"label": {"user_id"}, "values": {"100000"}}, &label)
+	if label.Label != "user_id" || label.Verdict == "" || len(label.Impact) == 0 {
+		t.Errorf("label check = %+v, want its verdict and impact", label)
+	}
+}
+
+func TestRateLimitedCallsAreRetried(t *testing.T) {
+	srv := newServer(t)
+	var calls atomic.Int32
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first two calls are turned away
+		if calls.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"slow down"}`))
+			return
+		}
+		proxy, _ := http.NewRequestWithContext(r.Context(), r.Method, srv.URL+r.URL.Path, r.Body)
+		proxy.Header = r.Header
+		resp, err := http.DefaultClient.Do(proxy)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		var body json.RawMessage
+		json.NewDecoder(resp.Body).Decode(&body)
+		w.Write(body)
+	}))
+	defer limited.Close()
+
+	c := NewClient(limited.URL, apiKey)
+	if _, err := c.Evaluate(context.Background(), goodMetrics, EvaluateOptions{}); err != nil {
+		t.Fatalf("Evaluate after two 429s = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+
+	calls.Store(0)
+	c.MaxRetries = 1
+	_, err := c.Evaluate(context.Background(), goodMetrics, EvaluateOptions{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "slow down" {
+		t.Errorf("Evaluate with one retry = %v, want the 429 once retries run out", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"5", 5 * time.Second},
+		{"0", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"", defaultRetryAfter},
+		{"-1", defaultRetryAfter},
+		{"soon", defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// ABOUTME: Terraform provider binary - validates metric names at plan time against a Good Telemetry server
+// ABOUTME: Serves the goodtelemetry provider over the Terraform plugin protocol
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+)
+
+func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "Run with support for debuggers like delve")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), newProvider, providerserver.ServeOpts{
+		Address: "registry.terraform.io/wbollock/goodtelemetry",
+		Debug:   debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Provider definition - endpoint and API key configuration shared by all resources
+// ABOUTME: Falls back to GOODTELEMETRY_ENDPOINT and GOODTELEMETRY_API_KEY when unset in HCL
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/wbollock/good_telemetry/pkg/client"
+)
+
+const defaultEndpoint = "http://localhost:8080"
+
+type goodTelemetryProvider struct{}
+
+type providerModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	APIKey   types.String `tfsdk:"api_key"`
+}
+
+func newProvider() provider.Provider {
+	return &goodTelemetryProvider{}
+}
+
+func (p *goodTelemetryProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "goodtelemetry"
+}
+
+func (p *goodTelemetryProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Evaluates Prometheus metrics with a Good Telemetry server.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "Base URL of the Good Telemetry server. Defaults to GOODTELEMETRY_ENDPOINT, then " + defaultEndpoint + ".",
+				Optional:    true,
+			},
+			"api_key": schema.StringAttribute{
+				Description: "One of the server's API_KEYS, so plans are not subject to the per-session evaluation cap. Defaults to GOODTELEMETRY_API_KEY.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (p *goodTelemetryProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var cfg providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoint := cfg.Endpoint.ValueString()
+	if endpoint == "" {
+		endpoint = os.Getenv("GOODTELEMETRY_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	apiKey := cfg.APIKey.ValueString()
+	if apiKey == "" {
+		apiKey = os.Getenv("GOODTELEMETRY_API_KEY")
+	}
+
+	resp.ResourceData = client.NewClient(endpoint, apiKey)
+}
+
+func (p *goodTelemetryProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{newMetricResource}
+}
+
+func (p *goodTelemetryProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
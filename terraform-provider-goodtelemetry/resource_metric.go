@@ -0,0 +1,257 @@
+// ABOUTME: goodtelemetry_metric resource - evaluates a metric during plan and fails below a minimum verdict
+// ABOUTME: Stores the verdict, issues and recommendations as attributes; nothing exists remotely to create or delete
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/wbollock/good_telemetry/pkg/client"
+)
+
+// Verdicts from worst to best, lowercased
+var verdictRank = map[string]int{
+	"poor":              0,
+	"needs improvement": 1,
+	"good":              2,
+}
+
+const defaultMinVerdict = "Needs Improvement"
+
+type metricResource struct {
+	client *client.Client
+}
+
+type metricModel struct {
+	ID              types.String `tfsdk:"id"`
+	Metric          types.String `tfsdk:"metric"`
+	MinVerdict      types.String `tfsdk:"min_verdict"`
+	Verdict         types.String `tfsdk:"verdict"`
+	Issues          types.List   `tfsdk:"issues"`
+	Recommendations types.List   `tfsdk:"recommendations"`
+	StaticFindings  types.List   `tfsdk:"static_findings"`
+}
+
+var (
+	_ resource.ResourceWithConfigure  = &metricResource{}
+	_ resource.ResourceWithModifyPlan = &metricResource{}
+)
+
+func newMetricResource() resource.Resource {
+	return &metricResource{}
+}
+
+func (r *metricResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_metric"
+}
+
+func (r *metricResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A Prometheus metric evaluated by Good Telemetry at plan time. The plan fails when the verdict is below min_verdict.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "SHA-256 of the metric text.",
+				Computed:    true,
+			},
+			"metric": schema.StringAttribute{
+				Description: "Metric in Prometheus exposition format, e.g. http_requests_total{method=\"GET\"} 1.",
+				Required:    true,
+			},
+			"min_verdict": schema.StringAttribute{
+				Description: "Lowest acceptable verdict: Good, Needs Improvement or Poor. Defaults to " + defaultMinVerdict + ".",
+				Optional:    true,
+			},
+			"verdict": schema.StringAttribute{
+				Description: "Verdict from the last evaluation.",
+				Computed:    true,
+			},
+			"issues": schema.ListAttribute{
+				Description: "Issues found by the LLM.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"recommendations": schema.ListAttribute{
+				Description: "Recommendations from the LLM.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"static_findings": schema.ListAttribute{
+				Description: "Problems reported by the static rules, as \"severity code: message\".",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *metricResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*client.Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("expected *client.Client, got %T", req.ProviderData))
+		return
+	}
+	r.client = client
+}
+
+// ModifyPlan evaluates the metric so a bad name fails terraform plan rather
+// than apply. Unchanged metrics keep their stored result: LLM verdicts are not
+// deterministic and re-evaluating every plan would show a perpetual diff.
+func (r *metricResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan metricModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Metric.IsUnknown() || plan.MinVerdict.IsUnknown() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state metricModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if state.Metric.Equal(plan.Metric) && state.MinVerdict.Equal(plan.MinVerdict) {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(r.evaluate(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *metricResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan metricModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Metrics built from other resources are only known at apply time
+	if plan.Verdict.IsUnknown() {
+		resp.Diagnostics.Append(r.evaluate(ctx, &plan)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read keeps the stored result; there is no remote object to refresh from
+func (r *metricResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state metricModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *metricResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan metricModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.Verdict.IsUnknown() {
+		resp.Diagnostics.Append(r.evaluate(ctx, &plan)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *metricResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// evaluate fills the computed attributes from the server, reporting an error
+// when the verdict is below the minimum
+func (r *metricResource) evaluate(ctx context.Context, m *metricModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	minVerdict := m.MinVerdict.ValueString()
+	if minVerdict == "" {
+		minVerdict = defaultMinVerdict
+	}
+	minRank, ok := verdictRank[strings.ToLower(minVerdict)]
+	if !ok {
+		diags.AddAttributeError(path.Root("min_verdict"), "Invalid min_verdict",
+			fmt.Sprintf("%q is not one of Good, Needs Improvement or Poor", minVerdict))
+		return diags
+	}
+
+	if r.client == nil {
+		diags.AddError("Provider not configured", "the goodtelemetry provider has no client")
+		return diags
+	}
+	result, err := r.client.Evaluate(ctx, m.Metric.ValueString(), client.EvaluateOptions{})
+	if err != nil {
+		diags.AddError("Evaluation failed", err.Error())
+		return diags
+	}
+
+	var findings []string
+	for _, f := range result.Problems {
+		findings = append(findings, fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message))
+	}
+
+	sum := sha256.Sum256([]byte(m.Metric.ValueString()))
+	m.ID = types.StringValue(hex.EncodeToString(sum[:]))
+	m.Verdict = types.StringValue(result.Evaluation.Verdict)
+	for _, list := range []struct {
+		dest   *types.List
+		values []string
+	}{
+		{&m.Issues, result.Evaluation.Issues},
+		{&m.Recommendations, result.Evaluation.Recommendations},
+		{&m.StaticFindings, findings},
+	} {
+		value, d := types.ListValueFrom(ctx, types.StringType, nonNil(list.values))
+		diags.Append(d...)
+		*list.dest = value
+	}
+
+	rank, ok := verdictRank[strings.ToLower(result.Evaluation.Verdict)]
+	if !ok {
+		diags.AddError("Unrecognized verdict", fmt.Sprintf("the server returned verdict %q", result.Evaluation.Verdict))
+		return diags
+	}
+	if rank < minRank {
+		detail := fmt.Sprintf("Verdict %q is below min_verdict %q.", result.Evaluation.Verdict, minVerdict)
+		if len(result.Evaluation.Issues) > 0 {
+			detail += "\n\nIssues:\n- " + strings.Join(result.Evaluation.Issues, "\n- ")
+		}
+		if len(findings) > 0 {
+			detail += "\n\nStatic findings:\n- " + strings.Join(findings, "\n- ")
+		}
+		diags.AddAttributeError(path.Root("metric"), "Metric quality below threshold", detail)
+	}
+	return diags
+}
+
+// nonNil stores empty results as [] rather than null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}